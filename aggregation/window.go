@@ -0,0 +1,267 @@
+package aggregation
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+type Config[V any] struct {
+	// Key returns the aggregation key of the event, events with false are not aggregated.
+	Key func(event interface{}) (string, bool)
+	// Add returns the value of the key after the event is added.
+	Add func(value V, event interface{}) V
+	// Emit is called once for each key of a window when the watermark passes the end of the window.
+	Emit func(window Window, key string, value V)
+	// Size is the length of the windows.
+	Size time.Duration
+	// Slide is the interval between the starts of the windows, zero means tumbling windows.
+	Slide time.Duration
+	// AllowedLateness delays the watermark, so events which come this late are still aggregated.
+	AllowedLateness time.Duration
+	// IdleTimeout excludes the vBuckets which have no event for this long from the watermark, zero disables it.
+	IdleTimeout time.Duration
+	// TickInterval is how often the watermark is advanced without events.
+	TickInterval time.Duration
+}
+
+type pendingAck struct {
+	end time.Time
+	ack func()
+}
+
+type vbState struct {
+	maxEventTime time.Time
+	lastSeen     time.Time
+	pending      []pendingAck
+}
+
+// Aggregator is a models.Consumer which aggregates mutation, deletion and expiration events into
+// event time windows. The watermark is the lowest latest event time of the vBuckets. An event is acked
+// only after every window it belongs to is emitted, so the state of open windows is rebuilt from the
+// checkpoint after a restart.
+type Aggregator[V any] struct {
+	windows   map[Window]map[string]V
+	vbs       map[uint16]*vbState
+	stopCh    chan struct{}
+	watermark time.Time
+	config    Config[V]
+	lock      sync.Mutex
+	stopOnce  sync.Once
+}
+
+func (a *Aggregator[V]) ConsumeEvent(ctx *models.ListenerContext) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	vb, ok := a.vbs[ctx.VbID]
+	if !ok {
+		vb = &vbState{}
+		a.vbs[ctx.VbID] = vb
+	}
+
+	vb.lastSeen = time.Now()
+
+	eventTime, ok := eventTimeOf(ctx.Event)
+	if !ok {
+		vb.pending = append(vb.pending, pendingAck{ack: ctx.Ack})
+		a.advance()
+		return
+	}
+
+	if eventTime.After(vb.maxEventTime) {
+		vb.maxEventTime = eventTime
+	}
+
+	var end time.Time
+
+	if key, ok := a.config.Key(ctx.Event); ok {
+		for _, window := range a.windowsOf(eventTime) {
+			// Windows behind the watermark are emitted already, late events are dropped.
+			if !window.End.After(a.watermark) {
+				continue
+			}
+
+			values, ok := a.windows[window]
+			if !ok {
+				values = map[string]V{}
+				a.windows[window] = values
+			}
+
+			values[key] = a.config.Add(values[key], ctx.Event)
+
+			if window.End.After(end) {
+				end = window.End
+			}
+		}
+	}
+
+	vb.pending = append(vb.pending, pendingAck{end: end, ack: ctx.Ack})
+	a.advance()
+}
+
+func (a *Aggregator[V]) TrackOffset(_ uint16, _ *models.Offset) {}
+
+// Discard drops the open windows when the stream closes, their events are streamed again from the checkpoint.
+// The watermark is kept, so the windows which are emitted already are not emitted again.
+func (a *Aggregator[V]) Discard() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.windows = map[Window]map[string]V{}
+	a.vbs = map[uint16]*vbState{}
+}
+
+func (a *Aggregator[V]) Close() {
+	a.stopOnce.Do(func() {
+		close(a.stopCh)
+	})
+}
+
+func (a *Aggregator[V]) windowsOf(eventTime time.Time) []Window {
+	slide := a.config.Slide
+	if slide <= 0 {
+		slide = a.config.Size
+	}
+
+	var windows []Window
+	for start := eventTime.Truncate(slide); start.Add(a.config.Size).After(eventTime); start = start.Add(-slide) {
+		windows = append(windows, Window{Start: start, End: start.Add(a.config.Size)})
+	}
+
+	return windows
+}
+
+func (a *Aggregator[V]) currentWatermark() time.Time {
+	var watermark, latest time.Time
+	active := false
+
+	for _, vb := range a.vbs {
+		if vb.maxEventTime.IsZero() {
+			continue
+		}
+
+		if vb.maxEventTime.After(latest) {
+			latest = vb.maxEventTime
+		}
+
+		if a.config.IdleTimeout > 0 && time.Since(vb.lastSeen) > a.config.IdleTimeout {
+			continue
+		}
+
+		if !active || vb.maxEventTime.Before(watermark) {
+			watermark = vb.maxEventTime
+			active = true
+		}
+	}
+
+	if !active {
+		watermark = latest
+	}
+
+	if watermark.IsZero() {
+		return watermark
+	}
+
+	return watermark.Add(-a.config.AllowedLateness)
+}
+
+// advance emits the windows behind the watermark and acks the events which have no open window left.
+// It must be called with the lock held.
+func (a *Aggregator[V]) advance() {
+	if watermark := a.currentWatermark(); watermark.After(a.watermark) {
+		a.watermark = watermark
+	}
+
+	var closed []Window
+	for window := range a.windows {
+		if !window.End.After(a.watermark) {
+			closed = append(closed, window)
+		}
+	}
+
+	sort.Slice(closed, func(i, j int) bool {
+		if closed[i].End.Equal(closed[j].End) {
+			return closed[i].Start.Before(closed[j].Start)
+		}
+		return closed[i].End.Before(closed[j].End)
+	})
+
+	for _, window := range closed {
+		for key, value := range a.windows[window] {
+			a.config.Emit(window, key, value)
+		}
+
+		delete(a.windows, window)
+	}
+
+	for _, vb := range a.vbs {
+		var ack func()
+
+		i := 0
+		for i < len(vb.pending) && !vb.pending[i].end.After(a.watermark) {
+			ack = vb.pending[i].ack
+			i++
+		}
+
+		vb.pending = vb.pending[i:]
+
+		if ack != nil {
+			ack()
+		}
+	}
+}
+
+func (a *Aggregator[V]) tick() {
+	ticker := time.NewTicker(a.config.TickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stopCh:
+			return
+		case <-ticker.C:
+			a.lock.Lock()
+			a.advance()
+			a.lock.Unlock()
+		}
+	}
+}
+
+func eventTimeOf(event interface{}) (time.Time, bool) {
+	switch v := event.(type) {
+	case models.DcpMutation:
+		return v.EventTime, true
+	case models.DcpDeletion:
+		return v.EventTime, true
+	case models.DcpExpiration:
+		return v.EventTime, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// NewAggregator creates an aggregator which can be passed to dcp.NewExtendedDcp as the consumer.
+func NewAggregator[V any](config Config[V]) *Aggregator[V] {
+	if config.TickInterval <= 0 {
+		config.TickInterval = time.Second
+	}
+
+	aggregator := &Aggregator[V]{
+		config:  config,
+		windows: map[Window]map[string]V{},
+		vbs:     map[uint16]*vbState{},
+		stopCh:  make(chan struct{}),
+	}
+
+	go aggregator.tick()
+
+	return aggregator
+}
@@ -0,0 +1,63 @@
+package aggregation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+func TestAggregatorTumblingWindow(t *testing.T) {
+	emitted := map[time.Time]int{}
+
+	aggregator := NewAggregator(Config[int]{
+		Size: time.Minute,
+		Key: func(_ interface{}) (string, bool) {
+			return "key", true
+		},
+		Add: func(count int, _ interface{}) int {
+			return count + 1
+		},
+		Emit: func(window Window, _ string, count int) {
+			emitted[window.Start] = count
+		},
+	})
+	defer aggregator.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	acked := 0
+
+	consume := func(eventTime time.Time) {
+		aggregator.ConsumeEvent(&models.ListenerContext{
+			Event: models.DcpMutation{EventTime: eventTime},
+			Ack:   func() { acked++ },
+		})
+	}
+
+	consume(start.Add(10 * time.Second))
+	consume(start.Add(20 * time.Second))
+
+	if len(emitted) != 0 || acked != 0 {
+		t.Fatalf("window must be open")
+	}
+
+	consume(start.Add(70 * time.Second))
+
+	if emitted[start] != 2 {
+		t.Errorf("count must be 2, got %d", emitted[start])
+	}
+
+	if acked != 1 {
+		t.Errorf("events of the emitted window must be acked once, got %d", acked)
+	}
+}
+
+func TestAggregatorSlidingWindows(t *testing.T) {
+	aggregator := NewAggregator(Config[int]{Size: time.Minute, Slide: 20 * time.Second})
+	defer aggregator.Close()
+
+	windows := aggregator.windowsOf(time.Date(2024, 1, 1, 0, 0, 50, 0, time.UTC))
+	if len(windows) != 3 {
+		t.Errorf("event must be in 3 windows, got %d", len(windows))
+	}
+}
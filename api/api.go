@@ -1,7 +1,17 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
 
 	"github.com/Trendyol/go-dcp/helpers"
 	"github.com/Trendyol/go-dcp/membership"
@@ -19,6 +29,7 @@ import (
 
 	"github.com/Trendyol/go-dcp/couchbase"
 	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/schema"
 	"github.com/Trendyol/go-dcp/servicediscovery"
 	"github.com/Trendyol/go-dcp/stream"
 
@@ -29,6 +40,12 @@ type API interface {
 	Listen()
 	Shutdown()
 	UnregisterMetricCollectors()
+	Handler() http.Handler
+	FastHTTPHandler() fasthttp.RequestHandler
+	MetricsHandler() http.Handler
+	FastHTTPMetricsHandler() fasthttp.RequestHandler
+	HealthHandler() http.Handler
+	FastHTTPHealthHandler() fasthttp.RequestHandler
 }
 
 type api struct {
@@ -40,6 +57,9 @@ type api struct {
 	registerer       *metric.Registerer
 	membershipInfo   *membership.Model
 	bus              EventBus.Bus
+	auditStore       couchbase.AuditStore
+	groupInspector   couchbase.GroupInspector
+	capabilities     *models.CapabilityReport
 }
 
 func (s *api) Listen() {
@@ -66,6 +86,32 @@ func (s *api) UnregisterMetricCollectors() {
 	s.registerer.UnregisterAll()
 }
 
+// Handler serves every endpoint of the api, so it can be mounted on a host net/http server.
+func (s *api) Handler() http.Handler {
+	return adaptor.FiberApp(s.app)
+}
+
+// FastHTTPHandler serves every endpoint of the api, so it can be mounted on a host fasthttp server.
+func (s *api) FastHTTPHandler() fasthttp.RequestHandler {
+	return s.app.Handler()
+}
+
+func (s *api) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{})
+}
+
+func (s *api) FastHTTPMetricsHandler() fasthttp.RequestHandler {
+	return fasthttpadaptor.NewFastHTTPHandler(s.MetricsHandler())
+}
+
+func (s *api) HealthHandler() http.Handler {
+	return adaptor.FiberHandler(s.status)
+}
+
+func (s *api) FastHTTPHealthHandler() fasthttp.RequestHandler {
+	return fasthttpadaptor.NewFastHTTPHandler(s.HealthHandler())
+}
+
 func (s *api) status(c *fiber.Ctx) error {
 	if _, err := s.client.Ping(); err != nil {
 		return err
@@ -74,6 +120,29 @@ func (s *api) status(c *fiber.Ctx) error {
 	return c.SendString("OK")
 }
 
+func (s *api) latency(c *fiber.Ctx) error {
+	metric, _ := s.stream.GetMetric()
+
+	return c.JSON(map[string]interface{}{
+		"process":   metric.ProcessLatency.Snapshot(),
+		"dcp":       metric.DcpLatency.Snapshot(),
+		"clockSkew": metric.ClockSkew,
+		"dcpRaw":    metric.DcpRawLatency,
+	})
+}
+
+func (s *api) capabilityReport(c *fiber.Ctx) error {
+	if s.capabilities == nil {
+		return c.Status(fiber.StatusNotFound).SendString("capability report is not available")
+	}
+
+	return c.JSON(s.capabilities)
+}
+
+func (s *api) watermarks(c *fiber.Ctx) error {
+	return c.JSON(s.stream.GetWatermarks())
+}
+
 func (s *api) offset(c *fiber.Ctx) error {
 	if !s.stream.IsOpen() {
 		return c.SendString("offset could not get, stream is not open")
@@ -82,11 +151,114 @@ func (s *api) offset(c *fiber.Ctx) error {
 	return c.JSON(offsets)
 }
 
+func (s *api) vBucketStats(c *fiber.Ctx) error {
+	if !s.stream.IsOpen() {
+		return c.SendString("vBucket stats could not get, stream is not open")
+	}
+
+	stats, err := s.stream.GetVBucketStats()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(stats)
+}
+
 func (s *api) rebalance(c *fiber.Ctx) error {
 	if !s.stream.IsOpen() {
 		return c.SendString("rebalance skipped, stream is not open")
 	}
+	s.audit(c, "rebalance", nil)
 	s.stream.Rebalance()
+	if s.stream.IsInMaintenanceMode() {
+		return c.SendString("rebalance deferred, maintenance mode is enabled")
+	}
+	return c.SendString("OK")
+}
+
+func (s *api) verifyCheckpoints(c *fiber.Ctx) error {
+	issues, err := s.stream.VerifyCheckpoints()
+	if err != nil {
+		return err
+	}
+
+	if issues == nil {
+		issues = []stream.CheckpointIssue{}
+	}
+
+	return c.JSON(issues)
+}
+
+func (s *api) failoverLogs(c *fiber.Ctx) error {
+	var vbIDs []uint16
+
+	if param := c.Query("vbIds"); param != "" {
+		for _, value := range strings.Split(param, ",") {
+			vbID, err := strconv.ParseUint(strings.TrimSpace(value), 10, 16)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("invalid vbIds: " + param)
+			}
+
+			vbIDs = append(vbIDs, uint16(vbID))
+		}
+	}
+
+	logs, err := s.stream.GetFailoverLogs(vbIDs)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(logs)
+}
+
+func (s *api) checkpointStatuses(c *fiber.Ctx) error {
+	statuses, err := s.stream.GetCheckpointStatuses()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(statuses)
+}
+
+func (s *api) pause(c *fiber.Ctx) error {
+	s.audit(c, "pause", nil)
+	s.stream.Pause()
+	return c.SendString("OK")
+}
+
+func (s *api) resume(c *fiber.Ctx) error {
+	s.audit(c, "resume", nil)
+	s.stream.Resume()
+	return c.SendString("OK")
+}
+
+func (s *api) maintenance(c *fiber.Ctx) error {
+	var req models.SetMaintenanceModeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid request body")
+	}
+
+	s.audit(c, "maintenance", map[string]interface{}{
+		"enabled": req.Enabled,
+	})
+
+	s.stream.SetMaintenanceMode(req.Enabled)
+
+	return c.SendString("OK")
+}
+
+func (s *api) hold(c *fiber.Ctx) error {
+	var req models.SetHoldModeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid request body")
+	}
+
+	s.audit(c, "hold", map[string]interface{}{
+		"enabled": req.Enabled,
+	})
+
+	s.stream.SetHoldMode(req.Enabled)
+
 	return c.SendString("OK")
 }
 
@@ -101,6 +273,11 @@ func (s *api) info(c *fiber.Ctx) error {
 		TotalMembers: req.TotalMembers,
 	}
 
+	s.audit(c, "membership.info", map[string]interface{}{
+		"memberNumber": req.MemberNumber,
+		"totalMembers": req.TotalMembers,
+	})
+
 	if newInfo.IsChanged(s.membershipInfo) {
 		s.membershipInfo = newInfo
 
@@ -112,6 +289,47 @@ func (s *api) info(c *fiber.Ctx) error {
 	return c.SendString("OK")
 }
 
+func (s *api) resetOffsets(c *fiber.Ctx) error {
+	var req models.ResetOffsetsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid request body")
+	}
+
+	s.audit(c, "offset.reset", map[string]interface{}{
+		"type":      req.Type,
+		"vbIds":     req.VbIDs,
+		"seqNos":    req.SeqNos,
+		"timestamp": req.Timestamp,
+	})
+
+	err := s.stream.ResetOffsets(stream.ResetPolicy{Type: req.Type, SeqNos: req.SeqNos, Timestamp: req.Timestamp}, req.VbIDs)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	return c.SendString("OK")
+}
+
+func (s *api) totalMembers(c *fiber.Ctx) error {
+	var req models.SetTotalMembersRequest
+	if err := c.BodyParser(&req); err != nil || req.TotalMembers < 1 {
+		return c.Status(fiber.StatusBadRequest).SendString("invalid request body")
+	}
+
+	s.audit(c, "membership.totalMembers", map[string]interface{}{
+		"totalMembers": req.TotalMembers,
+	})
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	if err := couchbase.SetTotalMembers(ctx, s.client, s.config, req.TotalMembers); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.SendString("OK")
+}
+
 func (s *api) followers(c *fiber.Ctx) error {
 	if s.serviceDiscovery == nil {
 		return c.SendString("service discovery is not enabled")
@@ -120,12 +338,56 @@ func (s *api) followers(c *fiber.Ctx) error {
 	return c.JSON(s.serviceDiscovery.GetAll())
 }
 
+func (s *api) groups(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	groups, err := s.groupInspector.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(groups)
+}
+
+func (s *api) group(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	group, err := s.groupInspector.Get(ctx, c.Params("name"))
+	if err != nil {
+		if errors.Is(err, couchbase.ErrGroupNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString(err.Error())
+		}
+
+		return err
+	}
+
+	return c.JSON(group)
+}
+
+func (s *api) schemas(c *fiber.Ctx) error {
+	return c.JSON(schema.All())
+}
+
+func (s *api) schema(c *fiber.Ctx) error {
+	found, ok := schema.Get(c.Params("name"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).SendString("schema not found: " + c.Params("name"))
+	}
+
+	c.Set(fiber.HeaderContentType, "application/schema+json")
+
+	return c.Send(found.Definition)
+}
+
 func NewAPI(config *dcp.Dcp,
 	client couchbase.Client,
 	stream stream.Stream,
 	serviceDiscovery servicediscovery.ServiceDiscovery,
 	collectors []prometheus.Collector,
 	bus EventBus.Bus,
+	capabilities *models.CapabilityReport,
 ) API {
 	app := fiber.New(fiber.Config{DisableStartupMessage: true})
 
@@ -137,6 +399,15 @@ func NewAPI(config *dcp.Dcp,
 		serviceDiscovery: serviceDiscovery,
 		registerer:       metric.WrapWithRegisterer(prometheus.DefaultRegisterer),
 		bus:              bus,
+		capabilities:     capabilities,
+	}
+
+	if config.IsAuditPersistenceEnabled() {
+		api.auditStore = couchbase.NewCBAuditStore(client, config)
+	}
+
+	if config.IsCouchbaseMetadata() {
+		api.groupInspector = couchbase.NewGroupInspector(client, config)
 	}
 
 	err := api.registerer.RegisterAll(collectors)
@@ -154,10 +425,30 @@ func NewAPI(config *dcp.Dcp,
 
 	if !config.HealthCheck.Disabled {
 		app.Get("/status", api.status)
+		app.Get("/status/latency", api.latency)
+		app.Get("/status/capabilities", api.capabilityReport)
+	}
+
+	if api.groupInspector != nil {
+		app.Get("/groups", api.groups)
+		app.Get("/groups/:name", api.group)
+		app.Put("/membership/totalMembers", api.totalMembers)
 	}
 
+	app.Get("/watermarks", api.watermarks)
+	app.Get("/stats/vbuckets", api.vBucketStats)
 	app.Get("/rebalance", api.rebalance)
+	app.Get("/offset/verify", api.verifyCheckpoints)
+	app.Get("/offset/checkpoints", api.checkpointStatuses)
+	app.Get("/offset/failoverLogs", api.failoverLogs)
+	app.Put("/offset/reset", api.resetOffsets)
+	app.Get("/pause", api.pause)
+	app.Get("/resume", api.resume)
 	app.Put("/membership/info", api.info)
+	app.Put("/maintenance", api.maintenance)
+	app.Put("/hold", api.hold)
+	app.Get("/schemas", api.schemas)
+	app.Get("/schemas/:name", api.schema)
 
 	return api
 }
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/valyala/fasthttp"
+
+	dcp "github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/stream"
+)
+
+type fakeClient struct {
+	couchbase.Client
+	pingErr error
+}
+
+func (c *fakeClient) Ping() (*models.PingResult, error) {
+	if c.pingErr != nil {
+		return nil, c.pingErr
+	}
+
+	return &models.PingResult{}, nil
+}
+
+type fakeStream struct {
+	stream.Stream
+	paused atomic.Int32
+}
+
+func (s *fakeStream) GetWatermarks() *stream.Watermarks {
+	return &stream.Watermarks{Global: time.Unix(10, 0).UTC(), VBuckets: map[uint16]time.Time{}}
+}
+
+func (s *fakeStream) Pause() {
+	s.paused.Add(1)
+}
+
+var (
+	testAPI     *api
+	testClient  *fakeClient
+	testStream  *fakeStream
+	testAPIOnce sync.Once
+)
+
+// newTestAPI creates the api once, its metric middleware is registered to the default registerer.
+func newTestAPI() (*api, *fakeClient, *fakeStream) {
+	testAPIOnce.Do(func() {
+		logger.InitDefaultLogger("error")
+
+		config := &dcp.Dcp{}
+		config.Metric.Path = "/metrics"
+
+		testClient = &fakeClient{}
+		testStream = &fakeStream{}
+		testAPI = NewAPI(config, testClient, testStream, nil, nil, EventBus.New(), nil).(*api)
+	})
+
+	return testAPI, testClient, testStream
+}
+
+func TestAPI_Handler(t *testing.T) {
+	a, _, s := newTestAPI()
+	paused := s.paused.Load()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "status", path: "/status", expected: "OK"},
+		{name: "watermarks", path: "/watermarks", expected: `{"global":"1970-01-01T00:00:10Z","vBuckets":{}}`},
+		{name: "pause", path: "/pause", expected: "OK"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			a.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if recorder.Code != http.StatusOK || recorder.Body.String() != tt.expected {
+				t.Fatalf("expected 200 %s, got %d %s", tt.expected, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+
+	if s.paused.Load() != paused+1 {
+		t.Fatalf("pause must be passed to the stream")
+	}
+}
+
+func TestAPI_FastHTTPHandler(t *testing.T) {
+	a, _, _ := newTestAPI()
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI("/status")
+	ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+
+	a.FastHTTPHandler()(&ctx)
+
+	if ctx.Response.StatusCode() != fasthttp.StatusOK || string(ctx.Response.Body()) != "OK" {
+		t.Fatalf("expected 200 OK, got %d %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+}
+
+func TestAPI_HealthHandler(t *testing.T) {
+	a, client, _ := newTestAPI()
+
+	recorder := httptest.NewRecorder()
+	a.HealthHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "OK" {
+		t.Fatalf("expected 200 OK, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	client.pingErr = errors.New("ping")
+	defer func() { client.pingErr = nil }()
+
+	recorder = httptest.NewRecorder()
+	a.HealthHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("failed ping must be unhealthy, got %d", recorder.Code)
+	}
+}
+
+func TestAPI_MetricsHandler(t *testing.T) {
+	a, _, _ := newTestAPI()
+
+	recorder := httptest.NewRecorder()
+	a.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	body, _ := io.ReadAll(recorder.Body)
+	if recorder.Code != http.StatusOK || !strings.Contains(string(body), "go_goroutines") {
+		t.Fatalf("expected prometheus metrics, got %d", recorder.Code)
+	}
+}
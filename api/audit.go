@@ -0,0 +1,41 @@
+package api
+
+import (
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// AuditCallerHeader names the caller of an audited operation. It is self-asserted by the client since the api has no
+// authentication, the remote address is recorded next to it.
+const AuditCallerHeader = "X-Audit-Caller"
+
+func (s *api) audit(c *fiber.Ctx, operation string, parameters map[string]interface{}) {
+	event := &models.AuditEvent{
+		Timestamp:            time.Now(),
+		Operation:            operation,
+		ClientSuppliedCaller: c.Get(AuditCallerHeader),
+		RemoteAddr:           c.Context().RemoteAddr().String(),
+		Parameters:           parameters,
+	}
+
+	payload, err := sonic.Marshal(event)
+	if err != nil {
+		logger.Log.Error("error while marshal audit event, err: %v", err)
+		return
+	}
+
+	logger.Log.Info("audit: %s", string(payload))
+
+	if s.auditStore == nil {
+		return
+	}
+
+	if err := s.auditStore.Save(event); err != nil {
+		logger.Log.Error("error while save audit event, operation: %s, err: %v", operation, err)
+	}
+}
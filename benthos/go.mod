@@ -0,0 +1,10 @@
+module github.com/Trendyol/go-dcp/benthos
+
+go 1.21
+
+replace github.com/Trendyol/go-dcp => ../
+
+require (
+	github.com/Trendyol/go-dcp v0.0.0
+	github.com/redpanda-data/benthos/v4 v4.30.0
+)
@@ -0,0 +1,103 @@
+// Package benthos registers the go_dcp input of Benthos and Redpanda Connect. It is imported for its side effect:
+//
+//	import _ "github.com/Trendyol/go-dcp/benthos"
+package benthos
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/Trendyol/go-dcp/stable"
+)
+
+const (
+	configField     = "config"
+	bufferSizeField = "buffer_size"
+)
+
+func init() {
+	spec := service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Summary("Streams the mutations, deletions and expirations of a couchbase bucket with go-dcp.").
+		Description("Offsets are checkpointed by go-dcp, an event is checkpointed once it and every event of its " +
+			"vBucket before it are acked. Members of a go-dcp group share the vBuckets of the bucket.").
+		Field(service.NewStringField(configField).
+			Description("Path of the go-dcp configuration file.")).
+		Field(service.NewIntField(bufferSizeField).
+			Description("Count of the events streamed ahead of the pipeline.").
+			Default(1024))
+
+	err := service.RegisterInput("go_dcp", spec, func(conf *service.ParsedConfig, _ *service.Resources) (service.Input, error) {
+		return newInput(conf)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type input struct {
+	reader stable.Reader
+}
+
+func newInput(conf *service.ParsedConfig) (service.Input, error) {
+	configPath, err := conf.FieldString(configField)
+	if err != nil {
+		return nil, err
+	}
+
+	bufferSize, err := conf.FieldInt(bufferSizeField)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := stable.NewReader(configPath, bufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// nacked events are delivered again, so a vBucket never moves past a failed event
+	return service.AutoRetryNacks(&input{reader: reader}), nil
+}
+
+func (i *input) Connect(context.Context) error {
+	i.reader.Start()
+	return nil
+}
+
+func (i *input) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	message, err := i.reader.Read(ctx)
+	if err != nil {
+		if errors.Is(err, stable.ErrReaderClosed) {
+			return nil, nil, service.ErrEndOfInput
+		}
+		return nil, nil, err
+	}
+
+	event := message.Event
+
+	msg := service.NewMessage(event.Value)
+	msg.MetaSet("dcp_key", string(event.Key))
+	msg.MetaSet("dcp_type", event.Type.String())
+	msg.MetaSet("dcp_collection", event.CollectionName)
+	msg.MetaSet("dcp_vb_id", strconv.Itoa(int(event.VbID)))
+	msg.MetaSet("dcp_seq_no", strconv.FormatUint(event.Offset.SeqNo, 10))
+	msg.MetaSet("dcp_cas", strconv.FormatUint(event.Cas, 10))
+	msg.MetaSet("dcp_event_time", event.EventTime.Format(time.RFC3339Nano))
+
+	return msg, func(_ context.Context, err error) error {
+		if err == nil {
+			message.Ack()
+		}
+		return nil
+	}, nil
+}
+
+func (i *input) Close(context.Context) error {
+	i.reader.Close()
+	return nil
+}
@@ -0,0 +1,185 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock which only moves with Advance. Sleeps, timers and tickers fire in the order of their deadlines,
+// so the timer based logic can be driven step by step.
+type Fake struct {
+	now     time.Time
+	cond    *sync.Cond
+	waiters []*fakeWaiter
+	lock    sync.Mutex
+}
+
+type fakeWaiter struct {
+	clock    *Fake
+	deadline time.Time
+	fire     func(now time.Time)
+	period   time.Duration
+}
+
+func (f *Fake) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+
+	f.add(&fakeWaiter{clock: f, fire: func(now time.Time) { ch <- now }}, d)
+
+	return ch
+}
+
+// AfterFunc calls the function in its own goroutine like time.AfterFunc.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.add(&fakeWaiter{clock: f, fire: func(time.Time) { go fn() }}, d)
+}
+
+// NewTicker drops the ticks which are not received like time.Ticker.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	ch := make(chan time.Time, 1)
+
+	w := &fakeWaiter{clock: f, period: d, fire: func(now time.Time) {
+		select {
+		case ch <- now:
+		default:
+		}
+	}}
+
+	f.add(w, d)
+
+	return &fakeTicker{fakeWaiter: w, ch: ch}
+}
+
+// Advance moves the time forward and fires everything with a deadline up to the new time.
+func (f *Fake) Advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	end := f.now.Add(d)
+
+	for len(f.waiters) > 0 && !f.waiters[0].deadline.After(end) {
+		w := f.waiters[0]
+		f.waiters = f.waiters[1:]
+		f.now = w.deadline
+
+		w.fire(f.now)
+
+		if w.period > 0 {
+			w.deadline = f.now.Add(w.period)
+			f.insert(w)
+		}
+	}
+
+	f.now = end
+}
+
+// BlockUntil waits until n sleeps, timers or tickers are pending. It lets a test advance the clock
+// only after the code under test has started waiting.
+func (f *Fake) BlockUntil(n int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+func (f *Fake) add(w *fakeWaiter, d time.Duration) *fakeWaiter {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	w.deadline = f.now.Add(d)
+	f.insert(w)
+
+	return w
+}
+
+func (f *Fake) insert(w *fakeWaiter) {
+	i := sort.Search(len(f.waiters), func(i int) bool {
+		return f.waiters[i].deadline.After(w.deadline)
+	})
+
+	f.waiters = append(f.waiters, nil)
+	copy(f.waiters[i+1:], f.waiters[i:])
+	f.waiters[i] = w
+
+	f.cond.Broadcast()
+}
+
+func (f *Fake) remove(w *fakeWaiter) bool {
+	for i, waiter := range f.waiters {
+		if waiter == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.lock.Lock()
+	defer w.clock.lock.Unlock()
+
+	return w.clock.remove(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.lock.Lock()
+	defer w.clock.lock.Unlock()
+
+	active := w.clock.remove(w)
+
+	w.deadline = w.clock.now.Add(d)
+	if w.period > 0 {
+		w.period = d
+	}
+	w.clock.insert(w)
+
+	return active
+}
+
+type fakeTicker struct {
+	*fakeWaiter
+	ch chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.fakeWaiter.Stop()
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.fakeWaiter.Reset(d)
+}
+
+// NewFake returns a clock stopped at the given time.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.lock)
+
+	return f
+}
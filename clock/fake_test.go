@@ -0,0 +1,89 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	after := c.After(time.Second)
+	ticker := c.NewTicker(400 * time.Millisecond)
+	fired := make(chan struct{})
+	c.AfterFunc(2*time.Second, func() { close(fired) })
+
+	c.Advance(999 * time.Millisecond)
+
+	select {
+	case <-after:
+		t.Fatalf("After fired before its deadline")
+	default:
+	}
+
+	if tick := <-ticker.C(); !tick.Equal(start.Add(400 * time.Millisecond)) {
+		t.Fatalf("ticker should keep the first tick and drop the rest, got %v", tick.Sub(start))
+	}
+
+	c.Advance(time.Millisecond)
+
+	if now := <-after; !now.Equal(start.Add(time.Second)) {
+		t.Fatalf("After fired at %v", now.Sub(start))
+	}
+
+	ticker.Stop()
+	c.Advance(time.Second)
+
+	<-fired
+
+	select {
+	case <-ticker.C():
+		t.Fatalf("stopped ticker ticked")
+	default:
+	}
+
+	if c.Since(start) != 2*time.Second {
+		t.Fatalf("clock is at %v", c.Since(start))
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	c := NewFake(time.Time{})
+	done := make(chan struct{})
+
+	go func() {
+		c.Sleep(time.Minute)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+
+	<-done
+}
+
+func TestFakeTimerReset(t *testing.T) {
+	c := NewFake(time.Time{})
+	fired := make(chan struct{}, 2)
+
+	timer := c.AfterFunc(time.Second, func() { fired <- struct{}{} })
+
+	c.Advance(500 * time.Millisecond)
+
+	if !timer.Reset(time.Second) {
+		t.Fatalf("Reset should report the active timer")
+	}
+
+	c.Advance(900 * time.Millisecond)
+
+	if !timer.Stop() {
+		t.Fatalf("Stop should report the active timer")
+	}
+
+	c.Advance(time.Hour)
+
+	if len(fired) != 0 {
+		t.Fatalf("stopped timer fired")
+	}
+}
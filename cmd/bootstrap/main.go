@@ -0,0 +1,57 @@
+// Command bootstrap creates the resources the metadata of the config needs and checks the permissions of the
+// connector, e.g. the scope and the collection of couchbase metadata. It is idempotent, so provisioners can run it
+// before every deployment. It exits with 1 when a step fails.
+//
+//	bootstrap -config config.yml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Trendyol/go-dcp"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+
+	// The metadata types of the connector config are registered by their packages.
+	_ "github.com/Trendyol/go-dcp/metadata/consul"
+	_ "github.com/Trendyol/go-dcp/metadata/etcd"
+	_ "github.com/Trendyol/go-dcp/metadata/mongodb"
+	_ "github.com/Trendyol/go-dcp/metadata/redis"
+	_ "github.com/Trendyol/go-dcp/metadata/s3"
+	_ "github.com/Trendyol/go-dcp/metadata/sql"
+	_ "github.com/Trendyol/go-dcp/metadata/zookeeper"
+)
+
+func main() {
+	configPath := flag.String("config", "config.yml", "path of the connector config")
+	flag.Parse()
+
+	if err := run(*configPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string) error {
+	logger.InitDefaultLogger("warn")
+
+	connector, err := dcp.NewDcp(configPath, func(_ *models.ListenerContext) {})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		connector.GetClient().DcpClose()
+		connector.GetClient().Close()
+	}()
+
+	steps, err := connector.Bootstrap()
+
+	for _, step := range steps {
+		fmt.Printf("%s: %s\n", step.Name, step.Status)
+	}
+
+	return err
+}
@@ -0,0 +1,85 @@
+// Command checkpoint exports the checkpoints of a group to a snapshot file, and imports a snapshot into the metadata
+// of the config. The group must be stopped while a snapshot is imported, running members overwrite the imported
+// checkpoints of their vBuckets with their next save.
+//
+//	checkpoint -config config.yml [-format json|cbor] [-file snapshot.json] export
+//	checkpoint -config config.yml [-file snapshot.json] import
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Trendyol/go-dcp"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+
+	// The metadata types of the connector config are registered by their packages.
+	_ "github.com/Trendyol/go-dcp/metadata/consul"
+	_ "github.com/Trendyol/go-dcp/metadata/etcd"
+	_ "github.com/Trendyol/go-dcp/metadata/mongodb"
+	_ "github.com/Trendyol/go-dcp/metadata/redis"
+	_ "github.com/Trendyol/go-dcp/metadata/s3"
+	_ "github.com/Trendyol/go-dcp/metadata/sql"
+	_ "github.com/Trendyol/go-dcp/metadata/zookeeper"
+)
+
+func main() {
+	configPath := flag.String("config", "config.yml", "path of the connector config")
+	format := flag.String("format", metadata.SnapshotFormatJSON, "snapshot format of export, json or cbor")
+	fileName := flag.String("file", "", "snapshot file, stdout for export and stdin for import when it is not set")
+	flag.Parse()
+
+	if flag.NArg() != 1 || (flag.Arg(0) != "export" && flag.Arg(0) != "import") {
+		fmt.Fprintln(os.Stderr, "usage: checkpoint -config config.yml [-format json|cbor] [-file snapshot] export|import")
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *configPath, *format, *fileName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(command string, configPath string, format string, fileName string) error {
+	logger.InitDefaultLogger("warn")
+
+	connector, err := dcp.NewDcp(configPath, func(_ *models.ListenerContext) {})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		connector.GetClient().DcpClose()
+		connector.GetClient().Close()
+	}()
+
+	if command == "export" {
+		w := io.Writer(os.Stdout)
+		if fileName != "" {
+			file, err := os.Create(fileName)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			w = file
+		}
+
+		return connector.ExportCheckpoints(w, format)
+	}
+
+	r := io.Reader(os.Stdin)
+	if fileName != "" {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	return connector.ImportCheckpoints(r)
+}
@@ -0,0 +1,108 @@
+// Command simulate compares the vBucket assignment strategies on the historical load of a group. The load is the
+// seqNos of a checkpoint snapshot exported by the checkpoint command, or a json file of the loads by vBucket id.
+//
+//	simulate -members 3 [-weights 1,2,1] [-strategies sticky,range] [-vbuckets 1024] -snapshot snapshot.json
+//	simulate -members 3 -load load.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/simulation"
+)
+
+func main() {
+	members := flag.Int("members", 1, "count of the members")
+	weights := flag.String("weights", "", "comma separated weights of the members, equal when it is not set")
+	strategies := flag.String("strategies", "", "comma separated assignment strategies, all when it is not set")
+	vBuckets := flag.Int("vbuckets", simulation.DefaultVBuckets, "count of the vBuckets")
+	snapshotPath := flag.String("snapshot", "", "checkpoint snapshot, seqNos are the loads of the vBuckets")
+	loadPath := flag.String("load", "", "json file of the loads by vBucket id")
+	flag.Parse()
+
+	if err := run(*members, *weights, *strategies, *vBuckets, *snapshotPath, *loadPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(members int, weights string, strategies string, vBuckets int, snapshotPath string, loadPath string) error {
+	scenario := simulation.Scenario{Members: members, VBuckets: vBuckets}
+
+	var err error
+
+	scenario.Load, err = readLoad(snapshotPath, loadPath)
+	if err != nil {
+		return err
+	}
+
+	if weights != "" {
+		for _, weight := range strings.Split(weights, ",") {
+			value, err := strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil {
+				return fmt.Errorf("invalid weight: %s", weight)
+			}
+			scenario.Weights = append(scenario.Weights, value)
+		}
+	}
+
+	var names []string
+	if strategies != "" {
+		names = strings.Split(strategies, ",")
+	}
+
+	results, err := simulation.Simulate(scenario, names...)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tLOAD VARIANCE\tMAX LOAD RATIO\tMOVED ON JOIN\tMOVED ON LEAVE")
+
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%.2f\t%.3f\t%d\t%d\n",
+			result.Strategy, result.LoadVariance, result.MaxLoadRatio, result.MovedOnJoin, result.MovedOnLeave)
+	}
+
+	return w.Flush()
+}
+
+func readLoad(snapshotPath string, loadPath string) (map[uint16]float64, error) {
+	switch {
+	case snapshotPath != "":
+		file, err := os.Open(snapshotPath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		snapshot, err := metadata.ReadSnapshot(file)
+		if err != nil {
+			return nil, err
+		}
+
+		return simulation.LoadOf(snapshot), nil
+	case loadPath != "":
+		data, err := os.ReadFile(loadPath)
+		if err != nil {
+			return nil, err
+		}
+
+		var load map[uint16]float64
+		if err = sonic.Unmarshal(data, &load); err != nil {
+			return nil, fmt.Errorf("cannot read load: %w", err)
+		}
+
+		return load, nil
+	default:
+		return nil, fmt.Errorf("usage: simulate -members n [-weights w,...] [-strategies s,...] -snapshot file|-load file")
+	}
+}
@@ -2,7 +2,12 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"regexp"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,9 +21,48 @@ const (
 	DefaultScopeName                                = "_default"
 	DefaultCollectionName                           = "_default"
 	FileMetadataFileNameConfig                      = "fileName"
+	FileMetadataGenerationsConfig                   = "generations"
 	MetadataTypeCouchbase                           = "couchbase"
 	MetadataTypeFile                                = "file"
+	MetadataTypeRedis                               = "redis"
+	MetadataTypeEtcd                                = "etcd"
+	MetadataTypeZookeeper                           = "zookeeper"
+	MetadataTypeSQL                                 = "sql"
+	MetadataTypeS3                                  = "s3"
+	MetadataTypeMongoDB                             = "mongodb"
+	MetadataTypeConsul                              = "consul"
+	MetadataTypeKafka                               = "kafka"
+	DeadLetterTypeCouchbase                         = "couchbase"
+	DeadLetterTypeFile                              = "file"
+	DecodeErrorPolicySkip                           = "skip"
+	DecodeErrorPolicyDeadLetter                     = "deadLetter"
+	DecodeErrorPolicyHalt                           = "halt"
+	DownstreamHealthTypeHTTP                        = "http"
+	DownstreamHealthTypeTCP                         = "tcp"
+	FlushResetEarliest                              = "earliest"
+	FlushResetLatest                                = "latest"
+	VBucketCountChangeFail                          = "fail"
+	VBucketCountChangeReset                         = "reset"
+	BinaryPolicyRaw                                 = "raw"
+	BinaryPolicyBase64                              = "base64"
+	BinaryPolicySkip                                = "skip"
+	BinaryPolicyDeadLetter                          = "deadLetter"
+	ValidationPolicyFlag                            = "flag"
+	ValidationPolicyDeadLetter                      = "deadLetter"
+	ValidationPolicyDrop                            = "drop"
+	StateTypeMemory                                 = "memory"
+	StateTypeBbolt                                  = "bbolt"
+	StateTypeCouchbase                              = "couchbase"
 	MembershipTypeCouchbase                         = "couchbase"
+	MembershipTypeKubernetesLease                   = "kubernetesLease"
+	MembershipTypeConsul                            = "consul"
+	MembershipTypeEtcd                              = "etcd"
+	MembershipTypeRedis                             = "redis"
+	MembershipAssignmentSticky                      = "sticky"
+	MembershipAssignmentRange                       = "range"
+	MembershipAssignmentRoundRobin                  = "roundRobin"
+	MembershipAssignmentConsistentHash              = "consistentHash"
+	MembershipWeightCPU                             = "cpu"
 	CouchbaseMetadataHostsConfig                    = "hosts"
 	CouchbaseMetadataUsernameConfig                 = "username"
 	CouchbaseMetadataPasswordConfig                 = "password"
@@ -30,19 +74,54 @@ const (
 	CouchbaseMetadataConnectionTimeoutConfig        = "connectionTimeout"
 	CouchbaseMetadataSecureConnectionConfig         = "secureConnection"
 	CouchbaseMetadataRootCAPathConfig               = "rootCAPath"
+	CouchbaseMetadataCertPathConfig                 = "certPath"
+	CouchbaseMetadataKeyPathConfig                  = "keyPath"
 	CheckpointTypeAuto                              = "auto"
+	CheckpointWriterLeader                          = "leader"
 	CouchbaseMembershipExpirySecondsConfig          = "expirySeconds"
 	CouchbaseMembershipHeartbeatIntervalConfig      = "heartbeatInterval"
 	CouchbaseMembershipHeartbeatToleranceConfig     = "heartbeatToleranceDuration"
 	CouchbaseMembershipMonitorIntervalConfig        = "monitorInterval"
+	CouchbaseMembershipHeartbeatMissThresholdConfig = "heartbeatMissThreshold"
 	CouchbaseMembershipTimeoutConfig                = "timeout"
 	KubernetesLeaderElectorLeaseLockNameConfig      = "leaseLockName"
 	KubernetesLeaderElectorLeaseLockNamespaceConfig = "leaseLockNamespace"
 	KubernetesLeaderElectorLeaseDurationConfig      = "leaseDuration"
 	KubernetesLeaderElectorRenewDeadlineConfig      = "renewDeadline"
 	KubernetesLeaderElectorRetryPeriodConfig        = "retryPeriod"
+	KubernetesLeaseMembershipNamespaceConfig        = "leaseNamespace"
+	KubernetesLeaseMembershipLeaseDurationConfig    = "leaseDuration"
+	KubernetesLeaseMembershipRenewIntervalConfig    = "renewInterval"
+	KubernetesLeaseMembershipTimeoutConfig          = "timeout"
+	ConsulMembershipAddressConfig                   = "address"
+	ConsulMembershipTokenConfig                     = "token"
+	ConsulMembershipDatacenterConfig                = "datacenter"
+	ConsulMembershipKeyPrefixConfig                 = "keyPrefix"
+	ConsulMembershipSecureConnectionConfig          = "secureConnection"
+	ConsulMembershipSessionTTLConfig                = "sessionTTL"
+	ConsulMembershipTimeoutConfig                   = "timeout"
+	EtcdMembershipEndpointsConfig                   = "endpoints"
+	EtcdMembershipUsernameConfig                    = "username"
+	EtcdMembershipPasswordConfig                    = "password"
+	EtcdMembershipKeyPrefixConfig                   = "keyPrefix"
+	EtcdMembershipSecureConnectionConfig            = "secureConnection"
+	EtcdMembershipTTLConfig                         = "ttl"
+	EtcdMembershipDialTimeoutConfig                 = "dialTimeout"
+	EtcdMembershipTimeoutConfig                     = "timeout"
+	RedisMembershipAddressConfig                    = "address"
+	RedisMembershipUsernameConfig                   = "username"
+	RedisMembershipPasswordConfig                   = "password"
+	RedisMembershipDBConfig                         = "db"
+	RedisMembershipKeyPrefixConfig                  = "keyPrefix"
+	RedisMembershipSecureConnectionConfig           = "secureConnection"
+	RedisMembershipHeartbeatIntervalConfig          = "heartbeatInterval"
+	RedisMembershipHeartbeatToleranceConfig         = "heartbeatToleranceDuration"
+	RedisMembershipTimeoutConfig                    = "timeout"
 )
 
+// DcpNoopInterval is the NOOP interval which gocbcore negotiates with the server on each dcp connection.
+const DcpNoopInterval = 180 * time.Second
+
 type DcpMode string
 
 const (
@@ -50,12 +129,51 @@ const (
 	DcpModeFinite   DcpMode = "finite"
 )
 
+const (
+	ReconnectPolicyCrash = "crash"
+	ReconnectPolicyHalt  = "halt"
+	ReconnectPolicyAlert = "alert"
+)
+
 type DCPGroupMembership struct {
-	Config         map[string]string `yaml:"config"`
-	Type           string            `yaml:"type"`
-	MemberNumber   int               `yaml:"memberNumber"`
-	TotalMembers   int               `yaml:"totalMembers"`
-	RebalanceDelay time.Duration     `yaml:"rebalanceDelay"`
+	Config         map[string]string             `yaml:"config"`
+	Type           string                        `yaml:"type"`
+	Assignment     string                        `yaml:"assignment"`
+	Weight         string                        `yaml:"weight"`
+	SplitBrain     DCPGroupMembershipSplitBrain  `yaml:"splitBrain"`
+	Reconfigure    DCPGroupMembershipReconfigure `yaml:"reconfigure"`
+	Barrier        DCPGroupMembershipBarrier     `yaml:"barrier"`
+	Handoff        DCPGroupMembershipHandoff     `yaml:"handoff"`
+	MemberNumber   int                           `yaml:"memberNumber"`
+	TotalMembers   int                           `yaml:"totalMembers"`
+	ActiveMembers  int                           `yaml:"activeMembers"`
+	RebalanceDelay time.Duration                 `yaml:"rebalanceDelay"`
+	Standby        bool                          `yaml:"standby"`
+}
+
+// DCPGroupMembershipBarrier holds the first assignment of a cold start till Fraction of Members are alive
+// or Timeout passes. It is disabled when Members is not set.
+type DCPGroupMembershipBarrier struct {
+	Members  int           `yaml:"members"`
+	Fraction float64       `yaml:"fraction"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// DCPGroupMembershipHandoff makes the next owner of a vBucket wait till its former owner releases it, or till
+// Timeout passes.
+type DCPGroupMembershipHandoff struct {
+	Timeout time.Duration `yaml:"timeout"`
+	Enabled bool          `yaml:"enabled"`
+}
+
+type DCPGroupMembershipReconfigure struct {
+	Interval time.Duration `yaml:"interval"`
+	Enabled  bool          `yaml:"enabled"`
+}
+
+type DCPGroupMembershipSplitBrain struct {
+	Interval time.Duration `yaml:"interval"`
+	Enabled  bool          `yaml:"enabled"`
 }
 
 type DCPGroup struct {
@@ -64,31 +182,133 @@ type DCPGroup struct {
 }
 
 type DCPListener struct {
-	SkipUntil *time.Time `yaml:"skipUntil"`
+	SkipUntil *time.Time           `yaml:"skipUntil"`
+	Scheduler DCPListenerScheduler `yaml:"scheduler"`
+	Batch     DCPListenerBatch     `yaml:"batch"`
+}
+
+type DCPListenerBatch struct {
+	Size               int           `yaml:"size"`
+	Window             time.Duration `yaml:"window"`
+	FlushOnSnapshotEnd bool          `yaml:"flushOnSnapshotEnd"`
+}
+
+type DCPListenerScheduler struct {
+	Enabled      bool          `yaml:"enabled"`
+	Workers      int           `yaml:"workers"`
+	MaxInFlight  int           `yaml:"maxInFlight"`
+	QueueSize    int           `yaml:"queueSize"`
+	StarvedAfter time.Duration `yaml:"starvedAfter"`
+}
+
+type DCPReconnect struct {
+	Policy      string        `yaml:"policy"`
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Window      time.Duration `yaml:"window"`
+}
+
+type DCPFlush struct {
+	Reset    string `yaml:"reset"`
+	Disabled bool   `yaml:"disabled"`
+}
+
+type DCPBinary struct {
+	Policy string `yaml:"policy"`
+}
+
+// DCPCompression delivers the values which are snappy compressed on the server without decompressing them when
+// Passthrough is set, the events are flagged as compressed.
+type DCPCompression struct {
+	Passthrough bool `yaml:"passthrough"`
+}
+
+type DCPValidation struct {
+	Policy string `yaml:"policy"`
+}
+
+type DCPFilter struct {
+	KeyRegex    string   `yaml:"keyRegex"`
+	KeyPrefixes []string `yaml:"keyPrefixes"`
 }
 
 type ExternalDcpConfig struct {
 	DisableChangeStreams bool `yaml:"disableChangeStreams"`
+	EnableOSOBackfill    bool `yaml:"enableOSOBackfill"`
 }
 
 type ExternalDcp struct {
 	BufferSize           any               `yaml:"bufferSize"`
 	Mode                 DcpMode           `yaml:"mode"`
 	ConnectionBufferSize any               `yaml:"connectionBufferSize"`
+	ConnectionsPerNode   int               `yaml:"connectionsPerNode"`
 	Listener             DCPListener       `yaml:"listener"`
+	Filter               DCPFilter         `yaml:"filter"`
+	Binary               DCPBinary         `yaml:"binary"`
+	Compression          DCPCompression    `yaml:"compression"`
+	Validation           DCPValidation     `yaml:"validation"`
+	Flush                DCPFlush          `yaml:"flush"`
 	Group                DCPGroup          `yaml:"group"`
+	Reconnect            DCPReconnect      `yaml:"reconnect"`
+	Noop                 DCPNoop           `yaml:"noop"`
 	MaxQueueSize         int               `yaml:"maxQueueSize"`
+	MapInitialSize       int               `yaml:"mapInitialSize"`
 	ConnectionTimeout    time.Duration     `yaml:"connectionTimeout"`
 	Config               ExternalDcpConfig `yaml:"config"`
+	Hold                 bool              `yaml:"hold"`
+}
+
+// DCPNoop is the idle detection of the dcp connections. The server sends a NOOP when a connection is idle for the
+// NOOP interval, a connection which receives nothing for IdleTimeout is stale, e.g. its tcp connection is half-open.
+type DCPNoop struct {
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+}
+
+type DeadLetter struct {
+	Type       string `yaml:"type"`
+	Collection string `yaml:"collection"`
+	FileName   string `yaml:"fileName"`
+}
+
+type Consumer struct {
+	DecodeErrorPolicy string        `yaml:"decodeErrorPolicy"`
+	Retry             ConsumerRetry `yaml:"retry"`
+}
+
+type ConsumerRetry struct {
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+}
+
+type State struct {
+	Type       string `yaml:"type"`
+	Collection string `yaml:"collection"`
+	FileName   string `yaml:"fileName"`
+	Handoff    bool   `yaml:"handoff"`
 }
 
 type API struct {
-	Disabled bool `yaml:"disabled"`
-	Port     int  `yaml:"port"`
+	Audit            APIAudit `yaml:"audit"`
+	Disabled         bool     `yaml:"disabled"`
+	ListenerDisabled bool     `yaml:"listenerDisabled"`
+	Port             int      `yaml:"port"`
+}
+
+type APIAudit struct {
+	Collection string `yaml:"collection"`
 }
 
 type Metric struct {
-	Path string `yaml:"path"`
+	Path       string           `yaml:"path"`
+	LargeFleet MetricLargeFleet `yaml:"largeFleet"`
+}
+
+// MetricLargeFleet collapses the per vBucket series into vBucket range groups,
+// so the series count does not grow with the vBucket count.
+type MetricLargeFleet struct {
+	VBucketGroups int  `yaml:"vBucketGroups"`
+	Enabled       bool `yaml:"enabled"`
 }
 
 type LeaderElection struct {
@@ -103,10 +323,28 @@ type RPC struct {
 }
 
 type Checkpoint struct {
-	Type      string        `yaml:"type"`
-	AutoReset string        `yaml:"autoReset"`
-	Interval  time.Duration `yaml:"interval"`
-	Timeout   time.Duration `yaml:"timeout"`
+	Type               string            `yaml:"type"`
+	AutoReset          string            `yaml:"autoReset"`
+	VBucketCountChange string            `yaml:"vBucketCountChange"`
+	Writer             string            `yaml:"writer"`
+	Retry              CheckpointRetry   `yaml:"retry"`
+	History            CheckpointHistory `yaml:"history"`
+	Interval           time.Duration     `yaml:"interval"`
+	Timeout            time.Duration     `yaml:"timeout"`
+	MaxStaleness       time.Duration     `yaml:"maxStaleness"`
+	CommitWindow       time.Duration     `yaml:"commitWindow"`
+}
+
+// CheckpointHistory keeps the last Size checkpoints of each vBucket, saved at least Interval apart, with its
+// checkpoint. History is disabled when Size is 0.
+type CheckpointHistory struct {
+	Size     int           `yaml:"size"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+type CheckpointRetry struct {
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Backoff     time.Duration `yaml:"backoff"`
 }
 
 type HealthCheck struct {
@@ -121,10 +359,95 @@ type RollbackMitigation struct {
 	ConfigWatchInterval time.Duration `yaml:"configWatchInterval"`
 }
 
+// DownstreamHealth probes the sink of the consumer, the intake is paused after FailureThreshold failed probes in a row
+// and resumed after SuccessThreshold successful probes in a row.
+type DownstreamHealth struct {
+	Type             string        `yaml:"type"`
+	Address          string        `yaml:"address"`
+	Interval         time.Duration `yaml:"interval"`
+	Timeout          time.Duration `yaml:"timeout"`
+	FailureThreshold int           `yaml:"failureThreshold"`
+	SuccessThreshold int           `yaml:"successThreshold"`
+}
+
+type NodeHealth struct {
+	Disabled bool          `yaml:"disabled"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Shutdown bounds the shutdown hooks, Timeout is the time the hooks and the drain of the stream before them can take.
+type Shutdown struct {
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type PurgeMonitor struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	Margin   uint64        `yaml:"margin"`
+}
+
 type Metadata struct {
-	Config   map[string]string `yaml:"config"`
-	Type     string            `yaml:"type"`
-	ReadOnly bool              `yaml:"readOnly"`
+	Config      map[string]string  `yaml:"config"`
+	Type        string             `yaml:"type"`
+	Compression string             `yaml:"compression"`
+	Secondary   MetadataSecondary  `yaml:"secondary"`
+	Fast        MetadataFast       `yaml:"fast"`
+	Encryption  MetadataEncryption `yaml:"encryption"`
+	GC          MetadataGC         `yaml:"gc"`
+	ReadOnly    bool               `yaml:"readOnly"`
+}
+
+// MetadataSecondary is written with the metadata of metadata.type, e.g. while the group moves to another metadata
+// type. It is disabled when Type is not set.
+type MetadataSecondary struct {
+	Config map[string]string `yaml:"config"`
+	Type   string            `yaml:"type"`
+}
+
+// MetadataFast is written with every checkpoint save, the metadata of metadata.type is the durable tier and it is
+// written once in DurableInterval. It is disabled when Type is not set.
+type MetadataFast struct {
+	Config          map[string]string `yaml:"config"`
+	Type            string            `yaml:"type"`
+	DurableInterval time.Duration     `yaml:"durableInterval"`
+}
+
+// MetadataEncryption encrypts the checkpoint and membership documents with the first key. The other keys are only
+// used to decrypt documents written before a key rotation.
+type MetadataEncryption struct {
+	Keys    []MetadataEncryptionKey `yaml:"keys"`
+	Enabled bool                    `yaml:"enabled"`
+}
+
+// MetadataEncryptionKey is a base64 encoded 16, 24 or 32 byte AES key, the id is stored with the documents it
+// encrypts.
+type MetadataEncryptionKey struct {
+	ID  string `yaml:"id"`
+	Key string `yaml:"key"`
+}
+
+type MetadataGC struct {
+	Enabled   bool          `yaml:"enabled"`
+	Interval  time.Duration `yaml:"interval"`
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Pipeline overrides the source collections and the checkpoint group of the base config.
+// Empty fields are taken from the base config, the group name defaults to the pipeline name.
+type Pipeline struct {
+	Name            string   `yaml:"name"`
+	GroupName       string   `yaml:"groupName"`
+	ScopeName       string   `yaml:"scopeName"`
+	CollectionNames []string `yaml:"collectionNames"`
+}
+
+// Bucket is a source bucket of a config streaming multiple buckets, it overrides the bucket, the collections and the
+// checkpoint group of the base config. The group name defaults to the group name of the base config and the bucket name.
+type Bucket struct {
+	Name            string   `yaml:"name"`
+	GroupName       string   `yaml:"groupName"`
+	ScopeName       string   `yaml:"scopeName"`
+	CollectionNames []string `yaml:"collectionNames"`
 }
 
 type Logging struct {
@@ -136,6 +459,9 @@ type Dcp struct {
 	Metric               Metric             `yaml:"metric"`
 	BucketName           string             `yaml:"bucketName"`
 	RootCAPath           string             `yaml:"rootCAPath"`
+	CertPath             string             `yaml:"certPath"`
+	KeyPath              string             `yaml:"keyPath"`
+	CredentialsProvider  string             `yaml:"credentialsProvider"`
 	Username             string             `yaml:"username"`
 	Logging              Logging            `yaml:"logging"`
 	ScopeName            string             `yaml:"scopeName"`
@@ -143,11 +469,20 @@ type Dcp struct {
 	Metadata             Metadata           `yaml:"metadata"`
 	CollectionNames      []string           `yaml:"collectionNames"`
 	Hosts                []string           `yaml:"hosts"`
+	Pipelines            []Pipeline         `yaml:"pipelines"`
+	Buckets              []Bucket           `yaml:"buckets"`
 	Checkpoint           Checkpoint         `yaml:"checkpoint"`
+	DeadLetter           DeadLetter         `yaml:"deadLetter"`
+	State                State              `yaml:"state"`
+	Consumer             Consumer           `yaml:"consumer"`
 	LeaderElection       LeaderElection     `yaml:"leaderElection"`
 	Dcp                  ExternalDcp        `yaml:"dcp"`
 	HealthCheck          HealthCheck        `yaml:"healthCheck"`
+	DownstreamHealth     DownstreamHealth   `yaml:"downstreamHealth"`
 	RollbackMitigation   RollbackMitigation `yaml:"rollbackMitigation"`
+	PurgeMonitor         PurgeMonitor       `yaml:"purgeMonitor"`
+	NodeHealth           NodeHealth         `yaml:"nodeHealth"`
+	Shutdown             Shutdown           `yaml:"shutdown"`
 	API                  API                `yaml:"api"`
 	MaxQueueSize         int                `yaml:"maxQueueSize"`
 	ConnectionTimeout    time.Duration      `yaml:"connectionTimeout"`
@@ -155,14 +490,141 @@ type Dcp struct {
 	Debug                bool               `yaml:"debug"`
 }
 
+// GetPipelineConfig returns a copy of the config for the pipeline. Only the first pipeline serves the api,
+// the others would bind the same port.
+func (c *Dcp) GetPipelineConfig(index int) *Dcp {
+	pipeline := c.Pipelines[index]
+
+	pipelineConfig := c.clone()
+	pipelineConfig.Pipelines = nil
+	pipelineConfig.Dcp.Group.Name = pipeline.Name
+
+	if pipeline.GroupName != "" {
+		pipelineConfig.Dcp.Group.Name = pipeline.GroupName
+	}
+
+	if pipeline.ScopeName != "" {
+		pipelineConfig.ScopeName = pipeline.ScopeName
+	}
+
+	if len(pipeline.CollectionNames) > 0 {
+		pipelineConfig.CollectionNames = slices.Clone(pipeline.CollectionNames)
+	}
+
+	if index > 0 {
+		pipelineConfig.API.Disabled = true
+	}
+
+	return &pipelineConfig
+}
+
+// clone returns a copy of the config which shares no maps or slices with it, so the configs of the pipelines do not
+// change each other.
+func (c *Dcp) clone() Dcp {
+	cloned := *c
+
+	cloned.CollectionNames = slices.Clone(c.CollectionNames)
+	cloned.Hosts = slices.Clone(c.Hosts)
+	cloned.Pipelines = slices.Clone(c.Pipelines)
+	cloned.Buckets = slices.Clone(c.Buckets)
+	cloned.Metadata.Config = maps.Clone(c.Metadata.Config)
+	cloned.Metadata.Secondary.Config = maps.Clone(c.Metadata.Secondary.Config)
+	cloned.Metadata.Fast.Config = maps.Clone(c.Metadata.Fast.Config)
+	cloned.Metadata.Encryption.Keys = slices.Clone(c.Metadata.Encryption.Keys)
+	cloned.LeaderElection.Config = maps.Clone(c.LeaderElection.Config)
+	cloned.Dcp.Group.Membership.Config = maps.Clone(c.Dcp.Group.Membership.Config)
+	cloned.Dcp.Filter.KeyPrefixes = slices.Clone(c.Dcp.Filter.KeyPrefixes)
+
+	if c.Dcp.Listener.SkipUntil != nil {
+		skipUntil := *c.Dcp.Listener.SkipUntil
+		cloned.Dcp.Listener.SkipUntil = &skipUntil
+	}
+
+	return cloned
+}
+
+// GetBucketConfig returns a copy of the config for the bucket. Only the first bucket serves the api, the others
+// would bind the same port.
+func (c *Dcp) GetBucketConfig(index int) *Dcp {
+	bucket := c.Buckets[index]
+
+	bucketConfig := c.clone()
+	bucketConfig.Buckets = nil
+	bucketConfig.BucketName = bucket.Name
+	bucketConfig.Dcp.Group.Name = c.Dcp.Group.Name + "-" + bucket.Name
+
+	if bucket.GroupName != "" {
+		bucketConfig.Dcp.Group.Name = bucket.GroupName
+	}
+
+	if bucket.ScopeName != "" {
+		bucketConfig.ScopeName = bucket.ScopeName
+	}
+
+	if len(bucket.CollectionNames) > 0 {
+		bucketConfig.CollectionNames = bucket.CollectionNames
+	}
+
+	if index > 0 {
+		bucketConfig.API.Disabled = true
+	}
+
+	return &bucketConfig
+}
+
+// GetSecondaryMetadataConfig returns a copy of the config with the secondary metadata as the metadata.
+func (c *Dcp) GetSecondaryMetadataConfig() *Dcp {
+	secondaryConfig := *c
+	secondaryConfig.Metadata.Type = c.Metadata.Secondary.Type
+	secondaryConfig.Metadata.Config = c.Metadata.Secondary.Config
+	secondaryConfig.Metadata.Secondary = MetadataSecondary{}
+
+	return &secondaryConfig
+}
+
+// GetFastMetadataConfig returns a copy of the config with the fast tier metadata as the metadata.
+func (c *Dcp) GetFastMetadataConfig() *Dcp {
+	fastConfig := *c
+	fastConfig.Metadata.Type = c.Metadata.Fast.Type
+	fastConfig.Metadata.Config = c.Metadata.Fast.Config
+	fastConfig.Metadata.Secondary = MetadataSecondary{}
+	fastConfig.Metadata.Fast = MetadataFast{}
+
+	return &fastConfig
+}
+
 func (c *Dcp) IsCouchbaseMetadata() bool {
 	return c.Metadata.Type == MetadataTypeCouchbase
 }
 
+// IsLeaderCheckpointWriter reports whether only the leader writes to the metadata, other members send their
+// checkpoints to the leader.
+func (c *Dcp) IsLeaderCheckpointWriter() bool {
+	return c.Checkpoint.Writer == CheckpointWriterLeader
+}
+
 func (c *Dcp) IsDcpModeFinite() bool {
 	return c.Dcp.Mode == DcpModeFinite
 }
 
+// GetMapInitialSize returns the initial capacity of per vBucket maps.
+// It uses dcp.mapInitialSize when it is set, otherwise the given vBucket count.
+func (c *Dcp) GetMapInitialSize(vBucketCount int) uint64 {
+	if c.Dcp.MapInitialSize > 0 {
+		return uint64(c.Dcp.MapInitialSize)
+	}
+
+	if vBucketCount < 0 {
+		return 0
+	}
+
+	return uint64(vBucketCount)
+}
+
+func (c *Dcp) IsAuditPersistenceEnabled() bool {
+	return c.API.Audit.Collection != "" && c.IsCouchbaseMetadata()
+}
+
 func (c *Dcp) IsFileMetadata() bool {
 	return c.Metadata.Type == MetadataTypeFile
 }
@@ -193,6 +655,29 @@ type CouchbaseMembership struct {
 	HeartbeatToleranceDuration time.Duration `yaml:"heartbeatToleranceDuration"`
 	MonitorInterval            time.Duration `yaml:"monitorInterval"`
 	Timeout                    time.Duration `yaml:"timeout"`
+	HeartbeatMissThreshold     int           `yaml:"heartbeatMissThreshold"`
+}
+
+// GetMembershipWeight returns the weight of the member, the cpu count of the host for cpu and 1 when it is not set.
+func (c *Dcp) GetMembershipWeight() int {
+	switch c.Dcp.Group.Membership.Weight {
+	case "":
+		return 1
+	case MembershipWeightCPU:
+		return runtime.NumCPU()
+	}
+
+	weight, err := strconv.Atoi(c.Dcp.Group.Membership.Weight)
+	if err == nil && weight < 1 {
+		err = errors.New("membership weight must be at least 1")
+	}
+
+	if err != nil {
+		logger.Log.Error("error while membership weight, err: %v", err)
+		panic(err)
+	}
+
+	return weight
 }
 
 func (c *Dcp) GetCouchbaseMembership() *CouchbaseMembership {
@@ -244,6 +729,16 @@ func (c *Dcp) GetCouchbaseMembership() *CouchbaseMembership {
 		couchbaseMembership.MonitorInterval = parsedMonitorInterval
 	}
 
+	if heartbeatMissThreshold, ok := c.Dcp.Group.Membership.Config[CouchbaseMembershipHeartbeatMissThresholdConfig]; ok {
+		parsedHeartbeatMissThreshold, err := strconv.Atoi(heartbeatMissThreshold)
+		if err != nil {
+			logger.Log.Error("error while parse membership heartbeat miss threshold, err: %v", err)
+			panic(err)
+		}
+
+		couchbaseMembership.HeartbeatMissThreshold = parsedHeartbeatMissThreshold
+	}
+
 	if timeout, ok := c.Dcp.Group.Membership.Config[CouchbaseMembershipTimeoutConfig]; ok {
 		parsedTimeout, err := time.ParseDuration(timeout)
 		if err != nil {
@@ -257,6 +752,265 @@ func (c *Dcp) GetCouchbaseMembership() *CouchbaseMembership {
 	return &couchbaseMembership
 }
 
+type KubernetesLeaseMembership struct {
+	Namespace     string        `yaml:"leaseNamespace"`
+	LeaseDuration time.Duration `yaml:"leaseDuration"`
+	RenewInterval time.Duration `yaml:"renewInterval"`
+	Timeout       time.Duration `yaml:"timeout"`
+}
+
+func (c *Dcp) GetKubernetesLeaseMembership() *KubernetesLeaseMembership {
+	kubernetesLeaseMembership := KubernetesLeaseMembership{
+		Namespace:     c.Dcp.Group.Membership.Config[KubernetesLeaseMembershipNamespaceConfig],
+		LeaseDuration: 15 * time.Second,
+		RenewInterval: 5 * time.Second,
+		Timeout:       10 * time.Second,
+	}
+
+	durations := map[string]*time.Duration{
+		KubernetesLeaseMembershipLeaseDurationConfig: &kubernetesLeaseMembership.LeaseDuration,
+		KubernetesLeaseMembershipRenewIntervalConfig: &kubernetesLeaseMembership.RenewInterval,
+		KubernetesLeaseMembershipTimeoutConfig:       &kubernetesLeaseMembership.Timeout,
+	}
+
+	for key, duration := range durations {
+		value, ok := c.Dcp.Group.Membership.Config[key]
+		if !ok {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			logger.Log.Error("error while parse kubernetes lease membership %s, err: %v", key, err)
+			panic(err)
+		}
+
+		*duration = parsed
+	}
+
+	if kubernetesLeaseMembership.RenewInterval >= kubernetesLeaseMembership.LeaseDuration {
+		err := errors.New("renewInterval must be shorter than leaseDuration")
+		logger.Log.Error("error while kubernetes lease membership configuration, err: %v", err)
+		panic(err)
+	}
+
+	return &kubernetesLeaseMembership
+}
+
+type ConsulMembership struct {
+	Address          string        `yaml:"address"`
+	Token            string        `yaml:"token"`
+	Datacenter       string        `yaml:"datacenter"`
+	KeyPrefix        string        `yaml:"keyPrefix"`
+	SessionTTL       time.Duration `yaml:"sessionTTL"`
+	Timeout          time.Duration `yaml:"timeout"`
+	SecureConnection bool          `yaml:"secureConnection"`
+}
+
+func (c *Dcp) GetConsulMembership() *ConsulMembership {
+	membershipConfig := c.Dcp.Group.Membership.Config
+
+	consulMembership := ConsulMembership{
+		Address:    membershipConfig[ConsulMembershipAddressConfig],
+		Token:      membershipConfig[ConsulMembershipTokenConfig],
+		Datacenter: membershipConfig[ConsulMembershipDatacenterConfig],
+		KeyPrefix:  "go-dcp/",
+		SessionTTL: 15 * time.Second,
+		Timeout:    10 * time.Second,
+	}
+
+	if keyPrefix, ok := membershipConfig[ConsulMembershipKeyPrefixConfig]; ok {
+		consulMembership.KeyPrefix = keyPrefix
+	}
+
+	if secureConnection, ok := membershipConfig[ConsulMembershipSecureConnectionConfig]; ok {
+		parsedSecureConnection, err := strconv.ParseBool(secureConnection)
+		if err != nil {
+			logger.Log.Error("error while parse consul membership secure connection, err: %v", err)
+			panic(err)
+		}
+
+		consulMembership.SecureConnection = parsedSecureConnection
+	}
+
+	durations := map[string]*time.Duration{
+		ConsulMembershipSessionTTLConfig: &consulMembership.SessionTTL,
+		ConsulMembershipTimeoutConfig:    &consulMembership.Timeout,
+	}
+
+	for key, duration := range durations {
+		value, ok := membershipConfig[key]
+		if !ok {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			logger.Log.Error("error while parse consul membership %s, err: %v", key, err)
+			panic(err)
+		}
+
+		*duration = parsed
+	}
+
+	// consul accepts session ttls between 10s and 24h
+	if consulMembership.SessionTTL < 10*time.Second || consulMembership.SessionTTL > 24*time.Hour {
+		err := errors.New("sessionTTL must be between 10s and 24h")
+		logger.Log.Error("error while consul membership configuration, err: %v", err)
+		panic(err)
+	}
+
+	return &consulMembership
+}
+
+type EtcdMembership struct {
+	Username         string        `yaml:"username"`
+	Password         string        `yaml:"password"`
+	KeyPrefix        string        `yaml:"keyPrefix"`
+	Endpoints        []string      `yaml:"endpoints"`
+	TTL              time.Duration `yaml:"ttl"`
+	DialTimeout      time.Duration `yaml:"dialTimeout"`
+	Timeout          time.Duration `yaml:"timeout"`
+	SecureConnection bool          `yaml:"secureConnection"`
+}
+
+func (c *Dcp) GetEtcdMembership() *EtcdMembership {
+	membershipConfig := c.Dcp.Group.Membership.Config
+
+	etcdMembership := EtcdMembership{
+		Username:    membershipConfig[EtcdMembershipUsernameConfig],
+		Password:    membershipConfig[EtcdMembershipPasswordConfig],
+		KeyPrefix:   membershipConfig[EtcdMembershipKeyPrefixConfig],
+		TTL:         10 * time.Second,
+		DialTimeout: 5 * time.Second,
+		Timeout:     10 * time.Second,
+	}
+
+	if endpoints := membershipConfig[EtcdMembershipEndpointsConfig]; endpoints != "" {
+		etcdMembership.Endpoints = strings.Split(endpoints, ",")
+	} else {
+		err := errors.New("endpoints is not set")
+		logger.Log.Error("error while etcd membership configuration, err: %v", err)
+		panic(err)
+	}
+
+	if secureConnection, ok := membershipConfig[EtcdMembershipSecureConnectionConfig]; ok {
+		parsedSecureConnection, err := strconv.ParseBool(secureConnection)
+		if err != nil {
+			logger.Log.Error("error while parse etcd membership secure connection, err: %v", err)
+			panic(err)
+		}
+
+		etcdMembership.SecureConnection = parsedSecureConnection
+	}
+
+	durations := map[string]*time.Duration{
+		EtcdMembershipTTLConfig:         &etcdMembership.TTL,
+		EtcdMembershipDialTimeoutConfig: &etcdMembership.DialTimeout,
+		EtcdMembershipTimeoutConfig:     &etcdMembership.Timeout,
+	}
+
+	for key, duration := range durations {
+		value, ok := membershipConfig[key]
+		if !ok {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			logger.Log.Error("error while parse etcd membership %s, err: %v", key, err)
+			panic(err)
+		}
+
+		*duration = parsed
+	}
+
+	// etcd leases are granted in seconds
+	if etcdMembership.TTL < 2*time.Second {
+		err := errors.New("ttl must be at least 2s")
+		logger.Log.Error("error while etcd membership configuration, err: %v", err)
+		panic(err)
+	}
+
+	return &etcdMembership
+}
+
+type RedisMembership struct {
+	Username                   string        `yaml:"username"`
+	Password                   string        `yaml:"password"`
+	KeyPrefix                  string        `yaml:"keyPrefix"`
+	Addresses                  []string      `yaml:"addresses"`
+	DB                         int           `yaml:"db"`
+	HeartbeatInterval          time.Duration `yaml:"heartbeatInterval"`
+	HeartbeatToleranceDuration time.Duration `yaml:"heartbeatToleranceDuration"`
+	Timeout                    time.Duration `yaml:"timeout"`
+	SecureConnection           bool          `yaml:"secureConnection"`
+}
+
+func (c *Dcp) GetRedisMembership() *RedisMembership {
+	membershipConfig := c.Dcp.Group.Membership.Config
+
+	redisMembership := RedisMembership{
+		Username:                   membershipConfig[RedisMembershipUsernameConfig],
+		Password:                   membershipConfig[RedisMembershipPasswordConfig],
+		KeyPrefix:                  membershipConfig[RedisMembershipKeyPrefixConfig],
+		HeartbeatInterval:          5 * time.Second,
+		HeartbeatToleranceDuration: 10 * time.Second,
+		Timeout:                    5 * time.Second,
+	}
+
+	if address := membershipConfig[RedisMembershipAddressConfig]; address != "" {
+		redisMembership.Addresses = strings.Split(address, ",")
+	} else {
+		err := errors.New("address is not set")
+		logger.Log.Error("error while redis membership configuration, err: %v", err)
+		panic(err)
+	}
+
+	if db, ok := membershipConfig[RedisMembershipDBConfig]; ok {
+		parsedDB, err := strconv.Atoi(db)
+		if err != nil {
+			logger.Log.Error("error while parse redis membership db, err: %v", err)
+			panic(err)
+		}
+
+		redisMembership.DB = parsedDB
+	}
+
+	if secureConnection, ok := membershipConfig[RedisMembershipSecureConnectionConfig]; ok {
+		parsedSecureConnection, err := strconv.ParseBool(secureConnection)
+		if err != nil {
+			logger.Log.Error("error while parse redis membership secure connection, err: %v", err)
+			panic(err)
+		}
+
+		redisMembership.SecureConnection = parsedSecureConnection
+	}
+
+	durations := map[string]*time.Duration{
+		RedisMembershipHeartbeatIntervalConfig:  &redisMembership.HeartbeatInterval,
+		RedisMembershipHeartbeatToleranceConfig: &redisMembership.HeartbeatToleranceDuration,
+		RedisMembershipTimeoutConfig:            &redisMembership.Timeout,
+	}
+
+	for key, duration := range durations {
+		value, ok := membershipConfig[key]
+		if !ok {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			logger.Log.Error("error while parse redis membership %s, err: %v", key, err)
+			panic(err)
+		}
+
+		*duration = parsed
+	}
+
+	return &redisMembership
+}
+
 type KubernetesLeaderElector struct {
 	LeaseLockName      string        `yaml:"leaseLockName"`
 	LeaseLockNamespace string        `yaml:"leaseLockNamespace"`
@@ -328,6 +1082,8 @@ type CouchbaseMetadata struct {
 	Scope                string        `yaml:"scope"`
 	Collection           string        `yaml:"collection"`
 	RootCAPath           string        `yaml:"rootCAPath"`
+	CertPath             string        `yaml:"certPath"`
+	KeyPath              string        `yaml:"keyPath"`
 	Hosts                []string      `yaml:"hosts"`
 	MaxQueueSize         int           `yaml:"maxQueueSize"`
 	ConnectionBufferSize uint          `yaml:"connectionBufferSize"`
@@ -348,6 +1104,8 @@ func (c *Dcp) GetCouchbaseMetadata() *CouchbaseMetadata {
 		ConnectionTimeout:    time.Minute,
 		SecureConnection:     c.SecureConnection,
 		RootCAPath:           c.RootCAPath,
+		CertPath:             c.CertPath,
+		KeyPath:              c.KeyPath,
 	}
 
 	if hosts, ok := c.Metadata.Config[CouchbaseMetadataHostsConfig]; ok {
@@ -405,6 +1163,14 @@ func (c *Dcp) GetCouchbaseMetadata() *CouchbaseMetadata {
 		couchbaseMetadata.RootCAPath = rootCAPath
 	}
 
+	if certPath, ok := c.Metadata.Config[CouchbaseMetadataCertPathConfig]; ok {
+		couchbaseMetadata.CertPath = certPath
+	}
+
+	if keyPath, ok := c.Metadata.Config[CouchbaseMetadataKeyPathConfig]; ok {
+		couchbaseMetadata.KeyPath = keyPath
+	}
+
 	return &couchbaseMetadata
 }
 
@@ -412,8 +1178,13 @@ func (c *Dcp) ApplyDefaults() {
 	c.applyDefaultRollbackMitigation()
 	c.applyDefaultCheckpoint()
 	c.applyDefaultHealthCheck()
+	c.applyDefaultDownstreamHealth()
+	c.applyDefaultPurgeMonitor()
+	c.applyDefaultNodeHealth()
+	c.applyDefaultShutdown()
 	c.applyDefaultGroupMembership()
 	c.applyDefaultConnectionTimeout()
+	c.applyDefaultClientCertificate()
 	c.applyDefaultCollections()
 	c.applyDefaultScopeName()
 	c.applyDefaultConnectionBufferSize()
@@ -423,9 +1194,27 @@ func (c *Dcp) ApplyDefaults() {
 	c.applyDefaultLeaderElection()
 	c.applyDefaultDcp()
 	c.applyDefaultMetadata()
+	c.applyDefaultConsumer()
 	c.applyLogging()
 }
 
+// Validate reports the settings which are invalid after the defaults are applied, NewDcp returns its error.
+func (c *Dcp) Validate() error {
+	if c.Dcp.Filter.KeyRegex != "" {
+		if _, err := regexp.Compile(c.Dcp.Filter.KeyRegex); err != nil {
+			return fmt.Errorf("dcp.filter.keyRegex is invalid: %w", err)
+		}
+	}
+
+	switch c.Dcp.Reconnect.Policy {
+	case "", ReconnectPolicyCrash, ReconnectPolicyHalt, ReconnectPolicyAlert:
+	default:
+		return fmt.Errorf("dcp.reconnect.policy is invalid: %s", c.Dcp.Reconnect.Policy)
+	}
+
+	return nil
+}
+
 func (c *Dcp) applyDefaultRollbackMitigation() {
 	if c.RollbackMitigation.Interval == 0 {
 		c.RollbackMitigation.Interval = time.Second
@@ -452,6 +1241,22 @@ func (c *Dcp) applyDefaultCheckpoint() {
 	if c.Checkpoint.AutoReset == "" {
 		c.Checkpoint.AutoReset = "earliest"
 	}
+
+	if c.Checkpoint.VBucketCountChange == "" {
+		c.Checkpoint.VBucketCountChange = VBucketCountChangeFail
+	}
+
+	if c.Checkpoint.Retry.MaxAttempts == 0 {
+		c.Checkpoint.Retry.MaxAttempts = 3
+	}
+
+	if c.Checkpoint.Retry.Backoff == 0 {
+		c.Checkpoint.Retry.Backoff = time.Second
+	}
+
+	if c.Checkpoint.History.Interval == 0 {
+		c.Checkpoint.History.Interval = 10 * time.Minute
+	}
 }
 
 func (c *Dcp) applyDefaultHealthCheck() {
@@ -464,6 +1269,46 @@ func (c *Dcp) applyDefaultHealthCheck() {
 	}
 }
 
+func (c *Dcp) applyDefaultDownstreamHealth() {
+	if c.DownstreamHealth.Interval == 0 {
+		c.DownstreamHealth.Interval = 5 * time.Second
+	}
+
+	if c.DownstreamHealth.Timeout == 0 {
+		c.DownstreamHealth.Timeout = 2 * time.Second
+	}
+
+	if c.DownstreamHealth.FailureThreshold == 0 {
+		c.DownstreamHealth.FailureThreshold = 3
+	}
+
+	if c.DownstreamHealth.SuccessThreshold == 0 {
+		c.DownstreamHealth.SuccessThreshold = 2
+	}
+}
+
+func (c *Dcp) applyDefaultPurgeMonitor() {
+	if c.PurgeMonitor.Interval == 0 {
+		c.PurgeMonitor.Interval = time.Minute
+	}
+
+	if c.PurgeMonitor.Margin == 0 {
+		c.PurgeMonitor.Margin = 10000
+	}
+}
+
+func (c *Dcp) applyDefaultNodeHealth() {
+	if c.NodeHealth.Interval == 0 {
+		c.NodeHealth.Interval = 5 * time.Second
+	}
+}
+
+func (c *Dcp) applyDefaultShutdown() {
+	if c.Shutdown.Timeout == 0 {
+		c.Shutdown.Timeout = 30 * time.Second
+	}
+}
+
 func (c *Dcp) applyDefaultGroupMembership() {
 	if c.Dcp.Group.Membership.RebalanceDelay == 0 {
 		c.Dcp.Group.Membership.RebalanceDelay = 30 * time.Second
@@ -481,6 +1326,36 @@ func (c *Dcp) applyDefaultGroupMembership() {
 		c.Dcp.Group.Membership.Type = MembershipTypeCouchbase
 	}
 
+	if c.Dcp.Group.Membership.Assignment == "" {
+		c.Dcp.Group.Membership.Assignment = MembershipAssignmentRange
+	}
+
+	if c.Dcp.Group.Membership.SplitBrain.Interval == 0 {
+		c.Dcp.Group.Membership.SplitBrain.Interval = 10 * time.Second
+	}
+
+	if c.Dcp.Group.Membership.Handoff.Timeout == 0 {
+		c.Dcp.Group.Membership.Handoff.Timeout = time.Minute
+	}
+
+	if c.Dcp.Group.Membership.Reconfigure.Interval == 0 {
+		c.Dcp.Group.Membership.Reconfigure.Interval = 5 * time.Second
+	}
+
+	if c.Dcp.Group.Membership.Barrier.Fraction == 0 {
+		c.Dcp.Group.Membership.Barrier.Fraction = 1
+	}
+
+	if c.Dcp.Group.Membership.Barrier.Fraction < 0 || c.Dcp.Group.Membership.Barrier.Fraction > 1 {
+		err := errors.New("membership barrier fraction must be between 0 and 1")
+		logger.Log.Error("error while membership configuration, err: %v", err)
+		panic(err)
+	}
+
+	if c.Dcp.Group.Membership.Barrier.Timeout == 0 {
+		c.Dcp.Group.Membership.Barrier.Timeout = 5 * time.Minute
+	}
+
 	if totalMembersFromEnvVariable := os.Getenv("GO_DCP__DCP_GROUP_MEMBERSHIP_TOTALMEMBERS"); totalMembersFromEnvVariable != "" {
 		t, err := strconv.Atoi(totalMembersFromEnvVariable)
 		if err != nil {
@@ -512,6 +1387,14 @@ func (c *Dcp) applyDefaultConnectionTimeout() {
 	}
 }
 
+func (c *Dcp) applyDefaultClientCertificate() {
+	if (c.CertPath == "") != (c.KeyPath == "") {
+		err := errors.New("certPath and keyPath must be set together")
+		logger.Log.Error("error while client certificate configuration, err: %v", err)
+		panic(err)
+	}
+}
+
 func (c *Dcp) applyDefaultCollections() {
 	if c.CollectionNames == nil {
 		c.CollectionNames = []string{DefaultCollectionName}
@@ -540,6 +1423,10 @@ func (c *Dcp) applyDefaultMetrics() {
 	if c.Metric.Path == "" {
 		c.Metric.Path = "/metrics"
 	}
+
+	if c.Metric.LargeFleet.VBucketGroups == 0 {
+		c.Metric.LargeFleet.VBucketGroups = 16
+	}
 }
 
 func (c *Dcp) applyDefaultAPI() {
@@ -570,12 +1457,96 @@ func (c *Dcp) applyDefaultDcp() {
 	if c.Dcp.MaxQueueSize == 0 {
 		c.Dcp.MaxQueueSize = 2048
 	}
+
+	if c.Dcp.ConnectionsPerNode < 1 {
+		c.Dcp.ConnectionsPerNode = 1
+	}
+
+	if c.Dcp.Reconnect.Policy == "" {
+		c.Dcp.Reconnect.Policy = ReconnectPolicyCrash
+	}
+
+	if c.Dcp.Reconnect.Window == 0 {
+		c.Dcp.Reconnect.Window = time.Hour
+	}
+
+	if c.Dcp.Noop.IdleTimeout == 0 {
+		c.Dcp.Noop.IdleTimeout = 2 * DcpNoopInterval
+	}
+
+	if c.Dcp.Listener.Scheduler.Workers == 0 {
+		c.Dcp.Listener.Scheduler.Workers = 4
+	}
+
+	if c.Dcp.Listener.Scheduler.QueueSize == 0 {
+		c.Dcp.Listener.Scheduler.QueueSize = 128
+	}
+
+	if c.Dcp.Listener.Scheduler.StarvedAfter == 0 {
+		c.Dcp.Listener.Scheduler.StarvedAfter = time.Second
+	}
+
+	if c.Dcp.Binary.Policy == "" {
+		c.Dcp.Binary.Policy = BinaryPolicyRaw
+	}
+
+	if c.Dcp.Validation.Policy == "" {
+		c.Dcp.Validation.Policy = ValidationPolicyFlag
+	}
+
+	if c.Dcp.Flush.Reset == "" {
+		c.Dcp.Flush.Reset = FlushResetEarliest
+	}
+
+	if c.Dcp.Listener.Batch.Size == 0 {
+		c.Dcp.Listener.Batch.Size = 1000
+	}
+
+	if c.Dcp.Listener.Batch.Window == 0 {
+		c.Dcp.Listener.Batch.Window = time.Second
+	}
+}
+
+func (c *Dcp) applyDefaultConsumer() {
+	if c.Consumer.DecodeErrorPolicy == "" {
+		c.Consumer.DecodeErrorPolicy = DecodeErrorPolicySkip
+	}
+
+	if c.Consumer.Retry.InitialBackoff == 0 {
+		c.Consumer.Retry.InitialBackoff = 100 * time.Millisecond
+	}
+
+	if c.Consumer.Retry.MaxBackoff == 0 {
+		c.Consumer.Retry.MaxBackoff = 10 * time.Second
+	}
+
+	if c.Consumer.Retry.Multiplier == 0 {
+		c.Consumer.Retry.Multiplier = 2
+	}
 }
 
 func (c *Dcp) applyDefaultMetadata() {
 	if c.Metadata.Type == "" {
 		c.Metadata.Type = MetadataTypeCouchbase
 	}
+
+	if c.Metadata.GC.Interval == 0 {
+		c.Metadata.GC.Interval = time.Hour
+	}
+
+	if c.Metadata.GC.Retention == 0 {
+		c.Metadata.GC.Retention = 7 * 24 * time.Hour
+	}
+
+	if c.Metadata.Fast.DurableInterval == 0 {
+		c.Metadata.Fast.DurableInterval = 10 * time.Minute
+	}
+
+	if c.Metadata.Encryption.Enabled && len(c.Metadata.Encryption.Keys) == 0 {
+		err := errors.New("metadata encryption keys are not set")
+		logger.Log.Error("error while metadata encryption configuration, err: %v", err)
+		panic(err)
+	}
 }
 
 func (c *Dcp) applyLogging() {
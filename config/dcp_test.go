@@ -1,6 +1,7 @@
 package config
 
 import (
+	"runtime"
 	"testing"
 	"time"
 
@@ -72,16 +73,54 @@ func TestGetCouchbaseMetadata(t *testing.T) {
 	}
 }
 
+func TestClientCertificateWithoutKey(t *testing.T) {
+	dcp := &Dcp{CertPath: "client.pem"}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("certPath without keyPath is accepted")
+		}
+	}()
+	dcp.applyDefaultClientCertificate()
+}
+
+func TestGetMembershipWeight(t *testing.T) {
+	dcp := &Dcp{}
+
+	if weight := dcp.GetMembershipWeight(); weight != 1 {
+		t.Errorf("Weight is not set to expected value, got: %v", weight)
+	}
+
+	dcp.Dcp.Group.Membership.Weight = "3"
+	if weight := dcp.GetMembershipWeight(); weight != 3 {
+		t.Errorf("Weight is not set to expected value, got: %v", weight)
+	}
+
+	dcp.Dcp.Group.Membership.Weight = MembershipWeightCPU
+	if weight := dcp.GetMembershipWeight(); weight != runtime.NumCPU() {
+		t.Errorf("Weight is not set to expected value, got: %v", weight)
+	}
+
+	dcp.Dcp.Group.Membership.Weight = "0"
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Weight 0 is accepted")
+		}
+	}()
+	dcp.GetMembershipWeight()
+}
+
 func TestGetCouchbaseMembership(t *testing.T) {
 	dcp := &Dcp{
 		Dcp: ExternalDcp{
 			Group: DCPGroup{
 				Membership: DCPGroupMembership{
 					Config: map[string]string{
-						CouchbaseMembershipExpirySecondsConfig:     "120",
-						CouchbaseMembershipHeartbeatIntervalConfig: "10s",
-						CouchbaseMembershipMonitorIntervalConfig:   "30s",
-						CouchbaseMembershipTimeoutConfig:           "30s",
+						CouchbaseMembershipExpirySecondsConfig:          "120",
+						CouchbaseMembershipHeartbeatIntervalConfig:      "10s",
+						CouchbaseMembershipMonitorIntervalConfig:        "30s",
+						CouchbaseMembershipTimeoutConfig:                "30s",
+						CouchbaseMembershipHeartbeatMissThresholdConfig: "3",
 					},
 				},
 			},
@@ -115,6 +154,136 @@ func TestGetCouchbaseMembership(t *testing.T) {
 	if couchbaseMembership.Timeout != expectedTimeout {
 		t.Errorf("Timeout is not set to expected value")
 	}
+
+	if couchbaseMembership.HeartbeatMissThreshold != 3 {
+		t.Errorf("HeartbeatMissThreshold is not set to expected value")
+	}
+}
+
+func TestGetKubernetesLeaseMembership(t *testing.T) {
+	dcp := &Dcp{
+		Dcp: ExternalDcp{
+			Group: DCPGroup{
+				Membership: DCPGroupMembership{
+					Config: map[string]string{
+						KubernetesLeaseMembershipNamespaceConfig:     "dcp",
+						KubernetesLeaseMembershipLeaseDurationConfig: "30s",
+					},
+				},
+			},
+		},
+	}
+
+	leaseMembership := dcp.GetKubernetesLeaseMembership()
+
+	if leaseMembership.Namespace != "dcp" {
+		t.Errorf("Namespace is not set to expected value")
+	}
+
+	if leaseMembership.LeaseDuration != 30*time.Second {
+		t.Errorf("LeaseDuration is not set to expected value")
+	}
+
+	if leaseMembership.RenewInterval != 5*time.Second {
+		t.Errorf("RenewInterval is not set to expected value")
+	}
+}
+
+func TestGetConsulMembership(t *testing.T) {
+	dcp := &Dcp{
+		Dcp: ExternalDcp{
+			Group: DCPGroup{
+				Membership: DCPGroupMembership{
+					Config: map[string]string{
+						ConsulMembershipAddressConfig:          "consul:8500",
+						ConsulMembershipSessionTTLConfig:       "30s",
+						ConsulMembershipSecureConnectionConfig: "true",
+					},
+				},
+			},
+		},
+	}
+
+	consulMembership := dcp.GetConsulMembership()
+
+	if consulMembership.Address != "consul:8500" {
+		t.Errorf("Address is not set to expected value")
+	}
+
+	if consulMembership.SessionTTL != 30*time.Second {
+		t.Errorf("SessionTTL is not set to expected value")
+	}
+
+	if !consulMembership.SecureConnection {
+		t.Errorf("SecureConnection is not set to expected value")
+	}
+
+	if consulMembership.KeyPrefix != "go-dcp/" {
+		t.Errorf("KeyPrefix is not set to expected value")
+	}
+}
+
+func TestGetEtcdMembership(t *testing.T) {
+	dcp := &Dcp{
+		Dcp: ExternalDcp{
+			Group: DCPGroup{
+				Membership: DCPGroupMembership{
+					Config: map[string]string{
+						EtcdMembershipEndpointsConfig: "etcd-0:2379,etcd-1:2379",
+						EtcdMembershipTTLConfig:       "20s",
+					},
+				},
+			},
+		},
+	}
+
+	etcdMembership := dcp.GetEtcdMembership()
+
+	if len(etcdMembership.Endpoints) != 2 || etcdMembership.Endpoints[1] != "etcd-1:2379" {
+		t.Errorf("Endpoints is not set to expected value")
+	}
+
+	if etcdMembership.TTL != 20*time.Second {
+		t.Errorf("TTL is not set to expected value")
+	}
+
+	if etcdMembership.DialTimeout != 5*time.Second {
+		t.Errorf("DialTimeout is not set to expected value")
+	}
+}
+
+func TestGetRedisMembership(t *testing.T) {
+	dcp := &Dcp{
+		Dcp: ExternalDcp{
+			Group: DCPGroup{
+				Membership: DCPGroupMembership{
+					Config: map[string]string{
+						RedisMembershipAddressConfig:           "localhost:6379",
+						RedisMembershipDBConfig:                "2",
+						RedisMembershipHeartbeatIntervalConfig: "2s",
+					},
+				},
+			},
+		},
+	}
+
+	redisMembership := dcp.GetRedisMembership()
+
+	if len(redisMembership.Addresses) != 1 || redisMembership.Addresses[0] != "localhost:6379" {
+		t.Errorf("Addresses is not set to expected value")
+	}
+
+	if redisMembership.DB != 2 {
+		t.Errorf("DB is not set to expected value")
+	}
+
+	if redisMembership.HeartbeatInterval != 2*time.Second {
+		t.Errorf("HeartbeatInterval is not set to expected value")
+	}
+
+	if redisMembership.HeartbeatToleranceDuration != 10*time.Second {
+		t.Errorf("HeartbeatToleranceDuration is not set to expected value")
+	}
 }
 
 func TestDcp_GetFileMetadata(t *testing.T) {
@@ -167,6 +336,18 @@ func TestDcpApplyDefaultCheckpoint(t *testing.T) {
 	if c.Checkpoint.AutoReset != "earliest" {
 		t.Errorf("Checkpoint.AutoReset is not set to expected value")
 	}
+
+	if c.Checkpoint.VBucketCountChange != VBucketCountChangeFail {
+		t.Errorf("Checkpoint.VBucketCountChange is not set to expected value")
+	}
+
+	if c.Checkpoint.Retry.MaxAttempts != 3 {
+		t.Errorf("Checkpoint.Retry.MaxAttempts is not set to expected value")
+	}
+
+	if c.Checkpoint.Retry.Backoff != time.Second {
+		t.Errorf("Checkpoint.Retry.Backoff is not set to expected value")
+	}
 }
 
 func TestDcpApplyDefaultHealthCheck(t *testing.T) {
@@ -205,6 +386,30 @@ func TestDcpApplyDefaultGroupMembership(t *testing.T) {
 	if c.Dcp.Group.Membership.Type != "couchbase" {
 		t.Errorf("Dcp.Group.Membership.Type is not set to expected value")
 	}
+
+	if c.Dcp.Group.Membership.SplitBrain.Interval != 10*time.Second {
+		t.Errorf("Dcp.Group.Membership.SplitBrain.Interval is not set to expected value")
+	}
+
+	if c.Dcp.Group.Membership.Handoff.Timeout != time.Minute {
+		t.Errorf("Dcp.Group.Membership.Handoff.Timeout is not set to expected value")
+	}
+
+	if c.Dcp.Group.Membership.Reconfigure.Interval != 5*time.Second {
+		t.Errorf("Dcp.Group.Membership.Reconfigure.Interval is not set to expected value")
+	}
+
+	if c.Dcp.Group.Membership.Barrier.Fraction != 1 {
+		t.Errorf("Dcp.Group.Membership.Barrier.Fraction is not set to expected value")
+	}
+
+	if c.Dcp.Group.Membership.Barrier.Timeout != 5*time.Minute {
+		t.Errorf("Dcp.Group.Membership.Barrier.Timeout is not set to expected value")
+	}
+
+	if c.Dcp.Group.Membership.Assignment != MembershipAssignmentRange {
+		t.Errorf("Dcp.Group.Membership.Assignment is not set to expected value")
+	}
 }
 
 func TestDcpApplyDefaultConnectionTimeout(t *testing.T) {
@@ -263,6 +468,10 @@ func TestDcpApplyDefaultMetrics(t *testing.T) {
 	if c.Metric.Path != "/metrics" {
 		t.Errorf("Metric.Path is not set to expected value")
 	}
+
+	if c.Metric.LargeFleet.VBucketGroups != 16 {
+		t.Errorf("Metric.LargeFleet.VBucketGroups is not set to expected value")
+	}
 }
 
 func TestDcpApplyDefaultAPI(t *testing.T) {
@@ -306,6 +515,135 @@ func TestDcpApplyDefaultDcp(t *testing.T) {
 	if c.Dcp.ConnectionBufferSize.(int) != 20971520 {
 		t.Errorf("Dcp.ConnectionBufferSize is not set to expected value")
 	}
+
+	if c.Dcp.ConnectionsPerNode != 1 {
+		t.Errorf("Dcp.ConnectionsPerNode is not set to expected value")
+	}
+
+	if c.Dcp.Noop.IdleTimeout != 6*time.Minute {
+		t.Errorf("Dcp.Noop.IdleTimeout is not set to expected value")
+	}
+
+	if c.Dcp.Reconnect.Policy != ReconnectPolicyCrash {
+		t.Errorf("Dcp.Reconnect.Policy is not set to expected value")
+	}
+
+	if c.Dcp.Reconnect.Window != time.Hour {
+		t.Errorf("Dcp.Reconnect.Window is not set to expected value")
+	}
+
+	if c.Dcp.Listener.Scheduler.Workers != 4 {
+		t.Errorf("Dcp.Listener.Scheduler.Workers is not set to expected value")
+	}
+
+	if c.Dcp.Listener.Scheduler.QueueSize != 128 {
+		t.Errorf("Dcp.Listener.Scheduler.QueueSize is not set to expected value")
+	}
+
+	if c.Dcp.Listener.Scheduler.StarvedAfter != time.Second {
+		t.Errorf("Dcp.Listener.Scheduler.StarvedAfter is not set to expected value")
+	}
+
+	if c.Dcp.Binary.Policy != BinaryPolicyRaw {
+		t.Errorf("Dcp.Binary.Policy is not set to expected value")
+	}
+
+	if c.Dcp.Validation.Policy != ValidationPolicyFlag {
+		t.Errorf("Dcp.Validation.Policy is not set to expected value")
+	}
+
+	if c.Dcp.Flush.Reset != FlushResetEarliest {
+		t.Errorf("Dcp.Flush.Reset is not set to expected value")
+	}
+
+	if c.Dcp.Listener.Batch.Size != 1000 {
+		t.Errorf("Dcp.Listener.Batch.Size is not set to expected value")
+	}
+
+	if c.Dcp.Listener.Batch.Window != time.Second {
+		t.Errorf("Dcp.Listener.Batch.Window is not set to expected value")
+	}
+}
+
+func TestApplyDefaultPurgeMonitor(t *testing.T) {
+	c := &Dcp{}
+	c.applyDefaultPurgeMonitor()
+
+	if c.PurgeMonitor.Enabled {
+		t.Errorf("PurgeMonitor.Enabled is not set to expected value")
+	}
+
+	if c.PurgeMonitor.Interval != time.Minute {
+		t.Errorf("PurgeMonitor.Interval is not set to expected value")
+	}
+
+	if c.PurgeMonitor.Margin != 10000 {
+		t.Errorf("PurgeMonitor.Margin is not set to expected value")
+	}
+}
+
+func TestApplyDefaultNodeHealth(t *testing.T) {
+	c := &Dcp{}
+	c.applyDefaultNodeHealth()
+
+	if c.NodeHealth.Disabled {
+		t.Errorf("NodeHealth.Disabled is not set to expected value")
+	}
+
+	if c.NodeHealth.Interval != 5*time.Second {
+		t.Errorf("NodeHealth.Interval is not set to expected value")
+	}
+}
+
+func TestApplyDefaultShutdown(t *testing.T) {
+	c := &Dcp{}
+	c.applyDefaultShutdown()
+
+	if c.Shutdown.Timeout != 30*time.Second {
+		t.Errorf("Shutdown.Timeout is not set to expected value")
+	}
+}
+
+func TestApplyDefaultDownstreamHealth(t *testing.T) {
+	c := &Dcp{}
+	c.applyDefaultDownstreamHealth()
+
+	if c.DownstreamHealth.Type != "" {
+		t.Errorf("DownstreamHealth.Type is not set to expected value")
+	}
+
+	if c.DownstreamHealth.Interval != 5*time.Second {
+		t.Errorf("DownstreamHealth.Interval is not set to expected value")
+	}
+
+	if c.DownstreamHealth.Timeout != 2*time.Second {
+		t.Errorf("DownstreamHealth.Timeout is not set to expected value")
+	}
+
+	if c.DownstreamHealth.FailureThreshold != 3 || c.DownstreamHealth.SuccessThreshold != 2 {
+		t.Errorf("DownstreamHealth thresholds are not set to expected values")
+	}
+}
+
+func TestApplyDefaultConsumer(t *testing.T) {
+	c := &Dcp{}
+	c.applyDefaultConsumer()
+
+	if c.Consumer.Retry.MaxAttempts != 0 {
+		t.Errorf("Consumer.Retry.MaxAttempts is not set to expected value")
+	}
+
+	if c.Consumer.Retry.InitialBackoff != 100*time.Millisecond {
+		t.Errorf("Consumer.Retry.InitialBackoff is not set to expected value")
+	}
+
+	if c.Consumer.Retry.MaxBackoff != 10*time.Second {
+		t.Errorf("Consumer.Retry.MaxBackoff is not set to expected value")
+	}
+
+	if c.Consumer.Retry.Multiplier != 2 {
+		t.Errorf("Consumer.Retry.Multiplier is not set to expected value")
+	}
 }
 
 func TestApplyDefaultMetadata(t *testing.T) {
@@ -322,6 +660,18 @@ func TestApplyDefaultMetadata(t *testing.T) {
 	if c.Metadata.Type != "couchbase" {
 		t.Errorf("Metadata.Type is not set to expected value")
 	}
+
+	if c.Metadata.GC.Interval != time.Hour {
+		t.Errorf("Metadata.GC.Interval is not set to expected value")
+	}
+
+	if c.Metadata.Fast.DurableInterval != 10*time.Minute {
+		t.Errorf("Metadata.Fast.DurableInterval is not set to expected value")
+	}
+
+	if c.Metadata.GC.Retention != 7*24*time.Hour {
+		t.Errorf("Metadata.GC.Retention is not set to expected value")
+	}
 }
 
 func TestDcpMode(t *testing.T) {
@@ -381,3 +731,156 @@ func TestDcpMode(t *testing.T) {
 		}
 	})
 }
+
+func TestDcp_GetMapInitialSize(t *testing.T) {
+	t.Run("it should return vBucket count when map initial size is not set", func(t *testing.T) {
+		// Arrange
+		dcp := &Dcp{}
+
+		// Act
+		actualValue := dcp.GetMapInitialSize(32)
+
+		// Assert
+		if actualValue != 32 {
+			t.Errorf("map initial size is not set to expected value. got %v want %v", actualValue, 32)
+		}
+	})
+
+	t.Run("it should return configured value when map initial size is set", func(t *testing.T) {
+		// Arrange
+		dcp := &Dcp{
+			Dcp: ExternalDcp{
+				MapInitialSize: 128,
+			},
+		}
+
+		// Act
+		actualValue := dcp.GetMapInitialSize(32)
+
+		// Assert
+		if actualValue != 128 {
+			t.Errorf("map initial size is not set to expected value. got %v want %v", actualValue, 128)
+		}
+	})
+}
+
+func TestDcp_GetPipelineConfig(t *testing.T) {
+	// Arrange
+	dcp := &Dcp{
+		ScopeName:       "base",
+		CollectionNames: []string{"base"},
+		Pipelines: []Pipeline{
+			{Name: "orders", CollectionNames: []string{"orders"}},
+			{Name: "users", GroupName: "users-group", ScopeName: "users"},
+		},
+	}
+
+	// Act
+	orders := dcp.GetPipelineConfig(0)
+	users := dcp.GetPipelineConfig(1)
+
+	// Assert
+	if orders.Dcp.Group.Name != "orders" || orders.ScopeName != "base" || orders.CollectionNames[0] != "orders" || orders.API.Disabled {
+		t.Errorf("orders pipeline config is not set to expected value")
+	}
+
+	if users.Dcp.Group.Name != "users-group" || users.ScopeName != "users" || users.CollectionNames[0] != "base" || !users.API.Disabled {
+		t.Errorf("users pipeline config is not set to expected value")
+	}
+
+	if orders.Pipelines != nil || len(dcp.Pipelines) != 2 || dcp.API.Disabled {
+		t.Errorf("base config is changed")
+	}
+}
+
+func TestDcp_GetPipelineConfigSharesNoMaps(t *testing.T) {
+	// Arrange
+	skipUntil := time.Unix(10, 0)
+	dcp := &Dcp{
+		Hosts:     []string{"localhost:8091"},
+		Metadata:  Metadata{Config: map[string]string{"bucket": "base"}},
+		Pipelines: []Pipeline{{Name: "orders", CollectionNames: []string{"orders"}}, {Name: "users"}},
+	}
+	dcp.Dcp.Group.Membership.Config = map[string]string{"timeout": "1s"}
+	dcp.Dcp.Filter.KeyPrefixes = []string{"order:"}
+	dcp.Dcp.Listener.SkipUntil = &skipUntil
+
+	// Act
+	orders := dcp.GetPipelineConfig(0)
+	orders.Hosts[0] = "changed"
+	orders.CollectionNames[0] = "changed"
+	orders.Metadata.Config["bucket"] = "changed"
+	orders.Dcp.Group.Membership.Config["timeout"] = "changed"
+	orders.Dcp.Filter.KeyPrefixes[0] = "changed"
+	*orders.Dcp.Listener.SkipUntil = time.Unix(20, 0)
+
+	users := dcp.GetPipelineConfig(1)
+
+	// Assert
+	if dcp.Hosts[0] != "localhost:8091" || dcp.Pipelines[0].CollectionNames[0] != "orders" ||
+		dcp.Metadata.Config["bucket"] != "base" || dcp.Dcp.Group.Membership.Config["timeout"] != "1s" ||
+		dcp.Dcp.Filter.KeyPrefixes[0] != "order:" || !dcp.Dcp.Listener.SkipUntil.Equal(skipUntil) {
+		t.Errorf("base config is changed through a pipeline config")
+	}
+
+	if users.Metadata.Config["bucket"] != "base" || users.Hosts[0] != "localhost:8091" {
+		t.Errorf("pipeline config is changed through another pipeline config")
+	}
+}
+
+func TestDcp_GetBucketConfig(t *testing.T) {
+	// Arrange
+	dcp := &Dcp{
+		BucketName:      "base",
+		CollectionNames: []string{"base"},
+		Buckets: []Bucket{
+			{Name: "orders", CollectionNames: []string{"orders"}},
+			{Name: "users", GroupName: "users-group", ScopeName: "users"},
+		},
+	}
+	dcp.Dcp.Group.Name = "connector"
+
+	// Act
+	orders := dcp.GetBucketConfig(0)
+	users := dcp.GetBucketConfig(1)
+
+	// Assert
+	if orders.BucketName != "orders" || orders.Dcp.Group.Name != "connector-orders" || orders.CollectionNames[0] != "orders" || orders.API.Disabled {
+		t.Errorf("orders bucket config is not set to expected value")
+	}
+
+	if users.BucketName != "users" || users.Dcp.Group.Name != "users-group" || users.ScopeName != "users" || !users.API.Disabled {
+		t.Errorf("users bucket config is not set to expected value")
+	}
+
+	if orders.Buckets != nil || len(dcp.Buckets) != 2 || dcp.BucketName != "base" {
+		t.Errorf("base config is changed")
+	}
+}
+
+func TestDcp_Validate(t *testing.T) {
+	tests := []struct {
+		name            string
+		keyRegex        string
+		reconnectPolicy string
+		valid           bool
+	}{
+		{name: "without key regex", valid: true},
+		{name: "valid key regex", keyRegex: "^order:[0-9]+$", valid: true},
+		{name: "invalid key regex", keyRegex: "order:("},
+		{name: "alert reconnect policy", reconnectPolicy: ReconnectPolicyAlert, valid: true},
+		{name: "unknown reconnect policy", reconnectPolicy: "retry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Dcp{}
+			c.Dcp.Filter.KeyRegex = tt.keyRegex
+			c.Dcp.Reconnect.Policy = tt.reconnectPolicy
+
+			if err := c.Validate(); (err == nil) != tt.valid {
+				t.Errorf("expected valid: %v, got err: %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type AuditStore interface {
+	Save(event *models.AuditEvent) error
+}
+
+const maxRelativeExpiry = 30 * 24 * time.Hour
+
+type cbAuditStore struct {
+	client         Client
+	config         *config.Dcp
+	scopeName      string
+	collectionName string
+}
+
+func (s *cbAuditStore) Save(event *models.AuditEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	payload, err := sonic.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	id := getAuditID(s.config.Dcp.Group.Name, event)
+
+	// audit documents cannot be looked up by group, so they expire with the retention of the metadata gc
+	var expiry uint32
+	if s.config.Metadata.GC.Enabled {
+		expiry = expiryOf(s.config.Metadata.GC.Retention)
+	}
+
+	return CreateDocument(ctx, s.client.GetMetaAgent(), s.scopeName, s.collectionName, id, payload, helpers.JSONFlags, expiry)
+}
+
+func NewCBAuditStore(client Client, config *config.Dcp) AuditStore {
+	if !config.IsCouchbaseMetadata() {
+		err := errors.New("unsupported metadata type")
+		logger.Log.Error("error while initialize couchbase audit store, err: %v", err)
+		panic(err)
+	}
+
+	return &cbAuditStore{
+		client:         client,
+		config:         config,
+		scopeName:      config.GetCouchbaseMetadata().Scope,
+		collectionName: config.API.Audit.Collection,
+	}
+}
+
+// expiryOf returns the expiry of a document which lives for the duration. The server takes an expiry longer than 30
+// days as a unix time.
+func expiryOf(duration time.Duration) uint32 {
+	if duration > maxRelativeExpiry {
+		return uint32(time.Now().Add(duration).Unix())
+	}
+
+	return uint32(duration.Seconds())
+}
+
+func getAuditID(groupName string, event *models.AuditEvent) []byte {
+	// _connector:cbgo:groupName:audit:timestamp:uuid
+	return []byte(
+		helpers.Prefix + groupName + ":audit:" + strconv.FormatInt(event.Timestamp.UnixNano(), 10) + ":" + uuid.New().String(),
+	)
+}
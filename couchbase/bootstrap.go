@@ -0,0 +1,122 @@
+package couchbase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/metadata"
+)
+
+// Bootstrap creates the scope and the collection of the metadata when they are missing, and checks that the member
+// can write, read and delete its documents.
+func (s *cbMetadata) Bootstrap(ctx context.Context) ([]metadata.BootstrapStep, error) {
+	bucketName := s.config.GetCouchbaseMetadata().Bucket
+	bucketPath := "/pools/default/buckets/" + url.PathEscape(bucketName)
+
+	var steps []metadata.BootstrapStep
+
+	if s.scopeName != config.DefaultScopeName {
+		status, err := s.createManifestEntry(ctx, bucketPath+"/scopes", s.scopeName)
+		if err != nil {
+			return steps, fmt.Errorf("cannot create metadata scope %s, err: %w", s.scopeName, err)
+		}
+
+		steps = append(steps, metadata.BootstrapStep{Name: "scope " + bucketName + "." + s.scopeName, Status: status})
+	}
+
+	if s.collectionName != config.DefaultCollectionName {
+		status, err := s.createManifestEntry(ctx, bucketPath+"/scopes/"+url.PathEscape(s.scopeName)+"/collections", s.collectionName)
+		if err != nil {
+			return steps, fmt.Errorf("cannot create metadata collection %s, err: %w", s.collectionName, err)
+		}
+
+		steps = append(steps, metadata.BootstrapStep{
+			Name:   "collection " + bucketName + "." + s.scopeName + "." + s.collectionName,
+			Status: status,
+		})
+	}
+
+	if err := s.checkAccess(ctx); err != nil {
+		return steps, fmt.Errorf("metadata documents cannot be written, check the data reader and writer roles, err: %w", err)
+	}
+
+	return append(steps, metadata.BootstrapStep{Name: "metadata read and write", Status: metadata.BootstrapStatusPassed}), nil
+}
+
+// createManifestEntry creates a scope or a collection with the management api, an existing one is not an error.
+func (s *cbMetadata) createManifestEntry(ctx context.Context, path string, name string) (string, error) {
+	opm := NewAsyncOp(ctx)
+
+	deadline, _ := ctx.Deadline()
+
+	ch := make(chan error, 1)
+
+	var statusCode int
+	var body []byte
+
+	op, err := s.client.GetMetaAgent().DoHTTPRequest(&gocbcore.HTTPRequest{
+		Service:     gocbcore.MgmtService,
+		Method:      http.MethodPost,
+		Path:        path,
+		Body:        []byte(url.Values{"name": []string{name}}.Encode()),
+		ContentType: "application/x-www-form-urlencoded",
+		Deadline:    deadline,
+	}, func(response *gocbcore.HTTPResponse, err error) {
+		opm.Resolve()
+
+		if err == nil {
+			statusCode = response.StatusCode
+			body, err = io.ReadAll(response.Body)
+			_ = response.Body.Close()
+		}
+
+		ch <- err
+	})
+
+	err = opm.Wait(op, err)
+	if err != nil {
+		return "", err
+	}
+
+	if err = <-ch; err != nil {
+		return "", err
+	}
+
+	if statusCode == http.StatusOK {
+		return metadata.BootstrapStatusCreated, nil
+	}
+
+	if strings.Contains(string(body), "already exists") {
+		return metadata.BootstrapStatusExists, nil
+	}
+
+	return "", fmt.Errorf("status: %d, response: %s", statusCode, body)
+}
+
+// checkAccess writes, reads and deletes a probe document. A new collection is retried till it is known by the nodes.
+func (s *cbMetadata) checkAccess(ctx context.Context) error {
+	agent := s.client.GetMetaAgent()
+
+	// _connector:cbgo:groupName:bootstrap
+	id := []byte(helpers.Prefix + s.config.Dcp.Group.Name + ":bootstrap")
+	payload := []byte(fmt.Sprintf(`{"bootstrapTime":%d}`, time.Now().UnixNano()))
+
+	if err := CreateDocument(ctx, agent, s.scopeName, s.collectionName, id, payload, helpers.JSONFlags, 0); err != nil {
+		return err
+	}
+
+	if _, err := Get(ctx, agent, s.scopeName, s.collectionName, id); err != nil {
+		return err
+	}
+
+	return DeleteDocument(ctx, agent, s.scopeName, s.collectionName, id)
+}
@@ -0,0 +1,181 @@
+package couchbase
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+const (
+	CapabilityCollections   = "collections"
+	CapabilityStreamEnd     = "streamEnd"
+	CapabilityExpiryOpcode  = "expiryOpcode"
+	CapabilitySnappy        = "snappy"
+	CapabilityPreserveTTL   = "preserveTTL"
+	CapabilityOSO           = "oso"
+	CapabilityChangeHistory = "changeHistory"
+)
+
+// negotiatedFeatures is what a dcp connection negotiated with its node. Known is false when no connection is found.
+type negotiatedFeatures struct {
+	hello     map[memd.HelloFeature]bool
+	streamEnd bool
+	known     bool
+}
+
+// NewCapabilityReport reports the features which the dcp connection negotiated with the server, useExpiryOpcode is
+// whether the dcp connection is made with the expiry opcode.
+func NewCapabilityReport(
+	client Client,
+	config *config.Dcp,
+	version *Version,
+	bucketInfo *BucketInfo,
+	useExpiryOpcode bool,
+) *models.CapabilityReport {
+	features := dcpNegotiatedFeatures(client)
+	if !features.known {
+		logger.Log.Warn("dcp connection features could not be read, they are reported as not supported")
+	}
+
+	expirySupported := version.Higher(SrvVer650) || version.Equal(SrvVer650)
+	osoSupported := IsOSOBackfillSupported(version)
+	changeHistorySupported := IsChangeHistorySupported(version, bucketInfo)
+
+	return &models.CapabilityReport{
+		Time:    time.Now(),
+		Version: version.String(),
+		Capabilities: []models.Capability{
+			{
+				Name:      CapabilityCollections,
+				Requested: isCollectionsRequested(config.ScopeName, config.CollectionNames),
+				Supported: features.hello[memd.FeatureCollections],
+				Enabled:   features.hello[memd.FeatureCollections],
+			},
+			{
+				Name:      CapabilityStreamEnd,
+				Requested: true,
+				Supported: features.streamEnd,
+				Enabled:   features.streamEnd,
+			},
+			{
+				Name:      CapabilityExpiryOpcode,
+				Requested: true,
+				Supported: expirySupported,
+				Enabled:   useExpiryOpcode,
+			},
+			{
+				Name:      CapabilitySnappy,
+				Requested: true,
+				Supported: features.hello[memd.FeatureSnappy],
+				Enabled:   features.hello[memd.FeatureSnappy],
+			},
+			{
+				Name:      CapabilityPreserveTTL,
+				Requested: true,
+				Supported: features.hello[memd.FeaturePreserveExpiry],
+				Enabled:   features.hello[memd.FeaturePreserveExpiry],
+			},
+			{
+				Name:      CapabilityOSO,
+				Requested: config.Dcp.Config.EnableOSOBackfill,
+				Supported: osoSupported,
+				Enabled:   config.Dcp.Config.EnableOSOBackfill && osoSupported,
+			},
+			{
+				Name:      CapabilityChangeHistory,
+				Requested: !config.Dcp.Config.DisableChangeStreams,
+				Supported: changeHistorySupported,
+				Enabled:   !config.Dcp.Config.DisableChangeStreams && changeHistorySupported,
+			},
+		},
+	}
+}
+
+// IsOSOBackfillSupported reports whether the server can backfill the streams out of sequence order, since 7.0.0.
+func IsOSOBackfillSupported(version *Version) bool {
+	return version.Higher(SrvVer700) || version.Equal(SrvVer700)
+}
+
+// IsChangeHistorySupported reports whether the streams of the bucket can deliver its change history, which is
+// retained by magma buckets since 7.2.0.
+func IsChangeHistorySupported(version *Version, bucketInfo *BucketInfo) bool {
+	return bucketInfo.IsMagma() && (version.Higher(SrvVer720) || version.Equal(SrvVer720))
+}
+
+func isCollectionsRequested(scopeName string, collectionNames []string) bool {
+	if scopeName != "" && scopeName != config.DefaultScopeName {
+		return true
+	}
+
+	for _, collectionName := range collectionNames {
+		if collectionName != config.DefaultCollectionName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LogCapabilityReport logs the capabilities and warns of the ones which are requested but not enabled.
+func LogCapabilityReport(report *models.CapabilityReport) {
+	logger.Log.Info("capability report of server version %s", report.Version)
+
+	for _, capability := range report.Capabilities {
+		logger.Log.Info("capability %s, requested: %v, supported: %v, enabled: %v",
+			capability.Name, capability.Requested, capability.Supported, capability.Enabled)
+
+		if capability.Requested && !capability.Enabled {
+			logger.Log.Warn("capability %s is requested but not enabled on the dcp connection, supported: %v",
+				capability.Name, capability.Supported)
+		}
+	}
+}
+
+// dcpNegotiatedFeatures reads the features of the first connected dcp connection, every connection negotiates the
+// same features with the nodes of a cluster.
+func dcpNegotiatedFeatures(client Client) negotiatedFeatures {
+	features := negotiatedFeatures{hello: map[memd.HelloFeature]bool{}}
+
+	configSnapshot, err := client.GetDcpAgentConfigSnapshot()
+	if err != nil {
+		return features
+	}
+
+	pipelines := reflect.ValueOf(configSnapshot).Elem().FieldByName("state").Elem().FieldByName("pipelines")
+
+	for i := 0; i < pipelines.Len(); i++ {
+		clients := pipelines.Index(i).Elem().FieldByName("clients")
+		if !clients.IsValid() {
+			continue
+		}
+
+		for j := 0; j < clients.Len(); j++ {
+			pipelineClient := clients.Index(j)
+			if pipelineClient.IsNil() {
+				continue
+			}
+
+			memdClient := pipelineClient.Elem().FieldByName("client")
+			if !memdClient.IsValid() || memdClient.IsNil() {
+				continue
+			}
+
+			hello := memdClient.Elem().FieldByName("features")
+			for k := 0; k < hello.Len(); k++ {
+				features.hello[memd.HelloFeature(hello.Index(k).Uint())] = true
+			}
+
+			features.streamEnd = !memdClient.Elem().FieldByName("streamEndNotSupported").Bool()
+			features.known = true
+
+			return features
+		}
+	}
+
+	return features
+}
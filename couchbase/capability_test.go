@@ -0,0 +1,70 @@
+package couchbase
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type disconnectedDcpClient struct {
+	mockClient
+}
+
+func (c *disconnectedDcpClient) GetDcpAgentConfigSnapshot() (*gocbcore.ConfigSnapshot, error) {
+	return nil, errors.New("not connected")
+}
+
+func TestNewCapabilityReport(t *testing.T) {
+	cfg := &config.Dcp{ScopeName: config.DefaultScopeName, CollectionNames: []string{"orders"}}
+
+	report := NewCapabilityReport(&disconnectedDcpClient{}, cfg, &Version{7, 1, 3, 0}, &BucketInfo{StorageBackend: "magma"}, true)
+
+	if report.Version != "7.1.3-0" {
+		t.Errorf("unexpected version %v", report.Version)
+	}
+
+	capabilities := map[string]models.Capability{}
+	for _, capability := range report.Capabilities {
+		capabilities[capability.Name] = capability
+	}
+
+	if c := capabilities[CapabilityCollections]; !c.Requested || c.Supported {
+		t.Errorf("collections must be requested and not supported without a connection, got %+v", c)
+	}
+
+	if c := capabilities[CapabilityExpiryOpcode]; !c.Requested || !c.Supported || !c.Enabled {
+		t.Errorf("expiry opcode must be enabled, got %+v", c)
+	}
+
+	if c := capabilities[CapabilityOSO]; c.Requested || !c.Supported {
+		t.Errorf("oso must be supported and not requested, got %+v", c)
+	}
+
+	if c := capabilities[CapabilityChangeHistory]; !c.Requested || c.Supported || c.Enabled {
+		t.Errorf("change history must be requested and not supported before 7.2.0, got %+v", c)
+	}
+}
+
+func TestIsChangeHistorySupported(t *testing.T) {
+	if !IsChangeHistorySupported(&Version{7, 2, 0, 0}, &BucketInfo{StorageBackend: "magma"}) {
+		t.Errorf("change history must be supported by magma buckets since 7.2.0")
+	}
+
+	if IsChangeHistorySupported(&Version{7, 6, 0, 0}, &BucketInfo{StorageBackend: "couchstore"}) {
+		t.Errorf("change history must not be supported by couchstore buckets")
+	}
+}
+
+func TestIsCollectionsRequested(t *testing.T) {
+	if isCollectionsRequested(config.DefaultScopeName, []string{config.DefaultCollectionName}) {
+		t.Errorf("default collection must not request collections")
+	}
+
+	if !isCollectionsRequested("inventory", []string{config.DefaultCollectionName}) {
+		t.Errorf("custom scope must request collections")
+	}
+}
@@ -0,0 +1,55 @@
+package couchbase
+
+import (
+	"crypto/tls"
+	"os"
+
+	"github.com/couchbase/gocbcore/v10"
+	"github.com/couchbase/gocbcore/v10/connstr"
+
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+// CertificateAuthProvider authenticates with a client certificate over tls, for the clusters whose password
+// authentication is disabled.
+type CertificateAuthProvider struct {
+	ClientCertificate *tls.Certificate
+}
+
+func (auth CertificateAuthProvider) SupportsNonTLS() bool {
+	return false
+}
+
+func (auth CertificateAuthProvider) SupportsTLS() bool {
+	return true
+}
+
+func (auth CertificateAuthProvider) Certificate(_ gocbcore.AuthCertRequest) (*tls.Certificate, error) {
+	return auth.ClientCertificate, nil
+}
+
+// Credentials returns empty credentials, the server takes the user from the certificate.
+func (auth CertificateAuthProvider) Credentials(_ gocbcore.AuthCredsRequest) ([]gocbcore.UserPassPair, error) {
+	return []gocbcore.UserPassPair{{}}, nil
+}
+
+func CreateCertificateAuthProvider(certPath string, keyPath string) CertificateAuthProvider {
+	cert, err := tls.LoadX509KeyPair(os.ExpandEnv(certPath), os.ExpandEnv(keyPath))
+	if err != nil {
+		logger.Log.Error("error while reading client certificate, err: %v", err)
+		panic(err)
+	}
+
+	return CertificateAuthProvider{ClientCertificate: &cert}
+}
+
+// isSecureHosts returns true when the hosts use the couchbases:// scheme, the Capella hosts use it by default.
+func isSecureHosts(hosts []string) bool {
+	for _, host := range hosts {
+		if parsedConnStr, err := connstr.Parse(normalizeHost(host)); err == nil && parsedConnStr.Scheme == "couchbases" {
+			return true
+		}
+	}
+
+	return false
+}
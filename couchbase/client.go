@@ -8,7 +8,9 @@ import (
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Trendyol/go-dcp/wrapper"
@@ -16,7 +18,6 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/Trendyol/go-dcp/helpers"
-	"github.com/couchbase/gocbcore/v10/connstr"
 
 	"github.com/Trendyol/go-dcp/config"
 
@@ -35,24 +36,30 @@ type Client interface {
 	GetMetaAgent() *gocbcore.Agent
 	Connect() error
 	Close()
-	DcpConnect(useExpiryOpcode bool, useChangeStreams bool) error
+	DcpConnect(useExpiryOpcode bool, useChangeStreams bool, useOSOBackfill bool) error
 	DcpClose()
 	GetVBucketSeqNos(awareCollection bool) (*wrapper.ConcurrentSwissMap[uint16, uint64], error)
 	GetNumVBuckets() int
+	GetPurgeSeqNos() (map[uint16]uint64, error)
 	GetFailOverLogs(vbID uint16) ([]gocbcore.FailoverEntry, error)
-	OpenStream(vbID uint16, collectionIDs map[uint32]string, offset *models.Offset, observer Observer) error
-	CloseStream(vbID uint16) error
+	GetFailoverLogs(vbIDs []uint16) (map[uint16][]gocbcore.FailoverEntry, error)
+	OpenStream(ctx context.Context, vbID uint16, collectionIDs map[uint32]string, offset *models.Offset, observer Observer) error
+	CloseStream(ctx context.Context, vbID uint16) error
 	GetCollectionIDs(scopeName string, collectionNames []string) (map[uint32]string, error)
 	GetAgentConfigSnapshot() (*gocbcore.ConfigSnapshot, error)
 	GetDcpAgentConfigSnapshot() (*gocbcore.ConfigSnapshot, error)
 	GetAgentQueues() []*models.AgentQueue
+	GetDcpNodeConnections() []*models.NodeConnection
 }
 
 type client struct {
-	agent     *gocbcore.Agent
-	metaAgent *gocbcore.Agent
-	dcpAgent  *gocbcore.DCPAgent
-	config    *config.Dcp
+	agent       *gocbcore.Agent
+	metaAgent   *gocbcore.Agent
+	dcpAgent    *gocbcore.DCPAgent
+	dcpAgents   []*gocbcore.DCPAgent
+	credentials CredentialsProvider
+	config      *config.Dcp
+	nodes       nodeConnectionTracker
 }
 
 func getServiceEndpoint(result *gocbcore.PingResult, serviceType gocbcore.ServiceType) string {
@@ -141,7 +148,20 @@ func (s *client) GetMetaAgent() *gocbcore.Agent {
 	return s.metaAgent
 }
 
+// CreateTLSRootCaProvider returns the certificates of rootCAPath, or the certificates of the system when it is not set.
 func CreateTLSRootCaProvider(rootCAPath string) func() *x509.CertPool {
+	if rootCAPath == "" {
+		certPool, err := x509.SystemCertPool()
+		if err != nil {
+			logger.Log.Error("error while reading system cert pool, err: %v", err)
+			panic(err)
+		}
+
+		return func() *x509.CertPool {
+			return certPool
+		}
+	}
+
 	cert, err := os.ReadFile(os.ExpandEnv(rootCAPath))
 	if err != nil {
 		logger.Log.Error("error while reading cert file, err: %v", err)
@@ -156,7 +176,11 @@ func CreateTLSRootCaProvider(rootCAPath string) func() *x509.CertPool {
 	}
 }
 
-func CreateSecurityConfig(username string, password string, secureConnection bool, rootCAPath string) gocbcore.SecurityConfig {
+// CreateSecurityConfig authenticates with the client certificate of certPath and keyPath when they are set, which
+// requires tls, and with the username and password otherwise.
+func CreateSecurityConfig(username string, password string, secureConnection bool,
+	rootCAPath string, certPath string, keyPath string,
+) gocbcore.SecurityConfig {
 	securityConfig := gocbcore.SecurityConfig{
 		Auth: gocbcore.PasswordAuthProvider{
 			Username: username,
@@ -164,6 +188,11 @@ func CreateSecurityConfig(username string, password string, secureConnection boo
 		},
 	}
 
+	if certPath != "" {
+		securityConfig.Auth = CreateCertificateAuthProvider(certPath, keyPath)
+		secureConnection = true
+	}
+
 	if secureConnection {
 		securityConfig.UseTLS = true
 		securityConfig.TLSRootCAProvider = CreateTLSRootCaProvider(rootCAPath)
@@ -173,16 +202,23 @@ func CreateSecurityConfig(username string, password string, secureConnection boo
 }
 
 func CreateAgent(httpAddresses []string, bucketName string,
-	username string, password string, secureConnection bool, rootCAPath string,
+	username string, password string, secureConnection bool, rootCAPath string, certPath string, keyPath string,
+	maxQueueSize int, poolSize int, connectionBufferSize uint, connectionTimeout time.Duration,
+) (*gocbcore.Agent, error) {
+	return createAgent(httpAddresses, bucketName,
+		CreateSecurityConfig(username, password, secureConnection || isSecureHosts(httpAddresses), rootCAPath, certPath, keyPath),
+		maxQueueSize, poolSize, connectionBufferSize, connectionTimeout,
+	)
+}
+
+func createAgent(httpAddresses []string, bucketName string, securityConfig gocbcore.SecurityConfig,
 	maxQueueSize int, poolSize int, connectionBufferSize uint, connectionTimeout time.Duration,
 ) (*gocbcore.Agent, error) {
 	agent, err := gocbcore.CreateAgent(
 		&gocbcore.AgentConfig{
-			BucketName: bucketName,
-			SeedConfig: gocbcore.SeedConfig{
-				HTTPAddrs: resolveHostsAsHTTP(httpAddresses),
-			},
-			SecurityConfig: CreateSecurityConfig(username, password, secureConnection, rootCAPath),
+			BucketName:     bucketName,
+			SeedConfig:     resolveSeedConfig(httpAddresses),
+			SecurityConfig: securityConfig,
 			CompressionConfig: gocbcore.CompressionConfig{
 				Enabled: true,
 			},
@@ -193,6 +229,10 @@ func CreateAgent(httpAddresses []string, bucketName string,
 				PoolSize:             poolSize,
 				ConnectionBufferSize: connectionBufferSize,
 				MaxQueueSize:         maxQueueSize,
+				ConnectTimeout:       connectTimeout(httpAddresses),
+			},
+			HTTPConfig: gocbcore.HTTPConfig{
+				ConnectTimeout: connectTimeout(httpAddresses),
 			},
 			DefaultRetryStrategy: gocbcore.NewBestEffortRetryStrategy(nil),
 		},
@@ -226,36 +266,23 @@ func CreateAgent(httpAddresses []string, bucketName string,
 func (s *client) connect(bucketName string,
 	maxQueueSize int, poolSize int, connectionBufferSize uint, connectionTimeout time.Duration,
 ) (*gocbcore.Agent, error) {
-	return CreateAgent(
-		s.config.Hosts, bucketName, s.config.Username, s.config.Password,
-		s.config.SecureConnection, s.config.RootCAPath,
+	return createAgent(
+		s.config.Hosts, bucketName, s.securityConfig(),
 		maxQueueSize, poolSize, connectionBufferSize, connectionTimeout,
 	)
 }
 
-func resolveHostsAsHTTP(hosts []string) []string {
-	var httpHosts []string
-	for _, host := range hosts {
-		parsedConnStr, err := connstr.Parse(host)
-		if err != nil {
-			err := errors.New(host + " " + err.Error())
-			logger.Log.Error("error while parsing connection string, err: %v", err)
-			panic(err)
-		}
-
-		out, err := connstr.Resolve(parsedConnStr)
-		if err != nil {
-			err := errors.New(parsedConnStr.String() + " " + err.Error())
-			logger.Log.Error("error while resolving connection string, err: %v", err)
-			panic(err)
-		}
+// securityConfig returns the security config of the source cluster, the credentials provider replaces the username
+// and password of the config.
+func (s *client) securityConfig() gocbcore.SecurityConfig {
+	securityConfig := CreateSecurityConfig(s.config.Username, s.config.Password,
+		s.config.SecureConnection || isSecureHosts(s.config.Hosts), s.config.RootCAPath, s.config.CertPath, s.config.KeyPath)
 
-		for _, specHost := range out.HttpHosts {
-			httpHosts = append(httpHosts, fmt.Sprintf("%s:%d", specHost.Host, specHost.Port))
-		}
+	if s.credentials != nil && s.config.CertPath == "" {
+		securityConfig.Auth = credentialsAuthProvider{provider: s.credentials}
 	}
 
-	return httpHosts
+	return securityConfig
 }
 
 func (s *client) isDcpAndMetadataSameHost() bool {
@@ -329,13 +356,25 @@ func (s *client) createMetadataAgent(couchbaseMetadataConfig *config.CouchbaseMe
 		)
 	}
 
-	return CreateAgent(
-		couchbaseMetadataConfig.Hosts,
-		couchbaseMetadataConfig.Bucket,
+	securityConfig := CreateSecurityConfig(
 		couchbaseMetadataConfig.Username,
 		couchbaseMetadataConfig.Password,
-		couchbaseMetadataConfig.SecureConnection,
+		couchbaseMetadataConfig.SecureConnection || isSecureHosts(couchbaseMetadataConfig.Hosts),
 		couchbaseMetadataConfig.RootCAPath,
+		couchbaseMetadataConfig.CertPath,
+		couchbaseMetadataConfig.KeyPath,
+	)
+
+	// the credentials provider authenticates the metadata cluster as well, unless metadata.config sets its own user
+	_, hasUsername := s.config.Metadata.Config[config.CouchbaseMetadataUsernameConfig]
+	if s.credentials != nil && !hasUsername && couchbaseMetadataConfig.CertPath == "" {
+		securityConfig.Auth = credentialsAuthProvider{provider: s.credentials}
+	}
+
+	return createAgent(
+		couchbaseMetadataConfig.Hosts,
+		couchbaseMetadataConfig.Bucket,
+		securityConfig,
 		0,
 		0,
 		couchbaseMetadataConfig.ConnectionBufferSize,
@@ -354,20 +393,27 @@ func (s *client) Close() {
 	logger.Log.Info("connections closed %s", s.config.Hosts)
 }
 
-func (s *client) DcpConnect(useExpiryOpcode bool, useChangeStreams bool) error {
+// IsUnsupportedFeature reports whether the error is returned because the server refuses a requested feature.
+func IsUnsupportedFeature(err error) bool {
+	return errors.Is(err, gocbcore.ErrFeatureNotAvailable) ||
+		errors.Is(err, gocbcore.ErrUnsupportedOperation) ||
+		errors.Is(err, gocbcore.ErrMemdNotSupported)
+}
+
+func (s *client) DcpConnect(useExpiryOpcode bool, useChangeStreams bool, useOSOBackfill bool) error {
 	agentConfig := &gocbcore.DCPAgentConfig{
-		BucketName: s.config.BucketName,
-		SeedConfig: gocbcore.SeedConfig{
-			HTTPAddrs: resolveHostsAsHTTP(s.config.Hosts),
-		},
-		SecurityConfig: CreateSecurityConfig(s.config.Username, s.config.Password, s.config.SecureConnection, s.config.RootCAPath),
+		BucketName:     s.config.BucketName,
+		SeedConfig:     resolveSeedConfig(s.config.Hosts),
+		SecurityConfig: s.securityConfig(),
 		CompressionConfig: gocbcore.CompressionConfig{
-			Enabled: true,
+			Enabled:              true,
+			DisableDecompression: s.config.Dcp.Compression.Passthrough,
 		},
 		DCPConfig: gocbcore.DCPConfig{
 			BufferSize:       helpers.ResolveUnionIntOrStringValue(s.config.Dcp.BufferSize),
 			UseExpiryOpcode:  useExpiryOpcode,
 			UseChangeStreams: useChangeStreams,
+			UseOSOBackfill:   useOSOBackfill,
 		},
 		IoConfig: gocbcore.IoConfig{
 			UseCollections: true,
@@ -375,17 +421,52 @@ func (s *client) DcpConnect(useExpiryOpcode bool, useChangeStreams bool) error {
 		KVConfig: gocbcore.KVConfig{
 			ConnectionBufferSize: uint(helpers.ResolveUnionIntOrStringValue(s.config.Dcp.ConnectionBufferSize)),
 			MaxQueueSize:         s.config.Dcp.MaxQueueSize,
+			ConnectTimeout:       connectTimeout(s.config.Hosts),
+		},
+		HTTPConfig: gocbcore.HTTPConfig{
+			ConnectTimeout: connectTimeout(s.config.Hosts),
 		},
 	}
 
+	flags := memd.DcpOpenFlagProducer
+	if useExpiryOpcode {
+		// Expiry opcode is enabled only on connections which include delete times.
+		flags |= memd.DcpOpenFlagIncludeDeleteTimes
+	}
+
+	// Each agent has a connection to every node, the vBuckets are sharded across the agents by dcpAgentOf.
+	agents := make([]*gocbcore.DCPAgent, 0, s.config.Dcp.ConnectionsPerNode)
+
+	for i := 0; i < s.config.Dcp.ConnectionsPerNode; i++ {
+		agent, err := s.connectDcpAgent(agentConfig, flags)
+		if err != nil {
+			for _, connected := range agents {
+				_ = connected.Close()
+			}
+
+			return err
+		}
+
+		agents = append(agents, agent)
+	}
+
+	s.dcpAgents = agents
+	s.dcpAgent = agents[0]
+	logger.Log.Info("connected to %s as dcp with %d connections per node, bucket: %s",
+		s.config.Hosts, len(agents), s.config.BucketName)
+
+	return nil
+}
+
+func (s *client) connectDcpAgent(agentConfig *gocbcore.DCPAgentConfig, flags memd.DcpOpenFlag) (*gocbcore.DCPAgent, error) {
 	client, err := gocbcore.CreateDcpAgent(
 		agentConfig,
 		fmt.Sprintf("%s_%s", s.config.Dcp.Group.Name, uuid.New().String()),
-		memd.DcpOpenFlagProducer,
+		flags,
 	)
 	if err != nil {
 		logger.Log.Error("error while connect to dcp, err: %v", err)
-		return err
+		return nil, err
 	}
 
 	ch := make(chan error, 1)
@@ -401,42 +482,47 @@ func (s *client) DcpConnect(useExpiryOpcode bool, useChangeStreams bool) error {
 	)
 	if err != nil {
 		logger.Log.Error("error while wait until ready to dcp, err: %v", err)
-		return err
+		_ = client.Close()
+		return nil, err
 	}
 
 	if err = <-ch; err != nil {
 		logger.Log.Error("error while wait until ready to dcp on callback, err: %v", err)
-		return err
+		_ = client.Close()
+		return nil, err
 	}
 
-	s.dcpAgent = client
-	logger.Log.Info("connected to %s as dcp, bucket: %s", s.config.Hosts, s.config.BucketName)
+	return client, nil
+}
 
-	return nil
+// dcpAgentOf returns the agent whose connections stream the vBucket.
+func (s *client) dcpAgentOf(vbID uint16) *gocbcore.DCPAgent {
+	return s.dcpAgents[int(vbID)%len(s.dcpAgents)]
 }
 
 func (s *client) GetAgentQueues() []*models.AgentQueue {
 	var configSnapshots []*gocbcore.ConfigSnapshot
-	var dcp *gocbcore.ConfigSnapshot
+	dcp := map[*gocbcore.ConfigSnapshot]bool{}
 
 	agentConfigSnapshot, err := s.GetAgentConfigSnapshot()
 	if err == nil {
 		configSnapshots = append(configSnapshots, agentConfigSnapshot)
 	}
-	dcpAgentConfigSnapshot, err := s.GetDcpAgentConfigSnapshot()
-	if err == nil {
-		dcp = dcpAgentConfigSnapshot
-		configSnapshots = append(configSnapshots, dcp)
+
+	for _, agent := range s.dcpAgents {
+		dcpAgentConfigSnapshot, err := agent.ConfigSnapshot()
+		if err == nil {
+			dcp[dcpAgentConfigSnapshot] = true
+			configSnapshots = append(configSnapshots, dcpAgentConfigSnapshot)
+		}
 	}
 
 	clientQueue := make([]*models.AgentQueue, 0)
+	dcpQueues := map[string]*models.AgentQueue{}
 
 	for i := range configSnapshots {
 		configSnapshot := configSnapshots[i]
-		var isDcp bool
-		if configSnapshot == dcp {
-			isDcp = true
-		}
+		isDcp := dcp[configSnapshot]
 
 		snapshot := reflect.ValueOf(configSnapshot).Elem()
 		state := snapshot.FieldByName("state").Elem()
@@ -452,12 +538,25 @@ func (s *client) GetAgentQueues() []*models.AgentQueue {
 			items := queue.FieldByName("items").Elem()
 			current := items.FieldByName("len").Int()
 
-			clientQueue = append(clientQueue, &models.AgentQueue{
+			// the queues of the dcp agents sharing a node are summed, connectionsPerNode agents connect to each node
+			if agentQueue, ok := dcpQueues[address]; ok && isDcp {
+				agentQueue.Current += int(current)
+				agentQueue.Max += int(max)
+				continue
+			}
+
+			agentQueue := &models.AgentQueue{
 				Address: address,
 				IsDcp:   isDcp,
 				Current: int(current),
 				Max:     int(max),
-			})
+			}
+
+			if isDcp {
+				dcpQueues[address] = agentQueue
+			}
+
+			clientQueue = append(clientQueue, agentQueue)
 		}
 	}
 
@@ -465,7 +564,9 @@ func (s *client) GetAgentQueues() []*models.AgentQueue {
 }
 
 func (s *client) DcpClose() {
-	_ = s.dcpAgent.Close()
+	for _, agent := range s.dcpAgents {
+		_ = agent.Close()
+	}
 	logger.Log.Info("dcp connection closed %s", s.config.Hosts)
 }
 
@@ -481,9 +582,14 @@ func (s *client) GetVBucketSeqNos(awareCollection bool) (*wrapper.ConcurrentSwis
 		return nil, err
 	}
 
+	numVBuckets, err := snapshot.NumVbuckets()
+	if err != nil {
+		return nil, err
+	}
+
 	eg := errgroup.Group{}
 
-	seqNos := wrapper.CreateConcurrentSwissMap[uint16, uint64](1024)
+	seqNos := wrapper.CreateConcurrentSwissMap[uint16, uint64](s.config.GetMapInitialSize(numVBuckets))
 
 	hasCollectionSupport := awareCollection && s.dcpAgent.HasCollectionsSupport()
 
@@ -545,6 +651,72 @@ func (s *client) GetVBucketSeqNos(awareCollection bool) (*wrapper.ConcurrentSwis
 	return seqNos, nil
 }
 
+// GetPurgeSeqNos returns the purge seqnos of the active vBuckets from vbucket-details stats of the nodes.
+func (s *client) GetPurgeSeqNos() (map[uint16]uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	defer cancel()
+
+	opm := NewAsyncOp(ctx)
+
+	deadline, _ := ctx.Deadline()
+
+	ch := make(chan error, 1)
+	purgeSeqNos := map[uint16]uint64{}
+
+	op, err := s.agent.Stats(gocbcore.StatsOptions{
+		Key:      "vbucket-details",
+		Deadline: deadline,
+	}, func(result *gocbcore.StatsResult, err error) {
+		opm.Resolve()
+
+		if err == nil {
+			for _, server := range result.Servers {
+				if server.Error != nil {
+					err = server.Error
+					break
+				}
+
+				collectPurgeSeqNos(server.Stats, purgeSeqNos)
+			}
+		}
+
+		ch <- err
+	})
+
+	err = opm.Wait(op, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = <-ch; err != nil {
+		return nil, err
+	}
+
+	return purgeSeqNos, nil
+}
+
+// collectPurgeSeqNos reads stats like "vb_12": "active" and "vb_12:purge_seqno": "42".
+func collectPurgeSeqNos(stats map[string]string, purgeSeqNos map[uint16]uint64) {
+	for key, value := range stats {
+		vb, stat, found := strings.Cut(strings.TrimPrefix(key, "vb_"), ":")
+		if !found || stat != "purge_seqno" || stats["vb_"+vb] != "active" {
+			continue
+		}
+
+		vbID, err := strconv.ParseUint(vb, 10, 16)
+		if err != nil {
+			continue
+		}
+
+		purgeSeqNo, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		purgeSeqNos[uint16(vbID)] = purgeSeqNo
+	}
+}
+
 func (s *client) GetNumVBuckets() int {
 	snapshot, err := s.GetDcpAgentConfigSnapshot()
 	if err != nil {
@@ -579,7 +751,7 @@ func (s *client) GetFailOverLogs(vbID uint16) ([]gocbcore.FailoverEntry, error)
 
 	var failOverLogs []gocbcore.FailoverEntry
 
-	op, err := s.dcpAgent.GetFailoverLog(
+	op, err := s.dcpAgentOf(vbID).GetFailoverLog(
 		vbID,
 		func(entries []gocbcore.FailoverEntry, err error) {
 			failOverLogs = entries
@@ -597,14 +769,50 @@ func (s *client) GetFailOverLogs(vbID uint16) ([]gocbcore.FailoverEntry, error)
 	return failOverLogs, <-ch
 }
 
-func (s *client) openStreamWithRollback(vbID uint16,
+// failoverLogConcurrency is how many failover logs are requested at once by GetFailoverLogs.
+const failoverLogConcurrency = 32
+
+// GetFailoverLogs returns the failover logs of the vBuckets, the newest entry of a log is the first one.
+func (s *client) GetFailoverLogs(vbIDs []uint16) (map[uint16][]gocbcore.FailoverEntry, error) {
+	eg := errgroup.Group{}
+	eg.SetLimit(failoverLogConcurrency)
+
+	var lock sync.Mutex
+	failoverLogs := make(map[uint16][]gocbcore.FailoverEntry, len(vbIDs))
+
+	for _, vbID := range vbIDs {
+		vbID := vbID
+
+		eg.Go(func() error {
+			entries, err := s.GetFailOverLogs(vbID)
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			failoverLogs[vbID] = entries
+			lock.Unlock()
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return failoverLogs, nil
+}
+
+func (s *client) openStreamWithRollback(ctx context.Context,
+	vbID uint16,
 	failedSeqNo gocbcore.SeqNo,
 	rollbackSeqNo gocbcore.SeqNo,
 	latestSeqNo gocbcore.SeqNo,
 	observer Observer,
 	openStreamOptions gocbcore.OpenStreamOptions,
 ) error {
-	logger.Log.Info(
+	logger.Ctx(ctx).Info(
 		"open stream with rollback, vbID: %d, failedSeqNo: %d, rollbackSeqNo: %d",
 		vbID, failedSeqNo, rollbackSeqNo,
 	)
@@ -624,14 +832,16 @@ func (s *client) openStreamWithRollback(vbID uint16,
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
 	defer cancel()
 
 	opm := NewAsyncOp(ctx)
 
 	ch := make(chan error, 1)
 
-	op, err := s.dcpAgent.OpenStream(
+	observer.SetStartSeqNo(rollbackSeqNo)
+
+	op, err := s.dcpAgentOf(vbID).OpenStream(
 		vbID,
 		0,
 		targetUUID,
@@ -662,15 +872,16 @@ func (s *client) openStreamWithRollback(vbID uint16,
 }
 
 func (s *client) OpenStream(
+	ctx context.Context,
 	vbID uint16,
 	collectionIDs map[uint32]string,
 	offset *models.Offset,
 	observer Observer,
 ) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	opCtx, cancel := context.WithTimeout(ctx, time.Minute)
 	defer cancel()
 
-	opm := NewAsyncOp(ctx)
+	opm := NewAsyncOp(opCtx)
 
 	openStreamOptions := gocbcore.OpenStreamOptions{}
 
@@ -692,7 +903,9 @@ func (s *client) OpenStream(
 
 	ch := make(chan error, 1)
 
-	op, err := s.dcpAgent.OpenStream(
+	observer.SetStartSeqNo(gocbcore.SeqNo(offset.SeqNo))
+
+	op, err := s.dcpAgentOf(vbID).OpenStream(
 		vbID,
 		0x80,
 		offset.VbUUID,
@@ -721,23 +934,23 @@ func (s *client) OpenStream(
 	err = <-ch
 	if err != nil {
 		if rollbackErr, ok := err.(gocbcore.DCPRollbackError); ok {
-			logger.Log.Info("need to rollback for vbID: %d, vbUUID: %d", vbID, offset.VbUUID)
-			return s.openStreamWithRollback(vbID, gocbcore.SeqNo(offset.SeqNo), rollbackErr.SeqNo,
+			logger.Ctx(ctx).Info("need to rollback for vbID: %d, vbUUID: %d", vbID, offset.VbUUID)
+			return s.openStreamWithRollback(ctx, vbID, gocbcore.SeqNo(offset.SeqNo), rollbackErr.SeqNo,
 				gocbcore.SeqNo(offset.LatestSeqNo), observer, openStreamOptions)
 		}
 	}
 	return err
 }
 
-func (s *client) CloseStream(vbID uint16) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+func (s *client) CloseStream(ctx context.Context, vbID uint16) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
 	defer cancel()
 
 	opm := NewAsyncOp(ctx)
 
 	ch := make(chan error, 1)
 
-	op, err := s.dcpAgent.CloseStream(
+	op, err := s.dcpAgentOf(vbID).CloseStream(
 		vbID,
 		gocbcore.CloseStreamOptions{},
 		func(err error) {
@@ -808,8 +1021,9 @@ func (s *client) GetCollectionIDs(scopeName string, collectionNames []string) (m
 
 func NewClient(config *config.Dcp) Client {
 	return &client{
-		agent:    nil,
-		dcpAgent: nil,
-		config:   config,
+		agent:       nil,
+		dcpAgent:    nil,
+		credentials: newCredentialsProvider(config),
+		config:      config,
 	}
 }
@@ -1,8 +1,11 @@
 package couchbase
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/couchbase/gocbcore/v10"
 )
 
 func TestClient_ResolveHttpAddress(t *testing.T) {
@@ -62,3 +65,105 @@ func TestClient_ResolveHttpAddress(t *testing.T) {
 		}
 	})
 }
+
+func TestClient_IsSecureHosts(t *testing.T) {
+	if !isSecureHosts([]string{"couchbases://localhost"}) {
+		t.Errorf("couchbases scheme must be secure")
+	}
+
+	if isSecureHosts([]string{"couchbase://localhost", "localhost:8091"}) {
+		t.Errorf("couchbase scheme must not be secure")
+	}
+}
+
+func TestClient_NormalizeHost(t *testing.T) {
+	if got := normalizeHost("cb.abc.cloud.couchbase.com"); got != "couchbases://cb.abc.cloud.couchbase.com" {
+		t.Errorf("capella host must use couchbases scheme, got %v", got)
+	}
+
+	for _, host := range []string{
+		"couchbase://cb.abc.cloud.couchbase.com",
+		"cb.abc.cloud.couchbase.com:18091",
+		"localhost:8091",
+	} {
+		if got := normalizeHost(host); got != host {
+			t.Errorf("host must not be changed, got %v want %v", got, host)
+		}
+	}
+
+	if !isSecureHosts([]string{"cb.abc.cloud.couchbase.com"}) {
+		t.Errorf("capella host must be secure")
+	}
+
+	if connectTimeout([]string{"localhost:8091"}) != 0 || connectTimeout([]string{"cb.abc.cloud.couchbase.com"}) != cloudConnectTimeout {
+		t.Errorf("only capella hosts must raise the connect timeout")
+	}
+}
+
+func TestIsUnsupportedFeature(t *testing.T) {
+	tests := []struct {
+		err      error
+		name     string
+		expected bool
+	}{
+		{name: "nil"},
+		{name: "feature not available", err: gocbcore.ErrFeatureNotAvailable, expected: true},
+		{name: "unsupported operation", err: gocbcore.ErrUnsupportedOperation, expected: true},
+		{name: "wrapped not supported status", err: fmt.Errorf("dcp control: %w", gocbcore.ErrMemdNotSupported), expected: true},
+		{name: "timeout", err: gocbcore.ErrTimeout},
+		{name: "authentication", err: gocbcore.ErrAuthenticationFailure},
+	}
+
+	for _, tt := range tests {
+		if actual := IsUnsupportedFeature(tt.err); actual != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestCollectPurgeSeqNos(t *testing.T) {
+	tests := []struct {
+		stats    map[string]string
+		expected map[uint16]uint64
+		name     string
+	}{
+		{
+			name:     "active vBuckets",
+			stats:    map[string]string{"vb_0": "active", "vb_0:purge_seqno": "42", "vb_12": "active", "vb_12:purge_seqno": "0"},
+			expected: map[uint16]uint64{0: 42, 12: 0},
+		},
+		{
+			name:     "replica vBucket",
+			stats:    map[string]string{"vb_1": "replica", "vb_1:purge_seqno": "42"},
+			expected: map[uint16]uint64{},
+		},
+		{
+			name:     "missing state",
+			stats:    map[string]string{"vb_1:purge_seqno": "42"},
+			expected: map[uint16]uint64{},
+		},
+		{
+			name:     "other stats",
+			stats:    map[string]string{"vb_1": "active", "vb_1:high_seqno": "42", "vb_1:uuid": "7"},
+			expected: map[uint16]uint64{},
+		},
+		{
+			name: "invalid numbers",
+			stats: map[string]string{
+				"vb_1": "active", "vb_1:purge_seqno": "abc",
+				"vb_x": "active", "vb_x:purge_seqno": "42",
+				"vb_70000": "active", "vb_70000:purge_seqno": "42",
+			},
+			expected: map[uint16]uint64{},
+		},
+	}
+
+	for _, tt := range tests {
+		purgeSeqNos := map[uint16]uint64{}
+		collectPurgeSeqNos(tt.stats, purgeSeqNos)
+
+		if !reflect.DeepEqual(purgeSeqNos, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, purgeSeqNos)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package couchbase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/couchbase/gocbcore/v10"
+	"github.com/couchbase/gocbcore/v10/connstr"
+)
+
+const (
+	cloudHostSuffix = ".cloud.couchbase.com"
+
+	// cloudConnectTimeout is the connect timeout of the hosts reached over the WAN, the defaults of gocbcore are
+	// tuned for the hosts in the same network.
+	cloudConnectTimeout = 20 * time.Second
+)
+
+// isCloudHost returns true when the host is a Capella connection string, e.g. cb.<id>.cloud.couchbase.com.
+func isCloudHost(host string) bool {
+	parsedConnStr, err := connstr.Parse(host)
+	if err != nil || len(parsedConnStr.Addresses) != 1 {
+		return false
+	}
+
+	return strings.HasSuffix(strings.ToLower(parsedConnStr.Addresses[0].Host), cloudHostSuffix)
+}
+
+func isCloudHosts(hosts []string) bool {
+	for _, host := range hosts {
+		if isCloudHost(host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeHost adds the couchbases:// scheme to the Capella hosts without a scheme and a port, so they are resolved
+// by their SRV record and connected with TLS.
+func normalizeHost(host string) string {
+	if !isCloudHost(host) {
+		return host
+	}
+
+	parsedConnStr, err := connstr.Parse(host)
+	if err != nil || parsedConnStr.Scheme != "" || parsedConnStr.Addresses[0].Port != -1 {
+		return host
+	}
+
+	return "couchbases://" + host
+}
+
+// resolveSeedConfig resolves the hosts to the seed config of the agents. The hosts which have an SRV record are seeded
+// with the memcached addresses of the record, since the record has no http addresses.
+func resolveSeedConfig(hosts []string) gocbcore.SeedConfig {
+	var seedConfig gocbcore.SeedConfig
+	for _, host := range hosts {
+		parsedConnStr, err := connstr.Parse(normalizeHost(host))
+		if err != nil {
+			err := errors.New(host + " " + err.Error())
+			logger.Log.Error("error while parsing connection string, err: %v", err)
+			panic(err)
+		}
+
+		out, err := connstr.Resolve(parsedConnStr)
+		if err != nil {
+			err := errors.New(parsedConnStr.String() + " " + err.Error())
+			logger.Log.Error("error while resolving connection string, err: %v", err)
+			panic(err)
+		}
+
+		for _, specHost := range out.HttpHosts {
+			seedConfig.HTTPAddrs = append(seedConfig.HTTPAddrs, fmt.Sprintf("%s:%d", specHost.Host, specHost.Port))
+		}
+
+		if out.SrvRecord != nil {
+			for _, specHost := range out.MemdHosts {
+				seedConfig.MemdAddrs = append(seedConfig.MemdAddrs, fmt.Sprintf("%s:%d", specHost.Host, specHost.Port))
+			}
+
+			seedConfig.SRVRecord = &gocbcore.SRVRecord{
+				Proto:  out.SrvRecord.Proto,
+				Scheme: out.SrvRecord.Scheme,
+				Host:   out.SrvRecord.Host,
+			}
+
+			logger.Log.Debug("resolved %s by srv record to %v", host, seedConfig.MemdAddrs)
+		}
+	}
+
+	return seedConfig
+}
+
+func resolveHostsAsHTTP(hosts []string) []string {
+	return resolveSeedConfig(hosts).HTTPAddrs
+}
+
+// connectTimeout returns the connect timeout of the hosts, zero keeps the default of gocbcore.
+func connectTimeout(hosts []string) time.Duration {
+	if isCloudHosts(hosts) {
+		return cloudConnectTimeout
+	}
+
+	return 0
+}
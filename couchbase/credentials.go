@@ -0,0 +1,182 @@
+package couchbase
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+// tokenFetchTimeout bounds a fetch of a token from the identity provider.
+const tokenFetchTimeout = 30 * time.Second
+
+// CredentialsProvider returns the username and password of the couchbase connections instead of the config, e.g. a
+// bearer token of an identity provider as the password of an LDAP-backed or Capella cluster. It is asked for each
+// new connection and http request, so a provider can refresh its token before it expires.
+type CredentialsProvider interface {
+	Credentials() (username string, password string, err error)
+}
+
+var ErrCredentialsProviderNotRegistered = errors.New("credentials provider is not registered")
+
+var (
+	credentialsProviders     = map[string]CredentialsProvider{}
+	credentialsProvidersLock sync.RWMutex
+)
+
+// RegisterCredentialsProvider makes a provider selectable with credentialsProvider config. It is meant to be called
+// from init functions and panics when the name is registered already or the provider is nil.
+func RegisterCredentialsProvider(name string, provider CredentialsProvider) {
+	credentialsProvidersLock.Lock()
+	defer credentialsProvidersLock.Unlock()
+
+	if provider == nil {
+		panic("couchbase: register credentials provider is nil for " + name)
+	}
+
+	if _, ok := credentialsProviders[name]; ok {
+		panic("couchbase: register credentials provider called twice for " + name)
+	}
+
+	credentialsProviders[name] = provider
+}
+
+// GetCredentialsProvider returns the registered provider.
+func GetCredentialsProvider(name string) (CredentialsProvider, error) {
+	credentialsProvidersLock.RLock()
+	defer credentialsProvidersLock.RUnlock()
+
+	provider, ok := credentialsProviders[name]
+	if !ok {
+		return nil, ErrCredentialsProviderNotRegistered
+	}
+
+	return provider, nil
+}
+
+// CredentialsProviders returns the names of the registered providers.
+func CredentialsProviders() []string {
+	credentialsProvidersLock.RLock()
+	defer credentialsProvidersLock.RUnlock()
+
+	names := make([]string, 0, len(credentialsProviders))
+	for name := range credentialsProviders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// newCredentialsProvider returns the provider of credentialsProvider config, nil when it is not set.
+func newCredentialsProvider(config *config.Dcp) CredentialsProvider {
+	if config.CredentialsProvider == "" {
+		return nil
+	}
+
+	provider, err := GetCredentialsProvider(config.CredentialsProvider)
+	if err != nil {
+		logger.Log.Error("error while try to use credentials provider: %s, registered: %v, err: %v",
+			config.CredentialsProvider, CredentialsProviders(), err)
+		panic(err)
+	}
+
+	if !config.SecureConnection && config.CertPath == "" && !isSecureHosts(config.Hosts) {
+		logger.Log.Warn("credentials provider is used without tls, tokens need PLAIN authentication which requires tls")
+	}
+
+	return provider
+}
+
+// credentialsOf returns the credentials provider of the client, nil when it uses the config.
+func credentialsOf(c Client) CredentialsProvider {
+	if cbClient, ok := c.(*client); ok {
+		return cbClient.credentials
+	}
+
+	return nil
+}
+
+// credentialsAuthProvider asks the credentials of each connection to the provider.
+type credentialsAuthProvider struct {
+	provider CredentialsProvider
+}
+
+func (auth credentialsAuthProvider) SupportsNonTLS() bool {
+	return true
+}
+
+func (auth credentialsAuthProvider) SupportsTLS() bool {
+	return true
+}
+
+func (auth credentialsAuthProvider) Certificate(_ gocbcore.AuthCertRequest) (*tls.Certificate, error) {
+	return nil, nil //nolint:nilnil
+}
+
+func (auth credentialsAuthProvider) Credentials(_ gocbcore.AuthCredsRequest) ([]gocbcore.UserPassPair, error) {
+	username, password, err := auth.provider.Credentials()
+	if err != nil {
+		logger.Log.Error("error while get credentials, err: %v", err)
+		return nil, err
+	}
+
+	return []gocbcore.UserPassPair{{Username: username, Password: password}}, nil
+}
+
+// TokenFetcher fetches a bearer token and its expiry time from an identity provider.
+type TokenFetcher func(ctx context.Context) (token string, expiresAt time.Time, err error)
+
+type tokenCredentialsProvider struct {
+	expiresAt     time.Time
+	fetch         TokenFetcher
+	username      string
+	token         string
+	refreshBefore time.Duration
+	lock          sync.Mutex
+}
+
+// Credentials returns the token as the password of the username. A new token is fetched refreshBefore the expiry of
+// the current one, which is still used while the fetch fails and it has not expired.
+func (p *tokenCredentialsProvider) Credentials() (string, string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.token != "" && time.Now().Add(p.refreshBefore).Before(p.expiresAt) {
+		return p.username, p.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenFetchTimeout)
+	defer cancel()
+
+	token, expiresAt, err := p.fetch(ctx)
+	if err != nil {
+		if p.token != "" && time.Now().Before(p.expiresAt) {
+			logger.Log.Warn("error while refresh token, current token expires at %v, err: %v", p.expiresAt, err)
+			return p.username, p.token, nil
+		}
+
+		return "", "", err
+	}
+
+	p.token, p.expiresAt = token, expiresAt
+
+	return p.username, p.token, nil
+}
+
+// NewTokenCredentialsProvider returns a provider which authenticates the username with the tokens of fetch.
+func NewTokenCredentialsProvider(username string, refreshBefore time.Duration, fetch TokenFetcher) CredentialsProvider {
+	return &tokenCredentialsProvider{
+		username:      username,
+		refreshBefore: refreshBefore,
+		fetch:         fetch,
+	}
+}
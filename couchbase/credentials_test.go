@@ -0,0 +1,71 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+func TestTokenCredentialsProvider(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	fetched := 0
+	expiresIn := time.Hour
+	var fetchErr error
+
+	provider := NewTokenCredentialsProvider("user", time.Minute, func(_ context.Context) (string, time.Time, error) {
+		if fetchErr != nil {
+			return "", time.Time{}, fetchErr
+		}
+
+		fetched++
+		return "token-" + strconv.Itoa(fetched), time.Now().Add(expiresIn), nil
+	})
+
+	username, password, err := provider.Credentials()
+	if err != nil || username != "user" || password != "token-1" {
+		t.Fatalf("token must be fetched, username: %s, password: %s, err: %v", username, password, err)
+	}
+
+	if _, password, _ = provider.Credentials(); password != "token-1" || fetched != 1 {
+		t.Errorf("valid token must be reused, password: %s, fetched: %d", password, fetched)
+	}
+
+	provider.(*tokenCredentialsProvider).expiresAt = time.Now().Add(30 * time.Second)
+
+	fetchErr = errors.New("identity provider is down")
+	if _, password, err = provider.Credentials(); err != nil || password != "token-1" {
+		t.Errorf("unexpired token must be used while refresh fails, password: %s, err: %v", password, err)
+	}
+
+	fetchErr = nil
+	if _, password, _ = provider.Credentials(); password != "token-2" {
+		t.Errorf("token must be refreshed before it expires, password: %s", password)
+	}
+}
+
+func TestCredentialsAuthProvider(t *testing.T) {
+	RegisterCredentialsProvider("test", NewTokenCredentialsProvider("user", 0, func(_ context.Context) (string, time.Time, error) {
+		return "token", time.Now().Add(time.Hour), nil
+	}))
+
+	provider, err := GetCredentialsProvider("test")
+	if err != nil {
+		t.Fatalf("registered provider must be returned, err: %v", err)
+	}
+
+	creds, err := credentialsAuthProvider{provider: provider}.Credentials(gocbcore.AuthCredsRequest{})
+	if err != nil || len(creds) != 1 || creds[0].Username != "user" || creds[0].Password != "token" {
+		t.Errorf("credentials are not set to expected values, creds: %v, err: %v", creds, err)
+	}
+
+	if _, err = GetCredentialsProvider("unknown"); !errors.Is(err, ErrCredentialsProviderNotRegistered) {
+		t.Errorf("unknown provider must not be returned, err: %v", err)
+	}
+}
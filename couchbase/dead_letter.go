@@ -0,0 +1,64 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type cbDeadLetterQueue struct {
+	client         Client
+	config         *config.Dcp
+	scopeName      string
+	collectionName string
+}
+
+func (q *cbDeadLetterQueue) Write(letter *models.DeadLetter) error {
+	ctx, cancel := context.WithTimeout(context.Background(), q.config.Checkpoint.Timeout)
+	defer cancel()
+
+	payload, err := sonic.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	id := getDeadLetterID(q.config.Dcp.Group.Name, letter)
+
+	return CreateDocument(ctx, q.client.GetMetaAgent(), q.scopeName, q.collectionName, id, payload, helpers.JSONFlags, 0)
+}
+
+func NewCBDeadLetterQueue(client Client, config *config.Dcp) models.DeadLetterQueue {
+	if !config.IsCouchbaseMetadata() {
+		err := errors.New("unsupported metadata type")
+		logger.Log.Error("error while initialize couchbase dead letter queue, err: %v", err)
+		panic(err)
+	}
+
+	couchbaseMetadataConfig := config.GetCouchbaseMetadata()
+
+	collectionName := config.DeadLetter.Collection
+	if collectionName == "" {
+		collectionName = couchbaseMetadataConfig.Collection
+	}
+
+	return &cbDeadLetterQueue{
+		client:         client,
+		config:         config,
+		scopeName:      couchbaseMetadataConfig.Scope,
+		collectionName: collectionName,
+	}
+}
+
+func getDeadLetterID(groupName string, letter *models.DeadLetter) []byte {
+	// _connector:cbgo:groupName:deadLetter:vbID:seqNo, an event streamed again overwrites its letter
+	return []byte(
+		helpers.Prefix + groupName + ":deadLetter:" + strconv.Itoa(int(letter.VbID)) + ":" + strconv.FormatUint(letter.SeqNo, 10),
+	)
+}
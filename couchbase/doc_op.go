@@ -47,6 +47,45 @@ func CreateDocument(ctx context.Context,
 	return <-ch
 }
 
+// InsertDocument creates the document only when it does not exist, gocbcore.ErrDocumentExists is returned otherwise.
+func InsertDocument(ctx context.Context,
+	agent *gocbcore.Agent,
+	scopeName string,
+	collectionName string,
+	id []byte,
+	value []byte,
+	flags uint32,
+	expiry uint32,
+) error {
+	opm := NewAsyncOp(ctx)
+
+	deadline, _ := ctx.Deadline()
+
+	ch := make(chan error, 1)
+
+	op, err := agent.Add(gocbcore.AddOptions{
+		Key:            id,
+		Value:          value,
+		Flags:          flags,
+		Deadline:       deadline,
+		Expiry:         expiry,
+		ScopeName:      scopeName,
+		CollectionName: collectionName,
+		RetryStrategy:  gocbcore.NewBestEffortRetryStrategy(nil),
+	}, func(result *gocbcore.StoreResult, err error) {
+		opm.Resolve()
+
+		ch <- err
+	})
+
+	err = opm.Wait(op, err)
+	if err != nil {
+		return err
+	}
+
+	return <-ch
+}
+
 func UpdateDocument(ctx context.Context,
 	agent *gocbcore.Agent,
 	scopeName string,
@@ -0,0 +1,204 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+var ErrGroupNotFound = errors.New("group not found")
+
+// GroupInspector reads the consumer groups which are registered in the metadata collection.
+// Lag is approximated against the high seqnos of the bucket this client is connected to.
+type GroupInspector interface {
+	List(ctx context.Context) ([]*models.GroupInfo, error)
+	Get(ctx context.Context, groupName string) (*models.GroupInfo, error)
+}
+
+type groupInspector struct {
+	client           Client
+	membershipConfig *config.CouchbaseMembership
+	codec            *metadata.Codec
+	scopeName        string
+	collectionName   string
+}
+
+func (g *groupInspector) List(ctx context.Context) ([]*models.GroupInfo, error) {
+	groups, _, err := getGroupRegistry(ctx, g.client, g.scopeName, g.collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	seqNos, err := g.client.GetVBucketSeqNos(false)
+	if err != nil {
+		return nil, err
+	}
+
+	vbSeqNos := seqNos.ToMap()
+	infos := make([]*models.GroupInfo, 0, len(groups))
+
+	for groupName, lastCheckpoint := range groups {
+		info, err := g.inspect(ctx, groupName, lastCheckpoint, vbSeqNos)
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos, nil
+}
+
+func (g *groupInspector) Get(ctx context.Context, groupName string) (*models.GroupInfo, error) {
+	groups, _, err := getGroupRegistry(ctx, g.client, g.scopeName, g.collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	lastCheckpoint, ok := groups[groupName]
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+
+	seqNos, err := g.client.GetVBucketSeqNos(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.inspect(ctx, groupName, lastCheckpoint, seqNos.ToMap())
+}
+
+func (g *groupInspector) inspect(
+	ctx context.Context,
+	groupName string,
+	lastCheckpoint int64,
+	seqNos map[uint16]uint64,
+) (*models.GroupInfo, error) {
+	memberCount, err := g.countMembers(ctx, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &models.GroupInfo{
+		Name:               groupName,
+		LastCheckpointTime: time.Unix(0, lastCheckpoint),
+		MemberCount:        memberCount,
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(len(seqNos))
+
+	for vbID, seqNo := range seqNos {
+		go func(vbID uint16, seqNo uint64) {
+			defer wg.Done()
+
+			var checkpoint uint64
+			found := false
+
+			data, err := GetXattrs(ctx, g.client.GetMetaAgent(), g.scopeName, g.collectionName, getCheckpointID(vbID, groupName), helpers.Name)
+			if err == nil {
+				data, err = g.codec.Decode(data)
+			}
+
+			if err == nil {
+				var doc *models.CheckpointDocument
+				if err = sonic.Unmarshal(data, &doc); err == nil && doc != nil && doc.Checkpoint != nil {
+					checkpoint = doc.Checkpoint.SeqNo
+					found = true
+				}
+			} else if !isKeyNotFound(err) {
+				logger.Log.Debug("error while inspect checkpoint, group: %s, vbID: %d, err: %v", groupName, vbID, err)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if found {
+				info.CheckpointCount++
+			}
+
+			if seqNo > checkpoint {
+				info.Lag += seqNo - checkpoint
+			}
+		}(vbID, seqNo)
+	}
+
+	wg.Wait()
+
+	return info, ctx.Err()
+}
+
+func (g *groupInspector) countMembers(ctx context.Context, groupName string) (int, error) {
+	doc, err := Get(ctx, g.client.GetMetaAgent(), g.scopeName, g.collectionName, getInstanceIndexID(groupName))
+	if err != nil {
+		if isKeyNotFound(err) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	all := map[string]int64{}
+
+	err = sonic.Unmarshal(doc.Value, &all)
+	if err != nil {
+		return 0, err
+	}
+
+	upperWaitLimit := g.membershipConfig.HeartbeatInterval + g.membershipConfig.HeartbeatToleranceDuration
+	threshold := time.Now().Add(-upperWaitLimit).UnixNano()
+
+	count := 0
+
+	for id := range all {
+		instanceDoc, err := Get(ctx, g.client.GetMetaAgent(), g.scopeName, g.collectionName, []byte(id))
+		if err != nil {
+			if isKeyNotFound(err) {
+				continue
+			}
+
+			return 0, err
+		}
+
+		var instance Instance
+		if err = unmarshalInstance(g.codec, instanceDoc.Value, &instance); err == nil && instance.HeartbeatTime >= threshold {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func NewGroupInspector(client Client, config *config.Dcp) GroupInspector {
+	if !config.IsCouchbaseMetadata() {
+		err := errors.New("unsupported metadata type")
+		logger.Log.Error("error while initialize group inspector, err: %v", err)
+		panic(err)
+	}
+
+	couchbaseMetadataConfig := config.GetCouchbaseMetadata()
+
+	return &groupInspector{
+		client:           client,
+		membershipConfig: config.GetCouchbaseMembership(),
+		codec:            newCodec(config),
+		scopeName:        couchbaseMetadataConfig.Scope,
+		collectionName:   couchbaseMetadataConfig.Collection,
+	}
+}
@@ -0,0 +1,131 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/couchbase/gocbcore/v10"
+	"github.com/couchbase/gocbcore/v10/memd"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+// The group registry is a single metadata document which maps every group name
+// to the unix nano time of its latest checkpoint or heartbeat.
+func getGroupRegistryID() []byte {
+	// _connector:cbgo:groups
+	return []byte(helpers.Prefix + "groups")
+}
+
+func getInstanceIndexID(groupName string) []byte {
+	// _connector:cbgo:groupName:instance:all
+	return []byte(helpers.Prefix + groupName + ":" + _type + ":all")
+}
+
+func touchGroup(ctx context.Context, client Client, scopeName string, collectionName string, groupName string) error {
+	payload, _ := sonic.Marshal(time.Now().UnixNano())
+
+	return CreatePath(
+		ctx, client.GetMetaAgent(), scopeName, collectionName, getGroupRegistryID(), []byte(groupName), payload, memd.SubdocDocFlagMkDoc,
+	)
+}
+
+// GroupHeartbeat touches the group registry in every groupTouchInterval while the group runs, so the metadata gc
+// of other groups does not take an idle group without checkpoints to save for a dead one.
+type GroupHeartbeat interface {
+	Start()
+	Stop()
+}
+
+type groupHeartbeat struct {
+	client         Client
+	config         *config.Dcp
+	cancelFunc     context.CancelFunc
+	scopeName      string
+	collectionName string
+	wg             sync.WaitGroup
+	startOnce      sync.Once
+	stopOnce       sync.Once
+}
+
+func (h *groupHeartbeat) Start() {
+	h.startOnce.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancelFunc = cancel
+		h.wg.Add(1)
+		go h.run(ctx)
+	})
+}
+
+func (h *groupHeartbeat) Stop() {
+	h.stopOnce.Do(func() {
+		if h.cancelFunc != nil {
+			h.cancelFunc()
+		}
+		h.wg.Wait()
+	})
+}
+
+func (h *groupHeartbeat) run(ctx context.Context) {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(groupTouchInterval)
+	defer ticker.Stop()
+
+	for {
+		touchCtx, cancel := context.WithTimeout(ctx, h.config.Checkpoint.Timeout)
+		err := touchGroup(touchCtx, h.client, h.scopeName, h.collectionName, h.config.Dcp.Group.Name)
+		cancel()
+
+		if err != nil && ctx.Err() == nil {
+			logger.Log.Warn("error while touch group registry, err: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewGroupHeartbeat(client Client, config *config.Dcp) GroupHeartbeat {
+	couchbaseMetadata := config.GetCouchbaseMetadata()
+
+	return &groupHeartbeat{
+		client:         client,
+		config:         config,
+		scopeName:      couchbaseMetadata.Scope,
+		collectionName: couchbaseMetadata.Collection,
+	}
+}
+
+func getGroupRegistry(ctx context.Context, client Client, scopeName string, collectionName string) (map[string]int64, gocbcore.Cas, error) {
+	groups := map[string]int64{}
+
+	doc, err := Get(ctx, client.GetMetaAgent(), scopeName, collectionName, getGroupRegistryID())
+	if err != nil {
+		if isKeyNotFound(err) {
+			return groups, 0, nil
+		}
+
+		return nil, 0, err
+	}
+
+	err = sonic.Unmarshal(doc.Value, &groups)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return groups, doc.Cas, nil
+}
+
+func isKeyNotFound(err error) bool {
+	var kvErr *gocbcore.KeyValueError
+	return errors.As(err, &kvErr) && kvErr.StatusCode == memd.StatusKeyNotFound
+}
@@ -207,7 +207,7 @@ func (m *mockClient) Close() {
 	panic("implement me")
 }
 
-func (m *mockClient) DcpConnect(useExpiryOpcode bool, useChangeStreams bool) error {
+func (m *mockClient) DcpConnect(useExpiryOpcode bool, useChangeStreams bool, useOSOBackfill bool) error {
 	panic("implement me")
 }
 
@@ -219,6 +219,10 @@ func (m *mockClient) GetVBucketSeqNos(awareCollection bool) (*wrapper.Concurrent
 	panic("implement me")
 }
 
+func (m *mockClient) GetPurgeSeqNos() (map[uint16]uint64, error) {
+	panic("implement me")
+}
+
 func (m *mockClient) GetNumVBuckets() int {
 	panic("implement me")
 }
@@ -227,11 +231,13 @@ func (m *mockClient) GetFailOverLogs(vbID uint16) ([]gocbcore.FailoverEntry, err
 	panic("implement me")
 }
 
-func (m *mockClient) OpenStream(vbID uint16, collectionIDs map[uint32]string, offset *models.Offset, observer Observer) error {
+func (m *mockClient) OpenStream(
+	_ context.Context, vbID uint16, collectionIDs map[uint32]string, offset *models.Offset, observer Observer,
+) error {
 	panic("implement me")
 }
 
-func (m *mockClient) CloseStream(vbID uint16) error {
+func (m *mockClient) CloseStream(_ context.Context, vbID uint16) error {
 	panic("implement me")
 }
 
@@ -247,6 +253,14 @@ func (m *mockClient) GetDcpAgentConfigSnapshot() (*gocbcore.ConfigSnapshot, erro
 	panic("implement me")
 }
 
+func (m *mockClient) GetFailoverLogs(_ []uint16) (map[uint16][]gocbcore.FailoverEntry, error) {
+	panic("implement me")
+}
+
 func (m *mockClient) GetAgentQueues() []*models.AgentQueue {
 	panic("implement me")
 }
+
+func (m *mockClient) GetDcpNodeConnections() []*models.NodeConnection {
+	panic("implement me")
+}
@@ -36,10 +36,11 @@ type HTTPClient interface {
 }
 
 type httpClient struct {
-	config     *config.Dcp
-	httpClient *fasthttp.Client
-	client     Client
-	baseURL    string
+	config      *config.Dcp
+	httpClient  *fasthttp.Client
+	client      Client
+	credentials CredentialsProvider
+	baseURL     string
 }
 
 func (h *httpClient) Connect() error {
@@ -55,9 +56,18 @@ func (h *httpClient) Connect() error {
 }
 
 func (h *httpClient) doRequest(req *fasthttp.Request, v interface{}) error {
+	username, password := h.config.Username, h.config.Password
+
+	if h.credentials != nil {
+		var err error
+		if username, password, err = h.credentials.Credentials(); err != nil {
+			return err
+		}
+	}
+
 	req.Header.Set(
 		"Authorization",
-		"Basic "+base64.StdEncoding.EncodeToString([]byte(h.config.Username+":"+h.config.Password)),
+		"Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)),
 	)
 
 	res := fasthttp.AcquireResponse()
@@ -115,8 +125,9 @@ func (h *httpClient) GetBucketInfo() (*BucketInfo, error) {
 
 func NewHTTPClient(config *config.Dcp, client Client) HTTPClient {
 	return &httpClient{
-		config:     config,
-		httpClient: &fasthttp.Client{},
-		client:     client,
+		config:      config,
+		httpClient:  &fasthttp.Client{},
+		client:      client,
+		credentials: credentialsOf(client),
 	}
 }
@@ -3,6 +3,7 @@ package couchbase
 import (
 	"context"
 	"errors"
+	"math"
 	"sort"
 	"sync"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"github.com/Trendyol/go-dcp/helpers"
 	"github.com/Trendyol/go-dcp/logger"
 	"github.com/Trendyol/go-dcp/membership"
+	"github.com/Trendyol/go-dcp/metadata"
 
 	"github.com/google/uuid"
 
@@ -28,14 +30,20 @@ type cbMembership struct {
 	bus                 EventBus.Bus
 	membershipConfig    *config.CouchbaseMembership
 	infoChan            chan *membership.Model
+	livenessProbe       membership.LivenessProbe
 	config              *config.Dcp
 	info                *membership.Model
+	codec               *metadata.Codec
 	scopeName           string
 	collectionName      string
 	lastActiveInstances []Instance
 	instanceAll         []byte
 	id                  []byte
 	clusterJoinTime     int64
+	livenessTime        int64
+	weight              int
+	activeMembers       int
+	standby             bool
 	heartbeatRunning    bool
 	monitorRunning      bool
 }
@@ -45,6 +53,9 @@ type Instance struct {
 	Type            string  `json:"type"`
 	HeartbeatTime   int64   `json:"heartbeatTime"`
 	ClusterJoinTime int64   `json:"clusterJoinTime"`
+	LivenessTime    int64   `json:"livenessTime,omitempty"`
+	Weight          int     `json:"weight,omitempty"`
+	Standby         bool    `json:"standby,omitempty"`
 }
 
 const (
@@ -72,14 +83,22 @@ func (h *cbMembership) register() {
 	}
 
 	h.clusterJoinTime = now
+	h.livenessTime = now
 
 	instance := Instance{
 		Type:            _type,
 		HeartbeatTime:   now,
 		ClusterJoinTime: now,
+		LivenessTime:    now,
+		Weight:          h.weight,
+		Standby:         h.standby,
 	}
 
-	payload, _ := sonic.Marshal(instance)
+	payload, err := h.marshalInstance(&instance)
+	if err != nil {
+		logger.Log.Error("error while register, err: %v", err)
+		panic(err)
+	}
 
 	err = UpdateDocument(ctx, h.client.GetMetaAgent(), h.scopeName, h.collectionName, h.id, payload, h.membershipConfig.ExpirySeconds, nil)
 
@@ -131,21 +150,51 @@ func (h *cbMembership) heartbeat() {
 	ctx, cancel := context.WithTimeout(context.Background(), h.membershipConfig.Timeout)
 	defer cancel()
 
+	now := time.Now().UnixNano()
+
+	// the liveness is not renewed while the member is stuck, the others evict it after the missed heartbeats
+	if h.isLivenessEnabled() && h.livenessProbe != nil && !h.livenessProbe(h.membershipConfig.HeartbeatInterval) {
+		logger.Log.Warn("member made no progress within heartbeat interval: %v, liveness is not renewed", h.membershipConfig.HeartbeatInterval)
+	} else {
+		h.livenessTime = now
+	}
+
 	instance := &Instance{
 		Type:            _type,
-		HeartbeatTime:   time.Now().UnixNano(),
+		HeartbeatTime:   now,
 		ClusterJoinTime: h.clusterJoinTime,
+		LivenessTime:    h.livenessTime,
+		Weight:          h.weight,
+		Standby:         h.standby,
 	}
 
-	payload, _ := sonic.Marshal(instance)
+	payload, err := h.marshalInstance(instance)
+	if err != nil {
+		logger.Log.Error("error while heartbeat: %v", err)
+		return
+	}
 
-	err := UpdateDocument(ctx, h.client.GetMetaAgent(), h.scopeName, h.collectionName, h.id, payload, h.membershipConfig.ExpirySeconds, nil)
+	err = UpdateDocument(ctx, h.client.GetMetaAgent(), h.scopeName, h.collectionName, h.id, payload, h.membershipConfig.ExpirySeconds, nil)
 	if err != nil {
 		logger.Log.Error("error while heartbeat: %v", err)
 		return
 	}
 }
 
+func (h *cbMembership) marshalInstance(instance *Instance) ([]byte, error) {
+	payload, _ := sonic.Marshal(instance)
+	return h.codec.Encode(payload)
+}
+
+func unmarshalInstance(codec *metadata.Codec, value []byte, instance *Instance) error {
+	value, err := codec.Decode(value)
+	if err != nil {
+		return err
+	}
+
+	return sonic.Unmarshal(value, instance)
+}
+
 func (h *cbMembership) isAlive(heartbeatTime int64) bool {
 	upperWaitLimit := h.membershipConfig.HeartbeatInterval.Nanoseconds() + h.membershipConfig.HeartbeatToleranceDuration.Nanoseconds()
 	passedTimeSinceLastHeartbeat := time.Now().UnixNano() - heartbeatTime
@@ -156,6 +205,26 @@ func (h *cbMembership) isAlive(heartbeatTime int64) bool {
 	return passedTimeSinceLastHeartbeat < upperWaitLimit
 }
 
+func (h *cbMembership) isLivenessEnabled() bool {
+	return h.membershipConfig.HeartbeatMissThreshold > 0
+}
+
+// isLive reports whether the instance renewed its liveness within heartbeatMissThreshold heartbeats. Instances of
+// older versions do not share their liveness, they are live while they are alive.
+func (h *cbMembership) isLive(instance *Instance) bool {
+	if !h.isLivenessEnabled() || instance.LivenessTime == 0 {
+		return true
+	}
+
+	window := h.membershipConfig.HeartbeatInterval * time.Duration(h.membershipConfig.HeartbeatMissThreshold)
+	return time.Now().UnixNano()-instance.LivenessTime < window.Nanoseconds()
+}
+
+// SetLivenessProbe renews the liveness of the member with the probe, when heartbeatMissThreshold is set.
+func (h *cbMembership) SetLivenessProbe(probe membership.LivenessProbe) {
+	h.livenessProbe = probe
+}
+
 //nolint:funlen
 func (h *cbMembership) monitor() {
 	ctx, cancel := context.WithTimeout(context.Background(), h.membershipConfig.Timeout)
@@ -205,7 +274,7 @@ func (h *cbMembership) monitor() {
 
 			copyID := id
 			instance := &Instance{ID: &copyID}
-			err = sonic.Unmarshal(doc.Value, instance)
+			err = unmarshalInstance(h.codec, doc.Value, instance)
 			if err != nil {
 				logger.Log.Error("error while monitor try to unmarshal instance %v, err: %v", string(doc.Value), err)
 				panic(err)
@@ -220,17 +289,30 @@ func (h *cbMembership) monitor() {
 	}
 	wg.Wait()
 
-	var filteredInstances []Instance
+	// stuck instances stay in the index, so they are assigned again once they renew their liveness
+	var filteredInstances, liveInstances []Instance
 	for _, instance := range instances {
-		if instance != nil {
-			filteredInstances = append(filteredInstances, *instance)
+		if instance == nil {
+			continue
+		}
+
+		filteredInstances = append(filteredInstances, *instance)
+
+		if h.isLive(instance) {
+			liveInstances = append(liveInstances, *instance)
+		} else {
+			logger.Log.Warn("instance %v is evicted, it missed %v liveness heartbeats", *instance.ID, h.membershipConfig.HeartbeatMissThreshold)
 		}
 	}
 
-	if h.isClusterChanged(filteredInstances) {
+	if h.info == nil && !h.isBarrierOpen(len(liveInstances)) {
+		return
+	}
+
+	if h.isClusterChanged(liveInstances) {
 		err = h.updateIndex(ctx, filteredInstances, data.Cas)
 		if err == nil {
-			h.rebalance(filteredInstances)
+			h.rebalance(liveInstances)
 		} else {
 			if errors.Is(err, gocbcore.ErrCasMismatch) {
 				logger.Log.Debug("cannot update instances: cas mismatch")
@@ -242,6 +324,30 @@ func (h *cbMembership) monitor() {
 	}
 }
 
+// isBarrierOpen holds the first assignment till enough members are alive, so the first members of a cold start
+// do not claim all vBuckets and rebalance again and again as the others join.
+func (h *cbMembership) isBarrierOpen(alive int) bool {
+	barrier := h.config.Dcp.Group.Membership.Barrier
+	if barrier.Members == 0 {
+		return true
+	}
+
+	required := int(math.Ceil(float64(barrier.Members) * barrier.Fraction))
+	if alive >= required {
+		logger.Log.Info("membership barrier is open, alive members: %v, required: %v", alive, required)
+		return true
+	}
+
+	waited := time.Since(time.Unix(0, h.clusterJoinTime))
+	if waited >= barrier.Timeout {
+		logger.Log.Warn("membership barrier timed out after %v, alive members: %v, required: %v", waited, alive, required)
+		return true
+	}
+
+	logger.Log.Info("waiting for members before assignment, alive members: %v, required: %v", alive, required)
+	return false
+}
+
 func (h *cbMembership) updateIndex(ctx context.Context, instances []Instance, cas gocbcore.Cas) error {
 	all := map[string]int64{}
 
@@ -268,15 +374,22 @@ func (h *cbMembership) rebalance(instances []Instance) {
 		}
 	}
 
-	if selfOrder == 0 {
+	if selfOrder == 0 && h.isLivenessEnabled() && !h.isLive(&Instance{LivenessTime: h.livenessTime}) {
+		// the member is evicted by the others as well, it streams no vBuckets till it renews its liveness
+		newInfo := membership.NewModel(membersOf(instances), -1, h.activeMembers)
+		logger.Log.Warn("member is evicted, active members: %v, no vbuckets are assigned", newInfo.TotalMembers)
+
+		if newInfo.IsChanged(h.info) {
+			h.bus.Publish(helpers.MembershipChangedBusEventName, newInfo)
+		}
+
+		h.lastActiveInstances = instances
+	} else if selfOrder == 0 {
 		err := errors.New("cant find self in cluster")
 		logger.Log.Error("error while rebalance, self = %v, err: %v", string(h.id), err)
 		panic(err)
 	} else {
-		newInfo := &membership.Model{
-			MemberNumber: selfOrder,
-			TotalMembers: len(instances),
-		}
+		newInfo := membership.NewModel(membersOf(instances), selfOrder-1, h.activeMembers)
 
 		if newInfo.IsChanged(h.info) {
 			logger.Log.Debug("new info arrived for member: %v/%v", newInfo.MemberNumber, newInfo.TotalMembers)
@@ -288,6 +401,16 @@ func (h *cbMembership) rebalance(instances []Instance) {
 	}
 }
 
+func membersOf(instances []Instance) []membership.Member {
+	// members of older versions do not share their weight, they weigh 1
+	members := make([]membership.Member, len(instances))
+	for i, instance := range instances {
+		members[i] = membership.Member{Weight: max(instance.Weight, 1), Standby: instance.Standby}
+	}
+
+	return members
+}
+
 func (h *cbMembership) startHeartbeat() {
 	h.heartbeatRunning = true
 
@@ -346,12 +469,16 @@ func NewCBMembership(config *config.Dcp, client Client, bus EventBus.Bus) member
 		infoChan:         make(chan *membership.Model),
 		client:           client,
 		id:               []byte(helpers.Prefix + config.Dcp.Group.Name + ":" + _type + ":" + uuid.New().String()),
-		instanceAll:      []byte(helpers.Prefix + config.Dcp.Group.Name + ":" + _type + ":all"),
+		instanceAll:      getInstanceIndexID(config.Dcp.Group.Name),
 		bus:              bus,
 		scopeName:        couchbaseMetadataConfig.Scope,
 		collectionName:   couchbaseMetadataConfig.Collection,
 		membershipConfig: config.GetCouchbaseMembership(),
 		config:           config,
+		codec:            newCodec(config),
+		weight:           config.GetMembershipWeight(),
+		activeMembers:    config.Dcp.Group.Membership.ActiveMembers,
+		standby:          config.Dcp.Group.Membership.Standby,
 	}
 
 	cbm.register()
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bytedance/sonic"
 
@@ -25,11 +26,16 @@ import (
 	"github.com/couchbase/gocbcore/v10/memd"
 )
 
+const groupTouchInterval = time.Minute
+
 type cbMetadata struct {
+	groupTouchedAt time.Time
 	client         Client
 	config         *config.Dcp
+	codec          *metadata.Codec
 	scopeName      string
 	collectionName string
+	groupTouchLock sync.Mutex
 }
 
 func (s *cbMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
@@ -43,14 +49,47 @@ func (s *cbMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffs
 			eg.Go(s.saveVBucketCheckpoint(ctx, vbID, state[vbID]))
 		}
 	}
-	return eg.Wait()
+
+	err := eg.Wait()
+	if err == nil {
+		s.touchGroup()
+	}
+
+	return err
+}
+
+// touchGroup records the latest checkpoint time of the group at most once in groupTouchInterval.
+func (s *cbMetadata) touchGroup() {
+	s.groupTouchLock.Lock()
+	defer s.groupTouchLock.Unlock()
+
+	if time.Since(s.groupTouchedAt) < groupTouchInterval {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	err := touchGroup(ctx, s.client, s.scopeName, s.collectionName, s.config.Dcp.Group.Name)
+	if err != nil {
+		logger.Log.Warn("error while touch group registry, err: %v", err)
+		return
+	}
+
+	s.groupTouchedAt = time.Now()
 }
 
 func (s *cbMetadata) saveVBucketCheckpoint(ctx context.Context, vbID uint16, checkpointDocument *models.CheckpointDocument) func() error {
 	return func() error {
 		id := getCheckpointID(vbID, s.config.Dcp.Group.Name)
 		payload, _ := sonic.Marshal(checkpointDocument)
-		err := UpsertXattrs(ctx, s.client.GetMetaAgent(), s.scopeName, s.collectionName, id, helpers.Name, payload, 0)
+
+		payload, err := s.codec.Encode(payload)
+		if err != nil {
+			return err
+		}
+
+		err = UpsertXattrs(ctx, s.client.GetMetaAgent(), s.scopeName, s.collectionName, id, helpers.Name, payload, 0)
 
 		var kvErr *gocbcore.KeyValueError
 		if err != nil && errors.As(err, &kvErr) && kvErr.StatusCode == memd.StatusKeyNotFound {
@@ -68,7 +107,7 @@ func (s *cbMetadata) Load(
 	vbIds []uint16,
 	bucketUUID string,
 ) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
-	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](1024)
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
 
 	wg := &sync.WaitGroup{}
 	wg.Add(len(vbIds))
@@ -86,7 +125,10 @@ func (s *cbMetadata) Load(
 			var doc *models.CheckpointDocument
 
 			if err == nil {
-				err = sonic.Unmarshal(data, &doc)
+				data, err = s.codec.Decode(data)
+				if err == nil {
+					err = sonic.Unmarshal(data, &doc)
+				}
 
 				if err != nil {
 					doc = models.NewEmptyCheckpointDocument(bucketUUID)
@@ -144,11 +186,22 @@ func NewCBMetadata(client Client, config *config.Dcp) metadata.Metadata {
 	return &cbMetadata{
 		client:         client,
 		config:         config,
+		codec:          newCodec(config),
 		scopeName:      couchbaseMetadataConfig.Scope,
 		collectionName: couchbaseMetadataConfig.Collection,
 	}
 }
 
+func newCodec(config *config.Dcp) *metadata.Codec {
+	codec, err := metadata.NewCodec(config.Metadata)
+	if err != nil {
+		logger.Log.Error("error while initialize metadata codec, err: %v", err)
+		panic(err)
+	}
+
+	return codec
+}
+
 func getCheckpointID(vbID uint16, groupName string) []byte {
 	// _connector:cbgo:groupName:stdout-listener:checkpoint:vbId
 	if strings.Contains(groupName, ".") {
@@ -0,0 +1,290 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// metadataGC removes metadata of long dead members and groups. Instance documents are removed when
+// their latest heartbeat is older than the retention, the documents of a group are removed when the group
+// has neither saved a checkpoint nor touched the group registry within the retention and none of its
+// instances is left. Running groups touch the registry with their GroupHeartbeat.
+type metadataGC struct {
+	client     Client
+	store      gcStore
+	stateStore gcStore
+	config     *config.Dcp
+	codec      *metadata.Codec
+}
+
+// gcStore reads, updates and deletes the metadata documents of the gc.
+type gcStore interface {
+	get(ctx context.Context, id []byte) ([]byte, gocbcore.Cas, error)
+	update(ctx context.Context, id []byte, value []byte, cas gocbcore.Cas) error
+	delete(ctx context.Context, id []byte) error
+}
+
+type agentGCStore struct {
+	client         Client
+	scopeName      string
+	collectionName string
+}
+
+func (s *agentGCStore) get(ctx context.Context, id []byte) ([]byte, gocbcore.Cas, error) {
+	doc, err := Get(ctx, s.client.GetMetaAgent(), s.scopeName, s.collectionName, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return doc.Value, doc.Cas, nil
+}
+
+func (s *agentGCStore) update(ctx context.Context, id []byte, value []byte, cas gocbcore.Cas) error {
+	return UpdateDocument(ctx, s.client.GetMetaAgent(), s.scopeName, s.collectionName, id, value, 0, &cas)
+}
+
+func (s *agentGCStore) delete(ctx context.Context, id []byte) error {
+	return DeleteDocument(ctx, s.client.GetMetaAgent(), s.scopeName, s.collectionName, id)
+}
+
+func (g *metadataGC) Name() string {
+	return "metadata-gc"
+}
+
+func (g *metadataGC) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.config.Metadata.GC.Interval)
+	defer ticker.Stop()
+
+	for {
+		collectCtx, cancel := context.WithTimeout(ctx, g.config.Metadata.GC.Interval)
+		g.collect(collectCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *metadataGC) collect(ctx context.Context) {
+	threshold := time.Now().Add(-g.config.Metadata.GC.Retention).UnixNano()
+
+	groups, cas, err := g.groupRegistry(ctx)
+	if err != nil {
+		logger.Log.Error("error while metadata gc get group registry, err: %v", err)
+		return
+	}
+
+	if _, ok := groups[g.config.Dcp.Group.Name]; !ok {
+		g.reapInstances(ctx, g.config.Dcp.Group.Name, threshold)
+	}
+
+	var reaped []string
+
+	for groupName, lastCheckpoint := range groups {
+		if ctx.Err() != nil {
+			return
+		}
+
+		alive := g.reapInstances(ctx, groupName, threshold)
+
+		if groupName == g.config.Dcp.Group.Name || lastCheckpoint >= threshold || alive > 0 {
+			continue
+		}
+
+		if g.reapGroup(ctx, groupName) {
+			reaped = append(reaped, groupName)
+		}
+	}
+
+	if len(reaped) == 0 {
+		return
+	}
+
+	for _, groupName := range reaped {
+		delete(groups, groupName)
+	}
+
+	payload, _ := sonic.Marshal(groups)
+
+	err = g.store.update(ctx, getGroupRegistryID(), payload, cas)
+	if err != nil {
+		if errors.Is(err, gocbcore.ErrCasMismatch) {
+			logger.Log.Debug("metadata gc cannot update group registry: cas mismatch")
+		} else {
+			logger.Log.Error("error while metadata gc update group registry, err: %v", err)
+		}
+	}
+}
+
+func (g *metadataGC) groupRegistry(ctx context.Context) (map[string]int64, gocbcore.Cas, error) {
+	groups := map[string]int64{}
+
+	value, cas, err := g.store.get(ctx, getGroupRegistryID())
+	if err != nil {
+		if isKeyNotFound(err) {
+			return groups, 0, nil
+		}
+
+		return nil, 0, err
+	}
+
+	if err = sonic.Unmarshal(value, &groups); err != nil {
+		return nil, 0, err
+	}
+
+	return groups, cas, nil
+}
+
+// reapInstances removes instances of the group which did not heartbeat since threshold
+// and returns the count of the remaining ones. Unreadable indexes count as one alive instance,
+// so the group is never removed on errors.
+func (g *metadataGC) reapInstances(ctx context.Context, groupName string, threshold int64) int {
+	indexID := getInstanceIndexID(groupName)
+
+	value, cas, err := g.store.get(ctx, indexID)
+	if err != nil {
+		if isKeyNotFound(err) {
+			return 0
+		}
+
+		logger.Log.Error("error while metadata gc get instance index, group: %s, err: %v", groupName, err)
+		return 1
+	}
+
+	all := map[string]int64{}
+
+	err = sonic.Unmarshal(value, &all)
+	if err != nil {
+		logger.Log.Error("error while metadata gc unmarshal instance index, group: %s, err: %v", groupName, err)
+		return 1
+	}
+
+	alive := 0
+	removed := false
+
+	for id := range all {
+		instanceValue, _, err := g.store.get(ctx, []byte(id))
+		if err != nil {
+			if isKeyNotFound(err) {
+				delete(all, id)
+				removed = true
+			} else {
+				logger.Log.Error("error while metadata gc get instance, id: %s, err: %v", id, err)
+				alive++
+			}
+
+			continue
+		}
+
+		var instance Instance
+		if err = unmarshalInstance(g.codec, instanceValue, &instance); err != nil || instance.HeartbeatTime >= threshold {
+			alive++
+			continue
+		}
+
+		err = g.store.delete(ctx, []byte(id))
+		if err != nil && !isKeyNotFound(err) {
+			logger.Log.Error("error while metadata gc delete instance, id: %s, err: %v", id, err)
+			alive++
+			continue
+		}
+
+		logger.Log.Info("metadata gc removed dead instance, id: %s", id)
+
+		delete(all, id)
+		removed = true
+	}
+
+	if removed {
+		payload, _ := sonic.Marshal(all)
+
+		err = g.store.update(ctx, indexID, payload, cas)
+		if err != nil {
+			logger.Log.Debug("metadata gc cannot update instance index, group: %s, err: %v", groupName, err)
+		}
+	}
+
+	return alive
+}
+
+// reapGroup removes the checkpoints with their history, the state snapshots, the handoffs, the total members and
+// the instance index of the group. Split brain slots expire on their own and audit documents expire after the
+// retention, so they are not looked up.
+func (g *metadataGC) reapGroup(ctx context.Context, groupName string) bool {
+	for vbID := 0; vbID < g.client.GetNumVBuckets(); vbID++ {
+		documents := []struct {
+			store gcStore
+			id    []byte
+		}{
+			{store: g.store, id: getCheckpointID(uint16(vbID), groupName)},
+			{store: g.store, id: getHandoffID(uint16(vbID), groupName)},
+			{store: g.stateStore, id: getStateID(uint16(vbID), groupName, stateKind)},
+			{store: g.stateStore, id: getStateID(uint16(vbID), groupName, stateHandoffKind)},
+		}
+
+		for _, document := range documents {
+			err := document.store.delete(ctx, document.id)
+			if err != nil && !isKeyNotFound(err) {
+				logger.Log.Error("error while metadata gc delete document, id: %s, err: %v", document.id, err)
+				return false
+			}
+		}
+	}
+
+	for _, id := range [][]byte{getTotalMembersID(groupName), getInstanceIndexID(groupName)} {
+		err := g.store.delete(ctx, id)
+		if err != nil && !isKeyNotFound(err) {
+			logger.Log.Error("error while metadata gc delete document, id: %s, err: %v", id, err)
+			return false
+		}
+	}
+
+	logger.Log.Info("metadata gc removed stale group: %s", groupName)
+
+	return true
+}
+
+func NewMetadataGC(client Client, config *config.Dcp) models.LeaderTask {
+	if !config.IsCouchbaseMetadata() {
+		err := errors.New("unsupported metadata type")
+		logger.Log.Error("error while initialize metadata gc, err: %v", err)
+		panic(err)
+	}
+
+	couchbaseMetadataConfig := config.GetCouchbaseMetadata()
+
+	store := &agentGCStore{
+		client:         client,
+		scopeName:      couchbaseMetadataConfig.Scope,
+		collectionName: couchbaseMetadataConfig.Collection,
+	}
+
+	var stateStore gcStore = store
+	if config.State.Collection != "" {
+		stateStore = &agentGCStore{
+			client:         client,
+			scopeName:      couchbaseMetadataConfig.Scope,
+			collectionName: config.State.Collection,
+		}
+	}
+
+	return &metadataGC{
+		client:     client,
+		store:      store,
+		stateStore: stateStore,
+		config:     config,
+		codec:      newCodec(config),
+	}
+}
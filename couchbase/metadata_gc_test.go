@@ -0,0 +1,176 @@
+package couchbase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/couchbase/gocbcore/v10"
+	"github.com/couchbase/gocbcore/v10/memd"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+type memoryGCStore struct {
+	docs map[string][]byte
+}
+
+func (s *memoryGCStore) get(_ context.Context, id []byte) ([]byte, gocbcore.Cas, error) {
+	value, ok := s.docs[string(id)]
+	if !ok {
+		return nil, 0, &gocbcore.KeyValueError{StatusCode: memd.StatusKeyNotFound}
+	}
+
+	return value, 1, nil
+}
+
+func (s *memoryGCStore) update(_ context.Context, id []byte, value []byte, _ gocbcore.Cas) error {
+	s.docs[string(id)] = value
+	return nil
+}
+
+func (s *memoryGCStore) delete(_ context.Context, id []byte) error {
+	if _, ok := s.docs[string(id)]; !ok {
+		return &gocbcore.KeyValueError{StatusCode: memd.StatusKeyNotFound}
+	}
+
+	delete(s.docs, string(id))
+	return nil
+}
+
+func (s *memoryGCStore) put(t *testing.T, id []byte, value any) {
+	payload, err := sonic.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.docs[string(id)] = payload
+}
+
+func (s *memoryGCStore) has(id []byte) bool {
+	_, ok := s.docs[string(id)]
+	return ok
+}
+
+type vBucketsClient struct {
+	mockClient
+}
+
+func (c *vBucketsClient) GetNumVBuckets() int {
+	return 2
+}
+
+func newTestMetadataGC(store gcStore) *metadataGC {
+	cfg := &config.Dcp{
+		Metadata: config.Metadata{GC: config.MetadataGC{Retention: time.Hour}},
+	}
+	cfg.Dcp.Group.Name = "self"
+
+	return &metadataGC{
+		client:     &vBucketsClient{},
+		store:      store,
+		stateStore: store,
+		config:     cfg,
+		codec:      newCodec(cfg),
+	}
+}
+
+func TestMetadataGC_Collect(t *testing.T) {
+	now := time.Now()
+	stale := now.Add(-2 * time.Hour).UnixNano()
+
+	tests := []struct {
+		name          string
+		lastTouch     int64
+		instanceTime  int64
+		withInstance  bool
+		expectReaped  bool
+		expectInIndex bool
+	}{
+		{name: "stale group without instances is reaped", lastTouch: stale, expectReaped: true},
+		{name: "recently touched group is kept", lastTouch: now.UnixNano()},
+		{name: "stale group with a live instance is kept", lastTouch: stale, withInstance: true, instanceTime: now.UnixNano(),
+			expectInIndex: true},
+		{name: "stale group with a dead instance is reaped", lastTouch: stale, withInstance: true, instanceTime: stale,
+			expectReaped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryGCStore{docs: map[string][]byte{}}
+			store.put(t, getGroupRegistryID(), map[string]int64{"self": stale, "other": tt.lastTouch})
+			store.put(t, getCheckpointID(0, "other"), map[string]any{})
+			store.put(t, getCheckpointID(1, "other"), map[string]any{})
+			store.put(t, getCheckpointID(0, "self"), map[string]any{})
+
+			instanceID := "_connector:cbgo:other:instance:1"
+			if tt.withInstance {
+				store.put(t, getInstanceIndexID("other"), map[string]int64{instanceID: 0})
+				store.put(t, []byte(instanceID), Instance{HeartbeatTime: tt.instanceTime})
+			}
+
+			newTestMetadataGC(store).collect(context.Background())
+
+			reaped := !store.has(getCheckpointID(0, "other")) && !store.has(getCheckpointID(1, "other"))
+			if reaped != tt.expectReaped {
+				t.Fatalf("checkpoints of the group reaped: %v, expected: %v", reaped, tt.expectReaped)
+			}
+
+			groups := map[string]int64{}
+			_ = sonic.Unmarshal(store.docs[string(getGroupRegistryID())], &groups)
+
+			if _, ok := groups["other"]; ok == tt.expectReaped {
+				t.Fatalf("group registry must drop only the reaped group, got %v", groups)
+			}
+
+			if !store.has(getCheckpointID(0, "self")) {
+				t.Fatalf("checkpoints of the own group must never be reaped")
+			}
+
+			if store.has([]byte(instanceID)) != tt.expectInIndex {
+				t.Fatalf("instance kept: %v, expected: %v", store.has([]byte(instanceID)), tt.expectInIndex)
+			}
+		})
+	}
+}
+
+func TestMetadataGC_ReapGroupRemovesEveryDocument(t *testing.T) {
+	store := &memoryGCStore{docs: map[string][]byte{}}
+	store.put(t, getGroupRegistryID(), map[string]int64{"other": time.Now().Add(-2 * time.Hour).UnixNano()})
+	store.put(t, getTotalMembersID("other"), map[string]int{"totalMembers": 2})
+	store.put(t, getInstanceIndexID("other"), map[string]int64{})
+
+	for vbID := uint16(0); vbID < 2; vbID++ {
+		store.put(t, getCheckpointID(vbID, "other"), map[string]any{"history": []any{}})
+		store.put(t, getHandoffID(vbID, "other"), map[string]any{})
+		store.put(t, getStateID(vbID, "other", stateKind), map[string]any{})
+		store.put(t, getStateID(vbID, "other", stateHandoffKind), map[string]any{})
+	}
+
+	store.put(t, getCheckpointID(0, "self"), map[string]any{})
+	store.put(t, getStateID(0, "self", stateKind), map[string]any{})
+
+	newTestMetadataGC(store).collect(context.Background())
+
+	for id := range store.docs {
+		if strings.Contains(id, ":other:") {
+			t.Errorf("document of the reaped group must be removed, id: %s", id)
+		}
+	}
+
+	if !store.has(getCheckpointID(0, "self")) || !store.has(getStateID(0, "self", stateKind)) {
+		t.Errorf("documents of the own group must be kept")
+	}
+}
+
+func TestExpiryOf(t *testing.T) {
+	if expiry := expiryOf(7 * 24 * time.Hour); expiry != uint32((7 * 24 * time.Hour).Seconds()) {
+		t.Errorf("expiry within 30 days must be relative, got %d", expiry)
+	}
+
+	if expiry := expiryOf(60 * 24 * time.Hour); int64(expiry) < time.Now().Add(59*24*time.Hour).Unix() {
+		t.Errorf("expiry beyond 30 days must be a unix time, got %d", expiry)
+	}
+}
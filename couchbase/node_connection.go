@@ -0,0 +1,125 @@
+package couchbase
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// nodeConnectionTracker keeps the connections seen by the last call of GetDcpNodeConnections. gocbcore creates a
+// new connection for each reconnect, so a connection which differs from the last seen one of its slot is a reconnect.
+type nodeConnectionTracker struct {
+	connections map[string]uintptr
+	reconnects  map[string]int64
+	lastErrors  map[string]string
+	lock        sync.Mutex
+}
+
+// GetDcpNodeConnections returns the health of the dcp connections to each node, the connections of the
+// connectionsPerNode agents to a node are reported together.
+func (s *client) GetDcpNodeConnections() []*models.NodeConnection {
+	s.nodes.lock.Lock()
+	defer s.nodes.lock.Unlock()
+
+	if s.nodes.connections == nil {
+		s.nodes.connections = map[string]uintptr{}
+		s.nodes.reconnects = map[string]int64{}
+		s.nodes.lastErrors = map[string]string{}
+	}
+
+	var nodes []*models.NodeConnection
+	byAddress := map[string]*models.NodeConnection{}
+
+	for agentIndex, agent := range s.dcpAgents {
+		configSnapshot, err := agent.ConfigSnapshot()
+		if err != nil {
+			continue
+		}
+
+		pipelines := reflect.ValueOf(configSnapshot).Elem().FieldByName("state").Elem().FieldByName("pipelines")
+
+		for i := 0; i < pipelines.Len(); i++ {
+			pipeline := pipelines.Index(i).Elem()
+			address := pipeline.FieldByName("address").String()
+
+			node, ok := byAddress[address]
+			if !ok {
+				node = &models.NodeConnection{Address: address, Connected: true}
+				byAddress[address] = node
+				nodes = append(nodes, node)
+			}
+
+			s.trackNodeConnections(node, strconv.Itoa(agentIndex)+"/"+address, pipeline)
+		}
+	}
+
+	for _, node := range nodes {
+		node.Reconnects = s.nodes.reconnects[node.Address]
+		node.LastError = s.nodes.lastErrors[node.Address]
+	}
+
+	return nodes
+}
+
+func (s *client) trackNodeConnections(node *models.NodeConnection, key string, pipeline reflect.Value) {
+	clients := pipeline.FieldByName("clients")
+	if !clients.IsValid() {
+		return
+	}
+
+	for i := 0; i < clients.Len(); i++ {
+		pipelineClient := clients.Index(i)
+		if pipelineClient.IsNil() {
+			continue
+		}
+
+		pipelineClient = pipelineClient.Elem()
+		node.Connections++
+
+		connected := pipelineClient.FieldByName("state").Uint() == uint64(gocbcore.EndpointStateConnected)
+		if !connected {
+			node.Connected = false
+		}
+
+		if err := connectError(pipelineClient); err != nil {
+			s.nodes.lastErrors[node.Address] = err.Error()
+		}
+
+		memdClient := pipelineClient.FieldByName("client")
+		if !memdClient.IsValid() || memdClient.IsNil() {
+			continue
+		}
+
+		if lastActivityNano := memdClient.Elem().FieldByName("lastActivity").Int(); connected && lastActivityNano != 0 {
+			lastActivity := time.Unix(0, lastActivityNano)
+			if node.LastActivity.IsZero() || lastActivity.Before(node.LastActivity) {
+				node.LastActivity = lastActivity
+			}
+		}
+
+		slot := key + "/" + strconv.Itoa(i)
+		if seen, ok := s.nodes.connections[slot]; ok && seen != memdClient.Pointer() {
+			s.nodes.reconnects[node.Address]++
+		}
+
+		s.nodes.connections[slot] = memdClient.Pointer()
+	}
+}
+
+// connectError returns the error of the last connection attempt of the pipeline client, it is nil once connected.
+func connectError(pipelineClient reflect.Value) error {
+	field := pipelineClient.FieldByName("connectError")
+	if !field.IsValid() || field.IsNil() || !field.CanAddr() {
+		return nil
+	}
+
+	err, _ := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface().(error)
+
+	return err
+}
@@ -0,0 +1,144 @@
+package couchbase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// NodeHealthMonitor checks the dcp connections to each node periodically, onChange is called when the connections to
+// a node are lost and when they are connected again, so node specific network issues are visible without reading the
+// stream end logs. onStale is called when a connection receives nothing for dcp.noop.idleTimeout.
+type NodeHealthMonitor interface {
+	Start()
+	Stop()
+	Nodes() []models.NodeConnection
+}
+
+type nodeHealthMonitor struct {
+	client     Client
+	config     *config.Dcp
+	onChange   func(event models.NodeConnectionChanged)
+	onStale    func(event models.DcpConnectionStale)
+	cancelFunc context.CancelFunc
+	nodes      map[string]models.NodeConnection
+	lock       sync.RWMutex
+	wg         sync.WaitGroup
+	startOnce  sync.Once
+	stopOnce   sync.Once
+}
+
+func NewNodeHealthMonitor(
+	client Client,
+	config *config.Dcp,
+	onChange func(event models.NodeConnectionChanged),
+	onStale func(event models.DcpConnectionStale),
+) NodeHealthMonitor {
+	return &nodeHealthMonitor{
+		client:   client,
+		config:   config,
+		onChange: onChange,
+		onStale:  onStale,
+		nodes:    map[string]models.NodeConnection{},
+	}
+}
+
+func (m *nodeHealthMonitor) Start() {
+	m.startOnce.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.cancelFunc = cancel
+		m.wg.Add(1)
+		go m.run(ctx)
+	})
+}
+
+func (m *nodeHealthMonitor) Stop() {
+	m.stopOnce.Do(func() {
+		if m.cancelFunc != nil {
+			m.cancelFunc()
+		}
+		m.wg.Wait()
+	})
+}
+
+// Nodes returns the connections of the nodes seen by the last check.
+func (m *nodeHealthMonitor) Nodes() []models.NodeConnection {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	nodes := make([]models.NodeConnection, 0, len(m.nodes))
+	for _, node := range m.nodes {
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+func (m *nodeHealthMonitor) run(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.NodeHealth.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *nodeHealthMonitor) check() {
+	var events []models.NodeConnectionChanged
+	var staleEvents []models.DcpConnectionStale
+
+	now := time.Now()
+
+	m.lock.Lock()
+
+	for _, connection := range m.client.GetDcpNodeConnections() {
+		node := *connection
+
+		idleFor := now.Sub(node.LastActivity)
+		node.Stale = node.Connected && !node.LastActivity.IsZero() && idleFor > m.config.Dcp.Noop.IdleTimeout
+
+		previous, ok := m.nodes[node.Address]
+		m.nodes[node.Address] = node
+
+		if node.Stale && !previous.Stale {
+			staleEvents = append(staleEvents, models.DcpConnectionStale{
+				Time:         now,
+				LastActivity: node.LastActivity,
+				Address:      node.Address,
+				IdleFor:      idleFor,
+			})
+		}
+
+		if !ok {
+			if !node.Connected {
+				events = append(events, models.NodeConnectionChanged{Time: time.Now(), NodeConnection: node})
+			}
+			continue
+		}
+
+		if previous.Connected != node.Connected || previous.Reconnects != node.Reconnects {
+			events = append(events, models.NodeConnectionChanged{Time: time.Now(), NodeConnection: node})
+		}
+	}
+
+	m.lock.Unlock()
+
+	for _, event := range events {
+		m.onChange(event)
+	}
+
+	for _, event := range staleEvents {
+		m.onStale(event)
+	}
+}
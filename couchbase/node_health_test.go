@@ -0,0 +1,93 @@
+package couchbase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type nodeConnectionsClient struct {
+	mockClient
+	connections []*models.NodeConnection
+}
+
+func (c *nodeConnectionsClient) GetDcpNodeConnections() []*models.NodeConnection {
+	return c.connections
+}
+
+func TestNodeHealthMonitor_Check(t *testing.T) {
+	client := &nodeConnectionsClient{}
+
+	var events []models.NodeConnectionChanged
+	monitor := NewNodeHealthMonitor(client, &config.Dcp{}, func(event models.NodeConnectionChanged) {
+		events = append(events, event)
+	}, func(models.DcpConnectionStale) {}).(*nodeHealthMonitor)
+
+	client.connections = []*models.NodeConnection{
+		{Address: "node1:11210", Connections: 1, Connected: true},
+		{Address: "node2:11210", Connections: 1, Connected: true},
+	}
+	monitor.check()
+
+	if len(events) != 0 {
+		t.Fatalf("connected nodes must not be notified, got %+v", events)
+	}
+
+	client.connections = []*models.NodeConnection{
+		{Address: "node1:11210", Connections: 1, Connected: true},
+		{Address: "node2:11210", Connections: 1, LastError: "connection refused"},
+	}
+	monitor.check()
+
+	if len(events) != 1 || events[0].Address != "node2:11210" || events[0].Connected || events[0].LastError == "" {
+		t.Fatalf("lost node must be notified, got %+v", events)
+	}
+
+	client.connections = []*models.NodeConnection{
+		{Address: "node1:11210", Connections: 1, Connected: true, Reconnects: 1},
+		{Address: "node2:11210", Connections: 1, Connected: true, Reconnects: 1},
+	}
+	monitor.check()
+
+	if len(events) != 3 || !events[1].Connected || !events[2].Connected {
+		t.Fatalf("reconnected nodes must be notified, got %+v", events)
+	}
+
+	if nodes := monitor.Nodes(); len(nodes) != 2 {
+		t.Errorf("unexpected nodes %+v", nodes)
+	}
+}
+
+func TestNodeHealthMonitor_Stale(t *testing.T) {
+	client := &nodeConnectionsClient{}
+
+	cfg := &config.Dcp{}
+	cfg.Dcp.Noop.IdleTimeout = time.Minute
+
+	var events []models.DcpConnectionStale
+	monitor := NewNodeHealthMonitor(client, cfg, func(models.NodeConnectionChanged) {}, func(event models.DcpConnectionStale) {
+		events = append(events, event)
+	}).(*nodeHealthMonitor)
+
+	client.connections = []*models.NodeConnection{
+		{Address: "node1:11210", Connections: 1, Connected: true, LastActivity: time.Now()},
+		{Address: "node2:11210", Connections: 1, Connected: true, LastActivity: time.Now().Add(-2 * time.Minute)},
+	}
+	monitor.check()
+	monitor.check()
+
+	if len(events) != 1 || events[0].Address != "node2:11210" || events[0].IdleFor < time.Minute {
+		t.Fatalf("idle node must be notified once, got %+v", events)
+	}
+
+	client.connections[1].LastActivity = time.Now()
+	monitor.check()
+
+	for _, node := range monitor.Nodes() {
+		if node.Stale {
+			t.Errorf("node must not be stale after activity, got %+v", node)
+		}
+	}
+}
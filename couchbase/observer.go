@@ -6,6 +6,9 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/helpers"
+
 	"github.com/Trendyol/go-dcp/tracing"
 
 	"github.com/Trendyol/go-dcp/logger"
@@ -15,6 +18,7 @@ import (
 	"github.com/Trendyol/go-dcp/models"
 
 	"github.com/couchbase/gocbcore/v10"
+	"github.com/couchbase/gocbcore/v10/memd"
 )
 
 type Observer interface {
@@ -38,6 +42,7 @@ type Observer interface {
 	CloseEnd()
 	SetCatchup(seqNo gocbcore.SeqNo)
 	SetVbUUID(vbUUID gocbcore.VbUUID)
+	SetStartSeqNo(seqNo gocbcore.SeqNo)
 }
 
 const DefaultCollectionName = "_default"
@@ -60,8 +65,16 @@ func (om *ObserverMetric) AddExpiration() {
 	om.TotalExpirations++
 }
 
+const (
+	osoSnapshotStart = 0x01
+	osoSnapshotEnd   = 0x02
+)
+
 type observer struct {
+	ctx             context.Context
+	logger          logger.Logger
 	config          *dcp.Dcp
+	clock           clock.Clock
 	currentSnapshot *models.SnapshotMarker
 	collectionIDs   map[uint32]string
 	metrics         *ObserverMetric
@@ -70,10 +83,13 @@ type observer struct {
 	endListener     func(context models.DcpStreamEndContext)
 	vbUUID          gocbcore.VbUUID
 	catchupSeqNo    uint64
+	startSeqNo      uint64
+	osoMaxSeqNo     uint64
 	persistSeqNo    gocbcore.SeqNo
 	latestSeqNo     uint64
 	vbID            uint16
 	isCatchupNeed   bool
+	oso             bool
 	closed          bool
 	endClosed       bool
 }
@@ -87,14 +103,24 @@ func (so *observer) checkPersistSeqNo(seqNo uint64) bool {
 	return gocbcore.SeqNo(seqNo) <= so.persistSeqNo || so.closed
 }
 
+// SetStartSeqNo sets the seqNo the stream is opened from, offsets of an oso backfill stay at it till the backfill ends.
+func (so *observer) SetStartSeqNo(seqNo gocbcore.SeqNo) {
+	so.startSeqNo = uint64(seqNo)
+}
+
 func (so *observer) needCatchup(seqNo uint64) bool {
 	if !so.isCatchupNeed {
 		return false
 	}
 
+	// seqNos of an oso backfill are not ordered, the catchup is completed when the backfill ends
+	if so.oso {
+		return seqNo <= so.catchupSeqNo
+	}
+
 	if seqNo >= so.catchupSeqNo {
 		so.isCatchupNeed = false
-		logger.Log.Info("catchup completed for vbID: %d", so.vbID)
+		so.logger.Info("catchup completed for vbID: %d", so.vbID)
 		return seqNo == so.catchupSeqNo
 	}
 
@@ -103,11 +129,11 @@ func (so *observer) needCatchup(seqNo uint64) bool {
 
 func (so *observer) waitRollbackMitigation(seqNo uint64) {
 	for {
-		if so.checkPersistSeqNo(seqNo) {
+		if so.checkPersistSeqNo(seqNo) || so.ctx.Err() != nil {
 			break
 		}
 
-		time.Sleep(so.config.RollbackMitigation.Interval / 5)
+		so.clock.Sleep(so.config.RollbackMitigation.Interval / 5)
 	}
 }
 
@@ -134,20 +160,25 @@ func (so *observer) convertToCollectionName(collectionID uint32) string {
 	return DefaultCollectionName
 }
 
+// isCompressed returns true when the value is delivered snappy compressed, only with dcp.compression.passthrough.
+func isCompressed(datatype uint8) bool {
+	return datatype&uint8(memd.DatatypeFlagCompressed) != 0
+}
+
 // nolint:staticcheck
 func (so *observer) sendOrSkip(args models.ListenerArgs) {
-	if so.closed {
+	if so.closed || so.ctx.Err() != nil {
 		return
 	}
 
 	opTrace := so.tracer.StartOpTelemeteryHandler(
 		"go-dcp-observer",
 		reflect.TypeOf(args.Event).Name(),
-		tracing.RequestSpanContext{RefCtx: context.Background(), Value: args.Event},
-		tracing.NewObserverLabels(so.vbID, so.collectionIDs),
+		tracing.RequestSpanContext{RefCtx: so.ctx, Value: args.Event},
+		tracing.NewObserverLabels(so.vbID, so.collectionIDs).WithAttributes(logger.Fields(so.ctx)),
 	)
 
-	tracingContextAwareListenerArgs := models.ListenerArgs{Event: args.Event, TraceContext: opTrace.RootContext()}
+	tracingContextAwareListenerArgs := models.ListenerArgs{Event: args.Event, TraceContext: opTrace.RootContext(), VbID: so.vbID}
 
 	so.listener(tracingContextAwareListenerArgs)
 
@@ -170,12 +201,17 @@ func (so *observer) SnapshotMarker(event models.DcpSnapshotMarker) {
 }
 
 func (so *observer) IsInSnapshotMarker(seqNo uint64) bool {
+	if so.oso {
+		so.osoMaxSeqNo = max(so.osoMaxSeqNo, seqNo)
+		return true
+	}
+
 	isIn := so.currentSnapshot != nil &&
 		seqNo >= so.currentSnapshot.StartSeqNo && seqNo <= so.currentSnapshot.EndSeqNo
 
 	if !isIn {
 		err := fmt.Errorf("seqNo not in snapshot: %v, vbID: %v", seqNo, so.vbID)
-		logger.Log.Error("error while snapshot marker check, err: %v", err)
+		so.logger.Error("error while snapshot marker check, err: %v", err)
 		panic(err)
 	}
 
@@ -187,6 +223,7 @@ func (so *observer) Mutation(event gocbcore.DcpMutation) { //nolint:dupl
 		return
 	}
 
+	receivedTime := time.Now()
 	eventTime := time.Unix(int64(event.Cas/1000000000), 0)
 	if so.isBeforeSkipWindow(eventTime) {
 		return
@@ -199,11 +236,14 @@ func (so *observer) Mutation(event gocbcore.DcpMutation) { //nolint:dupl
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 				CollectionName: so.convertToCollectionName(event.CollectionID),
 				EventTime:      eventTime,
+				ServerTime:     helpers.CasToTime(event.Cas),
+				ReceivedTime:   receivedTime,
+				Compressed:     isCompressed(event.Datatype),
 			},
 		})
 
@@ -216,6 +256,7 @@ func (so *observer) Deletion(event gocbcore.DcpDeletion) { //nolint:dupl
 		return
 	}
 
+	receivedTime := time.Now()
 	eventTime := time.Unix(int64(event.Cas/1000000000), 0)
 	if so.isBeforeSkipWindow(eventTime) {
 		return
@@ -228,11 +269,14 @@ func (so *observer) Deletion(event gocbcore.DcpDeletion) { //nolint:dupl
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 				CollectionName: so.convertToCollectionName(event.CollectionID),
 				EventTime:      eventTime,
+				ServerTime:     helpers.CasToTime(event.Cas),
+				ReceivedTime:   receivedTime,
+				Compressed:     isCompressed(event.Datatype),
 			},
 		})
 
@@ -245,6 +289,7 @@ func (so *observer) Expiration(event gocbcore.DcpExpiration) { //nolint:dupl
 		return
 	}
 
+	receivedTime := time.Now()
 	eventTime := time.Unix(int64(event.Cas/1000000000), 0)
 	if so.isBeforeSkipWindow(eventTime) {
 		return
@@ -257,11 +302,13 @@ func (so *observer) Expiration(event gocbcore.DcpExpiration) { //nolint:dupl
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 				CollectionName: so.convertToCollectionName(event.CollectionID),
 				EventTime:      eventTime,
+				ServerTime:     helpers.CasToTime(event.Cas),
+				ReceivedTime:   receivedTime,
 			},
 		})
 
@@ -293,7 +340,7 @@ func (so *observer) CreateCollection(event gocbcore.DcpCollectionCreation) {
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 				CollectionName: so.convertToCollectionName(event.CollectionID),
@@ -314,7 +361,7 @@ func (so *observer) DeleteCollection(event gocbcore.DcpCollectionDeletion) {
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 				CollectionName: so.convertToCollectionName(event.CollectionID),
@@ -335,7 +382,7 @@ func (so *observer) FlushCollection(event gocbcore.DcpCollectionFlush) {
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 				CollectionName: so.convertToCollectionName(event.CollectionID),
@@ -356,7 +403,7 @@ func (so *observer) CreateScope(event gocbcore.DcpScopeCreation) {
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 			},
@@ -376,7 +423,7 @@ func (so *observer) DeleteScope(event gocbcore.DcpScopeDeletion) {
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 			},
@@ -396,7 +443,7 @@ func (so *observer) ModifyCollection(event gocbcore.DcpCollectionModification) {
 				Offset: &models.Offset{
 					SnapshotMarker: so.currentSnapshot,
 					VbUUID:         so.vbUUID,
-					SeqNo:          event.SeqNo,
+					SeqNo:          so.offsetSeqNo(event.SeqNo),
 					LatestSeqNo:    so.latestSeqNo,
 				},
 				CollectionName: so.convertToCollectionName(event.CollectionID),
@@ -405,7 +452,46 @@ func (so *observer) ModifyCollection(event gocbcore.DcpCollectionModification) {
 	}
 }
 
+// offsetSeqNo is the seqNo of the offset of an event. Events of an oso backfill arrive in key order, so their offsets
+// stay at the start of the backfill and the offset moves to its end seqNo when it ends.
+func (so *observer) offsetSeqNo(seqNo uint64) uint64 {
+	if so.oso {
+		return so.startSeqNo
+	}
+
+	return seqNo
+}
+
 func (so *observer) OSOSnapshot(event gocbcore.DcpOSOSnapshot) {
+	switch {
+	case event.SnapshotType&osoSnapshotStart != 0:
+		so.logger.Debug("oso backfill started for vbID: %d from seqNo: %d", so.vbID, so.startSeqNo)
+
+		so.oso = true
+		so.osoMaxSeqNo = so.startSeqNo
+		so.currentSnapshot = &models.SnapshotMarker{
+			StartSeqNo: so.startSeqNo,
+			EndSeqNo:   so.startSeqNo,
+		}
+	case event.SnapshotType&osoSnapshotEnd != 0 && so.oso:
+		so.logger.Debug("oso backfill ended for vbID: %d at seqNo: %d", so.vbID, so.osoMaxSeqNo)
+
+		so.oso = false
+
+		if so.isCatchupNeed && so.osoMaxSeqNo >= so.catchupSeqNo {
+			so.isCatchupNeed = false
+			so.logger.Info("catchup completed for vbID: %d", so.vbID)
+		}
+
+		so.sendOrSkip(models.ListenerArgs{
+			Event: event,
+		})
+
+		so.SeqNoAdvanced(gocbcore.DcpSeqNoAdvanced{SeqNo: so.osoMaxSeqNo, VbID: so.vbID})
+
+		return
+	}
+
 	so.sendOrSkip(models.ListenerArgs{
 		Event: event,
 	})
@@ -416,6 +502,12 @@ func (so *observer) SeqNoAdvanced(advanced gocbcore.DcpSeqNoAdvanced) {
 		return
 	}
 
+	// the highest seqNo of an oso backfill is advanced before it ends, when it is not an event of the stream
+	if so.oso {
+		so.osoMaxSeqNo = max(so.osoMaxSeqNo, advanced.SeqNo)
+		return
+	}
+
 	snapshot := &models.SnapshotMarker{
 		StartSeqNo: advanced.SeqNo,
 		EndSeqNo:   advanced.SeqNo,
@@ -450,15 +542,15 @@ func (so *observer) SetPersistSeqNo(persistSeqNo gocbcore.SeqNo) {
 			so.persistSeqNo = persistSeqNo
 		}
 	} else {
-		logger.Log.Trace("persistSeqNo: %v on vbID: %v", persistSeqNo, so.vbID)
+		so.logger.Trace("persistSeqNo: %v on vbID: %v", persistSeqNo, so.vbID)
 	}
 }
 
 // nolint:staticcheck
 func (so *observer) Close() {
-	logger.Log.Debug("observer closing")
+	so.logger.Debug("observer closing")
 	so.closed = true
-	logger.Log.Debug("observer closed")
+	so.logger.Debug("observer closed")
 }
 
 func (so *observer) SetVbUUID(vbUUID gocbcore.VbUUID) {
@@ -471,6 +563,7 @@ func (so *observer) CloseEnd() {
 }
 
 func NewObserver(
+	ctx context.Context,
 	config *dcp.Dcp,
 	vbID uint16,
 	latestSeqNo uint64,
@@ -478,8 +571,11 @@ func NewObserver(
 	endListener func(context models.DcpStreamEndContext),
 	collectionIDs map[uint32]string,
 	tc *tracing.TracerComponent,
+	clock clock.Clock,
 ) Observer {
 	return &observer{
+		ctx:           ctx,
+		logger:        logger.Ctx(ctx),
 		vbID:          vbID,
 		latestSeqNo:   latestSeqNo,
 		metrics:       &ObserverMetric{},
@@ -488,5 +584,6 @@ func NewObserver(
 		listener:      listener,
 		endListener:   endListener,
 		config:        config,
+		clock:         clock,
 	}
 }
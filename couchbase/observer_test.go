@@ -0,0 +1,61 @@
+package couchbase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/tracing"
+)
+
+func TestObserver_OSOSnapshot(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	var offsets []*models.Offset
+
+	listener := func(args models.ListenerArgs) {
+		switch v := args.Event.(type) {
+		case models.InternalDcpMutation:
+			offsets = append(offsets, v.Offset)
+		case models.InternalDcpSeqNoAdvance:
+			offsets = append(offsets, v.Offset)
+		}
+	}
+
+	cfg := &config.Dcp{RollbackMitigation: config.RollbackMitigation{Disabled: true}}
+	observer := NewObserver(context.Background(), cfg, 0, 0, listener, func(models.DcpStreamEndContext) {},
+		map[uint32]string{}, tracing.NewTracerComponent(), clock.New())
+
+	observer.SetStartSeqNo(0)
+	observer.OSOSnapshot(gocbcore.DcpOSOSnapshot{SnapshotType: osoSnapshotStart})
+	observer.Mutation(gocbcore.DcpMutation{SeqNo: 7, Key: []byte("a")})
+	observer.Mutation(gocbcore.DcpMutation{SeqNo: 3, Key: []byte("b")})
+	observer.SeqNoAdvanced(gocbcore.DcpSeqNoAdvanced{SeqNo: 9})
+	observer.OSOSnapshot(gocbcore.DcpOSOSnapshot{SnapshotType: osoSnapshotEnd})
+
+	if len(offsets) != 3 {
+		t.Fatalf("oso events and the end of the backfill must be forwarded, got %d", len(offsets))
+	}
+
+	for _, offset := range offsets[:2] {
+		if offset.SeqNo != 0 {
+			t.Errorf("offsets of an oso backfill must stay at its start, got %d", offset.SeqNo)
+		}
+	}
+
+	if end := offsets[2]; end.SeqNo != 9 || end.StartSeqNo != 9 || end.EndSeqNo != 9 {
+		t.Errorf("offset must move to the end of the oso backfill, got %+v", end)
+	}
+
+	observer.SnapshotMarker(models.DcpSnapshotMarker{StartSeqNo: 10, EndSeqNo: 10})
+	observer.Mutation(gocbcore.DcpMutation{SeqNo: 10, Key: []byte("c")})
+
+	if last := offsets[len(offsets)-1]; last.SeqNo != 10 {
+		t.Errorf("offsets must follow the events after the oso backfill, got %d", last.SeqNo)
+	}
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/Trendyol/go-dcp/models"
 
+	"github.com/Trendyol/go-dcp/clock"
 	"github.com/Trendyol/go-dcp/config"
 
 	"github.com/Trendyol/go-dcp/logger"
@@ -60,7 +61,8 @@ func (v *vbUUIDAndSeqNo) IsOutdated(last *gocbcore.ObserveVbResult) bool {
 
 type rollbackMitigation struct {
 	client                 Client
-	observeTimer           *time.Ticker
+	clock                  clock.Clock
+	observeTimer           clock.Ticker
 	configSnapshot         *gocbcore.ConfigSnapshot
 	persistedSeqNos        *wrapper.ConcurrentSwissMap[uint16, []*vbUUIDAndSeqNo]
 	observeCount           *atomic.Uint32
@@ -197,14 +199,14 @@ func (r *rollbackMitigation) markAbsentInstances() error { //nolint:unused
 }
 
 func (r *rollbackMitigation) startObserve(groupID int) {
-	r.vbUUIDMap = wrapper.CreateConcurrentSwissMap[uint16, gocbcore.VbUUID](1024)
+	r.vbUUIDMap = wrapper.CreateConcurrentSwissMap[uint16, gocbcore.VbUUID](r.config.GetMapInitialSize(len(r.vbIds)))
 
 	r.loadVbUUIDMap()
 
-	r.observeTimer = time.NewTicker(r.config.RollbackMitigation.Interval)
+	r.observeTimer = r.clock.NewTicker(r.config.RollbackMitigation.Interval)
 	for {
 		select {
-		case <-r.observeTimer.C:
+		case <-r.observeTimer.C():
 			wg := &sync.WaitGroup{}
 			wg.Add(int(r.observeCount.Load()))
 			r.persistedSeqNos.Range(func(vbID uint16, replicas []*vbUUIDAndSeqNo) bool {
@@ -359,7 +361,7 @@ func (r *rollbackMitigation) reset() {
 		panic(err)
 	}
 
-	r.persistedSeqNos = wrapper.CreateConcurrentSwissMap[uint16, []*vbUUIDAndSeqNo](1024)
+	r.persistedSeqNos = wrapper.CreateConcurrentSwissMap[uint16, []*vbUUIDAndSeqNo](r.config.GetMapInitialSize(len(r.vbIds)))
 
 	var observeCount int
 	for _, vbID := range r.vbIds {
@@ -415,7 +417,7 @@ func (r *rollbackMitigation) Start() {
 	go func() {
 		r.configWatchRunning = true
 		for r.configWatchRunning {
-			time.Sleep(r.config.RollbackMitigation.ConfigWatchInterval)
+			r.clock.Sleep(r.config.RollbackMitigation.ConfigWatchInterval)
 			r.configWatch()
 		}
 	}()
@@ -441,6 +443,7 @@ func NewRollbackMitigation(client Client,
 	config *config.Dcp,
 	vbIds []uint16,
 	persistSeqNoDispatcher models.PersistSeqNoDispatcher,
+	clock clock.Clock,
 ) RollbackMitigation {
 	return &rollbackMitigation{
 		client:                 client,
@@ -450,5 +453,6 @@ func NewRollbackMitigation(client Client,
 		observeCloseCh:         make(chan struct{}, 1),
 		observeCloseDoneCh:     make(chan struct{}, 1),
 		persistSeqNoDispatcher: persistSeqNoDispatcher,
+		clock:                  clock,
 	}
 }
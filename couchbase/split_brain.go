@@ -0,0 +1,240 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/couchbase/gocbcore/v10"
+	"github.com/google/uuid"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// slotExpiryIntervals is how many intervals a slot claim lives without a heartbeat.
+const slotExpiryIntervals = 3
+
+// SplitBrainGuard claims the membership slot of the member in the metadata bucket. A member which finds its slot
+// claimed by another live member is fenced till the claim expires, so two members never stream the same vBuckets.
+type SplitBrainGuard interface {
+	Start()
+	Stop()
+	IsFenced() bool
+	Conflicts() int64
+}
+
+type slotDocument struct {
+	Owner         string `json:"owner"`
+	HeartbeatTime int64  `json:"heartbeatTime"`
+}
+
+type splitBrainGuard struct {
+	client         Client
+	config         *config.Dcp
+	slot           func() (memberNumber int, totalMembers int)
+	onChange       func(event models.SplitBrain)
+	cancelFunc     context.CancelFunc
+	owner          string
+	scopeName      string
+	collectionName string
+	claimed        []byte
+	wg             sync.WaitGroup
+	conflicts      int64
+	startOnce      sync.Once
+	stopOnce       sync.Once
+	fenced         atomic.Bool
+}
+
+// NewSplitBrainGuard creates the guard, slot returns the current membership of the member and onChange is called
+// when the member is fenced and when it is unfenced again.
+func NewSplitBrainGuard(
+	client Client,
+	config *config.Dcp,
+	slot func() (memberNumber int, totalMembers int),
+	onChange func(event models.SplitBrain),
+) SplitBrainGuard {
+	couchbaseMetadata := config.GetCouchbaseMetadata()
+
+	return &splitBrainGuard{
+		client:         client,
+		config:         config,
+		slot:           slot,
+		onChange:       onChange,
+		owner:          uuid.New().String(),
+		scopeName:      couchbaseMetadata.Scope,
+		collectionName: couchbaseMetadata.Collection,
+	}
+}
+
+func (g *splitBrainGuard) Start() {
+	g.startOnce.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		g.cancelFunc = cancel
+		g.wg.Add(1)
+		go g.run(ctx)
+	})
+}
+
+func (g *splitBrainGuard) Stop() {
+	g.stopOnce.Do(func() {
+		if g.cancelFunc != nil {
+			g.cancelFunc()
+		}
+		g.wg.Wait()
+
+		ctx, cancel := context.WithTimeout(context.Background(), g.config.Dcp.Group.Membership.SplitBrain.Interval)
+		defer cancel()
+
+		g.release(ctx)
+	})
+}
+
+func (g *splitBrainGuard) IsFenced() bool {
+	return g.fenced.Load()
+}
+
+func (g *splitBrainGuard) Conflicts() int64 {
+	return atomic.LoadInt64(&g.conflicts)
+}
+
+func (g *splitBrainGuard) run(ctx context.Context) {
+	defer g.wg.Done()
+
+	interval := g.config.Dcp.Group.Membership.SplitBrain.Interval
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		g.check(checkCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *splitBrainGuard) slotID(memberNumber int, totalMembers int) []byte {
+	// _connector:cbgo:groupName:slot:memberNumber:totalMembers
+	return []byte(helpers.Prefix + g.config.Dcp.Group.Name + ":slot:" + strconv.Itoa(memberNumber) + ":" + strconv.Itoa(totalMembers))
+}
+
+func (g *splitBrainGuard) check(ctx context.Context) {
+	memberNumber, totalMembers := g.slot()
+	if memberNumber == 0 || totalMembers == 0 {
+		return
+	}
+
+	id := g.slotID(memberNumber, totalMembers)
+
+	if g.claimed != nil && string(g.claimed) != string(id) {
+		g.release(ctx)
+	}
+
+	payload, _ := sonic.Marshal(slotDocument{Owner: g.owner, HeartbeatTime: time.Now().UnixNano()})
+	expiry := uint32((slotExpiryIntervals * g.config.Dcp.Group.Membership.SplitBrain.Interval).Seconds())
+
+	doc, err := Get(ctx, g.client.GetMetaAgent(), g.scopeName, g.collectionName, id)
+	if err != nil {
+		if !isKeyNotFound(err) {
+			logger.Log.Error("error while split brain guard get slot, id: %s, err: %v", id, err)
+			return
+		}
+
+		err = InsertDocument(ctx, g.client.GetMetaAgent(), g.scopeName, g.collectionName, id, payload, 0, expiry)
+		if err != nil {
+			if !errors.Is(err, gocbcore.ErrDocumentExists) {
+				logger.Log.Error("error while split brain guard claim slot, id: %s, err: %v", id, err)
+			}
+			return
+		}
+
+		g.claimed = id
+		g.setFenced(false, g.owner, memberNumber, totalMembers)
+		return
+	}
+
+	var slot slotDocument
+	if err = sonic.Unmarshal(doc.Value, &slot); err != nil {
+		logger.Log.Error("error while split brain guard unmarshal slot, id: %s, err: %v", id, err)
+		return
+	}
+
+	if slot.Owner != g.owner {
+		g.setFenced(true, slot.Owner, memberNumber, totalMembers)
+		return
+	}
+
+	err = UpdateDocument(ctx, g.client.GetMetaAgent(), g.scopeName, g.collectionName, id, payload, expiry, &doc.Cas)
+	if err != nil {
+		if !errors.Is(err, gocbcore.ErrCasMismatch) {
+			logger.Log.Error("error while split brain guard heartbeat slot, id: %s, err: %v", id, err)
+		}
+		return
+	}
+
+	g.claimed = id
+	g.setFenced(false, g.owner, memberNumber, totalMembers)
+}
+
+// release removes the claim of the previous slot, so the member which takes it over is not fenced till it expires.
+func (g *splitBrainGuard) release(ctx context.Context) {
+	if g.claimed == nil {
+		return
+	}
+
+	id := g.claimed
+	g.claimed = nil
+
+	doc, err := Get(ctx, g.client.GetMetaAgent(), g.scopeName, g.collectionName, id)
+	if err != nil {
+		if !isKeyNotFound(err) {
+			logger.Log.Warn("error while split brain guard release slot, id: %s, err: %v", id, err)
+		}
+		return
+	}
+
+	var slot slotDocument
+	if err = sonic.Unmarshal(doc.Value, &slot); err != nil || slot.Owner != g.owner {
+		return
+	}
+
+	if err = DeleteDocument(ctx, g.client.GetMetaAgent(), g.scopeName, g.collectionName, id); err != nil && !isKeyNotFound(err) {
+		logger.Log.Warn("error while split brain guard release slot, id: %s, err: %v", id, err)
+	}
+}
+
+func (g *splitBrainGuard) setFenced(fenced bool, owner string, memberNumber int, totalMembers int) {
+	if g.fenced.Swap(fenced) == fenced {
+		return
+	}
+
+	if fenced {
+		atomic.AddInt64(&g.conflicts, 1)
+		logger.Log.Error(
+			"split brain detected, membership %d/%d is claimed by another live member: %s, fencing this member",
+			memberNumber, totalMembers, owner,
+		)
+	} else {
+		logger.Log.Info("membership %d/%d is claimed, this member is no longer fenced", memberNumber, totalMembers)
+	}
+
+	g.onChange(models.SplitBrain{
+		Time:         time.Now(),
+		Owner:        owner,
+		MemberNumber: memberNumber,
+		TotalMembers: totalMembers,
+		Fenced:       fenced,
+	})
+}
@@ -0,0 +1,182 @@
+package couchbase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/state"
+)
+
+const (
+	stateKind        = "state"
+	stateHandoffKind = "stateHandoff"
+)
+
+// cbStateBackend keeps the snapshot of each vBucket in a document, so a snapshot is written atomically with its offset.
+type cbStateBackend struct {
+	client         Client
+	config         *config.Dcp
+	scopeName      string
+	collectionName string
+	kind           string
+}
+
+func (b *cbStateBackend) Save(snapshots map[uint16]*models.StateSnapshot) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.Checkpoint.Timeout)
+	defer cancel()
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	for vbID, snapshot := range snapshots {
+		vbID, snapshot := vbID, snapshot
+
+		eg.Go(func() error {
+			payload, err := sonic.Marshal(snapshot)
+			if err != nil {
+				return err
+			}
+
+			id := getStateID(vbID, b.config.Dcp.Group.Name, b.kind)
+
+			return CreateDocument(ctx, b.client.GetMetaAgent(), b.scopeName, b.collectionName, id, payload, helpers.JSONFlags, 0)
+		})
+	}
+
+	return eg.Wait()
+}
+
+func (b *cbStateBackend) Load(vbIds []uint16) (map[uint16]*models.StateSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.Checkpoint.Timeout)
+	defer cancel()
+
+	snapshots := make([]*models.StateSnapshot, len(vbIds))
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	for i, vbID := range vbIds {
+		i, vbID := i, vbID
+
+		eg.Go(func() error {
+			doc, err := Get(ctx, b.client.GetMetaAgent(), b.scopeName, b.collectionName, getStateID(vbID, b.config.Dcp.Group.Name, b.kind))
+			if err != nil {
+				if isKeyNotFound(err) {
+					return nil
+				}
+
+				return err
+			}
+
+			var snapshot models.StateSnapshot
+			if err = sonic.Unmarshal(doc.Value, &snapshot); err != nil {
+				return err
+			}
+
+			snapshots[i] = &snapshot
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	result := map[uint16]*models.StateSnapshot{}
+
+	for i, snapshot := range snapshots {
+		if snapshot != nil {
+			result[vbIds[i]] = snapshot
+		}
+	}
+
+	return result, nil
+}
+
+// delete removes the documents of the vBuckets, documents which do not exist are skipped.
+func (b *cbStateBackend) delete(vbIds []uint16) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.Checkpoint.Timeout)
+	defer cancel()
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	for _, vbID := range vbIds {
+		vbID := vbID
+
+		eg.Go(func() error {
+			id := getStateID(vbID, b.config.Dcp.Group.Name, b.kind)
+
+			err := DeleteDocument(ctx, b.client.GetMetaAgent(), b.scopeName, b.collectionName, id)
+			if err != nil && !isKeyNotFound(err) {
+				return err
+			}
+
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
+
+func NewCBStateBackend(client Client, config *config.Dcp) state.Backend {
+	return newCBStateBackend(client, config, stateKind)
+}
+
+// cbStateHandoff publishes the snapshots to documents apart from the state, for state backends
+// which are not shared by the members.
+type cbStateHandoff struct {
+	backend *cbStateBackend
+}
+
+func (h *cbStateHandoff) Publish(snapshots map[uint16]*models.StateSnapshot) error {
+	return h.backend.Save(snapshots)
+}
+
+func (h *cbStateHandoff) Fetch(vbIds []uint16) (map[uint16]*models.StateSnapshot, error) {
+	return h.backend.Load(vbIds)
+}
+
+func (h *cbStateHandoff) Remove(vbIds []uint16) error {
+	return h.backend.delete(vbIds)
+}
+
+func NewCBStateHandoff(client Client, config *config.Dcp) state.Handoff {
+	return &cbStateHandoff{
+		backend: newCBStateBackend(client, config, stateHandoffKind),
+	}
+}
+
+func newCBStateBackend(client Client, config *config.Dcp, kind string) *cbStateBackend {
+	if !config.IsCouchbaseMetadata() {
+		err := errors.New("unsupported metadata type")
+		logger.Log.Error("error while initialize couchbase %s backend, err: %v", kind, err)
+		panic(err)
+	}
+
+	couchbaseMetadataConfig := config.GetCouchbaseMetadata()
+
+	collectionName := config.State.Collection
+	if collectionName == "" {
+		collectionName = couchbaseMetadataConfig.Collection
+	}
+
+	return &cbStateBackend{
+		client:         client,
+		config:         config,
+		scopeName:      couchbaseMetadataConfig.Scope,
+		collectionName: collectionName,
+		kind:           kind,
+	}
+}
+
+func getStateID(vbID uint16, groupName string, kind string) []byte {
+	// _connector:cbgo:groupName:state:vbId
+	return []byte(helpers.Prefix + groupName + ":" + kind + ":" + strconv.Itoa(int(vbID)))
+}
@@ -0,0 +1,138 @@
+package couchbase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+// totalMembersDocument keeps the totalMembers of a static membership group, it overrides the config of its members.
+type totalMembersDocument struct {
+	TotalMembers int   `json:"totalMembers"`
+	UpdatedAt    int64 `json:"updatedAt"`
+}
+
+func getTotalMembersID(groupName string) []byte {
+	// _connector:cbgo:groupName:membership:totalMembers
+	return []byte(helpers.Prefix + groupName + ":membership:totalMembers")
+}
+
+// SetTotalMembers stores the totalMembers of the group. Members of the group pick it up within
+// dcp.group.membership.reconfigure.interval and are reassigned after dcp.group.membership.rebalanceDelay.
+func SetTotalMembers(ctx context.Context, client Client, config *config.Dcp, totalMembers int) error {
+	couchbaseMetadata := config.GetCouchbaseMetadata()
+
+	payload, _ := sonic.Marshal(totalMembersDocument{TotalMembers: totalMembers, UpdatedAt: time.Now().UnixNano()})
+
+	return CreateDocument(
+		ctx, client.GetMetaAgent(), couchbaseMetadata.Scope, couchbaseMetadata.Collection, getTotalMembersID(config.Dcp.Group.Name), payload, 0, 0,
+	)
+}
+
+// GetTotalMembers returns the stored totalMembers of the group, false when it is not stored.
+func GetTotalMembers(ctx context.Context, client Client, config *config.Dcp) (int, bool, error) {
+	couchbaseMetadata := config.GetCouchbaseMetadata()
+
+	doc, err := Get(ctx, client.GetMetaAgent(), couchbaseMetadata.Scope, couchbaseMetadata.Collection, getTotalMembersID(config.Dcp.Group.Name))
+	if err != nil {
+		if isKeyNotFound(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	var document totalMembersDocument
+	if err = sonic.Unmarshal(doc.Value, &document); err != nil {
+		return 0, false, err
+	}
+
+	return document.TotalMembers, document.TotalMembers > 0, nil
+}
+
+type TotalMembersWatcher interface {
+	Start()
+	Stop()
+}
+
+type totalMembersWatcher struct {
+	client       Client
+	config       *config.Dcp
+	onChange     func(totalMembers int)
+	cancelFunc   context.CancelFunc
+	wg           sync.WaitGroup
+	totalMembers int
+	startOnce    sync.Once
+	stopOnce     sync.Once
+}
+
+// NewTotalMembersWatcher creates a watcher which calls onChange when the stored totalMembers of the group
+// differs from the totalMembers the member streams with.
+func NewTotalMembersWatcher(client Client, config *config.Dcp, onChange func(totalMembers int)) TotalMembersWatcher {
+	return &totalMembersWatcher{
+		client:       client,
+		config:       config,
+		onChange:     onChange,
+		totalMembers: config.Dcp.Group.Membership.TotalMembers,
+	}
+}
+
+func (w *totalMembersWatcher) Start() {
+	w.startOnce.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		w.cancelFunc = cancel
+		w.wg.Add(1)
+		go w.run(ctx)
+	})
+}
+
+func (w *totalMembersWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		if w.cancelFunc != nil {
+			w.cancelFunc()
+		}
+		w.wg.Wait()
+	})
+}
+
+func (w *totalMembersWatcher) run(ctx context.Context) {
+	defer w.wg.Done()
+
+	interval := w.config.Dcp.Group.Membership.Reconfigure.Interval
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			w.check(checkCtx)
+			cancel()
+		}
+	}
+}
+
+func (w *totalMembersWatcher) check(ctx context.Context) {
+	totalMembers, ok, err := GetTotalMembers(ctx, w.client, w.config)
+	if err != nil {
+		logger.Log.Error("error while get stored total members, err: %v", err)
+		return
+	}
+
+	if !ok || totalMembers == w.totalMembers {
+		return
+	}
+
+	logger.Log.Info("total members of the group is changed from %v to %v", w.totalMembers, totalMembers)
+
+	w.totalMembers = totalMembers
+	w.onChange(totalMembers)
+}
@@ -0,0 +1,168 @@
+package couchbase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+// handoffPollInterval is how often a claim checks whether the former owners released the vBuckets.
+const handoffPollInterval = time.Second
+
+// VBucketHandoff hands the vBuckets over between the members through the metadata bucket. The former owner of a
+// vBucket saves its checkpoint and closes its stream before it marks the vBucket released, the next owner waits
+// for the mark before it opens the stream, so a vBucket is not streamed by two members at once.
+type VBucketHandoff interface {
+	Claim(vbIDs []uint16)
+	Release(vbIDs []uint16)
+}
+
+type handoffDocument struct {
+	Owner    string `json:"owner"`
+	Released bool   `json:"released"`
+}
+
+type vBucketHandoff struct {
+	client         Client
+	config         *config.Dcp
+	owner          string
+	scopeName      string
+	collectionName string
+}
+
+// Claim waits till the vBuckets are released by their former owners or handoff.timeout passes, e.g. when a former
+// owner crashed, and then claims them for the member.
+func (h *vBucketHandoff) Claim(vbIDs []uint16) {
+	if len(vbIDs) == 0 {
+		return
+	}
+
+	timeout := h.config.Dcp.Group.Membership.Handoff.Timeout
+	deadline := time.Now().Add(timeout)
+
+	held := h.held(vbIDs)
+	for len(held) > 0 {
+		if time.Now().After(deadline) {
+			logger.Log.Warn("%d vBuckets are not released by their former owners in %v, they are claimed", len(held), timeout)
+			break
+		}
+
+		logger.Log.Info("waiting for %d vBuckets to be released by their former owners", len(held))
+		time.Sleep(handoffPollInterval)
+
+		held = h.held(held)
+	}
+
+	if err := h.write(vbIDs, false); err != nil {
+		logger.Log.Error("error while claim vBuckets, err: %v", err)
+	}
+}
+
+// Release marks the vBuckets released, their checkpoints must be saved and their streams closed before.
+func (h *vBucketHandoff) Release(vbIDs []uint16) {
+	if len(vbIDs) == 0 {
+		return
+	}
+
+	if err := h.write(vbIDs, true); err != nil {
+		logger.Log.Error("error while release vBuckets, err: %v", err)
+		return
+	}
+
+	logger.Log.Info("%d vBuckets are handed off", len(vbIDs))
+}
+
+// held returns the vBuckets claimed by another member and not released yet. A vBucket which cannot be read is held,
+// so it is claimed after the timeout at the latest.
+func (h *vBucketHandoff) held(vbIDs []uint16) []uint16 {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Checkpoint.Timeout)
+	defer cancel()
+
+	isHeld := make([]bool, len(vbIDs))
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	for i, vbID := range vbIDs {
+		i, vbID := i, vbID
+
+		eg.Go(func() error {
+			doc, err := Get(ctx, h.client.GetMetaAgent(), h.scopeName, h.collectionName, getHandoffID(vbID, h.config.Dcp.Group.Name))
+			if err != nil {
+				if !isKeyNotFound(err) {
+					logger.Log.Error("error while get handoff of vbID: %v, err: %v", vbID, err)
+					isHeld[i] = true
+				}
+
+				return nil
+			}
+
+			var handoff handoffDocument
+			if err = sonic.Unmarshal(doc.Value, &handoff); err != nil {
+				logger.Log.Error("error while unmarshal handoff of vbID: %v, err: %v", vbID, err)
+				return nil
+			}
+
+			isHeld[i] = !handoff.Released && handoff.Owner != h.owner
+
+			return nil
+		})
+	}
+
+	_ = eg.Wait()
+
+	var result []uint16
+	for i, vbID := range vbIDs {
+		if isHeld[i] {
+			result = append(result, vbID)
+		}
+	}
+
+	return result
+}
+
+func (h *vBucketHandoff) write(vbIDs []uint16, released bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Checkpoint.Timeout)
+	defer cancel()
+
+	payload, err := sonic.Marshal(handoffDocument{Owner: h.owner, Released: released})
+	if err != nil {
+		return err
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	for _, vbID := range vbIDs {
+		id := getHandoffID(vbID, h.config.Dcp.Group.Name)
+
+		eg.Go(func() error {
+			return CreateDocument(ctx, h.client.GetMetaAgent(), h.scopeName, h.collectionName, id, payload, helpers.JSONFlags, 0)
+		})
+	}
+
+	return eg.Wait()
+}
+
+func NewVBucketHandoff(client Client, config *config.Dcp) VBucketHandoff {
+	couchbaseMetadata := config.GetCouchbaseMetadata()
+
+	return &vBucketHandoff{
+		client:         client,
+		config:         config,
+		owner:          uuid.New().String(),
+		scopeName:      couchbaseMetadata.Scope,
+		collectionName: couchbaseMetadata.Collection,
+	}
+}
+
+func getHandoffID(vbID uint16, groupName string) []byte {
+	// _connector:cbgo:groupName:handoff:vbId
+	return []byte(helpers.Prefix + groupName + ":handoff:" + strconv.Itoa(int(vbID)))
+}
@@ -18,6 +18,7 @@ type Version struct {
 var (
 	SrvVer550 = &Version{5, 5, 0, 0}
 	SrvVer650 = &Version{6, 5, 0, 0}
+	SrvVer700 = &Version{7, 0, 0, 0}
 	SrvVer720 = &Version{7, 2, 0, 0}
 )
 
@@ -61,6 +62,10 @@ func (v *Version) Lower(ov *Version) bool {
 	return !v.Higher(ov) && !v.Equal(ov)
 }
 
+func (v *Version) String() string {
+	return fmt.Sprintf("%d.%d.%d-%d", v.Major, v.Minor, v.Patch, v.Build)
+}
+
 func nodeVersionFromString(version string) (*Version, error) {
 	vSplit := strings.Split(version, ".")
 	lenSplit := len(vSplit)
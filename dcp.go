@@ -2,13 +2,17 @@ package dcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/bytedance/sonic"
@@ -28,6 +32,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/Trendyol/go-dcp/api"
+	"github.com/Trendyol/go-dcp/clock"
 	"github.com/Trendyol/go-dcp/config"
 	"github.com/Trendyol/go-dcp/couchbase"
 	"github.com/Trendyol/go-dcp/helpers"
@@ -35,20 +40,45 @@ import (
 	"github.com/Trendyol/go-dcp/metadata"
 	"github.com/Trendyol/go-dcp/models"
 	"github.com/Trendyol/go-dcp/servicediscovery"
+	"github.com/Trendyol/go-dcp/state"
 	"github.com/Trendyol/go-dcp/stream"
 )
 
 type Dcp interface {
 	WaitUntilReady() chan struct{}
+	Connect(ctx context.Context) error
 	Start()
 	Close()
 	Commit()
+	CommitAsync() <-chan error
+	Pause()
+	Resume()
+	SetMaintenanceMode(enabled bool)
+	SetHoldMode(enabled bool)
+	VerifyCheckpoints() ([]stream.CheckpointIssue, error)
+	ExportCheckpoints(w io.Writer, format string) error
+	SetTotalMembers(totalMembers int) error
+	ResetOffsets(policy stream.ResetPolicy, vbIDs []uint16) error
+	ImportCheckpoints(r io.Reader) error
+	Bootstrap() ([]metadata.BootstrapStep, error)
+	GetWatermarks() *stream.Watermarks
 	GetClient() couchbase.Client
 	GetConfig() *config.Dcp
 	GetVersion() *couchbase.Version
+	GetCapabilities() *models.CapabilityReport
+	GetAPI() api.API
+	GetMetricsSnapshot() *stream.MetricsSnapshot
+	RegisterLeaderTask(task models.LeaderTask)
+	OnShutdown(priority int, fn func(ctx context.Context))
 	SetMetadata(metadata metadata.Metadata)
+	SetStateBackend(backend state.Backend)
+	SetClock(clock clock.Clock)
 	SetMetricCollectors(collectors ...prometheus.Collector)
 	SetEventHandler(handler models.EventHandler)
+	SetValidators(validators ...models.Validator)
+	SetDecorators(decorators ...models.Decorator)
+	SetIDGenerator(generator models.IDGenerator)
+	SetDownstreamProbe(probe models.HealthProbe)
 }
 
 type dcp struct {
@@ -56,28 +86,56 @@ type dcp struct {
 	stream           stream.Stream
 	api              api.API
 	leaderElection   stream.LeaderElection
+	leaderTasks      stream.LeaderTaskRunner
 	vBucketDiscovery stream.VBucketDiscovery
 	serviceDiscovery servicediscovery.ServiceDiscovery
 	metadata         metadata.Metadata
+	stateBackend     state.Backend
+	clock            clock.Clock
 	eventHandler     models.EventHandler
 	client           couchbase.Client
 	apiShutdown      chan struct{}
 	config           *config.Dcp
 	version          *couchbase.Version
+	capabilities     *models.CapabilityReport
 	bucketInfo       *couchbase.BucketInfo
 	healthCheck      couchbase.HealthCheck
+	splitBrainGuard  couchbase.SplitBrainGuard
+	groupHeartbeat   couchbase.GroupHeartbeat
+	downstreamHealth stream.DownstreamHealth
+	nodeHealth       couchbase.NodeHealthMonitor
+	downstreamProbe  models.HealthProbe
+	totalMembers     couchbase.TotalMembersWatcher
 	consumer         models.Consumer
 	readyCh          chan struct{}
 	cancelCh         chan os.Signal
 	stopCh           chan struct{}
 	metricCollectors []prometheus.Collector
+	validators       []models.Validator
+	decorators       []models.Decorator
+	idGenerator      models.IDGenerator
+	shutdownHooks    []shutdownHook
 	closeWithCancel  bool
+	connected        bool
+	idleIntake       bool
+	apiLock          sync.RWMutex
 }
 
 func (s *dcp) SetMetadata(metadata metadata.Metadata) {
 	s.metadata = metadata
 }
 
+// SetStateBackend sets the backend of the state store, it overrides the state config.
+func (s *dcp) SetStateBackend(backend state.Backend) {
+	s.stateBackend = backend
+}
+
+// SetClock sets the clock of the checkpoint schedule, rebalance delays and rollback mitigation polling.
+// A clock.Fake drives them in tests without sleeps.
+func (s *dcp) SetClock(clock clock.Clock) {
+	s.clock = clock
+}
+
 func (s *dcp) SetMetricCollectors(metricCollectors ...prometheus.Collector) {
 	s.metricCollectors = append(s.metricCollectors, metricCollectors...)
 }
@@ -86,33 +144,205 @@ func (s *dcp) SetEventHandler(eventHandler models.EventHandler) {
 	s.eventHandler = eventHandler
 }
 
+// SetValidators sets the validators which check the events before they are delivered to the consumer.
+// Rejected events are handled with dcp.validation.policy.
+func (s *dcp) SetValidators(validators ...models.Validator) {
+	s.validators = append(s.validators, validators...)
+}
+
+// SetDecorators sets the decorators which attach metadata to every event before it is delivered to the consumer.
+// The metadata is passed with the event and written into its binary envelope and dead letter.
+func (s *dcp) SetDecorators(decorators ...models.Decorator) {
+	s.decorators = append(s.decorators, decorators...)
+}
+
+// SetIDGenerator sets the generator of the event ids, the id is passed with the event and written into its binary
+// envelope and dead letter.
+func (s *dcp) SetIDGenerator(generator models.IDGenerator) {
+	s.idGenerator = generator
+}
+
+// SetDownstreamProbe probes the sink of the consumer with downstreamHealth settings instead of downstreamHealth.type.
+func (s *dcp) SetDownstreamProbe(probe models.HealthProbe) {
+	s.downstreamProbe = probe
+}
+
+// RegisterLeaderTask registers a task which runs only on the leader member of the group.
+// It requires leader election to be enabled, leader election is supported by the kubernetes type only.
+func (s *dcp) RegisterLeaderTask(task models.LeaderTask) {
+	s.leaderTasks.Register(task)
+}
+
+// OnShutdown registers a cleanup, e.g. flushing a sink, which runs when the dcp closes. The hooks run after the
+// stream is drained and before the final checkpoint, from the lowest priority to the highest.
+func (s *dcp) OnShutdown(priority int, fn func(ctx context.Context)) {
+	s.shutdownHooks = append(s.shutdownHooks, shutdownHook{priority: priority, fn: fn})
+}
+
+func (s *dcp) newStateBackend() state.Backend {
+	switch s.config.State.Type {
+	case "":
+		return nil
+	case config.StateTypeMemory:
+		return state.NewMemoryBackend()
+	case config.StateTypeBbolt:
+		backend, err := state.NewBoltBackend(s.config.State.FileName, s.config.Dcp.Group.Name)
+		if err != nil {
+			logger.Log.Error("error while open state file, err: %v", err)
+			panic(err)
+		}
+		return backend
+	case config.StateTypeCouchbase:
+		return couchbase.NewCBStateBackend(s.client, s.config)
+	default:
+		err := errors.New("invalid state type")
+		logger.Log.Error("error while dcp start, err: %v", err)
+		panic(err)
+	}
+}
+
+// newStateHandoff moves the state through the metadata bucket when the state backend is not shared by the members.
+func (s *dcp) newStateHandoff() state.Handoff {
+	if s.stateBackend == nil || !s.config.State.Handoff || s.config.State.Type == config.StateTypeCouchbase {
+		return nil
+	}
+
+	return couchbase.NewCBStateHandoff(s.client, s.config)
+}
+
 func (s *dcp) membershipChangedListener(_ *membership.Model) {
 	s.stream.Rebalance()
 }
 
+// initMetadata creates the metadata of metadata.type unless it is set with SetMetadata.
+func (s *dcp) initMetadata() error {
+	if s.metadata != nil {
+		return nil
+	}
+
+	m, err := s.buildMetadata()
+	if err != nil {
+		return err
+	}
+
+	s.metadata = m
+
+	return nil
+}
+
+func (s *dcp) buildMetadata() (metadata.Metadata, error) {
+	m, err := s.newMetadata(s.config)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.Metadata.Secondary.Type != "" {
+		secondary, err := s.newMetadata(s.config.GetSecondaryMetadataConfig())
+		if err != nil {
+			return nil, fmt.Errorf("secondary metadata type: %s, err: %w", s.config.Metadata.Secondary.Type, err)
+		}
+
+		m = metadata.NewDualMetadata(m, secondary)
+		logger.Log.Info("checkpoints are written to secondary metadata type: %s", s.config.Metadata.Secondary.Type)
+	}
+
+	if s.config.Metadata.Fast.Type != "" {
+		fast, err := s.newMetadata(s.config.GetFastMetadataConfig())
+		if err != nil {
+			return nil, fmt.Errorf("fast metadata type: %s, err: %w", s.config.Metadata.Fast.Type, err)
+		}
+
+		m = metadata.NewTieredMetadata(fast, m, s.config.Metadata.Fast.DurableInterval)
+		logger.Log.Info("checkpoints are written to fast metadata type: %s, durable metadata is written every %v",
+			s.config.Metadata.Fast.Type, s.config.Metadata.Fast.DurableInterval)
+	}
+
+	if s.config.Metadata.ReadOnly {
+		m = metadata.NewReadMetadata(m)
+	}
+
+	return m, nil
+}
+
+// initLeaderMetadata makes the leader the only member writing to the metadata with checkpoint.writer leader,
+// the metadata set with SetMetadata or metadata.type is created once this member becomes the leader.
+func (s *dcp) initLeaderMetadata() error {
+	if !s.config.LeaderElection.Enabled {
+		return errors.New("checkpoint writer leader requires leader election to be enabled")
+	}
+
+	local := s.metadata
+
+	s.serviceDiscovery = servicediscovery.NewServiceDiscovery(s.config, s.bus)
+
+	leaderMetadata := servicediscovery.NewLeaderMetadata(s.config, s.serviceDiscovery, func() (metadata.Metadata, error) {
+		if local != nil {
+			return local, nil
+		}
+
+		return s.buildMetadata()
+	})
+
+	s.serviceDiscovery.SetCheckpointWriter(leaderMetadata)
+	s.metadata = leaderMetadata
+
+	return nil
+}
+
+func (s *dcp) newMetadata(c *config.Dcp) (metadata.Metadata, error) {
+	if c.IsCouchbaseMetadata() {
+		return couchbase.NewCBMetadata(s.client, c), nil
+	}
+
+	m, err := metadata.New(c.Metadata.Type, c)
+	if errors.Is(err, metadata.ErrMetadataTypeNotRegistered) {
+		return nil, fmt.Errorf("%w: %s, import its package to register it", err, c.Metadata.Type)
+	}
+
+	return m, err
+}
+
+// Start connects when Connect is not called before, and blocks until the dcp is closed.
+//
 //nolint:funlen
 func (s *dcp) Start() {
-	if s.metadata == nil {
-		switch {
-		case s.config.IsCouchbaseMetadata():
-			s.metadata = couchbase.NewCBMetadata(s.client, s.config)
-		case s.config.IsFileMetadata():
-			s.metadata = metadata.NewFSMetadata(s.config)
-		default:
-			err := errors.New("invalid metadata type")
+	if err := s.Connect(context.Background()); err != nil {
+		logger.Log.Error("error while dcp start, err: %v", err)
+		panic(err)
+	}
+
+	if s.config.IsLeaderCheckpointWriter() {
+		if err := s.initLeaderMetadata(); err != nil {
 			logger.Log.Error("error while dcp start, err: %v", err)
 			panic(err)
 		}
+	} else if err := s.initMetadata(); err != nil {
+		logger.Log.Error("error while dcp start, metadata type: %s, registered: %v, err: %v",
+			s.config.Metadata.Type, metadata.Types(), err)
+		panic(err)
 	}
 
-	if s.config.Metadata.ReadOnly {
-		s.metadata = metadata.NewReadMetadata(s.metadata)
+	logger.Log.Info("using %v metadata", reflect.TypeOf(s.metadata))
+
+	if s.stateBackend == nil {
+		s.stateBackend = s.newStateBackend()
 	}
 
-	logger.Log.Info("using %v metadata", reflect.TypeOf(s.metadata))
+	if s.clock == nil {
+		s.clock = clock.New()
+	}
+
+	if s.isReconfigurable() {
+		s.applyStoredTotalMembers()
+	}
 
 	vBuckets := s.client.GetNumVBuckets()
 
+	if err := configureDecoding(s.consumer, s.client, s.config); err != nil {
+		logger.Log.Error("error while dcp start, err: %v", err)
+		panic(err)
+	}
+
 	s.vBucketDiscovery = stream.NewVBucketDiscovery(s.client, s.config, vBuckets, s.bus)
 
 	tc := tracing.NewTracerComponent()
@@ -125,33 +355,124 @@ func (s *dcp) Start() {
 
 	s.stream = stream.NewStream(
 		s.client, s.metadata, s.config, s.version, s.bucketInfo, s.vBucketDiscovery,
-		s.consumer, collectionIDs, s.stopCh, s.eventHandler, tc,
+		s.consumer, collectionIDs, s.stopCh, s.eventHandler, tc, s.stateBackend, s.newStateHandoff(),
+		s.clock, s.validators, s.idGenerator, s.decorators,
 	)
 
+	s.vBucketDiscovery.SetLivenessProbe(s.stream.IsProgressing)
+
 	if s.config.LeaderElection.Enabled {
-		s.serviceDiscovery = servicediscovery.NewServiceDiscovery(s.config, s.bus)
+		if s.serviceDiscovery == nil {
+			s.serviceDiscovery = servicediscovery.NewServiceDiscovery(s.config, s.bus)
+		}
+
 		s.serviceDiscovery.StartHeartbeat()
 		s.serviceDiscovery.StartMonitor()
 
-		s.leaderElection = stream.NewLeaderElection(s.config, s.serviceDiscovery, s.bus)
+		if s.config.Metadata.GC.Enabled && s.config.IsCouchbaseMetadata() {
+			s.leaderTasks.Register(couchbase.NewMetadataGC(s.client, s.config))
+		}
+
+		s.leaderElection = stream.NewLeaderElection(s.config, s.serviceDiscovery, s.bus, s.leaderTasks)
 		s.leaderElection.Start()
+	} else {
+		logger.Log.Debug("leader tasks will not run, leader election is disabled")
+	}
+
+	if s.config.IsCouchbaseMetadata() && !s.config.Metadata.ReadOnly {
+		s.groupHeartbeat = couchbase.NewGroupHeartbeat(s.client, s.config)
+	}
+
+	if s.config.Dcp.Group.Membership.SplitBrain.Enabled {
+		if s.config.IsCouchbaseMetadata() {
+			s.splitBrainGuard = couchbase.NewSplitBrainGuard(s.client, s.config, s.membershipSlot, s.splitBrainChanged)
+		} else {
+			logger.Log.Warn("split brain detection is disabled, it requires couchbase metadata")
+		}
+	}
+
+	if s.downstreamProbe != nil || s.config.DownstreamHealth.Type != "" {
+		s.downstreamHealth, err = stream.NewDownstreamHealth(
+			&s.config.DownstreamHealth, s.downstreamProbe, s.downstreamHealthChanged, s.clock,
+		)
+		if err != nil {
+			logger.Log.Error("error while create downstream health, err: %v", err)
+			panic(err)
+		}
+	}
+
+	if !s.config.NodeHealth.Disabled {
+		s.nodeHealth = couchbase.NewNodeHealthMonitor(s.client, s.config, s.nodeConnectionChanged, s.dcpConnectionStale)
 	}
 
 	if !s.config.API.Disabled {
-		go func() {
+		if s.nodeHealth != nil {
+			s.metricCollectors = append(s.metricCollectors, metric.NewNodeHealthCollector(s.nodeHealth))
+		}
+
+		if s.splitBrainGuard != nil {
+			s.metricCollectors = append(s.metricCollectors, metric.NewSplitBrainCollector(s.splitBrainGuard))
+		}
+
+		if s.downstreamHealth != nil {
+			s.metricCollectors = append(s.metricCollectors, metric.NewDownstreamHealthCollector(s.downstreamHealth))
+		}
+
+		m := s.metadata
+
+		if tiered, ok := m.(*metadata.TieredMetadata); ok {
+			s.metricCollectors = append(s.metricCollectors, metric.NewTieredMetadataCollector(tiered))
+			m = tiered.Durable()
+		}
+
+		if dual, ok := m.(*metadata.DualMetadata); ok {
+			s.metricCollectors = append(s.metricCollectors, metric.NewDualMetadataCollector(dual))
+		}
+
+		s.metricCollectors = append(s.metricCollectors, metric.NewMetricCollector(s.client, s.stream, s.vBucketDiscovery, &s.config.Metric))
+		dcpAPI := api.NewAPI(s.config, s.client, s.stream, s.serviceDiscovery, s.metricCollectors, s.bus, s.capabilities)
+
+		s.apiLock.Lock()
+		s.api = dcpAPI
+		s.apiLock.Unlock()
+
+		if s.config.API.ListenerDisabled {
+			logger.Log.Info("api listener is disabled, handlers can be mounted from GetAPI()")
+		} else {
 			go func() {
-				<-s.apiShutdown
-				s.api.Shutdown()
-			}()
+				go func() {
+					<-s.apiShutdown
+					dcpAPI.Shutdown()
+				}()
 
-			s.metricCollectors = append(s.metricCollectors, metric.NewMetricCollector(s.client, s.stream, s.vBucketDiscovery))
-			s.api = api.NewAPI(s.config, s.client, s.stream, s.serviceDiscovery, s.metricCollectors, s.bus)
-			s.api.Listen()
-		}()
+				dcpAPI.Listen()
+			}()
+		}
 	}
 
 	s.stream.Open()
 
+	if s.groupHeartbeat != nil {
+		s.groupHeartbeat.Start()
+	}
+
+	if s.splitBrainGuard != nil {
+		s.splitBrainGuard.Start()
+	}
+
+	if s.downstreamHealth != nil {
+		s.downstreamHealth.Start()
+	}
+
+	if s.nodeHealth != nil {
+		s.nodeHealth.Start()
+	}
+
+	if s.isReconfigurable() {
+		s.totalMembers = couchbase.NewTotalMembersWatcher(s.client, s.config, s.totalMembersChanged)
+		s.totalMembers.Start()
+	}
+
 	err = s.bus.SubscribeAsync(helpers.MembershipChangedBusEventName, s.membershipChangedListener, true)
 	if err != nil {
 		logger.Log.Error("error while subscribe to membership changed event, err: %v", err)
@@ -180,6 +501,134 @@ func (s *dcp) Start() {
 	s.close()
 }
 
+// isReconfigurable reports whether totalMembers of a static membership is read from the couchbase metadata.
+func (s *dcp) isReconfigurable() bool {
+	if !s.config.Dcp.Group.Membership.Reconfigure.Enabled {
+		return false
+	}
+
+	if s.config.Dcp.Group.Membership.Type != membership.StaticMembershipType || !s.config.IsCouchbaseMetadata() {
+		logger.Log.Warn("membership reconfigure is disabled, it requires static membership and couchbase metadata")
+		return false
+	}
+
+	return true
+}
+
+// applyStoredTotalMembers starts the member with the stored totalMembers, so a restarted member does not
+// stream with the outdated totalMembers of its config.
+func (s *dcp) applyStoredTotalMembers() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	totalMembers, ok, err := couchbase.GetTotalMembers(ctx, s.client, s.config)
+	if err != nil {
+		logger.Log.Error("error while get stored total members, err: %v", err)
+		panic(err)
+	}
+
+	if ok && totalMembers != s.config.Dcp.Group.Membership.TotalMembers {
+		logger.Log.Info("using stored total members: %v instead of config: %v", totalMembers, s.config.Dcp.Group.Membership.TotalMembers)
+		s.config.Dcp.Group.Membership.TotalMembers = totalMembers
+	}
+}
+
+// totalMembersChanged reassigns the vBuckets of the member with the stored totalMembers. Every member closes its
+// streams when it picks up the change and opens them after the rebalance delay, so the delay must be longer than
+// the reconfigure interval. A member whose number is out of totalMembers pauses its intake till it is included again.
+func (s *dcp) totalMembersChanged(totalMembers int) {
+	info := &membership.Model{
+		MemberNumber: s.config.Dcp.Group.Membership.MemberNumber,
+		TotalMembers: totalMembers,
+	}
+
+	if info.MemberNumber > info.TotalMembers {
+		logger.Log.Warn("member: %v is out of total members: %v, member is idle", info.MemberNumber, info.TotalMembers)
+
+		if !s.idleIntake {
+			s.stream.Save()
+			s.stream.PauseFor(stream.PauseReasonIdleMember)
+			s.idleIntake = true
+		}
+		return
+	}
+
+	s.bus.Publish(helpers.MembershipChangedBusEventName, info)
+
+	if s.idleIntake {
+		// The streams of the previous vBuckets are closed before the intake is resumed.
+		s.stream.Rebalance()
+		s.idleIntake = false
+		s.stream.ResumeFor(stream.PauseReasonIdleMember)
+	}
+}
+
+// SetTotalMembers changes totalMembers of a static membership group without a restart. It is stored in the couchbase
+// metadata and picked up by every member with dcp.group.membership.reconfigure enabled.
+func (s *dcp) SetTotalMembers(totalMembers int) error {
+	if totalMembers < 1 {
+		return errors.New("total members must be positive")
+	}
+
+	if !s.config.IsCouchbaseMetadata() {
+		return errors.New("total members can be set with couchbase metadata only")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	return couchbase.SetTotalMembers(ctx, s.client, s.config, totalMembers)
+}
+
+func (s *dcp) membershipSlot() (int, int) {
+	vBucketDiscoveryMetric := s.vBucketDiscovery.GetMetric()
+	return vBucketDiscoveryMetric.MemberNumber, vBucketDiscoveryMetric.TotalMembers
+}
+
+// splitBrainChanged pauses the intake while the membership is claimed by another member, and clears the pause
+// when the membership is claimed back. The intake stays paused while any other pause reason is set.
+func (s *dcp) splitBrainChanged(event models.SplitBrain) {
+	if event.Fenced {
+		s.stream.PauseFor(stream.PauseReasonSplitBrain)
+	} else {
+		s.stream.ResumeFor(stream.PauseReasonSplitBrain)
+	}
+
+	if handler, ok := s.eventHandler.(models.SplitBrainHandler); ok {
+		handler.OnSplitBrain(event)
+	}
+}
+
+// downstreamHealthChanged pauses the intake while the downstream is unhealthy, and clears the pause when the
+// downstream is healthy again. The intake stays paused while any other pause reason is set.
+func (s *dcp) downstreamHealthChanged(healthy bool) {
+	if healthy {
+		s.stream.ResumeFor(stream.PauseReasonUnhealthyDownstream)
+	} else {
+		s.stream.PauseFor(stream.PauseReasonUnhealthyDownstream)
+	}
+}
+
+func (s *dcp) nodeConnectionChanged(event models.NodeConnectionChanged) {
+	if event.Connected {
+		logger.Log.Info("dcp connections to node %s are connected, reconnects: %d", event.Address, event.Reconnects)
+	} else {
+		logger.Log.Warn("dcp connections to node %s are lost, reconnecting, last error: %s", event.Address, event.LastError)
+	}
+
+	if handler, ok := s.eventHandler.(models.NodeConnectionHandler); ok {
+		handler.OnNodeConnectionChanged(event)
+	}
+}
+
+func (s *dcp) dcpConnectionStale(event models.DcpConnectionStale) {
+	logger.Log.Warn("dcp connection to node %s is stale, nothing is received for %v", event.Address, event.IdleFor)
+
+	if handler, ok := s.eventHandler.(models.DcpConnectionStaleHandler); ok {
+		handler.OnDcpConnectionStale(event)
+	}
+}
+
 func (s *dcp) GetClient() couchbase.Client {
 	return s.client
 }
@@ -192,12 +641,42 @@ func (s *dcp) Close() {
 	s.cancelCh <- syscall.SIGTERM
 }
 
+// stop closes the dcp without blocking when a close is already requested.
+func (s *dcp) stop() {
+	select {
+	case s.cancelCh <- syscall.SIGTERM:
+	default:
+	}
+}
+
 func (s *dcp) close() {
 	if !s.config.HealthCheck.Disabled {
 		s.healthCheck.Stop()
 	}
 	s.vBucketDiscovery.Close()
 
+	if s.groupHeartbeat != nil {
+		s.groupHeartbeat.Stop()
+	}
+
+	if s.splitBrainGuard != nil {
+		s.splitBrainGuard.Stop()
+	}
+
+	if s.downstreamHealth != nil {
+		s.downstreamHealth.Stop()
+	}
+
+	if s.nodeHealth != nil {
+		s.nodeHealth.Stop()
+	}
+
+	if s.totalMembers != nil {
+		s.totalMembers.Stop()
+	}
+
+	s.shutdown()
+
 	if s.config.Checkpoint.Type == stream.CheckpointTypeAuto {
 		s.stream.Save()
 	}
@@ -209,6 +688,18 @@ func (s *dcp) close() {
 
 	s.stream.Close(s.closeWithCancel)
 
+	m := s.metadata
+
+	if leaderMetadata, ok := m.(*servicediscovery.LeaderMetadata); ok {
+		m = leaderMetadata.Local()
+	}
+
+	if tiered, ok := m.(*metadata.TieredMetadata); ok {
+		if err := tiered.Flush(); err != nil {
+			logger.Log.Error("error while flushing checkpoint to durable metadata, err: %v", err)
+		}
+	}
+
 	if s.config.LeaderElection.Enabled {
 		s.leaderElection.Stop()
 
@@ -216,15 +707,17 @@ func (s *dcp) close() {
 		s.serviceDiscovery.StopHeartbeat()
 	}
 
-	if s.api != nil && !s.config.API.Disabled {
+	dcpAPI := s.GetAPI()
+
+	if dcpAPI != nil && !s.config.API.Disabled && !s.config.API.ListenerDisabled {
 		s.apiShutdown <- struct{}{}
 	}
 
 	s.client.DcpClose()
 	s.client.Close()
 
-	if s.api != nil && !s.config.API.Disabled {
-		s.api.UnregisterMetricCollectors()
+	if dcpAPI != nil && !s.config.API.Disabled {
+		dcpAPI.UnregisterMetricCollectors()
 	}
 
 	s.metricCollectors = []prometheus.Collector{}
@@ -232,77 +725,329 @@ func (s *dcp) close() {
 	logger.Log.Info("dcp stream closed")
 }
 
+// Commit saves the checkpoint and returns when it is persisted. Commits within checkpoint.commitWindow are saved
+// together.
 func (s *dcp) Commit() {
-	s.stream.Save()
+	<-s.stream.Commit()
+}
+
+// CommitAsync commits like Commit without waiting, the channel receives the result once the checkpoint is saved.
+func (s *dcp) CommitAsync() <-chan error {
+	return s.stream.Commit()
+}
+
+// Pause stops dispatching events to the consumer without closing dcp streams.
+func (s *dcp) Pause() {
+	if s.stream != nil {
+		s.stream.Pause()
+	}
+}
+
+func (s *dcp) Resume() {
+	if s.stream != nil {
+		s.stream.Resume()
+	}
+}
+
+// SetMaintenanceMode defers the rebalances of membership changes while it is enabled, e.g. during a rolling
+// deployment. The latest membership is applied with one rebalance when it is disabled.
+func (s *dcp) SetMaintenanceMode(enabled bool) {
+	if s.stream != nil {
+		s.stream.SetMaintenanceMode(enabled)
+	}
+}
+
+// SetHoldMode holds the position while it is enabled, e.g. during a maintenance of the downstream. Events are
+// streamed for the stats and the lag but not passed to the consumer, and the offsets do not advance. It is applied
+// when the dcp starts if it is set before.
+func (s *dcp) SetHoldMode(enabled bool) {
+	if s.stream == nil {
+		s.config.Dcp.Hold = enabled
+		return
+	}
+
+	s.stream.SetHoldMode(enabled)
+}
+
+// VerifyCheckpoints reports the saved checkpoints which do not match the failover logs or seqnos of the server.
+func (s *dcp) VerifyCheckpoints() ([]stream.CheckpointIssue, error) {
+	if s.stream == nil {
+		return nil, errors.New("dcp is not started")
+	}
+
+	return s.stream.VerifyCheckpoints()
+}
+
+// ExportCheckpoints writes a json or cbor snapshot of the checkpoints of all vBuckets of the group. The offsets are
+// saved first when the dcp is started, it can be called without starting the dcp to export the saved checkpoints.
+func (s *dcp) ExportCheckpoints(w io.Writer, format string) error {
+	if s.stream != nil {
+		return s.stream.Export(w, format)
+	}
+
+	if err := s.connectMetadata(); err != nil {
+		return err
+	}
+
+	bucketUUID, err := s.getBucketUUID()
+	if err != nil {
+		return err
+	}
+
+	vbIDs := make([]uint16, s.client.GetNumVBuckets())
+	for i := range vbIDs {
+		vbIDs[i] = uint16(i)
+	}
+
+	return metadata.Export(w, format, s.metadata, s.config.Dcp.Group.Name, vbIDs, bucketUUID)
+}
+
+// ImportCheckpoints saves the checkpoints of a snapshot written by ExportCheckpoints to the metadata.
+// The stream of a started dcp is opened again from the imported checkpoints.
+func (s *dcp) ImportCheckpoints(r io.Reader) error {
+	if s.stream != nil {
+		return s.stream.Import(r)
+	}
+
+	if err := s.connectMetadata(); err != nil {
+		return err
+	}
+
+	bucketUUID, err := s.getBucketUUID()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := metadata.Import(r, s.metadata, bucketUUID)
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Info("imported %d checkpoints of group: %s taken at %v", len(snapshot.Checkpoints), snapshot.GroupName, snapshot.CreatedAt)
+	return nil
+}
+
+// Bootstrap creates the resources the metadata needs and checks the permissions of the member without starting the dcp,
+// so provisioners can run it before the first deployment. Running it again changes nothing.
+func (s *dcp) Bootstrap() ([]metadata.BootstrapStep, error) {
+	if err := s.connectMetadata(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	var steps []metadata.BootstrapStep
+
+	if bootstrapper, ok := s.metadata.(metadata.Bootstrapper); ok {
+		created, err := bootstrapper.Bootstrap(ctx)
+		steps = append(steps, created...)
+		if err != nil {
+			return steps, err
+		}
+	}
+
+	bucketUUID, err := s.getBucketUUID()
+	if err != nil {
+		return steps, err
+	}
+
+	if _, _, err = s.metadata.Load([]uint16{0}, bucketUUID); err != nil {
+		return steps, fmt.Errorf("checkpoints cannot be read, err: %w", err)
+	}
+
+	steps = append(steps, metadata.BootstrapStep{Name: "checkpoint read", Status: metadata.BootstrapStatusPassed})
+
+	if _, err = s.client.GetFailOverLogs(0); err != nil {
+		return steps, fmt.Errorf("dcp streams cannot be opened, check the data dcp reader role, err: %w", err)
+	}
+
+	steps = append(steps, metadata.BootstrapStep{Name: "dcp stream", Status: metadata.BootstrapStatusPassed})
+
+	return steps, nil
+}
+
+// connectMetadata connects a dcp constructed with New, as Start does, and initializes the metadata for the operations
+// which run without starting the dcp.
+func (s *dcp) connectMetadata() error {
+	if err := s.Connect(context.Background()); err != nil {
+		return err
+	}
+
+	return s.initMetadata()
+}
+
+func (s *dcp) getBucketUUID() (string, error) {
+	snapshot, err := s.client.GetDcpAgentConfigSnapshot()
+	if err != nil {
+		return "", err
+	}
+
+	return snapshot.BucketUUID(), nil
+}
+
+// ResetOffsets rewrites the checkpoints of the vBuckets of the member with the policy and opens the stream again.
+func (s *dcp) ResetOffsets(policy stream.ResetPolicy, vbIDs []uint16) error {
+	if s.stream == nil {
+		return errors.New("dcp is not started")
+	}
+
+	return s.stream.ResetOffsets(policy, vbIDs)
+}
+
+// GetWatermarks returns nil until the dcp is started.
+func (s *dcp) GetWatermarks() *stream.Watermarks {
+	if s.stream == nil {
+		return nil
+	}
+
+	return s.stream.GetWatermarks()
 }
 
 func (s *dcp) GetConfig() *config.Dcp {
 	return s.config
 }
 
+// GetAPI returns the api of a started dcp, nil when the api is disabled. It is safe to call while the dcp starts.
+func (s *dcp) GetAPI() api.API {
+	s.apiLock.RLock()
+	defer s.apiLock.RUnlock()
+
+	return s.api
+}
+
 func (s *dcp) GetVersion() *couchbase.Version {
 	return s.version
 }
 
+// GetCapabilities returns the features negotiated with the server, it is nil until the dcp is connected.
+func (s *dcp) GetCapabilities() *models.CapabilityReport {
+	return s.capabilities
+}
+
+// GetMetricsSnapshot returns nil until the dcp is started.
+func (s *dcp) GetMetricsSnapshot() *stream.MetricsSnapshot {
+	if s.stream == nil {
+		return nil
+	}
+
+	return s.stream.GetMetricsSnapshot()
+}
+
 func newDcp(config *config.Dcp, consumer models.Consumer) (Dcp, error) {
+	d, err := newDeferredDcp(config, consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = d.Connect(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// newDeferredDcp applies the defaults of the config without any connection, they are made by Connect or Start.
+func newDeferredDcp(config *config.Dcp, consumer models.Consumer) (Dcp, error) {
+	if len(config.Buckets) > 0 {
+		return newMultiBucketDcp(config, sharedConsumer(consumer))
+	}
+
 	config.ApplyDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	copyOfConfig := config
 	printConfiguration(*copyOfConfig)
 
-	client := couchbase.NewClient(config)
+	return &dcp{
+		client:           couchbase.NewClient(config),
+		consumer:         consumer,
+		config:           config,
+		apiShutdown:      make(chan struct{}, 1),
+		cancelCh:         make(chan os.Signal, 1),
+		stopCh:           make(chan struct{}, 1),
+		readyCh:          make(chan struct{}, 1),
+		metricCollectors: []prometheus.Collector{},
+		eventHandler:     models.DefaultEventHandler,
+		bus:              EventBus.New(),
+		leaderTasks:      stream.NewLeaderTaskRunner(),
+	}, nil
+}
+
+// Connect connects to the cluster and opens the dcp connection. It returns nil when the dcp is connected already.
+// The context is checked between the connection steps, each of them is bounded by dcp.connectionTimeout.
+func (s *dcp) Connect(ctx context.Context) error {
+	if s.connected {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	err := client.Connect()
+	err := s.client.Connect()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	httpClient := couchbase.NewHTTPClient(config, client)
+	httpClient := couchbase.NewHTTPClient(s.config, s.client)
 
 	err = httpClient.Connect()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	version, err := httpClient.GetVersion()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	bucketInfo, err := httpClient.GetBucketInfo()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if err = ctx.Err(); err != nil {
+		s.client.Close()
+		return err
 	}
 
 	var useExpiryOpcode bool
 	var useChangeStreams bool
+	var useOSOBackfill bool
 
 	if version.Higher(couchbase.SrvVer650) || version.Equal(couchbase.SrvVer650) {
 		useExpiryOpcode = true
 	}
 
-	if bucketInfo.IsMagma() && (version.Higher(couchbase.SrvVer720) || version.Equal(couchbase.SrvVer720)) {
+	if couchbase.IsChangeHistorySupported(version, bucketInfo) && !s.config.Dcp.Config.DisableChangeStreams {
 		useChangeStreams = true
 	}
 
-	err = client.DcpConnect(useExpiryOpcode, useChangeStreams)
+	if couchbase.IsOSOBackfillSupported(version) && s.config.Dcp.Config.EnableOSOBackfill {
+		useOSOBackfill = true
+	}
+
+	err = s.client.DcpConnect(useExpiryOpcode, useChangeStreams, useOSOBackfill)
+	if useExpiryOpcode && couchbase.IsUnsupportedFeature(err) {
+		logger.Log.Warn("cannot connect dcp with expiry opcode, expirations will be delivered as deletions, err: %v", err)
+		useExpiryOpcode = false
+		err = s.client.DcpConnect(false, useChangeStreams, useOSOBackfill)
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &dcp{
-		client:           client,
-		consumer:         consumer,
-		config:           config,
-		version:          version,
-		bucketInfo:       bucketInfo,
-		apiShutdown:      make(chan struct{}, 1),
-		cancelCh:         make(chan os.Signal, 1),
-		stopCh:           make(chan struct{}, 1),
-		readyCh:          make(chan struct{}, 1),
-		metricCollectors: []prometheus.Collector{},
-		eventHandler:     models.DefaultEventHandler,
-		bus:              EventBus.New(),
-	}, nil
+	s.capabilities = couchbase.NewCapabilityReport(s.client, s.config, version, bucketInfo, useExpiryOpcode)
+	couchbase.LogCapabilityReport(s.capabilities)
+
+	s.version = version
+	s.bucketInfo = bucketInfo
+	s.connected = true
+
+	return nil
 }
 
 type simplifiedConsumer struct {
@@ -336,6 +1081,20 @@ func NewExtendedDcp(cfg any, consumer models.Consumer) (Dcp, error) {
 	}
 }
 
+// New creates a new Dcp client without connecting to the cluster, so it can be built before the cluster is reachable,
+// e.g. in a dependency injection container. Connect makes the connections, Start calls it when it is not called before.
+//
+// config: path to a configuration file or a configuration struct
+// consumer must implement models.Consumer interface containing both ConsumeEvent and TrackOffset methods
+func New(cfg any, consumer models.Consumer) (Dcp, error) {
+	c, err := resolveConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDeferredDcp(c, consumer)
+}
+
 // NewDcp creates a new Dcp client
 //
 // config: path to a configuration file or a configuration struct
@@ -353,6 +1112,95 @@ func NewDcp(cfg any, listener models.Listener) (Dcp, error) {
 	}
 }
 
+// NewBatchDcp creates a new Dcp client
+//
+// config: path to a configuration file or a configuration struct
+// consumer receives the mutation, deletion and expiration events of a vBucket in batches of dcp.listener.batch settings
+func NewBatchDcp(cfg any, consumer models.BatchConsumer) (Dcp, error) {
+	c, err := resolveConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Buckets) > 0 {
+		m, err := newMultiBucketDcp(c, func(bucketConfig *config.Dcp) models.Consumer {
+			return stream.NewBatchConsumer(consumer, bucketConfig)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err = m.Connect(context.Background()); err != nil {
+			return nil, err
+		}
+
+		return m, nil
+	}
+
+	return newDcp(c, stream.NewBatchConsumer(consumer, c))
+}
+
+// NewDeadLetterDcp creates a new Dcp client
+//
+// config: path to a configuration file or a configuration struct
+// consumer reports the events it fails to process, they are written to the queue and their offsets still advance.
+// Failed events are retried before with consumer.retry settings when consumer.retry.maxAttempts is more than one.
+// When queue is nil, the queue is created from the deadLetter config.
+func NewDeadLetterDcp(cfg any, consumer models.FailableConsumer, queue models.DeadLetterQueue) (Dcp, error) {
+	c, err := resolveConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Buckets) > 0 {
+		return nil, fmt.Errorf("dead letter dcp is %w", ErrMultipleBuckets)
+	}
+
+	if queue == nil && c.DeadLetter.Type == config.DeadLetterTypeFile {
+		queue = stream.NewFileDeadLetterQueue(c.DeadLetter.FileName)
+	}
+
+	if queue == nil && c.DeadLetter.Type != config.DeadLetterTypeCouchbase {
+		return nil, errors.New("dead letter queue is not configured")
+	}
+
+	if c.Consumer.Retry.MaxAttempts > 1 {
+		consumer = stream.NewRetryConsumer(consumer, c, nil)
+	}
+
+	d, err := newDcp(c, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	connector := d.(*dcp)
+
+	if queue == nil {
+		queue = couchbase.NewCBDeadLetterQueue(connector.client, c)
+	}
+
+	connector.consumer = stream.NewDeadLetterConsumer(consumer, queue)
+
+	return connector, nil
+}
+
+func resolveConfig(cfg any) (*config.Dcp, error) {
+	switch v := cfg.(type) {
+	case *config.Dcp:
+		return v, nil
+	case config.Dcp:
+		return &v, nil
+	case string:
+		c, err := newDcpConfig(v)
+		if err != nil {
+			return nil, err
+		}
+		return &c, nil
+	default:
+		return nil, errors.New("invalid config")
+	}
+}
+
 func newDcpWithPath(path string, consumer models.Consumer) (Dcp, error) {
 	c, err := newDcpConfig(path)
 	if err != nil {
@@ -397,9 +1245,57 @@ func NewDcpWithLogger(cfg any, listener models.Listener, logrus *logrus.Logger)
 	return NewDcp(cfg, listener)
 }
 
+const maskedValue = "*****"
+
+// secretConfigKeys are the keys of the metadata, leader election and membership configs which hold credentials.
+// The sql dsn and the mongodb uri carry the credentials of their connections.
+var secretConfigKeys = map[string]bool{
+	"password":  true,
+	"token":     true,
+	"secretKey": true,
+	"dsn":       true,
+	"uri":       true,
+}
+
+func redactConfigMap(configMap map[string]string) map[string]string {
+	if configMap == nil {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(configMap))
+	for key, value := range configMap {
+		if secretConfigKeys[key] {
+			value = maskedValue
+		}
+
+		redacted[key] = value
+	}
+
+	return redacted
+}
+
+// redactConfiguration masks the password, the encryption keys and the credentials of the configs. The maps and
+// slices are copied, so the config in use is not changed.
+func redactConfiguration(c config.Dcp) config.Dcp {
+	c.Password = maskedValue
+	c.Metadata.Config = redactConfigMap(c.Metadata.Config)
+	c.Metadata.Secondary.Config = redactConfigMap(c.Metadata.Secondary.Config)
+	c.Metadata.Fast.Config = redactConfigMap(c.Metadata.Fast.Config)
+	c.LeaderElection.Config = redactConfigMap(c.LeaderElection.Config)
+	c.Dcp.Group.Membership.Config = redactConfigMap(c.Dcp.Group.Membership.Config)
+
+	keys := make([]config.MetadataEncryptionKey, len(c.Metadata.Encryption.Keys))
+	for i, key := range c.Metadata.Encryption.Keys {
+		key.Key = maskedValue
+		keys[i] = key
+	}
+	c.Metadata.Encryption.Keys = keys
+
+	return c
+}
+
 func printConfiguration(config config.Dcp) {
-	config.Password = "*****"
-	configJSON, _ := sonic.Marshal(config)
+	configJSON, _ := sonic.Marshal(redactConfiguration(config))
 
 	dst := &bytes.Buffer{}
 	if err := json.Compact(dst, configJSON); err != nil {
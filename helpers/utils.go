@@ -38,6 +38,72 @@ func ChunkSlice[T any](slice []T, chunks int) [][]T {
 	return result
 }
 
+// StickyChunkSlice splits the slice into chunks balanced like ChunkSlice. The chunks of n are the chunks of n-1 which
+// handed their last items to the new chunk, the largest one first, so adding or removing the last chunk moves only the
+// items of that chunk.
+func StickyChunkSlice[T any](slice []T, chunks int) [][]T {
+	weights := make([]int, chunks)
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	return WeightedStickyChunkSlice(slice, weights)
+}
+
+// WeightedStickyChunkSlice is StickyChunkSlice with chunks sized by their weights. The new chunk takes the items of
+// the chunks most above their share first.
+func WeightedStickyChunkSlice[T any](slice []T, weights []int) [][]T {
+	result := make([][]T, len(weights))
+	result[0] = append([]T(nil), slice...)
+
+	total := weights[0]
+
+	for i := 1; i < len(weights); i++ {
+		total += weights[i]
+		share := len(slice) * weights[i] / total
+
+		// excess is the count of items of the chunk above its share, multiplied by the total weight
+		excess := func(j int) int {
+			return len(result[j])*total - len(slice)*weights[j]
+		}
+
+		for len(result[i]) < share {
+			largest := 0
+			for j := 1; j < i; j++ {
+				if excess(j) > excess(largest) {
+					largest = j
+				}
+			}
+
+			last := len(result[largest]) - 1
+			result[i] = append(result[i], result[largest][last])
+			result[largest] = result[largest][:last]
+		}
+	}
+
+	return result
+}
+
+// WeightedChunkSlice splits the slice into contiguous chunks sized by their weights.
+func WeightedChunkSlice[T any](slice []T, weights []int) [][]T {
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	result := make([][]T, len(weights))
+
+	start, cumulative := 0, 0
+	for i, weight := range weights {
+		cumulative += weight
+		end := len(slice) * cumulative / total
+		result[i] = slice[start:end]
+		start = end
+	}
+
+	return result
+}
+
 func ChunkSliceWithSize[T any](slice []T, chunkSize int) [][]T {
 	var chunks [][]T
 	for i := 0; i < len(slice); i += chunkSize {
@@ -67,3 +133,8 @@ func Retry(f func() error, attempts int, sleep time.Duration) (err error) {
 
 	return err
 }
+
+// CasToTime converts a hybrid logical clock CAS value to the server time it was assigned at.
+func CasToTime(cas uint64) time.Time {
+	return time.Unix(0, int64(cas))
+}
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -88,6 +89,94 @@ func TestChunkSlice(t *testing.T) {
 	}
 }
 
+func TestStickyChunkSlice(t *testing.T) {
+	size := 1024
+	slice := make([]int, size)
+	for i := 0; i < size; i++ {
+		slice[i] = i
+	}
+
+	for chunks := 1; chunks <= 20; chunks++ {
+		result := StickyChunkSlice[int](slice, chunks)
+		next := StickyChunkSlice[int](slice, chunks+1)
+
+		seen := map[int]bool{}
+		for _, chunk := range result {
+			if len(chunk) != size/chunks && len(chunk) != size/chunks+1 {
+				t.Fatalf("chunk of %v is not balanced, size: %v", chunks, len(chunk))
+			}
+
+			for _, item := range chunk {
+				seen[item] = true
+			}
+		}
+
+		if len(seen) != size {
+			t.Fatalf("chunks of %v do not cover the slice", chunks)
+		}
+
+		owners := map[int]int{}
+		for i, chunk := range result {
+			for _, item := range chunk {
+				owners[item] = i
+			}
+		}
+
+		moved := 0
+		for i, chunk := range next[:chunks] {
+			for _, item := range chunk {
+				if owners[item] != i {
+					moved++
+				}
+			}
+		}
+
+		if moved != 0 || len(next[chunks]) != size/(chunks+1) {
+			t.Fatalf("adding a chunk to %v moved %v items", chunks, moved+len(next[chunks]))
+		}
+	}
+}
+
+func TestWeightedStickyChunkSlice(t *testing.T) {
+	size := 1024
+	slice := make([]int, size)
+	for i := 0; i < size; i++ {
+		slice[i] = i
+	}
+
+	weights := []int{1, 2, 1, 4}
+	expected := []int{128, 256, 128, 512}
+
+	result := WeightedStickyChunkSlice[int](slice, weights)
+	for i, chunk := range result {
+		if len(chunk) != expected[i] {
+			t.Fatalf("chunk %v has %v items, expected: %v", i, len(chunk), expected[i])
+		}
+	}
+
+	previous := WeightedStickyChunkSlice[int](slice, weights[:3])
+	for i, chunk := range previous {
+		for _, item := range result[i] {
+			if !slices.Contains(chunk, item) {
+				t.Fatalf("item %v moved to chunk %v which is not the new chunk", item, i)
+			}
+		}
+	}
+}
+
+func TestWeightedChunkSlice(t *testing.T) {
+	slice := make([]int, 1024)
+	for i := range slice {
+		slice[i] = i
+	}
+
+	result := WeightedChunkSlice[int](slice, []int{1, 3})
+
+	if len(result[0]) != 256 || len(result[1]) != 768 || result[1][0] != 256 {
+		t.Fatalf("chunks are not sized by their weights")
+	}
+}
+
 func TestChunkSliceWithSize(t *testing.T) {
 	size := 1001
 	slice := make([]int, size)
@@ -151,3 +240,11 @@ func TestChunkSliceWithSize(t *testing.T) {
 		t.Errorf("ChunkSliceWithSize failed")
 	}
 }
+
+func TestCasToTime(t *testing.T) {
+	cas := uint64(1700000000123456789)
+
+	if CasToTime(cas).UnixNano() != int64(cas) {
+		t.Errorf("CasToTime failed")
+	}
+}
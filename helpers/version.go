@@ -0,0 +1,33 @@
+package helpers
+
+import "runtime/debug"
+
+const modulePath = "github.com/Trendyol/go-dcp"
+
+// Version is the version of the library in the build, it is stored with the checkpoints.
+var Version = moduleVersion()
+
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+
+		return dep.Version
+	}
+
+	return "unknown"
+}
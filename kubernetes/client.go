@@ -26,6 +26,7 @@ type Client interface {
 	AddLabel(key string, value string)
 	RemoveLabel(key string)
 	GetIdentity() *models.Identity
+	GetNamespace() string
 }
 
 type client struct {
@@ -132,6 +133,10 @@ func (le *client) GetIdentity() *models.Identity {
 	return le.myIdentity
 }
 
+func (le *client) GetNamespace() string {
+	return le.namespace
+}
+
 func NewClient() Client {
 	kubernetesConfig, err := rest.InClusterConfig()
 	if err != nil {
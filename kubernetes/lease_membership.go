@@ -0,0 +1,309 @@
+package kubernetes
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	coordinationV1 "k8s.io/api/coordination/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/asaskevich/EventBus"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/membership"
+)
+
+const (
+	leaseManagedByLabel = "app.kubernetes.io/managed-by"
+	leaseManagedBy      = "go-dcp"
+	leaseGroupKey       = "go-dcp/group"
+)
+
+var invalidLeaseNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// leaseMembership registers each member as a Lease which it renews, members are ordered by the acquire time of their
+// leases. It needs no stable pod names, so members of a plain Deployment can join a group.
+type leaseMembership struct {
+	client      Client
+	bus         EventBus.Bus
+	config      *config.Dcp
+	leaseConfig *config.KubernetesLeaseMembership
+	info        *membership.Model
+	infoChan    chan *membership.Model
+	cancelFunc  context.CancelFunc
+	name        string
+	namespace   string
+	holder      string
+	lastMembers []string
+	acquireTime metaV1.MicroTime
+}
+
+func (l *leaseMembership) GetInfo() *membership.Model {
+	if l.info != nil {
+		return l.info
+	}
+
+	return <-l.infoChan
+}
+
+func (l *leaseMembership) Close() {
+	err := l.bus.Unsubscribe(helpers.MembershipChangedBusEventName, l.membershipChangedListener)
+	if err != nil {
+		logger.Log.Error("error while unsubscribe: %v", err)
+	}
+
+	l.cancelFunc()
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.leaseConfig.Timeout)
+	defer cancel()
+
+	err = l.client.CoordinationV1().Leases(l.namespace).Delete(ctx, l.name, metaV1.DeleteOptions{})
+	if err != nil && !apiErrors.IsNotFound(err) {
+		logger.Log.Error("error while delete member lease, err: %v", err)
+	}
+}
+
+func (l *leaseMembership) membershipChangedListener(model *membership.Model) {
+	shouldSendMessage := l.info == nil
+	l.info = model
+	if shouldSendMessage {
+		go func() {
+			l.infoChan <- model
+		}()
+	}
+}
+
+func (l *leaseMembership) newLease(now metaV1.MicroTime) *coordinationV1.Lease {
+	leaseDurationSeconds := int32(l.leaseConfig.LeaseDuration.Seconds())
+
+	return &coordinationV1.Lease{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:        l.name,
+			Namespace:   l.namespace,
+			Labels:      map[string]string{leaseManagedByLabel: leaseManagedBy},
+			Annotations: map[string]string{leaseGroupKey: l.config.Dcp.Group.Name},
+		},
+		Spec: coordinationV1.LeaseSpec{
+			HolderIdentity:       &l.holder,
+			LeaseDurationSeconds: &leaseDurationSeconds,
+			AcquireTime:          &l.acquireTime,
+			RenewTime:            &now,
+		},
+	}
+}
+
+// register creates the lease of the member, a lease left by a previous process of the same pod is taken over.
+func (l *leaseMembership) register(ctx context.Context) error {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+
+	now := metaV1.NowMicro()
+	l.acquireTime = now
+
+	_, err := leases.Create(ctx, l.newLease(now), metaV1.CreateOptions{})
+	if !apiErrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	lease, err := leases.Get(ctx, l.name, metaV1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	lease.Spec = l.newLease(now).Spec
+
+	_, err = leases.Update(ctx, lease, metaV1.UpdateOptions{})
+	return err
+}
+
+func (l *leaseMembership) renew(ctx context.Context) {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+
+	lease, err := leases.Get(ctx, l.name, metaV1.GetOptions{})
+	if apiErrors.IsNotFound(err) {
+		logger.Log.Warn("member lease is removed, registering again")
+
+		if err = l.register(ctx); err != nil {
+			logger.Log.Error("error while register member lease, err: %v", err)
+		}
+		return
+	}
+
+	if err != nil {
+		logger.Log.Error("error while get member lease, err: %v", err)
+		return
+	}
+
+	now := metaV1.NowMicro()
+	lease.Spec.RenewTime = &now
+
+	if _, err = leases.Update(ctx, lease, metaV1.UpdateOptions{}); err != nil {
+		logger.Log.Error("error while renew member lease, err: %v", err)
+	}
+}
+
+func (l *leaseMembership) isAlive(lease *coordinationV1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return false
+	}
+
+	expiresAt := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return now.Before(expiresAt)
+}
+
+func (l *leaseMembership) monitor(ctx context.Context) {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+
+	list, err := leases.List(ctx, metaV1.ListOptions{LabelSelector: leaseManagedByLabel + "=" + leaseManagedBy})
+	if err != nil {
+		logger.Log.Error("error while list member leases, err: %v", err)
+		return
+	}
+
+	now := time.Now()
+
+	var alive []coordinationV1.Lease
+
+	for i := range list.Items {
+		lease := list.Items[i]
+		if lease.Annotations[leaseGroupKey] != l.config.Dcp.Group.Name {
+			continue
+		}
+
+		if l.isAlive(&lease, now) {
+			alive = append(alive, lease)
+			continue
+		}
+
+		logger.Log.Info("member lease %v is expired", lease.Name)
+
+		err = leases.Delete(ctx, lease.Name, metaV1.DeleteOptions{
+			Preconditions: &metaV1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+		})
+		if err != nil && !apiErrors.IsNotFound(err) && !apiErrors.IsConflict(err) {
+			logger.Log.Warn("error while delete expired member lease %v, err: %v", lease.Name, err)
+		}
+	}
+
+	sort.SliceStable(alive, func(i, j int) bool {
+		a, b := alive[i].Spec.AcquireTime, alive[j].Spec.AcquireTime
+		if a == nil || b == nil || a.Equal(b) {
+			return alive[i].Name < alive[j].Name
+		}
+		return a.Before(b)
+	})
+
+	members := make([]string, len(alive))
+	selfOrder := 0
+
+	for i := range alive {
+		members[i] = alive[i].Name
+		if alive[i].Name == l.name {
+			selfOrder = i + 1
+		}
+	}
+
+	if selfOrder == 0 {
+		logger.Log.Warn("member lease is not alive, waiting for the next renew")
+		return
+	}
+
+	if strings.Join(members, ",") == strings.Join(l.lastMembers, ",") {
+		return
+	}
+
+	l.lastMembers = members
+
+	newInfo := &membership.Model{
+		MemberNumber: selfOrder,
+		TotalMembers: len(members),
+	}
+
+	if newInfo.IsChanged(l.info) {
+		logger.Log.Debug("new info arrived for member: %v/%v", newInfo.MemberNumber, newInfo.TotalMembers)
+
+		l.bus.Publish(helpers.MembershipChangedBusEventName, newInfo)
+	}
+}
+
+func (l *leaseMembership) run(ctx context.Context) {
+	logger.Log.Info("kubernetes lease membership will start after %v", l.config.Dcp.Group.Membership.RebalanceDelay)
+
+	monitorAt := time.Now().Add(l.config.Dcp.Group.Membership.RebalanceDelay)
+
+	ticker := time.NewTicker(l.leaseConfig.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		opCtx, cancel := context.WithTimeout(ctx, l.leaseConfig.Timeout)
+
+		l.renew(opCtx)
+
+		if !time.Now().Before(monitorAt) {
+			l.monitor(opCtx)
+		}
+
+		cancel()
+	}
+}
+
+func getLeaseName(groupName string, podName string) string {
+	name := invalidLeaseNameChars.ReplaceAllString(strings.ToLower("go-dcp-"+groupName+"-"+podName), "-")
+	if len(name) > 253 {
+		name = name[len(name)-253:]
+	}
+
+	return strings.Trim(name, "-")
+}
+
+func NewLeaseMembership(config *config.Dcp, client Client, bus EventBus.Bus) membership.Membership {
+	leaseConfig := config.GetKubernetesLeaseMembership()
+
+	namespace := leaseConfig.Namespace
+	if namespace == "" {
+		namespace = client.GetNamespace()
+	}
+
+	lm := &leaseMembership{
+		client:      client,
+		bus:         bus,
+		config:      config,
+		leaseConfig: leaseConfig,
+		infoChan:    make(chan *membership.Model),
+		name:        getLeaseName(config.Dcp.Group.Name, client.GetIdentity().Name),
+		namespace:   namespace,
+		holder:      client.GetIdentity().Name,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaseConfig.Timeout)
+	defer cancel()
+
+	if err := lm.register(ctx); err != nil {
+		logger.Log.Error("error while register member lease, err: %v", err)
+		panic(err)
+	}
+
+	err := bus.SubscribeAsync(helpers.MembershipChangedBusEventName, lm.membershipChangedListener, true)
+	if err != nil {
+		logger.Log.Error("error while subscribe membership changed event, err: %v", err)
+		panic(err)
+	}
+
+	runCtx, runCancel := context.WithCancel(context.Background())
+	lm.cancelFunc = runCancel
+
+	go lm.run(runCtx)
+
+	return lm
+}
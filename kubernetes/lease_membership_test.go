@@ -0,0 +1,196 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	coordinationV1 "k8s.io/api/coordination/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/membership"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type fakeClient struct {
+	*fake.Clientset
+	identity *models.Identity
+}
+
+func (c *fakeClient) AddLabel(string, string) {}
+
+func (c *fakeClient) RemoveLabel(string) {}
+
+func (c *fakeClient) GetIdentity() *models.Identity {
+	return c.identity
+}
+
+func (c *fakeClient) GetNamespace() string {
+	return DefaultNamespace
+}
+
+func newTestLeaseMembership(clientSet *fake.Clientset, podName string) *leaseMembership {
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{}
+	c.Dcp.Group.Name = "group"
+
+	client := &fakeClient{Clientset: clientSet, identity: &models.Identity{Name: podName}}
+
+	return &leaseMembership{
+		client:      client,
+		bus:         EventBus.New(),
+		config:      c,
+		leaseConfig: c.GetKubernetesLeaseMembership(),
+		infoChan:    make(chan *membership.Model),
+		name:        getLeaseName(c.Dcp.Group.Name, podName),
+		namespace:   DefaultNamespace,
+		holder:      podName,
+	}
+}
+
+func getLease(t *testing.T, clientSet *fake.Clientset, name string) *coordinationV1.Lease {
+	t.Helper()
+
+	lease, err := clientSet.CoordinationV1().Leases(DefaultNamespace).Get(context.Background(), name, metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lease
+}
+
+func TestGetLeaseName(t *testing.T) {
+	tests := []struct {
+		group    string
+		pod      string
+		expected string
+	}{
+		{group: "group", pod: "pod-0", expected: "go-dcp-group-pod-0"},
+		{group: "My_Group", pod: "pod.1", expected: "go-dcp-my-group-pod-1"},
+		{group: "group", pod: "pod_", expected: "go-dcp-group-pod"},
+	}
+
+	for _, tt := range tests {
+		if name := getLeaseName(tt.group, tt.pod); name != tt.expected {
+			t.Errorf("expected lease name %s, got %s", tt.expected, name)
+		}
+	}
+}
+
+func TestLeaseMembership_Register(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	lm := newTestLeaseMembership(clientSet, "pod-0")
+
+	if err := lm.register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lease := getLease(t, clientSet, lm.name)
+
+	if lease.Labels[leaseManagedByLabel] != leaseManagedBy || lease.Annotations[leaseGroupKey] != "group" {
+		t.Fatalf("lease must be labeled with the group, got %+v", lease.ObjectMeta)
+	}
+
+	if *lease.Spec.HolderIdentity != "pod-0" || *lease.Spec.LeaseDurationSeconds != 15 {
+		t.Fatalf("unexpected lease spec %+v", lease.Spec)
+	}
+
+	// a restarted process of the same pod takes the lease over
+	restarted := newTestLeaseMembership(clientSet, "pod-0")
+	if err := restarted.register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if lease = getLease(t, clientSet, lm.name); !lease.Spec.AcquireTime.Equal(&restarted.acquireTime) {
+		t.Fatalf("lease must be acquired again, got %v", lease.Spec.AcquireTime)
+	}
+}
+
+func TestLeaseMembership_Renew(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	lm := newTestLeaseMembership(clientSet, "pod-0")
+
+	if err := lm.register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	registered := getLease(t, clientSet, lm.name).Spec.RenewTime
+	time.Sleep(time.Millisecond)
+
+	lm.renew(context.Background())
+
+	if renewed := getLease(t, clientSet, lm.name).Spec.RenewTime; !registered.Before(renewed) {
+		t.Fatalf("renew time must be moved, registered: %v, renewed: %v", registered, renewed)
+	}
+
+	err := clientSet.CoordinationV1().Leases(DefaultNamespace).Delete(context.Background(), lm.name, metaV1.DeleteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lm.renew(context.Background())
+
+	getLease(t, clientSet, lm.name)
+}
+
+func TestLeaseMembership_Monitor(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+
+	var members []*leaseMembership
+	for _, pod := range []string{"pod-b", "pod-a", "pod-expired", "pod-other-group"} {
+		lm := newTestLeaseMembership(clientSet, pod)
+		if pod == "pod-other-group" {
+			lm.config.Dcp.Group.Name = "other"
+		}
+
+		if err := lm.register(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		members = append(members, lm)
+
+		// members are ordered by the acquire time of their leases
+		time.Sleep(time.Millisecond)
+	}
+
+	leases := clientSet.CoordinationV1().Leases(DefaultNamespace)
+
+	expired := getLease(t, clientSet, members[2].name)
+	renewTime := metaV1.NewMicroTime(time.Now().Add(-time.Minute))
+	expired.Spec.RenewTime = &renewTime
+	if _, err := leases.Update(context.Background(), expired, metaV1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	lm := members[1]
+
+	var info *membership.Model
+	if err := lm.bus.Subscribe(helpers.MembershipChangedBusEventName, func(model *membership.Model) { info = model }); err != nil {
+		t.Fatal(err)
+	}
+
+	lm.monitor(context.Background())
+
+	if _, err := leases.Get(context.Background(), members[2].name, metaV1.GetOptions{}); !apiErrors.IsNotFound(err) {
+		t.Fatalf("expired lease must be deleted, got %v", err)
+	}
+
+	// pod-b acquired its lease first
+	if info == nil || info.MemberNumber != 2 || info.TotalMembers != 2 {
+		t.Fatalf("expected member 2/2, got %+v", info)
+	}
+
+	info = nil
+	lm.monitor(context.Background())
+
+	if info != nil {
+		t.Fatalf("unchanged members must not be published, got %+v", info)
+	}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type fieldsContextKey struct{}
+
+// WithFields returns a copy of ctx which carries the given fields in addition to the fields of ctx.
+func WithFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	merged := make(map[string]interface{}, len(fields))
+	for key, value := range Fields(ctx) {
+		merged[key] = value
+	}
+
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, fieldsContextKey{}, merged)
+}
+
+// Fields returns the fields carried by ctx.
+func Fields(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, _ := ctx.Value(fieldsContextKey{}).(map[string]interface{})
+	return fields
+}
+
+// Ctx returns a Logger that appends the fields carried by ctx to every message.
+func Ctx(ctx context.Context) Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return Log
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var suffix strings.Builder
+	for _, key := range keys {
+		suffix.WriteString(fmt.Sprintf(", %s: %v", key, fields[key]))
+	}
+
+	return &contextLogger{suffix: suffix.String()}
+}
+
+type contextLogger struct {
+	suffix string
+}
+
+func (l *contextLogger) Trace(message string, args ...interface{}) {
+	l.Log(TRACE, message, args...)
+}
+
+func (l *contextLogger) Debug(message string, args ...interface{}) {
+	l.Log(DEBUG, message, args...)
+}
+
+func (l *contextLogger) Info(message string, args ...interface{}) {
+	l.Log(INFO, message, args...)
+}
+
+func (l *contextLogger) Warn(message string, args ...interface{}) {
+	l.Log(WARN, message, args...)
+}
+
+func (l *contextLogger) Error(message string, args ...interface{}) {
+	l.Log(ERROR, message, args...)
+}
+
+func (l *contextLogger) Log(level string, message string, args ...interface{}) {
+	Log.Log(level, "%s%s", fmt.Sprintf(message, args...), l.suffix)
+}
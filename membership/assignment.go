@@ -0,0 +1,198 @@
+package membership
+
+import (
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+)
+
+// consistentHashPoints is the count of points of a member of weight 1 on the hash ring.
+const consistentHashPoints = 128
+
+// AssignmentStrategy assigns the vBuckets to the members of a group. Assign returns the vBuckets of each member in the
+// order of the member numbers, weights are the weights of the members and nil when they are equal. Every member
+// computes the vBuckets of its own number, so the assignment must depend only on its arguments.
+type AssignmentStrategy interface {
+	Assign(vBuckets []uint16, totalMembers int, weights []int) [][]uint16
+}
+
+// AssignmentStrategyFunc assigns the vBuckets with a function.
+type AssignmentStrategyFunc func(vBuckets []uint16, totalMembers int, weights []int) [][]uint16
+
+func (f AssignmentStrategyFunc) Assign(vBuckets []uint16, totalMembers int, weights []int) [][]uint16 {
+	return f(vBuckets, totalMembers, weights)
+}
+
+var ErrAssignmentStrategyNotRegistered = errors.New("assignment strategy is not registered")
+
+var (
+	strategies = map[string]AssignmentStrategy{
+		config.MembershipAssignmentSticky:         AssignmentStrategyFunc(stickyAssignment),
+		config.MembershipAssignmentRange:          AssignmentStrategyFunc(rangeAssignment),
+		config.MembershipAssignmentRoundRobin:     AssignmentStrategyFunc(roundRobinAssignment),
+		config.MembershipAssignmentConsistentHash: AssignmentStrategyFunc(consistentHashAssignment),
+	}
+	builtinStrategies = map[string]bool{
+		config.MembershipAssignmentSticky:         true,
+		config.MembershipAssignmentRange:          true,
+		config.MembershipAssignmentRoundRobin:     true,
+		config.MembershipAssignmentConsistentHash: true,
+	}
+	strategiesLock sync.RWMutex
+)
+
+// RegisterAssignmentStrategy makes a strategy selectable with dcp.group.membership.assignment config. It is meant to
+// be called from init functions and panics when the name is registered already or the strategy is nil. Built-in
+// strategies cannot be replaced.
+func RegisterAssignmentStrategy(name string, strategy AssignmentStrategy) {
+	strategiesLock.Lock()
+	defer strategiesLock.Unlock()
+
+	if strategy == nil {
+		panic("membership: register assignment strategy is nil for " + name)
+	}
+
+	if _, ok := strategies[name]; ok || builtinStrategies[name] {
+		panic("membership: register assignment strategy called twice for " + name)
+	}
+
+	strategies[name] = strategy
+}
+
+// GetAssignmentStrategy returns the built-in or registered strategy.
+func GetAssignmentStrategy(name string) (AssignmentStrategy, error) {
+	strategiesLock.RLock()
+	defer strategiesLock.RUnlock()
+
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, ErrAssignmentStrategyNotRegistered
+	}
+
+	return strategy, nil
+}
+
+// AssignmentStrategies returns the names of the built-in and registered strategies.
+func AssignmentStrategies() []string {
+	strategiesLock.RLock()
+	defer strategiesLock.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// stickyAssignment moves only the vBuckets of the member which joins or leaves at the end of the group.
+func stickyAssignment(vBuckets []uint16, totalMembers int, weights []int) [][]uint16 {
+	if weights == nil {
+		return helpers.StickyChunkSlice[uint16](vBuckets, totalMembers)
+	}
+
+	return helpers.WeightedStickyChunkSlice[uint16](vBuckets, weights)
+}
+
+// rangeAssignment gives each member a contiguous range, nearly every vBucket moves when the group changes.
+func rangeAssignment(vBuckets []uint16, totalMembers int, weights []int) [][]uint16 {
+	if weights == nil {
+		return helpers.ChunkSlice[uint16](vBuckets, totalMembers)
+	}
+
+	return helpers.WeightedChunkSlice[uint16](vBuckets, weights)
+}
+
+// roundRobinAssignment deals the vBuckets to the members one by one, members of a larger weight are dealt more often
+// with smooth weighted round robin. The vBuckets of a member are spread over the whole range.
+func roundRobinAssignment(vBuckets []uint16, totalMembers int, weights []int) [][]uint16 {
+	result := make([][]uint16, totalMembers)
+
+	if weights == nil {
+		for i, vbID := range vBuckets {
+			result[i%totalMembers] = append(result[i%totalMembers], vbID)
+		}
+
+		return result
+	}
+
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	current := make([]int, totalMembers)
+
+	for _, vbID := range vBuckets {
+		selected := 0
+		for i, weight := range weights {
+			current[i] += weight
+			if current[i] > current[selected] {
+				selected = i
+			}
+		}
+
+		current[selected] -= total
+		result[selected] = append(result[selected], vbID)
+	}
+
+	return result
+}
+
+type hashPoint struct {
+	hash   uint32
+	member int
+}
+
+// consistentHashAssignment places the members on a hash ring with points in proportion to their weights, a vBucket
+// belongs to the member of the first point after its hash. A member joining or leaving moves only the vBuckets of its
+// points, wherever its number is, but the shares of the members are not exactly equal.
+func consistentHashAssignment(vBuckets []uint16, totalMembers int, weights []int) [][]uint16 {
+	ring := make([]hashPoint, 0, totalMembers*consistentHashPoints)
+
+	for member := 0; member < totalMembers; member++ {
+		points := consistentHashPoints
+		if weights != nil {
+			points *= weights[member]
+		}
+
+		for point := 0; point < points; point++ {
+			ring = append(ring, hashPoint{hash: hashOf("member:" + strconv.Itoa(member) + ":" + strconv.Itoa(point)), member: member})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+
+	result := make([][]uint16, totalMembers)
+
+	for _, vbID := range vBuckets {
+		hash := hashOf("vBucket:" + strconv.Itoa(int(vbID)))
+
+		i := sort.Search(len(ring), func(i int) bool {
+			return ring[i].hash >= hash
+		})
+		if i == len(ring) {
+			i = 0
+		}
+
+		result[ring[i].member] = append(result[ring[i].member], vbID)
+	}
+
+	return result
+}
+
+func hashOf(value string) uint32 {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(value))
+
+	return hash.Sum32()
+}
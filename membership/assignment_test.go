@@ -0,0 +1,107 @@
+package membership
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+func vBucketsOf(count int) []uint16 {
+	vBuckets := make([]uint16, count)
+	for i := range vBuckets {
+		vBuckets[i] = uint16(i)
+	}
+
+	return vBuckets
+}
+
+func TestAssignmentStrategiesAssignEachVBucketOnce(t *testing.T) {
+	for _, name := range []string{
+		config.MembershipAssignmentSticky,
+		config.MembershipAssignmentRange,
+		config.MembershipAssignmentRoundRobin,
+		config.MembershipAssignmentConsistentHash,
+	} {
+		strategy, err := GetAssignmentStrategy(name)
+		if err != nil {
+			t.Fatalf("%s must be registered, err: %v", name, err)
+		}
+
+		for _, weights := range [][]int{nil, {1, 2, 1}} {
+			chunks := strategy.Assign(vBucketsOf(1024), 3, weights)
+			if len(chunks) != 3 {
+				t.Fatalf("%s must assign 3 members, assigned: %d", name, len(chunks))
+			}
+
+			seen := make(map[uint16]bool, 1024)
+			for _, chunk := range chunks {
+				for _, vbID := range chunk {
+					if seen[vbID] {
+						t.Fatalf("%s assigned vbID %d twice", name, vbID)
+					}
+					seen[vbID] = true
+				}
+			}
+
+			if len(seen) != 1024 {
+				t.Errorf("%s must assign 1024 vBuckets, assigned: %d", name, len(seen))
+			}
+		}
+	}
+}
+
+func TestRoundRobinAssignmentWeights(t *testing.T) {
+	chunks := roundRobinAssignment(vBucketsOf(1024), 2, []int{1, 3})
+
+	if len(chunks[0]) != 256 || len(chunks[1]) != 768 {
+		t.Errorf("members must be assigned by weight, assigned: %d, %d", len(chunks[0]), len(chunks[1]))
+	}
+}
+
+func TestConsistentHashAssignmentMovesFewVBuckets(t *testing.T) {
+	before := consistentHashAssignment(vBucketsOf(1024), 4, nil)
+	after := consistentHashAssignment(vBucketsOf(1024), 5, nil)
+
+	owners := make(map[uint16]int, 1024)
+	for member, chunk := range before {
+		for _, vbID := range chunk {
+			owners[vbID] = member
+		}
+	}
+
+	moved := 0
+	for member, chunk := range after {
+		for _, vbID := range chunk {
+			if owners[vbID] != member {
+				moved++
+			}
+		}
+	}
+
+	if moved != len(after[4]) {
+		t.Errorf("only vBuckets of the new member must move, moved: %d, new member: %d", moved, len(after[4]))
+	}
+}
+
+func TestRegisterAssignmentStrategy(t *testing.T) {
+	RegisterAssignmentStrategy("test", AssignmentStrategyFunc(rangeAssignment))
+
+	if _, err := GetAssignmentStrategy("test"); err != nil {
+		t.Fatalf("registered strategy must be returned, err: %v", err)
+	}
+
+	if _, err := GetAssignmentStrategy("unknown"); !errors.Is(err, ErrAssignmentStrategyNotRegistered) {
+		t.Errorf("unknown strategy must not be returned, err: %v", err)
+	}
+}
+
+func TestRegisterBuiltinAssignmentStrategy(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a built-in strategy must panic")
+		}
+	}()
+
+	RegisterAssignmentStrategy(config.MembershipAssignmentSticky, AssignmentStrategyFunc(rangeAssignment))
+}
@@ -0,0 +1,354 @@
+package consul
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/bytedance/sonic"
+	"github.com/hashicorp/consul/api"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/membership"
+)
+
+// watchWaitTime is the longest a blocking query on the member keys waits for a change.
+const watchWaitTime = time.Minute
+
+var errNotAcquired = errors.New("consul membership key is not acquired")
+
+// consulSession is the part of the consul session client used by the membership.
+type consulSession interface {
+	Create(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error)
+	Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error)
+	Destroy(id string, q *api.WriteOptions) (*api.WriteMeta, error)
+}
+
+// consulKV is the part of the consul kv client used by the membership.
+type consulKV interface {
+	Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
+}
+
+// consulMembership registers each member as a key acquired by its consul session. Sessions are renewed within their
+// ttl, and a member whose session expires loses its key, so the member set is the keys of the group in the order
+// they are created.
+type consulMembership struct {
+	session          consulSession
+	kv               consulKV
+	bus              EventBus.Bus
+	config           *config.Dcp
+	membershipConfig *config.ConsulMembership
+	info             *membership.Model
+	infoChan         chan *membership.Model
+	cancelFunc       context.CancelFunc
+	name             string
+	weight           int
+	activeMembers    int
+	standby          bool
+	sessionID        string
+	lastMembers      []string
+	wg               sync.WaitGroup
+	sessionLock      sync.Mutex
+}
+
+func (c *consulMembership) GetInfo() *membership.Model {
+	if c.info != nil {
+		return c.info
+	}
+
+	return <-c.infoChan
+}
+
+func (c *consulMembership) Close() {
+	err := c.bus.Unsubscribe(helpers.MembershipChangedBusEventName, c.membershipChangedListener)
+	if err != nil {
+		logger.Log.Error("error while unsubscribe: %v", err)
+	}
+
+	c.cancelFunc()
+	c.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.membershipConfig.Timeout)
+	defer cancel()
+
+	// destroying the session deletes the member key, the other members see it at once
+	if _, err = c.session.Destroy(c.getSessionID(), (&api.WriteOptions{}).WithContext(ctx)); err != nil {
+		logger.Log.Error("error while destroy consul membership session, err: %v", err)
+	}
+}
+
+func (c *consulMembership) membershipChangedListener(model *membership.Model) {
+	shouldSendMessage := c.info == nil
+	c.info = model
+	if shouldSendMessage {
+		go func() {
+			c.infoChan <- model
+		}()
+	}
+}
+
+func (c *consulMembership) getMembersPrefix() string {
+	// go-dcp/groupName/members/
+	return c.membershipConfig.KeyPrefix + c.config.Dcp.Group.Name + "/members/"
+}
+
+func (c *consulMembership) getSessionID() string {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+
+	return c.sessionID
+}
+
+// register creates a session and acquires the member key with it. A member registered again joins at the end.
+func (c *consulMembership) register(ctx context.Context) error {
+	writeOptions := (&api.WriteOptions{}).WithContext(ctx)
+
+	sessionID, _, err := c.session.Create(&api.SessionEntry{
+		Name:     "go-dcp-" + c.config.Dcp.Group.Name,
+		TTL:      c.membershipConfig.SessionTTL.String(),
+		Behavior: api.SessionBehaviorDelete,
+	}, writeOptions)
+	if err != nil {
+		return err
+	}
+
+	value, _ := sonic.Marshal(membership.MemberRecord{
+		Name: c.name, ClusterJoinTime: time.Now().UnixNano(), Weight: c.weight, Standby: c.standby,
+	})
+
+	acquired, _, err := c.kv.Acquire(&api.KVPair{
+		Key:     c.getMembersPrefix() + sessionID,
+		Value:   value,
+		Session: sessionID,
+	}, writeOptions)
+	if err == nil && !acquired {
+		err = errNotAcquired
+	}
+
+	if err != nil {
+		_, _ = c.session.Destroy(sessionID, writeOptions)
+		return err
+	}
+
+	c.sessionLock.Lock()
+	c.sessionID = sessionID
+	c.sessionLock.Unlock()
+
+	logger.Log.Info("registered to consul membership with session: %s", sessionID)
+
+	return nil
+}
+
+func (c *consulMembership) renew(ctx context.Context) {
+	renewCtx, cancel := context.WithTimeout(ctx, c.membershipConfig.Timeout)
+	defer cancel()
+
+	entry, _, err := c.session.Renew(c.getSessionID(), (&api.WriteOptions{}).WithContext(renewCtx))
+	if err != nil {
+		logger.Log.Error("error while renew consul membership session, err: %v", err)
+		return
+	}
+
+	if entry != nil {
+		return
+	}
+
+	logger.Log.Warn("consul membership session is expired, registering again")
+
+	if err = c.register(renewCtx); err != nil {
+		logger.Log.Error("error while register consul membership, err: %v", err)
+	}
+}
+
+func (c *consulMembership) runRenew(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.membershipConfig.SessionTTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.renew(ctx)
+		}
+	}
+}
+
+func (c *consulMembership) runWatch(ctx context.Context) {
+	defer c.wg.Done()
+
+	logger.Log.Info("consul membership will start after %v", c.config.Dcp.Group.Membership.RebalanceDelay)
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(c.config.Dcp.Group.Membership.RebalanceDelay):
+	}
+
+	var waitIndex uint64
+
+	for {
+		queryOptions := (&api.QueryOptions{WaitIndex: waitIndex, WaitTime: watchWaitTime}).WithContext(ctx)
+
+		pairs, meta, err := c.kv.List(c.getMembersPrefix(), queryOptions)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			logger.Log.Error("error while watch consul membership, err: %v", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.membershipConfig.Timeout):
+			}
+
+			continue
+		}
+
+		// the index is reset when it goes backwards, e.g. after a consul snapshot restore
+		if meta.LastIndex < waitIndex {
+			waitIndex = 0
+		} else {
+			waitIndex = meta.LastIndex
+		}
+
+		c.monitor(pairs)
+	}
+}
+
+func (c *consulMembership) monitor(pairs api.KVPairs) {
+	alive := make(api.KVPairs, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair.Session != "" {
+			alive = append(alive, pair)
+		}
+	}
+
+	sort.Slice(alive, func(i, j int) bool {
+		if alive[i].CreateIndex == alive[j].CreateIndex {
+			return alive[i].Key < alive[j].Key
+		}
+		return alive[i].CreateIndex < alive[j].CreateIndex
+	})
+
+	sessionID := c.getSessionID()
+
+	members := make([]string, len(alive))
+	settings := make([]membership.Member, len(alive))
+	selfOrder := 0
+
+	for i, pair := range alive {
+		members[i] = pair.Session
+		settings[i] = membership.MemberOf(pair.Value)
+		if pair.Session == sessionID {
+			selfOrder = i + 1
+		}
+	}
+
+	if selfOrder == 0 {
+		logger.Log.Warn("consul membership key is not found, waiting for the next renew")
+		return
+	}
+
+	if strings.Join(members, ",") == strings.Join(c.lastMembers, ",") {
+		return
+	}
+
+	c.lastMembers = members
+
+	newInfo := membership.NewModel(settings, selfOrder-1, c.activeMembers)
+
+	if newInfo.IsChanged(c.info) {
+		logger.Log.Debug("new info arrived for member: %v/%v", newInfo.MemberNumber, newInfo.TotalMembers)
+
+		c.bus.Publish(helpers.MembershipChangedBusEventName, newInfo)
+	}
+}
+
+func newClientConfig(membershipConfig *config.ConsulMembership) *api.Config {
+	clientConfig := api.DefaultConfig()
+
+	if membershipConfig.Address != "" {
+		clientConfig.Address = membershipConfig.Address
+	}
+
+	if membershipConfig.Token != "" {
+		clientConfig.Token = membershipConfig.Token
+	}
+
+	if membershipConfig.Datacenter != "" {
+		clientConfig.Datacenter = membershipConfig.Datacenter
+	}
+
+	if membershipConfig.SecureConnection {
+		clientConfig.Scheme = "https"
+	}
+
+	return clientConfig
+}
+
+// NewMembership creates the membership with address, token, datacenter, keyPrefix, secureConnection, sessionTTL and
+// timeout keys of dcp.group.membership.config. Unset keys fall back to the CONSUL_* environment variables.
+func NewMembership(config *config.Dcp, bus EventBus.Bus) membership.Membership {
+	membershipConfig := config.GetConsulMembership()
+
+	client, err := api.NewClient(newClientConfig(membershipConfig))
+	if err != nil {
+		logger.Log.Error("error while create consul client, err: %v", err)
+		panic(err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Log.Error("error while getting hostname, err: %v", err)
+		panic(err)
+	}
+
+	cm := &consulMembership{
+		session:          client.Session(),
+		kv:               client.KV(),
+		bus:              bus,
+		config:           config,
+		membershipConfig: membershipConfig,
+		infoChan:         make(chan *membership.Model),
+		name:             hostname,
+		weight:           config.GetMembershipWeight(),
+		activeMembers:    config.Dcp.Group.Membership.ActiveMembers,
+		standby:          config.Dcp.Group.Membership.Standby,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), membershipConfig.Timeout)
+	defer cancel()
+
+	if err = cm.register(ctx); err != nil {
+		logger.Log.Error("error while register consul membership, err: %v", err)
+		panic(err)
+	}
+
+	err = bus.SubscribeAsync(helpers.MembershipChangedBusEventName, cm.membershipChangedListener, true)
+	if err != nil {
+		logger.Log.Error("error while subscribe membership changed event, err: %v", err)
+		panic(err)
+	}
+
+	runCtx, runCancel := context.WithCancel(context.Background())
+	cm.cancelFunc = runCancel
+
+	cm.wg.Add(2)
+	go cm.runRenew(runCtx)
+	go cm.runWatch(runCtx)
+
+	return cm
+}
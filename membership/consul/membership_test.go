@@ -0,0 +1,222 @@
+package consul
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/hashicorp/consul/api"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/membership"
+)
+
+// fakeConsul keeps the sessions and the keys acquired by them, destroying a session deletes its keys.
+type fakeConsul struct {
+	sessions map[string]bool
+	pairs    map[string]*api.KVPair
+	index    uint64
+	lock     sync.Mutex
+}
+
+func (f *fakeConsul) Create(_ *api.SessionEntry, _ *api.WriteOptions) (string, *api.WriteMeta, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.index++
+	id := "session-" + strconv.FormatUint(f.index, 10)
+	f.sessions[id] = true
+
+	return id, &api.WriteMeta{}, nil
+}
+
+func (f *fakeConsul) Renew(id string, _ *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.sessions[id] {
+		return nil, &api.WriteMeta{}, nil
+	}
+
+	return &api.SessionEntry{ID: id}, &api.WriteMeta{}, nil
+}
+
+func (f *fakeConsul) Destroy(id string, _ *api.WriteOptions) (*api.WriteMeta, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.expire(id)
+
+	return &api.WriteMeta{}, nil
+}
+
+func (f *fakeConsul) expire(id string) {
+	delete(f.sessions, id)
+
+	for key, pair := range f.pairs {
+		if pair.Session == id {
+			delete(f.pairs, key)
+		}
+	}
+}
+
+func (f *fakeConsul) Acquire(p *api.KVPair, _ *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if pair, ok := f.pairs[p.Key]; !f.sessions[p.Session] || (ok && pair.Session != p.Session) {
+		return false, &api.WriteMeta{}, nil
+	}
+
+	f.index++
+	f.pairs[p.Key] = &api.KVPair{Key: p.Key, Value: p.Value, Session: p.Session, CreateIndex: f.index}
+
+	return true, &api.WriteMeta{}, nil
+}
+
+func (f *fakeConsul) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var pairs api.KVPairs
+	for key, pair := range f.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs, &api.QueryMeta{LastIndex: f.index}, nil
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{sessions: map[string]bool{}, pairs: map[string]*api.KVPair{}}
+}
+
+func newTestMembership(t *testing.T, fake *fakeConsul, name string) *consulMembership {
+	t.Helper()
+
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{}
+	c.Dcp.Group.Name = "group"
+
+	cm := &consulMembership{
+		session:          fake,
+		kv:               fake,
+		bus:              EventBus.New(),
+		config:           c,
+		membershipConfig: c.GetConsulMembership(),
+		infoChan:         make(chan *membership.Model, 1),
+		name:             name,
+		weight:           1,
+		activeMembers:    c.Dcp.Group.Membership.ActiveMembers,
+	}
+
+	_, cm.cancelFunc = context.WithCancel(context.Background())
+
+	if err := cm.bus.Subscribe(helpers.MembershipChangedBusEventName, cm.membershipChangedListener); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cm.register(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return cm
+}
+
+func monitorMembers(t *testing.T, c *consulMembership) {
+	t.Helper()
+
+	pairs, _, err := c.kv.List(c.getMembersPrefix(), &api.QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.monitor(pairs)
+}
+
+func assertInfo(t *testing.T, c *consulMembership, memberNumber int, totalMembers int) {
+	t.Helper()
+
+	info := c.GetInfo()
+	if info.MemberNumber != memberNumber || info.TotalMembers != totalMembers {
+		t.Fatalf("%s: expected member %d/%d, got %d/%d", c.name, memberNumber, totalMembers, info.MemberNumber, info.TotalMembers)
+	}
+}
+
+func TestConsulMembership_JoinAndLeave(t *testing.T) {
+	fake := newFakeConsul()
+
+	first := newTestMembership(t, fake, "first")
+	second := newTestMembership(t, fake, "second")
+
+	monitorMembers(t, first)
+	monitorMembers(t, second)
+
+	assertInfo(t, first, 1, 2)
+	assertInfo(t, second, 2, 2)
+
+	first.Close()
+
+	if len(fake.pairs) != 1 {
+		t.Fatalf("member key must be deleted with its session, got %d keys", len(fake.pairs))
+	}
+
+	monitorMembers(t, second)
+
+	assertInfo(t, second, 1, 1)
+}
+
+func TestConsulMembership_RenewRegistersExpiredSession(t *testing.T) {
+	fake := newFakeConsul()
+
+	first := newTestMembership(t, fake, "first")
+	second := newTestMembership(t, fake, "second")
+
+	sessionID := first.getSessionID()
+	first.renew(context.Background())
+
+	if first.getSessionID() != sessionID {
+		t.Fatalf("alive session must be kept")
+	}
+
+	fake.lock.Lock()
+	fake.expire(sessionID)
+	fake.lock.Unlock()
+
+	first.renew(context.Background())
+
+	if first.getSessionID() == sessionID {
+		t.Fatalf("expired session must be created again")
+	}
+
+	// a member registered again joins at the end
+	monitorMembers(t, first)
+	assertInfo(t, first, 2, 2)
+
+	monitorMembers(t, second)
+	assertInfo(t, second, 1, 2)
+}
+
+func TestConsulMembership_RegisterDestroysSessionWithoutKey(t *testing.T) {
+	fake := newFakeConsul()
+	cm := newTestMembership(t, fake, "member")
+
+	// the key of the next session is held by an other session
+	fake.pairs[cm.getMembersPrefix()+"session-3"] = &api.KVPair{Session: cm.getSessionID()}
+
+	err := cm.register(context.Background())
+	if err != errNotAcquired {
+		t.Fatalf("expected not acquired error, got %v", err)
+	}
+
+	if fake.sessions["session-3"] {
+		t.Fatalf("session of the failed register must be destroyed")
+	}
+}
@@ -0,0 +1,350 @@
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/bytedance/sonic"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/membership"
+)
+
+// etcdClient is the part of the etcd client used by the membership.
+type etcdClient interface {
+	clientv3.KV
+	clientv3.Lease
+	clientv3.Watcher
+}
+
+// etcdMembership registers each member as a key attached to its own lease. The lease is kept alive by the process,
+// and a member whose lease expires loses its key, so the member set is the keys of the group in the order they are
+// created. When the keep alive stops, e.g. after a network partition, the member registers again with a new lease.
+type etcdMembership struct {
+	client           etcdClient
+	bus              EventBus.Bus
+	config           *config.Dcp
+	membershipConfig *config.EtcdMembership
+	info             *membership.Model
+	infoChan         chan *membership.Model
+	keepAliveChan    <-chan *clientv3.LeaseKeepAliveResponse
+	cancelFunc       context.CancelFunc
+	name             string
+	weight           int
+	activeMembers    int
+	standby          bool
+	keyPrefix        string
+	lastMembers      []string
+	leaseID          clientv3.LeaseID
+	wg               sync.WaitGroup
+	leaseLock        sync.Mutex
+}
+
+func (e *etcdMembership) GetInfo() *membership.Model {
+	if e.info != nil {
+		return e.info
+	}
+
+	return <-e.infoChan
+}
+
+func (e *etcdMembership) Close() {
+	err := e.bus.Unsubscribe(helpers.MembershipChangedBusEventName, e.membershipChangedListener)
+	if err != nil {
+		logger.Log.Error("error while unsubscribe: %v", err)
+	}
+
+	e.cancelFunc()
+	e.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.membershipConfig.Timeout)
+	defer cancel()
+
+	// revoking the lease deletes the member key, the other members see it at once
+	if _, err = e.client.Revoke(ctx, e.getLeaseID()); err != nil {
+		logger.Log.Error("error while revoke etcd membership lease, err: %v", err)
+	}
+
+	if err = e.client.Close(); err != nil {
+		logger.Log.Error("error while close etcd client, err: %v", err)
+	}
+}
+
+func (e *etcdMembership) membershipChangedListener(model *membership.Model) {
+	shouldSendMessage := e.info == nil
+	e.info = model
+	if shouldSendMessage {
+		go func() {
+			e.infoChan <- model
+		}()
+	}
+}
+
+func (e *etcdMembership) getMembersPrefix() string {
+	// _connector:cbgo:groupName:members:
+	return e.keyPrefix + e.config.Dcp.Group.Name + ":members:"
+}
+
+func (e *etcdMembership) getLeaseID() clientv3.LeaseID {
+	e.leaseLock.Lock()
+	defer e.leaseLock.Unlock()
+
+	return e.leaseID
+}
+
+// register grants a lease, puts the member key with it and keeps the lease alive till runCtx is done. A member
+// registered again joins at the end.
+func (e *etcdMembership) register(ctx context.Context, runCtx context.Context) error {
+	lease, err := e.client.Grant(ctx, int64(e.membershipConfig.TTL.Seconds()))
+	if err != nil {
+		return err
+	}
+
+	value, _ := sonic.Marshal(membership.MemberRecord{
+		Name: e.name, ClusterJoinTime: time.Now().UnixNano(), Weight: e.weight, Standby: e.standby,
+	})
+	key := e.getMembersPrefix() + strconv.FormatInt(int64(lease.ID), 16)
+
+	_, err = e.client.Put(ctx, key, string(value), clientv3.WithLease(lease.ID))
+	if err == nil {
+		e.keepAliveChan, err = e.client.KeepAlive(runCtx, lease.ID)
+	}
+
+	if err != nil {
+		_, _ = e.client.Revoke(ctx, lease.ID)
+		return err
+	}
+
+	e.leaseLock.Lock()
+	e.leaseID = lease.ID
+	e.leaseLock.Unlock()
+
+	logger.Log.Info("registered to etcd membership with lease: %x", int64(lease.ID))
+
+	return nil
+}
+
+// runKeepAlive drains the keep alive responses. The channel is closed when the lease is lost or cannot be renewed
+// within its ttl, then the member registers again until it succeeds or ctx is done.
+func (e *etcdMembership) runKeepAlive(ctx context.Context) {
+	defer e.wg.Done()
+
+	for {
+		for range e.keepAliveChan { //nolint:revive
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Log.Warn("etcd membership lease is lost, registering again")
+
+		for {
+			registerCtx, cancel := context.WithTimeout(ctx, e.membershipConfig.Timeout)
+			err := e.register(registerCtx, ctx)
+			cancel()
+
+			if err == nil {
+				break
+			}
+
+			logger.Log.Error("error while register etcd membership, err: %v", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(e.membershipConfig.TTL / 2):
+			}
+		}
+	}
+}
+
+func (e *etcdMembership) runWatch(ctx context.Context) {
+	defer e.wg.Done()
+
+	logger.Log.Info("etcd membership will start after %v", e.config.Dcp.Group.Membership.RebalanceDelay)
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(e.config.Dcp.Group.Membership.RebalanceDelay):
+	}
+
+	for {
+		if err := e.watch(ctx); err != nil {
+			logger.Log.Error("error while watch etcd membership, err: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.membershipConfig.Timeout):
+		}
+	}
+}
+
+// watch lists the members and lists them again on each change of the member keys. It returns when the watch is
+// canceled, which happens on compaction or when the watched member loses the leader.
+func (e *etcdMembership) watch(ctx context.Context) error {
+	revision, err := e.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	watchChan := e.client.Watch(watchCtx, e.getMembersPrefix(), clientv3.WithPrefix(), clientv3.WithRev(revision+1))
+
+	for response := range watchChan {
+		if err = response.Err(); err != nil {
+			return err
+		}
+
+		if _, err = e.list(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *etcdMembership) list(ctx context.Context) (int64, error) {
+	getCtx, cancel := context.WithTimeout(ctx, e.membershipConfig.Timeout)
+	defer cancel()
+
+	response, err := e.client.Get(getCtx, e.getMembersPrefix(), clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+
+	e.monitor(response.Kvs)
+
+	return response.Header.Revision, nil
+}
+
+func (e *etcdMembership) monitor(kvs []*mvccpb.KeyValue) {
+	sort.Slice(kvs, func(i, j int) bool {
+		if kvs[i].CreateRevision == kvs[j].CreateRevision {
+			return string(kvs[i].Key) < string(kvs[j].Key)
+		}
+		return kvs[i].CreateRevision < kvs[j].CreateRevision
+	})
+
+	leaseID := int64(e.getLeaseID())
+
+	members := make([]string, len(kvs))
+	settings := make([]membership.Member, len(kvs))
+	selfOrder := 0
+
+	for i, kv := range kvs {
+		members[i] = string(kv.Key)
+		settings[i] = membership.MemberOf(kv.Value)
+		if kv.Lease == leaseID {
+			selfOrder = i + 1
+		}
+	}
+
+	if selfOrder == 0 {
+		logger.Log.Warn("etcd membership key is not found, waiting for the registration")
+		return
+	}
+
+	if strings.Join(members, ",") == strings.Join(e.lastMembers, ",") {
+		return
+	}
+
+	e.lastMembers = members
+
+	newInfo := membership.NewModel(settings, selfOrder-1, e.activeMembers)
+
+	if newInfo.IsChanged(e.info) {
+		logger.Log.Debug("new info arrived for member: %v/%v", newInfo.MemberNumber, newInfo.TotalMembers)
+
+		e.bus.Publish(helpers.MembershipChangedBusEventName, newInfo)
+	}
+}
+
+func newClientConfig(membershipConfig *config.EtcdMembership) clientv3.Config {
+	clientConfig := clientv3.Config{
+		Endpoints:   membershipConfig.Endpoints,
+		Username:    membershipConfig.Username,
+		Password:    membershipConfig.Password,
+		DialTimeout: membershipConfig.DialTimeout,
+	}
+
+	if membershipConfig.SecureConnection {
+		clientConfig.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return clientConfig
+}
+
+// NewMembership creates the membership with endpoints, username, password, keyPrefix, secureConnection, ttl,
+// dialTimeout and timeout keys of dcp.group.membership.config. Endpoints are comma separated.
+func NewMembership(config *config.Dcp, bus EventBus.Bus) membership.Membership {
+	membershipConfig := config.GetEtcdMembership()
+
+	client, err := clientv3.New(newClientConfig(membershipConfig))
+	if err != nil {
+		logger.Log.Error("error while create etcd client, err: %v", err)
+		panic(err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Log.Error("error while getting hostname, err: %v", err)
+		panic(err)
+	}
+
+	em := &etcdMembership{
+		client:           client,
+		bus:              bus,
+		config:           config,
+		membershipConfig: membershipConfig,
+		infoChan:         make(chan *membership.Model),
+		name:             hostname,
+		weight:           config.GetMembershipWeight(),
+		activeMembers:    config.Dcp.Group.Membership.ActiveMembers,
+		standby:          config.Dcp.Group.Membership.Standby,
+		keyPrefix:        helpers.Prefix,
+	}
+
+	if membershipConfig.KeyPrefix != "" {
+		em.keyPrefix = membershipConfig.KeyPrefix
+	}
+
+	runCtx, runCancel := context.WithCancel(context.Background())
+	em.cancelFunc = runCancel
+
+	ctx, cancel := context.WithTimeout(context.Background(), membershipConfig.Timeout)
+	defer cancel()
+
+	if err = em.register(ctx, runCtx); err != nil {
+		logger.Log.Error("error while register etcd membership, err: %v", err)
+		panic(err)
+	}
+
+	err = bus.SubscribeAsync(helpers.MembershipChangedBusEventName, em.membershipChangedListener, true)
+	if err != nil {
+		logger.Log.Error("error while subscribe membership changed event, err: %v", err)
+		panic(err)
+	}
+
+	em.wg.Add(2)
+	go em.runKeepAlive(runCtx)
+	go em.runWatch(runCtx)
+
+	return em
+}
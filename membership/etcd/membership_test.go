@@ -0,0 +1,241 @@
+package etcd
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/membership"
+)
+
+type fakeLease struct {
+	keepAlive chan *clientv3.LeaseKeepAliveResponse
+	once      sync.Once
+}
+
+func (l *fakeLease) stop() {
+	l.once.Do(func() { close(l.keepAlive) })
+}
+
+// fakeEtcd keeps the keys with their leases, a revoked or expired lease deletes its keys and stops its keep alive.
+type fakeEtcd struct {
+	etcdClient
+	leases   map[clientv3.LeaseID]*fakeLease
+	kvs      map[string]*mvccpb.KeyValue
+	revision int64
+	lock     sync.Mutex
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{leases: map[clientv3.LeaseID]*fakeLease{}, kvs: map[string]*mvccpb.KeyValue{}}
+}
+
+func (f *fakeEtcd) Grant(_ context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.revision++
+	id := clientv3.LeaseID(f.revision)
+	f.leases[id] = &fakeLease{keepAlive: make(chan *clientv3.LeaseKeepAliveResponse)}
+
+	return &clientv3.LeaseGrantResponse{ID: id, TTL: ttl}, nil
+}
+
+func (f *fakeEtcd) Put(_ context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	op := clientv3.OpPut(key, val, opts...)
+
+	f.revision++
+	f.kvs[key] = &mvccpb.KeyValue{
+		Key:            []byte(key),
+		Value:          []byte(val),
+		CreateRevision: f.revision,
+		Lease:          int64(leaseOf(op)),
+	}
+
+	return &clientv3.PutResponse{}, nil
+}
+
+// leaseOf reads the lease of a put op, which has no accessor.
+func leaseOf(op clientv3.Op) clientv3.LeaseID {
+	return clientv3.LeaseID(reflect.ValueOf(op).FieldByName("leaseID").Int())
+}
+
+func (f *fakeEtcd) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	lease := f.leases[id]
+
+	go func() {
+		<-ctx.Done()
+		lease.stop()
+	}()
+
+	return lease.keepAlive, nil
+}
+
+func (f *fakeEtcd) Revoke(_ context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.expire(id)
+
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+func (f *fakeEtcd) expire(id clientv3.LeaseID) {
+	if lease, ok := f.leases[id]; ok {
+		lease.stop()
+		delete(f.leases, id)
+	}
+
+	for key, kv := range f.kvs {
+		if kv.Lease == int64(id) {
+			delete(f.kvs, key)
+		}
+	}
+}
+
+func (f *fakeEtcd) Get(_ context.Context, key string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	response := &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: f.revision}}
+	for k, kv := range f.kvs {
+		if strings.HasPrefix(k, key) {
+			response.Kvs = append(response.Kvs, kv)
+		}
+	}
+
+	return response, nil
+}
+
+func (f *fakeEtcd) Close() error {
+	return nil
+}
+
+func newTestMembership(t *testing.T, fake *fakeEtcd, name string) *etcdMembership {
+	t.Helper()
+
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{}
+	c.Dcp.Group.Name = "group"
+	c.Dcp.Group.Membership.Config = map[string]string{config.EtcdMembershipEndpointsConfig: "localhost:2379"}
+
+	em := &etcdMembership{
+		client:           fake,
+		bus:              EventBus.New(),
+		config:           c,
+		membershipConfig: c.GetEtcdMembership(),
+		infoChan:         make(chan *membership.Model, 1),
+		name:             name,
+		weight:           1,
+		keyPrefix:        helpers.Prefix,
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	em.cancelFunc = cancel
+	t.Cleanup(cancel)
+
+	if err := em.bus.Subscribe(helpers.MembershipChangedBusEventName, em.membershipChangedListener); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := em.register(context.Background(), runCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	return em
+}
+
+func listMembers(t *testing.T, e *etcdMembership) {
+	t.Helper()
+
+	if _, err := e.list(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertInfo(t *testing.T, e *etcdMembership, memberNumber int, totalMembers int) {
+	t.Helper()
+
+	info := e.GetInfo()
+	if info.MemberNumber != memberNumber || info.TotalMembers != totalMembers {
+		t.Fatalf("%s: expected member %d/%d, got %d/%d", e.name, memberNumber, totalMembers, info.MemberNumber, info.TotalMembers)
+	}
+}
+
+func TestEtcdMembership_JoinAndLeave(t *testing.T) {
+	fake := newFakeEtcd()
+
+	first := newTestMembership(t, fake, "first")
+	second := newTestMembership(t, fake, "second")
+
+	listMembers(t, first)
+	listMembers(t, second)
+
+	assertInfo(t, first, 1, 2)
+	assertInfo(t, second, 2, 2)
+
+	first.Close()
+
+	if len(fake.kvs) != 1 {
+		t.Fatalf("member key must be deleted with its lease, got %d keys", len(fake.kvs))
+	}
+
+	listMembers(t, second)
+
+	assertInfo(t, second, 1, 1)
+}
+
+func TestEtcdMembership_RegistersAgainAfterLeaseLost(t *testing.T) {
+	fake := newFakeEtcd()
+
+	first := newTestMembership(t, fake, "first")
+	second := newTestMembership(t, fake, "second")
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first.wg.Add(1)
+	go first.runKeepAlive(runCtx)
+
+	leaseID := first.getLeaseID()
+
+	fake.lock.Lock()
+	fake.expire(leaseID)
+	fake.lock.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for first.getLeaseID() == leaseID {
+		if time.Now().After(deadline) {
+			t.Fatalf("member must register again with a new lease")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// a member registered again joins at the end
+	listMembers(t, first)
+	assertInfo(t, first, 2, 2)
+
+	listMembers(t, second)
+	assertInfo(t, second, 1, 2)
+
+	cancel()
+	first.wg.Wait()
+}
@@ -1,21 +1,46 @@
 package membership
 
-import "github.com/Trendyol/go-dcp/logger"
+import (
+	"slices"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/Trendyol/go-dcp/logger"
+)
 
 type Membership interface {
 	GetInfo() *Model
 	Close()
 }
 
+// LivenessProbe reports whether the member made progress within the window, a member whose consumer is stuck
+// on an event for longer is not live although it is still registered.
+type LivenessProbe func(window time.Duration) bool
+
+// LivenessAware is a membership which shares the liveness of the member with the others, so a stuck member is
+// evicted and its vBuckets are reassigned.
+type LivenessAware interface {
+	SetLivenessProbe(probe LivenessProbe)
+}
+
 const (
 	StaticMembershipType                = "static"
 	CouchbaseMembershipType             = "couchbase"
 	KubernetesStatefulSetMembershipType = "kubernetesStatefulSet"
 	KubernetesHaMembershipType          = "kubernetesHa"
 	DynamicMembershipType               = "dynamic"
+	KubernetesLeaseMembershipType       = "kubernetesLease"
+	ConsulMembershipType                = "consul"
+	EtcdMembershipType                  = "etcd"
+	RedisMembershipType                 = "redis"
 )
 
+// Model is the place of the member in the group. Weights are the weights of the members in the order of their
+// numbers, nil when the membership does not share them, so the members get equal shares. MemberNumber is 0 for
+// an idle standby member, it streams no vBuckets.
 type Model struct {
+	Weights      []int
 	MemberNumber int
 	TotalMembers int
 }
@@ -25,10 +50,81 @@ func (s *Model) IsChanged(other *Model) bool {
 		return true
 	}
 
-	res := s.MemberNumber != other.MemberNumber || s.TotalMembers != other.TotalMembers
+	res := s.MemberNumber != other.MemberNumber || s.TotalMembers != other.TotalMembers || !slices.Equal(s.Weights, other.Weights)
 	if !res {
 		logger.Log.Info("membership info not changed")
 	}
 
 	return res
 }
+
+func (s *Model) IsIdle() bool {
+	return s.MemberNumber == 0
+}
+
+// Member is a live member of the memberships which share the settings of their members.
+type Member struct {
+	Weight  int
+	Standby bool
+}
+
+// MemberRecord is the value a member registers with in the key value stores, e.g. etcd and consul.
+type MemberRecord struct {
+	Name            string `json:"name"`
+	ClusterJoinTime int64  `json:"clusterJoinTime"`
+	Weight          int    `json:"weight,omitempty"`
+	Standby         bool   `json:"standby,omitempty"`
+}
+
+// NewMember returns the settings of a member, members of older versions do not share their weight and weigh 1.
+func NewMember(weight int, standby bool) Member {
+	return Member{Weight: max(weight, 1), Standby: standby}
+}
+
+// MemberOf returns the settings of the member whose record is value.
+func MemberOf(value []byte) Member {
+	var record MemberRecord
+	_ = sonic.Unmarshal(value, &record)
+
+	return NewMember(record.Weight, record.Standby)
+}
+
+// NewModel returns the place of the member at index self of the members in their join order. Standby members take
+// the places of the missing active members in their join order, after the active members, when fewer than
+// activeMembers or no active members are alive. The other standby members are idle.
+func NewModel(members []Member, self int, activeMembers int) *Model {
+	active := 0
+	for _, member := range members {
+		if !member.Standby {
+			active++
+		}
+	}
+
+	standbys := max(max(activeMembers, 1)-active, 0)
+
+	model := &Model{Weights: make([]int, 0, len(members))}
+
+	for _, standby := range []bool{false, true} {
+		for i, member := range members {
+			if member.Standby != standby {
+				continue
+			}
+
+			if standby {
+				if standbys == 0 {
+					break
+				}
+				standbys--
+			}
+
+			model.Weights = append(model.Weights, member.Weight)
+			if i == self {
+				model.MemberNumber = len(model.Weights)
+			}
+		}
+	}
+
+	model.TotalMembers = len(model.Weights)
+
+	return model
+}
@@ -0,0 +1,64 @@
+package membership
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewModel(t *testing.T) {
+	members := []Member{
+		{Weight: 1, Standby: true},
+		{Weight: 2},
+		{Weight: 1, Standby: true},
+		{Weight: 3},
+	}
+
+	tests := []struct {
+		name          string
+		weights       []int
+		self          int
+		activeMembers int
+		memberNumber  int
+	}{
+		{name: "active member", self: 3, activeMembers: 2, memberNumber: 2, weights: []int{2, 3}},
+		{name: "idle standby member", self: 0, activeMembers: 2, memberNumber: 0, weights: []int{2, 3}},
+		{name: "standby member in place of a missing active member", self: 0, activeMembers: 3, memberNumber: 3, weights: []int{2, 3, 1}},
+		{name: "later standby member stays idle", self: 2, activeMembers: 3, memberNumber: 0, weights: []int{2, 3, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := NewModel(members, tt.self, tt.activeMembers)
+
+			if model.MemberNumber != tt.memberNumber || model.TotalMembers != len(tt.weights) || !slices.Equal(model.Weights, tt.weights) {
+				t.Errorf("unexpected model: %+v", model)
+			}
+		})
+	}
+
+	model := NewModel([]Member{{Weight: 1, Standby: true}, {Weight: 1, Standby: true}}, 0, 0)
+	if model.MemberNumber != 1 || model.TotalMembers != 1 {
+		t.Errorf("a standby member must be active when no active member is alive, got: %+v", model)
+	}
+}
+
+func TestMemberOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected Member
+	}{
+		{name: "weighted member", value: `{"name":"a","weight":3}`, expected: Member{Weight: 3}},
+		{name: "standby member", value: `{"name":"a","weight":2,"standby":true}`, expected: Member{Weight: 2, Standby: true}},
+		{name: "member of an older version", value: `{"name":"a","clusterJoinTime":1}`, expected: Member{Weight: 1}},
+		{name: "invalid record", value: `{`, expected: Member{Weight: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if member := MemberOf([]byte(tt.value)); member != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, member)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/membership"
+)
+
+// standbyMark suffixes the ids of the standby members.
+const standbyMark = "standby"
+
+// redisMembership keeps the members of a group in a sorted set scored by their latest heartbeat. Members start with
+// their join time, so the set in lexical order is the join order. A member whose heartbeat is older than the tolerance
+// is removed by the next heartbeat of any member, and changes of the set are published on a channel so the other
+// members look at it at once instead of on their next tick.
+type redisMembership struct {
+	client           goredis.UniversalClient
+	bus              EventBus.Bus
+	config           *config.Dcp
+	membershipConfig *config.RedisMembership
+	info             *membership.Model
+	infoChan         chan *membership.Model
+	cancelFunc       context.CancelFunc
+	id               string
+	keyPrefix        string
+	lastMembers      []string
+	weight           int
+	activeMembers    int
+	standby          bool
+	wg               sync.WaitGroup
+	idLock           sync.Mutex
+}
+
+func (r *redisMembership) GetInfo() *membership.Model {
+	if r.info != nil {
+		return r.info
+	}
+
+	return <-r.infoChan
+}
+
+func (r *redisMembership) Close() {
+	err := r.bus.Unsubscribe(helpers.MembershipChangedBusEventName, r.membershipChangedListener)
+	if err != nil {
+		logger.Log.Error("error while unsubscribe: %v", err)
+	}
+
+	r.cancelFunc()
+	r.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.membershipConfig.Timeout)
+	defer cancel()
+
+	// removing the member and notifying lets the other members rebalance at once
+	if err = r.client.ZRem(ctx, r.getMembersKey(), r.getID()).Err(); err != nil {
+		logger.Log.Error("error while remove redis membership member, err: %v", err)
+	} else {
+		r.notify(ctx)
+	}
+
+	if err = r.client.Close(); err != nil {
+		logger.Log.Error("error while close redis client, err: %v", err)
+	}
+}
+
+func (r *redisMembership) membershipChangedListener(model *membership.Model) {
+	shouldSendMessage := r.info == nil
+	r.info = model
+	if shouldSendMessage {
+		go func() {
+			r.infoChan <- model
+		}()
+	}
+}
+
+func (r *redisMembership) getMembersKey() string {
+	// _connector:cbgo:groupName:members
+	return r.keyPrefix + r.config.Dcp.Group.Name + ":members"
+}
+
+func (r *redisMembership) getChannel() string {
+	// _connector:cbgo:groupName:membership
+	return r.keyPrefix + r.config.Dcp.Group.Name + ":membership"
+}
+
+func (r *redisMembership) getID() string {
+	r.idLock.Lock()
+	defer r.idLock.Unlock()
+
+	return r.id
+}
+
+func (r *redisMembership) notify(ctx context.Context) {
+	if err := r.client.Publish(ctx, r.getChannel(), r.getID()).Err(); err != nil {
+		logger.Log.Error("error while publish redis membership change, err: %v", err)
+	}
+}
+
+// heartbeat updates the score of the member and removes the members which missed their heartbeats. A member which is
+// not in the set, e.g. removed after a network partition, joins at the end with a new id. Joins and removals are
+// published.
+func (r *redisMembership) heartbeat(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.membershipConfig.Timeout)
+	defer cancel()
+
+	now := time.Now()
+	deadline := now.Add(-r.membershipConfig.HeartbeatToleranceDuration).UnixMilli()
+
+	pipe := r.client.TxPipeline()
+	updated := pipe.ZAddArgs(ctx, r.getMembersKey(), goredis.ZAddArgs{
+		XX:      true,
+		Ch:      true,
+		Members: []goredis.Z{{Score: float64(now.UnixMilli()), Member: r.getID()}},
+	})
+	removed := pipe.ZRemRangeByScore(ctx, r.getMembersKey(), "-inf", "("+strconv.FormatInt(deadline, 10))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	if removed.Val() > 0 {
+		logger.Log.Info("removed %v dead members from redis membership", removed.Val())
+	}
+
+	joined := updated.Val() == 0
+	if joined {
+		if err := r.join(ctx, now); err != nil {
+			return err
+		}
+	}
+
+	if joined || removed.Val() > 0 {
+		r.notify(ctx)
+	}
+
+	return nil
+}
+
+func (r *redisMembership) join(ctx context.Context, now time.Time) error {
+	id := newMemberID(r.weight, r.standby)
+
+	if err := r.client.ZAdd(ctx, r.getMembersKey(), goredis.Z{Score: float64(now.UnixMilli()), Member: id}).Err(); err != nil {
+		return err
+	}
+
+	r.idLock.Lock()
+	r.id = id
+	r.idLock.Unlock()
+
+	logger.Log.Info("joined redis membership with id: %s", id)
+
+	return nil
+}
+
+func (r *redisMembership) runHeartbeat(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.membershipConfig.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.heartbeat(ctx); err != nil && ctx.Err() == nil {
+				logger.Log.Error("error while redis membership heartbeat, err: %v", err)
+			}
+		}
+	}
+}
+
+// runMonitor reads the members on each published change, and on each heartbeat interval in case a change is missed
+// while the subscription reconnects.
+func (r *redisMembership) runMonitor(ctx context.Context) {
+	defer r.wg.Done()
+
+	logger.Log.Info("redis membership will start after %v", r.config.Dcp.Group.Membership.RebalanceDelay)
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(r.config.Dcp.Group.Membership.RebalanceDelay):
+	}
+
+	pubSub := r.client.Subscribe(ctx, r.getChannel())
+	defer pubSub.Close()
+
+	changes := pubSub.Channel()
+
+	ticker := time.NewTicker(r.membershipConfig.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		r.monitor(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *redisMembership) monitor(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, r.membershipConfig.Timeout)
+	defer cancel()
+
+	deadline := time.Now().Add(-r.membershipConfig.HeartbeatToleranceDuration).UnixMilli()
+
+	members, err := r.client.ZRangeByScore(ctx, r.getMembersKey(), &goredis.ZRangeBy{
+		Min: strconv.FormatInt(deadline, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Log.Error("error while monitor redis membership, err: %v", err)
+		}
+		return
+	}
+
+	// members start with their join time, see newMemberID
+	sort.Strings(members)
+
+	id := r.getID()
+	settings := make([]membership.Member, len(members))
+	selfOrder := 0
+
+	for i, member := range members {
+		settings[i] = memberOf(member)
+		if member == id {
+			selfOrder = i + 1
+		}
+	}
+
+	if selfOrder == 0 {
+		logger.Log.Warn("redis membership member is not found, waiting for the next heartbeat")
+		return
+	}
+
+	if strings.Join(members, ",") == strings.Join(r.lastMembers, ",") {
+		return
+	}
+
+	r.lastMembers = members
+
+	newInfo := membership.NewModel(settings, selfOrder-1, r.activeMembers)
+
+	if newInfo.IsChanged(r.info) {
+		logger.Log.Debug("new info arrived for member: %v/%v", newInfo.MemberNumber, newInfo.TotalMembers)
+
+		r.bus.Publish(helpers.MembershipChangedBusEventName, newInfo)
+	}
+}
+
+// newMemberID prefixes the member with its zero padded join time, so members sort in the order they join, and
+// suffixes it with its weight and the standby mark of standby members.
+func newMemberID(weight int, standby bool) string {
+	id := fmt.Sprintf("%020d:%s:%d", time.Now().UnixNano(), uuid.New().String(), weight)
+	if standby {
+		id += ":" + standbyMark
+	}
+
+	return id
+}
+
+// memberOf returns the settings of the member whose id is given, ids of older versions have no weight.
+func memberOf(id string) membership.Member {
+	parts := strings.Split(id, ":")
+	if len(parts) < 3 {
+		return membership.NewMember(1, false)
+	}
+
+	weight, _ := strconv.Atoi(parts[2])
+
+	return membership.NewMember(weight, len(parts) == 4 && parts[3] == standbyMark)
+}
+
+func newClientOptions(membershipConfig *config.RedisMembership) *goredis.UniversalOptions {
+	options := &goredis.UniversalOptions{
+		Addrs:    membershipConfig.Addresses,
+		Username: membershipConfig.Username,
+		Password: membershipConfig.Password,
+		DB:       membershipConfig.DB,
+	}
+
+	if membershipConfig.SecureConnection {
+		options.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return options
+}
+
+// NewMembership creates the membership with address, username, password, db, keyPrefix, secureConnection,
+// heartbeatInterval, heartbeatToleranceDuration and timeout keys of dcp.group.membership.config. Addresses are comma
+// separated.
+func NewMembership(config *config.Dcp, bus EventBus.Bus) membership.Membership {
+	membershipConfig := config.GetRedisMembership()
+
+	rm := &redisMembership{
+		client:           goredis.NewUniversalClient(newClientOptions(membershipConfig)),
+		bus:              bus,
+		config:           config,
+		membershipConfig: membershipConfig,
+		infoChan:         make(chan *membership.Model),
+		keyPrefix:        helpers.Prefix,
+		weight:           config.GetMembershipWeight(),
+		activeMembers:    config.Dcp.Group.Membership.ActiveMembers,
+		standby:          config.Dcp.Group.Membership.Standby,
+	}
+
+	if membershipConfig.KeyPrefix != "" {
+		rm.keyPrefix = membershipConfig.KeyPrefix
+	}
+
+	if err := rm.heartbeat(context.Background()); err != nil {
+		logger.Log.Error("error while register redis membership, err: %v", err)
+		panic(err)
+	}
+
+	err := bus.SubscribeAsync(helpers.MembershipChangedBusEventName, rm.membershipChangedListener, true)
+	if err != nil {
+		logger.Log.Error("error while subscribe membership changed event, err: %v", err)
+		panic(err)
+	}
+
+	runCtx, runCancel := context.WithCancel(context.Background())
+	rm.cancelFunc = runCancel
+
+	rm.wg.Add(2)
+	go rm.runHeartbeat(runCtx)
+	go rm.runMonitor(runCtx)
+
+	return rm
+}
@@ -0,0 +1,290 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/membership"
+)
+
+// fakeRedis keeps the sorted sets of the membership and counts the published changes.
+type fakeRedis struct {
+	goredis.UniversalClient
+	sets      map[string]map[string]float64
+	published int
+	lock      sync.Mutex
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{sets: map[string]map[string]float64{}}
+}
+
+func (f *fakeRedis) set(key string) map[string]float64 {
+	if f.sets[key] == nil {
+		f.sets[key] = map[string]float64{}
+	}
+
+	return f.sets[key]
+}
+
+func (f *fakeRedis) ZAdd(ctx context.Context, key string, members ...goredis.Z) *goredis.IntCmd {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	cmd := goredis.NewIntCmd(ctx)
+	for _, member := range members {
+		f.set(key)[member.Member.(string)] = member.Score
+	}
+	cmd.SetVal(int64(len(members)))
+
+	return cmd
+}
+
+func (f *fakeRedis) ZRem(ctx context.Context, key string, members ...interface{}) *goredis.IntCmd {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	cmd := goredis.NewIntCmd(ctx)
+	for _, member := range members {
+		delete(f.set(key), member.(string))
+	}
+
+	return cmd
+}
+
+func (f *fakeRedis) ZRangeByScore(ctx context.Context, key string, opt *goredis.ZRangeBy) *goredis.StringSliceCmd {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	minScore, _ := strconv.ParseFloat(opt.Min, 64)
+
+	var members []string
+	for member, score := range f.set(key) {
+		if score >= minScore {
+			members = append(members, member)
+		}
+	}
+
+	cmd := goredis.NewStringSliceCmd(ctx)
+	cmd.SetVal(members)
+
+	return cmd
+}
+
+func (f *fakeRedis) Publish(ctx context.Context, _ string, _ interface{}) *goredis.IntCmd {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.published++
+
+	return goredis.NewIntCmd(ctx)
+}
+
+func (f *fakeRedis) TxPipeline() goredis.Pipeliner {
+	return &fakePipeline{client: f}
+}
+
+func (f *fakeRedis) Close() error {
+	return nil
+}
+
+// fakePipeline runs the queued commands against the sets of the client on Exec.
+type fakePipeline struct {
+	goredis.Pipeliner
+	client   *fakeRedis
+	commands []func()
+}
+
+func (p *fakePipeline) ZAddArgs(ctx context.Context, key string, args goredis.ZAddArgs) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+
+	p.commands = append(p.commands, func() {
+		set := p.client.set(key)
+
+		var changed int64
+		for _, member := range args.Members {
+			score, ok := set[member.Member.(string)]
+			if args.XX && !ok {
+				continue
+			}
+			if !ok || score != member.Score {
+				changed++
+			}
+			set[member.Member.(string)] = member.Score
+		}
+
+		cmd.SetVal(changed)
+	})
+
+	return cmd
+}
+
+func (p *fakePipeline) ZRemRangeByScore(ctx context.Context, key, _, maxScore string) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+
+	p.commands = append(p.commands, func() {
+		deadline, _ := strconv.ParseFloat(strings.TrimPrefix(maxScore, "("), 64)
+
+		var removed int64
+		for member, score := range p.client.set(key) {
+			if score < deadline {
+				delete(p.client.sets[key], member)
+				removed++
+			}
+		}
+
+		cmd.SetVal(removed)
+	})
+
+	return cmd
+}
+
+func (p *fakePipeline) Exec(_ context.Context) ([]goredis.Cmder, error) {
+	p.client.lock.Lock()
+	defer p.client.lock.Unlock()
+
+	for _, command := range p.commands {
+		command()
+	}
+
+	return nil, nil
+}
+
+func newTestMembership(t *testing.T, client *fakeRedis) *redisMembership {
+	t.Helper()
+
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{}
+	c.Dcp.Group.Name = "group"
+	c.Dcp.Group.Membership.Config = map[string]string{config.RedisMembershipAddressConfig: "localhost:6379"}
+
+	rm := &redisMembership{
+		client:           client,
+		bus:              EventBus.New(),
+		config:           c,
+		membershipConfig: c.GetRedisMembership(),
+		infoChan:         make(chan *membership.Model, 1),
+		keyPrefix:        helpers.Prefix,
+		weight:           1,
+	}
+
+	_, rm.cancelFunc = context.WithCancel(context.Background())
+
+	if err := rm.bus.Subscribe(helpers.MembershipChangedBusEventName, rm.membershipChangedListener); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rm.heartbeat(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return rm
+}
+
+func assertInfo(t *testing.T, r *redisMembership, memberNumber int, totalMembers int) {
+	t.Helper()
+
+	r.monitor(context.Background())
+
+	info := r.GetInfo()
+	if info.MemberNumber != memberNumber || info.TotalMembers != totalMembers {
+		t.Fatalf("expected member %d/%d, got %d/%d", memberNumber, totalMembers, info.MemberNumber, info.TotalMembers)
+	}
+}
+
+func TestRedisMembership_JoinAndLeave(t *testing.T) {
+	client := newFakeRedis()
+
+	first := newTestMembership(t, client)
+	second := newTestMembership(t, client)
+
+	if client.published != 2 {
+		t.Fatalf("joins must be published, got %d", client.published)
+	}
+
+	assertInfo(t, first, 1, 2)
+	assertInfo(t, second, 2, 2)
+
+	first.Close()
+
+	if client.published != 3 {
+		t.Fatalf("leave must be published, got %d", client.published)
+	}
+
+	assertInfo(t, second, 1, 1)
+}
+
+func TestRedisMembership_HeartbeatRemovesDeadMembers(t *testing.T) {
+	client := newFakeRedis()
+	rm := newTestMembership(t, client)
+
+	dead := time.Now().Add(-2 * rm.membershipConfig.HeartbeatToleranceDuration).UnixMilli()
+	client.set(rm.getMembersKey())[newMemberID(1, false)] = float64(dead)
+
+	published := client.published
+
+	// the score of the member changes on the next millisecond
+	time.Sleep(2 * time.Millisecond)
+
+	if err := rm.heartbeat(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.set(rm.getMembersKey())) != 1 || client.published != published+1 {
+		t.Fatalf("dead member must be removed and published, members: %v", client.sets)
+	}
+
+	assertInfo(t, rm, 1, 1)
+}
+
+func TestRedisMembership_HeartbeatJoinsAgainAfterRemoval(t *testing.T) {
+	client := newFakeRedis()
+
+	first := newTestMembership(t, client)
+	second := newTestMembership(t, client)
+
+	id := first.getID()
+
+	// the member is removed by the others after a network partition
+	delete(client.set(first.getMembersKey()), id)
+
+	if err := first.heartbeat(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.getID() == id {
+		t.Fatalf("member must join again with a new id")
+	}
+
+	assertInfo(t, first, 2, 2)
+	assertInfo(t, second, 1, 2)
+}
+
+func TestMemberOf(t *testing.T) {
+	tests := []struct {
+		id       string
+		expected membership.Member
+	}{
+		{id: newMemberID(3, false), expected: membership.Member{Weight: 3}},
+		{id: newMemberID(2, true), expected: membership.Member{Weight: 2, Standby: true}},
+		{id: newMemberID(0, false), expected: membership.Member{Weight: 1}},
+		{id: "00000000000000000001:uuid", expected: membership.Member{Weight: 1}},
+	}
+
+	for _, tt := range tests {
+		if member := memberOf(tt.id); member != tt.expected {
+			t.Errorf("id %s: expected %+v, got %+v", tt.id, tt.expected, member)
+		}
+	}
+}
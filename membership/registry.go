@@ -0,0 +1,82 @@
+package membership
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/asaskevich/EventBus"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+// MembershipFactory creates the membership of a registered type from the config. Memberships publish a *Model on
+// helpers.MembershipChangedBusEventName of the bus when the member number or total members change, the streams are
+// rebalanced with it.
+type MembershipFactory func(config *config.Dcp, bus EventBus.Bus) (Membership, error) //nolint:revive
+
+var (
+	factories     = map[string]MembershipFactory{}
+	factoriesLock sync.RWMutex
+)
+
+var ErrMembershipTypeNotRegistered = errors.New("membership type is not registered")
+
+// builtinTypes are created by the package, they cannot be replaced.
+var builtinTypes = map[string]bool{
+	StaticMembershipType:                true,
+	CouchbaseMembershipType:             true,
+	KubernetesStatefulSetMembershipType: true,
+	KubernetesHaMembershipType:          true,
+	DynamicMembershipType:               true,
+	KubernetesLeaseMembershipType:       true,
+	ConsulMembershipType:                true,
+	EtcdMembershipType:                  true,
+	RedisMembershipType:                 true,
+}
+
+// Register makes a membership type selectable with dcp.group.membership.type config. It is meant to be called from
+// init functions and panics when the name is registered already or the factory is nil. Built-in types cannot be
+// replaced.
+func Register(name string, factory MembershipFactory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	if factory == nil {
+		panic("membership: register factory is nil for " + name)
+	}
+
+	if _, ok := factories[name]; ok || builtinTypes[name] {
+		panic("membership: register called twice for " + name)
+	}
+
+	factories[name] = factory
+}
+
+// New creates the membership of the registered type.
+func New(name string, config *config.Dcp, bus EventBus.Bus) (Membership, error) {
+	factoriesLock.RLock()
+	factory, ok := factories[name]
+	factoriesLock.RUnlock()
+
+	if !ok {
+		return nil, ErrMembershipTypeNotRegistered
+	}
+
+	return factory(config, bus)
+}
+
+// Types returns the names of the registered membership types.
+func Types() []string {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
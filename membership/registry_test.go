@@ -0,0 +1,37 @@
+package membership
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/asaskevich/EventBus"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+func TestRegister(t *testing.T) {
+	Register("test", func(_ *config.Dcp, bus EventBus.Bus) (Membership, error) {
+		return NewDynamicMembership(bus), nil
+	})
+
+	m, err := New("test", &config.Dcp{}, EventBus.New())
+	if err != nil || m == nil {
+		t.Fatalf("registered membership must be created, err: %v", err)
+	}
+
+	if _, err = New("unknown", &config.Dcp{}, EventBus.New()); !errors.Is(err, ErrMembershipTypeNotRegistered) {
+		t.Errorf("unknown membership type must not be created, err: %v", err)
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a built-in type must panic")
+		}
+	}()
+
+	Register(StaticMembershipType, func(_ *config.Dcp, _ EventBus.Bus) (Membership, error) {
+		return nil, nil
+	})
+}
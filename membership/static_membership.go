@@ -1,25 +1,56 @@
 package membership
 
 import (
+	"sync"
+
 	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/asaskevich/EventBus"
 )
 
 type staticMembership struct {
 	info *Model
+	bus  EventBus.Bus
+	lock sync.RWMutex
 }
 
 func (s *staticMembership) GetInfo() *Model {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
 	return s.info
 }
 
 func (s *staticMembership) Close() {
+	err := s.bus.Unsubscribe(helpers.MembershipChangedBusEventName, s.membershipChangedListener)
+	if err != nil {
+		logger.Log.Error("error while unsubscribe: %v", err)
+	}
 }
 
-func NewStaticMembership(config *config.Dcp) Membership {
-	return &staticMembership{
+// membershipChangedListener replaces the configured info, so totalMembers can be changed without a restart.
+func (s *staticMembership) membershipChangedListener(m *Model) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.info = m
+}
+
+func NewStaticMembership(config *config.Dcp, bus EventBus.Bus) Membership {
+	sm := &staticMembership{
 		info: &Model{
 			MemberNumber: config.Dcp.Group.Membership.MemberNumber,
 			TotalMembers: config.Dcp.Group.Membership.TotalMembers,
 		},
+		bus: bus,
+	}
+
+	err := bus.Subscribe(helpers.MembershipChangedBusEventName, sm.membershipChangedListener)
+	if err != nil {
+		logger.Log.Error("error while subscribe membership changed event, err: %v", err)
+		panic(err)
 	}
+
+	return sm
 }
@@ -0,0 +1,21 @@
+package metadata
+
+import "context"
+
+const (
+	BootstrapStatusCreated = "created"
+	BootstrapStatusExists  = "exists"
+	BootstrapStatusPassed  = "passed"
+)
+
+// BootstrapStep is an action of a bootstrap with its outcome.
+type BootstrapStep struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Bootstrapper is implemented by metadata which need resources before the first member starts. Bootstrap creates the
+// missing ones and checks the permissions of the member, running it again changes nothing.
+type Bootstrapper interface {
+	Bootstrap(ctx context.Context) ([]BootstrapStep, error)
+}
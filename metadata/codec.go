@@ -0,0 +1,159 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// compressedPrefix marks the compressed values, they are stored as a json string of
+// "cbgo:<compression>:<base64 compressed value>" so they stay valid json for the stores which require it.
+const compressedPrefix = "cbgo:"
+
+var ErrCodecNotSupported = errors.New("metadata type does not support compression or encryption")
+
+// Codec compresses and then encrypts the metadata values before they are written, and reverses both on load. Values
+// are decompressed by the compression they carry, so the compression can be changed or disabled on an existing group
+// and values written without it are read as they are.
+//
+// A nil Codec leaves the values as they are.
+type Codec struct {
+	encryption      *Encryption
+	zstdEncoder     *zstd.Encoder
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+	compression     string
+	zstdDecoderOnce sync.Once
+}
+
+func (c *Codec) Encode(value []byte) ([]byte, error) {
+	if c == nil {
+		return value, nil
+	}
+
+	value, err := c.compress(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.encryption.Encrypt(value)
+}
+
+func (c *Codec) Decode(value []byte) ([]byte, error) {
+	if c == nil {
+		return value, nil
+	}
+
+	value, err := c.encryption.Decrypt(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decompress(value)
+}
+
+func (c *Codec) compress(value []byte) ([]byte, error) {
+	var compressed []byte
+
+	switch c.compression {
+	case CompressionSnappy:
+		compressed = snappy.Encode(nil, value)
+	case CompressionZstd:
+		compressed = c.zstdEncoder.EncodeAll(value, nil)
+	default:
+		return value, nil
+	}
+
+	return sonic.Marshal(compressedPrefix + c.compression + ":" + base64.StdEncoding.EncodeToString(compressed))
+}
+
+func (c *Codec) decompress(value []byte) ([]byte, error) {
+	compression := compressionOf(value)
+	if compression == "" {
+		return value, nil
+	}
+
+	var encoded string
+	if err := sonic.Unmarshal(value, &encoded); err != nil {
+		return nil, err
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(encoded[len(compressedPrefix)+len(compression)+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	if compression == CompressionSnappy {
+		return snappy.Decode(nil, compressed)
+	}
+
+	c.zstdDecoderOnce.Do(func() {
+		c.zstdDecoder, c.zstdDecoderErr = zstd.NewReader(nil)
+	})
+
+	if c.zstdDecoderErr != nil {
+		return nil, c.zstdDecoderErr
+	}
+
+	return c.zstdDecoder.DecodeAll(compressed, nil)
+}
+
+func compressionOf(value []byte) string {
+	for _, compression := range []string{CompressionSnappy, CompressionZstd} {
+		if bytes.HasPrefix(value, []byte(`"`+compressedPrefix+compression+":")) {
+			return compression
+		}
+	}
+
+	return ""
+}
+
+// NewCodec creates the codec of metadata.compression and metadata.encryption.
+func NewCodec(metadataConfig config.Metadata) (*Codec, error) {
+	encryption, err := NewEncryption(metadataConfig.Encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	compression := metadataConfig.Compression
+	if compression == "" {
+		compression = CompressionNone
+	}
+
+	c := &Codec{
+		encryption:  encryption,
+		compression: compression,
+	}
+
+	switch compression {
+	case CompressionNone, CompressionSnappy:
+	case CompressionZstd:
+		if c.zstdEncoder, err = zstd.NewWriter(nil); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown metadata compression: %s", compression)
+	}
+
+	return c, nil
+}
+
+// IsCodecEnabled reports whether the metadata values are compressed or encrypted.
+func IsCodecEnabled(metadataConfig config.Metadata) bool {
+	compressed := metadataConfig.Compression != "" && metadataConfig.Compression != CompressionNone
+	return compressed || metadataConfig.Encryption.Enabled
+}
@@ -0,0 +1,60 @@
+package metadata
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+func TestCodecCompression(t *testing.T) {
+	value := bytes.Repeat([]byte(`{"checkpoint":{"vbuuid":1,"seqNo":1}}`), 64)
+
+	for _, compression := range []string{CompressionSnappy, CompressionZstd} {
+		codec, err := NewCodec(config.Metadata{
+			Compression: compression,
+			Encryption:  config.MetadataEncryption{Enabled: true, Keys: []config.MetadataEncryptionKey{testKey1}},
+		})
+		if err != nil {
+			t.Fatalf("codec must be created, err: %v", err)
+		}
+
+		encoded, err := codec.Encode(value)
+		if err != nil {
+			t.Fatalf("value must be encoded, compression: %v, err: %v", compression, err)
+		}
+
+		if len(encoded) >= len(value) {
+			t.Fatalf("value must be compressed, compression: %v, size: %v", compression, len(encoded))
+		}
+
+		decoded, err := codec.Decode(encoded)
+		if err != nil || !bytes.Equal(decoded, value) {
+			t.Fatalf("value must be decoded, compression: %v, err: %v", compression, err)
+		}
+	}
+}
+
+func TestCodecDecodesChangedCompression(t *testing.T) {
+	value := []byte(`{"checkpoint":{"seqNo":1}}`)
+
+	zstdCodec, _ := NewCodec(config.Metadata{Compression: CompressionZstd})
+	encoded, _ := zstdCodec.Encode(value)
+
+	codec, err := NewCodec(config.Metadata{})
+	if err != nil {
+		t.Fatalf("codec must be created, err: %v", err)
+	}
+
+	if decoded, err := codec.Decode(encoded); err != nil || !bytes.Equal(decoded, value) {
+		t.Fatalf("value compressed before must be decoded, value: %s, err: %v", decoded, err)
+	}
+
+	if decoded, err := codec.Decode(value); err != nil || !bytes.Equal(decoded, value) {
+		t.Fatalf("plain value must be returned as it is, value: %s, err: %v", decoded, err)
+	}
+
+	if _, err = NewCodec(config.Metadata{Compression: "lz4"}); err == nil {
+		t.Fatalf("unknown compression must be rejected")
+	}
+}
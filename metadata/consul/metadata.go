@@ -0,0 +1,367 @@
+package consul
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/hashicorp/consul/api"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const (
+	AddressConfig          = "address"
+	TokenConfig            = "token"
+	DatacenterConfig       = "datacenter"
+	KeyPrefixConfig        = "keyPrefix"
+	MaxTxnOpsConfig        = "maxTxnOps"
+	SecureConnectionConfig = "secureConnection"
+)
+
+const (
+	defaultKeyPrefix = "go-dcp/"
+	// defaultMaxTxnOps is the operation limit of a consul transaction.
+	defaultMaxTxnOps = 64
+	maxCASRetries    = 3
+)
+
+// consulKV is the part of the consul kv client used by the metadata.
+type consulKV interface {
+	Get(key string, q *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error)
+	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
+	Txn(txn api.KVTxnOps, q *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error)
+}
+
+// consulMetadata keeps the checkpoint of each vBucket in a key. Writes are check-and-set operations on the
+// modify index seen by this member, so a checkpoint written by another member in the meantime is never
+// overwritten silently.
+type consulMetadata struct {
+	kv        consulKV
+	config    *config.Dcp
+	codec     *metadata.Codec
+	indexes   map[string]uint64
+	keyPrefix string
+	maxTxnOps int
+	lock      sync.Mutex
+}
+
+func (s *consulMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var ops api.KVTxnOps
+
+	for vbID, doc := range state {
+		if !dirtyOffsets[vbID] {
+			continue
+		}
+
+		value, err := sonic.Marshal(doc)
+		if err == nil {
+			value, err = s.codec.Encode(value)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		key := s.getCheckpointKey(vbID)
+
+		ops = append(ops, &api.KVTxnOp{Verb: api.KVCAS, Key: key, Value: value, Index: s.indexes[key]})
+	}
+
+	for start := 0; start < len(ops); start += s.maxTxnOps {
+		end := start + s.maxTxnOps
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		if err := s.commit(ctx, ops[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commit runs the check-and-set operations in a transaction, and retries the conflicting ones with the latest
+// modify indexes.
+func (s *consulMetadata) commit(ctx context.Context, ops api.KVTxnOps) error {
+	for attempt := 0; len(ops) > 0; attempt++ {
+		ok, response, _, err := s.kv.Txn(ops, s.queryOptions(ctx))
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			for _, pair := range response.Results {
+				s.indexes[pair.Key] = pair.ModifyIndex
+			}
+			return nil
+		}
+
+		if attempt == maxCASRetries {
+			return fmt.Errorf("consul metadata transaction failed: %v", response.Errors)
+		}
+
+		if ops, err = s.resolveConflicts(ctx, ops, response.Errors); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveConflicts refreshes the modify indexes of the failed operations. A key which already holds a newer
+// checkpoint of the same vBucket history is left out, so a stale member can not move the checkpoint back.
+func (s *consulMetadata) resolveConflicts(ctx context.Context, ops api.KVTxnOps, txnErrors api.TxnErrors) (api.KVTxnOps, error) {
+	failed := make(map[int]bool, len(txnErrors))
+	for _, txnError := range txnErrors {
+		failed[txnError.OpIndex] = true
+	}
+
+	resolved := make(api.KVTxnOps, 0, len(ops))
+
+	for i, op := range ops {
+		if !failed[i] {
+			resolved = append(resolved, op)
+			continue
+		}
+
+		pair, _, err := s.kv.Get(op.Key, s.queryOptions(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		if pair == nil {
+			op.Index = 0
+			resolved = append(resolved, op)
+			continue
+		}
+
+		s.indexes[op.Key] = pair.ModifyIndex
+
+		if s.isNewer(pair.Value, op.Value) {
+			logger.Log.Warn("newer checkpoint is written by another member, key: %v", op.Key)
+			continue
+		}
+
+		op.Index = pair.ModifyIndex
+		resolved = append(resolved, op)
+	}
+
+	return resolved, nil
+}
+
+func (s *consulMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	pairs, _, err := s.kv.List(s.getCheckpointKeyPrefix(), s.queryOptions(ctx))
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	values := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		values[pair.Key] = pair.Value
+		s.indexes[pair.Key] = pair.ModifyIndex
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+	exist := false
+
+	for _, vbID := range vbIds {
+		key := s.getCheckpointKey(vbID)
+
+		value, ok := values[key]
+		if !ok {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		doc, err := s.unmarshalCheckpoint(value)
+		if err != nil {
+			logger.Log.Warn("corrupted checkpoint, vbID: %d, key: %v, err: %v", vbID, key, err)
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		state.Store(vbID, doc)
+		exist = true
+	}
+
+	return state, exist, nil
+}
+
+func (s *consulMetadata) Clear(vbIds []uint16) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ops := make(api.KVTxnOps, 0, len(vbIds))
+	for _, vbID := range vbIds {
+		ops = append(ops, &api.KVTxnOp{Verb: api.KVDelete, Key: s.getCheckpointKey(vbID)})
+	}
+
+	for start := 0; start < len(ops); start += s.maxTxnOps {
+		end := start + s.maxTxnOps
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		ok, response, _, err := s.kv.Txn(ops[start:end], s.queryOptions(ctx))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("consul metadata transaction failed: %v", response.Errors)
+		}
+	}
+
+	for _, op := range ops {
+		delete(s.indexes, op.Key)
+	}
+
+	return nil
+}
+
+func (s *consulMetadata) queryOptions(ctx context.Context) *api.QueryOptions {
+	return (&api.QueryOptions{}).WithContext(ctx)
+}
+
+func (s *consulMetadata) getCheckpointKeyPrefix() string {
+	// go-dcp/groupName/checkpoint/
+	return s.keyPrefix + s.config.Dcp.Group.Name + "/checkpoint/"
+}
+
+func (s *consulMetadata) getCheckpointKey(vbID uint16) string {
+	// go-dcp/groupName/checkpoint/vbId
+	return s.getCheckpointKeyPrefix() + strconv.Itoa(int(vbID))
+}
+
+func (s *consulMetadata) unmarshalCheckpoint(value []byte) (*models.CheckpointDocument, error) {
+	value, err := s.codec.Decode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *models.CheckpointDocument
+	if err = sonic.Unmarshal(value, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc == nil || doc.Checkpoint == nil {
+		return nil, errors.New("checkpoint is empty")
+	}
+
+	return doc, nil
+}
+
+// isNewer reports whether the stored checkpoint is ahead of the new one on the same vBucket history.
+func (s *consulMetadata) isNewer(stored []byte, value []byte) bool {
+	storedDoc, err := s.unmarshalCheckpoint(stored)
+	if err != nil {
+		return false
+	}
+
+	doc, err := s.unmarshalCheckpoint(value)
+	if err != nil {
+		return false
+	}
+
+	return storedDoc.Checkpoint.VbUUID == doc.Checkpoint.VbUUID && storedDoc.Checkpoint.SeqNo > doc.Checkpoint.SeqNo
+}
+
+func newClientConfig(metadataConfig map[string]string) (*api.Config, error) {
+	clientConfig := api.DefaultConfig()
+
+	if address, ok := metadataConfig[AddressConfig]; ok {
+		clientConfig.Address = address
+	}
+
+	if token, ok := metadataConfig[TokenConfig]; ok {
+		clientConfig.Token = token
+	}
+
+	if datacenter, ok := metadataConfig[DatacenterConfig]; ok {
+		clientConfig.Datacenter = datacenter
+	}
+
+	if secureConnection, ok := metadataConfig[SecureConnectionConfig]; ok {
+		v, err := strconv.ParseBool(secureConnection)
+		if err != nil {
+			return nil, err
+		}
+		if v {
+			clientConfig.Scheme = "https"
+		}
+	}
+
+	return clientConfig, nil
+}
+
+// NewMetadata creates the metadata with address, token, datacenter, keyPrefix, maxTxnOps and secureConnection
+// keys of metadata.config. Unset keys fall back to the CONSUL_* environment variables of the consul client.
+func NewMetadata(config *config.Dcp) (metadata.Metadata, error) {
+	clientConfig, err := newClientConfig(config.Metadata.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &consulMetadata{
+		config:    config,
+		indexes:   map[string]uint64{},
+		keyPrefix: defaultKeyPrefix,
+		maxTxnOps: defaultMaxTxnOps,
+	}
+
+	if prefix, ok := config.Metadata.Config[KeyPrefixConfig]; ok {
+		s.keyPrefix = prefix
+	}
+
+	if maxTxnOps, ok := config.Metadata.Config[MaxTxnOpsConfig]; ok {
+		if s.maxTxnOps, err = strconv.Atoi(maxTxnOps); err != nil {
+			return nil, err
+		}
+		if s.maxTxnOps <= 0 {
+			return nil, errors.New("consul metadata maxTxnOps must be positive")
+		}
+	}
+
+	if s.codec, err = metadata.NewCodec(config.Metadata); err != nil {
+		return nil, err
+	}
+
+	client, err := api.NewClient(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	s.kv = client.KV()
+
+	return s, nil
+}
+
+func init() {
+	metadata.Register(config.MetadataTypeConsul, NewMetadata)
+}
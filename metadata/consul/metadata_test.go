@@ -0,0 +1,230 @@
+package consul
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/hashicorp/consul/api"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// fakeKV keeps the pairs by key, a transaction is applied only when every check-and-set matches.
+type fakeKV struct {
+	pairs map[string]*api.KVPair
+	index uint64
+	txns  int
+}
+
+func (k *fakeKV) Get(key string, _ *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	return k.pairs[key], &api.QueryMeta{}, nil
+}
+
+func (k *fakeKV) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	var pairs api.KVPairs
+	for key, pair := range k.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs, &api.QueryMeta{}, nil
+}
+
+func (k *fakeKV) Txn(ops api.KVTxnOps, _ *api.QueryOptions) (bool, *api.KVTxnResponse, *api.QueryMeta, error) {
+	k.txns++
+
+	response := &api.KVTxnResponse{}
+
+	for i, op := range ops {
+		var modifyIndex uint64
+		if pair, ok := k.pairs[op.Key]; ok {
+			modifyIndex = pair.ModifyIndex
+		}
+
+		if op.Verb == api.KVCAS && op.Index != modifyIndex {
+			response.Errors = append(response.Errors, &api.TxnError{OpIndex: i, What: "index mismatch"})
+		}
+	}
+
+	if len(response.Errors) > 0 {
+		return false, response, &api.QueryMeta{}, nil
+	}
+
+	for _, op := range ops {
+		switch op.Verb {
+		case api.KVCAS:
+			response.Results = append(response.Results, k.put(op.Key, op.Value))
+		case api.KVDelete:
+			delete(k.pairs, op.Key)
+		}
+	}
+
+	return true, response, &api.QueryMeta{}, nil
+}
+
+func (k *fakeKV) put(key string, value []byte) *api.KVPair {
+	k.index++
+	k.pairs[key] = &api.KVPair{Key: key, Value: value, ModifyIndex: k.index}
+
+	return k.pairs[key]
+}
+
+func newTestMetadata(t *testing.T, maxTxnOps int) (*consulMetadata, *fakeKV) {
+	t.Helper()
+
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{Checkpoint: config.Checkpoint{Timeout: time.Second}}
+	c.Dcp.Group.Name = "group"
+
+	codec, err := metadata.NewCodec(c.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kv := &fakeKV{pairs: map[string]*api.KVPair{}}
+
+	return &consulMetadata{
+		kv:        kv,
+		config:    c,
+		codec:     codec,
+		indexes:   map[string]uint64{},
+		keyPrefix: defaultKeyPrefix,
+		maxTxnOps: maxTxnOps,
+	}, kv
+}
+
+func newTestCheckpoint(seqNo uint64) *models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.VbUUID = 7
+	doc.Checkpoint.SeqNo = seqNo
+	doc.Checkpoint.Snapshot.StartSeqNo = seqNo
+	doc.Checkpoint.Snapshot.EndSeqNo = seqNo + 1
+
+	return doc
+}
+
+func TestConsulMetadata_SaveLoad(t *testing.T) {
+	m, kv := newTestMetadata(t, defaultMaxTxnOps)
+
+	for _, seqNo := range []uint64{10, 11} {
+		err := m.Save(map[uint16]*models.CheckpointDocument{
+			0: newTestCheckpoint(seqNo),
+			1: newTestCheckpoint(20),
+		}, map[uint16]bool{0: true}, "bucket")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := kv.pairs["go-dcp/group/checkpoint/0"]; !ok || len(kv.pairs) != 1 {
+		t.Fatalf("only dirty offsets must be saved, got %v", kv.pairs)
+	}
+
+	state, exist, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoint must be loaded, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, newTestCheckpoint(11)) {
+		t.Errorf("last saved checkpoint must be loaded, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("vBucket without a checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestConsulMetadata_SaveSplitsTransactions(t *testing.T) {
+	m, kv := newTestMetadata(t, 2)
+
+	state := map[uint16]*models.CheckpointDocument{}
+	dirtyOffsets := map[uint16]bool{}
+
+	for vbID := uint16(0); vbID < 5; vbID++ {
+		state[vbID] = newTestCheckpoint(uint64(vbID))
+		dirtyOffsets[vbID] = true
+	}
+
+	if err := m.Save(state, dirtyOffsets, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	if kv.txns != 3 || len(kv.pairs) != 5 {
+		t.Fatalf("5 checkpoints must be saved in 3 transactions, got %d keys in %d", len(kv.pairs), kv.txns)
+	}
+}
+
+func TestConsulMetadata_SaveResolvesConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		other    uint64
+		expected uint64
+	}{
+		{name: "older checkpoint of an other member is overwritten", other: 5, expected: 10},
+		{name: "newer checkpoint of an other member is kept", other: 15, expected: 15},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			m, kv := newTestMetadata(t, defaultMaxTxnOps)
+
+			// the other member writes the checkpoint after this one has seen the key
+			value, err := sonic.Marshal(newTestCheckpoint(tt.other))
+			if err != nil {
+				t.Fatal(err)
+			}
+			kv.put(m.getCheckpointKey(0), value)
+
+			err = m.Save(map[uint16]*models.CheckpointDocument{0: newTestCheckpoint(10)}, map[uint16]bool{0: true}, "bucket")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			doc, err := m.unmarshalCheckpoint(kv.pairs[m.getCheckpointKey(0)].Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if doc.Checkpoint.SeqNo != tt.expected {
+				t.Fatalf("expected seqNo %d, got %d", tt.expected, doc.Checkpoint.SeqNo)
+			}
+		})
+	}
+}
+
+func TestConsulMetadata_Clear(t *testing.T) {
+	m, kv := newTestMetadata(t, defaultMaxTxnOps)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true, 1: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Clear([]uint16{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := kv.pairs[m.getCheckpointKey(0)]; ok {
+		t.Errorf("cleared checkpoint must be deleted")
+	}
+
+	if _, ok := m.indexes[m.getCheckpointKey(0)]; ok {
+		t.Errorf("modify index of the cleared checkpoint must be forgotten")
+	}
+
+	if _, ok := kv.pairs[m.getCheckpointKey(1)]; !ok {
+		t.Errorf("other checkpoints must be kept")
+	}
+}
@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+// DualMetadata writes the checkpoints to the primary and the secondary metadata in parallel and loads them from the
+// primary, so the group can move to another metadata type without a downtime. Secondary failures do not fail the
+// checkpoint, the vBuckets the secondary missed are divergent and written to it with the next save even when they
+// are not dirty.
+type DualMetadata struct {
+	primary   Metadata
+	secondary Metadata
+	divergent map[uint16]bool
+	failures  atomic.Int64
+	lock      sync.Mutex
+}
+
+func (s *DualMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, bucketUUID string) error {
+	s.lock.Lock()
+	secondaryDirtyOffsets := make(map[uint16]bool, len(dirtyOffsets))
+	for vbID, dirty := range dirtyOffsets {
+		secondaryDirtyOffsets[vbID] = dirty || s.divergent[vbID]
+	}
+	for vbID := range s.divergent {
+		if _, ok := state[vbID]; ok {
+			secondaryDirtyOffsets[vbID] = true
+		}
+	}
+	s.lock.Unlock()
+
+	var secondaryErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		secondaryErr = s.secondary.Save(state, secondaryDirtyOffsets, bucketUUID)
+		wg.Done()
+	}()
+
+	err := s.primary.Save(state, dirtyOffsets, bucketUUID)
+
+	wg.Wait()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if secondaryErr != nil {
+		s.failures.Add(1)
+		logger.Log.Warn("error while saving checkpoint to secondary metadata, err: %v", secondaryErr)
+
+		for vbID, dirty := range secondaryDirtyOffsets {
+			if dirty {
+				s.divergent[vbID] = true
+			}
+		}
+	} else {
+		for vbID := range state {
+			delete(s.divergent, vbID)
+		}
+	}
+
+	return err
+}
+
+// Load loads the checkpoints from the primary, the vBuckets whose checkpoints differ in the secondary are divergent.
+func (s *DualMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	state, exist, err := s.primary.Load(vbIds, bucketUUID)
+	if err != nil {
+		return state, exist, err
+	}
+
+	secondaryState, _, secondaryErr := s.secondary.Load(vbIds, bucketUUID)
+	if secondaryErr != nil {
+		s.failures.Add(1)
+		logger.Log.Warn("error while loading checkpoint from secondary metadata, err: %v", secondaryErr)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, vbID := range vbIds {
+		doc, _ := state.Load(vbID)
+
+		var secondaryDoc *models.CheckpointDocument
+		if secondaryErr == nil {
+			secondaryDoc, _ = secondaryState.Load(vbID)
+		}
+
+		if isSameCheckpoint(doc, secondaryDoc) {
+			delete(s.divergent, vbID)
+		} else {
+			s.divergent[vbID] = true
+		}
+	}
+
+	return state, exist, nil
+}
+
+func (s *DualMetadata) Clear(vbIds []uint16) error {
+	if err := s.secondary.Clear(vbIds); err != nil {
+		s.failures.Add(1)
+		logger.Log.Warn("error while clearing secondary metadata, err: %v", err)
+	}
+
+	return s.primary.Clear(vbIds)
+}
+
+// Divergence returns the count of the vBuckets whose checkpoints are not the same in the secondary metadata.
+func (s *DualMetadata) Divergence() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.divergent)
+}
+
+// SecondaryFailures returns the count of the failed secondary metadata operations.
+func (s *DualMetadata) SecondaryFailures() int64 {
+	return s.failures.Load()
+}
+
+func isSameCheckpoint(doc *models.CheckpointDocument, other *models.CheckpointDocument) bool {
+	if doc == nil || other == nil || doc.Checkpoint == nil || other.Checkpoint == nil {
+		return doc == other
+	}
+
+	return doc.Checkpoint.VbUUID == other.Checkpoint.VbUUID && doc.Checkpoint.SeqNo == other.Checkpoint.SeqNo
+}
+
+func NewDualMetadata(primary Metadata, secondary Metadata) *DualMetadata {
+	return &DualMetadata{
+		primary:   primary,
+		secondary: secondary,
+		divergent: map[uint16]bool{},
+	}
+}
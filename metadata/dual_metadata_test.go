@@ -0,0 +1,58 @@
+package metadata
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type failingMetadata struct {
+	Metadata
+	fail bool
+}
+
+func (s *failingMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, bucketUUID string) error {
+	if s.fail {
+		return errors.New("secondary is down")
+	}
+
+	return s.Metadata.Save(state, dirtyOffsets, bucketUUID)
+}
+
+func TestDualMetadata(t *testing.T) {
+	primary, _ := newTestFileMetadata(t)
+	secondaryFile, _ := newTestFileMetadata(t)
+	secondary := &failingMetadata{Metadata: secondaryFile}
+
+	dual := NewDualMetadata(primary, secondary)
+
+	if err := dual.Save(newTestCheckpoint(3), map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatalf("checkpoint must be saved, err: %v", err)
+	}
+
+	if loadSeqNo(t, primary) != 3 || loadSeqNo(t, secondaryFile) != 3 {
+		t.Fatalf("checkpoint must be written to both metadata")
+	}
+
+	secondary.fail = true
+
+	if err := dual.Save(newTestCheckpoint(5), map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatalf("secondary failure must not fail the checkpoint, err: %v", err)
+	}
+
+	if dual.Divergence() != 1 || dual.SecondaryFailures() != 1 {
+		t.Fatalf("vBucket must be divergent, divergence: %v, failures: %v", dual.Divergence(), dual.SecondaryFailures())
+	}
+
+	secondary.fail = false
+
+	// the divergent vBucket is written to the secondary even when it is not dirty
+	if err := dual.Save(newTestCheckpoint(5), map[uint16]bool{0: false}, "bucket"); err != nil {
+		t.Fatalf("checkpoint must be saved, err: %v", err)
+	}
+
+	if loadSeqNo(t, secondaryFile) != 5 || dual.Divergence() != 0 {
+		t.Errorf("secondary must catch up, divergence: %v", dual.Divergence())
+	}
+}
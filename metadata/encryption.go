@@ -0,0 +1,134 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+// encryptedPrefix marks the encrypted values, they are stored as a json string of
+// "cbgo:enc:v1:<key id>:<base64 nonce and sealed value>" so they stay valid json for the stores which require it.
+const encryptedPrefix = "cbgo:enc:v1:"
+
+var (
+	ErrEncryptionKeyNotFound = errors.New("metadata encryption key is not found")
+	errEncryptedValueInvalid = errors.New("encrypted metadata value is invalid")
+)
+
+// Encryption seals the metadata values with AES-GCM before they are written. Values are encrypted with the first key
+// and decrypted with the key whose id they carry, so keys can be rotated by adding a new key to the front and removing
+// the old one once every value is written again. Values which are not encrypted are returned as they are, so the
+// encryption can be enabled on an existing group.
+//
+// A nil Encryption leaves the values as they are.
+type Encryption struct {
+	aeads    map[string]cipher.AEAD
+	activeID string
+}
+
+func (e *Encryption) Encrypt(value []byte) ([]byte, error) {
+	if e == nil {
+		return value, nil
+	}
+
+	aead := e.aeads[e.activeID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := aead.Seal(nonce, nonce, value, []byte(e.activeID))
+
+	return sonic.Marshal(encryptedPrefix + e.activeID + ":" + base64.StdEncoding.EncodeToString(sealed))
+}
+
+func (e *Encryption) Decrypt(value []byte) ([]byte, error) {
+	if e == nil || !isEncrypted(value) {
+		return value, nil
+	}
+
+	var encrypted string
+	if err := sonic.Unmarshal(value, &encrypted); err != nil {
+		return nil, err
+	}
+
+	keyID, encoded, ok := strings.Cut(encrypted[len(encryptedPrefix):], ":")
+	if !ok {
+		return nil, errEncryptedValueInvalid
+	}
+
+	aead, ok := e.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEncryptionKeyNotFound, keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errEncryptedValueInvalid
+	}
+
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(keyID))
+}
+
+func isEncrypted(value []byte) bool {
+	return bytes.HasPrefix(value, []byte(`"`+encryptedPrefix))
+}
+
+// NewEncryption creates the encryption of metadata.encryption, it returns nil when the encryption is not enabled.
+func NewEncryption(encryptionConfig config.MetadataEncryption) (*Encryption, error) {
+	if !encryptionConfig.Enabled {
+		return nil, nil //nolint:nilnil
+	}
+
+	if len(encryptionConfig.Keys) == 0 {
+		return nil, errors.New("metadata encryption keys are not set")
+	}
+
+	e := &Encryption{
+		aeads:    map[string]cipher.AEAD{},
+		activeID: encryptionConfig.Keys[0].ID,
+	}
+
+	for _, key := range encryptionConfig.Keys {
+		if key.ID == "" || strings.Contains(key.ID, ":") {
+			return nil, fmt.Errorf("metadata encryption key id %q is invalid", key.ID)
+		}
+
+		if _, ok := e.aeads[key.ID]; ok {
+			return nil, fmt.Errorf("metadata encryption key id %q is duplicated", key.ID)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("metadata encryption key %q is not base64: %w", key.ID, err)
+		}
+
+		block, err := aes.NewCipher(decoded)
+		if err != nil {
+			return nil, fmt.Errorf("metadata encryption key %q: %w", key.ID, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		e.aeads[key.ID] = aead
+	}
+
+	return e, nil
+}
@@ -0,0 +1,82 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+func newTestEncryption(t *testing.T, keys ...config.MetadataEncryptionKey) *Encryption {
+	encryption, err := NewEncryption(config.MetadataEncryption{Enabled: true, Keys: keys})
+	if err != nil {
+		t.Fatalf("encryption must be created, err: %v", err)
+	}
+
+	return encryption
+}
+
+var (
+	testKey1 = config.MetadataEncryptionKey{ID: "k1", Key: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}
+	testKey2 = config.MetadataEncryptionKey{ID: "k2", Key: "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="}
+)
+
+func TestEncryptionRotation(t *testing.T) {
+	value := []byte(`{"checkpoint":{"seqNo":1}}`)
+
+	encrypted, err := newTestEncryption(t, testKey1).Encrypt(value)
+	if err != nil {
+		t.Fatalf("value must be encrypted, err: %v", err)
+	}
+
+	if bytes.Contains(encrypted, []byte("seqNo")) {
+		t.Fatalf("encrypted value must not contain the plain value")
+	}
+
+	rotated := newTestEncryption(t, testKey2, testKey1)
+
+	decrypted, err := rotated.Decrypt(encrypted)
+	if err != nil || !bytes.Equal(decrypted, value) {
+		t.Fatalf("value must be decrypted with the old key, value: %s, err: %v", decrypted, err)
+	}
+
+	decrypted, err = rotated.Decrypt(value)
+	if err != nil || !bytes.Equal(decrypted, value) {
+		t.Fatalf("plain value must be returned as it is, value: %s, err: %v", decrypted, err)
+	}
+
+	if _, err = newTestEncryption(t, testKey2).Decrypt(encrypted); !errors.Is(err, ErrEncryptionKeyNotFound) {
+		t.Fatalf("value of a removed key must not be decrypted, err: %v", err)
+	}
+}
+
+func TestFileMetadataEncryption(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	fileName := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	m := NewFSMetadata(&config.Dcp{
+		Metadata: config.Metadata{
+			Type:       config.MetadataTypeFile,
+			Config:     map[string]string{config.FileMetadataFileNameConfig: fileName},
+			Encryption: config.MetadataEncryption{Enabled: true, Keys: []config.MetadataEncryptionKey{testKey1}},
+		},
+	})
+
+	if err := m.Save(newTestCheckpoint(7), nil, "bucket"); err != nil {
+		t.Fatalf("checkpoint must be saved, err: %v", err)
+	}
+
+	file, _ := os.ReadFile(fileName)
+	if bytes.Contains(file, []byte("seqNo")) {
+		t.Fatalf("file must not contain the plain checkpoint")
+	}
+
+	if seqNo := loadSeqNo(t, m); seqNo != 7 {
+		t.Fatalf("seqNo must be 7, got: %v", seqNo)
+	}
+}
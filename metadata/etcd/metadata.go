@@ -0,0 +1,297 @@
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const (
+	EndpointsConfig        = "endpoints"
+	UsernameConfig         = "username"
+	PasswordConfig         = "password"
+	KeyPrefixConfig        = "keyPrefix"
+	LeaseTTLConfig         = "leaseTTL"
+	MaxTxnOpsConfig        = "maxTxnOps"
+	DialTimeoutConfig      = "dialTimeout"
+	SecureConnectionConfig = "secureConnection"
+)
+
+// defaultMaxTxnOps is the default --max-txn-ops of etcd servers.
+const defaultMaxTxnOps = 128
+
+// etcdClient is the part of the etcd client used by the metadata.
+type etcdClient interface {
+	clientv3.KV
+	clientv3.Lease
+}
+
+// etcdMetadata keeps the checkpoint of each vBucket in a key. The dirty vBuckets of a save are written in transactions,
+// so they are committed together. When leaseTTL is set, the keys are attached to a lease kept alive by the process.
+type etcdMetadata struct {
+	client    etcdClient
+	config    *config.Dcp
+	codec     *metadata.Codec
+	keyPrefix string
+	leaseTTL  time.Duration
+	maxTxnOps int
+	leaseID   clientv3.LeaseID
+	leaseLock sync.Mutex
+}
+
+func (s *etcdMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	var opts []clientv3.OpOption
+	if s.leaseTTL > 0 {
+		leaseID, err := s.getLease(ctx)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, clientv3.WithLease(leaseID))
+	}
+
+	var ops []clientv3.Op
+
+	for vbID, doc := range state {
+		if !dirtyOffsets[vbID] {
+			continue
+		}
+
+		value, err := sonic.Marshal(doc)
+		if err == nil {
+			value, err = s.codec.Encode(value)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		ops = append(ops, clientv3.OpPut(s.getCheckpointKey(vbID), string(value), opts...))
+	}
+
+	return s.commit(ctx, ops)
+}
+
+func (s *etcdMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	response, err := s.client.Get(ctx, s.getCheckpointKeyPrefix(), clientv3.WithPrefix())
+	if err != nil {
+		return nil, false, err
+	}
+
+	values := make(map[string][]byte, len(response.Kvs))
+	for _, kv := range response.Kvs {
+		values[string(kv.Key)] = kv.Value
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+	exist := false
+
+	for _, vbID := range vbIds {
+		key := s.getCheckpointKey(vbID)
+
+		value, ok := values[key]
+		if !ok {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		var doc *models.CheckpointDocument
+		value, err := s.codec.Decode(value)
+		if err == nil {
+			err = sonic.Unmarshal(value, &doc)
+		}
+
+		if err != nil || doc == nil || doc.Checkpoint == nil {
+			logger.Log.Warn("corrupted checkpoint, vbID: %d, key: %v, err: %v", vbID, key, err)
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		state.Store(vbID, doc)
+		exist = true
+	}
+
+	return state, exist, nil
+}
+
+func (s *etcdMetadata) Clear(vbIds []uint16) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	ops := make([]clientv3.Op, 0, len(vbIds))
+	for _, vbID := range vbIds {
+		ops = append(ops, clientv3.OpDelete(s.getCheckpointKey(vbID)))
+	}
+
+	return s.commit(ctx, ops)
+}
+
+// commit runs the ops in transactions of at most maxTxnOps, which is the limit of the server.
+func (s *etcdMetadata) commit(ctx context.Context, ops []clientv3.Op) error {
+	for start := 0; start < len(ops); start += s.maxTxnOps {
+		end := start + s.maxTxnOps
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		if _, err := s.client.Txn(ctx).Then(ops[start:end]...).Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getLease grants the lease of the keys on first use, and again after the previous one is lost.
+func (s *etcdMetadata) getLease(ctx context.Context) (clientv3.LeaseID, error) {
+	s.leaseLock.Lock()
+	defer s.leaseLock.Unlock()
+
+	if s.leaseID != clientv3.NoLease {
+		return s.leaseID, nil
+	}
+
+	lease, err := s.client.Grant(ctx, int64(s.leaseTTL.Seconds()))
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+
+	keepAlive, err := s.client.KeepAlive(context.Background(), lease.ID)
+	if err != nil {
+		return clientv3.NoLease, err
+	}
+
+	s.leaseID = lease.ID
+
+	go s.keepAlive(lease.ID, keepAlive)
+
+	return lease.ID, nil
+}
+
+func (s *etcdMetadata) keepAlive(leaseID clientv3.LeaseID, keepAlive <-chan *clientv3.LeaseKeepAliveResponse) {
+	for range keepAlive {
+		// responses are drained till the lease is lost
+	}
+
+	logger.Log.Warn("etcd metadata lease lost, leaseID: %x", leaseID)
+
+	s.leaseLock.Lock()
+	if s.leaseID == leaseID {
+		s.leaseID = clientv3.NoLease
+	}
+	s.leaseLock.Unlock()
+}
+
+func (s *etcdMetadata) getCheckpointKeyPrefix() string {
+	// _connector:cbgo:groupName:checkpoint:
+	return s.keyPrefix + s.config.Dcp.Group.Name + ":checkpoint:"
+}
+
+func (s *etcdMetadata) getCheckpointKey(vbID uint16) string {
+	// _connector:cbgo:groupName:checkpoint:vbId
+	return s.getCheckpointKeyPrefix() + strconv.Itoa(int(vbID))
+}
+
+func newClientConfig(metadataConfig map[string]string) (*clientv3.Config, error) {
+	endpoints, ok := metadataConfig[EndpointsConfig]
+	if !ok || endpoints == "" {
+		return nil, errors.New("etcd metadata endpoints is not set")
+	}
+
+	clientConfig := &clientv3.Config{
+		Endpoints:   strings.Split(endpoints, ","),
+		Username:    metadataConfig[UsernameConfig],
+		Password:    metadataConfig[PasswordConfig],
+		DialTimeout: 5 * time.Second,
+	}
+
+	if dialTimeout, ok := metadataConfig[DialTimeoutConfig]; ok {
+		v, err := time.ParseDuration(dialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		clientConfig.DialTimeout = v
+	}
+
+	if secureConnection, ok := metadataConfig[SecureConnectionConfig]; ok {
+		v, err := strconv.ParseBool(secureConnection)
+		if err != nil {
+			return nil, err
+		}
+		if v {
+			clientConfig.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+	}
+
+	return clientConfig, nil
+}
+
+// NewMetadata creates the metadata with endpoints, username, password, keyPrefix, leaseTTL, maxTxnOps, dialTimeout
+// and secureConnection keys of metadata.config. Endpoints are comma separated.
+func NewMetadata(config *config.Dcp) (metadata.Metadata, error) {
+	clientConfig, err := newClientConfig(config.Metadata.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &etcdMetadata{
+		config:    config,
+		keyPrefix: helpers.Prefix,
+		maxTxnOps: defaultMaxTxnOps,
+	}
+
+	if prefix, ok := config.Metadata.Config[KeyPrefixConfig]; ok {
+		s.keyPrefix = prefix
+	}
+
+	if leaseTTL, ok := config.Metadata.Config[LeaseTTLConfig]; ok {
+		if s.leaseTTL, err = time.ParseDuration(leaseTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	if maxTxnOps, ok := config.Metadata.Config[MaxTxnOpsConfig]; ok {
+		if s.maxTxnOps, err = strconv.Atoi(maxTxnOps); err != nil {
+			return nil, err
+		}
+		if s.maxTxnOps <= 0 {
+			return nil, errors.New("etcd metadata maxTxnOps must be positive")
+		}
+	}
+
+	if s.codec, err = metadata.NewCodec(config.Metadata); err != nil {
+		return nil, err
+	}
+
+	if s.client, err = clientv3.New(*clientConfig); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func init() {
+	metadata.Register(config.MetadataTypeEtcd, NewMetadata)
+}
@@ -0,0 +1,196 @@
+package etcd
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type fakeKV struct {
+	etcdClient
+	values map[string]string
+	txns   int
+}
+
+func (c *fakeKV) Get(_ context.Context, key string, _ ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	response := &clientv3.GetResponse{}
+
+	for k, v := range c.values {
+		if strings.HasPrefix(k, key) {
+			response.Kvs = append(response.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+		}
+	}
+
+	return response, nil
+}
+
+func (c *fakeKV) Txn(_ context.Context) clientv3.Txn {
+	return &fakeTxn{client: c}
+}
+
+// fakeTxn applies the put and delete ops of Then to the values of the client on Commit.
+type fakeTxn struct {
+	clientv3.Txn
+	client *fakeKV
+	ops    []clientv3.Op
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.ops = append(t.ops, ops...)
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	for _, op := range t.ops {
+		switch {
+		case op.IsPut():
+			t.client.values[string(op.KeyBytes())] = string(op.ValueBytes())
+		case op.IsDelete():
+			delete(t.client.values, string(op.KeyBytes()))
+		}
+	}
+
+	t.client.txns++
+
+	return &clientv3.TxnResponse{}, nil
+}
+
+func newTestMetadata(t *testing.T, maxTxnOps int) (*etcdMetadata, *fakeKV) {
+	t.Helper()
+
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{Checkpoint: config.Checkpoint{Timeout: time.Second}}
+	c.Dcp.Group.Name = "group"
+
+	codec, err := metadata.NewCodec(c.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeKV{values: map[string]string{}}
+
+	return &etcdMetadata{
+		client:    client,
+		config:    c,
+		codec:     codec,
+		keyPrefix: helpers.Prefix,
+		maxTxnOps: maxTxnOps,
+	}, client
+}
+
+func newTestCheckpoint(seqNo uint64) *models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.VbUUID = 7
+	doc.Checkpoint.SeqNo = seqNo
+	doc.Checkpoint.Snapshot.StartSeqNo = seqNo
+	doc.Checkpoint.Snapshot.EndSeqNo = seqNo + 1
+
+	return doc
+}
+
+func TestEtcdMetadata_SaveLoad(t *testing.T) {
+	m, client := newTestMetadata(t, defaultMaxTxnOps)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for key := range client.values {
+		keys = append(keys, key)
+	}
+
+	if expected := []string{"_connector:cbgo:group:checkpoint:0"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("only dirty offsets must be saved to %v, got %v", expected, keys)
+	}
+
+	state, exist, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoint must be loaded, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, newTestCheckpoint(10)) {
+		t.Errorf("saved checkpoint must be loaded, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("vBucket without a checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestEtcdMetadata_SaveSplitsTransactions(t *testing.T) {
+	m, client := newTestMetadata(t, 2)
+
+	state := map[uint16]*models.CheckpointDocument{}
+	dirtyOffsets := map[uint16]bool{}
+
+	for vbID := uint16(0); vbID < 5; vbID++ {
+		state[vbID] = newTestCheckpoint(uint64(vbID))
+		dirtyOffsets[vbID] = true
+	}
+
+	if err := m.Save(state, dirtyOffsets, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	if client.txns != 3 || len(client.values) != 5 {
+		t.Fatalf("5 checkpoints must be saved in 3 transactions, got %d keys in %d", len(client.values), client.txns)
+	}
+}
+
+func TestEtcdMetadata_LoadCorruptedCheckpoint(t *testing.T) {
+	m, client := newTestMetadata(t, defaultMaxTxnOps)
+	client.values[m.getCheckpointKey(0)] = "invalid"
+
+	state, exist, err := m.Load([]uint16{0}, "bucket")
+	if err != nil || exist {
+		t.Fatalf("corrupted checkpoint must not exist, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("corrupted checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestEtcdMetadata_Clear(t *testing.T) {
+	m, client := newTestMetadata(t, defaultMaxTxnOps)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true, 1: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Clear([]uint16{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for key := range client.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if expected := []string{m.getCheckpointKey(1)}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("only the cleared checkpoint must be deleted, expected %v, got %v", expected, keys)
+	}
+}
@@ -1,8 +1,13 @@
 package metadata
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"hash/crc32"
 	"os"
+	"path/filepath"
+	"strconv"
 
 	"github.com/bytedance/sonic"
 
@@ -15,44 +20,188 @@ import (
 	"github.com/Trendyol/go-dcp/logger"
 )
 
+const defaultFileMetadataGenerations = 2
+
+var errFileMetadataCorrupted = errors.New("file metadata checksum mismatch")
+
+// fileEnvelope keeps the checkpoints with their checksum, so a torn or edited file is detected on load.
+type fileEnvelope struct {
+	Checkpoints json.RawMessage `json:"checkpoints"`
+	CRC         uint32          `json:"crc"`
+}
+
+// fileMetadata writes the checkpoints to a temp file which replaces the file after it is synced, so a crash leaves
+// either the previous or the new checkpoint. The previous files are kept as fileName.1 ... fileName.N and loaded
+// when the newer ones are corrupted.
 type fileMetadata struct { //nolint:unused
-	fileName string
+	config      *config.Dcp
+	codec       *Codec
+	fileName    string
+	generations int
 }
 
 func (s *fileMetadata) Save(state map[uint16]*models.CheckpointDocument, _ map[uint16]bool, _ string) error { //nolint:unused
-	file, _ := sonic.MarshalIndent(state, "", "  ")
-	_ = os.WriteFile(s.fileName, file, 0o644) //nolint:gosec
+	checkpoints, err := sonic.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	checkpoints, err = s.codec.Encode(checkpoints)
+	if err != nil {
+		return err
+	}
+
+	file, err := sonic.Marshal(fileEnvelope{Checkpoints: checkpoints, CRC: crc32.ChecksumIEEE(checkpoints)})
+	if err != nil {
+		return err
+	}
+
+	tmpFileName, err := s.writeTemp(file)
+	if err != nil {
+		return err
+	}
+
+	if err = s.rotate(); err != nil {
+		_ = os.Remove(tmpFileName)
+		return err
+	}
+
+	if err = os.Rename(tmpFileName, s.fileName); err != nil {
+		_ = os.Remove(tmpFileName)
+		return err
+	}
+
+	return syncDir(filepath.Dir(s.fileName))
+}
+
+func (s *fileMetadata) writeTemp(data []byte) (string, error) { //nolint:unused
+	tmp, err := os.CreateTemp(filepath.Dir(s.fileName), filepath.Base(s.fileName)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+
+	if err = errors.Join(err, tmp.Close()); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// rotate shifts the previous generations by one, the oldest one is overwritten.
+func (s *fileMetadata) rotate() error { //nolint:unused
+	if s.generations == 0 {
+		return nil
+	}
+
+	for i := s.generations - 1; i >= 0; i-- {
+		err := os.Rename(s.generationFileName(i), s.generationFileName(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (s *fileMetadata) generationFileName(generation int) string { //nolint:unused
+	if generation == 0 {
+		return s.fileName
+	}
+
+	return s.fileName + "." + strconv.Itoa(generation)
+}
+
 func (s *fileMetadata) Load(vbIds []uint16, bucketUUID string) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) { //nolint:lll,unused
-	file, err := os.ReadFile(s.fileName)
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
 
-	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](1024)
-	exist := true
+	var lastErr error
 
-	if err != nil {
+	for generation := 0; generation <= s.generations; generation++ {
+		fileName := s.generationFileName(generation)
+
+		checkpoints, err := readFileCheckpoints(fileName)
 		if errors.Is(err, os.ErrNotExist) {
-			exist = false
+			continue
+		}
+
+		if err == nil {
+			checkpoints, err = s.codec.Decode(checkpoints)
+		}
 
-			for _, vbID := range vbIds {
-				state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
-			}
-		} else {
-			return nil, exist, err
+		if err == nil {
+			err = state.UnmarshalJSON(checkpoints)
 		}
-	} else {
-		_ = state.UnmarshalJSON(file)
+
+		if err != nil {
+			logger.Log.Warn("error while loading file metadata, file: %v, err: %v", fileName, err)
+			lastErr = err
+			state = wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+			continue
+		}
+
+		if generation > 0 {
+			logger.Log.Warn("file metadata is loaded from an older generation, file: %v", fileName)
+		}
+
+		return state, true, nil
+	}
+
+	if lastErr != nil {
+		return nil, true, fmt.Errorf("no valid file metadata generation: %w", lastErr)
+	}
+
+	for _, vbID := range vbIds {
+		state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+	}
+
+	return state, false, nil
+}
+
+// readFileCheckpoints returns the checkpoints of the file after its checksum is verified.
+// Files written before the checksum are returned as they are.
+func readFileCheckpoints(fileName string) ([]byte, error) { //nolint:unused
+	file, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope fileEnvelope
+	if err = sonic.Unmarshal(file, &envelope); err != nil {
+		return nil, err
+	}
+
+	if envelope.Checkpoints == nil {
+		return file, nil
 	}
 
-	return state, exist, nil
+	if crc32.ChecksumIEEE(envelope.Checkpoints) != envelope.CRC {
+		return nil, errFileMetadataCorrupted
+	}
+
+	return envelope.Checkpoints, nil
 }
 
 func (s *fileMetadata) Clear(_ []uint16) error { //nolint:unused
-	_ = os.Remove(s.fileName)
+	for generation := 0; generation <= s.generations; generation++ {
+		_ = os.Remove(s.generationFileName(generation))
+	}
 	return nil
 }
 
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	return errors.Join(d.Sync(), d.Close())
+}
+
 func NewFSMetadata(config *config.Dcp) Metadata { //nolint:unused
 	if !config.IsFileMetadata() {
 		err := errors.New("unsupported metadata type")
@@ -60,7 +209,32 @@ func NewFSMetadata(config *config.Dcp) Metadata { //nolint:unused
 		panic(err)
 	}
 
+	codec, err := NewCodec(config.Metadata)
+	if err != nil {
+		logger.Log.Error("error while initialize file metadata, err: %s", err)
+		panic(err)
+	}
+
 	return &fileMetadata{
-		fileName: config.GetFileMetadata(),
+		config:      config,
+		codec:       codec,
+		fileName:    config.GetFileMetadata(),
+		generations: getFileGenerations(config.Metadata.Config),
+	}
+}
+
+func getFileGenerations(metadataConfig map[string]string) int {
+	value, ok := metadataConfig[config.FileMetadataGenerationsConfig]
+	if !ok {
+		return defaultFileMetadataGenerations
 	}
+
+	generations, err := strconv.Atoi(value)
+	if err != nil || generations < 0 {
+		err = errors.Join(errors.New("file metadata generations must be a non-negative number"), err)
+		logger.Log.Error("error while initialize file metadata, err: %s", err)
+		panic(err)
+	}
+
+	return generations
 }
@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+func newTestFileMetadata(t *testing.T) (Metadata, string) {
+	logger.InitDefaultLogger("error")
+
+	fileName := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	return NewFSMetadata(&config.Dcp{
+		Metadata: config.Metadata{
+			Type:   config.MetadataTypeFile,
+			Config: map[string]string{config.FileMetadataFileNameConfig: fileName},
+		},
+	}), fileName
+}
+
+func newTestCheckpoint(seqNo uint64) map[uint16]*models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.SeqNo = seqNo
+
+	return map[uint16]*models.CheckpointDocument{0: doc}
+}
+
+func loadSeqNo(t *testing.T, m Metadata) uint64 {
+	state, exist, err := m.Load([]uint16{0}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoint must be loaded, exist: %v, err: %v", exist, err)
+	}
+
+	doc, _ := state.Load(0)
+
+	return doc.Checkpoint.SeqNo
+}
+
+func TestFileMetadataSaveLoad(t *testing.T) {
+	m, fileName := newTestFileMetadata(t)
+
+	for seqNo := uint64(1); seqNo <= 4; seqNo++ {
+		if err := m.Save(newTestCheckpoint(seqNo), nil, "bucket"); err != nil {
+			t.Fatalf("checkpoint must be saved, err: %v", err)
+		}
+	}
+
+	if seqNo := loadSeqNo(t, m); seqNo != 4 {
+		t.Errorf("latest checkpoint must be loaded, seqNo: %v", seqNo)
+	}
+
+	if _, err := os.Stat(fileName + ".2"); err != nil {
+		t.Errorf("previous generations must be kept, err: %v", err)
+	}
+
+	if _, err := os.Stat(fileName + ".3"); err == nil {
+		t.Errorf("generations more than the limit must not be kept")
+	}
+}
+
+func TestFileMetadataLoadCorrupted(t *testing.T) {
+	m, fileName := newTestFileMetadata(t)
+
+	_ = m.Save(newTestCheckpoint(1), nil, "bucket")
+	_ = m.Save(newTestCheckpoint(2), nil, "bucket")
+
+	file, _ := os.ReadFile(fileName)
+	_ = os.WriteFile(fileName, file[:len(file)/2], 0o600)
+
+	if seqNo := loadSeqNo(t, m); seqNo != 1 {
+		t.Errorf("previous generation must be loaded when the file is truncated, seqNo: %v", seqNo)
+	}
+
+	_ = os.WriteFile(fileName+".1", []byte(`{"checkpoints":{},"crc":1}`), 0o600)
+
+	if _, _, err := m.Load([]uint16{0}, "bucket"); err == nil {
+		t.Errorf("load must fail when no generation is valid")
+	}
+}
+
+func TestFileMetadataLoadWithoutChecksum(t *testing.T) {
+	m, fileName := newTestFileMetadata(t)
+
+	_ = os.WriteFile(fileName, []byte(`{"0":{"checkpoint":{"vbuuid":0,"seqno":7,"snapshot":{"startSeqno":0,"endSeqno":0}},"bucketUuid":""}}`), 0o600)
+
+	if seqNo := loadSeqNo(t, m); seqNo != 7 {
+		t.Errorf("file written before the checksum must be loaded, seqNo: %v", seqNo)
+	}
+}
+
+func TestFileMetadataKeepsProcessedTime(t *testing.T) {
+	m, _ := newTestFileMetadata(t)
+
+	checkpoint := newTestCheckpoint(1)
+	checkpoint[0].Version = "v1.2.3"
+	checkpoint[0].ProcessedTime = 1700000000000000000
+
+	if err := m.Save(checkpoint, nil, "bucket"); err != nil {
+		t.Fatalf("checkpoint must be saved, err: %v", err)
+	}
+
+	state, _, _ := m.Load([]uint16{0}, "bucket")
+	doc, _ := state.Load(0)
+
+	if doc.Version != "v1.2.3" || doc.ProcessedTime != 1700000000000000000 {
+		t.Errorf("version and processed time must be kept, version: %v, processed time: %v", doc.Version, doc.ProcessedTime)
+	}
+}
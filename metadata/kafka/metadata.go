@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const TopicConfig = "topic"
+
+const defaultTopic = "go-dcp-checkpoints"
+
+// Record is a message of the checkpoint topic. A nil value is a tombstone which removes the key on compaction.
+type Record struct {
+	Key   []byte
+	Value []byte
+}
+
+// Client is the part of a Kafka client the metadata uses. It is implemented by a small adapter of the client
+// the application already uses, so the connector does not bring its own Kafka client.
+type Client interface {
+	// Produce writes the records to the topic, and returns after all in-sync replicas acknowledge them.
+	Produce(ctx context.Context, topic string, records []Record) error
+	// ReadAll reads the topic from the earliest offsets to the end offsets at the time of the call.
+	ReadAll(ctx context.Context, topic string) ([]Record, error)
+}
+
+// kafkaMetadata keeps the checkpoint of each vBucket as the latest record of its key in a compacted topic.
+// Keys are group name/vbId, so checkpoints can be inspected with the standard Kafka tooling.
+type kafkaMetadata struct {
+	client Client
+	config *config.Dcp
+	codec  *metadata.Codec
+	topic  string
+}
+
+func (s *kafkaMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	records := make([]Record, 0, len(state))
+
+	for vbID, doc := range state {
+		if !dirtyOffsets[vbID] {
+			continue
+		}
+
+		value, err := sonic.Marshal(doc)
+		if err == nil {
+			value, err = s.codec.Encode(value)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		records = append(records, Record{Key: s.getCheckpointKey(vbID), Value: value})
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	return s.client.Produce(ctx, s.topic, records)
+}
+
+func (s *kafkaMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	records, err := s.client.ReadAll(ctx, s.topic)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Records of a key are read in order, the latest one is the checkpoint.
+	values := map[string][]byte{}
+	for _, record := range records {
+		key := string(record.Key)
+		if !strings.HasPrefix(key, s.getCheckpointKeyPrefix()) {
+			continue
+		}
+
+		if record.Value == nil {
+			delete(values, key)
+		} else {
+			values[key] = record.Value
+		}
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+	exist := false
+
+	for _, vbID := range vbIds {
+		key := string(s.getCheckpointKey(vbID))
+
+		value, ok := values[key]
+		if !ok {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		var doc *models.CheckpointDocument
+		value, err := s.codec.Decode(value)
+		if err == nil {
+			err = sonic.Unmarshal(value, &doc)
+		}
+
+		if err != nil || doc == nil || doc.Checkpoint == nil {
+			logger.Log.Warn("corrupted checkpoint, vbID: %d, key: %v, err: %v", vbID, key, err)
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		state.Store(vbID, doc)
+		exist = true
+	}
+
+	return state, exist, nil
+}
+
+func (s *kafkaMetadata) Clear(vbIds []uint16) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	records := make([]Record, 0, len(vbIds))
+	for _, vbID := range vbIds {
+		records = append(records, Record{Key: s.getCheckpointKey(vbID)})
+	}
+
+	return s.client.Produce(ctx, s.topic, records)
+}
+
+func (s *kafkaMetadata) getCheckpointKeyPrefix() string {
+	// groupName/
+	return s.config.Dcp.Group.Name + "/"
+}
+
+func (s *kafkaMetadata) getCheckpointKey(vbID uint16) []byte {
+	// groupName/vbId
+	return []byte(s.getCheckpointKeyPrefix() + strconv.Itoa(int(vbID)))
+}
+
+// NewMetadata creates the metadata with the topic key of metadata.config. The topic is expected to exist
+// with cleanup.policy=compact.
+func NewMetadata(config *config.Dcp, client Client) metadata.Metadata {
+	topic := defaultTopic
+	if v, ok := config.Metadata.Config[TopicConfig]; ok && v != "" {
+		topic = v
+	}
+
+	codec, err := metadata.NewCodec(config.Metadata)
+	if err != nil {
+		logger.Log.Error("error while initialize kafka metadata, err: %v", err)
+		panic(err)
+	}
+
+	return &kafkaMetadata{
+		client: client,
+		config: config,
+		codec:  codec,
+		topic:  topic,
+	}
+}
+
+// Use registers the client to be used by metadata.type kafka, it is called once before the connector is created.
+func Use(client Client) {
+	metadata.Register(config.MetadataTypeKafka, func(config *config.Dcp) (metadata.Metadata, error) {
+		return NewMetadata(config, client), nil
+	})
+}
@@ -0,0 +1,160 @@
+package kafka
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// fakeClient appends the produced records to the log of their topic, as a topic before compaction.
+type fakeClient struct {
+	topics map[string][]Record
+}
+
+func (c *fakeClient) Produce(_ context.Context, topic string, records []Record) error {
+	c.topics[topic] = append(c.topics[topic], records...)
+	return nil
+}
+
+func (c *fakeClient) ReadAll(_ context.Context, topic string) ([]Record, error) {
+	return c.topics[topic], nil
+}
+
+func newTestConfig(metadataConfig map[string]string) *config.Dcp {
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{
+		Checkpoint: config.Checkpoint{Timeout: time.Second},
+		Metadata:   config.Metadata{Type: config.MetadataTypeKafka, Config: metadataConfig},
+	}
+	c.Dcp.Group.Name = "group"
+
+	return c
+}
+
+func newTestCheckpoint(seqNo uint64) *models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.VbUUID = 7
+	doc.Checkpoint.SeqNo = seqNo
+	doc.Checkpoint.Snapshot.StartSeqNo = seqNo
+	doc.Checkpoint.Snapshot.EndSeqNo = seqNo + 1
+
+	return doc
+}
+
+func TestKafkaMetadata_Topic(t *testing.T) {
+	tests := []struct {
+		config   map[string]string
+		expected string
+	}{
+		{config: nil, expected: defaultTopic},
+		{config: map[string]string{TopicConfig: ""}, expected: defaultTopic},
+		{config: map[string]string{TopicConfig: "checkpoints"}, expected: "checkpoints"},
+	}
+
+	for _, tt := range tests {
+		client := &fakeClient{topics: map[string][]Record{}}
+		m := NewMetadata(newTestConfig(tt.config), client)
+
+		err := m.Save(map[uint16]*models.CheckpointDocument{0: newTestCheckpoint(10)}, map[uint16]bool{0: true}, "bucket")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(client.topics[tt.expected]) != 1 {
+			t.Errorf("checkpoint must be produced to %s, got %v", tt.expected, client.topics)
+		}
+	}
+}
+
+func TestKafkaMetadata_SaveLoad(t *testing.T) {
+	client := &fakeClient{topics: map[string][]Record{}}
+	m := NewMetadata(newTestConfig(nil), client)
+
+	for _, seqNo := range []uint64{10, 11} {
+		err := m.Save(map[uint16]*models.CheckpointDocument{
+			0: newTestCheckpoint(seqNo),
+			1: newTestCheckpoint(20),
+		}, map[uint16]bool{0: true}, "bucket")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the checkpoint of an other group shares the topic
+	client.topics[defaultTopic] = append(client.topics[defaultTopic], Record{Key: []byte("other/1"), Value: []byte("{}")})
+
+	records := client.topics[defaultTopic]
+	if len(records) != 3 || string(records[0].Key) != "group/0" {
+		t.Fatalf("only dirty offsets must be produced with group/vbId keys, got %d records", len(records))
+	}
+
+	state, exist, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoint must be loaded, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, newTestCheckpoint(11)) {
+		t.Errorf("latest record of the key must be loaded, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("vBucket without a checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestKafkaMetadata_LoadCorruptedCheckpoint(t *testing.T) {
+	client := &fakeClient{topics: map[string][]Record{
+		defaultTopic: {{Key: []byte("group/0"), Value: []byte("invalid")}},
+	}}
+	m := NewMetadata(newTestConfig(nil), client)
+
+	state, exist, err := m.Load([]uint16{0}, "bucket")
+	if err != nil || exist {
+		t.Fatalf("corrupted checkpoint must not exist, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("corrupted checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestKafkaMetadata_ClearProducesTombstones(t *testing.T) {
+	client := &fakeClient{topics: map[string][]Record{}}
+	m := NewMetadata(newTestConfig(nil), client)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true, 1: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Clear([]uint16{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	records := client.topics[defaultTopic]
+	if tombstone := records[len(records)-1]; string(tombstone.Key) != "group/0" || tombstone.Value != nil {
+		t.Fatalf("clear must produce a tombstone, got %+v", tombstone)
+	}
+
+	state, _, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("cleared checkpoint must be loaded empty, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, newTestCheckpoint(20)) {
+		t.Errorf("other checkpoints must be kept, got %+v", doc)
+	}
+}
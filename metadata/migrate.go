@@ -0,0 +1,76 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+var ErrMigrationVerification = errors.New("migrated checkpoint does not match the source")
+
+// MigrationReport is the outcome of a migration. Migrated are the vBuckets whose checkpoints are copied and verified,
+// Skipped is the count of vBuckets without a checkpoint in the source.
+type MigrationReport struct {
+	Migrated []uint16
+	Skipped  int
+}
+
+// Migrate copies the checkpoints of the vBuckets with their history from src to dst, and loads them back from dst to
+// verify them. vBuckets without a checkpoint in src are skipped, so they are not overwritten in dst. The members of
+// the group must be stopped, a checkpoint saved to src during the migration is not copied.
+func Migrate(src Metadata, dst Metadata, vbIds []uint16) (*MigrationReport, error) {
+	if _, ok := dst.(*readMetadata); ok {
+		return nil, ErrMetadataReadOnly
+	}
+
+	state, _, err := src.Load(vbIds, "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot load source checkpoints: %w", err)
+	}
+
+	report := &MigrationReport{}
+
+	checkpoints := make(map[uint16]*models.CheckpointDocument, len(vbIds))
+	dirtyOffsets := make(map[uint16]bool, len(vbIds))
+	bucketUUID := ""
+
+	for _, vbID := range vbIds {
+		doc, ok := state.Load(vbID)
+		if !ok || isEmptyCheckpoint(doc) {
+			report.Skipped++
+			continue
+		}
+
+		checkpoints[vbID] = doc
+		dirtyOffsets[vbID] = true
+		bucketUUID = doc.BucketUUID
+		report.Migrated = append(report.Migrated, vbID)
+	}
+
+	if len(checkpoints) == 0 {
+		return report, nil
+	}
+
+	if err = dst.Save(checkpoints, dirtyOffsets, bucketUUID); err != nil {
+		return nil, fmt.Errorf("cannot save checkpoints to destination: %w", err)
+	}
+
+	migrated, _, err := dst.Load(report.Migrated, bucketUUID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load destination checkpoints: %w", err)
+	}
+
+	for _, vbID := range report.Migrated {
+		doc, _ := migrated.Load(vbID)
+		if !isSameCheckpoint(checkpoints[vbID], doc) {
+			return nil, fmt.Errorf("%w, vbID: %d", ErrMigrationVerification, vbID)
+		}
+	}
+
+	return report, nil
+}
+
+func isEmptyCheckpoint(doc *models.CheckpointDocument) bool {
+	return doc == nil || doc.Checkpoint == nil || doc.Checkpoint.VbUUID == 0 && doc.Checkpoint.SeqNo == 0 && len(doc.History) == 0
+}
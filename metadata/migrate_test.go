@@ -0,0 +1,52 @@
+package metadata
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+type lossyMetadata struct {
+	Metadata
+}
+
+func (s *lossyMetadata) Load(vbIds []uint16, bucketUUID string) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	state, exist, err := s.Metadata.Load(vbIds, bucketUUID)
+	if err == nil {
+		state.Store(0, models.NewEmptyCheckpointDocument(bucketUUID))
+	}
+
+	return state, exist, err
+}
+
+func TestMigrate(t *testing.T) {
+	src, _ := newTestFileMetadata(t)
+	dst, _ := newTestFileMetadata(t)
+
+	if err := src.Save(newTestCheckpoint(7), map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatalf("checkpoint must be saved, err: %v", err)
+	}
+
+	report, err := Migrate(src, dst, []uint16{0, 1})
+	if err != nil {
+		t.Fatalf("metadata must be migrated, err: %v", err)
+	}
+
+	if len(report.Migrated) != 1 || report.Migrated[0] != 0 || report.Skipped != 1 {
+		t.Fatalf("only the vBucket with a checkpoint must be migrated, report: %+v", report)
+	}
+
+	if loadSeqNo(t, dst) != 7 {
+		t.Fatalf("checkpoint must be copied to destination")
+	}
+
+	if _, err = Migrate(src, &lossyMetadata{Metadata: dst}, []uint16{0}); !errors.Is(err, ErrMigrationVerification) {
+		t.Fatalf("migration must be verified, err: %v", err)
+	}
+
+	if _, err = Migrate(src, NewReadMetadata(dst), []uint16{0}); !errors.Is(err, ErrMetadataReadOnly) {
+		t.Fatalf("read only destination must be rejected, err: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const (
+	URIConfig        = "uri"
+	DatabaseConfig   = "database"
+	CollectionConfig = "collection"
+	TTLConfig        = "ttl"
+)
+
+const (
+	defaultDatabase   = "go_dcp"
+	defaultCollection = "checkpoints"
+)
+
+const (
+	groupField     = "group"
+	vbIDField      = "vbId"
+	updatedAtField = "updatedAt"
+)
+
+// checkpointDocument is the stored form of a vBucket checkpoint. BSON has no unsigned integers,
+// so the uint64 values are kept with the same bits in int64 fields.
+type checkpointDocument struct {
+	ID            string    `bson:"_id"`
+	Group         string    `bson:"group"`
+	BucketUUID    string    `bson:"bucketUuid"`
+	Version       string    `bson:"version,omitempty"`
+	UpdatedAt     time.Time `bson:"updatedAt"`
+	ProcessedTime int64     `bson:"processedTime,omitempty"`
+	VbUUID        int64     `bson:"vbUuid"`
+	SeqNo         int64     `bson:"seqNo"`
+	StartSeqNo    int64     `bson:"startSeqNo"`
+	EndSeqNo      int64     `bson:"endSeqNo"`
+	VbID          uint16    `bson:"vbId"`
+}
+
+// mongoCollection is the part of the collection used by the metadata.
+type mongoCollection interface {
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	Indexes() mongo.IndexView
+}
+
+// mongoMetadata keeps the checkpoint of each vBucket in a document. The dirty vBuckets of a save are written
+// with one unordered bulk upsert. When ttl is set, documents not written for ttl are removed by a TTL index,
+// so the checkpoints of removed groups are cleaned up.
+type mongoMetadata struct {
+	client     *mongo.Client
+	collection mongoCollection
+	config     *config.Dcp
+	written    map[uint16]time.Time
+	ttl        time.Duration
+	lock       sync.Mutex
+}
+
+func (s *mongoMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	vbIDs := make([]uint16, 0, len(state))
+	writes := make([]mongo.WriteModel, 0, len(state))
+
+	for vbID, doc := range state {
+		if !dirtyOffsets[vbID] && !s.expiring(vbID, now) {
+			continue
+		}
+
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: s.getDocumentID(vbID)}}).
+			SetReplacement(s.newDocument(vbID, doc, now)).
+			SetUpsert(true),
+		)
+		vbIDs = append(vbIDs, vbID)
+	}
+
+	if len(writes) == 0 {
+		return nil
+	}
+
+	if _, err := s.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+
+	for _, vbID := range vbIDs {
+		s.written[vbID] = now
+	}
+
+	return nil
+}
+
+// expiring reports whether the checkpoint of a clean vBucket should be rewritten to keep it away from the TTL index.
+func (s *mongoMetadata) expiring(vbID uint16, now time.Time) bool {
+	if s.ttl == 0 {
+		return false
+	}
+
+	return now.Sub(s.written[vbID]) > s.ttl/2
+}
+
+func (s *mongoMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	cursor, err := s.collection.Find(ctx, s.getFilter(vbIds))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var docs []checkpointDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, false, err
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+
+	s.lock.Lock()
+	for _, doc := range docs {
+		state.Store(doc.VbID, &models.CheckpointDocument{
+			Checkpoint: &models.CheckpointDocumentCheckpoint{
+				Snapshot: &models.CheckpointDocumentSnapshot{
+					StartSeqNo: uint64(doc.StartSeqNo),
+					EndSeqNo:   uint64(doc.EndSeqNo),
+				},
+				VbUUID: uint64(doc.VbUUID),
+				SeqNo:  uint64(doc.SeqNo),
+			},
+			BucketUUID:    doc.BucketUUID,
+			Version:       doc.Version,
+			ProcessedTime: doc.ProcessedTime,
+		})
+		s.written[doc.VbID] = doc.UpdatedAt
+	}
+	s.lock.Unlock()
+
+	for _, vbID := range vbIds {
+		if _, ok := state.Load(vbID); !ok {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+		}
+	}
+
+	return state, len(docs) > 0, nil
+}
+
+func (s *mongoMetadata) Clear(vbIds []uint16) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	_, err := s.collection.DeleteMany(ctx, s.getFilter(vbIds))
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	for _, vbID := range vbIds {
+		delete(s.written, vbID)
+	}
+	s.lock.Unlock()
+
+	return nil
+}
+
+func (s *mongoMetadata) getFilter(vbIds []uint16) bson.D {
+	return bson.D{
+		{Key: groupField, Value: s.config.Dcp.Group.Name},
+		{Key: vbIDField, Value: bson.D{{Key: "$in", Value: vbIds}}},
+	}
+}
+
+func (s *mongoMetadata) getDocumentID(vbID uint16) string {
+	// groupName:checkpoint:vbId
+	return s.config.Dcp.Group.Name + ":checkpoint:" + strconv.Itoa(int(vbID))
+}
+
+func (s *mongoMetadata) newDocument(vbID uint16, doc *models.CheckpointDocument, now time.Time) *checkpointDocument {
+	return &checkpointDocument{
+		ID:            s.getDocumentID(vbID),
+		Group:         s.config.Dcp.Group.Name,
+		VbID:          vbID,
+		VbUUID:        int64(doc.Checkpoint.VbUUID),
+		SeqNo:         int64(doc.Checkpoint.SeqNo),
+		StartSeqNo:    int64(doc.Checkpoint.Snapshot.StartSeqNo),
+		EndSeqNo:      int64(doc.Checkpoint.Snapshot.EndSeqNo),
+		BucketUUID:    doc.BucketUUID,
+		Version:       doc.Version,
+		ProcessedTime: doc.ProcessedTime,
+		UpdatedAt:     now,
+	}
+}
+
+func (s *mongoMetadata) createIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: groupField, Value: 1}, {Key: vbIDField, Value: 1}}},
+	}
+
+	if s.ttl > 0 {
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: updatedAtField, Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(s.ttl.Seconds())),
+		})
+	}
+
+	_, err := s.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// NewMetadata creates the metadata with uri, database, collection and ttl keys of metadata.config.
+// The indexes of the collection are created when they do not exist.
+func NewMetadata(config *config.Dcp) (metadata.Metadata, error) {
+	// checkpoints are stored as fields to be queried, so they can not be compressed or encrypted as a whole
+	if metadata.IsCodecEnabled(config.Metadata) {
+		return nil, metadata.ErrCodecNotSupported
+	}
+
+	uri, ok := config.Metadata.Config[URIConfig]
+	if !ok || uri == "" {
+		return nil, errors.New("mongodb metadata uri is not set")
+	}
+
+	database := defaultDatabase
+	if v, ok := config.Metadata.Config[DatabaseConfig]; ok {
+		database = v
+	}
+
+	collection := defaultCollection
+	if v, ok := config.Metadata.Config[CollectionConfig]; ok {
+		collection = v
+	}
+
+	s := &mongoMetadata{
+		config:  config,
+		written: map[uint16]time.Time{},
+	}
+
+	if ttl, ok := config.Metadata.Config[TTLConfig]; ok {
+		var err error
+		if s.ttl, err = time.ParseDuration(ttl); err != nil {
+			return nil, err
+		}
+		if s.ttl > 0 && s.ttl < time.Second {
+			return nil, errors.New("mongodb metadata ttl must be at least one second")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Checkpoint.Timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	s.client = client
+	s.collection = client.Database(database).Collection(collection)
+
+	if err := s.createIndexes(ctx); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func init() {
+	metadata.Register(config.MetadataTypeMongoDB, NewMetadata)
+}
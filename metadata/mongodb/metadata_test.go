@@ -0,0 +1,194 @@
+package mongodb
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// fakeCollection keeps the checkpoint documents by id and matches the group and vbId filter of the metadata.
+type fakeCollection struct {
+	mongoCollection
+	docs   map[string]*checkpointDocument
+	writes int
+}
+
+func (c *fakeCollection) BulkWrite(
+	_ context.Context,
+	models []mongo.WriteModel,
+	_ ...*options.BulkWriteOptions,
+) (*mongo.BulkWriteResult, error) {
+	for _, model := range models {
+		doc := model.(*mongo.ReplaceOneModel).Replacement.(*checkpointDocument)
+		c.docs[doc.ID] = doc
+	}
+
+	c.writes += len(models)
+
+	return &mongo.BulkWriteResult{UpsertedCount: int64(len(models))}, nil
+}
+
+func (c *fakeCollection) Find(_ context.Context, filter interface{}, _ ...*options.FindOptions) (*mongo.Cursor, error) {
+	var docs []interface{}
+	for _, doc := range c.docs {
+		if matches(filter, doc) {
+			docs = append(docs, doc)
+		}
+	}
+
+	return mongo.NewCursorFromDocuments(docs, nil, nil)
+}
+
+func (c *fakeCollection) DeleteMany(_ context.Context, filter interface{}, _ ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	var deleted int64
+	for id, doc := range c.docs {
+		if matches(filter, doc) {
+			delete(c.docs, id)
+			deleted++
+		}
+	}
+
+	return &mongo.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func matches(filter interface{}, doc *checkpointDocument) bool {
+	d := filter.(bson.D)
+
+	if d[0].Key != groupField || d[0].Value != doc.Group || d[1].Key != vbIDField {
+		return false
+	}
+
+	for _, vbID := range d[1].Value.(bson.D)[0].Value.([]uint16) {
+		if vbID == doc.VbID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newTestMetadata(ttl time.Duration) (*mongoMetadata, *fakeCollection) {
+	c := &config.Dcp{Checkpoint: config.Checkpoint{Timeout: time.Second}}
+	c.Dcp.Group.Name = "group"
+
+	collection := &fakeCollection{docs: map[string]*checkpointDocument{}}
+
+	return &mongoMetadata{
+		collection: collection,
+		config:     c,
+		written:    map[uint16]time.Time{},
+		ttl:        ttl,
+	}, collection
+}
+
+func newTestCheckpoint(seqNo uint64) *models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.VbUUID = 7
+	doc.Checkpoint.SeqNo = seqNo
+	doc.Checkpoint.Snapshot.StartSeqNo = seqNo
+	doc.Checkpoint.Snapshot.EndSeqNo = seqNo + 1
+	doc.Version = "v1.0.0"
+	doc.ProcessedTime = 42
+
+	return doc
+}
+
+func TestMongoMetadata_SaveLoad(t *testing.T) {
+	m, collection := newTestMetadata(0)
+
+	// uint64 values over the int64 range keep their bits
+	large := newTestCheckpoint(10)
+	large.Checkpoint.VbUUID = math.MaxUint64
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: large,
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := collection.docs["group:checkpoint:0"]; !ok || len(collection.docs) != 1 {
+		t.Fatalf("only dirty offsets must be saved, got %v", collection.docs)
+	}
+
+	state, exist, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoint must be loaded, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, large) {
+		t.Errorf("saved checkpoint must be loaded, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("vBucket without a checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestMongoMetadata_SaveRewritesExpiringCheckpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		written time.Duration
+		writes  int
+	}{
+		{name: "without ttl", ttl: 0, written: -time.Hour, writes: 0},
+		{name: "recently written", ttl: time.Hour, written: -time.Minute, writes: 0},
+		{name: "over half of ttl", ttl: time.Hour, written: -31 * time.Minute, writes: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			m, collection := newTestMetadata(tt.ttl)
+			m.written[0] = time.Now().Add(tt.written)
+
+			err := m.Save(map[uint16]*models.CheckpointDocument{0: newTestCheckpoint(10)}, map[uint16]bool{}, "bucket")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if collection.writes != tt.writes {
+				t.Fatalf("expected %d writes of the clean checkpoint, got %d", tt.writes, collection.writes)
+			}
+		})
+	}
+}
+
+func TestMongoMetadata_Clear(t *testing.T) {
+	m, collection := newTestMetadata(0)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true, 1: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Clear([]uint16{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := collection.docs["group:checkpoint:0"]; ok {
+		t.Errorf("cleared checkpoint must be deleted")
+	}
+
+	if _, ok := collection.docs["group:checkpoint:1"]; !ok {
+		t.Errorf("other checkpoints must be kept")
+	}
+
+	if _, ok := m.written[0]; ok {
+		t.Errorf("write time of the cleared checkpoint must be forgotten")
+	}
+}
@@ -0,0 +1,237 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strconv"
+	"strings"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const (
+	AddressConfig          = "address"
+	UsernameConfig         = "username"
+	PasswordConfig         = "password"
+	DBConfig               = "db"
+	KeyPrefixConfig        = "keyPrefix"
+	SecureConnectionConfig = "secureConnection"
+)
+
+const (
+	vbUUIDField     = "vbuuid"
+	seqNoField      = "seqno"
+	startSeqNoField = "startSeqno"
+	endSeqNoField   = "endSeqno"
+	bucketUUIDField = "bucketUuid"
+	versionField    = "version"
+	processedField  = "processedTime"
+)
+
+// redisMetadata keeps the checkpoint of each vBucket in a hash. Saves and loads of the vBuckets are pipelined.
+type redisMetadata struct {
+	client    goredis.UniversalClient
+	config    *config.Dcp
+	keyPrefix string
+}
+
+func (s *redisMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	pipe := s.client.Pipeline()
+
+	for vbID, doc := range state {
+		if !dirtyOffsets[vbID] {
+			continue
+		}
+
+		pipe.HSet(ctx, s.getCheckpointKey(vbID),
+			vbUUIDField, doc.Checkpoint.VbUUID,
+			seqNoField, doc.Checkpoint.SeqNo,
+			startSeqNoField, doc.Checkpoint.Snapshot.StartSeqNo,
+			endSeqNoField, doc.Checkpoint.Snapshot.EndSeqNo,
+			bucketUUIDField, doc.BucketUUID,
+			versionField, doc.Version,
+			processedField, doc.ProcessedTime,
+		)
+	}
+
+	if pipe.Len() == 0 {
+		return nil
+	}
+
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+func (s *redisMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	pipe := s.client.Pipeline()
+
+	commands := make([]*goredis.MapStringStringCmd, len(vbIds))
+	for i, vbID := range vbIds {
+		commands[i] = pipe.HGetAll(ctx, s.getCheckpointKey(vbID))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, false, err
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+	exist := false
+
+	for i, vbID := range vbIds {
+		fields := commands[i].Val()
+		if len(fields) == 0 {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		doc, err := parseCheckpointDocument(fields)
+		if err != nil {
+			logger.Log.Warn("corrupted checkpoint, vbID: %d, key: %v, err: %v", vbID, s.getCheckpointKey(vbID), err)
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		state.Store(vbID, doc)
+		exist = true
+	}
+
+	return state, exist, nil
+}
+
+func (s *redisMetadata) Clear(vbIds []uint16) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	pipe := s.client.Pipeline()
+
+	for _, vbID := range vbIds {
+		pipe.Del(ctx, s.getCheckpointKey(vbID))
+	}
+
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+func (s *redisMetadata) getCheckpointKey(vbID uint16) string {
+	// _connector:cbgo:groupName:checkpoint:vbId
+	return s.keyPrefix + s.config.Dcp.Group.Name + ":checkpoint:" + strconv.Itoa(int(vbID))
+}
+
+func parseCheckpointDocument(fields map[string]string) (*models.CheckpointDocument, error) {
+	values := map[string]uint64{}
+
+	for _, field := range []string{vbUUIDField, seqNoField, startSeqNoField, endSeqNoField} {
+		value, err := strconv.ParseUint(fields[field], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		values[field] = value
+	}
+
+	doc := &models.CheckpointDocument{
+		Checkpoint: &models.CheckpointDocumentCheckpoint{
+			Snapshot: &models.CheckpointDocumentSnapshot{
+				StartSeqNo: values[startSeqNoField],
+				EndSeqNo:   values[endSeqNoField],
+			},
+			VbUUID: values[vbUUIDField],
+			SeqNo:  values[seqNoField],
+		},
+		BucketUUID: fields[bucketUUIDField],
+		Version:    fields[versionField],
+	}
+
+	// checkpoints saved by older versions have no processed time
+	if value, ok := fields[processedField]; ok {
+		processedTime, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		doc.ProcessedTime = processedTime
+	}
+
+	return doc, nil
+}
+
+func newClientOptions(metadataConfig map[string]string) (*goredis.UniversalOptions, error) {
+	address, ok := metadataConfig[AddressConfig]
+	if !ok || address == "" {
+		return nil, errors.New("redis metadata address is not set")
+	}
+
+	options := &goredis.UniversalOptions{
+		Addrs:    strings.Split(address, ","),
+		Username: metadataConfig[UsernameConfig],
+		Password: metadataConfig[PasswordConfig],
+	}
+
+	if db, ok := metadataConfig[DBConfig]; ok {
+		v, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, err
+		}
+		options.DB = v
+	}
+
+	if secureConnection, ok := metadataConfig[SecureConnectionConfig]; ok {
+		v, err := strconv.ParseBool(secureConnection)
+		if err != nil {
+			return nil, err
+		}
+		if v {
+			options.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+	}
+
+	return options, nil
+}
+
+// NewMetadata creates the metadata with address, username, password, db, keyPrefix and secureConnection
+// keys of metadata.config. Comma separated addresses connect to a redis cluster.
+func NewMetadata(config *config.Dcp) (metadata.Metadata, error) {
+	// checkpoints are stored as fields to be queried, so they can not be compressed or encrypted as a whole
+	if metadata.IsCodecEnabled(config.Metadata) {
+		return nil, metadata.ErrCodecNotSupported
+	}
+
+	options, err := newClientOptions(config.Metadata.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPrefix := helpers.Prefix
+	if prefix, ok := config.Metadata.Config[KeyPrefixConfig]; ok {
+		keyPrefix = prefix
+	}
+
+	return &redisMetadata{
+		client:    goredis.NewUniversalClient(options),
+		config:    config,
+		keyPrefix: keyPrefix,
+	}, nil
+}
+
+func init() {
+	metadata.Register(config.MetadataTypeRedis, NewMetadata)
+}
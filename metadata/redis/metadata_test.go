@@ -0,0 +1,187 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type fakeRedis struct {
+	goredis.UniversalClient
+	hashes map[string]map[string]string
+}
+
+func (c *fakeRedis) Pipeline() goredis.Pipeliner {
+	return &fakePipeline{client: c}
+}
+
+// fakePipeline runs the queued commands against the hashes of the client on Exec.
+type fakePipeline struct {
+	goredis.Pipeliner
+	client   *fakeRedis
+	commands []func()
+}
+
+func (p *fakePipeline) HSet(ctx context.Context, key string, values ...interface{}) *goredis.IntCmd {
+	p.commands = append(p.commands, func() {
+		hash := map[string]string{}
+		for i := 0; i < len(values); i += 2 {
+			hash[values[i].(string)] = fmt.Sprint(values[i+1])
+		}
+
+		p.client.hashes[key] = hash
+	})
+
+	return goredis.NewIntCmd(ctx)
+}
+
+func (p *fakePipeline) HGetAll(ctx context.Context, key string) *goredis.MapStringStringCmd {
+	cmd := goredis.NewMapStringStringCmd(ctx)
+
+	p.commands = append(p.commands, func() {
+		cmd.SetVal(p.client.hashes[key])
+	})
+
+	return cmd
+}
+
+func (p *fakePipeline) Del(ctx context.Context, keys ...string) *goredis.IntCmd {
+	p.commands = append(p.commands, func() {
+		for _, key := range keys {
+			delete(p.client.hashes, key)
+		}
+	})
+
+	return goredis.NewIntCmd(ctx)
+}
+
+func (p *fakePipeline) Len() int {
+	return len(p.commands)
+}
+
+func (p *fakePipeline) Exec(_ context.Context) ([]goredis.Cmder, error) {
+	for _, command := range p.commands {
+		command()
+	}
+
+	p.commands = nil
+
+	return nil, nil
+}
+
+func newTestMetadata(keyPrefix string) (*redisMetadata, *fakeRedis) {
+	logger.InitDefaultLogger("error")
+
+	client := &fakeRedis{hashes: map[string]map[string]string{}}
+
+	c := &config.Dcp{Checkpoint: config.Checkpoint{Timeout: time.Second}}
+	c.Dcp.Group.Name = "group"
+
+	return &redisMetadata{client: client, config: c, keyPrefix: keyPrefix}, client
+}
+
+func newTestCheckpoint(seqNo uint64) *models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.VbUUID = 7
+	doc.Checkpoint.SeqNo = seqNo
+	doc.Checkpoint.Snapshot.StartSeqNo = seqNo
+	doc.Checkpoint.Snapshot.EndSeqNo = seqNo + 1
+	doc.Version = "v1.0.0"
+	doc.ProcessedTime = 42
+
+	return doc
+}
+
+func TestRedisMetadata_GetCheckpointKey(t *testing.T) {
+	tests := []struct {
+		keyPrefix string
+		expected  string
+	}{
+		{keyPrefix: helpers.Prefix, expected: "_connector:cbgo:group:checkpoint:5"},
+		{keyPrefix: "dcp:", expected: "dcp:group:checkpoint:5"},
+	}
+
+	for _, tt := range tests {
+		m, _ := newTestMetadata(tt.keyPrefix)
+
+		if key := m.getCheckpointKey(5); key != tt.expected {
+			t.Errorf("expected key %s, got %s", tt.expected, key)
+		}
+	}
+}
+
+func TestRedisMetadata_SaveLoad(t *testing.T) {
+	m, client := newTestMetadata(helpers.Prefix)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.hashes) != 1 {
+		t.Fatalf("only dirty offsets must be saved, got %v", client.hashes)
+	}
+
+	state, exist, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoint must be loaded, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, newTestCheckpoint(10)) {
+		t.Errorf("saved checkpoint must be loaded, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("vBucket without a checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestRedisMetadata_LoadCorruptedCheckpoint(t *testing.T) {
+	m, client := newTestMetadata(helpers.Prefix)
+	client.hashes[m.getCheckpointKey(0)] = map[string]string{seqNoField: "invalid"}
+
+	state, exist, err := m.Load([]uint16{0}, "bucket")
+	if err != nil || exist {
+		t.Fatalf("corrupted checkpoint must not exist, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("corrupted checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestRedisMetadata_Clear(t *testing.T) {
+	m, client := newTestMetadata(helpers.Prefix)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true, 1: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Clear([]uint16{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := client.hashes[m.getCheckpointKey(0)]; ok {
+		t.Errorf("cleared checkpoint must be deleted")
+	}
+
+	if _, ok := client.hashes[m.getCheckpointKey(1)]; !ok {
+		t.Errorf("other checkpoints must be kept")
+	}
+}
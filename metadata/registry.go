@@ -0,0 +1,70 @@
+package metadata
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+// MetadataFactory creates the metadata of a registered type from the config.
+type MetadataFactory func(config *config.Dcp) (Metadata, error)
+
+var (
+	factories     = map[string]MetadataFactory{}
+	factoriesLock sync.RWMutex
+)
+
+var ErrMetadataTypeNotRegistered = errors.New("metadata type is not registered")
+
+// Register makes a metadata type selectable with metadata.type config. It is meant to be called from init functions
+// and panics when the name is registered already or the factory is nil. The couchbase type cannot be replaced.
+func Register(name string, factory MetadataFactory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	if factory == nil {
+		panic("metadata: register factory is nil for " + name)
+	}
+
+	if _, ok := factories[name]; ok || name == config.MetadataTypeCouchbase {
+		panic("metadata: register called twice for " + name)
+	}
+
+	factories[name] = factory
+}
+
+// New creates the metadata of the registered type.
+func New(name string, config *config.Dcp) (Metadata, error) {
+	factoriesLock.RLock()
+	factory, ok := factories[name]
+	factoriesLock.RUnlock()
+
+	if !ok {
+		return nil, ErrMetadataTypeNotRegistered
+	}
+
+	return factory(config)
+}
+
+// Types returns the names of the registered metadata types.
+func Types() []string {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func init() {
+	Register(config.MetadataTypeFile, func(config *config.Dcp) (Metadata, error) {
+		return NewFSMetadata(config), nil
+	})
+}
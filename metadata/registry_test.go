@@ -0,0 +1,35 @@
+package metadata
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+func TestRegister(t *testing.T) {
+	Register("test", func(_ *config.Dcp) (Metadata, error) {
+		return NewReadMetadata(nil), nil
+	})
+
+	m, err := New("test", &config.Dcp{})
+	if err != nil || m == nil {
+		t.Fatalf("registered metadata must be created, err: %v", err)
+	}
+
+	if _, err = New("unknown", &config.Dcp{}); !errors.Is(err, ErrMetadataTypeNotRegistered) {
+		t.Errorf("unknown metadata type must not be created, err: %v", err)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a type twice must panic")
+		}
+	}()
+
+	Register(config.MetadataTypeFile, func(_ *config.Dcp) (Metadata, error) {
+		return nil, nil
+	})
+}
@@ -0,0 +1,281 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const (
+	EndpointConfig         = "endpoint"
+	AccessKeyConfig        = "accessKey"
+	SecretKeyConfig        = "secretKey"
+	BucketConfig           = "bucket"
+	RegionConfig           = "region"
+	KeyPrefixConfig        = "keyPrefix"
+	SecureConnectionConfig = "secureConnection"
+	MaxRetriesConfig       = "maxRetries"
+)
+
+const (
+	defaultKeyPrefix  = "go-dcp/"
+	defaultMaxRetries = 5
+)
+
+var errConflict = errors.New("offsets document is changed by an other member")
+
+// offsetsDocument is the compacted checkpoint of all vBuckets of a group.
+type offsetsDocument struct {
+	VBuckets map[uint16]*models.CheckpointDocument `json:"vBuckets"`
+}
+
+// s3Metadata keeps the checkpoints of a group in a single object. Members of the group update it with
+// conditional writes on its ETag, and retry with the latest version when an other member wrote it first.
+type s3Metadata struct {
+	client     *minio.Client
+	config     *config.Dcp
+	codec      *metadata.Codec
+	document   *offsetsDocument
+	bucket     string
+	key        string
+	etag       string
+	maxRetries int
+	lock       sync.Mutex
+}
+
+func (s *s3Metadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	dirty := map[uint16]*models.CheckpointDocument{}
+	for vbID, doc := range state {
+		if dirtyOffsets[vbID] {
+			dirty[vbID] = doc
+		}
+	}
+
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	return s.update(func(document *offsetsDocument) {
+		for vbID, doc := range dirty {
+			document.VBuckets[vbID] = doc
+		}
+	})
+}
+
+func (s *s3Metadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	if err := s.fetch(ctx); err != nil {
+		return nil, false, err
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+	exist := false
+
+	for _, vbID := range vbIds {
+		doc, ok := s.document.VBuckets[vbID]
+		if !ok || doc == nil || doc.Checkpoint == nil {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		state.Store(vbID, doc)
+		exist = true
+	}
+
+	return state, exist, nil
+}
+
+func (s *s3Metadata) Clear(vbIds []uint16) error {
+	return s.update(func(document *offsetsDocument) {
+		for _, vbID := range vbIds {
+			delete(document.VBuckets, vbID)
+		}
+	})
+}
+
+// update applies the change to the cached document and writes it, a conflicting write of an other member
+// fetches the latest document and applies the change again.
+func (s *s3Metadata) update(change func(document *offsetsDocument)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	if s.document == nil {
+		if err := s.fetch(ctx); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		change(s.document)
+
+		err := s.put(ctx)
+		if !errors.Is(err, errConflict) {
+			return err
+		}
+
+		if attempt == s.maxRetries {
+			return err
+		}
+
+		logger.Log.Debug("offsets document conflict, key: %v, attempt: %d", s.key, attempt+1)
+
+		if err := s.fetch(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *s3Metadata) fetch(ctx context.Context) error {
+	object, err := s.client.GetObject(ctx, s.bucket, s.key, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer object.Close()
+
+	info, err := object.Stat()
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			s.document, s.etag = &offsetsDocument{VBuckets: map[uint16]*models.CheckpointDocument{}}, ""
+			return nil
+		}
+		return err
+	}
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return err
+	}
+
+	data, err = s.codec.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	document := &offsetsDocument{}
+	if err = sonic.Unmarshal(data, document); err != nil {
+		return err
+	}
+
+	if document.VBuckets == nil {
+		document.VBuckets = map[uint16]*models.CheckpointDocument{}
+	}
+
+	s.document, s.etag = document, info.ETag
+
+	return nil
+}
+
+func (s *s3Metadata) put(ctx context.Context) error {
+	data, err := sonic.Marshal(s.document)
+	if err == nil {
+		data, err = s.codec.Encode(data)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	options := minio.PutObjectOptions{ContentType: "application/json"}
+	if s.etag == "" {
+		options.SetMatchETagExcept("*")
+	} else {
+		options.SetMatchETag(s.etag)
+	}
+
+	info, err := s.client.PutObject(ctx, s.bucket, s.key, bytes.NewReader(data), int64(len(data)), options)
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusPreconditionFailed {
+			return errConflict
+		}
+		return err
+	}
+
+	s.etag = info.ETag
+
+	return nil
+}
+
+// NewMetadata creates the metadata with endpoint, accessKey, secretKey, bucket, region, keyPrefix, secureConnection
+// and maxRetries keys of metadata.config. The object of a group is keyPrefix + groupName + "/offsets.json".
+func NewMetadata(config *config.Dcp) (metadata.Metadata, error) {
+	metadataConfig := config.Metadata.Config
+
+	endpoint, bucket := metadataConfig[EndpointConfig], metadataConfig[BucketConfig]
+	if endpoint == "" || bucket == "" {
+		return nil, errors.New("s3 metadata endpoint or bucket is not set")
+	}
+
+	secure := true
+	if v, ok := metadataConfig[SecureConnectionConfig]; ok {
+		var err error
+		if secure, err = strconv.ParseBool(v); err != nil {
+			return nil, err
+		}
+	}
+
+	maxRetries := defaultMaxRetries
+	if v, ok := metadataConfig[MaxRetriesConfig]; ok {
+		var err error
+		if maxRetries, err = strconv.Atoi(v); err != nil {
+			return nil, err
+		}
+	}
+
+	keyPrefix := defaultKeyPrefix
+	if v, ok := metadataConfig[KeyPrefixConfig]; ok {
+		keyPrefix = v
+	}
+
+	codec, err := metadata.NewCodec(config.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(metadataConfig[AccessKeyConfig], metadataConfig[SecretKeyConfig], ""),
+		Secure: secure,
+		Region: metadataConfig[RegionConfig],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Metadata{
+		client:     client,
+		config:     config,
+		codec:      codec,
+		bucket:     bucket,
+		key:        keyPrefix + config.Dcp.Group.Name + "/offsets.json",
+		maxRetries: maxRetries,
+	}, nil
+}
+
+func init() {
+	metadata.Register(config.MetadataTypeS3, NewMetadata)
+}
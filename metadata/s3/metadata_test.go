@@ -0,0 +1,257 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// fakeObjectStore serves a single object with the conditional writes of s3.
+type fakeObjectStore struct {
+	data    []byte
+	version int
+	puts    int
+	lock    sync.Mutex
+}
+
+func (s *fakeObjectStore) etag() string {
+	return `"v` + strconv.Itoa(s.version) + `"`
+}
+
+func (s *fakeObjectStore) write(data []byte) {
+	s.data = data
+	s.version++
+}
+
+func (s *fakeObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if s.data == nil {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		w.Header().Set("ETag", s.etag())
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(s.data)
+		}
+	case http.MethodPut:
+		s.puts++
+
+		ifNoneMatch, ifMatch := r.Header.Get("If-None-Match"), r.Header.Get("If-Match")
+		if (ifNoneMatch != "" && s.data != nil) || (ifMatch != "" && ifMatch != s.etag()) {
+			writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		s.write(data)
+
+		w.Header().Set("ETag", s.etag())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("<Error><Code>" + code + "</Code></Error>"))
+}
+
+func newTestMetadata(t *testing.T) (*s3Metadata, *fakeObjectStore) {
+	t.Helper()
+
+	logger.InitDefaultLogger("error")
+
+	store := &fakeObjectStore{}
+	// payloads are signed in chunks over plain http, tls keeps the bodies as they are
+	server := httptest.NewTLSServer(store)
+	t.Cleanup(server.Close)
+
+	client, err := minio.New(strings.TrimPrefix(server.URL, "https://"), &minio.Options{
+		Creds:     credentials.NewStaticV4("access", "secret", ""),
+		Region:    "us-east-1",
+		Secure:    true,
+		Transport: server.Client().Transport,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &config.Dcp{Checkpoint: config.Checkpoint{Timeout: time.Second}}
+	c.Dcp.Group.Name = "group"
+
+	codec, err := metadata.NewCodec(c.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &s3Metadata{
+		client:     client,
+		config:     c,
+		codec:      codec,
+		bucket:     "bucket",
+		key:        defaultKeyPrefix + "group/offsets.json",
+		maxRetries: 2,
+	}, store
+}
+
+func newTestCheckpoint(seqNo uint64) *models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.VbUUID = 7
+	doc.Checkpoint.SeqNo = seqNo
+	doc.Checkpoint.Snapshot.StartSeqNo = seqNo
+	doc.Checkpoint.Snapshot.EndSeqNo = seqNo + 1
+
+	return doc
+}
+
+func TestS3Metadata_SaveLoad(t *testing.T) {
+	m, _ := newTestMetadata(t)
+
+	for _, seqNo := range []uint64{10, 11} {
+		err := m.Save(map[uint16]*models.CheckpointDocument{
+			0: newTestCheckpoint(seqNo),
+			1: newTestCheckpoint(20),
+		}, map[uint16]bool{0: true}, "bucket")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state, exist, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoint must be loaded, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, newTestCheckpoint(11)) {
+		t.Errorf("last saved checkpoint must be loaded, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("vBucket without a checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestS3Metadata_LoadWithoutObject(t *testing.T) {
+	m, _ := newTestMetadata(t)
+
+	state, exist, err := m.Load([]uint16{0}, "bucket")
+	if err != nil || exist {
+		t.Fatalf("missing object must not exist, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("vBucket must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestS3Metadata_SaveMergesConflictingWrite(t *testing.T) {
+	m, store := newTestMetadata(t)
+	other, _ := newTestMetadata(t)
+	other.client = m.client
+
+	if err := m.Save(map[uint16]*models.CheckpointDocument{0: newTestCheckpoint(10)}, map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	// an other member writes its vBucket after the document is cached
+	if err := other.Save(map[uint16]*models.CheckpointDocument{1: newTestCheckpoint(20)}, map[uint16]bool{1: true}, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Save(map[uint16]*models.CheckpointDocument{0: newTestCheckpoint(11)}, map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.puts != 4 {
+		t.Fatalf("conflicting write must be retried once, got %d puts", store.puts)
+	}
+
+	state, _, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, newTestCheckpoint(11)) {
+		t.Errorf("retried checkpoint must be saved, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, newTestCheckpoint(20)) {
+		t.Errorf("checkpoint of the other member must be kept, got %+v", doc)
+	}
+}
+
+func TestS3Metadata_SaveGivesUpAfterMaxRetries(t *testing.T) {
+	m, store := newTestMetadata(t)
+
+	if err := m.Save(map[uint16]*models.CheckpointDocument{0: newTestCheckpoint(10)}, map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+
+	m.etag = "stale"
+	m.maxRetries = 0
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{0: newTestCheckpoint(11)}, map[uint16]bool{0: true}, "bucket")
+	if err != errConflict {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+
+	if store.puts != 2 {
+		t.Fatalf("write must not be retried, got %d puts", store.puts)
+	}
+}
+
+func TestS3Metadata_Clear(t *testing.T) {
+	m, _ := newTestMetadata(t)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true, 1: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Clear([]uint16{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, _, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("cleared checkpoint must be loaded empty, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, newTestCheckpoint(20)) {
+		t.Errorf("other checkpoints must be kept, got %+v", doc)
+	}
+}
@@ -0,0 +1,128 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+const (
+	SnapshotFormatJSON = "json"
+	SnapshotFormatCBOR = "cbor"
+)
+
+const snapshotVersion = 1
+
+var (
+	ErrSnapshotBucketMismatch = errors.New("snapshot is taken from another bucket")
+	ErrMetadataReadOnly       = errors.New("metadata is read only")
+)
+
+// Snapshot is a portable copy of the checkpoints of all vBuckets of a group. It is independent of the metadata type,
+// so it can be imported into another metadata to migrate between types.
+type Snapshot struct {
+	CreatedAt   time.Time                             `json:"createdAt"`
+	Checkpoints map[uint16]*models.CheckpointDocument `json:"checkpoints"`
+	GroupName   string                                `json:"groupName"`
+	BucketUUID  string                                `json:"bucketUuid"`
+	Version     int                                   `json:"version"`
+}
+
+// Export writes the checkpoints of the vBuckets in the metadata to w as a json or cbor snapshot.
+func Export(w io.Writer, format string, metadata Metadata, groupName string, vbIds []uint16, bucketUUID string) error {
+	state, _, err := metadata.Load(vbIds, bucketUUID)
+	if err != nil {
+		return err
+	}
+
+	snapshot := Snapshot{
+		Version:     snapshotVersion,
+		CreatedAt:   time.Now(),
+		GroupName:   groupName,
+		BucketUUID:  bucketUUID,
+		Checkpoints: state.ToMap(),
+	}
+
+	var data []byte
+
+	switch format {
+	case SnapshotFormatJSON:
+		data, err = sonic.Marshal(snapshot)
+	case SnapshotFormatCBOR:
+		data, err = cbor.Marshal(snapshot)
+	default:
+		err = fmt.Errorf("unknown snapshot format: %s", format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// Import reads a json or cbor snapshot from r and saves its checkpoints to the metadata. Snapshots of another bucket
+// are rejected, their vbUUIDs and seqNos do not belong to the bucket.
+func Import(r io.Reader, metadata Metadata, bucketUUID string) (*Snapshot, error) {
+	if _, ok := metadata.(*readMetadata); ok {
+		return nil, ErrMetadataReadOnly
+	}
+
+	snapshot, err := ReadSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.BucketUUID != bucketUUID {
+		return nil, fmt.Errorf("%w, snapshot: %s, bucket: %s", ErrSnapshotBucketMismatch, snapshot.BucketUUID, bucketUUID)
+	}
+
+	dirtyOffsets := make(map[uint16]bool, len(snapshot.Checkpoints))
+	for vbID, doc := range snapshot.Checkpoints {
+		if doc == nil || doc.Checkpoint == nil {
+			return nil, fmt.Errorf("snapshot checkpoint of vbID: %d is empty", vbID)
+		}
+
+		dirtyOffsets[vbID] = true
+	}
+
+	if err = metadata.Save(snapshot.Checkpoints, dirtyOffsets, bucketUUID); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// ReadSnapshot reads a json or cbor snapshot from r without importing it.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = sonic.Unmarshal(data, &snapshot)
+	} else {
+		err = cbor.Unmarshal(data, &snapshot)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snapshot: %w", err)
+	}
+
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version: %d", snapshot.Version)
+	}
+
+	return &snapshot, nil
+}
@@ -0,0 +1,57 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSnapshotExportImport(t *testing.T) {
+	for _, format := range []string{SnapshotFormatJSON, SnapshotFormatCBOR} {
+		source, _ := newTestFileMetadata(t)
+		target, _ := newTestFileMetadata(t)
+
+		_ = source.Save(newTestCheckpoint(5), nil, "bucket")
+
+		var buf bytes.Buffer
+		if err := Export(&buf, format, source, "group", []uint16{0}, "bucket"); err != nil {
+			t.Fatalf("%s snapshot must be exported, err: %v", format, err)
+		}
+
+		snapshot, err := Import(&buf, target, "bucket")
+		if err != nil {
+			t.Fatalf("%s snapshot must be imported, err: %v", format, err)
+		}
+
+		if snapshot.GroupName != "group" {
+			t.Errorf("%s snapshot group name is not set to expected value", format)
+		}
+
+		if seqNo := loadSeqNo(t, target); seqNo != 5 {
+			t.Errorf("%s snapshot checkpoint must be imported, seqNo: %v", format, seqNo)
+		}
+	}
+}
+
+func TestSnapshotImportRejected(t *testing.T) {
+	source, _ := newTestFileMetadata(t)
+	_ = source.Save(newTestCheckpoint(5), nil, "bucket")
+
+	var buf bytes.Buffer
+	_ = Export(&buf, SnapshotFormatJSON, source, "group", []uint16{0}, "bucket")
+	data := buf.Bytes()
+
+	target, _ := newTestFileMetadata(t)
+
+	if _, err := Import(bytes.NewReader(data), target, "another-bucket"); !errors.Is(err, ErrSnapshotBucketMismatch) {
+		t.Errorf("snapshot of another bucket must be rejected, err: %v", err)
+	}
+
+	if _, err := Import(bytes.NewReader(data), NewReadMetadata(target), "bucket"); !errors.Is(err, ErrMetadataReadOnly) {
+		t.Errorf("snapshot must not be imported to read only metadata, err: %v", err)
+	}
+
+	if err := Export(&buf, "xml", source, "group", []uint16{0}, "bucket"); err == nil {
+		t.Errorf("unknown snapshot format must be rejected")
+	}
+}
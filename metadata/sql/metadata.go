@@ -0,0 +1,365 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const (
+	DriverConfig      = "driver"
+	DSNConfig         = "dsn"
+	DialectConfig     = "dialect"
+	TableConfig       = "table"
+	AutoMigrateConfig = "autoMigrate"
+	BatchSizeConfig   = "batchSize"
+)
+
+const (
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+)
+
+const (
+	defaultTable     = "go_dcp_checkpoint"
+	defaultBatchSize = 256
+	columnCount      = 10
+)
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+type dialect struct {
+	placeholder func(i int) string
+	createTable string
+	upsert      string
+}
+
+var dialects = map[string]dialect{
+	DialectPostgres: {
+		placeholder: func(i int) string { return "$" + strconv.Itoa(i) },
+		createTable: `CREATE TABLE IF NOT EXISTS %s (
+	group_name VARCHAR(255) NOT NULL,
+	vb_id INTEGER NOT NULL,
+	vb_uuid NUMERIC(20) NOT NULL,
+	seq_no NUMERIC(20) NOT NULL,
+	start_seq_no NUMERIC(20) NOT NULL,
+	end_seq_no NUMERIC(20) NOT NULL,
+	bucket_uuid VARCHAR(64) NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	processed_time BIGINT NOT NULL DEFAULT 0,
+	lib_version VARCHAR(64) NOT NULL DEFAULT '',
+	PRIMARY KEY (group_name, vb_id)
+)`,
+		upsert: ` ON CONFLICT (group_name, vb_id) DO UPDATE SET vb_uuid = EXCLUDED.vb_uuid, seq_no = EXCLUDED.seq_no, ` +
+			`start_seq_no = EXCLUDED.start_seq_no, end_seq_no = EXCLUDED.end_seq_no, bucket_uuid = EXCLUDED.bucket_uuid, ` +
+			`updated_at = EXCLUDED.updated_at, processed_time = EXCLUDED.processed_time, lib_version = EXCLUDED.lib_version`,
+	},
+	DialectMySQL: {
+		placeholder: func(int) string { return "?" },
+		createTable: `CREATE TABLE IF NOT EXISTS %s (
+	group_name VARCHAR(255) NOT NULL,
+	vb_id INT NOT NULL,
+	vb_uuid BIGINT UNSIGNED NOT NULL,
+	seq_no BIGINT UNSIGNED NOT NULL,
+	start_seq_no BIGINT UNSIGNED NOT NULL,
+	end_seq_no BIGINT UNSIGNED NOT NULL,
+	bucket_uuid VARCHAR(64) NOT NULL,
+	updated_at DATETIME(3) NOT NULL,
+	processed_time BIGINT NOT NULL DEFAULT 0,
+	lib_version VARCHAR(64) NOT NULL DEFAULT '',
+	PRIMARY KEY (group_name, vb_id)
+)`,
+		upsert: ` ON DUPLICATE KEY UPDATE vb_uuid = VALUES(vb_uuid), seq_no = VALUES(seq_no), ` +
+			`start_seq_no = VALUES(start_seq_no), end_seq_no = VALUES(end_seq_no), bucket_uuid = VALUES(bucket_uuid), ` +
+			`updated_at = VALUES(updated_at), processed_time = VALUES(processed_time), lib_version = VALUES(lib_version)`,
+	},
+}
+
+// sqlMetadata keeps the checkpoint of each vBucket in a row keyed by group name and vBucket id.
+// A save upserts the dirty vBuckets in batches of multi row inserts within a transaction.
+type sqlMetadata struct {
+	db        *sql.DB
+	config    *config.Dcp
+	dialect   dialect
+	table     string
+	batchSize int
+}
+
+func (s *sqlMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	updatedAt := time.Now().UTC()
+	args := make([]any, 0, s.batchSize*columnCount)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for vbID, doc := range state {
+		if !dirtyOffsets[vbID] {
+			continue
+		}
+
+		// uint64 values with the high bit set are not supported by database/sql, they are passed as text
+		args = append(args,
+			s.config.Dcp.Group.Name,
+			int(vbID),
+			strconv.FormatUint(doc.Checkpoint.VbUUID, 10),
+			strconv.FormatUint(doc.Checkpoint.SeqNo, 10),
+			strconv.FormatUint(doc.Checkpoint.Snapshot.StartSeqNo, 10),
+			strconv.FormatUint(doc.Checkpoint.Snapshot.EndSeqNo, 10),
+			doc.BucketUUID,
+			updatedAt,
+			doc.ProcessedTime,
+			doc.Version,
+		)
+
+		if len(args) == s.batchSize*columnCount {
+			if _, err := tx.ExecContext(ctx, s.upsertQuery(len(args)/columnCount), args...); err != nil {
+				return err
+			}
+			args = args[:0]
+		}
+	}
+
+	if len(args) > 0 {
+		if _, err := tx.ExecContext(ctx, s.upsertQuery(len(args)/columnCount), args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT vb_id, vb_uuid, seq_no, start_seq_no, end_seq_no, bucket_uuid, processed_time, lib_version FROM "+s.table+
+			" WHERE group_name = "+s.dialect.placeholder(1),
+		s.config.Dcp.Group.Name,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer rows.Close()
+
+	docs := map[uint16]*models.CheckpointDocument{}
+
+	for rows.Next() {
+		var vbID uint16
+		doc := models.NewEmptyCheckpointDocument("")
+
+		if err := rows.Scan(
+			&vbID,
+			&doc.Checkpoint.VbUUID,
+			&doc.Checkpoint.SeqNo,
+			&doc.Checkpoint.Snapshot.StartSeqNo,
+			&doc.Checkpoint.Snapshot.EndSeqNo,
+			&doc.BucketUUID,
+			&doc.ProcessedTime,
+			&doc.Version,
+		); err != nil {
+			return nil, false, err
+		}
+
+		docs[vbID] = doc
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+	exist := false
+
+	for _, vbID := range vbIds {
+		if doc, ok := docs[vbID]; ok {
+			state.Store(vbID, doc)
+			exist = true
+		} else {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+		}
+	}
+
+	return state, exist, nil
+}
+
+func (s *sqlMetadata) Clear(vbIds []uint16) error {
+	if len(vbIds) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	placeholders := make([]string, len(vbIds))
+	args := make([]any, 0, len(vbIds)+1)
+	args = append(args, s.config.Dcp.Group.Name)
+
+	for i, vbID := range vbIds {
+		placeholders[i] = s.dialect.placeholder(i + 2)
+		args = append(args, int(vbID))
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM "+s.table+" WHERE group_name = "+s.dialect.placeholder(1)+
+			" AND vb_id IN ("+strings.Join(placeholders, ", ")+")",
+		args...,
+	)
+
+	return err
+}
+
+func (s *sqlMetadata) upsertQuery(rows int) string {
+	var query strings.Builder
+
+	query.WriteString("INSERT INTO " + s.table +
+		" (group_name, vb_id, vb_uuid, seq_no, start_seq_no, end_seq_no, bucket_uuid, updated_at, processed_time, lib_version) VALUES ")
+
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			query.WriteString(", ")
+		}
+
+		query.WriteString("(")
+		for column := 0; column < columnCount; column++ {
+			if column > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString(s.dialect.placeholder(row*columnCount + column + 1))
+		}
+		query.WriteString(")")
+	}
+
+	query.WriteString(s.dialect.upsert)
+
+	return query.String()
+}
+
+func (s *sqlMetadata) migrate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Checkpoint.Timeout)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(s.dialect.createTable, s.table)); err != nil {
+		return err
+	}
+
+	// tables created by older versions have no processed time and version columns
+	if _, err := s.db.ExecContext(ctx, "SELECT processed_time FROM "+s.table+" WHERE 1 = 0"); err == nil {
+		return nil
+	}
+
+	_, err := s.db.ExecContext(ctx, "ALTER TABLE "+s.table+
+		" ADD COLUMN processed_time BIGINT NOT NULL DEFAULT 0, ADD COLUMN lib_version VARCHAR(64) NOT NULL DEFAULT ''")
+
+	return err
+}
+
+func resolveDialect(metadataConfig map[string]string) (dialect, error) {
+	name := metadataConfig[DialectConfig]
+	if name == "" {
+		switch driver := metadataConfig[DriverConfig]; {
+		case strings.Contains(driver, "mysql"):
+			name = DialectMySQL
+		case driver == "pgx" || strings.Contains(driver, "postgres"):
+			name = DialectPostgres
+		}
+	}
+
+	d, ok := dialects[name]
+	if !ok {
+		return dialect{}, fmt.Errorf("sql metadata dialect is not supported: %q", name)
+	}
+
+	return d, nil
+}
+
+// NewMetadata creates the metadata with driver, dsn, dialect, table, autoMigrate and batchSize keys of metadata.config.
+// The database/sql driver is not imported by this package, it has to be registered by the application.
+func NewMetadata(config *config.Dcp) (metadata.Metadata, error) {
+	// checkpoints are stored as fields to be queried, so they can not be compressed or encrypted as a whole
+	if metadata.IsCodecEnabled(config.Metadata) {
+		return nil, metadata.ErrCodecNotSupported
+	}
+
+	metadataConfig := config.Metadata.Config
+
+	driver, dsn := metadataConfig[DriverConfig], metadataConfig[DSNConfig]
+	if driver == "" || dsn == "" {
+		return nil, errors.New("sql metadata driver or dsn is not set")
+	}
+
+	d, err := resolveDialect(metadataConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sqlMetadata{
+		config:    config,
+		dialect:   d,
+		table:     defaultTable,
+		batchSize: defaultBatchSize,
+	}
+
+	if table, ok := metadataConfig[TableConfig]; ok {
+		if !tableNamePattern.MatchString(table) {
+			return nil, fmt.Errorf("sql metadata table name is not valid: %q", table)
+		}
+		s.table = table
+	}
+
+	if batchSize, ok := metadataConfig[BatchSizeConfig]; ok {
+		if s.batchSize, err = strconv.Atoi(batchSize); err != nil {
+			return nil, err
+		}
+		if s.batchSize <= 0 {
+			return nil, errors.New("sql metadata batchSize must be positive")
+		}
+	}
+
+	if s.db, err = sql.Open(driver, dsn); err != nil {
+		return nil, err
+	}
+
+	autoMigrate := true
+	if v, ok := metadataConfig[AutoMigrateConfig]; ok {
+		if autoMigrate, err = strconv.ParseBool(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if autoMigrate {
+		if err := s.migrate(); err != nil {
+			_ = s.db.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func init() {
+	metadata.Register(config.MetadataTypeSQL, NewMetadata)
+}
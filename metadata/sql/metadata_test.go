@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSqlMetadata_UpsertQuery(t *testing.T) {
+	tests := []struct {
+		dialect  string
+		expected string
+	}{
+		{
+			dialect: DialectPostgres,
+			expected: "INSERT INTO checkpoint (group_name, vb_id, vb_uuid, seq_no, start_seq_no, end_seq_no, bucket_uuid, updated_at, " +
+				"processed_time, lib_version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10), " +
+				"($11, $12, $13, $14, $15, $16, $17, $18, $19, $20)" + dialects[DialectPostgres].upsert,
+		},
+		{
+			dialect: DialectMySQL,
+			expected: "INSERT INTO checkpoint (group_name, vb_id, vb_uuid, seq_no, start_seq_no, end_seq_no, bucket_uuid, updated_at, " +
+				"processed_time, lib_version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?), " +
+				"(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)" + dialects[DialectMySQL].upsert,
+		},
+	}
+
+	for _, tt := range tests {
+		s := &sqlMetadata{dialect: dialects[tt.dialect], table: "checkpoint"}
+
+		if query := s.upsertQuery(2); query != tt.expected {
+			t.Errorf("unexpected %s query:\n%s\nexpected:\n%s", tt.dialect, query, tt.expected)
+		}
+	}
+}
+
+func TestResolveDialect(t *testing.T) {
+	tests := []struct {
+		config      map[string]string
+		name        string
+		placeholder string
+		err         bool
+	}{
+		{name: "postgres dialect", config: map[string]string{DialectConfig: DialectPostgres}, placeholder: "$3"},
+		{name: "mysql dialect", config: map[string]string{DialectConfig: DialectMySQL}, placeholder: "?"},
+		{name: "pgx driver", config: map[string]string{DriverConfig: "pgx"}, placeholder: "$3"},
+		{name: "postgres driver", config: map[string]string{DriverConfig: "postgres"}, placeholder: "$3"},
+		{name: "mysql driver", config: map[string]string{DriverConfig: "mysql"}, placeholder: "?"},
+		{name: "dialect over driver", config: map[string]string{DriverConfig: "pgx", DialectConfig: DialectMySQL}, placeholder: "?"},
+		{name: "unknown driver", config: map[string]string{DriverConfig: "sqlite3"}, err: true},
+		{name: "unknown dialect", config: map[string]string{DialectConfig: "oracle"}, err: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := resolveDialect(tt.config)
+			if tt.err {
+				if err == nil || !strings.Contains(err.Error(), "not supported") {
+					t.Fatalf("expected unsupported dialect error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if placeholder := d.placeholder(3); placeholder != tt.placeholder {
+				t.Errorf("expected placeholder %s, got %s", tt.placeholder, placeholder)
+			}
+		})
+	}
+}
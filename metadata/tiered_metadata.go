@@ -0,0 +1,189 @@
+package metadata
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+// TieredMetadata writes the checkpoints to the fast metadata with every save and to the durable metadata once in the
+// durable interval, so the redelivery window is bounded by the checkpoint interval while the durable metadata is
+// written rarely. The durable metadata is written with every save while the fast one fails, and its vBuckets which
+// changed since its last write stay pending while it fails. Checkpoints are loaded from the fresher tier.
+type TieredMetadata struct {
+	lastDurableSave time.Time
+	lastBucketUUID  string
+	fast            Metadata
+	durable         Metadata
+	pending         map[uint16]bool
+	lastState       map[uint16]*models.CheckpointDocument
+	now             func() time.Time
+	durableInterval time.Duration
+	failures        atomic.Int64
+	lock            sync.Mutex
+}
+
+func (s *TieredMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, bucketUUID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for vbID, dirty := range dirtyOffsets {
+		if dirty {
+			s.pending[vbID] = true
+		}
+	}
+
+	s.lastState, s.lastBucketUUID = state, bucketUUID
+
+	fastErr := s.fast.Save(state, dirtyOffsets, bucketUUID)
+	if fastErr != nil {
+		s.failures.Add(1)
+		logger.Log.Warn("error while saving checkpoint to fast metadata, saving to durable metadata, err: %v", fastErr)
+	}
+
+	if fastErr == nil && s.now().Sub(s.lastDurableSave) < s.durableInterval {
+		return nil
+	}
+
+	if err := s.saveDurable(state, bucketUUID); err != nil {
+		if fastErr != nil {
+			return err
+		}
+
+		s.failures.Add(1)
+		logger.Log.Warn("error while saving checkpoint to durable metadata, err: %v", err)
+	}
+
+	return nil
+}
+
+func (s *TieredMetadata) saveDurable(state map[uint16]*models.CheckpointDocument, bucketUUID string) error {
+	durableDirtyOffsets := make(map[uint16]bool, len(state))
+	for vbID := range state {
+		durableDirtyOffsets[vbID] = s.pending[vbID]
+	}
+
+	if err := s.durable.Save(state, durableDirtyOffsets, bucketUUID); err != nil {
+		return err
+	}
+
+	for vbID := range state {
+		delete(s.pending, vbID)
+	}
+
+	s.lastDurableSave = s.now()
+
+	return nil
+}
+
+// Flush writes the pending vBuckets of the last saved checkpoint to the durable metadata, e.g. before the member
+// stops.
+func (s *TieredMetadata) Flush() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.pending) == 0 || s.lastState == nil {
+		return nil
+	}
+
+	return s.saveDurable(s.lastState, s.lastBucketUUID)
+}
+
+// Load loads the checkpoints from both tiers and takes the fresher one of each vBucket. The fast checkpoint is
+// taken when it is ahead on the same vBucket uuid, or processed later on another one after a failover.
+func (s *TieredMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	state, exist, err := s.durable.Load(vbIds, bucketUUID)
+	if err != nil {
+		return state, exist, err
+	}
+
+	fastState, fastExist, fastErr := s.fast.Load(vbIds, bucketUUID)
+	if fastErr != nil {
+		s.failures.Add(1)
+		logger.Log.Warn("error while loading checkpoint from fast metadata, err: %v", fastErr)
+		return state, exist, nil
+	}
+
+	if !fastExist {
+		return state, exist, nil
+	}
+
+	for _, vbID := range vbIds {
+		fastDoc, ok := fastState.Load(vbID)
+		if !ok {
+			continue
+		}
+
+		doc, _ := state.Load(vbID)
+		if isFresherCheckpoint(fastDoc, doc) {
+			state.Store(vbID, fastDoc)
+			exist = true
+		}
+	}
+
+	return state, exist, nil
+}
+
+func (s *TieredMetadata) Clear(vbIds []uint16) error {
+	if err := s.fast.Clear(vbIds); err != nil {
+		s.failures.Add(1)
+		logger.Log.Warn("error while clearing fast metadata, err: %v", err)
+	}
+
+	return s.durable.Clear(vbIds)
+}
+
+// Durable returns the metadata of the durable tier.
+func (s *TieredMetadata) Durable() Metadata {
+	return s.durable
+}
+
+// Pending returns the count of the vBuckets which are not written to the durable metadata yet.
+func (s *TieredMetadata) Pending() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.pending)
+}
+
+// Failures returns the count of the failed metadata operations of the tiers which did not fail the checkpoint.
+func (s *TieredMetadata) Failures() int64 {
+	return s.failures.Load()
+}
+
+func isFresherCheckpoint(doc *models.CheckpointDocument, other *models.CheckpointDocument) bool {
+	if doc == nil || doc.Checkpoint == nil {
+		return false
+	}
+
+	if other == nil || other.Checkpoint == nil {
+		return true
+	}
+
+	if doc.BucketUUID != other.BucketUUID {
+		return false
+	}
+
+	if doc.Checkpoint.VbUUID == other.Checkpoint.VbUUID {
+		return doc.Checkpoint.SeqNo > other.Checkpoint.SeqNo
+	}
+
+	return doc.ProcessedTime > other.ProcessedTime
+}
+
+func NewTieredMetadata(fast Metadata, durable Metadata, durableInterval time.Duration) *TieredMetadata {
+	return &TieredMetadata{
+		fast:            fast,
+		durable:         durable,
+		durableInterval: durableInterval,
+		pending:         map[uint16]bool{},
+		now:             time.Now,
+	}
+}
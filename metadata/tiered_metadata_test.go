@@ -0,0 +1,70 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTieredMetadata(t *testing.T) {
+	fastFile, _ := newTestFileMetadata(t)
+	fast := &failingMetadata{Metadata: fastFile}
+	durable, _ := newTestFileMetadata(t)
+
+	now := time.Now()
+	tiered := NewTieredMetadata(fast, durable, time.Minute)
+	tiered.now = func() time.Time { return now }
+
+	if err := tiered.Save(newTestCheckpoint(3), map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatalf("checkpoint must be saved, err: %v", err)
+	}
+
+	if loadSeqNo(t, fastFile) != 3 || loadSeqNo(t, durable) != 3 {
+		t.Fatalf("first checkpoint must be written to both tiers")
+	}
+
+	now = now.Add(time.Second)
+
+	if err := tiered.Save(newTestCheckpoint(5), map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatalf("checkpoint must be saved, err: %v", err)
+	}
+
+	if loadSeqNo(t, fastFile) != 5 || loadSeqNo(t, durable) != 3 || tiered.Pending() != 1 {
+		t.Fatalf("checkpoint must be written to fast tier only within durable interval, pending: %v", tiered.Pending())
+	}
+
+	if loadSeqNo(t, tiered) != 5 {
+		t.Errorf("fresher checkpoint of fast tier must be loaded")
+	}
+
+	fast.fail = true
+
+	if err := tiered.Save(newTestCheckpoint(7), map[uint16]bool{0: true}, "bucket"); err != nil {
+		t.Fatalf("fast tier failure must not fail the checkpoint, err: %v", err)
+	}
+
+	if loadSeqNo(t, durable) != 7 || tiered.Pending() != 0 || tiered.Failures() != 1 {
+		t.Errorf("checkpoint must be written to durable tier when fast tier fails, pending: %v", tiered.Pending())
+	}
+
+	if loadSeqNo(t, tiered) != 7 {
+		t.Errorf("fresher checkpoint of durable tier must be loaded")
+	}
+}
+
+func TestTieredMetadataFlush(t *testing.T) {
+	fast, _ := newTestFileMetadata(t)
+	durable, _ := newTestFileMetadata(t)
+
+	tiered := NewTieredMetadata(fast, durable, time.Hour)
+
+	_ = tiered.Save(newTestCheckpoint(3), map[uint16]bool{0: true}, "bucket")
+	_ = tiered.Save(newTestCheckpoint(5), map[uint16]bool{0: true}, "bucket")
+
+	if err := tiered.Flush(); err != nil {
+		t.Fatalf("pending checkpoint must be flushed, err: %v", err)
+	}
+
+	if loadSeqNo(t, durable) != 5 || tiered.Pending() != 0 {
+		t.Errorf("durable tier must be written with flush, pending: %v", tiered.Pending())
+	}
+}
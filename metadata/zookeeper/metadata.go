@@ -0,0 +1,322 @@
+package zookeeper
+
+import (
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-zookeeper/zk"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const (
+	ServersConfig        = "servers"
+	SessionTimeoutConfig = "sessionTimeout"
+	RootPathConfig       = "rootPath"
+	UsernameConfig       = "username"
+	PasswordConfig       = "password"
+)
+
+const (
+	defaultRootPath = "/go-dcp"
+	loadConcurrency = 32
+)
+
+// zkConn is the part of the zookeeper connection used by the metadata.
+type zkConn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	Children(path string) ([]string, *zk.Stat, error)
+	Multi(ops ...interface{}) ([]zk.MultiResponse, error)
+}
+
+// zookeeperMetadata keeps the checkpoint of each vBucket in a znode under rootPath/groupName/checkpoint.
+// The dirty vBuckets of a save are committed together with a multi operation.
+type zookeeperMetadata struct {
+	conn           zkConn
+	config         *config.Dcp
+	codec          *metadata.Codec
+	existingNodes  map[uint16]bool
+	checkpointPath string
+	acl            []zk.ACL
+	lock           sync.Mutex
+}
+
+func (s *zookeeperMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	docs := map[uint16][]byte{}
+
+	for vbID, doc := range state {
+		if !dirtyOffsets[vbID] {
+			continue
+		}
+
+		value, err := sonic.Marshal(doc)
+		if err == nil {
+			value, err = s.codec.Encode(value)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		docs[vbID] = value
+	}
+
+	if len(docs) == 0 {
+		return nil
+	}
+
+	err := s.commit(docs)
+	if errors.Is(err, zk.ErrNodeExists) || errors.Is(err, zk.ErrNoNode) {
+		// znodes are created or removed by an other member, the multi is retried with the current ones
+		if err = s.refreshExistingNodes(); err != nil {
+			return err
+		}
+
+		err = s.commit(docs)
+	}
+
+	return err
+}
+
+func (s *zookeeperMetadata) commit(docs map[uint16][]byte) error {
+	ops := make([]interface{}, 0, len(docs))
+
+	for vbID, value := range docs {
+		if s.existingNodes[vbID] {
+			ops = append(ops, &zk.SetDataRequest{Path: s.getCheckpointPath(vbID), Data: value, Version: -1})
+		} else {
+			ops = append(ops, &zk.CreateRequest{Path: s.getCheckpointPath(vbID), Data: value, Acl: s.acl})
+		}
+	}
+
+	if _, err := s.conn.Multi(ops...); err != nil {
+		return err
+	}
+
+	for vbID := range docs {
+		s.existingNodes[vbID] = true
+	}
+
+	return nil
+}
+
+func (s *zookeeperMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.refreshExistingNodes(); err != nil {
+		return nil, false, err
+	}
+
+	values := make([][]byte, len(vbIds))
+
+	eg := errgroup.Group{}
+	eg.SetLimit(loadConcurrency)
+
+	for i, vbID := range vbIds {
+		if !s.existingNodes[vbID] {
+			continue
+		}
+
+		i, vbID := i, vbID
+		eg.Go(func() error {
+			value, _, err := s.conn.Get(s.getCheckpointPath(vbID))
+			if errors.Is(err, zk.ErrNoNode) {
+				return nil
+			}
+
+			values[i] = value
+
+			return err
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, false, err
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](s.config.GetMapInitialSize(len(vbIds)))
+	exist := false
+
+	for i, vbID := range vbIds {
+		if values[i] == nil {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		var doc *models.CheckpointDocument
+		value, err := s.codec.Decode(values[i])
+		if err == nil {
+			err = sonic.Unmarshal(value, &doc)
+		}
+
+		if err != nil || doc == nil || doc.Checkpoint == nil {
+			logger.Log.Warn("corrupted checkpoint, vbID: %d, path: %v, err: %v", vbID, s.getCheckpointPath(vbID), err)
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+			continue
+		}
+
+		state.Store(vbID, doc)
+		exist = true
+	}
+
+	return state, exist, nil
+}
+
+func (s *zookeeperMetadata) Clear(vbIds []uint16) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.refreshExistingNodes(); err != nil {
+		return err
+	}
+
+	var ops []interface{}
+
+	for _, vbID := range vbIds {
+		if s.existingNodes[vbID] {
+			ops = append(ops, &zk.DeleteRequest{Path: s.getCheckpointPath(vbID), Version: -1})
+		}
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	if _, err := s.conn.Multi(ops...); err != nil {
+		return err
+	}
+
+	for _, vbID := range vbIds {
+		delete(s.existingNodes, vbID)
+	}
+
+	return nil
+}
+
+func (s *zookeeperMetadata) refreshExistingNodes() error {
+	children, _, err := s.conn.Children(s.checkpointPath)
+	if err != nil {
+		return err
+	}
+
+	s.existingNodes = make(map[uint16]bool, len(children))
+
+	for _, child := range children {
+		vbID, err := strconv.ParseUint(child, 10, 16)
+		if err != nil {
+			continue
+		}
+
+		s.existingNodes[uint16(vbID)] = true
+	}
+
+	return nil
+}
+
+// createParents creates the missing znodes of the checkpoint path.
+func (s *zookeeperMetadata) createParents() error {
+	current := ""
+
+	for _, node := range strings.Split(strings.Trim(s.checkpointPath, "/"), "/") {
+		current += "/" + node
+
+		_, err := s.conn.Create(current, nil, 0, s.acl)
+		if err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *zookeeperMetadata) getCheckpointPath(vbID uint16) string {
+	// /go-dcp/groupName/checkpoint/vbId
+	return s.checkpointPath + "/" + strconv.Itoa(int(vbID))
+}
+
+type zkLogger struct{}
+
+func (zkLogger) Printf(format string, args ...interface{}) {
+	logger.Log.Debug("zookeeper: "+format, args...)
+}
+
+// NewMetadata creates the metadata with servers, sessionTimeout, rootPath, username and password keys
+// of metadata.config. Servers are comma separated, username and password are used for digest auth.
+func NewMetadata(config *config.Dcp) (metadata.Metadata, error) {
+	metadataConfig := config.Metadata.Config
+
+	servers, ok := metadataConfig[ServersConfig]
+	if !ok || servers == "" {
+		return nil, errors.New("zookeeper metadata servers is not set")
+	}
+
+	sessionTimeout := 10 * time.Second
+	if v, ok := metadataConfig[SessionTimeoutConfig]; ok {
+		var err error
+		if sessionTimeout, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+	}
+
+	rootPath := defaultRootPath
+	if v, ok := metadataConfig[RootPathConfig]; ok {
+		rootPath = v
+	}
+
+	codec, err := metadata.NewCodec(config.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, _, err := zk.Connect(strings.Split(servers, ","), sessionTimeout, zk.WithLogger(zkLogger{}))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &zookeeperMetadata{
+		conn:           conn,
+		config:         config,
+		codec:          codec,
+		existingNodes:  map[uint16]bool{},
+		checkpointPath: path.Join("/", rootPath, config.Dcp.Group.Name, "checkpoint"),
+		acl:            zk.WorldACL(zk.PermAll),
+	}
+
+	if username := metadataConfig[UsernameConfig]; username != "" {
+		password := metadataConfig[PasswordConfig]
+		if err := conn.AddAuth("digest", []byte(username+":"+password)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		s.acl = zk.DigestACL(zk.PermAll, username, password)
+	}
+
+	if err := s.createParents(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func init() {
+	metadata.Register(config.MetadataTypeZookeeper, NewMetadata)
+}
@@ -0,0 +1,236 @@
+package zookeeper
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// fakeConn keeps the znodes by path, a multi fails without changes when one of its requests fails.
+type fakeConn struct {
+	nodes map[string][]byte
+	lock  sync.Mutex
+}
+
+func (c *fakeConn) Create(path string, data []byte, _ int32, _ []zk.ACL) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.nodes[path]; ok {
+		return "", zk.ErrNodeExists
+	}
+
+	c.nodes[path] = data
+
+	return path, nil
+}
+
+func (c *fakeConn) Get(path string) ([]byte, *zk.Stat, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	data, ok := c.nodes[path]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+
+	return data, &zk.Stat{}, nil
+}
+
+func (c *fakeConn) Children(path string) ([]string, *zk.Stat, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var children []string
+	for node := range c.nodes {
+		if child, ok := strings.CutPrefix(node, path+"/"); ok && !strings.Contains(child, "/") {
+			children = append(children, child)
+		}
+	}
+
+	return children, &zk.Stat{}, nil
+}
+
+func (c *fakeConn) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	nodes := make(map[string][]byte, len(c.nodes))
+	for path, data := range c.nodes {
+		nodes[path] = data
+	}
+
+	for _, op := range ops {
+		switch req := op.(type) {
+		case *zk.CreateRequest:
+			if _, ok := nodes[req.Path]; ok {
+				return nil, zk.ErrNodeExists
+			}
+			nodes[req.Path] = req.Data
+		case *zk.SetDataRequest:
+			if _, ok := nodes[req.Path]; !ok {
+				return nil, zk.ErrNoNode
+			}
+			nodes[req.Path] = req.Data
+		case *zk.DeleteRequest:
+			if _, ok := nodes[req.Path]; !ok {
+				return nil, zk.ErrNoNode
+			}
+			delete(nodes, req.Path)
+		}
+	}
+
+	c.nodes = nodes
+
+	return make([]zk.MultiResponse, len(ops)), nil
+}
+
+func newTestMetadata(t *testing.T) (*zookeeperMetadata, *fakeConn) {
+	t.Helper()
+
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{Checkpoint: config.Checkpoint{Timeout: time.Second}}
+	c.Dcp.Group.Name = "group"
+
+	codec, err := metadata.NewCodec(c.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &fakeConn{nodes: map[string][]byte{}}
+
+	m := &zookeeperMetadata{
+		conn:           conn,
+		config:         c,
+		codec:          codec,
+		existingNodes:  map[uint16]bool{},
+		checkpointPath: "/go-dcp/group/checkpoint",
+		acl:            zk.WorldACL(zk.PermAll),
+	}
+
+	if err = m.createParents(); err != nil {
+		t.Fatal(err)
+	}
+
+	return m, conn
+}
+
+func newTestCheckpoint(seqNo uint64) *models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.VbUUID = 7
+	doc.Checkpoint.SeqNo = seqNo
+	doc.Checkpoint.Snapshot.StartSeqNo = seqNo
+	doc.Checkpoint.Snapshot.EndSeqNo = seqNo + 1
+
+	return doc
+}
+
+func TestZookeeperMetadata_CreateParents(t *testing.T) {
+	_, conn := newTestMetadata(t)
+
+	for _, path := range []string{"/go-dcp", "/go-dcp/group", "/go-dcp/group/checkpoint"} {
+		if _, ok := conn.nodes[path]; !ok {
+			t.Errorf("parent %s must be created", path)
+		}
+	}
+}
+
+func TestZookeeperMetadata_SaveLoad(t *testing.T) {
+	m, conn := newTestMetadata(t)
+
+	for _, seqNo := range []uint64{10, 11} {
+		err := m.Save(map[uint16]*models.CheckpointDocument{
+			0: newTestCheckpoint(seqNo),
+			1: newTestCheckpoint(20),
+		}, map[uint16]bool{0: true}, "bucket")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := conn.nodes["/go-dcp/group/checkpoint/1"]; ok {
+		t.Fatalf("only dirty offsets must be saved")
+	}
+
+	state, exist, err := m.Load([]uint16{0, 1}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoint must be loaded, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, newTestCheckpoint(11)) {
+		t.Errorf("last saved checkpoint must be loaded, got %+v", doc)
+	}
+
+	if doc, _ := state.Load(1); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("vBucket without a checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestZookeeperMetadata_SaveRetriesWithCurrentNodes(t *testing.T) {
+	m, conn := newTestMetadata(t)
+
+	// the znode is created by an other member after the nodes are refreshed
+	conn.nodes[m.getCheckpointPath(0)] = nil
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{0: newTestCheckpoint(10)}, map[uint16]bool{0: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, _, err := m.Load([]uint16{0}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, newTestCheckpoint(10)) {
+		t.Errorf("checkpoint must be saved over the existing znode, got %+v", doc)
+	}
+}
+
+func TestZookeeperMetadata_LoadCorruptedCheckpoint(t *testing.T) {
+	m, conn := newTestMetadata(t)
+	conn.nodes[m.getCheckpointPath(0)] = []byte("invalid")
+
+	state, exist, err := m.Load([]uint16{0}, "bucket")
+	if err != nil || exist {
+		t.Fatalf("corrupted checkpoint must not exist, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := state.Load(0); !reflect.DeepEqual(doc, models.NewEmptyCheckpointDocument("bucket")) {
+		t.Errorf("corrupted checkpoint must be loaded empty, got %+v", doc)
+	}
+}
+
+func TestZookeeperMetadata_Clear(t *testing.T) {
+	m, conn := newTestMetadata(t)
+
+	err := m.Save(map[uint16]*models.CheckpointDocument{
+		0: newTestCheckpoint(10),
+		1: newTestCheckpoint(20),
+	}, map[uint16]bool{0: true, 1: true}, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Clear([]uint16{0, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := conn.nodes[m.getCheckpointPath(0)]; ok {
+		t.Errorf("cleared checkpoint must be deleted")
+	}
+
+	if _, ok := conn.nodes[m.getCheckpointPath(1)]; !ok {
+		t.Errorf("other checkpoints must be kept")
+	}
+}
@@ -3,8 +3,7 @@ package metric
 import (
 	"strconv"
 
-	"github.com/Trendyol/go-dcp/models"
-
+	"github.com/Trendyol/go-dcp/config"
 	"github.com/Trendyol/go-dcp/couchbase"
 	"github.com/Trendyol/go-dcp/helpers"
 	"github.com/Trendyol/go-dcp/stream"
@@ -16,6 +15,7 @@ type metricCollector struct {
 	stream           stream.Stream
 	client           couchbase.Client
 	vBucketDiscovery stream.VBucketDiscovery
+	config           *config.Metric
 
 	mutation   *prometheus.Desc
 	deletion   *prometheus.Desc
@@ -29,14 +29,32 @@ type metricCollector struct {
 	endSeqNo     *prometheus.Desc
 	persistSeqNo *prometheus.Desc
 
-	processLatency *prometheus.Desc
-	dcpLatency     *prometheus.Desc
-	rebalance      *prometheus.Desc
-
-	lag      *prometheus.Desc
-	totalLag *prometheus.Desc
+	processLatency        *prometheus.Desc
+	dcpLatency            *prometheus.Desc
+	processLatencySummary *prometheus.Desc
+	dcpLatencySummary     *prometheus.Desc
+	clockSkew             *prometheus.Desc
+	dcpRawLatency         *prometheus.Desc
+	watermarkLag          *prometheus.Desc
+	rebalance             *prometheus.Desc
+	reconnect             *prometheus.Desc
+	reconnectFailure      *prometheus.Desc
+	filtered              *prometheus.Desc
+	binary                *prometheus.Desc
+	invalid               *prometheus.Desc
+
+	lag             *prometheus.Desc
+	totalLag        *prometheus.Desc
+	lagDistribution *prometheus.Desc
+
+	schedulerQueued   *prometheus.Desc
+	schedulerInFlight *prometheus.Desc
+	schedulerStarved  *prometheus.Desc
+	purgeMargin       *prometheus.Desc
 
 	activeStream      *prometheus.Desc
+	paused            *prometheus.Desc
+	held              *prometheus.Desc
 	totalMembers      *prometheus.Desc
 	memberNumber      *prometheus.Desc
 	membershipType    *prometheus.Desc
@@ -44,8 +62,20 @@ type metricCollector struct {
 	vBucketRangeStart *prometheus.Desc
 	vBucketRangeEnd   *prometheus.Desc
 
-	offsetWrite        *prometheus.Desc
-	offsetWriteLatency *prometheus.Desc
+	offsetWrite           *prometheus.Desc
+	offsetWriteLatency    *prometheus.Desc
+	checkpointSaveFailure *prometheus.Desc
+	checkpointStaleness   *prometheus.Desc
+	metadataUnavailable   *prometheus.Desc
+}
+
+// groupSize returns how many vBuckets share a series, it is one unless the large fleet mode is enabled.
+func (s *metricCollector) groupSize() int {
+	if !s.config.LargeFleet.Enabled {
+		return 1
+	}
+
+	return vBucketGroupSize(s.client.GetNumVBuckets(), s.config.LargeFleet.VBucketGroups)
 }
 
 func (s *metricCollector) Describe(ch chan<- *prometheus.Desc) {
@@ -54,46 +84,23 @@ func (s *metricCollector) Describe(ch chan<- *prometheus.Desc) {
 
 //nolint:funlen
 func (s *metricCollector) Collect(ch chan<- prometheus.Metric) {
-	observers := s.stream.GetObservers()
-	if observers == nil {
+	snapshot := s.stream.GetMetricsSnapshot()
+	if !snapshot.Open {
 		return
 	}
 
 	seqNoMap, err := s.client.GetVBucketSeqNos(true)
 
-	observers.Range(func(vbID uint16, observer couchbase.Observer) bool {
-		ch <- prometheus.MustNewConstMetric(
-			s.persistSeqNo,
-			prometheus.CounterValue,
-			float64(observer.GetPersistSeqNo()),
-			strconv.Itoa(int(vbID)),
-		)
-
-		metrics := observer.GetMetrics()
+	vBuckets := newVBucketSeries(s.groupSize())
 
-		ch <- prometheus.MustNewConstMetric(
-			s.mutation,
-			prometheus.CounterValue,
-			metrics.TotalMutations,
-			strconv.Itoa(int(vbID)),
-		)
+	for vbID, observer := range snapshot.Observers {
+		metrics := observer.Metric
 
-		ch <- prometheus.MustNewConstMetric(
-			s.deletion,
-			prometheus.CounterValue,
-			metrics.TotalDeletions,
-			strconv.Itoa(int(vbID)),
-		)
-
-		ch <- prometheus.MustNewConstMetric(
-			s.expiration,
-			prometheus.CounterValue,
-			metrics.TotalExpirations,
-			strconv.Itoa(int(vbID)),
-		)
-
-		return true
-	})
+		vBuckets.Sum(s.persistSeqNo, prometheus.CounterValue, vbID, float64(observer.PersistSeqNo))
+		vBuckets.Sum(s.mutation, prometheus.CounterValue, vbID, metrics.TotalMutations)
+		vBuckets.Sum(s.deletion, prometheus.CounterValue, vbID, metrics.TotalDeletions)
+		vBuckets.Sum(s.expiration, prometheus.CounterValue, vbID, metrics.TotalExpirations)
+	}
 
 	queues := s.client.GetAgentQueues()
 	for i := range queues {
@@ -116,58 +123,40 @@ func (s *metricCollector) Collect(ch chan<- prometheus.Metric) {
 		)
 	}
 
-	offsets, _, _ := s.stream.GetOffsets()
-
 	var totalLag float64
 
-	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
-		ch <- prometheus.MustNewConstMetric(
-			s.currentSeqNo,
-			prometheus.GaugeValue,
-			float64(offset.SeqNo),
-			strconv.Itoa(int(vbID)),
-		)
+	lags := make([]float64, 0, len(snapshot.Offsets))
 
-		ch <- prometheus.MustNewConstMetric(
-			s.startSeqNo,
-			prometheus.GaugeValue,
-			float64(offset.StartSeqNo),
-			strconv.Itoa(int(vbID)),
-		)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(s.lag, err)
+	}
 
-		ch <- prometheus.MustNewConstMetric(
-			s.endSeqNo,
-			prometheus.GaugeValue,
-			float64(offset.EndSeqNo),
-			strconv.Itoa(int(vbID)),
-		)
+	for vbID, offset := range snapshot.Offsets {
+		vBuckets.Sum(s.currentSeqNo, prometheus.GaugeValue, vbID, float64(offset.SeqNo))
+		vBuckets.Sum(s.startSeqNo, prometheus.GaugeValue, vbID, float64(offset.StartSeqNo))
+		vBuckets.Sum(s.endSeqNo, prometheus.GaugeValue, vbID, float64(offset.EndSeqNo))
 
 		if err != nil {
-			ch <- prometheus.NewInvalidMetric(
-				s.lag,
-				err,
-			)
-		} else {
-			var lag float64
-
-			seqNo, _ := seqNoMap.Load(vbID)
-
-			if seqNo > offset.SeqNo {
-				lag = float64(seqNo - offset.SeqNo)
-			}
-
-			totalLag += lag
-
-			ch <- prometheus.MustNewConstMetric(
-				s.lag,
-				prometheus.GaugeValue,
-				lag,
-				strconv.Itoa(int(vbID)),
-			)
+			continue
 		}
 
-		return true
-	})
+		var lag float64
+
+		seqNo, _ := seqNoMap.Load(vbID)
+
+		if seqNo > offset.SeqNo {
+			lag = float64(seqNo - offset.SeqNo)
+		}
+
+		totalLag += lag
+		lags = append(lags, lag)
+
+		vBuckets.Sum(s.lag, prometheus.GaugeValue, vbID, lag)
+	}
+
+	if s.config.LargeFleet.Enabled && err == nil {
+		ch <- newLagHistogram(s.lagDistribution, lags)
+	}
 
 	ch <- prometheus.MustNewConstMetric(
 		s.totalLag,
@@ -176,33 +165,133 @@ func (s *metricCollector) Collect(ch chan<- prometheus.Metric) {
 		[]string{}...,
 	)
 
-	streamMetric, activeStream := s.stream.GetMetric()
-
 	ch <- prometheus.MustNewConstMetric(
 		s.activeStream,
 		prometheus.GaugeValue,
-		float64(activeStream),
+		float64(snapshot.ActiveStreams),
+		[]string{}...,
+	)
+
+	for vbID, scheduler := range snapshot.Scheduler {
+		vBuckets.Sum(s.schedulerQueued, prometheus.GaugeValue, vbID, float64(scheduler.Queued))
+		vBuckets.Sum(s.schedulerInFlight, prometheus.GaugeValue, vbID, float64(scheduler.InFlight))
+		vBuckets.Sum(s.schedulerStarved, prometheus.CounterValue, vbID, float64(scheduler.Starved))
+	}
+
+	for vbID, margin := range snapshot.PurgeMargin {
+		vBuckets.Min(s.purgeMargin, prometheus.GaugeValue, vbID, float64(margin))
+	}
+
+	vBuckets.Collect(ch)
+
+	var paused float64
+	if snapshot.Paused {
+		paused = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		s.paused,
+		prometheus.GaugeValue,
+		paused,
 		[]string{}...,
 	)
 
+	var held float64
+	if snapshot.Held {
+		held = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		s.held,
+		prometheus.GaugeValue,
+		held,
+		[]string{}...,
+	)
+
+	processLatency := snapshot.ProcessLatency
+
 	ch <- prometheus.MustNewConstMetric(
 		s.processLatency,
 		prometheus.GaugeValue,
-		float64(streamMetric.ProcessLatency),
+		float64(processLatency.Last),
 		[]string{}...,
 	)
 
+	ch <- newLatencySummary(s.processLatencySummary, processLatency)
+
+	dcpLatency := snapshot.DcpLatency
+
 	ch <- prometheus.MustNewConstMetric(
 		s.dcpLatency,
 		prometheus.CounterValue,
-		float64(streamMetric.DcpLatency),
+		float64(dcpLatency.Last),
+		[]string{}...,
+	)
+
+	ch <- newLatencySummary(s.dcpLatencySummary, dcpLatency)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.clockSkew,
+		prometheus.GaugeValue,
+		float64(snapshot.ClockSkew),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.dcpRawLatency,
+		prometheus.GaugeValue,
+		float64(snapshot.DcpRawLatency),
 		[]string{}...,
 	)
 
 	ch <- prometheus.MustNewConstMetric(
 		s.rebalance,
 		prometheus.CounterValue,
-		float64(streamMetric.Rebalance),
+		float64(snapshot.Rebalance),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.reconnect,
+		prometheus.CounterValue,
+		float64(snapshot.Reconnect),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.reconnectFailure,
+		prometheus.CounterValue,
+		float64(snapshot.ReconnectFailure),
+		[]string{}...,
+	)
+
+	if !snapshot.Watermark.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			s.watermarkLag,
+			prometheus.GaugeValue,
+			float64(snapshot.CreatedAt.Sub(snapshot.Watermark).Milliseconds()),
+			[]string{}...,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		s.filtered,
+		prometheus.CounterValue,
+		float64(snapshot.Filtered),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.binary,
+		prometheus.CounterValue,
+		float64(snapshot.Binary),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.invalid,
+		prometheus.CounterValue,
+		float64(snapshot.Invalid),
 		[]string{}...,
 	)
 
@@ -250,7 +339,7 @@ func (s *metricCollector) Collect(ch chan<- prometheus.Metric) {
 		[]string{}...,
 	)
 
-	checkpointMetric := s.stream.GetCheckpointMetric()
+	checkpointMetric := snapshot.Checkpoint
 
 	ch <- prometheus.MustNewConstMetric(
 		s.offsetWrite,
@@ -265,31 +354,68 @@ func (s *metricCollector) Collect(ch chan<- prometheus.Metric) {
 		float64(checkpointMetric.OffsetWriteLatency),
 		[]string{}...,
 	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.checkpointSaveFailure,
+		prometheus.CounterValue,
+		float64(checkpointMetric.SaveFailure),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.checkpointStaleness,
+		prometheus.GaugeValue,
+		float64(snapshot.CreatedAt.Sub(checkpointMetric.LastSavedAt).Milliseconds()),
+		[]string{}...,
+	)
+
+	var metadataUnavailable float64
+	if !checkpointMetric.UnavailableSince.IsZero() {
+		metadataUnavailable = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		s.metadataUnavailable,
+		prometheus.GaugeValue,
+		metadataUnavailable,
+		[]string{}...,
+	)
 }
 
 //nolint:funlen
-func NewMetricCollector(client couchbase.Client, stream stream.Stream, vBucketDiscovery stream.VBucketDiscovery) *metricCollector {
+func NewMetricCollector(
+	client couchbase.Client,
+	stream stream.Stream,
+	vBucketDiscovery stream.VBucketDiscovery,
+	config *config.Metric,
+) *metricCollector {
+	vBucketLabel := "vbId"
+	if config.LargeFleet.Enabled {
+		vBucketLabel = "vbGroup"
+	}
+
 	return &metricCollector{
 		stream:           stream,
 		client:           client,
 		vBucketDiscovery: vBucketDiscovery,
+		config:           config,
 
 		mutation: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "mutation", "total"),
 			"Mutation count",
-			[]string{"vbId"},
+			[]string{vBucketLabel},
 			nil,
 		),
 		deletion: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "deletion", "total"),
 			"Deletion count",
-			[]string{"vbId"},
+			[]string{vBucketLabel},
 			nil,
 		),
 		expiration: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "expiration", "total"),
 			"Expiration count",
-			[]string{"vbId"},
+			[]string{vBucketLabel},
 			nil,
 		),
 		agentQueueCurrent: prometheus.NewDesc(
@@ -307,31 +433,61 @@ func NewMetricCollector(client couchbase.Client, stream stream.Stream, vBucketDi
 		currentSeqNo: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "seq_no", "current"),
 			"Current seq no",
-			[]string{"vbId"},
+			[]string{vBucketLabel},
 			nil,
 		),
 		startSeqNo: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "start_seq_no", "current"),
 			"Start seq no",
-			[]string{"vbId"},
+			[]string{vBucketLabel},
 			nil,
 		),
 		endSeqNo: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "end_seq_no", "current"),
 			"End seq no",
-			[]string{"vbId"},
+			[]string{vBucketLabel},
 			nil,
 		),
 		persistSeqNo: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "persist_seq_no", "current"),
 			"Persist seq no",
-			[]string{"vbId"},
+			[]string{vBucketLabel},
 			nil,
 		),
 		lag: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "lag", "current"),
 			"Lag",
-			[]string{"vbId"},
+			[]string{vBucketLabel},
+			nil,
+		),
+		schedulerQueued: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "scheduler_queued", "current"),
+			"Events waiting in the scheduler queue of the vBucket",
+			[]string{vBucketLabel},
+			nil,
+		),
+		schedulerInFlight: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "scheduler_in_flight", "current"),
+			"Events of the vBucket consumed but not acked yet",
+			[]string{vBucketLabel},
+			nil,
+		),
+		schedulerStarved: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "scheduler_starvation", "total"),
+			"Events of the vBucket which waited in the scheduler longer than the starvation threshold",
+			[]string{vBucketLabel},
+			nil,
+		),
+		purgeMargin: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "purge_margin", "current"),
+			"Seqnos between the offset and the purge seqno of the vBucket, negative when purged",
+			[]string{vBucketLabel},
+			nil,
+		),
+		lagDistribution: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "lag_vbuckets", ""),
+			"Distribution of the vBucket lags, only exposed in the large fleet mode",
+			[]string{},
 			nil,
 		),
 		totalLag: prometheus.NewDesc(
@@ -352,18 +508,90 @@ func NewMetricCollector(client couchbase.Client, stream stream.Stream, vBucketDi
 			[]string{},
 			nil,
 		),
+		processLatencySummary: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "process_latency_ms", ""),
+			"Process latency ms percentiles over the latest events",
+			[]string{},
+			nil,
+		),
+		dcpLatencySummary: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "dcp_latency_ms", ""),
+			"Dcp message latency ms percentiles over the latest events",
+			[]string{},
+			nil,
+		),
+		clockSkew: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "clock_skew_ms", "current"),
+			"Estimated local clock skew against the cluster clock ms",
+			[]string{},
+			nil,
+		),
+		dcpRawLatency: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "dcp_raw_latency_ms", "current"),
+			"Latest consumed dcp message latency ms without the clock skew correction",
+			[]string{},
+			nil,
+		),
 		rebalance: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "rebalance", "current"),
 			"Rebalance count",
 			[]string{},
 			nil,
 		),
+		reconnect: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "reconnect", "total"),
+			"Stream re-open attempt count",
+			[]string{},
+			nil,
+		),
+		reconnectFailure: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "reconnect_failure", "total"),
+			"Stream re-open give up count",
+			[]string{},
+			nil,
+		),
+		watermarkLag: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "watermark_lag_ms", "current"),
+			"Time passed since the global low watermark in milliseconds",
+			[]string{},
+			nil,
+		),
+		filtered: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "filtered", "total"),
+			"Events dropped by the key filter",
+			[]string{},
+			nil,
+		),
+		binary: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "binary_documents", "total"),
+			"Mutations with non-json values",
+			[]string{},
+			nil,
+		),
+		invalid: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "invalid_events", "total"),
+			"Events rejected by the validators",
+			[]string{},
+			nil,
+		),
 		activeStream: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "active_stream", "current"),
 			"Active stream",
 			[]string{},
 			nil,
 		),
+		paused: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "paused", "current"),
+			"Stream paused state, 1 while paused",
+			[]string{},
+			nil,
+		),
+		held: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "held", "current"),
+			"Stream hold mode, 1 while the events are dropped and the offsets are held",
+			[]string{},
+			nil,
+		),
 		totalMembers: prometheus.NewDesc(
 			prometheus.BuildFQName(helpers.Name, "total_members", "current"),
 			"Total members",
@@ -412,5 +640,36 @@ func NewMetricCollector(client couchbase.Client, stream stream.Stream, vBucketDi
 			[]string{},
 			nil,
 		),
+		checkpointSaveFailure: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "checkpoint_save_failure", "total"),
+			"Checkpoint saves failed after their retries",
+			[]string{},
+			nil,
+		),
+		checkpointStaleness: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "checkpoint_staleness_ms", "current"),
+			"Time passed since the checkpoint is up to date in milliseconds",
+			[]string{},
+			nil,
+		),
+		metadataUnavailable: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "metadata_unavailable", "current"),
+			"Metadata availability, 1 while checkpoint saves fail",
+			[]string{},
+			nil,
+		),
 	}
 }
+
+func newLatencySummary(desc *prometheus.Desc, snapshot stream.LatencySnapshot) prometheus.Metric {
+	return prometheus.MustNewConstSummary(
+		desc,
+		snapshot.Count,
+		float64(snapshot.Sum),
+		map[float64]float64{
+			0.5:  float64(snapshot.P50),
+			0.95: float64(snapshot.P95),
+			0.99: float64(snapshot.P99),
+		},
+	)
+}
@@ -0,0 +1,73 @@
+package metric
+
+import (
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/stream"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type downstreamHealthCollector struct {
+	health stream.DownstreamHealth
+
+	healthy  *prometheus.Desc
+	failures *prometheus.Desc
+	pauses   *prometheus.Desc
+}
+
+func (s *downstreamHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(s, ch)
+}
+
+func (s *downstreamHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	var healthy float64
+	if s.health.IsHealthy() {
+		healthy = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		s.healthy,
+		prometheus.GaugeValue,
+		healthy,
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.failures,
+		prometheus.CounterValue,
+		float64(s.health.Failures()),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.pauses,
+		prometheus.CounterValue,
+		float64(s.health.Pauses()),
+		[]string{}...,
+	)
+}
+
+func NewDownstreamHealthCollector(health stream.DownstreamHealth) prometheus.Collector {
+	return &downstreamHealthCollector{
+		health: health,
+
+		healthy: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "downstream_healthy", "current"),
+			"Health of the downstream, 0 while the intake is paused for it",
+			[]string{},
+			nil,
+		),
+		failures: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "downstream_probe_failures", "total"),
+			"Failed downstream health probes",
+			[]string{},
+			nil,
+		),
+		pauses: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "downstream_pauses", "total"),
+			"Times the intake is paused for an unhealthy downstream",
+			[]string{},
+			nil,
+		),
+	}
+}
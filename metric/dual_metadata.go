@@ -0,0 +1,54 @@
+package metric
+
+import (
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/metadata"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type dualMetadataCollector struct {
+	metadata *metadata.DualMetadata
+
+	divergence *prometheus.Desc
+	failures   *prometheus.Desc
+}
+
+func (s *dualMetadataCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(s, ch)
+}
+
+func (s *dualMetadataCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(
+		s.divergence,
+		prometheus.GaugeValue,
+		float64(s.metadata.Divergence()),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.failures,
+		prometheus.CounterValue,
+		float64(s.metadata.SecondaryFailures()),
+		[]string{}...,
+	)
+}
+
+func NewDualMetadataCollector(dual *metadata.DualMetadata) prometheus.Collector {
+	return &dualMetadataCollector{
+		metadata: dual,
+
+		divergence: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "secondary_metadata_divergent_vbuckets", "current"),
+			"vBuckets whose checkpoints are not the same in the secondary metadata",
+			[]string{},
+			nil,
+		),
+		failures: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "secondary_metadata_failures", "total"),
+			"Failed operations of the secondary metadata",
+			[]string{},
+			nil,
+		),
+	}
+}
@@ -0,0 +1,80 @@
+package metric
+
+import (
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/helpers"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type nodeHealthCollector struct {
+	monitor couchbase.NodeHealthMonitor
+
+	connected  *prometheus.Desc
+	reconnects *prometheus.Desc
+	stale      *prometheus.Desc
+}
+
+func (s *nodeHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(s, ch)
+}
+
+func (s *nodeHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, node := range s.monitor.Nodes() {
+		var connected float64
+		if node.Connected {
+			connected = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			s.connected,
+			prometheus.GaugeValue,
+			connected,
+			node.Address,
+		)
+
+		var stale float64
+		if node.Stale {
+			stale = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			s.stale,
+			prometheus.GaugeValue,
+			stale,
+			node.Address,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			s.reconnects,
+			prometheus.CounterValue,
+			float64(node.Reconnects),
+			node.Address,
+		)
+	}
+}
+
+func NewNodeHealthCollector(monitor couchbase.NodeHealthMonitor) prometheus.Collector {
+	return &nodeHealthCollector{
+		monitor: monitor,
+
+		connected: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "dcp_node_connected", "current"),
+			"Connection state of the dcp connections to the node, 0 while they are reconnecting",
+			[]string{"address"},
+			nil,
+		),
+		reconnects: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "dcp_node_reconnects", "total"),
+			"Times the dcp connections to the node are connected again after they were lost",
+			[]string{"address"},
+			nil,
+		),
+		stale: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "dcp_node_stale", "current"),
+			"1 while a dcp connection to the node receives nothing for dcp.noop.idleTimeout",
+			[]string{"address"},
+			nil,
+		),
+	}
+}
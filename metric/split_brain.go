@@ -0,0 +1,59 @@
+package metric
+
+import (
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/helpers"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type splitBrainCollector struct {
+	guard couchbase.SplitBrainGuard
+
+	fenced    *prometheus.Desc
+	conflicts *prometheus.Desc
+}
+
+func (s *splitBrainCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(s, ch)
+}
+
+func (s *splitBrainCollector) Collect(ch chan<- prometheus.Metric) {
+	var fenced float64
+	if s.guard.IsFenced() {
+		fenced = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		s.fenced,
+		prometheus.GaugeValue,
+		fenced,
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.conflicts,
+		prometheus.CounterValue,
+		float64(s.guard.Conflicts()),
+		[]string{}...,
+	)
+}
+
+func NewSplitBrainCollector(guard couchbase.SplitBrainGuard) prometheus.Collector {
+	return &splitBrainCollector{
+		guard: guard,
+
+		fenced: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "split_brain_fenced", "current"),
+			"Fenced state of the member, 1 while its membership is claimed by another member",
+			[]string{},
+			nil,
+		),
+		conflicts: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "split_brain_conflicts", "total"),
+			"Times the membership is found claimed by another live member",
+			[]string{},
+			nil,
+		),
+	}
+}
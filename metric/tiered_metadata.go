@@ -0,0 +1,54 @@
+package metric
+
+import (
+	"github.com/Trendyol/go-dcp/helpers"
+	"github.com/Trendyol/go-dcp/metadata"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type tieredMetadataCollector struct {
+	metadata *metadata.TieredMetadata
+
+	pending  *prometheus.Desc
+	failures *prometheus.Desc
+}
+
+func (s *tieredMetadataCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(s, ch)
+}
+
+func (s *tieredMetadataCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(
+		s.pending,
+		prometheus.GaugeValue,
+		float64(s.metadata.Pending()),
+		[]string{}...,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		s.failures,
+		prometheus.CounterValue,
+		float64(s.metadata.Failures()),
+		[]string{}...,
+	)
+}
+
+func NewTieredMetadataCollector(tiered *metadata.TieredMetadata) prometheus.Collector {
+	return &tieredMetadataCollector{
+		metadata: tiered,
+
+		pending: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "durable_metadata_pending_vbuckets", "current"),
+			"vBuckets whose checkpoints are not written to the durable metadata yet",
+			[]string{},
+			nil,
+		),
+		failures: prometheus.NewDesc(
+			prometheus.BuildFQName(helpers.Name, "tiered_metadata_failures", "total"),
+			"Failed operations of the fast and durable metadata which did not fail the checkpoint",
+			[]string{},
+			nil,
+		),
+	}
+}
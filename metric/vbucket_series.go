@@ -0,0 +1,117 @@
+package metric
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var lagBuckets = prometheus.ExponentialBuckets(10, 10, 7)
+
+// vBucketSeries buffers the per vBucket values of a scrape. When the group size is bigger than one,
+// the values of the vBuckets in the same range are merged into one series labeled with the range.
+type vBucketSeries struct {
+	values    map[*prometheus.Desc]map[int]*vBucketValue
+	descs     []*prometheus.Desc
+	groupSize int
+}
+
+type vBucketValue struct {
+	value     float64
+	valueType prometheus.ValueType
+}
+
+func newVBucketSeries(groupSize int) *vBucketSeries {
+	if groupSize < 1 {
+		groupSize = 1
+	}
+
+	return &vBucketSeries{
+		values:    map[*prometheus.Desc]map[int]*vBucketValue{},
+		groupSize: groupSize,
+	}
+}
+
+// Sum adds the value to the series of the vBucket group.
+func (s *vBucketSeries) Sum(desc *prometheus.Desc, valueType prometheus.ValueType, vbID uint16, value float64) {
+	s.merge(desc, valueType, vbID, value, func(current, value float64) float64 {
+		return current + value
+	})
+}
+
+// Min keeps the smallest value of the vBucket group.
+func (s *vBucketSeries) Min(desc *prometheus.Desc, valueType prometheus.ValueType, vbID uint16, value float64) {
+	s.merge(desc, valueType, vbID, value, math.Min)
+}
+
+func (s *vBucketSeries) merge(
+	desc *prometheus.Desc,
+	valueType prometheus.ValueType,
+	vbID uint16,
+	value float64,
+	fn func(current, value float64) float64,
+) {
+	groups, ok := s.values[desc]
+	if !ok {
+		groups = map[int]*vBucketValue{}
+		s.values[desc] = groups
+		s.descs = append(s.descs, desc)
+	}
+
+	group := int(vbID) / s.groupSize
+
+	if current, ok := groups[group]; ok {
+		current.value = fn(current.value, value)
+	} else {
+		groups[group] = &vBucketValue{value: value, valueType: valueType}
+	}
+}
+
+func (s *vBucketSeries) label(group int) string {
+	if s.groupSize == 1 {
+		return strconv.Itoa(group)
+	}
+
+	start := group * s.groupSize
+	return fmt.Sprintf("%d-%d", start, start+s.groupSize-1)
+}
+
+func (s *vBucketSeries) Collect(ch chan<- prometheus.Metric) {
+	for _, desc := range s.descs {
+		for group, value := range s.values[desc] {
+			ch <- prometheus.MustNewConstMetric(desc, value.valueType, value.value, s.label(group))
+		}
+	}
+}
+
+// vBucketGroupSize returns how many vBuckets share a series to stay in the given group count.
+func vBucketGroupSize(numVBuckets int, groups int) int {
+	if groups < 1 || numVBuckets <= groups {
+		return 1
+	}
+
+	return (numVBuckets + groups - 1) / groups
+}
+
+func newLagHistogram(desc *prometheus.Desc, lags []float64) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(lagBuckets))
+	for _, bucket := range lagBuckets {
+		buckets[bucket] = 0
+	}
+
+	var sum float64
+
+	for _, lag := range lags {
+		sum += lag
+
+		for _, bucket := range lagBuckets {
+			if lag <= bucket {
+				buckets[bucket]++
+			}
+		}
+	}
+
+	return prometheus.MustNewConstHistogram(desc, uint64(len(lags)), sum, buckets)
+}
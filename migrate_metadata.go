@@ -0,0 +1,29 @@
+package dcp
+
+import (
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+)
+
+// maxVBuckets is the vBucket count of couchbase on linux, buckets on other platforms have fewer, their missing
+// vBuckets have no checkpoints.
+const maxVBuckets = 1024
+
+// MigrateMetadata copies the checkpoints of every vBucket of a group from src to dst and verifies them, e.g. to move
+// a group from couchbase metadata to etcd. Stop the members of the group before and start them with the metadata
+// type of dst after.
+func MigrateMetadata(src metadata.Metadata, dst metadata.Metadata) (*metadata.MigrationReport, error) {
+	vbIDs := make([]uint16, maxVBuckets)
+	for i := range vbIDs {
+		vbIDs[i] = uint16(i)
+	}
+
+	report, err := metadata.Migrate(src, dst, vbIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("metadata is migrated, vBuckets: %d, skipped: %d", len(report.Migrated), report.Skipped)
+
+	return report, nil
+}
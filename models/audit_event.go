@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// AuditEvent is an admin api operation. ClientSuppliedCaller is the caller the client claims in the audit header, it is
+// not authenticated. RemoteAddr is the address the call came from.
+type AuditEvent struct {
+	Timestamp            time.Time              `json:"timestamp"`
+	Parameters           map[string]interface{} `json:"parameters,omitempty"`
+	Operation            string                 `json:"operation"`
+	ClientSuppliedCaller string                 `json:"callerClaimed,omitempty"`
+	RemoteAddr           string                 `json:"remoteAddr"`
+}
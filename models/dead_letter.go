@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// DeadLetter is an event which the consumer failed to process.
+type DeadLetter struct {
+	Time           time.Time         `json:"time"`
+	Event          interface{}       `json:"-"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	ID             string            `json:"id,omitempty"`
+	Error          string            `json:"error"`
+	Type           string            `json:"type"`
+	CollectionName string            `json:"collectionName"`
+	Key            string            `json:"key"`
+	Value          []byte            `json:"value,omitempty"`
+	Cas            uint64            `json:"cas"`
+	SeqNo          uint64            `json:"seqNo"`
+	VbID           uint16            `json:"vbId"`
+}
+
+type DeadLetterQueue interface {
+	Write(letter *DeadLetter) error
+}
+
+// DeadLetterQueueFunc writes dead letters with a callback.
+type DeadLetterQueueFunc func(letter *DeadLetter) error
+
+func (f DeadLetterQueueFunc) Write(letter *DeadLetter) error {
+	return f(letter)
+}
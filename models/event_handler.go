@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type EventHandler interface {
 	BeforeRebalanceStart()
 	AfterRebalanceStart()
@@ -37,4 +39,114 @@ func (h *EmptyEventHandler) BeforeStreamStop() {
 func (h *EmptyEventHandler) AfterStreamStop() {
 }
 
+// PurgeRisk is a vBucket whose offset is within the margin of its purge seqno. Purged means the offset is behind
+// the purge seqno already, deletions may be lost and the vBucket may roll back to zero when it is opened again.
+type PurgeRisk struct {
+	SeqNo      uint64
+	PurgeSeqNo uint64
+	VbID       uint16
+	Purged     bool
+}
+
+// PurgeRiskHandler is implemented by event handlers which are notified of vBuckets close to their purge seqno.
+type PurgeRiskHandler interface {
+	OnPurgeRisk(risks []PurgeRisk)
+}
+
+// BucketFlushed is notified when the seqnos and the failover logs of the vBuckets are reset by a bucket flush.
+// Offsets of the vBuckets are reset with dcp.flush.reset and their streams are opened again.
+type BucketFlushed struct {
+	Time  time.Time
+	VbIDs []uint16
+}
+
+// BucketFlushHandler is implemented by event handlers which are notified of bucket flushes.
+type BucketFlushHandler interface {
+	OnBucketFlushed(event BucketFlushed)
+}
+
+// MetadataAvailability is notified when checkpoint saves start to fail, when the intake is paused because the latest
+// saved checkpoint is older than checkpoint.maxStaleness, and when a checkpoint is saved again.
+type MetadataAvailability struct {
+	UnavailableSince time.Time
+	LastSavedAt      time.Time
+	Err              error
+	Available        bool
+	IntakePaused     bool
+}
+
+// MetadataAvailabilityHandler is implemented by event handlers which are notified of metadata availability.
+type MetadataAvailabilityHandler interface {
+	OnMetadataAvailability(event MetadataAvailability)
+}
+
+// SplitBrain is notified when the membership slot of the member is claimed by another live member and the member
+// is fenced, and when the slot is claimed by the member again. Owner is the member which holds the slot.
+type SplitBrain struct {
+	Time         time.Time
+	Owner        string
+	MemberNumber int
+	TotalMembers int
+	Fenced       bool
+}
+
+// SplitBrainHandler is implemented by event handlers which are notified of duplicate memberships.
+type SplitBrainHandler interface {
+	OnSplitBrain(event SplitBrain)
+}
+
+// VBucketCountChanged is notified when the checkpoints are saved from a bucket with another vBucket count, e.g. the
+// bucket is recreated. Reset is true when the checkpoints are reset with checkpoint.vBucketCountChange, the stream
+// does not start otherwise.
+type VBucketCountChanged struct {
+	Time         time.Time
+	Checkpointed int
+	Current      int
+	Reset        bool
+}
+
+// VBucketCountChangeHandler is implemented by event handlers which are notified of vBucket count changes.
+type VBucketCountChangeHandler interface {
+	OnVBucketCountChanged(event VBucketCountChanged)
+}
+
+// WatermarkAdvanced is notified when the global watermark moves forward, the events older than Watermark are
+// processed by every vBucket of the member.
+type WatermarkAdvanced struct {
+	Time      time.Time
+	Watermark time.Time
+}
+
+// WatermarkHandler is implemented by event handlers which are notified of watermark advances. It is called when
+// offsets advance, so it must return quickly.
+type WatermarkHandler interface {
+	OnWatermarkAdvanced(event WatermarkAdvanced)
+}
+
+// NodeConnectionChanged is notified when the dcp connections to a node are lost, and when they are connected again.
+// A reconnect which happens between two health checks is notified with Connected true and an increased Reconnects.
+type NodeConnectionChanged struct {
+	Time time.Time
+	NodeConnection
+}
+
+// NodeConnectionHandler is implemented by event handlers which are notified of node connection changes.
+type NodeConnectionHandler interface {
+	OnNodeConnectionChanged(event NodeConnectionChanged)
+}
+
+// DcpConnectionStale is notified when a connection to a node receives nothing, not even a NOOP, for
+// dcp.noop.idleTimeout, e.g. its tcp connection is half-open.
+type DcpConnectionStale struct {
+	Time         time.Time
+	LastActivity time.Time
+	Address      string
+	IdleFor      time.Duration
+}
+
+// DcpConnectionStaleHandler is implemented by event handlers which are notified of stale dcp connections.
+type DcpConnectionStaleHandler interface {
+	OnDcpConnectionStale(event DcpConnectionStale)
+}
+
 var DefaultEventHandler EventHandler = &EmptyEventHandler{}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type GroupInfo struct {
+	LastCheckpointTime time.Time `json:"lastCheckpointTime"`
+	Name               string    `json:"name"`
+	MemberCount        int       `json:"memberCount"`
+	CheckpointCount    int       `json:"checkpointCount"`
+	Lag                uint64    `json:"lag"`
+}
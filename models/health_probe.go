@@ -0,0 +1,7 @@
+package models
+
+import "context"
+
+// HealthProbe checks the health of the sink of the consumer, it returns an error when the sink is unhealthy.
+// Probes must return when ctx is done, ctx is canceled after downstreamHealth.timeout.
+type HealthProbe func(ctx context.Context) error
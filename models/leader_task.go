@@ -0,0 +1,10 @@
+package models
+
+import "context"
+
+// LeaderTask is a singleton job of the group, it is run by the leader member only.
+// Run must return when ctx is done, ctx is canceled when the member loses leadership.
+type LeaderTask interface {
+	Name() string
+	Run(ctx context.Context)
+}
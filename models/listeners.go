@@ -6,14 +6,53 @@ import (
 
 type ListenerContext struct {
 	Commit                  func()
+	CommitAsync             func() <-chan error
 	Event                   interface{}
 	Ack                     func()
 	ListenerTracerComponent tracing.ListenerTracerComponent
+	State                   State
+	// ValidationError is set when a validator rejects the event and dcp.validation.policy is flag.
+	ValidationError error
+	// Metadata is attached to the event by the decorators, it is nil when no decorator is set.
+	Metadata map[string]string
+	// ID is the id of the event generated by the id generator, it is empty when no id generator is set.
+	ID string
+	// SnapshotComplete is true when the event is the last of its dcp snapshot, the events of the vBucket are
+	// consistent with the source up to it.
+	SnapshotComplete bool
+	// Bucket is the name of the source bucket, it tells the events of the buckets apart when multiple are streamed.
+	Bucket string
+	VbID   uint16
+}
+
+// BatchListenerContext holds the events of a vBucket in order. Ack acknowledges every event of the batch.
+type BatchListenerContext struct {
+	Commit      func()
+	CommitAsync func() <-chan error
+	Ack         func()
+	State       State
+	Events      []interface{}
+	// IDs and Metadata are the ids and the metadata of the events, in the order of the events.
+	IDs      []string
+	Metadata []map[string]string
+	Bucket   string
+	// SnapshotComplete is true when the last event of the batch is the last of its dcp snapshot.
+	SnapshotComplete bool
+	VbID             uint16
+}
+
+// State is the processing state of a vBucket. Changes are kept with the offset of the vBucket when the event is acked,
+// and committed with the checkpoint. It is nil when the state store is not configured.
+type State interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
 }
 
 type ListenerArgs struct {
 	Event        interface{}
 	TraceContext tracing.RequestSpanContext
+	VbID         uint16
 }
 
 type DcpStreamEndContext struct {
@@ -22,6 +61,12 @@ type DcpStreamEndContext struct {
 }
 
 type (
+	EventFilter func(event interface{}) bool
+	Validator   func(event interface{}) error
+	// Decorator attaches metadata to the event, e.g. a trace id, a tenant label or a schema version.
+	Decorator func(event interface{}, metadata map[string]string)
+	// IDGenerator generates the id of the event.
+	IDGenerator            func(event interface{}) string
 	Listener               func(*ListenerContext)
 	ListenerCh             chan ListenerArgs
 	ListenerEndCh          chan DcpStreamEndContext
@@ -32,3 +77,64 @@ type Consumer interface {
 	ConsumeEvent(ctx *ListenerContext)
 	TrackOffset(vbID uint16, offset *Offset)
 }
+
+// FailableConsumer reports the events it fails to process, they are written to the dead letter queue.
+type FailableConsumer interface {
+	ConsumeEvent(ctx *ListenerContext) error
+	TrackOffset(vbID uint16, offset *Offset)
+}
+
+// RetryClassifier is implemented by failable consumers which retry only some of their errors.
+type RetryClassifier interface {
+	IsRetryable(err error) bool
+}
+
+const (
+	OrderingPerVBucket = "perVBucket"
+	OrderingPerKey     = "perKey"
+	OrderingNone       = "none"
+)
+
+// ConsumerCapabilities is the ordering the consumer requires and the count of events it can consume at once.
+// Events of a vBucket are consumed in order with OrderingPerVBucket, events of a key with OrderingPerKey and in any
+// order with OrderingNone. Concurrency defaults to dcp.listener.scheduler.workers.
+type ConsumerCapabilities struct {
+	Ordering    string
+	Concurrency int
+}
+
+// CapableConsumer is implemented by consumers which declare their capabilities, so events are consumed in parallel
+// as far as they allow. Consumers without it get the events of each connection one at a time.
+type CapableConsumer interface {
+	Capabilities() ConsumerCapabilities
+}
+
+type BatchConsumer interface {
+	ConsumeBatch(ctx *BatchListenerContext)
+}
+
+// BufferedConsumer is implemented by consumers which hold events before they are consumed.
+// Held events are discarded when the stream closes, they are streamed again from the checkpoint.
+type BufferedConsumer interface {
+	Discard()
+}
+
+// ConsumerWrapper is embedded by consumers which wrap another consumer, it forwards Discard and Capabilities to the
+// wrapped one when it implements them.
+type ConsumerWrapper struct {
+	Wrapped any
+}
+
+func (w ConsumerWrapper) Discard() {
+	if buffered, ok := w.Wrapped.(BufferedConsumer); ok {
+		buffered.Discard()
+	}
+}
+
+func (w ConsumerWrapper) Capabilities() ConsumerCapabilities {
+	if capable, ok := w.Wrapped.(CapableConsumer); ok {
+		return capable.Capabilities()
+	}
+
+	return ConsumerCapabilities{}
+}
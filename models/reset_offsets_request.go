@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type ResetOffsetsRequest struct {
+	Timestamp time.Time         `json:"timestamp"`
+	SeqNos    map[uint16]uint64 `json:"seqNos"`
+	Type      string            `json:"type"`
+	VbIDs     []uint16          `json:"vbIds"`
+}
@@ -4,3 +4,15 @@ type SetInfoRequest struct {
 	MemberNumber int `json:"memberNumber"`
 	TotalMembers int `json:"totalMembers"`
 }
+
+type SetTotalMembersRequest struct {
+	TotalMembers int `json:"totalMembers"`
+}
+
+type SetMaintenanceModeRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+type SetHoldModeRequest struct {
+	Enabled bool `json:"enabled"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+type StreamIdentity struct {
+	GroupName    string
+	MemberNumber int
+	TotalMembers int
+}
+
+func (i *StreamIdentity) MemberID() string {
+	return fmt.Sprintf("%d/%d", i.MemberNumber, i.TotalMembers)
+}
+
+type streamIdentityContextKey struct{}
+
+// NewStreamContext returns a context carrying the stream identity. Logs written through
+// logger.Ctx and spans started with it carry the group name and member id.
+func NewStreamContext(parent context.Context, identity *StreamIdentity) context.Context {
+	ctx := context.WithValue(parent, streamIdentityContextKey{}, identity)
+
+	return logger.WithFields(ctx, map[string]interface{}{
+		"groupName": identity.GroupName,
+		"memberID":  identity.MemberID(),
+	})
+}
+
+// StreamIdentityFromContext returns nil when ctx is not a stream context.
+func StreamIdentityFromContext(ctx context.Context) *StreamIdentity {
+	if ctx == nil {
+		return nil
+	}
+
+	identity, _ := ctx.Value(streamIdentityContextKey{}).(*StreamIdentity)
+	return identity
+}
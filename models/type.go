@@ -4,10 +4,12 @@ import (
 	"time"
 
 	"github.com/couchbase/gocbcore/v10"
+	"github.com/golang/snappy"
 )
 
 type Offset struct {
 	*SnapshotMarker
+	ProcessedAt time.Time
 	VbUUID      gocbcore.VbUUID
 	SeqNo       uint64
 	LatestSeqNo uint64
@@ -32,26 +34,56 @@ type SnapshotMarker struct {
 	EndSeqNo   uint64
 }
 
+// InternalDcpMutation is a mutation of the stream. Compressed is true when the value is delivered snappy compressed
+// with dcp.compression.passthrough.
 type InternalDcpMutation struct {
-	EventTime time.Time
+	EventTime    time.Time
+	ServerTime   time.Time
+	ReceivedTime time.Time
 	*gocbcore.DcpMutation
 	Offset         *Offset
 	CollectionName string
+	Compressed     bool
 }
 
 func (i *InternalDcpMutation) IsCreated() bool {
 	return i.RevNo == 1
 }
 
+// DecompressedValue returns the value, it is decompressed with each call when the value is compressed.
+func (i *InternalDcpMutation) DecompressedValue() ([]byte, error) {
+	return decompressedValue(i.Value, i.Compressed)
+}
+
+// InternalDcpDeletion is a deletion of the stream. Compressed is true when the value, e.g. the xattrs of the deleted
+// document, is delivered snappy compressed with dcp.compression.passthrough.
 type InternalDcpDeletion struct {
-	EventTime time.Time
+	EventTime    time.Time
+	ServerTime   time.Time
+	ReceivedTime time.Time
 	*gocbcore.DcpDeletion
 	Offset         *Offset
 	CollectionName string
+	Compressed     bool
+}
+
+// DecompressedValue returns the value, it is decompressed with each call when the value is compressed.
+func (i *InternalDcpDeletion) DecompressedValue() ([]byte, error) {
+	return decompressedValue(i.Value, i.Compressed)
+}
+
+func decompressedValue(value []byte, compressed bool) ([]byte, error) {
+	if !compressed || len(value) == 0 {
+		return value, nil
+	}
+
+	return snappy.Decode(nil, value)
 }
 
 type InternalDcpExpiration struct {
-	EventTime time.Time
+	EventTime    time.Time
+	ServerTime   time.Time
+	ReceivedTime time.Time
 	*gocbcore.DcpExpiration
 	Offset         *Offset
 	CollectionName string
@@ -106,6 +138,7 @@ type PingResult struct {
 	MgmtEndpoint string
 }
 
+// AgentQueue is the pipeline of the agent to a node. Current is the count of the requests waiting in its queue.
 type AgentQueue struct {
 	Address string
 	IsDcp   bool
@@ -113,6 +146,36 @@ type AgentQueue struct {
 	Max     int
 }
 
+// Capability is a feature of the dcp connection. Requested is true when the config or the library asks for it,
+// Supported when the server supports it and Enabled when the dcp connection uses it.
+type Capability struct {
+	Name      string `json:"name"`
+	Requested bool   `json:"requested"`
+	Supported bool   `json:"supported"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// CapabilityReport is the result of the feature negotiation of the dcp connection with the server.
+type CapabilityReport struct {
+	Time         time.Time    `json:"time"`
+	Version      string       `json:"version"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// NodeConnection is the health of the dcp connections to a node. Connected is true while all its connections are
+// connected, they are reconnecting otherwise. Reconnects is the count of the connections made again after they
+// were lost, and LastError is the last error of a connection attempt. LastActivity is the time of the last packet
+// received by its most idle connection, Stale is true while it is idle longer than dcp.noop.idleTimeout.
+type NodeConnection struct {
+	LastActivity time.Time
+	Address      string
+	LastError    string
+	Reconnects   int64
+	Connections  int
+	Connected    bool
+	Stale        bool
+}
+
 type (
 	DcpSnapshotMarker         = gocbcore.DcpSnapshotMarker
 	DcpMutation               = InternalDcpMutation
@@ -140,9 +203,22 @@ type CheckpointDocumentCheckpoint struct {
 	SeqNo    uint64                      `json:"seqno"`
 }
 
+// CheckpointDocument is the persisted offset of a vBucket. ProcessedTime is the unix nano time the offset was last
+// advanced, Version is the library version which saved it and VBuckets is the vBucket count of the bucket, they are
+// empty in documents of older versions.
 type CheckpointDocument struct {
+	Checkpoint    *CheckpointDocumentCheckpoint `json:"checkpoint"`
+	BucketUUID    string                        `json:"bucketUuid"`
+	Version       string                        `json:"version,omitempty"`
+	History       []*CheckpointHistoryEntry     `json:"history,omitempty"`
+	ProcessedTime int64                         `json:"processedTime,omitempty"`
+	VBuckets      int                           `json:"vBuckets,omitempty"`
+}
+
+// CheckpointHistoryEntry is a former checkpoint of a vBucket, entries are kept from the oldest to the newest.
+type CheckpointHistoryEntry struct {
 	Checkpoint *CheckpointDocumentCheckpoint `json:"checkpoint"`
-	BucketUUID string                        `json:"bucketUuid"`
+	SavedTime  int64                         `json:"savedTime"`
 }
 
 func NewEmptyCheckpointDocument(bucketUUID string) *CheckpointDocument {
@@ -158,3 +234,9 @@ func NewEmptyCheckpointDocument(bucketUUID string) *CheckpointDocument {
 		BucketUUID: bucketUUID,
 	}
 }
+
+// StateSnapshot is the state of a vBucket with the checkpoint it is consistent with.
+type StateSnapshot struct {
+	Checkpoint *CheckpointDocument `json:"checkpoint"`
+	Values     map[string][]byte   `json:"values"`
+}
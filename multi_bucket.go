@@ -0,0 +1,368 @@
+package dcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/Trendyol/go-dcp/api"
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/state"
+	"github.com/Trendyol/go-dcp/stream"
+)
+
+var ErrMultipleBuckets = errors.New("not supported with multiple buckets, use the dcp of a bucket from GetBucket")
+
+// MultiBucketDcp is the Dcp of a config with buckets. It streams each bucket with its own client, stream and
+// checkpoint group, and passes the events of all buckets to the consumer with the bucket name in the context.
+// Methods change every bucket, the getters return the first bucket, GetBucket returns the Dcp of a bucket.
+type MultiBucketDcp interface {
+	Dcp
+	GetBucket(name string) Dcp
+	GetBuckets() []string
+}
+
+type multiBucketDcp struct {
+	readyCh chan struct{}
+	buckets map[string]*dcp
+	dcps    []*dcp
+	names   []string
+}
+
+// sharedConsumer passes the events of all buckets to the same consumer.
+func sharedConsumer(consumer models.Consumer) func(bucketConfig *config.Dcp) models.Consumer {
+	return func(_ *config.Dcp) models.Consumer {
+		return consumer
+	}
+}
+
+func newMultiBucketDcp(c *config.Dcp, newConsumer func(bucketConfig *config.Dcp) models.Consumer) (Dcp, error) {
+	if c.LeaderElection.Enabled && len(c.Buckets) > 1 {
+		return nil, errors.New("leader election is not supported with multiple buckets")
+	}
+
+	if len(c.Pipelines) > 0 {
+		return nil, errors.New("pipelines are not supported with multiple buckets")
+	}
+
+	m := &multiBucketDcp{
+		readyCh: make(chan struct{}, 1),
+		buckets: map[string]*dcp{},
+	}
+
+	for i, bucket := range c.Buckets {
+		if bucket.Name == "" || m.buckets[bucket.Name] != nil {
+			return nil, fmt.Errorf("bucket %q is unnamed or duplicated", bucket.Name)
+		}
+
+		bucketConfig := c.GetBucketConfig(i)
+
+		d, err := newDeferredDcp(bucketConfig, newConsumer(bucketConfig))
+		if err != nil {
+			return nil, err
+		}
+
+		m.buckets[bucket.Name] = d.(*dcp)
+		m.dcps = append(m.dcps, d.(*dcp))
+		m.names = append(m.names, bucket.Name)
+	}
+
+	return m, nil
+}
+
+// Connect connects the buckets in order, the connected ones are closed when a bucket cannot be connected.
+func (m *multiBucketDcp) Connect(ctx context.Context) error {
+	for i, d := range m.dcps {
+		if err := d.Connect(ctx); err != nil {
+			m.closeClients()
+			return fmt.Errorf("bucket %s: %w", m.names[i], err)
+		}
+	}
+
+	return nil
+}
+
+func (m *multiBucketDcp) closeClients() {
+	for _, d := range m.dcps {
+		if !d.connected {
+			continue
+		}
+
+		d.client.DcpClose()
+		d.client.Close()
+		d.connected = false
+	}
+}
+
+func (m *multiBucketDcp) GetBucket(name string) Dcp {
+	if d, ok := m.buckets[name]; ok {
+		return d
+	}
+
+	return nil
+}
+
+func (m *multiBucketDcp) GetBuckets() []string {
+	return m.names
+}
+
+func (m *multiBucketDcp) WaitUntilReady() chan struct{} {
+	return m.readyCh
+}
+
+// Start connects the buckets when Connect is not called before, starts them and blocks until all of them are closed. When a bucket stops, the others are closed too.
+func (m *multiBucketDcp) Start() {
+	if err := m.Connect(context.Background()); err != nil {
+		logger.Log.Error("error while dcp start, err: %v", err)
+		panic(err)
+	}
+
+	var wg sync.WaitGroup
+	stopped := make(chan struct{}, len(m.dcps))
+
+	for _, d := range m.dcps {
+		wg.Add(1)
+
+		go func(d *dcp) {
+			defer wg.Done()
+
+			d.Start()
+			stopped <- struct{}{}
+		}(d)
+	}
+
+	go func() {
+		for _, d := range m.dcps {
+			<-d.WaitUntilReady()
+		}
+
+		m.readyCh <- struct{}{}
+	}()
+
+	<-stopped
+
+	logger.Log.Info("a bucket is stopped, closing all buckets")
+
+	m.Close()
+
+	wg.Wait()
+}
+
+func (m *multiBucketDcp) Close() {
+	for _, d := range m.dcps {
+		d.stop()
+	}
+}
+
+func (m *multiBucketDcp) Commit() {
+	for _, d := range m.dcps {
+		d.Commit()
+	}
+}
+
+func (m *multiBucketDcp) CommitAsync() <-chan error {
+	result := make(chan error, 1)
+
+	channels := make([]<-chan error, 0, len(m.dcps))
+	for _, d := range m.dcps {
+		channels = append(channels, d.CommitAsync())
+	}
+
+	go func() {
+		var errs []error
+		for _, ch := range channels {
+			errs = append(errs, <-ch)
+		}
+
+		result <- errors.Join(errs...)
+	}()
+
+	return result
+}
+
+func (m *multiBucketDcp) Pause() {
+	for _, d := range m.dcps {
+		d.Pause()
+	}
+}
+
+func (m *multiBucketDcp) Resume() {
+	for _, d := range m.dcps {
+		d.Resume()
+	}
+}
+
+func (m *multiBucketDcp) SetMaintenanceMode(enabled bool) {
+	for _, d := range m.dcps {
+		d.SetMaintenanceMode(enabled)
+	}
+}
+
+func (m *multiBucketDcp) SetHoldMode(enabled bool) {
+	for _, d := range m.dcps {
+		d.SetHoldMode(enabled)
+	}
+}
+
+func (m *multiBucketDcp) VerifyCheckpoints() ([]stream.CheckpointIssue, error) {
+	var issues []stream.CheckpointIssue
+
+	for i, d := range m.dcps {
+		bucketIssues, err := d.VerifyCheckpoints()
+		if err != nil {
+			return nil, fmt.Errorf("bucket %s: %w", m.names[i], err)
+		}
+
+		issues = append(issues, bucketIssues...)
+	}
+
+	return issues, nil
+}
+
+func (m *multiBucketDcp) ExportCheckpoints(_ io.Writer, _ string) error {
+	return fmt.Errorf("checkpoint export is %w", ErrMultipleBuckets)
+}
+
+func (m *multiBucketDcp) ImportCheckpoints(_ io.Reader) error {
+	return fmt.Errorf("checkpoint import is %w", ErrMultipleBuckets)
+}
+
+func (m *multiBucketDcp) SetTotalMembers(totalMembers int) error {
+	for i, d := range m.dcps {
+		if err := d.SetTotalMembers(totalMembers); err != nil {
+			return fmt.Errorf("bucket %s: %w", m.names[i], err)
+		}
+	}
+
+	return nil
+}
+
+func (m *multiBucketDcp) ResetOffsets(policy stream.ResetPolicy, vbIDs []uint16) error {
+	for i, d := range m.dcps {
+		if err := d.ResetOffsets(policy, vbIDs); err != nil {
+			return fmt.Errorf("bucket %s: %w", m.names[i], err)
+		}
+	}
+
+	return nil
+}
+
+func (m *multiBucketDcp) Bootstrap() ([]metadata.BootstrapStep, error) {
+	var steps []metadata.BootstrapStep
+
+	for i, d := range m.dcps {
+		bucketSteps, err := d.Bootstrap()
+		steps = append(steps, bucketSteps...)
+		if err != nil {
+			return steps, fmt.Errorf("bucket %s: %w", m.names[i], err)
+		}
+	}
+
+	return steps, nil
+}
+
+func (m *multiBucketDcp) GetWatermarks() *stream.Watermarks {
+	return m.dcps[0].GetWatermarks()
+}
+
+func (m *multiBucketDcp) GetClient() couchbase.Client {
+	return m.dcps[0].GetClient()
+}
+
+func (m *multiBucketDcp) GetConfig() *config.Dcp {
+	return m.dcps[0].GetConfig()
+}
+
+func (m *multiBucketDcp) GetVersion() *couchbase.Version {
+	return m.dcps[0].GetVersion()
+}
+
+func (m *multiBucketDcp) GetCapabilities() *models.CapabilityReport {
+	return m.dcps[0].GetCapabilities()
+}
+
+func (m *multiBucketDcp) GetAPI() api.API {
+	return m.dcps[0].GetAPI()
+}
+
+func (m *multiBucketDcp) GetMetricsSnapshot() *stream.MetricsSnapshot {
+	return m.dcps[0].GetMetricsSnapshot()
+}
+
+// RegisterLeaderTask registers the task to the first bucket only, so the group runs a single instance of it.
+func (m *multiBucketDcp) RegisterLeaderTask(task models.LeaderTask) {
+	m.dcps[0].RegisterLeaderTask(task)
+}
+
+// OnShutdown registers the hook to every bucket, it runs for each bucket after its stream is drained.
+func (m *multiBucketDcp) OnShutdown(priority int, fn func(ctx context.Context)) {
+	for _, d := range m.dcps {
+		d.OnShutdown(priority, fn)
+	}
+}
+
+// SetMetadata sets the same metadata to every bucket, it must keep the checkpoints of the buckets apart, e.g. by
+// their bucket uuids. Set a metadata for each bucket from GetBucket otherwise.
+func (m *multiBucketDcp) SetMetadata(metadata metadata.Metadata) {
+	for _, d := range m.dcps {
+		d.SetMetadata(metadata)
+	}
+}
+
+// SetStateBackend panics, the snapshots of a backend are kept by vBucket so it cannot be shared by the buckets. Set a
+// backend for each bucket from GetBucket.
+func (m *multiBucketDcp) SetStateBackend(_ state.Backend) {
+	err := fmt.Errorf("state backend is %w", ErrMultipleBuckets)
+	logger.Log.Error("error while set state backend, err: %v", err)
+	panic(err)
+}
+
+func (m *multiBucketDcp) SetClock(clock clock.Clock) {
+	for _, d := range m.dcps {
+		d.SetClock(clock)
+	}
+}
+
+// SetMetricCollectors adds the collectors to the first bucket, which serves the api and metrics.
+func (m *multiBucketDcp) SetMetricCollectors(collectors ...prometheus.Collector) {
+	m.dcps[0].SetMetricCollectors(collectors...)
+}
+
+func (m *multiBucketDcp) SetEventHandler(handler models.EventHandler) {
+	for _, d := range m.dcps {
+		d.SetEventHandler(handler)
+	}
+}
+
+func (m *multiBucketDcp) SetValidators(validators ...models.Validator) {
+	for _, d := range m.dcps {
+		d.SetValidators(validators...)
+	}
+}
+
+func (m *multiBucketDcp) SetDecorators(decorators ...models.Decorator) {
+	for _, d := range m.dcps {
+		d.SetDecorators(decorators...)
+	}
+}
+
+func (m *multiBucketDcp) SetIDGenerator(generator models.IDGenerator) {
+	for _, d := range m.dcps {
+		d.SetIDGenerator(generator)
+	}
+}
+
+func (m *multiBucketDcp) SetDownstreamProbe(probe models.HealthProbe) {
+	for _, d := range m.dcps {
+		d.SetDownstreamProbe(probe)
+	}
+}
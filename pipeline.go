@@ -0,0 +1,151 @@
+package dcp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// Pipeline is the code part of a named pipeline of the config. Events are passed to the consumer
+// only when every filter accepts them, rejected events are acked so the checkpoint moves on.
+// Validators check the accepted events with dcp.validation.policy, and Decorators attach metadata to them.
+type Pipeline struct {
+	Consumer   models.Consumer
+	Filters    []models.EventFilter
+	Validators []models.Validator
+	Decorators []models.Decorator
+}
+
+type filteredConsumer struct {
+	models.Consumer
+	models.ConsumerWrapper
+	filters []models.EventFilter
+}
+
+func (c *filteredConsumer) ConsumeEvent(ctx *models.ListenerContext) {
+	for _, filter := range c.filters {
+		if !filter(ctx.Event) {
+			ctx.Ack()
+			return
+		}
+	}
+
+	c.Consumer.ConsumeEvent(ctx)
+}
+
+func newFilteredConsumer(consumer models.Consumer, filters []models.EventFilter) *filteredConsumer {
+	return &filteredConsumer{
+		Consumer:        consumer,
+		ConsumerWrapper: models.ConsumerWrapper{Wrapped: consumer},
+		filters:         filters,
+	}
+}
+
+// RunPipelines runs a Dcp for each pipeline of the config with its own checkpoint group,
+// and blocks until all of them are closed. When a pipeline stops, the others are closed too.
+// A pipeline which fails to start stops the others, the errors are returned joined in the order they occur.
+//
+// config: path to a configuration file or a configuration struct
+// pipelines: consumers and filters of the configured pipelines by name
+func RunPipelines(cfg any, pipelines map[string]Pipeline) error {
+	c, err := resolveConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(c.Pipelines) == 0 {
+		return errors.New("no pipeline is configured")
+	}
+
+	if len(c.Buckets) > 0 {
+		return errors.New("pipelines are not supported with multiple buckets")
+	}
+
+	if c.LeaderElection.Enabled && len(c.Pipelines) > 1 {
+		return errors.New("leader election is not supported with multiple pipelines")
+	}
+
+	dcps := make([]*dcp, 0, len(c.Pipelines))
+	names := map[string]bool{}
+
+	closeAll := func() {
+		for _, d := range dcps {
+			d.client.DcpClose()
+			d.client.Close()
+		}
+	}
+
+	for i, pipelineConfig := range c.Pipelines {
+		pipeline, ok := pipelines[pipelineConfig.Name]
+		if pipelineConfig.Name == "" || names[pipelineConfig.Name] || !ok || pipeline.Consumer == nil {
+			closeAll()
+			return fmt.Errorf("pipeline %q is unnamed, duplicated or has no consumer", pipelineConfig.Name)
+		}
+
+		names[pipelineConfig.Name] = true
+
+		d, err := newDcp(c.GetPipelineConfig(i), newFilteredConsumer(pipeline.Consumer, pipeline.Filters))
+		if err != nil {
+			closeAll()
+			return err
+		}
+
+		d.SetValidators(pipeline.Validators...)
+		d.SetDecorators(pipeline.Decorators...)
+
+		dcps = append(dcps, d.(*dcp))
+	}
+
+	var wg sync.WaitGroup
+	var errs []error
+	var errsLock sync.Mutex
+	stopped := make(chan struct{}, len(dcps))
+
+	for _, d := range dcps {
+		wg.Add(1)
+
+		go func(d *dcp) {
+			defer wg.Done()
+
+			if err := startPipeline(d); err != nil {
+				errsLock.Lock()
+				errs = append(errs, err)
+				errsLock.Unlock()
+			}
+
+			stopped <- struct{}{}
+		}(d)
+	}
+
+	<-stopped
+
+	logger.Log.Info("a pipeline is stopped, closing all pipelines")
+
+	for _, d := range dcps {
+		d.stop()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// startPipeline starts the dcp of the pipeline and returns the error it panics with.
+func startPipeline(d *dcp) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if recovered, ok := r.(error); ok {
+				err = fmt.Errorf("pipeline %s: %w", d.config.Dcp.Group.Name, recovered)
+			} else {
+				err = fmt.Errorf("pipeline %s: %v", d.config.Dcp.Group.Name, r)
+			}
+		}
+	}()
+
+	d.Start()
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package dcp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+)
+
+func TestStartPipelineReturnsStartError(t *testing.T) {
+	logger.InitDefaultLogger("fatal")
+
+	c := &config.Dcp{}
+	c.Dcp.Group.Name = "orders"
+	c.Metadata.Type = "unregistered"
+
+	err := startPipeline(&dcp{config: c, connected: true})
+	if err == nil {
+		t.Fatalf("panic of start must be returned as an error")
+	}
+
+	if !errors.Is(err, metadata.ErrMetadataTypeNotRegistered) || !strings.HasPrefix(err.Error(), "pipeline orders:") {
+		t.Fatalf("error must wrap the start error with the pipeline name, err: %v", err)
+	}
+}
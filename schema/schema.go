@@ -0,0 +1,74 @@
+// Package schema publishes the json schemas of the formats the library writes for other services, so consumers in
+// other languages can be generated against them. A schema changes its Version only with a breaking change, fields
+// added to a format do not change it.
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"sort"
+)
+
+const (
+	AuditEvent         = "auditEvent"
+	BinaryEnvelope     = "binaryEnvelope"
+	CheckpointSnapshot = "checkpointSnapshot"
+	DeadLetter         = "deadLetter"
+)
+
+// Version is the version of every schema of the package.
+const Version = 1
+
+//go:embed *.json
+var files embed.FS
+
+var fileNames = map[string]string{
+	AuditEvent:         "audit_event.json",
+	BinaryEnvelope:     "binary_envelope.json",
+	CheckpointSnapshot: "checkpoint_snapshot.json",
+	DeadLetter:         "dead_letter.json",
+}
+
+type Schema struct {
+	Name       string          `json:"name"`
+	Definition json.RawMessage `json:"definition"`
+	Version    int             `json:"version"`
+}
+
+// Get returns the schema of the format, false when there is no such format.
+func Get(name string) (Schema, bool) {
+	fileName, ok := fileNames[name]
+	if !ok {
+		return Schema{}, false
+	}
+
+	definition, err := files.ReadFile(fileName)
+	if err != nil {
+		return Schema{}, false
+	}
+
+	return Schema{Name: name, Version: Version, Definition: definition}, true
+}
+
+// Names returns the names of the formats in order.
+func Names() []string {
+	names := make([]string, 0, len(fileNames))
+	for name := range fileNames {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// All returns the schemas of every format in the order of their names.
+func All() []Schema {
+	schemas := make([]Schema, 0, len(fileNames))
+	for _, name := range Names() {
+		s, _ := Get(name)
+		schemas = append(schemas, s)
+	}
+
+	return schemas
+}
@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
+
+func TestSchemasMatchFormats(t *testing.T) {
+	formats := map[string]reflect.Type{
+		AuditEvent:         reflect.TypeOf(models.AuditEvent{}),
+		CheckpointSnapshot: reflect.TypeOf(metadata.Snapshot{}),
+		DeadLetter:         reflect.TypeOf(models.DeadLetter{}),
+	}
+
+	for _, s := range All() {
+		var definition struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		}
+
+		if err := json.Unmarshal(s.Definition, &definition); err != nil {
+			t.Fatalf("schema %s must be json, err: %v", s.Name, err)
+		}
+
+		format, ok := formats[s.Name]
+		if !ok {
+			continue
+		}
+
+		properties := make([]string, 0, len(definition.Properties))
+		for property := range definition.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+
+		if fields := jsonFields(format); !reflect.DeepEqual(properties, fields) {
+			t.Errorf("schema %s must describe fields %v, properties: %v", s.Name, fields, properties)
+		}
+	}
+
+	if _, ok := Get("unknown"); ok {
+		t.Errorf("unknown schema must not be found")
+	}
+}
@@ -0,0 +1,181 @@
+package servicediscovery
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const leaderWaitInterval = 500 * time.Millisecond
+
+var errLeaderNotConnected = errors.New("leader is not connected to write checkpoints")
+
+// LeaderMetadata is the metadata of checkpoint.writer leader. The leader writes the checkpoints to its own metadata,
+// other members send their dirty offsets to the leader over rpc, so only the leader connects to the metadata store.
+type LeaderMetadata struct {
+	serviceDiscovery ServiceDiscovery
+	local            metadata.Metadata
+	newLocal         func() (metadata.Metadata, error)
+	config           *config.Dcp
+	mu               sync.Mutex
+}
+
+func (m *LeaderMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, bucketUUID string) error {
+	if m.serviceDiscovery.IsLeader() {
+		return m.saveLocal(state, dirtyOffsets, bucketUUID)
+	}
+
+	dirtyState := make(map[uint16]*models.CheckpointDocument, len(dirtyOffsets))
+	for vbID, dirty := range dirtyOffsets {
+		if doc, ok := state[vbID]; ok && dirty {
+			dirtyState[vbID] = doc
+		}
+	}
+
+	if len(dirtyState) == 0 {
+		return nil
+	}
+
+	leader, err := m.leader()
+	if err != nil {
+		return err
+	}
+
+	return leader.SaveCheckpoints(dirtyState, bucketUUID)
+}
+
+func (m *LeaderMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	if m.serviceDiscovery.IsLeader() {
+		return m.loadLocal(vbIds, bucketUUID)
+	}
+
+	leader, err := m.leader()
+	if err != nil {
+		return nil, false, err
+	}
+
+	checkpoints, exist, err := leader.LoadCheckpoints(vbIds, bucketUUID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](uint64(len(checkpoints)))
+	for vbID, doc := range checkpoints {
+		state.Store(vbID, doc)
+	}
+
+	return state, exist, nil
+}
+
+func (m *LeaderMetadata) Clear(vbIds []uint16) error {
+	if m.serviceDiscovery.IsLeader() {
+		return m.clearLocal(vbIds)
+	}
+
+	leader, err := m.leader()
+	if err != nil {
+		return err
+	}
+
+	return leader.ClearCheckpoints(vbIds)
+}
+
+// Local returns the metadata the leader writes to, it is nil until this member writes a checkpoint as the leader.
+func (m *LeaderMetadata) Local() metadata.Metadata {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.local
+}
+
+// leader waits up to checkpoint.timeout for a connected leader, since the leader can be changing.
+func (m *LeaderMetadata) leader() (Client, error) {
+	deadline := time.Now().Add(m.config.Checkpoint.Timeout)
+
+	for {
+		if leader := m.serviceDiscovery.Leader(); leader != nil && leader.Client.IsConnected() {
+			return leader.Client, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errLeaderNotConnected
+		}
+
+		time.Sleep(leaderWaitInterval)
+	}
+}
+
+func (m *LeaderMetadata) saveLocal(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, bucketUUID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	local, err := m.getLocal()
+	if err != nil {
+		return err
+	}
+
+	return local.Save(state, dirtyOffsets, bucketUUID)
+}
+
+func (m *LeaderMetadata) loadLocal(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	local, err := m.getLocal()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return local.Load(vbIds, bucketUUID)
+}
+
+func (m *LeaderMetadata) clearLocal(vbIds []uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	local, err := m.getLocal()
+	if err != nil {
+		return err
+	}
+
+	return local.Clear(vbIds)
+}
+
+func (m *LeaderMetadata) getLocal() (metadata.Metadata, error) {
+	if m.local != nil {
+		return m.local, nil
+	}
+
+	local, err := m.newLocal()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("this member is the checkpoint writer, checkpoints of all members are written to its metadata")
+
+	m.local = local
+
+	return m.local, nil
+}
+
+// NewLeaderMetadata creates the metadata of checkpoint.writer leader, newLocal is called once this member
+// becomes the leader to create the metadata it writes to.
+func NewLeaderMetadata(config *config.Dcp, serviceDiscovery ServiceDiscovery, newLocal func() (metadata.Metadata, error)) *LeaderMetadata {
+	return &LeaderMetadata{
+		config:           config,
+		serviceDiscovery: serviceDiscovery,
+		newLocal:         newLocal,
+	}
+}
@@ -0,0 +1,157 @@
+package servicediscovery
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/metadata"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+type memoryMetadata struct {
+	state map[uint16]*models.CheckpointDocument
+	mu    sync.Mutex
+}
+
+func (m *memoryMetadata) Save(state map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for vbID, dirty := range dirtyOffsets {
+		if dirty {
+			m.state[vbID] = state[vbID]
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryMetadata) Load(
+	vbIds []uint16,
+	bucketUUID string,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](0)
+	exist := false
+
+	for _, vbID := range vbIds {
+		if doc, ok := m.state[vbID]; ok {
+			state.Store(vbID, doc)
+			exist = true
+		} else {
+			state.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+		}
+	}
+
+	return state, exist, nil
+}
+
+func (m *memoryMetadata) Clear(vbIds []uint16) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, vbID := range vbIds {
+		delete(m.state, vbID)
+	}
+
+	return nil
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	return port
+}
+
+func newCheckpoint(seqNo uint64) *models.CheckpointDocument {
+	doc := models.NewEmptyCheckpointDocument("bucket")
+	doc.Checkpoint.SeqNo = seqNo
+
+	return doc
+}
+
+func TestLeaderMetadata(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{Checkpoint: config.Checkpoint{Timeout: time.Second}}
+	port := freePort(t)
+
+	leaderLocal := &memoryMetadata{state: map[uint16]*models.CheckpointDocument{}}
+	leaderDiscovery := NewServiceDiscovery(c, EventBus.New())
+	leaderDiscovery.BeLeader()
+	leaderDiscovery.SetCheckpointWriter(NewLeaderMetadata(c, leaderDiscovery, func() (metadata.Metadata, error) {
+		return leaderLocal, nil
+	}))
+
+	leaderIdentity := &models.Identity{IP: "127.0.0.1", Name: "leader"}
+	server := NewServer(port, leaderIdentity, leaderDiscovery)
+	server.Listen()
+	defer server.Shutdown()
+
+	leaderClient, err := NewClient(port, &models.Identity{IP: "127.0.0.1", Name: "follower"}, leaderIdentity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer leaderClient.Close()
+
+	followerDiscovery := NewServiceDiscovery(c, EventBus.New())
+	followerDiscovery.AssignLeader(NewService(leaderClient, leaderIdentity.Name, 0))
+
+	follower := NewLeaderMetadata(c, followerDiscovery, func() (metadata.Metadata, error) {
+		t.Fatal("follower must not create its own metadata")
+		return nil, nil
+	})
+
+	state := map[uint16]*models.CheckpointDocument{0: newCheckpoint(3), 1: newCheckpoint(5)}
+
+	if err := follower.Save(state, map[uint16]bool{0: true, 1: false}, "bucket"); err != nil {
+		t.Fatalf("checkpoint must be saved by the leader, err: %v", err)
+	}
+
+	if len(leaderLocal.state) != 1 || leaderLocal.state[0].Checkpoint.SeqNo != 3 {
+		t.Fatalf("only dirty offsets must be sent to the leader, got %v", leaderLocal.state)
+	}
+
+	loaded, exist, err := follower.Load([]uint16{0, 1}, "bucket")
+	if err != nil || !exist {
+		t.Fatalf("checkpoints must be loaded from the leader, exist: %v, err: %v", exist, err)
+	}
+
+	if doc, _ := loaded.Load(0); doc.Checkpoint.SeqNo != 3 {
+		t.Fatalf("loaded checkpoint must be 3, got %v", doc.Checkpoint.SeqNo)
+	}
+
+	if err := follower.Clear([]uint16{0}); err != nil || len(leaderLocal.state) != 0 {
+		t.Fatalf("checkpoints must be cleared by the leader, err: %v", err)
+	}
+
+	if follower.Local() != nil {
+		t.Fatalf("follower must not have a local metadata")
+	}
+}
+
+func TestLeaderMetadata_WithoutLeader(t *testing.T) {
+	c := &config.Dcp{Checkpoint: config.Checkpoint{Timeout: 100 * time.Millisecond}}
+
+	follower := NewLeaderMetadata(c, NewServiceDiscovery(c, EventBus.New()), nil)
+
+	err := follower.Save(map[uint16]*models.CheckpointDocument{0: newCheckpoint(1)}, map[uint16]bool{0: true}, "bucket")
+	if err != errLeaderNotConnected {
+		t.Fatalf("save must fail without a leader, got %v", err)
+	}
+}
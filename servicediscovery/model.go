@@ -25,6 +25,28 @@ type Rebalance struct {
 	TotalMembers int
 }
 
+type SaveCheckpoints struct {
+	From       *models.Identity
+	State      map[uint16]*models.CheckpointDocument
+	BucketUUID string
+}
+
+type LoadCheckpoints struct {
+	From       *models.Identity
+	BucketUUID string
+	VbIDs      []uint16
+}
+
+type LoadedCheckpoints struct {
+	Checkpoints map[uint16]*models.CheckpointDocument
+	Exist       bool
+}
+
+type ClearCheckpoints struct {
+	From  *models.Identity
+	VbIDs []uint16
+}
+
 type Service struct {
 	Client          Client
 	Name            string
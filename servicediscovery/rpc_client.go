@@ -19,6 +19,9 @@ type Client interface {
 	IsConnected() bool
 	Reconnect() error
 	Rebalance(memberNumber int, totalMembers int) error
+	SaveCheckpoints(state map[uint16]*models.CheckpointDocument, bucketUUID string) error
+	LoadCheckpoints(vbIDs []uint16, bucketUUID string) (map[uint16]*models.CheckpointDocument, bool, error)
+	ClearCheckpoints(vbIDs []uint16) error
 }
 
 type client struct {
@@ -112,6 +115,52 @@ func (c *client) Rebalance(memberNumber int, totalMembers int) error {
 	)
 }
 
+func (c *client) SaveCheckpoints(state map[uint16]*models.CheckpointDocument, bucketUUID string) error {
+	return helpers.Retry(
+		func() error {
+			var reply bool
+
+			return c.client.Call(
+				"Handler.SaveCheckpoints",
+				SaveCheckpoints{From: c.myIdentity, State: state, BucketUUID: bucketUUID},
+				&reply,
+			)
+		},
+		3,
+		100*time.Millisecond,
+	)
+}
+
+func (c *client) LoadCheckpoints(vbIDs []uint16, bucketUUID string) (map[uint16]*models.CheckpointDocument, bool, error) {
+	var reply LoadedCheckpoints
+
+	err := helpers.Retry(
+		func() error {
+			return c.client.Call(
+				"Handler.LoadCheckpoints",
+				LoadCheckpoints{From: c.myIdentity, VbIDs: vbIDs, BucketUUID: bucketUUID},
+				&reply,
+			)
+		},
+		3,
+		100*time.Millisecond,
+	)
+
+	return reply.Checkpoints, reply.Exist, err
+}
+
+func (c *client) ClearCheckpoints(vbIDs []uint16) error {
+	return helpers.Retry(
+		func() error {
+			var reply bool
+
+			return c.client.Call("Handler.ClearCheckpoints", ClearCheckpoints{From: c.myIdentity, VbIDs: vbIDs}, &reply)
+		},
+		3,
+		100*time.Millisecond,
+	)
+}
+
 func NewClient(port int, myIdentity *models.Identity, targetIdentity *models.Identity) (Client, error) {
 	client := &client{
 		port:           port,
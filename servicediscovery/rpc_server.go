@@ -1,6 +1,7 @@
 package servicediscovery
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/rpc"
@@ -60,6 +61,79 @@ func (rh *Handler) Rebalance(payload Rebalance, reply *bool) error {
 	return nil
 }
 
+func (rh *Handler) SaveCheckpoints(payload SaveCheckpoints, reply *bool) error {
+	writer, err := rh.checkpointWriter()
+	if err != nil {
+		*reply = false
+		return err
+	}
+
+	dirtyOffsets := make(map[uint16]bool, len(payload.State))
+	for vbID := range payload.State {
+		dirtyOffsets[vbID] = true
+	}
+
+	if err = writer.saveLocal(payload.State, dirtyOffsets, payload.BucketUUID); err != nil {
+		*reply = false
+		return err
+	}
+
+	logger.Log.Debug("saved %v checkpoints of %s", len(payload.State), payload.From.Name)
+
+	*reply = true
+
+	return nil
+}
+
+func (rh *Handler) LoadCheckpoints(payload LoadCheckpoints, reply *LoadedCheckpoints) error {
+	writer, err := rh.checkpointWriter()
+	if err != nil {
+		return err
+	}
+
+	checkpoints, exist, err := writer.loadLocal(payload.VbIDs, payload.BucketUUID)
+	if err != nil {
+		return err
+	}
+
+	*reply = LoadedCheckpoints{
+		Checkpoints: checkpoints.ToMap(),
+		Exist:       exist,
+	}
+
+	return nil
+}
+
+func (rh *Handler) ClearCheckpoints(payload ClearCheckpoints, reply *bool) error {
+	writer, err := rh.checkpointWriter()
+	if err != nil {
+		*reply = false
+		return err
+	}
+
+	if err = writer.clearLocal(payload.VbIDs); err != nil {
+		*reply = false
+		return err
+	}
+
+	*reply = true
+
+	return nil
+}
+
+func (rh *Handler) checkpointWriter() (*LeaderMetadata, error) {
+	if !rh.serviceDiscovery.IsLeader() {
+		return nil, errors.New("checkpoints are written by the leader, this member is not the leader")
+	}
+
+	writer := rh.serviceDiscovery.GetCheckpointWriter()
+	if writer == nil {
+		return nil, errors.New("checkpoint writer is not set")
+	}
+
+	return writer, nil
+}
+
 func (s *server) Listen() {
 	server := rpc.NewServer()
 
@@ -32,11 +32,16 @@ type ServiceDiscovery interface {
 	SetInfo(memberNumber int, totalMembers int)
 	BeLeader()
 	DontBeLeader()
+	IsLeader() bool
+	Leader() *Service
+	SetCheckpointWriter(writer *LeaderMetadata)
+	GetCheckpointWriter() *LeaderMetadata
 }
 
 type serviceDiscovery struct {
 	bus              EventBus.Bus
 	leaderService    *Service
+	checkpointWriter *LeaderMetadata
 	services         *wrapper.ConcurrentSwissMap[string, *Service]
 	info             *membership.Model
 	config           *config.Dcp
@@ -79,6 +84,22 @@ func (s *serviceDiscovery) DontBeLeader() {
 	s.amILeader = false
 }
 
+func (s *serviceDiscovery) IsLeader() bool {
+	return s.amILeader
+}
+
+func (s *serviceDiscovery) Leader() *Service {
+	return s.leaderService
+}
+
+func (s *serviceDiscovery) SetCheckpointWriter(writer *LeaderMetadata) {
+	s.checkpointWriter = writer
+}
+
+func (s *serviceDiscovery) GetCheckpointWriter() *LeaderMetadata {
+	return s.checkpointWriter
+}
+
 func (s *serviceDiscovery) AssignLeader(leaderService *Service) {
 	s.leaderService = leaderService
 }
@@ -0,0 +1,49 @@
+package dcp
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+type shutdownHook struct {
+	fn       func(ctx context.Context)
+	priority int
+}
+
+// runShutdownHooks runs the hooks from the lowest priority to the highest, hooks of the same priority run in
+// the order they are registered.
+func runShutdownHooks(ctx context.Context, hooks []shutdownHook) {
+	ordered := make([]shutdownHook, len(hooks))
+	copy(ordered, hooks)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].priority < ordered[j].priority
+	})
+
+	for _, hook := range ordered {
+		if ctx.Err() != nil {
+			logger.Log.Warn("shutdown hooks are not completed, err: %v", ctx.Err())
+			return
+		}
+
+		hook.fn(ctx)
+	}
+}
+
+// shutdown drains the stream and runs the shutdown hooks within shutdown.timeout, before the final checkpoint.
+func (s *dcp) shutdown() {
+	if len(s.shutdownHooks) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Shutdown.Timeout)
+	defer cancel()
+
+	s.stream.Drain(ctx)
+
+	logger.Log.Info("running %d shutdown hooks", len(s.shutdownHooks))
+
+	runShutdownHooks(ctx, s.shutdownHooks)
+}
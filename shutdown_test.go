@@ -0,0 +1,56 @@
+package dcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+func TestRunShutdownHooks(t *testing.T) {
+	var order []string
+
+	hook := func(name string) func(ctx context.Context) {
+		return func(_ context.Context) {
+			order = append(order, name)
+		}
+	}
+
+	hooks := []shutdownHook{
+		{priority: 10, fn: hook("close pools")},
+		{priority: 0, fn: hook("flush sink")},
+		{priority: 10, fn: hook("close tracer")},
+	}
+
+	runShutdownHooks(context.Background(), hooks)
+
+	expected := []string{"flush sink", "close pools", "close tracer"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("hooks must run by priority and registration order, got %v", order)
+	}
+}
+
+func TestRunShutdownHooks_StopsWhenContextIsDone(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var ran []int
+
+	hooks := []shutdownHook{
+		{priority: 0, fn: func(_ context.Context) {
+			ran = append(ran, 0)
+			cancel()
+		}},
+		{priority: 1, fn: func(_ context.Context) {
+			ran = append(ran, 1)
+		}},
+	}
+
+	runShutdownHooks(ctx, hooks)
+
+	if len(ran) != 1 {
+		t.Fatalf("hooks must not run after the context is done, ran %v", ran)
+	}
+}
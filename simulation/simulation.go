@@ -0,0 +1,192 @@
+// Package simulation compares the vBucket assignment strategies on the load of a group, so a strategy can be chosen
+// before it is enabled. It assigns the vBuckets the way the members do, and reports how even the load of the members
+// is and how many vBuckets move when a member joins or leaves.
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Trendyol/go-dcp/membership"
+	"github.com/Trendyol/go-dcp/metadata"
+)
+
+// DefaultVBuckets is the vBucket count of a couchbase bucket on linux.
+const DefaultVBuckets = 1024
+
+var ErrInvalidScenario = errors.New("invalid simulation scenario")
+
+// Scenario is the group to simulate. Load is the historical load of each vBucket, e.g. its mutation count, vBuckets
+// without a load count as 0. Weights are the weights of the members, nil when they are equal. The member which joins
+// has weight 1 and the member which leaves is the last one.
+type Scenario struct {
+	Load     map[uint16]float64
+	Weights  []int
+	Members  int
+	VBuckets int
+}
+
+// Result is the outcome of a strategy. MemberLoads are the loads of the members, LoadVariance is the variance of the
+// loads per weight of the members and MaxLoadRatio is the highest load per weight relative to the mean, 1 is even.
+type Result struct {
+	Strategy     string
+	MemberLoads  []float64
+	LoadVariance float64
+	MaxLoadRatio float64
+	MovedOnJoin  int
+	MovedOnLeave int
+}
+
+// Simulate assigns the vBuckets of the scenario with each strategy, all registered strategies when none is given.
+func Simulate(scenario Scenario, strategies ...string) ([]Result, error) {
+	if scenario.Members < 1 {
+		return nil, fmt.Errorf("%w, members must be at least 1", ErrInvalidScenario)
+	}
+
+	if scenario.Weights != nil && len(scenario.Weights) != scenario.Members {
+		return nil, fmt.Errorf("%w, weights: %d, members: %d", ErrInvalidScenario, len(scenario.Weights), scenario.Members)
+	}
+
+	for _, weight := range scenario.Weights {
+		if weight < 1 {
+			return nil, fmt.Errorf("%w, weights must be at least 1", ErrInvalidScenario)
+		}
+	}
+
+	if scenario.VBuckets == 0 {
+		scenario.VBuckets = DefaultVBuckets
+	}
+
+	if len(strategies) == 0 {
+		strategies = membership.AssignmentStrategies()
+	}
+
+	vBuckets := make([]uint16, scenario.VBuckets)
+	for i := range vBuckets {
+		vBuckets[i] = uint16(i)
+	}
+
+	results := make([]Result, 0, len(strategies))
+
+	for _, name := range strategies {
+		strategy, err := membership.GetAssignmentStrategy(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		}
+
+		results = append(results, simulate(scenario, name, strategy, vBuckets))
+	}
+
+	return results, nil
+}
+
+func simulate(scenario Scenario, name string, strategy membership.AssignmentStrategy, vBuckets []uint16) Result {
+	owners := ownersOf(assign(strategy, vBuckets, scenario.Weights, scenario.Members), len(vBuckets))
+
+	weights := scenario.Weights
+	if weights == nil {
+		weights = make([]int, scenario.Members)
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+
+	result := Result{
+		Strategy:    name,
+		MemberLoads: make([]float64, scenario.Members),
+	}
+
+	for vbID, owner := range owners {
+		result.MemberLoads[owner] += scenario.Load[uint16(vbID)]
+	}
+
+	result.LoadVariance, result.MaxLoadRatio = spreadOf(result.MemberLoads, weights)
+
+	joined := append(append([]int{}, weights...), 1)
+	result.MovedOnJoin = moved(owners, ownersOf(assign(strategy, vBuckets, joined, scenario.Members+1), len(vBuckets)))
+
+	if scenario.Members > 1 {
+		left := weights[:scenario.Members-1]
+		result.MovedOnLeave = moved(owners, ownersOf(assign(strategy, vBuckets, left, scenario.Members-1), len(vBuckets)))
+	}
+
+	return result
+}
+
+// assign passes nil for equal weights like the vBucket discovery of the members.
+func assign(strategy membership.AssignmentStrategy, vBuckets []uint16, weights []int, members int) [][]uint16 {
+	if isUniform(weights) {
+		weights = nil
+	}
+
+	return strategy.Assign(vBuckets, members, weights)
+}
+
+func isUniform(weights []int) bool {
+	for _, weight := range weights {
+		if weight != weights[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ownersOf(chunks [][]uint16, vBuckets int) []int {
+	owners := make([]int, vBuckets)
+	for member, chunk := range chunks {
+		for _, vbID := range chunk {
+			owners[vbID] = member
+		}
+	}
+
+	return owners
+}
+
+func moved(before []int, after []int) int {
+	count := 0
+	for vbID := range before {
+		if before[vbID] != after[vbID] {
+			count++
+		}
+	}
+
+	return count
+}
+
+func spreadOf(loads []float64, weights []int) (float64, float64) {
+	totalLoad, totalWeight := 0.0, 0
+	for i, load := range loads {
+		totalLoad += load
+		totalWeight += weights[i]
+	}
+
+	if totalLoad == 0 {
+		return 0, 1
+	}
+
+	mean := totalLoad / float64(totalWeight)
+
+	variance, maxRatio := 0.0, 0.0
+	for i, load := range loads {
+		perWeight := load / float64(weights[i])
+		variance += (perWeight - mean) * (perWeight - mean)
+		maxRatio = math.Max(maxRatio, perWeight/mean)
+	}
+
+	return variance / float64(len(loads)), maxRatio
+}
+
+// LoadOf returns the seqNos of the checkpoints in the snapshot as the load of the vBuckets, a seqNo is the count of
+// the mutations of its vBucket.
+func LoadOf(snapshot *metadata.Snapshot) map[uint16]float64 {
+	load := make(map[uint16]float64, len(snapshot.Checkpoints))
+	for vbID, doc := range snapshot.Checkpoints {
+		if doc != nil && doc.Checkpoint != nil {
+			load[vbID] = float64(doc.Checkpoint.SeqNo)
+		}
+	}
+
+	return load
+}
@@ -0,0 +1,86 @@
+package simulation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/membership"
+)
+
+func uniformLoad(vBuckets int) map[uint16]float64 {
+	load := make(map[uint16]float64, vBuckets)
+	for i := 0; i < vBuckets; i++ {
+		load[uint16(i)] = 10
+	}
+
+	return load
+}
+
+func TestSimulate(t *testing.T) {
+	results, err := Simulate(Scenario{Members: 4, Load: uniformLoad(DefaultVBuckets)},
+		config.MembershipAssignmentSticky, config.MembershipAssignmentRange)
+	if err != nil {
+		t.Fatalf("scenario must be simulated, err: %v", err)
+	}
+
+	sticky, ranged := results[0], results[1]
+
+	if sticky.LoadVariance != 0 || sticky.MaxLoadRatio != 1 {
+		t.Errorf("equal loads must be spread evenly, variance: %v, ratio: %v", sticky.LoadVariance, sticky.MaxLoadRatio)
+	}
+
+	if sticky.MovedOnJoin != 204 || sticky.MovedOnLeave != 256 {
+		t.Errorf("sticky must move only the vBuckets of the last member, join: %d, leave: %d",
+			sticky.MovedOnJoin, sticky.MovedOnLeave)
+	}
+
+	if ranged.MovedOnJoin <= sticky.MovedOnJoin {
+		t.Errorf("range must move more vBuckets than sticky, range: %d, sticky: %d", ranged.MovedOnJoin, sticky.MovedOnJoin)
+	}
+}
+
+func TestSimulateWeightedLoad(t *testing.T) {
+	load := uniformLoad(DefaultVBuckets)
+	for i := 0; i < 256; i++ {
+		load[uint16(i)] = 30
+	}
+
+	results, err := Simulate(Scenario{Members: 2, Weights: []int{1, 3}, Load: load}, config.MembershipAssignmentRange)
+	if err != nil {
+		t.Fatalf("scenario must be simulated, err: %v", err)
+	}
+
+	if results[0].MemberLoads[0] != 7680 || results[0].MemberLoads[1] != 7680 {
+		t.Errorf("member loads are not set to expected values, loads: %v", results[0].MemberLoads)
+	}
+
+	if results[0].MaxLoadRatio != 2 {
+		t.Errorf("max load ratio is not set to expected value, ratio: %v", results[0].MaxLoadRatio)
+	}
+}
+
+func TestSimulateAllStrategies(t *testing.T) {
+	results, err := Simulate(Scenario{Members: 3, Load: uniformLoad(DefaultVBuckets)})
+	if err != nil {
+		t.Fatalf("scenario must be simulated, err: %v", err)
+	}
+
+	if len(results) != len(membership.AssignmentStrategies()) {
+		t.Errorf("all strategies must be simulated, simulated: %d", len(results))
+	}
+}
+
+func TestSimulateInvalid(t *testing.T) {
+	if _, err := Simulate(Scenario{Members: 0}); !errors.Is(err, ErrInvalidScenario) {
+		t.Errorf("scenario without members must be rejected, err: %v", err)
+	}
+
+	if _, err := Simulate(Scenario{Members: 2, Weights: []int{1}}); !errors.Is(err, ErrInvalidScenario) {
+		t.Errorf("scenario with missing weights must be rejected, err: %v", err)
+	}
+
+	if _, err := Simulate(Scenario{Members: 2}, "unknown"); !errors.Is(err, membership.ErrAssignmentStrategyNotRegistered) {
+		t.Errorf("unknown strategy must be rejected, err: %v", err)
+	}
+}
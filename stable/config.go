@@ -0,0 +1,76 @@
+package stable
+
+import (
+	"errors"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Config holds the settings of the connection, the group and the metadata, the other settings keep their defaults.
+// A configuration file passed to NewDcp or NewReader accepts every setting of the client.
+type Config struct {
+	MetadataConfig   map[string]string
+	Hosts            []string
+	CollectionNames  []string
+	Username         string
+	Password         string
+	BucketName       string
+	ScopeName        string
+	GroupName        string
+	MembershipType   string
+	MetadataType     string
+	CheckpointType   string
+	SecureConnection bool
+}
+
+func (c *Config) toDcpConfig() config.Dcp {
+	cfg := config.Dcp{
+		Hosts:            c.Hosts,
+		Username:         c.Username,
+		Password:         c.Password,
+		BucketName:       c.BucketName,
+		ScopeName:        c.ScopeName,
+		CollectionNames:  c.CollectionNames,
+		SecureConnection: c.SecureConnection,
+		Metadata:         config.Metadata{Type: c.MetadataType, Config: c.MetadataConfig},
+		Checkpoint:       config.Checkpoint{Type: c.CheckpointType},
+	}
+
+	cfg.Dcp.Group.Name = c.GroupName
+	cfg.Dcp.Group.Membership.Type = c.MembershipType
+
+	return cfg
+}
+
+func newConfig(cfg *config.Dcp) *Config {
+	return &Config{
+		Hosts:            cfg.Hosts,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		BucketName:       cfg.BucketName,
+		ScopeName:        cfg.ScopeName,
+		CollectionNames:  cfg.CollectionNames,
+		SecureConnection: cfg.SecureConnection,
+		GroupName:        cfg.Dcp.Group.Name,
+		MembershipType:   cfg.Dcp.Group.Membership.Type,
+		MetadataType:     cfg.Metadata.Type,
+		MetadataConfig:   cfg.Metadata.Config,
+		CheckpointType:   cfg.Checkpoint.Type,
+	}
+}
+
+// dcpConfigOf returns the configuration file path or the client config of the config.
+func dcpConfigOf(cfg any) (any, error) {
+	switch v := cfg.(type) {
+	case string:
+		return v, nil
+	case *Config:
+		return v.toDcpConfig(), nil
+	case Config:
+		return v.toDcpConfig(), nil
+	default:
+		return nil, ErrInvalidConfig
+	}
+}
@@ -0,0 +1,53 @@
+package stable
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+func TestDcpConfigOf(t *testing.T) {
+	cfg := Config{
+		Hosts:          []string{"localhost:8091"},
+		Username:       "user",
+		Password:       "password",
+		BucketName:     "dcp-test",
+		GroupName:      "group",
+		MembershipType: config.MembershipTypeCouchbase,
+		MetadataType:   "file",
+		MetadataConfig: map[string]string{"fileName": "checkpoints.json"},
+		CheckpointType: "manual",
+	}
+
+	tests := []struct {
+		cfg  any
+		name string
+	}{
+		{name: "config", cfg: cfg},
+		{name: "config pointer", cfg: &cfg},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dcpConfig, err := dcpConfigOf(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := dcpConfig.(config.Dcp)
+			if !reflect.DeepEqual(newConfig(&c), &cfg) {
+				t.Errorf("config must be kept by the client config, got %+v", newConfig(&c))
+			}
+		})
+	}
+
+	if path, err := dcpConfigOf("config.yml"); err != nil || path != "config.yml" {
+		t.Errorf("configuration file path must be passed as is, got %v", path)
+	}
+
+	if _, err := dcpConfigOf(config.Dcp{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("client config must be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Package stable is the stable API of go-dcp. It exposes only the types defined here, so the internals of the
+// client like offset storage, the config and the couchbase driver can change without breaking consumers.
+package stable
+
+import (
+	godcp "github.com/Trendyol/go-dcp"
+)
+
+type Dcp interface {
+	Start()
+	Close()
+	WaitUntilReady() chan struct{}
+	Commit()
+	CommitAsync() <-chan error
+	Pause()
+	Resume()
+	GetOffsets() map[uint16]Offset
+	GetConfig() *Config
+}
+
+type client struct {
+	dcp godcp.Dcp
+}
+
+func (c *client) Start() {
+	c.dcp.Start()
+}
+
+func (c *client) Close() {
+	c.dcp.Close()
+}
+
+func (c *client) WaitUntilReady() chan struct{} {
+	return c.dcp.WaitUntilReady()
+}
+
+func (c *client) Commit() {
+	c.dcp.Commit()
+}
+
+func (c *client) CommitAsync() <-chan error {
+	return c.dcp.CommitAsync()
+}
+
+func (c *client) Pause() {
+	c.dcp.Pause()
+}
+
+func (c *client) Resume() {
+	c.dcp.Resume()
+}
+
+// GetOffsets returns the offsets of the vBuckets streamed by this member.
+func (c *client) GetOffsets() map[uint16]Offset {
+	snapshot := c.dcp.GetMetricsSnapshot()
+
+	offsets := make(map[uint16]Offset, len(snapshot.Offsets))
+	for vbID, offset := range snapshot.Offsets {
+		offset := offset
+		offsets[vbID] = newOffset(&offset)
+	}
+
+	return offsets
+}
+
+func (c *client) GetConfig() *Config {
+	return newConfig(c.dcp.GetConfig())
+}
+
+// NewDcp creates a new Dcp client
+//
+// config: path to a configuration file or a Config
+// consumer: receives the mutations, deletions and expirations of the documents
+func NewDcp(cfg any, consumer Consumer) (Dcp, error) {
+	dcpConfig, err := dcpConfigOf(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := godcp.NewExtendedDcp(dcpConfig, &consumerAdapter{consumer: consumer})
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{dcp: d}, nil
+}
@@ -0,0 +1,179 @@
+package stable
+
+import (
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type EventType int
+
+const (
+	Mutation EventType = iota
+	Deletion
+	Expiration
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Mutation:
+		return "mutation"
+	case Deletion:
+		return "deletion"
+	case Expiration:
+		return "expiration"
+	default:
+		return "unknown"
+	}
+}
+
+// Offset is the position of a vBucket stream.
+type Offset struct {
+	VbUUID      uint64
+	SeqNo       uint64
+	StartSeqNo  uint64
+	EndSeqNo    uint64
+	LatestSeqNo uint64
+}
+
+// Event is a change of a document. Value is empty for deletions and expirations. Compressed is true when the value
+// is delivered snappy compressed with dcp.compression.passthrough.
+type Event struct {
+	EventTime      time.Time
+	CollectionName string
+	Key            []byte
+	Value          []byte
+	Offset         Offset
+	Cas            uint64
+	RevNo          uint64
+	Type           EventType
+	Flags          uint32
+	VbID           uint16
+	Datatype       uint8
+	Compressed     bool
+}
+
+// DecompressedValue returns the value, it is decompressed with each call when the value is compressed.
+func (e *Event) DecompressedValue() ([]byte, error) {
+	if !e.Compressed || len(e.Value) == 0 {
+		return e.Value, nil
+	}
+
+	return snappy.Decode(nil, e.Value)
+}
+
+// Context carries an event to the consumer. Ack marks the event as processed, so its offset can be
+// saved with the next checkpoint, Commit saves the checkpoint and returns once it is persisted.
+type Context struct {
+	ctx   *models.ListenerContext
+	Event Event
+}
+
+func (c *Context) Ack() {
+	c.ctx.Ack()
+}
+
+func (c *Context) Commit() {
+	c.ctx.Commit()
+}
+
+// CommitAsync commits without waiting, the channel receives the result once the checkpoint is saved.
+func (c *Context) CommitAsync() <-chan error {
+	return c.ctx.CommitAsync()
+}
+
+type Consumer interface {
+	Consume(ctx *Context)
+}
+
+type ConsumerFunc func(ctx *Context)
+
+func (f ConsumerFunc) Consume(ctx *Context) {
+	f(ctx)
+}
+
+func newOffset(offset *models.Offset) Offset {
+	if offset == nil {
+		return Offset{}
+	}
+
+	o := Offset{
+		VbUUID:      uint64(offset.VbUUID),
+		SeqNo:       offset.SeqNo,
+		LatestSeqNo: offset.LatestSeqNo,
+	}
+
+	if offset.SnapshotMarker != nil {
+		o.StartSeqNo = offset.StartSeqNo
+		o.EndSeqNo = offset.EndSeqNo
+	}
+
+	return o
+}
+
+// newEvent converts the document events of the stream, the others are not part of this package.
+func newEvent(event interface{}) (Event, bool) {
+	switch e := event.(type) {
+	case models.DcpMutation:
+		return Event{
+			Type:           Mutation,
+			EventTime:      e.EventTime,
+			CollectionName: e.CollectionName,
+			Key:            e.Key,
+			Value:          e.Value,
+			Offset:         newOffset(e.Offset),
+			Cas:            e.Cas,
+			RevNo:          e.RevNo,
+			Flags:          e.Flags,
+			VbID:           e.VbID,
+			Datatype:       e.Datatype,
+			Compressed:     e.Compressed,
+		}, true
+	case models.DcpDeletion:
+		return Event{
+			Type:           Deletion,
+			EventTime:      e.EventTime,
+			CollectionName: e.CollectionName,
+			Key:            e.Key,
+			Value:          e.Value,
+			Offset:         newOffset(e.Offset),
+			Cas:            e.Cas,
+			RevNo:          e.RevNo,
+			VbID:           e.VbID,
+			Datatype:       e.Datatype,
+			Compressed:     e.Compressed,
+		}, true
+	case models.DcpExpiration:
+		return Event{
+			Type:           Expiration,
+			EventTime:      e.EventTime,
+			CollectionName: e.CollectionName,
+			Key:            e.Key,
+			Offset:         newOffset(e.Offset),
+			Cas:            e.Cas,
+			RevNo:          e.RevNo,
+			VbID:           e.VbID,
+		}, true
+	default:
+		return Event{}, false
+	}
+}
+
+type consumerAdapter struct {
+	consumer Consumer
+}
+
+// ConsumeEvent acks the events which are not document changes, they never reach the consumer.
+func (c *consumerAdapter) ConsumeEvent(ctx *models.ListenerContext) {
+	event, ok := newEvent(ctx.Event)
+	if !ok {
+		ctx.Ack()
+		return
+	}
+
+	c.consumer.Consume(&Context{ctx: ctx, Event: event})
+}
+
+func (c *consumerAdapter) TrackOffset(uint16, *models.Offset) {}
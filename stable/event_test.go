@@ -0,0 +1,78 @@
+package stable
+
+import (
+	"testing"
+
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/couchbase/gocbcore/v10"
+	"github.com/golang/snappy"
+)
+
+func TestConsumerAdapter(t *testing.T) {
+	var received []Event
+
+	adapter := &consumerAdapter{consumer: ConsumerFunc(func(ctx *Context) {
+		received = append(received, ctx.Event)
+		ctx.Ack()
+	})}
+
+	acked := 0
+	newContext := func(event interface{}) *models.ListenerContext {
+		return &models.ListenerContext{Event: event, Ack: func() { acked++ }}
+	}
+
+	adapter.ConsumeEvent(newContext(models.DcpMutation{
+		DcpMutation: &gocbcore.DcpMutation{Key: []byte("key"), Value: []byte(`{}`), VbID: 7, RevNo: 1},
+		Offset: &models.Offset{
+			SnapshotMarker: &models.SnapshotMarker{StartSeqNo: 10, EndSeqNo: 20},
+			VbUUID:         99,
+			SeqNo:          12,
+		},
+		CollectionName: "_default",
+	}))
+	adapter.ConsumeEvent(newContext(models.DcpExpiration{
+		DcpExpiration: &gocbcore.DcpExpiration{Key: []byte("key"), VbID: 7},
+	}))
+	adapter.ConsumeEvent(newContext(models.DcpSeqNoAdvanced{
+		DcpSeqNoAdvanced: &gocbcore.DcpSeqNoAdvanced{VbID: 7, SeqNo: 13},
+	}))
+
+	if len(received) != 2 || acked != 3 {
+		t.Fatalf("expected 2 events and 3 acks, got %d events and %d acks", len(received), acked)
+	}
+
+	mutation := received[0]
+	if mutation.Type != Mutation || string(mutation.Key) != "key" || mutation.VbID != 7 || mutation.CollectionName != "_default" {
+		t.Fatalf("unexpected mutation %+v", mutation)
+	}
+
+	if mutation.Offset != (Offset{VbUUID: 99, SeqNo: 12, StartSeqNo: 10, EndSeqNo: 20}) {
+		t.Fatalf("unexpected offset %+v", mutation.Offset)
+	}
+
+	if received[1].Type != Expiration || received[1].Offset != (Offset{}) {
+		t.Fatalf("unexpected expiration %+v", received[1])
+	}
+}
+
+func TestNewEventWithCompressedValue(t *testing.T) {
+	value := []byte(`{"id":1}`)
+
+	event, ok := newEvent(models.DcpMutation{
+		DcpMutation: &gocbcore.DcpMutation{Key: []byte("key"), Value: snappy.Encode(nil, value)},
+		Compressed:  true,
+	})
+	if !ok || !event.Compressed {
+		t.Fatalf("compressed flag must be passed with the event, got %+v", event)
+	}
+
+	decompressed, err := event.DecompressedValue()
+	if err != nil || string(decompressed) != string(value) {
+		t.Fatalf("expected %s, got %s, err: %v", value, decompressed, err)
+	}
+
+	plain := Event{Value: value}
+	if decompressed, _ := plain.DecompressedValue(); string(decompressed) != string(value) {
+		t.Fatalf("uncompressed value must be returned as it is, got %s", decompressed)
+	}
+}
@@ -0,0 +1,176 @@
+package stable
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	godcp "github.com/Trendyol/go-dcp"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+var ErrReaderClosed = errors.New("reader is closed")
+
+// Reader is the pull based API of the client for stream processors which read events at their own pace and ack
+// them asynchronously. Acks may come in any order, the offset of a vBucket advances only over its events which are
+// acked without a gap, so an event which is read but not acked is streamed again after a restart.
+type Reader interface {
+	// Start starts the client in the background and returns when the streams are open, later calls return at once.
+	Start()
+	// Read blocks till an event is streamed, ctx is done or the reader is closed.
+	Read(ctx context.Context) (*Message, error)
+	Close()
+	GetOffsets() map[uint16]Offset
+}
+
+// Message is an event read from a Reader. Ack marks it as processed, acks of messages read before a rebalance are
+// ignored since their vBuckets are streamed again from the checkpoint.
+type Message struct {
+	ctx        *models.ListenerContext
+	reader     *reader
+	Event      Event
+	generation uint64
+	acked      bool
+}
+
+func (m *Message) Ack() {
+	m.reader.ack(m)
+}
+
+type reader struct {
+	client     *client
+	messages   chan *Message
+	closeCh    chan struct{}
+	discardCh  chan struct{}
+	pending    map[uint16][]*Message
+	generation uint64
+	lock       sync.Mutex
+	startOnce  sync.Once
+	closeOnce  sync.Once
+}
+
+func (r *reader) Start() {
+	r.startOnce.Do(func() {
+		go r.client.Start()
+		<-r.client.WaitUntilReady()
+	})
+}
+
+func (r *reader) Read(ctx context.Context) (*Message, error) {
+	select {
+	case message := <-r.messages:
+		return message, nil
+	case <-r.closeCh:
+		return nil, ErrReaderClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *reader) Close() {
+	r.closeOnce.Do(func() {
+		close(r.closeCh)
+		r.client.Close()
+	})
+}
+
+func (r *reader) GetOffsets() map[uint16]Offset {
+	return r.client.GetOffsets()
+}
+
+// ConsumeEvent passes the document events to Read and waits while the buffer is full, so a slow processor holds
+// the stream back. Other events are acked once the messages read before them are acked.
+func (r *reader) ConsumeEvent(ctx *models.ListenerContext) {
+	event, ok := newEvent(ctx.Event)
+
+	r.lock.Lock()
+	message := &Message{ctx: ctx, reader: r, Event: event, generation: r.generation}
+	r.pending[ctx.VbID] = append(r.pending[ctx.VbID], message)
+	discardCh := r.discardCh
+	r.lock.Unlock()
+
+	if !ok {
+		r.ack(message)
+		return
+	}
+
+	select {
+	case r.messages <- message:
+	case <-discardCh:
+	case <-r.closeCh:
+	}
+}
+
+func (r *reader) TrackOffset(uint16, *models.Offset) {}
+
+// Discard drops the messages of the closed streams, waiting and buffered ones are not read anymore and the read ones
+// are not acked.
+func (r *reader) Discard() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.generation++
+	r.pending = map[uint16][]*Message{}
+
+	close(r.discardCh)
+	r.discardCh = make(chan struct{})
+
+	for {
+		select {
+		case <-r.messages:
+		default:
+			return
+		}
+	}
+}
+
+func (r *reader) ack(message *Message) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if message.generation != r.generation || message.acked {
+		return
+	}
+
+	message.acked = true
+
+	pending := r.pending[message.ctx.VbID]
+	for len(pending) > 0 && pending[0].acked {
+		pending[0].ctx.Ack()
+		pending[0] = nil
+		pending = pending[1:]
+	}
+
+	r.pending[message.ctx.VbID] = pending
+}
+
+func newReader(bufferSize int) *reader {
+	return &reader{
+		messages:  make(chan *Message, bufferSize),
+		closeCh:   make(chan struct{}),
+		discardCh: make(chan struct{}),
+		pending:   map[uint16][]*Message{},
+	}
+}
+
+// NewReader creates a new Reader
+//
+// config: path to a configuration file or a Config
+// bufferSize: count of the events streamed ahead of Read
+func NewReader(cfg any, bufferSize int) (Reader, error) {
+	dcpConfig, err := dcpConfigOf(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReader(bufferSize)
+
+	d, err := godcp.NewExtendedDcp(dcpConfig, r)
+	if err != nil {
+		return nil, err
+	}
+
+	r.client = &client{dcp: d}
+
+	return r, nil
+}
@@ -0,0 +1,85 @@
+package stable
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/couchbase/gocbcore/v10"
+)
+
+func TestReaderAcksInOrder(t *testing.T) {
+	r := newReader(4)
+
+	var acked []uint64
+	newContext := func(seqNo uint64) *models.ListenerContext {
+		return &models.ListenerContext{
+			Event: models.DcpMutation{
+				DcpMutation: &gocbcore.DcpMutation{Key: []byte("key"), VbID: 1, SeqNo: seqNo},
+				Offset:      &models.Offset{SeqNo: seqNo},
+			},
+			Ack:  func() { acked = append(acked, seqNo) },
+			VbID: 1,
+		}
+	}
+
+	for seqNo := uint64(1); seqNo <= 3; seqNo++ {
+		r.ConsumeEvent(newContext(seqNo))
+	}
+
+	var messages []*Message
+	for i := 0; i < 3; i++ {
+		message, err := r.Read(context.Background())
+		if err != nil {
+			t.Fatalf("message must be read, err: %v", err)
+		}
+		messages = append(messages, message)
+	}
+
+	messages[2].Ack()
+	messages[1].Ack()
+
+	if len(acked) != 0 {
+		t.Fatalf("offset must not advance over an unacked message, acked: %v", acked)
+	}
+
+	messages[0].Ack()
+
+	if len(acked) != 3 || acked[2] != 3 {
+		t.Errorf("messages must be acked in order, acked: %v", acked)
+	}
+}
+
+func TestReaderDiscard(t *testing.T) {
+	r := newReader(1)
+
+	acked := 0
+	r.ConsumeEvent(&models.ListenerContext{
+		Event: models.DcpMutation{DcpMutation: &gocbcore.DcpMutation{VbID: 1}, Offset: &models.Offset{}},
+		Ack:   func() { acked++ },
+		VbID:  1,
+	})
+
+	message, _ := r.Read(context.Background())
+
+	r.ConsumeEvent(&models.ListenerContext{
+		Event: models.DcpMutation{DcpMutation: &gocbcore.DcpMutation{VbID: 1}, Offset: &models.Offset{}},
+		Ack:   func() { acked++ },
+		VbID:  1,
+	})
+
+	r.Discard()
+	message.Ack()
+
+	if acked != 0 {
+		t.Errorf("messages read before a discard must not be acked")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := r.Read(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("buffered messages must be discarded, err: %v", err)
+	}
+}
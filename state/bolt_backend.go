@@ -0,0 +1,86 @@
+package state
+
+import (
+	"encoding/binary"
+
+	"github.com/bytedance/sonic"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// boltBackend keeps the snapshots in a bbolt file, one key for each vBucket of the group bucket.
+type boltBackend struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+func (b *boltBackend) Save(snapshots map[uint16]*models.StateSnapshot) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(b.bucket)
+		if err != nil {
+			return err
+		}
+
+		for vbID, snapshot := range snapshots {
+			value, err := sonic.Marshal(snapshot)
+			if err != nil {
+				return err
+			}
+
+			if err = bucket.Put(vbKey(vbID), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *boltBackend) Load(vbIds []uint16) (map[uint16]*models.StateSnapshot, error) {
+	snapshots := map[uint16]*models.StateSnapshot{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		if bucket == nil {
+			return nil
+		}
+
+		for _, vbID := range vbIds {
+			value := bucket.Get(vbKey(vbID))
+			if value == nil {
+				continue
+			}
+
+			var snapshot models.StateSnapshot
+			if err := sonic.Unmarshal(value, &snapshot); err != nil {
+				return err
+			}
+
+			snapshots[vbID] = &snapshot
+		}
+
+		return nil
+	})
+
+	return snapshots, err
+}
+
+func vbKey(vbID uint16) []byte {
+	key := make([]byte, 2)
+	binary.BigEndian.PutUint16(key, vbID)
+	return key
+}
+
+// NewBoltBackend opens the bbolt file, snapshots of each group are kept in a separate bucket.
+func NewBoltBackend(fileName string, groupName string) (Backend, error) {
+	db, err := bolt.Open(fileName, 0o600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltBackend{
+		db:     db,
+		bucket: []byte(groupName),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+func TestBoltBackendSaveAndLoad(t *testing.T) {
+	backend, err := NewBoltBackend(filepath.Join(t.TempDir(), "state.db"), "group")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = backend.Save(map[uint16]*models.StateSnapshot{
+		7: {
+			Checkpoint: &models.CheckpointDocument{
+				Checkpoint: &models.CheckpointDocumentCheckpoint{SeqNo: 42},
+				BucketUUID: "bucket",
+			},
+			Values: map[string][]byte{"count": []byte("3")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := backend.Load([]uint16{7, 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 1 {
+		t.Fatalf("only saved vBuckets must be loaded, got %d", len(snapshots))
+	}
+
+	if snapshots[7].Checkpoint.Checkpoint.SeqNo != 42 || string(snapshots[7].Values["count"]) != "3" {
+		t.Errorf("snapshot must be loaded as saved")
+	}
+}
@@ -0,0 +1,45 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// memoryBackend keeps the snapshots in the process, they survive rebalances but not restarts.
+type memoryBackend struct {
+	snapshots map[uint16]*models.StateSnapshot
+	lock      sync.RWMutex
+}
+
+func (b *memoryBackend) Save(snapshots map[uint16]*models.StateSnapshot) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for vbID, snapshot := range snapshots {
+		b.snapshots[vbID] = snapshot
+	}
+
+	return nil
+}
+
+func (b *memoryBackend) Load(vbIds []uint16) (map[uint16]*models.StateSnapshot, error) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	snapshots := map[uint16]*models.StateSnapshot{}
+
+	for _, vbID := range vbIds {
+		if snapshot, ok := b.snapshots[vbID]; ok {
+			snapshots[vbID] = snapshot
+		}
+	}
+
+	return snapshots, nil
+}
+
+func NewMemoryBackend() Backend {
+	return &memoryBackend{
+		snapshots: map[uint16]*models.StateSnapshot{},
+	}
+}
@@ -0,0 +1,19 @@
+package state
+
+import "github.com/Trendyol/go-dcp/models"
+
+// Backend persists the state snapshots of vBuckets. Save must write the snapshots atomically,
+// a snapshot holds the checkpoint of its vBucket, so the state and the offset never diverge.
+type Backend interface {
+	Save(snapshots map[uint16]*models.StateSnapshot) error
+	Load(vbIds []uint16) (map[uint16]*models.StateSnapshot, error)
+}
+
+// Handoff moves the snapshots of vBuckets between members. Snapshots are published when the stream closes
+// for a rebalance, and the next owner fetches them, so it resumes without rebuilding the state from scratch.
+// The next owner removes the snapshots once they are saved to its backend.
+type Handoff interface {
+	Publish(snapshots map[uint16]*models.StateSnapshot) error
+	Fetch(vbIds []uint16) (map[uint16]*models.StateSnapshot, error)
+	Remove(vbIds []uint16) error
+}
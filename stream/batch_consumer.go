@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type vbBatch struct {
+	timer       *time.Timer
+	commit      func()
+	commitAsync func() <-chan error
+	state       models.State
+	events      []interface{}
+	ids         []string
+	metadata    []map[string]string
+	acks        []func()
+	lock        sync.Mutex
+	generation  uint64
+	// snapshotComplete is true when the last event of the batch is the last of its snapshot.
+	snapshotComplete bool
+}
+
+// batchConsumer collects the events of each vBucket and passes them to the batch consumer
+// when the batch size is reached or the window of the first event is over, or at the end of a snapshot with
+// dcp.listener.batch.flushOnSnapshotEnd.
+type batchConsumer struct {
+	consumer models.BatchConsumer
+	config   *config.Dcp
+	batches  map[uint16]*vbBatch
+	lock     sync.Mutex
+}
+
+func (c *batchConsumer) getBatch(vbID uint16) *vbBatch {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	b, ok := c.batches[vbID]
+	if !ok {
+		b = &vbBatch{}
+		c.batches[vbID] = b
+	}
+
+	return b
+}
+
+func (c *batchConsumer) ConsumeEvent(ctx *models.ListenerContext) {
+	b := c.getBatch(ctx.VbID)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.events = append(b.events, ctx.Event)
+	b.ids = append(b.ids, ctx.ID)
+	b.metadata = append(b.metadata, ctx.Metadata)
+	b.acks = append(b.acks, ctx.Ack)
+	b.state = ctx.State
+	b.commit = ctx.Commit
+	b.commitAsync = ctx.CommitAsync
+	b.snapshotComplete = ctx.SnapshotComplete
+
+	if len(b.events) >= c.config.Dcp.Listener.Batch.Size || (b.snapshotComplete && c.config.Dcp.Listener.Batch.FlushOnSnapshotEnd) {
+		c.flush(ctx.VbID, b)
+		return
+	}
+
+	if b.timer == nil {
+		generation := b.generation
+		b.timer = time.AfterFunc(c.config.Dcp.Listener.Batch.Window, func() {
+			b.lock.Lock()
+			defer b.lock.Unlock()
+
+			if b.generation == generation {
+				c.flush(ctx.VbID, b)
+			}
+		})
+	}
+}
+
+// flush must be called with the lock of the batch held.
+func (c *batchConsumer) flush(vbID uint16, b *vbBatch) {
+	events, ids, metadata, acks := b.events, b.ids, b.metadata, b.acks
+	commit, commitAsync, state := b.commit, b.commitAsync, b.state
+	snapshotComplete := b.snapshotComplete
+	c.reset(b)
+
+	if len(events) == 0 {
+		return
+	}
+
+	c.consumer.ConsumeBatch(&models.BatchListenerContext{
+		Commit:           commit,
+		CommitAsync:      commitAsync,
+		Ack:              ackAll(acks),
+		State:            state,
+		Events:           events,
+		IDs:              ids,
+		Metadata:         metadata,
+		Bucket:           c.config.BucketName,
+		SnapshotComplete: snapshotComplete,
+		VbID:             vbID,
+	})
+}
+
+// ackAll acks every event of the batch in order. Acking only the last one would set the offset of the batch, but
+// leave the other events in flight with dcp.listener.scheduler.maxInFlight.
+func ackAll(acks []func()) func() {
+	return func() {
+		for _, ack := range acks {
+			if ack != nil {
+				ack()
+			}
+		}
+	}
+}
+
+func (c *batchConsumer) reset(b *vbBatch) {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	b.generation++
+	b.events, b.ids, b.metadata, b.acks = nil, nil, nil, nil
+	b.commit, b.commitAsync, b.state = nil, nil, nil
+	b.snapshotComplete = false
+}
+
+func (c *batchConsumer) TrackOffset(_ uint16, _ *models.Offset) {}
+
+func (c *batchConsumer) Discard() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, b := range c.batches {
+		b.lock.Lock()
+		c.reset(b)
+		b.lock.Unlock()
+	}
+}
+
+// NewBatchConsumer adapts the batch consumer to models.Consumer.
+// The batch settings are read from the config on each event, so defaults applied later are used.
+func NewBatchConsumer(consumer models.BatchConsumer, config *config.Dcp) models.Consumer {
+	return &batchConsumer{
+		consumer: consumer,
+		config:   config,
+		batches:  map[uint16]*vbBatch{},
+	}
+}
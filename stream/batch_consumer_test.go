@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type recordingBatchConsumer struct {
+	batches []*models.BatchListenerContext
+	lock    sync.Mutex
+}
+
+func (c *recordingBatchConsumer) ConsumeBatch(ctx *models.BatchListenerContext) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.batches = append(c.batches, ctx)
+}
+
+func (c *recordingBatchConsumer) sizes() []int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sizes := make([]int, 0, len(c.batches))
+	for _, batch := range c.batches {
+		sizes = append(sizes, len(batch.Events))
+	}
+
+	return sizes
+}
+
+func TestBatchConsumer(t *testing.T) {
+	tests := []struct {
+		name               string
+		snapshotComplete   []bool
+		expectedSizes      []int
+		size               int
+		flushOnSnapshotEnd bool
+	}{
+		{
+			name:             "batches are flushed when they are full",
+			snapshotComplete: make([]bool, 5),
+			size:             2,
+			expectedSizes:    []int{2, 2},
+		},
+		{
+			name:               "batch is flushed at the end of a snapshot",
+			snapshotComplete:   []bool{false, true, false},
+			size:               10,
+			flushOnSnapshotEnd: true,
+			expectedSizes:      []int{2},
+		},
+		{
+			name:             "end of a snapshot is ignored without flushOnSnapshotEnd",
+			snapshotComplete: []bool{false, true, false},
+			size:             10,
+			expectedSizes:    []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config.Dcp{}
+			c.Dcp.Listener.Batch = config.DCPListenerBatch{
+				Size: tt.size, Window: time.Hour, FlushOnSnapshotEnd: tt.flushOnSnapshotEnd,
+			}
+
+			recorder := &recordingBatchConsumer{}
+			consumer := NewBatchConsumer(recorder, c)
+
+			for i, snapshotComplete := range tt.snapshotComplete {
+				consumer.ConsumeEvent(&models.ListenerContext{Event: i, SnapshotComplete: snapshotComplete})
+			}
+
+			if sizes := recorder.sizes(); !reflect.DeepEqual(sizes, tt.expectedSizes) {
+				t.Fatalf("expected batch sizes %v, got %v", tt.expectedSizes, sizes)
+			}
+
+			consumer.(models.BufferedConsumer).Discard()
+		})
+	}
+}
+
+func TestBatchConsumer_Window(t *testing.T) {
+	c := &config.Dcp{}
+	c.Dcp.Listener.Batch = config.DCPListenerBatch{Size: 10, Window: 10 * time.Millisecond}
+
+	recorder := &recordingBatchConsumer{}
+	consumer := NewBatchConsumer(recorder, c)
+
+	consumer.ConsumeEvent(&models.ListenerContext{Event: 1, VbID: 0})
+	consumer.ConsumeEvent(&models.ListenerContext{Event: 2, VbID: 1})
+	consumer.ConsumeEvent(&models.ListenerContext{Event: 3, VbID: 0})
+
+	waitFor(t, func() bool { return len(recorder.sizes()) == 2 })
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	for _, batch := range recorder.batches {
+		expected := map[uint16][]interface{}{0: {1, 3}, 1: {2}}[batch.VbID]
+		if !reflect.DeepEqual(batch.Events, expected) {
+			t.Fatalf("events of vbID: %d must be batched in order, got %v", batch.VbID, batch.Events)
+		}
+	}
+}
+
+func TestBatchConsumer_Discard(t *testing.T) {
+	c := &config.Dcp{}
+	c.Dcp.Listener.Batch = config.DCPListenerBatch{Size: 10, Window: 10 * time.Millisecond}
+
+	recorder := &recordingBatchConsumer{}
+	consumer := NewBatchConsumer(recorder, c)
+
+	consumer.ConsumeEvent(&models.ListenerContext{Event: 1})
+	consumer.(models.BufferedConsumer).Discard()
+
+	time.Sleep(30 * time.Millisecond)
+
+	if sizes := recorder.sizes(); len(sizes) != 0 {
+		t.Fatalf("discarded events must not be flushed, got %v", sizes)
+	}
+}
+
+func TestBatchConsumer_AckReleasesEveryEventWithMaxInFlight(t *testing.T) {
+	c := &config.Dcp{}
+	c.Dcp.Listener.Batch = config.DCPListenerBatch{Size: 2, Window: time.Hour}
+
+	scheduler := newFairScheduler(1, 1, 2, 10, time.Minute, newPauseGate())
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	recorder := &recordingBatchConsumer{}
+	consumer := NewBatchConsumer(recorder, c)
+
+	var acked []int
+	for i := 0; i < 4; i++ {
+		i := i
+		scheduler.Schedule(0, func() {
+			scheduler.Acquire(0)
+			consumer.ConsumeEvent(&models.ListenerContext{Event: i, Ack: func() {
+				acked = append(acked, i)
+				scheduler.Release(0)
+			}})
+		})
+
+		if i%2 == 1 {
+			waitFor(t, func() bool { return len(recorder.sizes()) == i/2+1 })
+
+			recorder.lock.Lock()
+			batch := recorder.batches[i/2]
+			recorder.lock.Unlock()
+
+			batch.Ack()
+		}
+	}
+
+	if expected := []int{0, 1, 2, 3}; !reflect.DeepEqual(acked, expected) {
+		t.Fatalf("batch ack must ack every event of the batch, expected %v, got %v", expected, acked)
+	}
+
+	if metric := scheduler.GetMetric()[0]; metric.InFlight != 0 {
+		t.Fatalf("batch ack must release every event of the batch, got %+v", metric)
+	}
+}
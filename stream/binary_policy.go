@@ -0,0 +1,86 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/bytedance/sonic"
+	"github.com/couchbase/gocbcore/v10/memd"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+var ErrBinaryDocument = errors.New("binary document")
+
+type binaryAction int
+
+const (
+	binaryForward binaryAction = iota
+	binarySkip
+	binaryDeadLetter
+)
+
+// binaryEnvelope wraps the value of a binary document for consumers which expect json, the value is base64 encoded.
+type binaryEnvelope struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Encoding string            `json:"encoding"`
+	ID       string            `json:"id,omitempty"`
+	Value    []byte            `json:"value"`
+}
+
+// deadLetterWriter is implemented by consumers which write events to the dead letter queue.
+type deadLetterWriter interface {
+	DeadLetter(ctx *models.ListenerContext, err error)
+}
+
+func validateBinaryPolicy(policy string, consumer models.Consumer) error {
+	switch policy {
+	case config.BinaryPolicyRaw, config.BinaryPolicyBase64, config.BinaryPolicySkip:
+		return nil
+	case config.BinaryPolicyDeadLetter:
+		if _, ok := consumer.(deadLetterWriter); !ok {
+			return errors.New("binary policy deadLetter requires a dead letter consumer")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown binary policy: %s", policy)
+	}
+}
+
+// applyBinaryPolicy detects mutations whose values are not flagged as json by the server,
+// and returns the event to forward with the action of dcp.binary.policy. The id and the metadata of the event are
+// written into the envelope.
+func (s *stream) applyBinaryPolicy(payload interface{}, id string, metadata map[string]string) (interface{}, binaryAction) {
+	mutation, ok := payload.(models.DcpMutation)
+	if !ok || len(mutation.Value) == 0 || mutation.Datatype&uint8(memd.DatatypeFlagJSON) != 0 {
+		return payload, binaryForward
+	}
+
+	atomic.AddInt64(&s.metric.Binary, 1)
+
+	switch s.config.Dcp.Binary.Policy {
+	case config.BinaryPolicySkip:
+		return payload, binarySkip
+	case config.BinaryPolicyDeadLetter:
+		return payload, binaryDeadLetter
+	case config.BinaryPolicyBase64:
+		value, err := mutation.DecompressedValue()
+		if err != nil {
+			return payload, binaryForward
+		}
+
+		envelope, _ := sonic.Marshal(binaryEnvelope{Encoding: "base64", Value: value, ID: id, Metadata: metadata})
+
+		wrapped := *mutation.DcpMutation
+		wrapped.Value = envelope
+		wrapped.Datatype = (wrapped.Datatype | uint8(memd.DatatypeFlagJSON)) &^ uint8(memd.DatatypeFlagCompressed)
+		mutation.DcpMutation = &wrapped
+		mutation.Compressed = false
+
+		return mutation, binaryForward
+	default:
+		return payload, binaryForward
+	}
+}
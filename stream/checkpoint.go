@@ -10,7 +10,9 @@ import (
 
 	"github.com/Trendyol/go-dcp/wrapper"
 
+	"github.com/Trendyol/go-dcp/clock"
 	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/helpers"
 
 	"github.com/Trendyol/go-dcp/metadata"
 
@@ -28,7 +30,10 @@ const (
 
 type Checkpoint interface {
 	Save()
+	Commit() <-chan error
 	Load() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool)
+	AddVBuckets(vbIDs []uint16) (*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool)
+	RemoveVBuckets(vbIDs []uint16)
 	Clear()
 	StartSchedule()
 	StopSchedule()
@@ -36,8 +41,11 @@ type Checkpoint interface {
 }
 
 type CheckpointMetric struct {
+	LastSavedAt        time.Time
+	UnavailableSince   time.Time
 	OffsetWrite        int
 	OffsetWriteLatency int64
+	SaveFailure        int64
 }
 
 type checkpoint struct {
@@ -49,23 +57,42 @@ type checkpoint struct {
 	loadLock              *sync.Mutex
 	metric                *CheckpointMetric
 	offsetLatestSeqNoInit *offset.OffsetLatestSeqNoInit
+	clock                 clock.Clock
+	commits               *commitBatcher
+	history               map[uint16][]*models.CheckpointHistoryEntry
 	bucketUUID            string
 	vbIds                 []uint16
-	running               bool
+	stopCh                chan struct{}
+	historyLock           sync.Mutex
+	pausedIntake          bool
 }
 
 func (s *checkpoint) Save() {
+	_ = s.save()
+}
+
+// Commit saves the checkpoint, commits within checkpoint.commitWindow are saved together. The channel receives the
+// result of the save once the offsets acked before the commit are persisted.
+func (s *checkpoint) Commit() <-chan error {
+	return s.commits.Commit()
+}
+
+//nolint:funlen
+func (s *checkpoint) save() error {
 	offsets, dirtyOffsets, anyDirtyOffset := s.stream.GetOffsets()
 
+	s.saveLock.Lock()
+	defer s.saveLock.Unlock()
+
 	if !anyDirtyOffset {
 		logger.Log.Trace("no need to save checkpoint")
-		return
+		// The saved checkpoint is up to date, it does not get stale till the next event.
+		s.metric.LastSavedAt = s.clock.Now()
+		return nil
 	}
 
-	s.saveLock.Lock()
-	defer s.saveLock.Unlock()
-
 	checkpointDump := map[uint16]*models.CheckpointDocument{}
+	now := s.clock.Now()
 
 	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
 		checkpointDump[vbID] = &models.CheckpointDocument{
@@ -78,8 +105,16 @@ func (s *checkpoint) Save() {
 				},
 			},
 			BucketUUID: s.bucketUUID,
+			Version:    helpers.Version,
+			VBuckets:   s.client.GetNumVBuckets(),
 		}
 
+		if !offset.ProcessedAt.IsZero() {
+			checkpointDump[vbID].ProcessedTime = offset.ProcessedAt.UnixNano()
+		}
+
+		s.recordHistory(vbID, checkpointDump[vbID], now)
+
 		return true
 	})
 
@@ -100,24 +135,69 @@ func (s *checkpoint) Save() {
 
 	start := time.Now()
 
-	err := s.metadata.Save(checkpointDump, dirtyOffsetsDump, s.bucketUUID)
+	// The state is committed first, its snapshots hold their own offsets, so a failed checkpoint loses nothing.
+	if err := s.stream.CommitState(); err != nil {
+		logger.Log.Error("error while committing state, err: %v", err)
+		return err
+	}
+
+	err := s.saveWithRetry(checkpointDump, dirtyOffsetsDump)
 
 	s.metric.OffsetWriteLatency = time.Since(start).Milliseconds()
 
 	if err == nil {
 		logger.Log.Trace("saved checkpoint")
 		s.stream.UnmarkDirtyOffsets()
+		s.saveSucceeded()
+
+		if committer, ok := s.stream.(snapshotCommitter); ok {
+			committer.snapshotsCommitted(checkpointDump)
+		}
 	} else {
 		logger.Log.Error("error while saving checkpoint document: %v", err)
+		s.saveFailed(err)
 	}
+
+	return err
 }
 
-//nolint:funlen
 func (s *checkpoint) Load() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool) {
+	return s.load(s.vbIds)
+}
+
+// AddVBuckets loads the checkpoints of the vBuckets gained by an incremental rebalance and saves them from then on.
+func (s *checkpoint) AddVBuckets(vbIDs []uint16) (
+	*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool,
+) {
+	offsets, dirtyOffsets, anyDirtyOffset := s.load(vbIDs)
+
+	s.loadLock.Lock()
+	s.vbIds = append(append(make([]uint16, 0, len(s.vbIds)+len(vbIDs)), s.vbIds...), vbIDs...)
+	s.loadLock.Unlock()
+
+	return offsets, dirtyOffsets, anyDirtyOffset
+}
+
+// RemoveVBuckets drops the vBuckets lost by an incremental rebalance, their history is loaded by the next owner.
+func (s *checkpoint) RemoveVBuckets(vbIDs []uint16) {
+	s.loadLock.Lock()
+	s.vbIds = difference(s.vbIds, vbIDs)
+	s.loadLock.Unlock()
+
+	s.historyLock.Lock()
+	defer s.historyLock.Unlock()
+
+	for _, vbID := range vbIDs {
+		delete(s.history, vbID)
+	}
+}
+
+//nolint:funlen
+func (s *checkpoint) load(vbIDs []uint16) (*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool) {
 	s.loadLock.Lock()
 	defer s.loadLock.Unlock()
 
-	dump, exist, err := s.metadata.Load(s.vbIds, s.bucketUUID)
+	dump, exist, err := s.metadata.Load(vbIDs, s.bucketUUID)
 	if err == nil {
 		logger.Log.Debug("loaded checkpoint")
 	} else {
@@ -125,14 +205,21 @@ func (s *checkpoint) Load() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset]
 		panic(err)
 	}
 
+	if exist && s.resetVBucketCountChange(dump) {
+		dump, exist = newEmptyCheckpoints(vbIDs, s.bucketUUID), false
+	}
+
+	s.loadHistory(dump)
+
 	seqNoMap, err := s.client.GetVBucketSeqNos(false)
 	if err != nil {
 		logger.Log.Error("error while getting vBucket seqNos, err: %v", err)
 		panic(err)
 	}
 
-	offsets := wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](1024)
-	dirtyOffsets := wrapper.CreateConcurrentSwissMap[uint16, bool](1024)
+	mapInitialSize := s.config.GetMapInitialSize(len(vbIDs))
+	offsets := wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](mapInitialSize)
+	dirtyOffsets := wrapper.CreateConcurrentSwissMap[uint16, bool](mapInitialSize)
 	anyDirtyOffset := false
 
 	if !exist && s.config.Checkpoint.AutoReset == CheckpointAutoResetTypeLatest {
@@ -170,6 +257,10 @@ func (s *checkpoint) Load() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset]
 		return offsets, dirtyOffsets, anyDirtyOffset
 	}
 
+	if exist && !s.config.Dcp.Flush.Disabled && s.resetFlushed(dump, seqNoMap, offsets, dirtyOffsets) {
+		return offsets, dirtyOffsets, true
+	}
+
 	dump.Range(func(vbID uint16, doc *models.CheckpointDocument) bool {
 		latestSeqNo, _ := seqNoMap.Load(vbID)
 		if doc.Checkpoint.SeqNo > latestSeqNo {
@@ -188,6 +279,7 @@ func (s *checkpoint) Load() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset]
 				StartSeqNo: doc.Checkpoint.Snapshot.StartSeqNo,
 				EndSeqNo:   doc.Checkpoint.Snapshot.EndSeqNo,
 			},
+			ProcessedAt: processedAt(doc),
 			VbUUID:      gocbcore.VbUUID(doc.Checkpoint.VbUUID),
 			SeqNo:       doc.Checkpoint.SeqNo,
 			LatestSeqNo: latestOffsetSeqNo,
@@ -199,6 +291,107 @@ func (s *checkpoint) Load() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset]
 	return offsets, dirtyOffsets, anyDirtyOffset
 }
 
+// recordHistory appends the checkpoint to the history of the vBucket when the newest entry is older than the history
+// interval, the oldest entries are dropped beyond the history size. The history is saved with the checkpoint.
+func (s *checkpoint) recordHistory(vbID uint16, doc *models.CheckpointDocument, now time.Time) {
+	historyConfig := s.config.Checkpoint.History
+	if historyConfig.Size <= 0 {
+		return
+	}
+
+	s.historyLock.Lock()
+	defer s.historyLock.Unlock()
+
+	history := s.history[vbID]
+
+	if n := len(history); n > 0 {
+		newest := history[n-1]
+		unchanged := newest.Checkpoint.VbUUID == doc.Checkpoint.VbUUID && newest.Checkpoint.SeqNo == doc.Checkpoint.SeqNo
+
+		if unchanged || now.Sub(time.Unix(0, newest.SavedTime)) < historyConfig.Interval {
+			doc.History = history
+			return
+		}
+	}
+
+	if len(history) >= historyConfig.Size {
+		history = history[len(history)-historyConfig.Size+1:]
+	}
+
+	history = append(append(make([]*models.CheckpointHistoryEntry, 0, len(history)+1), history...),
+		&models.CheckpointHistoryEntry{Checkpoint: doc.Checkpoint, SavedTime: now.UnixNano()})
+
+	s.history[vbID] = history
+	doc.History = history
+}
+
+func (s *checkpoint) loadHistory(dump *wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument]) {
+	s.historyLock.Lock()
+	defer s.historyLock.Unlock()
+
+	dump.Range(func(vbID uint16, doc *models.CheckpointDocument) bool {
+		if len(doc.History) > 0 {
+			s.history[vbID] = doc.History
+		}
+
+		return true
+	})
+}
+
+// processedAt keeps the processed time of a loaded checkpoint, so it is not lost till the vBucket advances again.
+func processedAt(doc *models.CheckpointDocument) time.Time {
+	if doc.ProcessedTime == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, doc.ProcessedTime)
+}
+
+// resetFlushed resets the offsets with dcp.flush.reset when the bucket is flushed after the checkpoint is saved.
+func (s *checkpoint) resetFlushed(
+	dump *wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument],
+	seqNoMap *wrapper.ConcurrentSwissMap[uint16, uint64],
+	offsets *wrapper.ConcurrentSwissMap[uint16, *models.Offset],
+	dirtyOffsets *wrapper.ConcurrentSwissMap[uint16, bool],
+) bool {
+	checkpointOffsets := map[uint16]*models.Offset{}
+	dump.Range(func(vbID uint16, doc *models.CheckpointDocument) bool {
+		checkpointOffsets[vbID] = &models.Offset{
+			VbUUID: gocbcore.VbUUID(doc.Checkpoint.VbUUID),
+			SeqNo:  doc.Checkpoint.SeqNo,
+		}
+		return true
+	})
+
+	if !detectFlush(s.client, checkpointOffsets, seqNoMap) {
+		return false
+	}
+
+	logger.Log.Warn("bucket flush detected, checkpoint is reset to %v", s.config.Dcp.Flush.Reset)
+
+	vbIDs := make([]uint16, 0, len(checkpointOffsets))
+
+	for vbID := range checkpointOffsets {
+		currentSeqNo, _ := seqNoMap.Load(vbID)
+
+		offset, err := newFlushOffset(s.client, s.config, vbID, currentSeqNo, s.offsetLatestSeqNoInit.InitializeLatestSeqNo(currentSeqNo))
+		if err != nil {
+			logger.Log.Error("error while reset checkpoint after flush, vbID: %d, err: %v", vbID, err)
+			panic(err)
+		}
+
+		offsets.Store(vbID, offset)
+		dirtyOffsets.Store(vbID, true)
+		vbIDs = append(vbIDs, vbID)
+	}
+
+	if notifier, ok := s.stream.(flushNotifier); ok {
+		notifier.notifyBucketFlush(vbIDs)
+	}
+
+	return true
+}
+
 func (s *checkpoint) Clear() {
 	_ = s.metadata.Clear(s.vbIds)
 	logger.Log.Debug("cleared checkpoint")
@@ -209,11 +402,17 @@ func (s *checkpoint) StartSchedule() {
 		return
 	}
 
+	stopCh := make(chan struct{})
+	s.stopCh = stopCh
+
 	go func() {
-		s.running = true
-		for s.running {
-			time.Sleep(s.config.Checkpoint.Interval)
-			s.Save()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-s.clock.After(s.config.Checkpoint.Interval):
+				s.Save()
+			}
 		}
 	}()
 
@@ -221,11 +420,17 @@ func (s *checkpoint) StartSchedule() {
 }
 
 func (s *checkpoint) StopSchedule() {
+	// pending commits are saved before the stream closes, so they do not wait for a stream which is gone
+	s.commits.Flush()
+
 	if s.config.Checkpoint.Type != CheckpointTypeAuto {
 		return
 	}
 
-	s.running = false
+	if s.stopCh != nil {
+		close(s.stopCh)
+		s.stopCh = nil
+	}
 
 	logger.Log.Debug("stopped checkpoint schedule")
 }
@@ -251,8 +456,9 @@ func NewCheckpoint(
 	metadata metadata.Metadata,
 	config *config.Dcp,
 	offsetLatestSeqNoInit *offset.OffsetLatestSeqNoInit,
+	clock clock.Clock,
 ) Checkpoint {
-	return &checkpoint{
+	c := &checkpoint{
 		client:                client,
 		stream:                stream,
 		vbIds:                 vbIds,
@@ -261,7 +467,13 @@ func NewCheckpoint(
 		config:                config,
 		saveLock:              &sync.Mutex{},
 		loadLock:              &sync.Mutex{},
-		metric:                &CheckpointMetric{},
+		metric:                &CheckpointMetric{LastSavedAt: clock.Now()},
 		offsetLatestSeqNoInit: offsetLatestSeqNoInit,
+		clock:                 clock,
+		history:               map[uint16][]*models.CheckpointHistoryEntry{},
 	}
+
+	c.commits = newCommitBatcher(clock, config.Checkpoint.CommitWindow, c.save)
+
+	return c
 }
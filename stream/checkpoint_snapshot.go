@@ -0,0 +1,44 @@
+package stream
+
+import (
+	"io"
+
+	"github.com/Trendyol/go-dcp/metadata"
+)
+
+// Export saves the offsets, then writes a json or cbor snapshot of the checkpoints of all vBuckets of the group.
+func (s *stream) Export(w io.Writer, format string) error {
+	s.Save()
+
+	return metadata.Export(w, format, s.metadata, s.config.Dcp.Group.Name, allVBucketIDs(s.client.GetNumVBuckets()), getBucketUUID(s.client))
+}
+
+// Import saves the checkpoints of a snapshot to the metadata. The stream is closed without saving its offsets and
+// opened again from the imported checkpoints. Other members keep their offsets in memory, they load the imported
+// checkpoints of their vBuckets only after their next rebalance.
+func (s *stream) Import(r io.Reader) error {
+	s.rebalanceLock.Lock()
+	defer s.rebalanceLock.Unlock()
+
+	open := s.IsOpen()
+	if open {
+		s.Close(false)
+	}
+
+	_, err := metadata.Import(r, s.metadata, getBucketUUID(s.client))
+
+	if open {
+		s.Open()
+	}
+
+	return err
+}
+
+func allVBucketIDs(vBuckets int) []uint16 {
+	vbIDs := make([]uint16, vBuckets)
+	for i := range vbIDs {
+		vbIDs[i] = uint16(i)
+	}
+
+	return vbIDs
+}
@@ -0,0 +1,55 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// CheckpointStatus is the saved checkpoint of a vBucket with the time it was last processed and the library version
+// which saved it. Staleness is the time since it was processed, it is zero for checkpoints saved by older versions.
+// History is the former checkpoints kept with checkpoint.history, the checkpoints can be reset to them.
+type CheckpointStatus struct {
+	ProcessedAt time.Time                        `json:"processedAt"`
+	Version     string                           `json:"version"`
+	History     []*models.CheckpointHistoryEntry `json:"history,omitempty"`
+	Staleness   int64                            `json:"stalenessMs"`
+	SeqNo       uint64                           `json:"seqNo"`
+	VbUUID      uint64                           `json:"vbUuid"`
+}
+
+// GetCheckpointStatuses returns the saved checkpoints of every vBucket of the group. vBuckets without a saved
+// checkpoint are skipped.
+func (s *stream) GetCheckpointStatuses() (map[uint16]CheckpointStatus, error) {
+	checkpoints, _, err := s.metadata.Load(allVBucketIDs(s.client.GetNumVBuckets()), getBucketUUID(s.client))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	statuses := make(map[uint16]CheckpointStatus, checkpoints.Count())
+
+	checkpoints.Range(func(vbID uint16, doc *models.CheckpointDocument) bool {
+		if doc.Checkpoint == nil || (doc.Checkpoint.VbUUID == 0 && doc.Checkpoint.SeqNo == 0) {
+			return true
+		}
+
+		status := CheckpointStatus{
+			Version: doc.Version,
+			History: doc.History,
+			SeqNo:   doc.Checkpoint.SeqNo,
+			VbUUID:  doc.Checkpoint.VbUUID,
+		}
+
+		if processed := processedAt(doc); !processed.IsZero() {
+			status.ProcessedAt = processed
+			status.Staleness = now.Sub(processed).Milliseconds()
+		}
+
+		statuses[vbID] = status
+
+		return true
+	})
+
+	return statuses, nil
+}
@@ -0,0 +1,122 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+// cleanStream has no dirty offsets, so each save of the checkpoint only reads the offsets.
+type cleanStream struct {
+	Stream
+	reads atomic.Int32
+}
+
+func (s *cleanStream) GetOffsets() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool) {
+	s.reads.Add(1)
+
+	return wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](0), wrapper.CreateConcurrentSwissMap[uint16, bool](0), false
+}
+
+func TestCheckpoint_ScheduleSavesOnInterval(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	cfg := &config.Dcp{}
+	cfg.Checkpoint.Type = CheckpointTypeAuto
+	cfg.Checkpoint.Interval = 10 * time.Second
+
+	stream := &cleanStream{}
+	c := &checkpoint{
+		stream:   stream,
+		config:   cfg,
+		clock:    fake,
+		saveLock: &sync.Mutex{},
+		metric:   &CheckpointMetric{},
+	}
+	c.commits = newCommitBatcher(fake, 0, c.save)
+
+	c.StartSchedule()
+
+	for i := int32(1); i <= 3; i++ {
+		fake.BlockUntil(1)
+
+		if reads := stream.reads.Load(); reads != i-1 {
+			t.Fatalf("checkpoint must not be saved before the interval, saves: %d", reads)
+		}
+
+		fake.Advance(10 * time.Second)
+
+		deadline := time.Now().Add(time.Second)
+		for stream.reads.Load() < i {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d saves, got %d", i, stream.reads.Load())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	c.StopSchedule()
+
+	// the metric is written under the save lock
+	c.saveLock.Lock()
+	lastSavedAt := c.GetMetric().LastSavedAt
+	c.saveLock.Unlock()
+
+	if lastSavedAt.Unix() < 20 {
+		t.Fatalf("saves must take the time of the clock, got %v", lastSavedAt)
+	}
+}
+
+func TestCommitBatcher(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	errSave := errors.New("save")
+
+	var saves atomic.Int32
+	batcher := newCommitBatcher(fake, time.Second, func() error {
+		saves.Add(1)
+		return errSave
+	})
+
+	first, second := batcher.Commit(), batcher.Commit()
+
+	fake.BlockUntil(1)
+
+	if saves.Load() != 0 {
+		t.Fatalf("commits must wait the window")
+	}
+
+	fake.Advance(time.Second)
+
+	for _, done := range []<-chan error{first, second} {
+		if err := <-done; !errors.Is(err, errSave) {
+			t.Fatalf("commit must resolve with the save result, got %v", err)
+		}
+	}
+
+	if saves.Load() != 1 {
+		t.Fatalf("commits of a window must be saved once, got %d saves", saves.Load())
+	}
+
+	third := batcher.Commit()
+	batcher.Flush()
+
+	if err := <-third; !errors.Is(err, errSave) || saves.Load() != 2 {
+		t.Fatalf("flush must save the pending commits, got %v with %d saves", err, saves.Load())
+	}
+
+	batcher.Flush()
+
+	if saves.Load() != 2 {
+		t.Fatalf("flush without pending commits must not save")
+	}
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+const (
+	CheckpointIssueBucketUUIDMismatch = "bucketUuidMismatch"
+	CheckpointIssueAheadOfServer      = "aheadOfServer"
+	CheckpointIssueUnknownVbUUID      = "unknownVbUuid"
+	CheckpointIssueDiverged           = "diverged"
+	CheckpointIssueVBucketCountChange = "vBucketCountChange"
+)
+
+// verifyConcurrency limits the failover log requests sent to the server at once.
+const verifyConcurrency = 32
+
+// CheckpointIssue describes a saved checkpoint which does not match the server state.
+// Streams of such vBuckets are rolled back or restarted from the beginning when they are opened.
+type CheckpointIssue struct {
+	Reason       string `json:"reason"`
+	SeqNo        uint64 `json:"seqNo"`
+	ServerSeqNo  uint64 `json:"serverSeqNo"`
+	VbUUID       uint64 `json:"vbUuid"`
+	ServerVbUUID uint64 `json:"serverVbUuid"`
+	VbID         uint16 `json:"vbId"`
+}
+
+// VerifyCheckpoints compares the saved checkpoints of every vBucket of the group with the failover logs
+// and the high seqnos of the server. vBuckets without a saved checkpoint are skipped.
+func (s *stream) VerifyCheckpoints() ([]CheckpointIssue, error) {
+	vbIDs := allVBucketIDs(s.client.GetNumVBuckets())
+
+	bucketUUID := getBucketUUID(s.client)
+
+	checkpoints, _, err := s.metadata.Load(vbIDs, bucketUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	seqNoMap, err := s.client.GetVBucketSeqNos(false)
+	if err != nil {
+		return nil, err
+	}
+
+	seqNos := seqNoMap.ToMap()
+
+	var issues []CheckpointIssue
+	var lock sync.Mutex
+
+	eg := errgroup.Group{}
+	eg.SetLimit(verifyConcurrency)
+
+	checkpoints.Range(func(vbID uint16, doc *models.CheckpointDocument) bool {
+		if doc.Checkpoint == nil || (doc.Checkpoint.VbUUID == 0 && doc.Checkpoint.SeqNo == 0) {
+			return true
+		}
+
+		eg.Go(func() error {
+			issue, err := s.verifyCheckpoint(vbID, doc, bucketUUID, seqNos[vbID])
+			if err != nil || issue == nil {
+				return err
+			}
+
+			lock.Lock()
+			issues = append(issues, *issue)
+			lock.Unlock()
+
+			return nil
+		})
+
+		return true
+	})
+
+	if err = eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].VbID < issues[j].VbID
+	})
+
+	return issues, nil
+}
+
+func (s *stream) verifyCheckpoint(vbID uint16, doc *models.CheckpointDocument, bucketUUID string, highSeqNo uint64) (*CheckpointIssue, error) {
+	issue := &CheckpointIssue{
+		VbID:        vbID,
+		SeqNo:       doc.Checkpoint.SeqNo,
+		VbUUID:      doc.Checkpoint.VbUUID,
+		ServerSeqNo: highSeqNo,
+	}
+
+	failoverLogs, err := s.client.GetFailOverLogs(vbID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(failoverLogs) > 0 {
+		issue.ServerVbUUID = uint64(failoverLogs[0].VbUUID)
+	}
+
+	if doc.VBuckets != 0 && doc.VBuckets != s.client.GetNumVBuckets() {
+		issue.Reason = CheckpointIssueVBucketCountChange
+		return issue, nil
+	}
+
+	if doc.BucketUUID != "" && doc.BucketUUID != bucketUUID {
+		issue.Reason = CheckpointIssueBucketUUIDMismatch
+		return issue, nil
+	}
+
+	if doc.Checkpoint.SeqNo > highSeqNo {
+		issue.Reason = CheckpointIssueAheadOfServer
+		return issue, nil
+	}
+
+	// Failover logs are ordered from the newest entry, the branch of an entry ends at the seqno of the previous one.
+	for i, entry := range failoverLogs {
+		if uint64(entry.VbUUID) != doc.Checkpoint.VbUUID {
+			continue
+		}
+
+		if i > 0 && doc.Checkpoint.SeqNo > uint64(failoverLogs[i-1].SeqNo) {
+			issue.Reason = CheckpointIssueDiverged
+			return issue, nil
+		}
+
+		return nil, nil
+	}
+
+	issue.Reason = CheckpointIssueUnknownVbUUID
+	return issue, nil
+}
@@ -0,0 +1,119 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type verificationClient struct {
+	couchbase.Client
+	failoverLogs []gocbcore.FailoverEntry
+	err          error
+}
+
+func (c *verificationClient) GetFailOverLogs(_ uint16) ([]gocbcore.FailoverEntry, error) {
+	return c.failoverLogs, c.err
+}
+
+func (c *verificationClient) GetNumVBuckets() int {
+	return 1024
+}
+
+func TestStream_VerifyCheckpoint(t *testing.T) {
+	// the branch of vbUUID 1 ends at seqno 100, vbUUID 2 is the current branch
+	failoverLogs := []gocbcore.FailoverEntry{{VbUUID: 2, SeqNo: 100}, {VbUUID: 1, SeqNo: 0}}
+
+	tests := []struct {
+		name     string
+		doc      models.CheckpointDocument
+		expected string
+	}{
+		{
+			name: "current branch",
+			doc:  models.CheckpointDocument{Checkpoint: &models.CheckpointDocumentCheckpoint{VbUUID: 2, SeqNo: 150}},
+		},
+		{
+			name: "previous branch before its end",
+			doc:  models.CheckpointDocument{Checkpoint: &models.CheckpointDocumentCheckpoint{VbUUID: 1, SeqNo: 100}},
+		},
+		{
+			name:     "previous branch after its end",
+			doc:      models.CheckpointDocument{Checkpoint: &models.CheckpointDocumentCheckpoint{VbUUID: 1, SeqNo: 120}},
+			expected: CheckpointIssueDiverged,
+		},
+		{
+			name:     "unknown vbUUID",
+			doc:      models.CheckpointDocument{Checkpoint: &models.CheckpointDocumentCheckpoint{VbUUID: 3, SeqNo: 10}},
+			expected: CheckpointIssueUnknownVbUUID,
+		},
+		{
+			name:     "ahead of server",
+			doc:      models.CheckpointDocument{Checkpoint: &models.CheckpointDocumentCheckpoint{VbUUID: 2, SeqNo: 250}},
+			expected: CheckpointIssueAheadOfServer,
+		},
+		{
+			name: "bucket uuid mismatch",
+			doc: models.CheckpointDocument{
+				Checkpoint: &models.CheckpointDocumentCheckpoint{VbUUID: 2, SeqNo: 150},
+				BucketUUID: "other",
+			},
+			expected: CheckpointIssueBucketUUIDMismatch,
+		},
+		{
+			name: "vBucket count change",
+			doc: models.CheckpointDocument{
+				Checkpoint: &models.CheckpointDocumentCheckpoint{VbUUID: 2, SeqNo: 150},
+				VBuckets:   64,
+			},
+			expected: CheckpointIssueVBucketCountChange,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := &stream{client: &verificationClient{failoverLogs: failoverLogs}}
+
+			issue, err := s.verifyCheckpoint(7, &tt.doc, "bucket", 200)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.expected == "" {
+				if issue != nil {
+					t.Fatalf("expected no issue, got %+v", issue)
+				}
+				return
+			}
+
+			expected := &CheckpointIssue{
+				Reason:       tt.expected,
+				SeqNo:        tt.doc.Checkpoint.SeqNo,
+				ServerSeqNo:  200,
+				VbUUID:       tt.doc.Checkpoint.VbUUID,
+				ServerVbUUID: 2,
+				VbID:         7,
+			}
+			if !reflect.DeepEqual(issue, expected) {
+				t.Fatalf("expected %+v, got %+v", expected, issue)
+			}
+		})
+	}
+}
+
+func TestStream_VerifyCheckpointFailoverLogError(t *testing.T) {
+	expected := errors.New("failover log")
+	s := &stream{client: &verificationClient{err: expected}}
+
+	doc := &models.CheckpointDocument{Checkpoint: &models.CheckpointDocumentCheckpoint{VbUUID: 2, SeqNo: 1}}
+
+	if _, err := s.verifyCheckpoint(0, doc, "bucket", 1); !errors.Is(err, expected) {
+		t.Fatalf("expected failover log error, got %v", err)
+	}
+}
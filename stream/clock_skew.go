@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	clockSkewWindow        = time.Minute
+	clockSkewMaxDiff       = 5 * time.Minute
+	clockSkewMaxCorrection = time.Second
+)
+
+// clockSkewEstimator estimates the offset between the local clock and the cluster clock.
+// Each event carries its CAS derived server time and its local receive time, the difference of them is
+// delivery delay plus clock skew. The smallest difference seen in a window has the least delivery delay,
+// so it is used as the skew estimate. Differences bigger than clockSkewMaxDiff are backfilled
+// documents rather than skew and are ignored.
+// A delay which no event in the window avoids, e.g. a consumer lagging behind all the time, is part of the
+// smallest difference too, so the correction is capped at clockSkewMaxCorrection and the raw latency is
+// reported next to the corrected one.
+type clockSkewEstimator struct {
+	windowStart time.Time
+	windowMin   time.Duration
+	estimate    time.Duration
+	lock        sync.Mutex
+	hasWindow   bool
+	hasEstimate bool
+}
+
+func (e *clockSkewEstimator) Observe(serverTime time.Time, receivedTime time.Time) {
+	diff := receivedTime.Sub(serverTime)
+	if diff > clockSkewMaxDiff || diff < -clockSkewMaxDiff {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !e.hasWindow || receivedTime.Sub(e.windowStart) >= clockSkewWindow {
+		if e.hasWindow {
+			e.estimate = e.windowMin
+			e.hasEstimate = true
+		}
+
+		e.windowStart = receivedTime
+		e.windowMin = diff
+		e.hasWindow = true
+	} else if diff < e.windowMin {
+		e.windowMin = diff
+	}
+
+	if !e.hasEstimate || e.windowMin < e.estimate {
+		e.estimate = e.windowMin
+		e.hasEstimate = true
+	}
+}
+
+// Skew returns the estimated local clock minus cluster clock difference, capped at clockSkewMaxCorrection.
+func (e *clockSkewEstimator) Skew() time.Duration {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return max(min(e.estimate, clockSkewMaxCorrection), -clockSkewMaxCorrection)
+}
+
+// RawLatency returns the delay between the server time and the given local time without any correction.
+func (e *clockSkewEstimator) RawLatency(serverTime time.Time, now time.Time) time.Duration {
+	return now.Sub(serverTime)
+}
+
+// Latency returns the skew corrected delay between the server time and the given local time.
+func (e *clockSkewEstimator) Latency(serverTime time.Time, now time.Time) time.Duration {
+	latency := now.Sub(serverTime) - e.Skew()
+	if latency < 0 {
+		return 0
+	}
+
+	return latency
+}
@@ -0,0 +1,73 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockSkewEstimator(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name         string
+		diffs        []time.Duration
+		expectedSkew time.Duration
+	}{
+		{
+			name:         "smallest difference is the skew",
+			diffs:        []time.Duration{300 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond},
+			expectedSkew: 200 * time.Millisecond,
+		},
+		{
+			name:         "negative skew is estimated",
+			diffs:        []time.Duration{-100 * time.Millisecond, 50 * time.Millisecond},
+			expectedSkew: -100 * time.Millisecond,
+		},
+		{
+			name:         "constant lag is capped",
+			diffs:        []time.Duration{10 * time.Second, 12 * time.Second},
+			expectedSkew: clockSkewMaxCorrection,
+		},
+		{
+			name:         "backfilled documents are ignored",
+			diffs:        []time.Duration{time.Hour, 100 * time.Millisecond},
+			expectedSkew: 100 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator := &clockSkewEstimator{}
+
+			for i, diff := range tt.diffs {
+				received := start.Add(time.Duration(i) * time.Second)
+				estimator.Observe(received.Add(-diff), received)
+			}
+
+			if skew := estimator.Skew(); skew != tt.expectedSkew {
+				t.Errorf("expected skew %v, got %v", tt.expectedSkew, skew)
+			}
+		})
+	}
+}
+
+func TestClockSkewEstimator_Latency(t *testing.T) {
+	estimator := &clockSkewEstimator{}
+
+	now := time.Unix(1700000000, 0)
+	serverTime := now.Add(-10 * time.Second)
+
+	estimator.Observe(serverTime, now)
+
+	if latency := estimator.Latency(serverTime, now); latency != 9*time.Second {
+		t.Errorf("lag must not be absorbed beyond the correction cap, got %v", latency)
+	}
+
+	if raw := estimator.RawLatency(serverTime, now); raw != 10*time.Second {
+		t.Errorf("raw latency must not be corrected, got %v", raw)
+	}
+
+	if latency := estimator.Latency(now.Add(time.Minute), now); latency != 0 {
+		t.Errorf("latency must not be negative, got %v", latency)
+	}
+}
@@ -0,0 +1,73 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Trendyol/go-dcp/clock"
+)
+
+// commitBatcher coalesces the commits within the window into one save, so the checkpoint of each vBucket is written
+// once per window however often the consumer commits. A commit resolves with the result of the first save which
+// starts after it, so its offsets are persisted when it resolves.
+type commitBatcher struct {
+	clock   clock.Clock
+	timer   clock.Timer
+	save    func() error
+	waiters []chan error
+	window  time.Duration
+	lock    sync.Mutex
+}
+
+func (b *commitBatcher) Commit() <-chan error {
+	done := make(chan error, 1)
+
+	if b.window <= 0 {
+		done <- b.save()
+		close(done)
+		return done
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.waiters = append(b.waiters, done)
+
+	if b.timer == nil {
+		b.timer = b.clock.AfterFunc(b.window, b.Flush)
+	}
+
+	return done
+}
+
+// Flush saves the pending commits without waiting for the end of the window.
+func (b *commitBatcher) Flush() {
+	b.lock.Lock()
+	waiters := b.waiters
+	b.waiters = nil
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.lock.Unlock()
+
+	if len(waiters) == 0 {
+		return
+	}
+
+	err := b.save()
+
+	for _, done := range waiters {
+		done <- err
+		close(done)
+	}
+}
+
+func newCommitBatcher(clock clock.Clock, window time.Duration, save func() error) *commitBatcher {
+	return &commitBatcher{
+		clock:  clock,
+		window: window,
+		save:   save,
+	}
+}
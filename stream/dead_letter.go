@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// deadLetterConsumer writes the events which the consumer fails to process to the dead letter queue
+// and acks them, so a poison document does not stall its vBucket.
+type deadLetterConsumer struct {
+	models.ConsumerWrapper
+	consumer models.FailableConsumer
+	queue    models.DeadLetterQueue
+}
+
+func (c *deadLetterConsumer) ConsumeEvent(ctx *models.ListenerContext) {
+	err := c.consumer.ConsumeEvent(ctx)
+	if err == nil {
+		return
+	}
+
+	if errors.Is(err, errRetryStopped) {
+		logger.Log.Debug("event is not dead lettered, retry is stopped, vbID: %v", ctx.VbID)
+		return
+	}
+
+	c.DeadLetter(ctx, err)
+}
+
+// DeadLetter writes the event to the queue and acks it. An event which cannot be written is left unacked, so the
+// offset is not advanced past it.
+func (c *deadLetterConsumer) DeadLetter(ctx *models.ListenerContext, err error) {
+	letter := newDeadLetter(ctx, err)
+
+	if err = c.queue.Write(letter); err != nil {
+		logger.Log.Error("error while write dead letter, vbID: %v, seqNo: %v, err: %v", letter.VbID, letter.SeqNo, err)
+		return
+	}
+
+	logger.Log.Warn("event written to dead letter queue, vbID: %v, seqNo: %v, err: %v", letter.VbID, letter.SeqNo, letter.Error)
+
+	ctx.Ack()
+}
+
+func (c *deadLetterConsumer) useClock(clock clock.Clock) {
+	if user, ok := c.consumer.(clockUser); ok {
+		user.useClock(clock)
+	}
+}
+
+func (c *deadLetterConsumer) TrackOffset(vbID uint16, offset *models.Offset) {
+	c.consumer.TrackOffset(vbID, offset)
+}
+
+func newDeadLetter(ctx *models.ListenerContext, err error) *models.DeadLetter {
+	letter := &models.DeadLetter{
+		Time:     time.Now(),
+		Event:    ctx.Event,
+		Error:    err.Error(),
+		VbID:     ctx.VbID,
+		ID:       ctx.ID,
+		Metadata: ctx.Metadata,
+	}
+
+	switch event := ctx.Event.(type) {
+	case models.DcpMutation:
+		letter.Type = "mutation"
+		letter.CollectionName = event.CollectionName
+		letter.Key = string(event.Key)
+		letter.Value = decompressedOrRaw(event.DecompressedValue, event.Value)
+		letter.Cas = event.Cas
+		letter.SeqNo = event.SeqNo
+	case models.DcpDeletion:
+		letter.Type = "deletion"
+		letter.CollectionName = event.CollectionName
+		letter.Key = string(event.Key)
+		letter.Value = decompressedOrRaw(event.DecompressedValue, event.Value)
+		letter.Cas = event.Cas
+		letter.SeqNo = event.SeqNo
+	case models.DcpExpiration:
+		letter.Type = "expiration"
+		letter.CollectionName = event.CollectionName
+		letter.Key = string(event.Key)
+		letter.Cas = event.Cas
+		letter.SeqNo = event.SeqNo
+	}
+
+	return letter
+}
+
+// decompressedOrRaw returns the decompressed value of a compressed event, the value as is when it cannot be
+// decompressed so the letter still keeps it.
+func decompressedOrRaw(decompress func() ([]byte, error), value []byte) []byte {
+	if decompressed, err := decompress(); err == nil {
+		return decompressed
+	}
+
+	return value
+}
+
+// NewDeadLetterConsumer creates a consumer which writes failed events of the consumer to the queue.
+func NewDeadLetterConsumer(consumer models.FailableConsumer, queue models.DeadLetterQueue) models.Consumer {
+	return &deadLetterConsumer{
+		ConsumerWrapper: models.ConsumerWrapper{Wrapped: consumer},
+		consumer:        consumer,
+		queue:           queue,
+	}
+}
+
+type fileDeadLetterQueue struct {
+	fileName string
+	lock     sync.Mutex
+}
+
+func (q *fileDeadLetterQueue) Write(letter *models.DeadLetter) error {
+	line, err := sonic.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	file, err := os.OpenFile(q.fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(line, '\n'))
+
+	return errors.Join(err, file.Close())
+}
+
+// NewFileDeadLetterQueue creates a queue which appends dead letters to the file as json lines.
+func NewFileDeadLetterQueue(fileName string) models.DeadLetterQueue {
+	return &fileDeadLetterQueue{fileName: fileName}
+}
@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/couchbase/gocbcore/v10"
+	"github.com/golang/snappy"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type consumerFunc func(ctx *models.ListenerContext) error
+
+func (f consumerFunc) ConsumeEvent(ctx *models.ListenerContext) error {
+	return f(ctx)
+}
+
+func (f consumerFunc) TrackOffset(_ uint16, _ *models.Offset) {}
+
+func TestNewDeadLetter(t *testing.T) {
+	value := []byte(`{"id":1}`)
+
+	tests := []struct {
+		event    interface{}
+		expected models.DeadLetter
+		name     string
+	}{
+		{
+			name: "mutation",
+			event: models.DcpMutation{
+				DcpMutation:    &gocbcore.DcpMutation{Key: []byte("key"), Value: value, Cas: 3, SeqNo: 5},
+				CollectionName: "orders",
+			},
+			expected: models.DeadLetter{Type: "mutation", CollectionName: "orders", Key: "key", Value: value, Cas: 3, SeqNo: 5},
+		},
+		{
+			name: "compressed mutation",
+			event: models.DcpMutation{
+				DcpMutation: &gocbcore.DcpMutation{Key: []byte("key"), Value: snappy.Encode(nil, value), SeqNo: 5},
+				Compressed:  true,
+			},
+			expected: models.DeadLetter{Type: "mutation", Key: "key", Value: value, SeqNo: 5},
+		},
+		{
+			name: "value which cannot be decompressed is kept as is",
+			event: models.DcpDeletion{
+				DcpDeletion: &gocbcore.DcpDeletion{Key: []byte("key"), Value: []byte("raw"), SeqNo: 6},
+				Compressed:  true,
+			},
+			expected: models.DeadLetter{Type: "deletion", Key: "key", Value: []byte("raw"), SeqNo: 6},
+		},
+		{
+			name:     "expiration",
+			event:    models.DcpExpiration{DcpExpiration: &gocbcore.DcpExpiration{Key: []byte("key"), Cas: 4, SeqNo: 7}},
+			expected: models.DeadLetter{Type: "expiration", Key: "key", Cas: 4, SeqNo: 7},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &models.ListenerContext{Event: tt.event, VbID: 9, ID: "id", Metadata: map[string]string{"k": "v"}}
+
+			letter := newDeadLetter(ctx, errConsume)
+
+			if letter.Time.IsZero() || letter.Event == nil {
+				t.Fatalf("letter must have its time and event, got %+v", letter)
+			}
+
+			expected := tt.expected
+			expected.Time, expected.Event = letter.Time, letter.Event
+			expected.Error, expected.VbID, expected.ID, expected.Metadata = errConsume.Error(), 9, "id", ctx.Metadata
+
+			actual, _ := sonic.Marshal(letter)
+			expectedJSON, _ := sonic.Marshal(&expected)
+			if string(actual) != string(expectedJSON) {
+				t.Fatalf("expected %s, got %s", expectedJSON, actual)
+			}
+		})
+	}
+}
+
+func TestDeadLetterConsumer(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	tests := []struct {
+		consumeErr    error
+		writeErr      error
+		name          string
+		expectedAcks  int
+		expectedWrite int
+	}{
+		{name: "consumed event is not written"},
+		{name: "failed event is written and acked", consumeErr: errConsume, expectedAcks: 1, expectedWrite: 1},
+		{name: "event which cannot be written is left unacked", consumeErr: errConsume, writeErr: errors.New("write"), expectedWrite: 1},
+		{name: "event of a stopped retry is not written", consumeErr: errRetryStopped},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			writes := 0
+			queue := models.DeadLetterQueueFunc(func(_ *models.DeadLetter) error {
+				writes++
+				return tt.writeErr
+			})
+
+			consumer := NewDeadLetterConsumer(consumerFunc(func(_ *models.ListenerContext) error {
+				return tt.consumeErr
+			}), queue)
+
+			acks := 0
+			consumer.ConsumeEvent(&models.ListenerContext{
+				Event: models.DcpExpiration{DcpExpiration: &gocbcore.DcpExpiration{Key: []byte("key")}},
+				Ack:   func() { acks++ },
+			})
+
+			if acks != tt.expectedAcks || writes != tt.expectedWrite {
+				t.Fatalf("expected %d acks and %d writes, got %d acks and %d writes", tt.expectedAcks, tt.expectedWrite, acks, writes)
+			}
+		})
+	}
+}
+
+func TestFileDeadLetterQueue(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	queue := NewFileDeadLetterQueue(fileName)
+
+	for _, key := range []string{"first", "second"} {
+		if err := queue.Write(&models.DeadLetter{Key: key, Error: "failed"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var keys []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var letter models.DeadLetter
+		if err = sonic.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, letter.Key)
+	}
+
+	if len(keys) != 2 || keys[0] != "first" || keys[1] != "second" {
+		t.Fatalf("letters must be appended as json lines, got %v", keys)
+	}
+}
+
+func TestFileDeadLetterQueue_WriteError(t *testing.T) {
+	queue := NewFileDeadLetterQueue(filepath.Join(t.TempDir(), "missing", "dead-letters.jsonl"))
+
+	if err := queue.Write(&models.DeadLetter{Key: "key"}); err == nil {
+		t.Fatalf("expected error for a file which cannot be opened")
+	}
+}
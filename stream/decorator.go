@@ -0,0 +1,21 @@
+package stream
+
+// decorate generates the id of the event and collects the metadata of the decorators, before the event is wrapped
+// by the binary policy. The metadata is nil when no decorator is set.
+func (s *stream) decorate(payload interface{}) (string, map[string]string) {
+	var id string
+	if s.idGenerator != nil {
+		id = s.idGenerator(payload)
+	}
+
+	if len(s.decorators) == 0 {
+		return id, nil
+	}
+
+	metadata := make(map[string]string, len(s.decorators))
+	for _, decorator := range s.decorators {
+		decorator(payload, metadata)
+	}
+
+	return id, metadata
+}
@@ -0,0 +1,191 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// DownstreamHealth probes the sink of the consumer and reports when it becomes unhealthy or healthy again. The state
+// changes only after the thresholds of downstreamHealth in a row, so a flapping sink does not pause and resume the
+// intake on every probe.
+type DownstreamHealth interface {
+	Start()
+	Stop()
+	IsHealthy() bool
+	Failures() int64
+	Pauses() int64
+}
+
+type downstreamHealth struct {
+	probe    models.HealthProbe
+	changed  func(healthy bool)
+	config   *config.DownstreamHealth
+	clock    clock.Clock
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	failures atomic.Int64
+	pauses   atomic.Int64
+	streak   int
+	healthy  atomic.Bool
+}
+
+func (h *downstreamHealth) Start() {
+	h.stopCh = make(chan struct{})
+
+	h.wg.Add(1)
+	go h.run(h.stopCh)
+
+	logger.Log.Info("downstream health will be probed with %v interval", h.config.Interval)
+}
+
+func (h *downstreamHealth) Stop() {
+	close(h.stopCh)
+	h.wg.Wait()
+
+	logger.Log.Debug("downstream health stopped")
+}
+
+func (h *downstreamHealth) IsHealthy() bool {
+	return h.healthy.Load()
+}
+
+func (h *downstreamHealth) Failures() int64 {
+	return h.failures.Load()
+}
+
+func (h *downstreamHealth) Pauses() int64 {
+	return h.pauses.Load()
+}
+
+func (h *downstreamHealth) run(stopCh chan struct{}) {
+	defer h.wg.Done()
+
+	ticker := h.clock.NewTicker(h.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C():
+			h.check()
+		}
+	}
+}
+
+func (h *downstreamHealth) check() {
+	// the timeout is taken from the clock, so a fake clock times the probe out without waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := h.clock.AfterFunc(h.config.Timeout, cancel)
+	err := h.probe(ctx)
+	timer.Stop()
+	cancel()
+
+	if err != nil {
+		h.failures.Add(1)
+		logger.Log.Debug("downstream health probe failed, err: %v", err)
+	}
+
+	healthy := h.healthy.Load()
+	if (err == nil) == healthy {
+		h.streak = 0
+		return
+	}
+
+	h.streak++
+
+	threshold := h.config.SuccessThreshold
+	if healthy {
+		threshold = h.config.FailureThreshold
+	}
+
+	if h.streak < threshold {
+		return
+	}
+
+	h.streak = 0
+	h.healthy.Store(!healthy)
+
+	if healthy {
+		h.pauses.Add(1)
+		logger.Log.Warn("downstream is unhealthy after %d failed probes, err: %v", threshold, err)
+	} else {
+		logger.Log.Info("downstream is healthy after %d successful probes", threshold)
+	}
+
+	h.changed(!healthy)
+}
+
+func newHTTPProbe(address string) models.HealthProbe {
+	client := &http.Client{}
+
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		_ = resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("downstream health returned status code: %d", resp.StatusCode)
+		}
+
+		return nil
+	}
+}
+
+func newTCPProbe(address string) models.HealthProbe {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
+
+// NewDownstreamHealth creates the downstream health with the probe, or with the probe of downstreamHealth.type when
+// the probe is nil. changed is called when the downstream becomes unhealthy or healthy again. Probes are scheduled and
+// timed out with the clock.
+func NewDownstreamHealth(
+	healthConfig *config.DownstreamHealth, probe models.HealthProbe, changed func(healthy bool), clock clock.Clock,
+) (DownstreamHealth, error) {
+	if probe == nil {
+		switch healthConfig.Type {
+		case config.DownstreamHealthTypeHTTP:
+			probe = newHTTPProbe(healthConfig.Address)
+		case config.DownstreamHealthTypeTCP:
+			probe = newTCPProbe(healthConfig.Address)
+		default:
+			return nil, fmt.Errorf("unknown downstream health type: %s", healthConfig.Type)
+		}
+	}
+
+	h := &downstreamHealth{
+		probe:   probe,
+		changed: changed,
+		config:  healthConfig,
+		clock:   clock,
+	}
+	h.healthy.Store(true)
+
+	return h, nil
+}
@@ -0,0 +1,172 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+var errProbe = errors.New("probe")
+
+func newTestDownstreamHealth(t *testing.T, results *[]error) (*downstreamHealth, *[]bool, *clock.Fake) {
+	t.Helper()
+
+	logger.InitDefaultLogger("error")
+
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	var changes []bool
+
+	probe := func(_ context.Context) error {
+		err := (*results)[0]
+		*results = (*results)[1:]
+		return err
+	}
+
+	health, err := NewDownstreamHealth(&config.DownstreamHealth{
+		Interval:         time.Second,
+		Timeout:          time.Second,
+		FailureThreshold: 3,
+		SuccessThreshold: 2,
+	}, probe, func(healthy bool) { changes = append(changes, healthy) }, fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return health.(*downstreamHealth), &changes, fake
+}
+
+func TestDownstreamHealth_Hysteresis(t *testing.T) {
+	tests := []struct {
+		name            string
+		results         []error
+		expectedChanges []bool
+		expectedHealthy bool
+		expectedPauses  int64
+	}{
+		{
+			name:            "failures below the threshold",
+			results:         []error{errProbe, errProbe, nil, errProbe, errProbe},
+			expectedHealthy: true,
+		},
+		{
+			name:            "failures reach the threshold",
+			results:         []error{errProbe, errProbe, errProbe},
+			expectedChanges: []bool{false},
+			expectedPauses:  1,
+		},
+		{
+			name:            "successes below the threshold",
+			results:         []error{errProbe, errProbe, errProbe, nil, errProbe, nil},
+			expectedChanges: []bool{false},
+			expectedPauses:  1,
+		},
+		{
+			name:            "successes reach the threshold",
+			results:         []error{errProbe, errProbe, errProbe, nil, nil},
+			expectedChanges: []bool{false, true},
+			expectedHealthy: true,
+			expectedPauses:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			results := append([]error(nil), tt.results...)
+			health, changes, _ := newTestDownstreamHealth(t, &results)
+
+			for range tt.results {
+				health.check()
+			}
+
+			if !reflect.DeepEqual(*changes, tt.expectedChanges) {
+				t.Fatalf("expected changes %v, got %v", tt.expectedChanges, *changes)
+			}
+
+			if health.IsHealthy() != tt.expectedHealthy || health.Pauses() != tt.expectedPauses {
+				t.Fatalf("expected healthy %v with %d pauses, got %v with %d",
+					tt.expectedHealthy, tt.expectedPauses, health.IsHealthy(), health.Pauses())
+			}
+		})
+	}
+}
+
+func TestDownstreamHealth_ProbesOnInterval(t *testing.T) {
+	results := []error{errProbe, errProbe, errProbe}
+	health, changes, fake := newTestDownstreamHealth(t, &results)
+
+	health.Start()
+
+	// the ticker is pending from the start, each tick is advanced after the probe of the previous one
+	fake.BlockUntil(1)
+
+	for i := int64(1); i <= 3; i++ {
+		fake.Advance(time.Second)
+
+		deadline := time.Now().Add(time.Second)
+		for health.Failures() < i {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d probes, got %d", i, health.Failures())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// stop waits the last probe, its change is recorded after the failure is counted
+	health.Stop()
+
+	if health.IsHealthy() {
+		t.Fatalf("downstream must be unhealthy after 3 failed probes")
+	}
+
+	if health.Failures() != 3 || len(*changes) != 1 {
+		t.Fatalf("expected 3 failures and 1 change, got %d and %v", health.Failures(), *changes)
+	}
+}
+
+func TestDownstreamHealth_ProbeTimesOutWithClock(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	probe := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	health, err := NewDownstreamHealth(&config.DownstreamHealth{
+		Interval:         time.Second,
+		Timeout:          5 * time.Second,
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+	}, probe, func(bool) {}, fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		health.(*downstreamHealth).check()
+		close(done)
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(5 * time.Second)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("probe must time out when the clock passes the timeout")
+	}
+
+	if health.IsHealthy() || health.Failures() != 1 {
+		t.Fatalf("timed out probe must fail, failures: %d", health.Failures())
+	}
+}
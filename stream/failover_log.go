@@ -0,0 +1,33 @@
+package stream
+
+// FailoverLogEntry is an entry of the failover log of a vBucket, a new entry is added with each failover of the
+// vBucket. A checkpoint resumes from the entry with its vbUUID, a seqNo behind its checkpoint rolls it back.
+type FailoverLogEntry struct {
+	VbUUID uint64 `json:"vbUuid"`
+	SeqNo  uint64 `json:"seqNo"`
+}
+
+// GetFailoverLogs returns the failover logs of the vBuckets, the logs of every vBucket when vbIDs is empty. The
+// newest entry of a log is the first one.
+func (s *stream) GetFailoverLogs(vbIDs []uint16) (map[uint16][]FailoverLogEntry, error) {
+	if len(vbIDs) == 0 {
+		vbIDs = allVBucketIDs(s.client.GetNumVBuckets())
+	}
+
+	failoverLogs, err := s.client.GetFailoverLogs(vbIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make(map[uint16][]FailoverLogEntry, len(failoverLogs))
+	for vbID, entries := range failoverLogs {
+		log := make([]FailoverLogEntry, 0, len(entries))
+		for _, entry := range entries {
+			log = append(log, FailoverLogEntry{VbUUID: uint64(entry.VbUUID), SeqNo: uint64(entry.SeqNo)})
+		}
+
+		logs[vbID] = log
+	}
+
+	return logs, nil
+}
@@ -0,0 +1,228 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type SchedulerMetric struct {
+	Queued   int
+	InFlight int
+	Starved  int64
+}
+
+type scheduledTask struct {
+	enqueuedAt time.Time
+	run        func()
+	epoch      uint64
+}
+
+type vbQueue struct {
+	tasks     chan scheduledTask
+	starved   atomic.Int64
+	inFlight  int
+	scheduled bool
+}
+
+// fairScheduler delivers events of the vBuckets in round robin order with a worker pool, so a hot vBucket
+// cannot starve the others. Events of a vBucket are delivered one at a time and in order, and a vBucket is
+// skipped while it has maxInFlight events which are consumed but not acked yet.
+type fairScheduler struct {
+	queues       map[uint16]*vbQueue
+	runQueue     chan uint16
+	stopCh       chan struct{}
+	pauseGate    *pauseGate
+	wg           sync.WaitGroup
+	epoch        uint64
+	workers      int
+	maxInFlight  int
+	starvedAfter time.Duration
+	lock         sync.Mutex
+	running      bool
+}
+
+// Schedule queues the task of the vBucket, it blocks while the stream is paused or the queue of the vBucket is full.
+// The observer calls it, so blocking while paused keeps the dcp buffer unacked as the listener does without the
+// scheduler.
+func (s *fairScheduler) Schedule(vbID uint16, run func()) {
+	if !s.pauseGate.Wait() {
+		return
+	}
+
+	q, ok := s.queues[vbID]
+	if !ok {
+		run()
+		return
+	}
+
+	s.lock.Lock()
+	stopCh, epoch, running := s.stopCh, s.epoch, s.running
+	s.lock.Unlock()
+
+	if !running {
+		return
+	}
+
+	select {
+	case q.tasks <- scheduledTask{enqueuedAt: time.Now(), run: run, epoch: epoch}:
+	case <-stopCh:
+		return
+	}
+
+	s.lock.Lock()
+	s.trySchedule(vbID, q)
+	s.lock.Unlock()
+}
+
+// Acquire marks an event of the vBucket as in flight until Release is called.
+func (s *fairScheduler) Acquire(vbID uint16) {
+	if q, ok := s.queues[vbID]; ok {
+		s.lock.Lock()
+		q.inFlight++
+		s.lock.Unlock()
+	}
+}
+
+func (s *fairScheduler) Release(vbID uint16) {
+	if q, ok := s.queues[vbID]; ok {
+		s.lock.Lock()
+		if q.inFlight > 0 {
+			q.inFlight--
+		}
+		s.trySchedule(vbID, q)
+		s.lock.Unlock()
+	}
+}
+
+// trySchedule puts the vBucket to the run queue when it has a task to deliver. It must be called with the lock held.
+func (s *fairScheduler) trySchedule(vbID uint16, q *vbQueue) {
+	if !s.running || q.scheduled || len(q.tasks) == 0 || (s.maxInFlight > 0 && q.inFlight >= s.maxInFlight) {
+		return
+	}
+
+	q.scheduled = true
+	s.runQueue <- vbID
+}
+
+func (s *fairScheduler) work(stopCh chan struct{}, epoch uint64) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case vbID := <-s.runQueue:
+			q := s.queues[vbID]
+			task := <-q.tasks
+
+			// Tasks of a previous start are dropped, they may be queued while stopping.
+			if task.epoch == epoch && s.pauseGate.Wait() {
+				if time.Since(task.enqueuedAt) > s.starvedAfter {
+					q.starved.Add(1)
+				}
+
+				task.run()
+			}
+
+			s.lock.Lock()
+			q.scheduled = false
+			s.trySchedule(vbID, q)
+			s.lock.Unlock()
+		}
+	}
+}
+
+func (s *fairScheduler) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.running {
+		return
+	}
+
+	s.running = true
+	s.stopCh = make(chan struct{})
+
+	s.wg.Add(s.workers)
+	for i := 0; i < s.workers; i++ {
+		go s.work(s.stopCh, s.epoch)
+	}
+}
+
+// Stop waits for the workers and drops the undelivered tasks, they are streamed again from the checkpoint.
+func (s *fairScheduler) Stop() {
+	s.lock.Lock()
+	if !s.running {
+		s.lock.Unlock()
+		return
+	}
+
+	s.running = false
+	s.epoch++
+	close(s.stopCh)
+	s.lock.Unlock()
+
+	s.wg.Wait()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for len(s.runQueue) > 0 {
+		<-s.runQueue
+	}
+
+	for _, q := range s.queues {
+		for len(q.tasks) > 0 {
+			<-q.tasks
+		}
+
+		q.inFlight = 0
+		q.scheduled = false
+	}
+}
+
+func (s *fairScheduler) GetMetric() map[uint16]SchedulerMetric {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	metric := make(map[uint16]SchedulerMetric, len(s.queues))
+	for vbID, q := range s.queues {
+		metric[vbID] = SchedulerMetric{
+			Queued:   len(q.tasks),
+			InFlight: q.inFlight,
+			Starved:  q.starved.Load(),
+		}
+	}
+
+	return metric
+}
+
+func newFairScheduler(
+	numVBuckets int,
+	workers int,
+	maxInFlight int,
+	queueSize int,
+	starvedAfter time.Duration,
+	pauseGate *pauseGate,
+) *fairScheduler {
+	queues := make(map[uint16]*vbQueue, numVBuckets)
+	for vbID := 0; vbID < numVBuckets; vbID++ {
+		queues[uint16(vbID)] = &vbQueue{
+			tasks: make(chan scheduledTask, queueSize),
+		}
+	}
+
+	stopCh := make(chan struct{})
+	close(stopCh)
+
+	return &fairScheduler{
+		queues:       queues,
+		runQueue:     make(chan uint16, numVBuckets),
+		stopCh:       stopCh,
+		pauseGate:    pauseGate,
+		workers:      workers,
+		maxInFlight:  maxInFlight,
+		starvedAfter: starvedAfter,
+	}
+}
@@ -0,0 +1,211 @@
+package stream
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition is not met in time")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFairScheduler_Ordering(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		events  int
+	}{
+		{name: "single worker", workers: 1, events: 50},
+		{name: "more workers than vBuckets", workers: 8, events: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newFairScheduler(2, tt.workers, 0, tt.events, time.Minute, newPauseGate())
+			scheduler.Start()
+			defer scheduler.Stop()
+
+			var lock sync.Mutex
+			delivered := map[uint16][]int{}
+
+			for i := 0; i < tt.events; i++ {
+				for vbID := uint16(0); vbID < 2; vbID++ {
+					i, vbID := i, vbID
+					scheduler.Schedule(vbID, func() {
+						lock.Lock()
+						delivered[vbID] = append(delivered[vbID], i)
+						lock.Unlock()
+					})
+				}
+			}
+
+			waitFor(t, func() bool {
+				lock.Lock()
+				defer lock.Unlock()
+				return len(delivered[0]) == tt.events && len(delivered[1]) == tt.events
+			})
+
+			for vbID, events := range delivered {
+				for i, event := range events {
+					if event != i {
+						t.Fatalf("events of vbID: %d must be delivered in order, got %v", vbID, events)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFairScheduler_MaxInFlight(t *testing.T) {
+	scheduler := newFairScheduler(1, 2, 1, 10, time.Minute, newPauseGate())
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	var delivered atomic.Int32
+
+	for i := 0; i < 3; i++ {
+		scheduler.Schedule(0, func() {
+			scheduler.Acquire(0)
+			delivered.Add(1)
+		})
+	}
+
+	waitFor(t, func() bool { return delivered.Load() == 1 })
+	time.Sleep(10 * time.Millisecond)
+
+	if delivered.Load() != 1 {
+		t.Fatalf("vBucket must be skipped while it has maxInFlight events, delivered %d", delivered.Load())
+	}
+
+	if metric := scheduler.GetMetric()[0]; metric.InFlight != 1 || metric.Queued != 2 {
+		t.Fatalf("unexpected metric %+v", metric)
+	}
+
+	scheduler.Release(0)
+	waitFor(t, func() bool { return delivered.Load() == 2 })
+
+	scheduler.Release(0)
+	waitFor(t, func() bool { return delivered.Load() == 3 })
+}
+
+func TestFairScheduler_StopDropsPendingEvents(t *testing.T) {
+	scheduler := newFairScheduler(1, 1, 1, 10, time.Minute, newPauseGate())
+	scheduler.Start()
+
+	var delivered []string
+	var lock sync.Mutex
+
+	deliver := func(name string) func() {
+		return func() {
+			scheduler.Acquire(0)
+
+			lock.Lock()
+			delivered = append(delivered, name)
+			lock.Unlock()
+		}
+	}
+
+	scheduler.Schedule(0, deliver("first"))
+	scheduler.Schedule(0, deliver("pending"))
+
+	waitFor(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(delivered) == 1
+	})
+
+	scheduler.Stop()
+
+	if metric := scheduler.GetMetric()[0]; metric.InFlight != 0 || metric.Queued != 0 {
+		t.Fatalf("stop must drop the pending events, got %+v", metric)
+	}
+
+	scheduler.Schedule(0, deliver("stopped"))
+
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	scheduler.Schedule(0, deliver("restarted"))
+
+	waitFor(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(delivered) == 2
+	})
+
+	if expected := []string{"first", "restarted"}; !reflect.DeepEqual(delivered, expected) {
+		t.Fatalf("expected %v, got %v", expected, delivered)
+	}
+}
+
+func TestFairScheduler_ScheduleBlocksWhilePaused(t *testing.T) {
+	pauseGate := newPauseGate()
+	scheduler := newFairScheduler(1, 1, 0, 10, time.Minute, pauseGate)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	pauseGate.Pause(PauseReasonManual)
+
+	var delivered atomic.Int32
+	scheduled := make(chan struct{})
+
+	go func() {
+		scheduler.Schedule(0, func() { delivered.Add(1) })
+		close(scheduled)
+	}()
+
+	select {
+	case <-scheduled:
+		t.Fatalf("schedule must block the observer while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if metric := scheduler.GetMetric()[0]; metric.Queued != 0 {
+		t.Fatalf("events must not be queued while paused, got %+v", metric)
+	}
+
+	pauseGate.Resume(PauseReasonManual)
+
+	<-scheduled
+	waitFor(t, func() bool { return delivered.Load() == 1 })
+}
+
+func TestFairScheduler_ScheduleDropsReleasedEvents(t *testing.T) {
+	pauseGate := newPauseGate()
+	scheduler := newFairScheduler(1, 1, 0, 10, time.Minute, pauseGate)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	pauseGate.Pause(PauseReasonManual)
+	pauseGate.Release()
+
+	ran := false
+	scheduler.Schedule(0, func() { ran = true })
+
+	if metric := scheduler.GetMetric()[0]; ran || metric.Queued != 0 {
+		t.Fatalf("events must be dropped when released while paused")
+	}
+}
+
+func TestFairScheduler_UnknownVBucketRunsAtOnce(t *testing.T) {
+	scheduler := newFairScheduler(1, 1, 0, 1, time.Minute, newPauseGate())
+
+	ran := false
+	scheduler.Schedule(7, func() { ran = true })
+
+	if !ran {
+		t.Fatalf("events of a vBucket without a queue must run at once")
+	}
+}
@@ -0,0 +1,143 @@
+package stream
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+// flushNotifier is implemented by the stream to notify the event handler of a flush found by the checkpoint.
+type flushNotifier interface {
+	notifyBucketFlush(vbIDs []uint16)
+}
+
+// detectFlush reports a bucket flush. A flush resets the seqnos and the failover logs of all vBuckets, so every
+// offset is ahead of its vBucket and its vbUUID is not in the failover log anymore. Otherwise, a vBucket behind
+// its offset is a failover, which is left to the rollback handling. Every vBucket behind is checked, a failover of
+// one of them must not be taken for a flush.
+func detectFlush(client couchbase.Client, offsets map[uint16]*models.Offset, seqNos *wrapper.ConcurrentSwissMap[uint16, uint64]) bool {
+	var behind []uint16
+
+	for vbID, offset := range offsets {
+		if offset.SeqNo == 0 {
+			continue
+		}
+
+		if seqNo, _ := seqNos.Load(vbID); seqNo >= offset.SeqNo {
+			return false
+		}
+
+		behind = append(behind, vbID)
+	}
+
+	if len(behind) == 0 {
+		return false
+	}
+
+	for _, vbID := range behind {
+		failOverLogs, err := client.GetFailOverLogs(vbID)
+		if err != nil {
+			logger.Log.Warn("error while get failOver logs to detect flush, vbID: %d, err: %v", vbID, err)
+			return false
+		}
+
+		for _, log := range failOverLogs {
+			if log.VbUUID == offsets[vbID].VbUUID {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// newFlushOffset returns the offset of a flushed vBucket with dcp.flush.reset.
+func newFlushOffset(client couchbase.Client, cfg *config.Dcp, vbID uint16, seqNo uint64, latestSeqNo uint64) (*models.Offset, error) {
+	if cfg.Dcp.Flush.Reset != config.FlushResetLatest {
+		return &models.Offset{
+			SnapshotMarker: &models.SnapshotMarker{},
+			LatestSeqNo:    latestSeqNo,
+		}, nil
+	}
+
+	var vbUUID gocbcore.VbUUID
+
+	if seqNo != 0 {
+		failOverLogs, err := client.GetFailOverLogs(vbID)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(failOverLogs) == 0 {
+			return nil, fmt.Errorf("failOver logs of vbID: %d are empty", vbID)
+		}
+
+		vbUUID = failOverLogs[0].VbUUID
+	}
+
+	return &models.Offset{
+		SnapshotMarker: &models.SnapshotMarker{
+			StartSeqNo: seqNo,
+			EndSeqNo:   seqNo,
+		},
+		VbUUID:      vbUUID,
+		SeqNo:       seqNo,
+		LatestSeqNo: latestSeqNo,
+	}, nil
+}
+
+// resetFlushedOffsets moves the offsets of the stream to dcp.flush.reset when the bucket is flushed while streaming.
+// It runs before a vBucket is opened again, the first of the re-opens finds the flush and resets all vBuckets.
+func (s *stream) resetFlushedOffsets() error {
+	s.flushLock.Lock()
+	defer s.flushLock.Unlock()
+
+	seqNos, err := s.client.GetVBucketSeqNos(false)
+	if err != nil {
+		return err
+	}
+
+	offsets := map[uint16]*models.Offset{}
+	s.offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		offsets[vbID] = offset
+		return true
+	})
+
+	if !detectFlush(s.client, offsets, seqNos) {
+		return nil
+	}
+
+	logger.Log.Warn("bucket flush detected, offsets are reset to %v", s.config.Dcp.Flush.Reset)
+
+	vbIDs := make([]uint16, 0, len(offsets))
+
+	for vbID := range offsets {
+		seqNo, _ := seqNos.Load(vbID)
+
+		offset, err := newFlushOffset(s.client, s.config, vbID, seqNo, seqNo)
+		if err != nil {
+			return err
+		}
+
+		s.setOffset(vbID, offset, true)
+		vbIDs = append(vbIDs, vbID)
+	}
+
+	s.anyDirtyOffset = true
+	s.notifyBucketFlush(vbIDs)
+
+	return nil
+}
+
+func (s *stream) notifyBucketFlush(vbIDs []uint16) {
+	if handler, ok := s.eventHandler.(models.BucketFlushHandler); ok {
+		handler.OnBucketFlushed(models.BucketFlushed{Time: time.Now(), VbIDs: vbIDs})
+	}
+}
@@ -0,0 +1,146 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+type flushClient struct {
+	couchbase.Client
+	failoverLogs map[uint16][]gocbcore.FailoverEntry
+	err          error
+}
+
+func (c *flushClient) GetFailOverLogs(vbID uint16) ([]gocbcore.FailoverEntry, error) {
+	return c.failoverLogs[vbID], c.err
+}
+
+func TestDetectFlush(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	offsets := map[uint16]*models.Offset{
+		0: {VbUUID: 1, SeqNo: 100},
+		1: {VbUUID: 2, SeqNo: 100},
+		2: {VbUUID: 3, SeqNo: 0},
+	}
+
+	tests := []struct {
+		name         string
+		seqNos       map[uint16]uint64
+		failoverLogs map[uint16][]gocbcore.FailoverEntry
+		err          error
+		expected     bool
+	}{
+		{
+			name:   "every vBucket is behind with a new branch",
+			seqNos: map[uint16]uint64{0: 5, 1: 5},
+			failoverLogs: map[uint16][]gocbcore.FailoverEntry{
+				0: {{VbUUID: 10}},
+				1: {{VbUUID: 20}},
+			},
+			expected: true,
+		},
+		{
+			name:   "a vBucket is not behind",
+			seqNos: map[uint16]uint64{0: 5, 1: 100},
+			failoverLogs: map[uint16][]gocbcore.FailoverEntry{
+				0: {{VbUUID: 10}},
+			},
+		},
+		{
+			name:   "a vBucket behind has its vbUUID in the failover log",
+			seqNos: map[uint16]uint64{0: 5, 1: 5},
+			failoverLogs: map[uint16][]gocbcore.FailoverEntry{
+				0: {{VbUUID: 10}},
+				1: {{VbUUID: 21}, {VbUUID: 2}},
+			},
+		},
+		{
+			name:   "failover logs cannot be read",
+			seqNos: map[uint16]uint64{0: 5, 1: 5},
+			err:    errors.New("failover log"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			seqNos := wrapper.CreateConcurrentSwissMap[uint16, uint64](0)
+			for vbID, seqNo := range tt.seqNos {
+				seqNos.Store(vbID, seqNo)
+			}
+
+			client := &flushClient{failoverLogs: tt.failoverLogs, err: tt.err}
+
+			if actual := detectFlush(client, offsets, seqNos); actual != tt.expected {
+				t.Fatalf("expected flush %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewFlushOffset(t *testing.T) {
+	tests := []struct {
+		name         string
+		reset        string
+		seqNo        uint64
+		failoverLogs []gocbcore.FailoverEntry
+		expected     *models.Offset
+		expectedErr  bool
+	}{
+		{
+			name:     "earliest",
+			reset:    config.FlushResetEarliest,
+			seqNo:    10,
+			expected: &models.Offset{SnapshotMarker: &models.SnapshotMarker{}, LatestSeqNo: 10},
+		},
+		{
+			name:         "latest",
+			reset:        config.FlushResetLatest,
+			seqNo:        10,
+			failoverLogs: []gocbcore.FailoverEntry{{VbUUID: 7}},
+			expected: &models.Offset{
+				SnapshotMarker: &models.SnapshotMarker{StartSeqNo: 10, EndSeqNo: 10}, VbUUID: 7, SeqNo: 10, LatestSeqNo: 10,
+			},
+		},
+		{
+			name:     "latest of an empty vBucket",
+			reset:    config.FlushResetLatest,
+			expected: &models.Offset{SnapshotMarker: &models.SnapshotMarker{}},
+		},
+		{
+			name:        "latest with empty failover logs",
+			reset:       config.FlushResetLatest,
+			seqNo:       10,
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Dcp{}
+			cfg.Dcp.Flush.Reset = tt.reset
+
+			client := &flushClient{failoverLogs: map[uint16][]gocbcore.FailoverEntry{0: tt.failoverLogs}}
+
+			offset, err := newFlushOffset(client, cfg, 0, tt.seqNo, tt.seqNo)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+
+			if !reflect.DeepEqual(offset, tt.expected) {
+				t.Fatalf("expected offset %+v, got %+v", tt.expected, offset)
+			}
+		})
+	}
+}
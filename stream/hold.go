@@ -0,0 +1,63 @@
+package stream
+
+import (
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// SetHoldMode holds the position of the stream while it is enabled, e.g. during a maintenance of the downstream. The
+// streams stay open and the events update the stats and the lag, but they are not passed to the consumer and the
+// offsets do not advance. When it is disabled, the stream is re-opened from the held offsets if any event is dropped.
+func (s *stream) SetHoldMode(enabled bool) {
+	s.rebalanceLock.Lock()
+	defer s.rebalanceLock.Unlock()
+
+	if s.hold.Swap(enabled) == enabled {
+		return
+	}
+
+	logger.Log.Info("hold mode is set to %v", enabled)
+
+	if enabled || !s.holdDropped.Swap(false) || !s.IsOpen() {
+		return
+	}
+
+	logger.Log.Info("stream is re-opening from the held offsets")
+
+	// the acks of the events delivered before the hold are saved, the dropped ones are streamed again
+	if s.config.Checkpoint.Type == CheckpointTypeAuto {
+		s.checkpoint.Save()
+	}
+
+	s.Close(false)
+	s.Open()
+}
+
+func (s *stream) IsInHoldMode() bool {
+	return s.hold.Load()
+}
+
+// holdEvent drops the event while the hold mode is enabled, it is still counted in the stats of its vBucket.
+func (s *stream) holdEvent(event interface{}) bool {
+	if !s.hold.Load() {
+		return false
+	}
+
+	switch v := event.(type) {
+	case models.DcpSnapshotMarker:
+		s.vBucketStats.Snapshot(v)
+		return true
+	case models.DcpMutation:
+		s.vBucketStats.Sent(v.VbID, v.SeqNo, true)
+	case models.DcpDeletion:
+		s.vBucketStats.Sent(v.VbID, v.SeqNo, true)
+	case models.DcpExpiration:
+		s.vBucketStats.Sent(v.VbID, v.SeqNo, true)
+	case models.DcpSeqNoAdvanced:
+		s.vBucketStats.Sent(v.VbID, v.Offset.SeqNo, false)
+	}
+
+	s.holdDropped.Store(true)
+
+	return true
+}
@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// canRebalanceIncrementally reports whether a rebalance can move only the vBuckets whose owner changes, the streams
+// of the other vBuckets keep their offsets and observers. The state store, the finite mode and the servers which
+// do not support stream end need the whole stream to be opened again.
+func (s *stream) canRebalanceIncrementally() bool {
+	return s.open && s.stateStore == nil && !s.config.IsDcpModeFinite() && s.streamEndNotSupportedData == nil
+}
+
+// releaseVBuckets closes the streams of the vBuckets which are not assigned to the member anymore, so their new
+// owners do not stream them at the same time while the rebalance waits for the delay.
+func (s *stream) releaseVBuckets() {
+	s.reassignLock.Lock()
+	defer s.reassignLock.Unlock()
+
+	lost := difference(s.vbIDs, s.vBucketDiscovery.Get())
+	if len(lost) == 0 {
+		return
+	}
+
+	s.closeVBuckets(lost)
+	s.vbIDs = difference(s.vbIDs, lost)
+
+	logger.Log.Info("%d vBuckets are released", len(lost))
+}
+
+// reassignVBuckets closes the streams of the lost vBuckets and opens the streams of the gained ones.
+func (s *stream) reassignVBuckets() {
+	s.reassignLock.Lock()
+	defer s.reassignLock.Unlock()
+
+	assigned := s.vBucketDiscovery.Get()
+	lost := difference(s.vbIDs, assigned)
+	gained := difference(assigned, s.vbIDs)
+
+	s.closeVBuckets(lost)
+
+	s.vbIDs = assigned
+
+	s.openVBuckets(gained)
+
+	if (len(lost) > 0 || len(gained) > 0) && !s.config.RollbackMitigation.Disabled {
+		s.rollbackMitigation.Stop()
+		s.rollbackMitigation = couchbase.NewRollbackMitigation(s.client, s.config, assigned, s.dispatchPersistSeqNo, s.clock)
+		s.rollbackMitigation.Start()
+	}
+
+	logger.Log.Info("vBuckets are reassigned, released: %d, acquired: %d", len(lost), len(gained))
+}
+
+func (s *stream) closeVBuckets(vbIDs []uint16) {
+	if len(vbIDs) == 0 {
+		return
+	}
+
+	s.stateLock.RLock()
+	observers := s.observers
+	s.stateLock.RUnlock()
+
+	s.snapshotTracer.Close(vbIDs)
+
+	// the end of a released stream is not a stream failure, so it is not passed to listenEnd
+	for _, vbID := range vbIDs {
+		if observer, ok := observers.Load(vbID); ok {
+			observer.Close()
+			observer.CloseEnd()
+		}
+	}
+
+	ctx := s.streamCtx()
+
+	var wg sync.WaitGroup
+	wg.Add(len(vbIDs))
+
+	for _, vbID := range vbIDs {
+		go func(vbID uint16) {
+			if err := s.client.CloseStream(ctx, vbID); err != nil {
+				logger.Ctx(ctx).Error("cannot close stream, vbID: %d, err: %v", vbID, err)
+			}
+
+			wg.Done()
+		}(vbID)
+	}
+
+	wg.Wait()
+
+	s.activeStreams.Add(-int32(len(vbIDs)))
+
+	// the new owners start from the latest offsets of the released vBuckets
+	if s.config.Checkpoint.Type == CheckpointTypeAuto {
+		s.checkpoint.Save()
+	}
+
+	s.stateLock.Lock()
+	for _, vbID := range vbIDs {
+		s.offsets.Delete(vbID)
+		s.dirtyOffsets.Delete(vbID)
+		s.observers.Delete(vbID)
+	}
+	s.stateLock.Unlock()
+
+	for _, vbID := range vbIDs {
+		s.resetTimes.Delete(vbID)
+	}
+
+	s.checkpoint.RemoveVBuckets(vbIDs)
+	s.watermarks.Remove(vbIDs)
+
+	if s.handoff != nil {
+		s.handoff.Release(vbIDs)
+	}
+}
+
+func (s *stream) openVBuckets(vbIDs []uint16) {
+	if len(vbIDs) == 0 {
+		return
+	}
+
+	if s.handoff != nil {
+		s.handoff.Claim(vbIDs)
+	}
+
+	offsets, dirtyOffsets, anyDirtyOffset := s.checkpoint.AddVBuckets(vbIDs)
+
+	// the identity of the member changes with the rebalance, the streams opened before keep the former one. It is
+	// derived from the root ctx, so the contexts do not chain with every rebalance.
+	discoveryMetric := s.vBucketDiscovery.GetMetric()
+
+	s.ctxLock.Lock()
+	ctx := models.NewStreamContext(s.rootCtx, &models.StreamIdentity{
+		GroupName:    s.config.Dcp.Group.Name,
+		MemberNumber: discoveryMetric.MemberNumber,
+		TotalMembers: discoveryMetric.TotalMembers,
+	})
+	s.ctx = ctx
+	s.ctxLock.Unlock()
+
+	s.stateLock.Lock()
+	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		s.offsets.Store(vbID, offset)
+		s.observers.Store(
+			vbID,
+			couchbase.NewObserver(ctx, s.config,
+				vbID, offset.LatestSeqNo, s.listen, s.listenEnd, s.collectionIDs, s.tracerComponent, s.clock,
+			),
+		)
+
+		return true
+	})
+	dirtyOffsets.Range(func(vbID uint16, dirty bool) bool {
+		s.dirtyOffsets.Store(vbID, dirty)
+		return true
+	})
+	s.anyDirtyOffset = s.anyDirtyOffset || anyDirtyOffset
+	s.stateLock.Unlock()
+
+	s.activeStreams.Add(int32(len(vbIDs)))
+	s.openAllStreams(vbIDs)
+}
+
+// difference returns the vBuckets of a which are not in b, in the order of a.
+func difference(a []uint16, b []uint16) []uint16 {
+	exclude := make(map[uint16]bool, len(b))
+	for _, vbID := range b {
+		exclude[vbID] = true
+	}
+
+	result := make([]uint16, 0, len(a))
+	for _, vbID := range a {
+		if !exclude[vbID] {
+			result = append(result, vbID)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,270 @@
+package stream
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/tracing"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+type rebalanceClient struct {
+	couchbase.Client
+	opened map[uint16]context.Context
+	closed []uint16
+	lock   sync.Mutex
+}
+
+func (c *rebalanceClient) OpenStream(
+	ctx context.Context, vbID uint16, _ map[uint32]string, _ *models.Offset, _ couchbase.Observer,
+) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.opened[vbID] = ctx
+	return nil
+}
+
+func (c *rebalanceClient) CloseStream(_ context.Context, vbID uint16) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = append(c.closed, vbID)
+	return nil
+}
+
+func (c *rebalanceClient) openedVBuckets() []uint16 {
+	vbIDs := make([]uint16, 0, len(c.opened))
+	for vbID := range c.opened {
+		vbIDs = append(vbIDs, vbID)
+	}
+
+	return sortVBuckets(vbIDs)
+}
+
+type rebalanceDiscovery struct {
+	VBucketDiscovery
+	assigned     []uint16
+	memberNumber int
+}
+
+func (d *rebalanceDiscovery) Get() []uint16 {
+	return d.assigned
+}
+
+func (d *rebalanceDiscovery) GetMetric() *VBucketDiscoveryMetric {
+	return &VBucketDiscoveryMetric{MemberNumber: d.memberNumber, TotalMembers: 2}
+}
+
+type rebalanceCheckpoint struct {
+	Checkpoint
+	removed []uint16
+	saves   int
+}
+
+func (c *rebalanceCheckpoint) Save() {
+	c.saves++
+}
+
+func (c *rebalanceCheckpoint) AddVBuckets(
+	vbIDs []uint16,
+) (*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool) {
+	offsets := wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](0)
+	dirtyOffsets := wrapper.CreateConcurrentSwissMap[uint16, bool](0)
+
+	for _, vbID := range vbIDs {
+		offsets.Store(vbID, &models.Offset{})
+		dirtyOffsets.Store(vbID, false)
+	}
+
+	return offsets, dirtyOffsets, false
+}
+
+func (c *rebalanceCheckpoint) RemoveVBuckets(vbIDs []uint16) {
+	c.removed = append(c.removed, vbIDs...)
+}
+
+func sortVBuckets(vbIDs []uint16) []uint16 {
+	sort.Slice(vbIDs, func(i, j int) bool { return vbIDs[i] < vbIDs[j] })
+	return vbIDs
+}
+
+func newRebalanceTestStream(vbIDs []uint16, assigned []uint16) (*stream, *rebalanceClient, *rebalanceCheckpoint) {
+	client := &rebalanceClient{opened: map[uint16]context.Context{}}
+	checkpoint := &rebalanceCheckpoint{}
+
+	cfg := &config.Dcp{
+		RollbackMitigation: config.RollbackMitigation{Disabled: true},
+		Checkpoint:         config.Checkpoint{Type: CheckpointTypeAuto},
+	}
+
+	s := &stream{
+		client:           client,
+		checkpoint:       checkpoint,
+		vBucketDiscovery: &rebalanceDiscovery{assigned: assigned, memberNumber: 2},
+		config:           cfg,
+		clock:            clock.New(),
+		collectionIDs:    map[uint32]string{},
+		tracerComponent:  tracing.NewTracerComponent(),
+		snapshotTracer:   newSnapshotTracer(nil),
+		watermarks:       newWatermarkTracker(),
+		offsets:          wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](0),
+		dirtyOffsets:     wrapper.CreateConcurrentSwissMap[uint16, bool](0),
+		observers:        wrapper.CreateConcurrentSwissMap[uint16, couchbase.Observer](0),
+		resetTimes:       wrapper.CreateConcurrentSwissMap[uint16, time.Time](0),
+		vbIDs:            vbIDs,
+	}
+
+	s.rootCtx, s.cancelCtx = context.WithCancel(context.Background())
+	s.ctx = models.NewStreamContext(s.rootCtx, &models.StreamIdentity{MemberNumber: 1, TotalMembers: 2})
+
+	for _, vbID := range vbIDs {
+		s.offsets.Store(vbID, &models.Offset{})
+		s.observers.Store(vbID, couchbase.NewObserver(s.ctx, cfg, vbID, 0, s.listen, s.listenEnd, s.collectionIDs,
+			s.tracerComponent, s.clock))
+	}
+
+	s.activeStreams.Store(int32(len(vbIDs)))
+
+	return s, client, checkpoint
+}
+
+func TestStream_ReassignVBuckets(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	tests := []struct {
+		name           string
+		vbIDs          []uint16
+		assigned       []uint16
+		expectedClosed []uint16
+		expectedOpened []uint16
+		expectedSaves  int
+	}{
+		{
+			name:           "lost vBuckets are closed and gained ones are opened",
+			vbIDs:          []uint16{0, 1, 2},
+			assigned:       []uint16{1, 2, 3, 4},
+			expectedClosed: []uint16{0},
+			expectedOpened: []uint16{3, 4},
+			expectedSaves:  1,
+		},
+		{
+			name:           "streams are kept when the assignment does not change",
+			vbIDs:          []uint16{0, 1},
+			assigned:       []uint16{0, 1},
+			expectedClosed: []uint16{},
+			expectedOpened: []uint16{},
+		},
+		{
+			name:           "every vBucket is released",
+			vbIDs:          []uint16{0, 1},
+			assigned:       []uint16{},
+			expectedClosed: []uint16{0, 1},
+			expectedOpened: []uint16{},
+			expectedSaves:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client, checkpoint := newRebalanceTestStream(tt.vbIDs, tt.assigned)
+
+			s.reassignVBuckets()
+
+			if closed := sortVBuckets(append([]uint16{}, client.closed...)); !reflect.DeepEqual(closed, tt.expectedClosed) {
+				t.Errorf("expected closed %v, got %v", tt.expectedClosed, closed)
+			}
+
+			if opened := client.openedVBuckets(); !reflect.DeepEqual(opened, tt.expectedOpened) {
+				t.Errorf("expected opened %v, got %v", tt.expectedOpened, opened)
+			}
+
+			if !reflect.DeepEqual(s.vbIDs, tt.assigned) {
+				t.Errorf("vBuckets must be the assigned ones, got %v", s.vbIDs)
+			}
+
+			if s.offsets.Count() != len(tt.assigned) || s.observers.Count() != len(tt.assigned) {
+				t.Errorf("offsets and observers must be kept for the assigned vBuckets only")
+			}
+
+			if int(s.activeStreams.Load()) != len(tt.assigned) {
+				t.Errorf("expected %d active streams, got %d", len(tt.assigned), s.activeStreams.Load())
+			}
+
+			if checkpoint.saves != tt.expectedSaves {
+				t.Errorf("expected %d saves, got %d", tt.expectedSaves, checkpoint.saves)
+			}
+		})
+	}
+}
+
+func TestStream_CloseVBuckets(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	s, client, checkpoint := newRebalanceTestStream([]uint16{0, 1, 2}, nil)
+
+	s.closeVBuckets([]uint16{0, 2})
+
+	if closed := sortVBuckets(client.closed); !reflect.DeepEqual(closed, []uint16{0, 2}) {
+		t.Errorf("streams of the closed vBuckets must be closed, got %v", closed)
+	}
+
+	if _, ok := s.offsets.Load(1); !ok || s.offsets.Count() != 1 {
+		t.Errorf("only the offsets of the closed vBuckets must be removed")
+	}
+
+	if !reflect.DeepEqual(checkpoint.removed, []uint16{0, 2}) {
+		t.Errorf("closed vBuckets must be removed from the checkpoint, got %v", checkpoint.removed)
+	}
+
+	if s.activeStreams.Load() != 1 {
+		t.Errorf("expected 1 active stream, got %d", s.activeStreams.Load())
+	}
+
+	s.closeVBuckets(nil)
+
+	if len(client.closed) != 2 || checkpoint.saves != 1 {
+		t.Errorf("closing no vBuckets must be a no-op")
+	}
+}
+
+func TestStream_OpenVBuckets(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	s, client, _ := newRebalanceTestStream(nil, nil)
+	rootCtx, cancel := s.rootCtx, s.cancelCtx
+
+	s.openVBuckets([]uint16{5})
+	s.openVBuckets([]uint16{6})
+
+	for vbID, ctx := range client.opened {
+		if identity := models.StreamIdentityFromContext(ctx); identity == nil || identity.MemberNumber != 2 {
+			t.Errorf("stream of vbID: %d must be opened with the identity after the rebalance, got %+v", vbID, identity)
+		}
+	}
+
+	if s.streamCtx() != client.opened[6] {
+		t.Errorf("stream ctx must be the ctx of the latest opened streams")
+	}
+
+	if s.activeStreams.Load() != 2 || s.offsets.Count() != 2 || s.observers.Count() != 2 {
+		t.Errorf("opened vBuckets must be tracked")
+	}
+
+	cancel()
+
+	for vbID, ctx := range client.opened {
+		if ctx.Err() == nil {
+			t.Errorf("stream ctx of vbID: %d must be derived from the root ctx %v", vbID, rootCtx)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package stream
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+// keyFilter accepts the keys which have one of the prefixes and match the regex, when they are set.
+// A nil filter accepts every key.
+type keyFilter struct {
+	regex    *regexp.Regexp
+	prefixes [][]byte
+}
+
+func (f *keyFilter) Match(key []byte) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.prefixes) > 0 {
+		matched := false
+		for _, prefix := range f.prefixes {
+			if bytes.HasPrefix(key, prefix) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return f.regex == nil || f.regex.Match(key)
+}
+
+func newKeyFilter(filter config.DCPFilter) *keyFilter {
+	if filter.KeyRegex == "" && len(filter.KeyPrefixes) == 0 {
+		return nil
+	}
+
+	f := &keyFilter{}
+
+	// the regex is validated with the config
+	if filter.KeyRegex != "" {
+		f.regex = regexp.MustCompile(filter.KeyRegex)
+	}
+
+	for _, prefix := range filter.KeyPrefixes {
+		f.prefixes = append(f.prefixes, []byte(prefix))
+	}
+
+	return f
+}
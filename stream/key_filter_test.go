@@ -0,0 +1,45 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+func TestKeyFilter_Match(t *testing.T) {
+	prefixes := config.DCPFilter{KeyPrefixes: []string{"user:", "order:"}}
+	regex := config.DCPFilter{KeyRegex: "^order:[0-9]+$"}
+
+	tests := []struct {
+		name     string
+		key      string
+		filter   config.DCPFilter
+		expected bool
+	}{
+		{name: "every key is included without a filter", key: "order:1", expected: true},
+		{name: "key with a prefix is included", key: "order:1", filter: prefixes, expected: true},
+		{name: "key without a prefix is excluded", key: "cart:1", filter: prefixes},
+		{name: "key matching the regex is included", key: "order:1", filter: regex, expected: true},
+		{name: "key not matching the regex is excluded", key: "order:a", filter: regex},
+		{
+			name:     "key must match both the prefix and the regex",
+			key:      "order:a",
+			filter:   config.DCPFilter{KeyPrefixes: []string{"order:"}, KeyRegex: "[0-9]$"},
+			expected: false,
+		},
+		{
+			name:     "key matching both the prefix and the regex is included",
+			key:      "order:1",
+			filter:   config.DCPFilter{KeyPrefixes: []string{"order:"}, KeyRegex: "[0-9]$"},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matched := newKeyFilter(tt.filter).Match([]byte(tt.key)); matched != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, matched)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"sort"
+	"sync"
+)
+
+const latencyWindowSize = 1024
+
+type LatencySnapshot struct {
+	Last  int64  `json:"last"`
+	P50   int64  `json:"p50"`
+	P95   int64  `json:"p95"`
+	P99   int64  `json:"p99"`
+	Sum   int64  `json:"sum"`
+	Count uint64 `json:"count"`
+}
+
+// LatencyHistogram keeps the latest latency samples in a fixed size window
+// and calculates percentiles over them.
+type LatencyHistogram struct {
+	samples []int64
+	next    int
+	last    int64
+	sum     int64
+	count   uint64
+	lock    sync.RWMutex
+	filled  bool
+}
+
+func (h *LatencyHistogram) Record(latency int64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.samples[h.next] = latency
+	h.next++
+	if h.next == len(h.samples) {
+		h.next = 0
+		h.filled = true
+	}
+
+	h.last = latency
+	h.sum += latency
+	h.count++
+}
+
+func (h *LatencyHistogram) Snapshot() LatencySnapshot {
+	h.lock.RLock()
+
+	size := h.next
+	if h.filled {
+		size = len(h.samples)
+	}
+
+	window := make([]int64, size)
+	copy(window, h.samples[:size])
+
+	snapshot := LatencySnapshot{
+		Last:  h.last,
+		Sum:   h.sum,
+		Count: h.count,
+	}
+
+	h.lock.RUnlock()
+
+	sort.Slice(window, func(i, j int) bool {
+		return window[i] < window[j]
+	})
+
+	snapshot.P50 = percentile(window, 0.5)
+	snapshot.P95 = percentile(window, 0.95)
+	snapshot.P99 = percentile(window, 0.99)
+
+	return snapshot
+}
+
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+
+	return sorted[rank]
+}
+
+func NewLatencyHistogram(windowSize int) *LatencyHistogram {
+	if windowSize <= 0 {
+		windowSize = latencyWindowSize
+	}
+
+	return &LatencyHistogram{
+		samples: make([]int64, windowSize),
+	}
+}
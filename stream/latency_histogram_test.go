@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"testing"
+)
+
+func TestLatencyHistogram_Snapshot(t *testing.T) {
+	tests := []struct {
+		name       string
+		windowSize int
+		samples    int
+		expected   LatencySnapshot
+	}{
+		{
+			name:       "empty",
+			windowSize: 10,
+			expected:   LatencySnapshot{},
+		},
+		{
+			name:       "single sample",
+			windowSize: 10,
+			samples:    1,
+			expected:   LatencySnapshot{Last: 1, P50: 1, P95: 1, P99: 1, Sum: 1, Count: 1},
+		},
+		{
+			name:       "full window",
+			windowSize: 100,
+			samples:    100,
+			expected:   LatencySnapshot{Last: 100, P50: 50, P95: 95, P99: 99, Sum: 5050, Count: 100},
+		},
+		{
+			// percentiles are of the latest 100 samples, 101..200, sum and count are of all samples
+			name:       "wrapped window",
+			windowSize: 100,
+			samples:    200,
+			expected:   LatencySnapshot{Last: 200, P50: 150, P95: 195, P99: 199, Sum: 20100, Count: 200},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewLatencyHistogram(tt.windowSize)
+
+			for i := 1; i <= tt.samples; i++ {
+				h.Record(int64(i))
+			}
+
+			if snapshot := h.Snapshot(); snapshot != tt.expected {
+				t.Fatalf("expected %+v, got %+v", tt.expected, snapshot)
+			}
+		})
+	}
+}
+
+func TestLatencyHistogram_SnapshotSortsWindow(t *testing.T) {
+	h := NewLatencyHistogram(10)
+
+	for _, latency := range []int64{30, 10, 40, 20} {
+		h.Record(latency)
+	}
+
+	expected := LatencySnapshot{Last: 20, P50: 20, P95: 40, P99: 40, Sum: 100, Count: 4}
+	if snapshot := h.Snapshot(); snapshot != expected {
+		t.Fatalf("expected %+v, got %+v", expected, snapshot)
+	}
+}
+
+func TestLatencyHistogram_DefaultWindowSize(t *testing.T) {
+	h := NewLatencyHistogram(0)
+
+	if len(h.samples) != latencyWindowSize {
+		t.Fatalf("expected window size %d, got %d", latencyWindowSize, len(h.samples))
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []int64{10, 20, 30, 40}
+
+	tests := []struct {
+		p        float64
+		expected int64
+	}{
+		{p: 0, expected: 10},
+		{p: 0.5, expected: 20},
+		{p: 0.95, expected: 40},
+		{p: 1, expected: 40},
+	}
+
+	for _, tt := range tests {
+		if value := percentile(sorted, tt.p); value != tt.expected {
+			t.Errorf("p%v: expected %d, got %d", tt.p*100, tt.expected, value)
+		}
+	}
+}
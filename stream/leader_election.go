@@ -37,14 +37,17 @@ type leaderElection struct {
 	config           *config.Dcp
 	newLeaderLock    *sync.Mutex
 	elector          leaderelector.LeaderElector
+	leaderTasks      LeaderTaskRunner
 }
 
 func (l *leaderElection) OnBecomeLeader() {
 	l.serviceDiscovery.BeLeader()
 	l.serviceDiscovery.RemoveLeader()
+	l.leaderTasks.Start()
 }
 
 func (l *leaderElection) OnResignLeader() {
+	l.leaderTasks.Stop()
 	l.serviceDiscovery.DontBeLeader()
 	l.serviceDiscovery.RemoveAll()
 }
@@ -53,6 +56,7 @@ func (l *leaderElection) OnBecomeFollower(leaderIdentity *models.Identity) {
 	l.newLeaderLock.Lock()
 	defer l.newLeaderLock.Unlock()
 
+	l.leaderTasks.Stop()
 	l.serviceDiscovery.DontBeLeader()
 	l.serviceDiscovery.RemoveAll()
 	l.serviceDiscovery.RemoveLeader()
@@ -96,6 +100,7 @@ func (l *leaderElection) Start() {
 }
 
 func (l *leaderElection) Stop() {
+	l.leaderTasks.Stop()
 	l.elector.Close()
 	l.rpcServer.Shutdown()
 }
@@ -104,11 +109,13 @@ func NewLeaderElection(
 	config *config.Dcp,
 	serviceDiscovery servicediscovery.ServiceDiscovery,
 	bus EventBus.Bus,
+	leaderTasks LeaderTaskRunner,
 ) LeaderElection {
 	return &leaderElection{
 		config:           config,
 		serviceDiscovery: serviceDiscovery,
 		newLeaderLock:    &sync.Mutex{},
 		bus:              bus,
+		leaderTasks:      leaderTasks,
 	}
 }
@@ -0,0 +1,92 @@
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type LeaderTaskRunner interface {
+	Register(task models.LeaderTask)
+	Start()
+	Stop()
+	IsRunning() bool
+}
+
+type leaderTaskRunner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	tasks  []models.LeaderTask
+	wg     sync.WaitGroup
+	lock   sync.Mutex
+}
+
+// Register adds the task, it is started right away when the runner is already running.
+func (r *leaderTaskRunner) Register(task models.LeaderTask) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.tasks = append(r.tasks, task)
+
+	if r.cancel != nil {
+		r.run(task)
+	}
+}
+
+func (r *leaderTaskRunner) Start() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+
+	for _, task := range r.tasks {
+		r.run(task)
+	}
+
+	logger.Log.Info("leader tasks started, count: %d", len(r.tasks))
+}
+
+func (r *leaderTaskRunner) Stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.cancel == nil {
+		return
+	}
+
+	r.cancel()
+	r.wg.Wait()
+
+	r.ctx, r.cancel = nil, nil
+
+	logger.Log.Info("leader tasks stopped")
+}
+
+func (r *leaderTaskRunner) IsRunning() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.cancel != nil
+}
+
+func (r *leaderTaskRunner) run(task models.LeaderTask) {
+	r.wg.Add(1)
+
+	go func(ctx context.Context) {
+		defer r.wg.Done()
+
+		logger.Log.Debug("leader task %s started", task.Name())
+		task.Run(ctx)
+		logger.Log.Debug("leader task %s finished", task.Name())
+	}(r.ctx)
+}
+
+func NewLeaderTaskRunner() LeaderTaskRunner {
+	return &leaderTaskRunner{}
+}
@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/logger"
+)
+
+// countingTask counts its runs and blocks till its ctx is done.
+type countingTask struct {
+	started  chan struct{}
+	runs     int32
+	finished int32
+}
+
+func newCountingTask() *countingTask {
+	return &countingTask{started: make(chan struct{}, 10)}
+}
+
+func (t *countingTask) Name() string {
+	return "counting"
+}
+
+func (t *countingTask) Run(ctx context.Context) {
+	atomic.AddInt32(&t.runs, 1)
+	t.started <- struct{}{}
+	<-ctx.Done()
+	atomic.AddInt32(&t.finished, 1)
+}
+
+func (t *countingTask) waitStarted(tb testing.TB) {
+	tb.Helper()
+
+	select {
+	case <-t.started:
+	case <-time.After(time.Second):
+		tb.Fatalf("task must be started")
+	}
+}
+
+func TestLeaderTaskRunner_StartStop(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	runner := NewLeaderTaskRunner()
+	task := newCountingTask()
+	runner.Register(task)
+
+	if runner.IsRunning() || atomic.LoadInt32(&task.runs) != 0 {
+		t.Fatalf("task must not run before start")
+	}
+
+	runner.Start()
+	runner.Start()
+	task.waitStarted(t)
+
+	if !runner.IsRunning() {
+		t.Fatalf("runner must be running")
+	}
+
+	runner.Stop()
+
+	if runner.IsRunning() || atomic.LoadInt32(&task.finished) != 1 {
+		t.Fatalf("stop must wait the task to finish")
+	}
+
+	if runs := atomic.LoadInt32(&task.runs); runs != 1 {
+		t.Fatalf("second start must not run the task again, got %d runs", runs)
+	}
+
+	// a member which becomes leader again runs the task again
+	runner.Start()
+	task.waitStarted(t)
+	runner.Stop()
+
+	if runs := atomic.LoadInt32(&task.runs); runs != 2 {
+		t.Fatalf("expected 2 runs, got %d", runs)
+	}
+}
+
+func TestLeaderTaskRunner_RegisterWhileRunning(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	runner := NewLeaderTaskRunner()
+	runner.Start()
+
+	task := newCountingTask()
+	runner.Register(task)
+	task.waitStarted(t)
+
+	runner.Stop()
+
+	if atomic.LoadInt32(&task.finished) != 1 {
+		t.Fatalf("task registered while running must be stopped")
+	}
+}
+
+func TestLeaderTaskRunner_StopWithoutStart(t *testing.T) {
+	runner := NewLeaderTaskRunner()
+	runner.Stop()
+
+	if runner.IsRunning() {
+		t.Fatalf("runner must not be running")
+	}
+}
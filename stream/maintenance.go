@@ -0,0 +1,44 @@
+package stream
+
+import "github.com/Trendyol/go-dcp/logger"
+
+// SetMaintenanceMode defers the rebalances while it is enabled, so the churn of a rolling deployment does not move
+// the vBuckets on every restart. A rebalance which has already started is completed. When it is disabled, one
+// rebalance applies the latest membership if any was deferred.
+func (s *stream) SetMaintenanceMode(enabled bool) {
+	s.maintenanceLock.Lock()
+	deferred := s.rebalanceDeferred && !enabled
+	s.maintenance = enabled
+	s.rebalanceDeferred = s.rebalanceDeferred && enabled
+	s.maintenanceLock.Unlock()
+
+	logger.Log.Info("maintenance mode is set to %v", enabled)
+
+	if deferred {
+		logger.Log.Info("deferred rebalance is starting")
+		s.Rebalance()
+	}
+}
+
+func (s *stream) IsInMaintenanceMode() bool {
+	s.maintenanceLock.Lock()
+	defer s.maintenanceLock.Unlock()
+
+	return s.maintenance
+}
+
+// deferRebalance reports whether a new rebalance is deferred till the maintenance mode is disabled. The timer of a
+// started rebalance calls Rebalance again, it is not deferred.
+func (s *stream) deferRebalance() bool {
+	s.maintenanceLock.Lock()
+	defer s.maintenanceLock.Unlock()
+
+	if !s.maintenance || s.balancing {
+		return false
+	}
+
+	s.rebalanceDeferred = true
+	logger.Log.Info("rebalance is deferred, maintenance mode is enabled")
+
+	return true
+}
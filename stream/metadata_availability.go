@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// metadataAvailabilityNotifier is implemented by streams which notify the event handler of metadata availability.
+type metadataAvailabilityNotifier interface {
+	notifyMetadataAvailability(event models.MetadataAvailability)
+}
+
+func (s *stream) notifyMetadataAvailability(event models.MetadataAvailability) {
+	if handler, ok := s.eventHandler.(models.MetadataAvailabilityHandler); ok {
+		handler.OnMetadataAvailability(event)
+	}
+}
+
+// saveWithRetry saves the checkpoint with checkpoint.retry, the backoff is doubled after each failed attempt.
+func (s *checkpoint) saveWithRetry(dump map[uint16]*models.CheckpointDocument, dirtyOffsets map[uint16]bool) error {
+	backoff := s.config.Checkpoint.Retry.Backoff
+
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = s.metadata.Save(dump, dirtyOffsets, s.bucketUUID); err == nil {
+			return nil
+		}
+
+		if attempt >= s.config.Checkpoint.Retry.MaxAttempts {
+			return err
+		}
+
+		logger.Log.Warn("error while saving checkpoint document, attempt: %d, retrying in %v, err: %v", attempt, backoff, err)
+
+		s.clock.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// saveFailed keeps the offsets dirty for the next save, and pauses the intake when the latest saved checkpoint
+// is older than checkpoint.maxStaleness, so the offsets to be streamed again after a crash stay bounded.
+func (s *checkpoint) saveFailed(err error) {
+	now := s.clock.Now()
+
+	s.metric.SaveFailure++
+
+	if s.metric.UnavailableSince.IsZero() {
+		s.metric.UnavailableSince = now
+		logger.Log.Error("metadata is unavailable, offsets are kept till the next save, err: %v", err)
+		s.notifyAvailability(now, err)
+	}
+
+	maxStaleness := s.config.Checkpoint.MaxStaleness
+	if maxStaleness == 0 || s.pausedIntake || now.Sub(s.metric.LastSavedAt) < maxStaleness {
+		return
+	}
+
+	logger.Log.Warn("checkpoint is not saved for %v, pausing intake till metadata is available", now.Sub(s.metric.LastSavedAt))
+
+	s.stream.PauseFor(PauseReasonMetadataUnavailable)
+	s.pausedIntake = true
+	s.notifyAvailability(s.metric.UnavailableSince, err)
+}
+
+// saveSucceeded resumes the intake paused by saveFailed.
+func (s *checkpoint) saveSucceeded() {
+	s.metric.LastSavedAt = s.clock.Now()
+
+	if s.metric.UnavailableSince.IsZero() {
+		return
+	}
+
+	since := s.metric.UnavailableSince
+	s.metric.UnavailableSince = time.Time{}
+
+	logger.Log.Info("metadata is available again, unavailable for %v", s.metric.LastSavedAt.Sub(since))
+
+	if s.pausedIntake {
+		s.pausedIntake = false
+		s.stream.ResumeFor(PauseReasonMetadataUnavailable)
+	}
+
+	s.notifyAvailability(since, nil)
+}
+
+func (s *checkpoint) notifyAvailability(since time.Time, err error) {
+	notifier, ok := s.stream.(metadataAvailabilityNotifier)
+	if !ok {
+		return
+	}
+
+	notifier.notifyMetadataAvailability(models.MetadataAvailability{
+		UnavailableSince: since,
+		LastSavedAt:      s.metric.LastSavedAt,
+		Err:              err,
+		Available:        err == nil,
+		IntakePaused:     s.pausedIntake,
+	})
+}
@@ -0,0 +1,45 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStream_GetMetric(t *testing.T) {
+	s := &stream{metric: newMetric()}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 1000; i++ {
+			atomic.AddInt64(&s.metric.Filtered, 1)
+			atomic.StoreInt64(&s.metric.ClockSkew, int64(i))
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 1000; i++ {
+			metric, _ := s.GetMetric()
+			if metric.Filtered < 0 || metric.Filtered > 1000 {
+				t.Errorf("unexpected filtered count %d", metric.Filtered)
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	metric, _ := s.GetMetric()
+	if metric.Filtered != 1000 || metric.ClockSkew != 999 {
+		t.Errorf("metric must be copied with the latest values, got %+v", metric)
+	}
+
+	if metric.ProcessLatency != s.metric.ProcessLatency {
+		t.Errorf("latency histograms must be shared")
+	}
+}
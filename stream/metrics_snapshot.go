@@ -0,0 +1,92 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+	"github.com/couchbase/gocbcore/v10"
+)
+
+// MetricsSnapshotVersion is increased whenever a MetricsSnapshot field is removed or changes its meaning.
+const MetricsSnapshotVersion = 1
+
+type ObserverSnapshot struct {
+	Metric       couchbase.ObserverMetric
+	PersistSeqNo gocbcore.SeqNo
+}
+
+// MetricsSnapshot is a point in time copy of the stream metrics. It does not share any state with
+// the stream, so it can be read safely while the stream is closed, opened or rebalanced.
+type MetricsSnapshot struct {
+	CreatedAt        time.Time
+	Watermark        time.Time
+	Observers        map[uint16]ObserverSnapshot
+	Offsets          map[uint16]models.Offset
+	Scheduler        map[uint16]SchedulerMetric
+	PurgeMargin      map[uint16]int64
+	Checkpoint       CheckpointMetric
+	ProcessLatency   LatencySnapshot
+	DcpLatency       LatencySnapshot
+	ClockSkew        int64
+	DcpRawLatency    int64
+	Reconnect        int64
+	ReconnectFailure int64
+	Filtered         int64
+	Binary           int64
+	Invalid          int64
+	Version          int
+	Rebalance        int64
+	ActiveStreams    int32
+	Open             bool
+	Paused           bool
+	Held             bool
+}
+
+func newMetricsSnapshot(
+	observers *wrapper.ConcurrentSwissMap[uint16, couchbase.Observer],
+	offsets *wrapper.ConcurrentSwissMap[uint16, *models.Offset],
+	metric *Metric,
+	activeStreams int32,
+	checkpointMetric *CheckpointMetric,
+	paused bool,
+) *MetricsSnapshot {
+	snapshot := &MetricsSnapshot{
+		Version:          MetricsSnapshotVersion,
+		CreatedAt:        time.Now(),
+		Offsets:          make(map[uint16]models.Offset, offsets.Count()),
+		Checkpoint:       *checkpointMetric,
+		ProcessLatency:   metric.ProcessLatency.Snapshot(),
+		DcpLatency:       metric.DcpLatency.Snapshot(),
+		ClockSkew:        metric.ClockSkew,
+		DcpRawLatency:    metric.DcpRawLatency,
+		Reconnect:        metric.Reconnect,
+		ReconnectFailure: metric.ReconnectFailure,
+		Filtered:         metric.Filtered,
+		Binary:           metric.Binary,
+		Invalid:          metric.Invalid,
+		Rebalance:        metric.Rebalance,
+		ActiveStreams:    activeStreams,
+		Open:             observers != nil,
+		Paused:           paused,
+	}
+
+	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		snapshot.Offsets[vbID] = *offset
+		return true
+	})
+
+	if observers != nil {
+		snapshot.Observers = make(map[uint16]ObserverSnapshot, observers.Count())
+		observers.Range(func(vbID uint16, observer couchbase.Observer) bool {
+			snapshot.Observers[vbID] = ObserverSnapshot{
+				Metric:       *observer.GetMetrics(),
+				PersistSeqNo: observer.GetPersistSeqNo(),
+			}
+			return true
+		})
+	}
+
+	return snapshot
+}
@@ -0,0 +1,206 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+const (
+	ResetPolicyTypeEarliest  = "earliest"
+	ResetPolicyTypeLatest    = "latest"
+	ResetPolicyTypeSeqNo     = "seqNo"
+	ResetPolicyTypeTimestamp = "timestamp"
+	ResetPolicyTypeHistory   = "history"
+)
+
+// ResetPolicy is where ResetOffsets moves the checkpoints of vBuckets. SeqNos is used by the seqNo type,
+// vBuckets missing in it are not reset. Timestamp is used by the timestamp and history types, the history type moves
+// each vBucket to the newest checkpoint of its history saved at or before it, vBuckets without one are not reset.
+type ResetPolicy struct {
+	Timestamp time.Time
+	SeqNos    map[uint16]uint64
+	Type      string
+}
+
+// ResetOffsets rewrites the checkpoints of the vBuckets with the policy, all vBuckets of the member when vbIDs is empty.
+// An open stream is closed without saving its offsets and opened again from the new checkpoints. The timestamp
+// type streams the vBuckets from the beginning and skips their events older than the timestamp, since the server
+// cannot seek by time.
+func (s *stream) ResetOffsets(policy ResetPolicy, vbIDs []uint16) error {
+	s.rebalanceLock.Lock()
+	defer s.rebalanceLock.Unlock()
+
+	open := s.IsOpen()
+
+	if len(vbIDs) == 0 {
+		if !open {
+			return errors.New("vBuckets must be given when the stream is not open")
+		}
+		vbIDs = s.vbIDs
+	}
+
+	if open {
+		owned := make(map[uint16]bool, len(s.vbIDs))
+		for _, vbID := range s.vbIDs {
+			owned[vbID] = true
+		}
+
+		for _, vbID := range vbIDs {
+			if !owned[vbID] {
+				return fmt.Errorf("vBucket %d is not streamed by this member", vbID)
+			}
+		}
+	}
+
+	bucketUUID := getBucketUUID(s.client)
+
+	current, _, err := s.metadata.Load(vbIDs, bucketUUID)
+	if err != nil {
+		return err
+	}
+
+	checkpoints, err := s.newResetCheckpoints(policy, vbIDs, bucketUUID, current)
+	if err != nil {
+		return err
+	}
+
+	// the history is kept, so the checkpoints can be reset to a former one again
+	for vbID, doc := range checkpoints {
+		if currentDoc, ok := current.Load(vbID); ok {
+			doc.History = currentDoc.History
+		}
+	}
+
+	if open {
+		s.Close(false)
+	}
+
+	dirtyOffsets := make(map[uint16]bool, len(checkpoints))
+	for vbID := range checkpoints {
+		dirtyOffsets[vbID] = true
+	}
+
+	err = s.metadata.Save(checkpoints, dirtyOffsets, bucketUUID)
+	if err == nil {
+		for vbID := range checkpoints {
+			if policy.Type == ResetPolicyTypeTimestamp {
+				s.resetTimes.Store(vbID, policy.Timestamp)
+			} else {
+				s.resetTimes.Delete(vbID)
+			}
+		}
+
+		logger.Log.Info("offsets of %d vBuckets are reset to %s", len(checkpoints), policy.Type)
+	}
+
+	if open {
+		s.Open()
+	}
+
+	return err
+}
+
+//nolint:funlen
+func (s *stream) newResetCheckpoints(
+	policy ResetPolicy,
+	vbIDs []uint16,
+	bucketUUID string,
+	current *wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument],
+) (map[uint16]*models.CheckpointDocument, error) {
+	checkpoints := make(map[uint16]*models.CheckpointDocument, len(vbIDs))
+
+	switch policy.Type {
+	case ResetPolicyTypeEarliest, ResetPolicyTypeTimestamp:
+		for _, vbID := range vbIDs {
+			checkpoints[vbID] = models.NewEmptyCheckpointDocument(bucketUUID)
+		}
+		return checkpoints, nil
+	case ResetPolicyTypeHistory:
+		for _, vbID := range vbIDs {
+			doc, _ := current.Load(vbID)
+			if entry := historyEntryAt(doc, policy.Timestamp); entry != nil {
+				checkpoints[vbID] = &models.CheckpointDocument{Checkpoint: entry.Checkpoint, BucketUUID: bucketUUID}
+			}
+		}
+		return checkpoints, nil
+	case ResetPolicyTypeLatest, ResetPolicyTypeSeqNo:
+	default:
+		return nil, fmt.Errorf("unknown reset policy type: %s", policy.Type)
+	}
+
+	seqNoMap, err := s.client.GetVBucketSeqNos(false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vbID := range vbIDs {
+		latestSeqNo, _ := seqNoMap.Load(vbID)
+
+		seqNo := latestSeqNo
+		if policy.Type == ResetPolicyTypeSeqNo {
+			var ok bool
+			if seqNo, ok = policy.SeqNos[vbID]; !ok {
+				continue
+			}
+
+			if seqNo > latestSeqNo {
+				return nil, fmt.Errorf("seqNo %d of vBucket %d is bigger than its latest seqNo %d", seqNo, vbID, latestSeqNo)
+			}
+		}
+
+		checkpoints[vbID] = models.NewEmptyCheckpointDocument(bucketUUID)
+
+		if seqNo == 0 {
+			continue
+		}
+
+		// The latest failover entry covers every seqNo up to the latest one, so the stream is not rolled back.
+		failOverLogs, err := s.client.GetFailOverLogs(vbID)
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoints[vbID].Checkpoint.VbUUID = uint64(failOverLogs[0].VbUUID)
+		checkpoints[vbID].Checkpoint.SeqNo = seqNo
+		checkpoints[vbID].Checkpoint.Snapshot.StartSeqNo = seqNo
+		checkpoints[vbID].Checkpoint.Snapshot.EndSeqNo = seqNo
+	}
+
+	return checkpoints, nil
+}
+
+// historyEntryAt returns the newest history entry of the checkpoint saved at or before the time.
+func historyEntryAt(doc *models.CheckpointDocument, at time.Time) *models.CheckpointHistoryEntry {
+	if doc == nil {
+		return nil
+	}
+
+	for i := len(doc.History) - 1; i >= 0; i-- {
+		if entry := doc.History[i]; entry.Checkpoint != nil && entry.SavedTime <= at.UnixNano() {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// isBeforeReset reports an event older than the timestamp the vBucket is reset to. Seqnos of a vBucket grow with time,
+// so events are skipped till the first later one.
+func (s *stream) isBeforeReset(vbID uint16, serverTime time.Time) bool {
+	resetTime, ok := s.resetTimes.Load(vbID)
+	if !ok {
+		return false
+	}
+
+	if serverTime.Before(resetTime) {
+		return true
+	}
+
+	s.resetTimes.Delete(vbID)
+	return false
+}
@@ -0,0 +1,234 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type pendingOffset struct {
+	offset *models.Offset
+	dirty  bool
+	done   bool
+}
+
+// parallelDispatcher consumes the events with a worker pool for the consumers which do not need the events of a
+// vBucket in order. Events of a key go to the same worker with models.OrderingPerKey, so they are consumed in order.
+// Acks can arrive in any order, the offset of a vBucket advances only when every former event of it is acked, so a
+// checkpoint never skips an event which is still being consumed.
+type parallelDispatcher struct {
+	queues  []chan func()
+	pending map[uint16][]*pendingOffset
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	workers int
+	lock    sync.Mutex
+	running bool
+}
+
+// Submit queues the consumption of an event, it blocks while the queue of the worker is full.
+func (d *parallelDispatcher) Submit(key []byte, run func()) {
+	d.lock.Lock()
+	stopCh, running := d.stopCh, d.running
+	d.lock.Unlock()
+
+	if !running {
+		return
+	}
+
+	queue := d.queues[0]
+	if len(d.queues) > 1 {
+		hash := fnv.New32a()
+		_, _ = hash.Write(key)
+		queue = d.queues[hash.Sum32()%uint32(len(d.queues))]
+	}
+
+	select {
+	case queue <- run:
+	case <-stopCh:
+	}
+}
+
+// Track records the offset of an event handed to the consumer, in the order of the events of the vBucket.
+func (d *parallelDispatcher) Track(vbID uint16, offset *models.Offset) *pendingOffset {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	pending := &pendingOffset{offset: offset, dirty: true}
+	d.pending[vbID] = append(d.pending[vbID], pending)
+
+	return pending
+}
+
+// Ack marks the event as consumed and applies the offsets of the vBucket which have no pending event before them.
+func (d *parallelDispatcher) Ack(vbID uint16, pending *pendingOffset, apply func(vbID uint16, offset *models.Offset, dirty bool)) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	pending.done = true
+	d.release(vbID, apply)
+}
+
+// Defer keeps the offset of an event which does not reach the consumer behind the pending events of the vBucket.
+// It reports false when there is no pending event, so the offset can be applied at once.
+func (d *parallelDispatcher) Defer(vbID uint16, offset *models.Offset, dirty bool) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.pending[vbID]) == 0 {
+		return false
+	}
+
+	d.pending[vbID] = append(d.pending[vbID], &pendingOffset{offset: offset, dirty: dirty, done: true})
+
+	return true
+}
+
+// release must be called with the lock held.
+func (d *parallelDispatcher) release(vbID uint16, apply func(vbID uint16, offset *models.Offset, dirty bool)) {
+	pending := d.pending[vbID]
+
+	n := 0
+	for n < len(pending) && pending[n].done {
+		apply(vbID, pending[n].offset, pending[n].dirty)
+		n++
+	}
+
+	if n == len(pending) {
+		delete(d.pending, vbID)
+	} else {
+		d.pending[vbID] = pending[n:]
+	}
+}
+
+func (d *parallelDispatcher) work(queue chan func(), stopCh chan struct{}) {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case run := <-queue:
+			run()
+		}
+	}
+}
+
+func (d *parallelDispatcher) Start() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.running {
+		return
+	}
+
+	d.running = true
+	d.stopCh = make(chan struct{})
+	// events tracked after the last stop are not consumed
+	d.pending = map[uint16][]*pendingOffset{}
+
+	d.wg.Add(d.workers)
+	for i := 0; i < d.workers; i++ {
+		go d.work(d.queues[i%len(d.queues)], d.stopCh)
+	}
+}
+
+// Stop waits for the workers and drops the unconsumed events and pending offsets, they are streamed again from the
+// checkpoint.
+func (d *parallelDispatcher) Stop() {
+	d.lock.Lock()
+	if !d.running {
+		d.lock.Unlock()
+		return
+	}
+
+	d.running = false
+	close(d.stopCh)
+	d.lock.Unlock()
+
+	d.wg.Wait()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, queue := range d.queues {
+		for len(queue) > 0 {
+			<-queue
+		}
+	}
+
+	d.pending = map[uint16][]*pendingOffset{}
+}
+
+func newParallelDispatcher(ordering string, workers int, queueSize int) *parallelDispatcher {
+	// workers share a queue when the order does not matter, so a slow event does not hold the others back
+	queues := make([]chan func(), 1)
+	if ordering == models.OrderingPerKey {
+		queues = make([]chan func(), workers)
+	}
+
+	for i := range queues {
+		queues[i] = make(chan func(), queueSize)
+	}
+
+	return &parallelDispatcher{
+		queues:  queues,
+		pending: map[uint16][]*pendingOffset{},
+		workers: workers,
+	}
+}
+
+func consumerCapabilities(consumer models.Consumer) (models.ConsumerCapabilities, error) {
+	capable, ok := consumer.(models.CapableConsumer)
+	if !ok {
+		return models.ConsumerCapabilities{}, nil
+	}
+
+	capabilities := capable.Capabilities()
+
+	switch capabilities.Ordering {
+	case "", models.OrderingPerVBucket, models.OrderingPerKey, models.OrderingNone:
+	default:
+		return capabilities, fmt.Errorf("unknown consumer ordering: %s", capabilities.Ordering)
+	}
+
+	if capabilities.Concurrency < 0 {
+		return capabilities, errors.New("consumer concurrency must not be negative")
+	}
+
+	return capabilities, nil
+}
+
+// applyCapabilities enables the parallelism the consumer allows, when dcp.listener.scheduler is not enabled by the
+// config. Consumers with a state store keep the order of their vBuckets, since the state is kept with the offsets.
+func (s *stream) applyCapabilities(capabilities models.ConsumerCapabilities, stateEnabled bool) {
+	scheduler := s.config.Dcp.Listener.Scheduler
+	if scheduler.Enabled || capabilities.Ordering == "" {
+		return
+	}
+
+	workers := capabilities.Concurrency
+	if workers == 0 {
+		workers = scheduler.Workers
+	}
+
+	ordering := capabilities.Ordering
+	if ordering != models.OrderingPerVBucket && stateEnabled {
+		logger.Log.Warn("consumer ordering %s is not supported with the state store, %s is used", ordering, models.OrderingPerVBucket)
+		ordering = models.OrderingPerVBucket
+	}
+
+	if ordering == models.OrderingPerVBucket {
+		s.scheduler = newFairScheduler(
+			s.client.GetNumVBuckets(), workers, scheduler.MaxInFlight, scheduler.QueueSize, scheduler.StarvedAfter, s.pauseGate,
+		)
+	} else {
+		s.parallel = newParallelDispatcher(ordering, workers, scheduler.QueueSize)
+	}
+
+	logger.Log.Info("consumer ordering: %s, concurrency: %d", ordering, workers)
+}
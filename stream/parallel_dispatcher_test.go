@@ -0,0 +1,223 @@
+package stream
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type offsetTrackingConsumer struct {
+	models.Consumer
+}
+
+func (c *offsetTrackingConsumer) TrackOffset(_ uint16, _ *models.Offset) {}
+
+func TestParallelDispatcher_OutOfOrderAcks(t *testing.T) {
+	tests := []struct {
+		name     string
+		ackOrder []int
+		applied  [][]uint64
+	}{
+		{
+			name:     "in order",
+			ackOrder: []int{0, 1, 2},
+			applied:  [][]uint64{{1}, {1, 2}, {1, 2, 3}},
+		},
+		{
+			name:     "reversed",
+			ackOrder: []int{2, 1, 0},
+			applied:  [][]uint64{nil, nil, {1, 2, 3}},
+		},
+		{
+			name:     "middle first",
+			ackOrder: []int{1, 0, 2},
+			applied:  [][]uint64{nil, {1, 2}, {1, 2, 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := newParallelDispatcher(models.OrderingNone, 1, 1)
+
+			pending := make([]*pendingOffset, 3)
+			for i := range pending {
+				pending[i] = dispatcher.Track(0, &models.Offset{SeqNo: uint64(i + 1)})
+			}
+
+			var applied []uint64
+			apply := func(_ uint16, offset *models.Offset, _ bool) {
+				applied = append(applied, offset.SeqNo)
+			}
+
+			for i, ack := range tt.ackOrder {
+				dispatcher.Ack(0, pending[ack], apply)
+
+				if !reflect.DeepEqual(applied, tt.applied[i]) {
+					t.Fatalf("after ack %d expected applied %v, got %v", i, tt.applied[i], applied)
+				}
+			}
+		})
+	}
+}
+
+func TestParallelDispatcher_Defer(t *testing.T) {
+	dispatcher := newParallelDispatcher(models.OrderingNone, 1, 1)
+
+	if dispatcher.Defer(0, &models.Offset{SeqNo: 1}, false) {
+		t.Fatalf("offset must be applied at once without a pending event")
+	}
+
+	pending := dispatcher.Track(0, &models.Offset{SeqNo: 2})
+
+	if !dispatcher.Defer(0, &models.Offset{SeqNo: 3}, false) {
+		t.Fatalf("offset must wait for the pending event")
+	}
+
+	var applied []uint64
+	dispatcher.Ack(0, pending, func(_ uint16, offset *models.Offset, _ bool) {
+		applied = append(applied, offset.SeqNo)
+	})
+
+	if !reflect.DeepEqual(applied, []uint64{2, 3}) {
+		t.Fatalf("deferred offset must be applied after the pending event, got %v", applied)
+	}
+}
+
+func TestParallelDispatcher_PerKeyOrdering(t *testing.T) {
+	dispatcher := newParallelDispatcher(models.OrderingPerKey, 4, 100)
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	var lock sync.Mutex
+	delivered := map[string][]int{}
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	for i := 0; i < 20; i++ {
+		for _, key := range keys {
+			i, key := i, key
+			dispatcher.Submit([]byte(key), func() {
+				lock.Lock()
+				delivered[key] = append(delivered[key], i)
+				lock.Unlock()
+			})
+		}
+	}
+
+	waitFor(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+
+		for _, key := range keys {
+			if len(delivered[key]) != 20 {
+				return false
+			}
+		}
+
+		return true
+	})
+
+	for key, events := range delivered {
+		for i, event := range events {
+			if event != i {
+				t.Fatalf("events of key: %s must be consumed in order, got %v", key, events)
+			}
+		}
+	}
+}
+
+func TestParallelDispatcher_StopAndStart(t *testing.T) {
+	dispatcher := newParallelDispatcher(models.OrderingNone, 1, 10)
+	dispatcher.Start()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+
+	var lock sync.Mutex
+	var delivered []string
+
+	dispatcher.Submit(nil, func() {
+		close(started)
+		<-block
+	})
+	<-started
+
+	dispatcher.Submit(nil, func() {
+		lock.Lock()
+		delivered = append(delivered, "pending")
+		lock.Unlock()
+	})
+	dispatcher.Track(0, &models.Offset{SeqNo: 1})
+
+	close(block)
+	dispatcher.Stop()
+
+	dispatcher.Submit(nil, func() {
+		lock.Lock()
+		delivered = append(delivered, "stopped")
+		lock.Unlock()
+	})
+
+	if dispatcher.Defer(0, &models.Offset{SeqNo: 2}, false) {
+		t.Fatalf("stop must drop the pending offsets")
+	}
+
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	dispatcher.Submit(nil, func() {
+		lock.Lock()
+		delivered = append(delivered, "restarted")
+		lock.Unlock()
+	})
+
+	waitFor(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(delivered) > 0 && delivered[len(delivered)-1] == "restarted"
+	})
+
+	for _, event := range delivered {
+		if event == "stopped" {
+			t.Fatalf("events submitted while stopped must not be consumed, got %v", delivered)
+		}
+	}
+}
+
+func TestStream_ParallelAcksAdvanceWatermarkInOrder(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	s, _, _ := newRebalanceTestStream([]uint16{0}, nil)
+	s.consumer = &offsetTrackingConsumer{}
+	s.parallel = newParallelDispatcher(models.OrderingNone, 1, 1)
+
+	start := time.Unix(0, 0)
+	eventTimes := []time.Time{start.Add(time.Second), start.Add(2 * time.Second), start.Add(3 * time.Second)}
+
+	pending := make([]*pendingOffset, len(eventTimes))
+	for i, eventTime := range eventTimes {
+		offset := &models.Offset{SeqNo: uint64(i + 1)}
+		s.watermarks.Observe(0, offset.SeqNo, eventTime)
+		pending[i] = s.parallel.Track(0, offset)
+	}
+
+	tests := []struct {
+		expected time.Time
+		ack      int
+	}{
+		{ack: 2, expected: eventTimes[0]},
+		{ack: 0, expected: eventTimes[1]},
+		{ack: 1, expected: eventTimes[2]},
+	}
+
+	for _, tt := range tests {
+		s.parallel.Ack(0, pending[tt.ack], s.applyOffset)
+
+		if watermark := s.watermarks.Get().VBuckets[0]; !watermark.Equal(tt.expected) {
+			t.Fatalf("after ack of seqNo: %d expected watermark %v, got %v", tt.ack+1, tt.expected, watermark)
+		}
+	}
+}
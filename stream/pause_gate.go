@@ -0,0 +1,119 @@
+package stream
+
+import "sync"
+
+// PauseReason is the cause of a pause. The stream stays paused while any reason is set, so a feature resuming
+// its own reason does not resume the intake paused by another one.
+type PauseReason string
+
+const (
+	PauseReasonManual              PauseReason = "manual"
+	PauseReasonShutdown            PauseReason = "shutdown"
+	PauseReasonIdleMember          PauseReason = "idleMember"
+	PauseReasonSplitBrain          PauseReason = "splitBrain"
+	PauseReasonUnhealthyDownstream PauseReason = "unhealthyDownstream"
+	PauseReasonMetadataUnavailable PauseReason = "metadataUnavailable"
+)
+
+// pauseGate blocks the listener while the stream is paused. Observer callbacks are not returned while blocked,
+// so the dcp buffer is not acked and the server stops sending once the buffer is full.
+type pauseGate struct {
+	resumeCh  chan struct{}
+	releaseCh chan struct{}
+	reasons   map[PauseReason]struct{}
+	lock      sync.Mutex
+	released  bool
+}
+
+// Wait blocks until the gate is resumed or released. It reports false when released while paused.
+func (g *pauseGate) Wait() bool {
+	g.lock.Lock()
+	resumeCh, releaseCh := g.resumeCh, g.releaseCh
+	g.lock.Unlock()
+
+	if resumeCh == nil {
+		return true
+	}
+
+	select {
+	case <-resumeCh:
+		return true
+	case <-releaseCh:
+		return false
+	}
+}
+
+// Pause sets the reason, it reports true when the gate is closed by it.
+func (g *pauseGate) Pause(reason PauseReason) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.reasons[reason] = struct{}{}
+
+	if g.resumeCh != nil {
+		return false
+	}
+
+	g.resumeCh = make(chan struct{})
+	return true
+}
+
+// Resume clears the reason, it reports true when the gate is opened by it, i.e. no other reason is left.
+func (g *pauseGate) Resume(reason PauseReason) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	delete(g.reasons, reason)
+
+	if g.resumeCh == nil || len(g.reasons) > 0 {
+		return false
+	}
+
+	close(g.resumeCh)
+	g.resumeCh = nil
+	return true
+}
+
+func (g *pauseGate) IsPaused() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.resumeCh != nil
+}
+
+func (g *pauseGate) IsPausedFor(reason PauseReason) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	_, ok := g.reasons[reason]
+	return ok
+}
+
+// Arm makes the gate block again after a release, the paused state is kept.
+func (g *pauseGate) Arm() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.released {
+		g.releaseCh = make(chan struct{})
+		g.released = false
+	}
+}
+
+// Release unblocks the waiting listeners, so the streams can be closed while paused.
+func (g *pauseGate) Release() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if !g.released {
+		close(g.releaseCh)
+		g.released = true
+	}
+}
+
+func newPauseGate() *pauseGate {
+	return &pauseGate{
+		releaseCh: make(chan struct{}),
+		reasons:   map[PauseReason]struct{}{},
+	}
+}
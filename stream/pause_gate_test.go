@@ -0,0 +1,35 @@
+package stream
+
+import "testing"
+
+func TestPauseGate_ResumesWhenEveryReasonIsCleared(t *testing.T) {
+	gate := newPauseGate()
+
+	if !gate.Pause(PauseReasonSplitBrain) {
+		t.Fatalf("first reason must close the gate")
+	}
+
+	if gate.Pause(PauseReasonManual) {
+		t.Fatalf("gate must be closed once")
+	}
+
+	if gate.Resume(PauseReasonManual) || !gate.IsPaused() {
+		t.Fatalf("manual resume must not open the gate fenced by split brain")
+	}
+
+	if gate.IsPausedFor(PauseReasonManual) || !gate.IsPausedFor(PauseReasonSplitBrain) {
+		t.Fatalf("only the resumed reason must be cleared")
+	}
+
+	if !gate.Resume(PauseReasonSplitBrain) || gate.IsPaused() {
+		t.Fatalf("gate must be opened when every reason is cleared")
+	}
+
+	if gate.Resume(PauseReasonSplitBrain) {
+		t.Fatalf("resuming a cleared reason must be a no-op")
+	}
+
+	if !gate.Wait() {
+		t.Fatalf("open gate must not block")
+	}
+}
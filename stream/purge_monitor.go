@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// purgeMonitor compares the offsets with the purge seqnos of the vBuckets. Once tombstones are purged beyond
+// an offset, the vBucket cannot resume cleanly, so lagging or paused consumers are warned before it happens.
+type purgeMonitor struct {
+	stream   *stream
+	margins  map[uint16]int64
+	stopCh   chan struct{}
+	stopOnce *sync.Once
+	lock     sync.RWMutex
+}
+
+// Start runs the checks until Stop, the stream starts the monitor again on each Open.
+func (m *purgeMonitor) Start() {
+	stopCh := make(chan struct{})
+
+	m.lock.Lock()
+	m.stopCh = stopCh
+	m.stopOnce = &sync.Once{}
+	m.lock.Unlock()
+
+	go func(stopCh chan struct{}) {
+		ticker := time.NewTicker(m.stream.config.PurgeMonitor.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				m.check()
+			}
+		}
+	}(stopCh)
+}
+
+func (m *purgeMonitor) Stop() {
+	m.lock.Lock()
+	stopCh, stopOnce := m.stopCh, m.stopOnce
+	m.margins = map[uint16]int64{}
+	m.lock.Unlock()
+
+	if stopOnce != nil {
+		stopOnce.Do(func() {
+			close(stopCh)
+		})
+	}
+}
+
+func (m *purgeMonitor) check() {
+	purgeSeqNos, err := m.stream.client.GetPurgeSeqNos()
+	if err != nil {
+		logger.Log.Warn("error while getting purge seqNos, err: %v", err)
+		return
+	}
+
+	offsets, _, _ := m.stream.GetOffsets()
+
+	margins := map[uint16]int64{}
+	var risks []models.PurgeRisk
+
+	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		purgeSeqNo, ok := purgeSeqNos[vbID]
+		if !ok {
+			return true
+		}
+
+		margins[vbID] = int64(offset.SeqNo) - int64(purgeSeqNo)
+
+		if purgeSeqNo > 0 && offset.SeqNo < purgeSeqNo+m.stream.config.PurgeMonitor.Margin {
+			risks = append(risks, models.PurgeRisk{
+				VbID:       vbID,
+				SeqNo:      offset.SeqNo,
+				PurgeSeqNo: purgeSeqNo,
+				Purged:     offset.SeqNo < purgeSeqNo,
+			})
+		}
+
+		return true
+	})
+
+	m.lock.Lock()
+	m.margins = margins
+	m.lock.Unlock()
+
+	if len(risks) == 0 {
+		return
+	}
+
+	sort.Slice(risks, func(i, j int) bool {
+		return risks[i].VbID < risks[j].VbID
+	})
+
+	for _, risk := range risks {
+		logger.Log.Warn(
+			"vbID: %v offset is close to purge seqNo, seqNo: %v, purge seqNo: %v, purged: %v",
+			risk.VbID, risk.SeqNo, risk.PurgeSeqNo, risk.Purged,
+		)
+	}
+
+	if handler, ok := m.stream.eventHandler.(models.PurgeRiskHandler); ok {
+		handler.OnPurgeRisk(risks)
+	}
+}
+
+// GetMargins returns the distance of the offsets to the purge seqnos, negative for purged vBuckets.
+func (m *purgeMonitor) GetMargins() map[uint16]int64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	margins := make(map[uint16]int64, len(m.margins))
+	for vbID, margin := range m.margins {
+		margins[vbID] = margin
+	}
+
+	return margins
+}
+
+func newPurgeMonitor(stream *stream) *purgeMonitor {
+	return &purgeMonitor{
+		stream:  stream,
+		margins: map[uint16]int64{},
+	}
+}
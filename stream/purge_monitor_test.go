@@ -0,0 +1,33 @@
+package stream
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+)
+
+func TestPurgeMonitor_StopIsIdempotent(t *testing.T) {
+	cfg := &config.Dcp{}
+	cfg.PurgeMonitor.Interval = time.Hour
+
+	monitor := newPurgeMonitor(&stream{config: cfg})
+
+	// stop before start must not close a channel
+	monitor.Stop()
+
+	for i := 0; i < 2; i++ {
+		monitor.Start()
+
+		var wg sync.WaitGroup
+		for j := 0; j < 3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				monitor.Stop()
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// reconnectBudget limits how many times a vBucket stream can be re-opened within a sliding window.
+// A budget with zero max attempts is unlimited.
+type reconnectBudget struct {
+	attempts    map[uint16][]time.Time
+	window      time.Duration
+	maxAttempts int
+	lock        sync.Mutex
+}
+
+// Take records an attempt for the vBucket and reports whether it is within the budget.
+func (b *reconnectBudget) Take(vbID uint16, now time.Time) bool {
+	if b.maxAttempts <= 0 {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	attempts := b.prune(vbID, now)
+	if len(attempts) >= b.maxAttempts {
+		return false
+	}
+
+	b.attempts[vbID] = append(attempts, now)
+	return true
+}
+
+// Available returns the time when the vBucket gets an attempt back.
+func (b *reconnectBudget) Available(vbID uint16, now time.Time) time.Time {
+	if b.maxAttempts <= 0 {
+		return now
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	attempts := b.prune(vbID, now)
+	if len(attempts) < b.maxAttempts {
+		return now
+	}
+
+	return attempts[0].Add(b.window)
+}
+
+func (b *reconnectBudget) prune(vbID uint16, now time.Time) []time.Time {
+	attempts := b.attempts[vbID]
+
+	i := 0
+	for i < len(attempts) && now.Sub(attempts[i]) >= b.window {
+		i++
+	}
+
+	attempts = attempts[i:]
+	b.attempts[vbID] = attempts
+
+	return attempts
+}
+
+func newReconnectBudget(maxAttempts int, window time.Duration) *reconnectBudget {
+	return &reconnectBudget{
+		attempts:    map[uint16][]time.Time{},
+		maxAttempts: maxAttempts,
+		window:      window,
+	}
+}
@@ -0,0 +1,78 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/clock"
+)
+
+func TestReconnectBudget_Take(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	budget := newReconnectBudget(2, time.Minute)
+
+	if !budget.Take(0, fake.Now()) || !budget.Take(0, fake.Now()) {
+		t.Fatalf("attempts within the budget must be taken")
+	}
+
+	if budget.Take(0, fake.Now()) {
+		t.Fatalf("attempt over the budget must not be taken")
+	}
+
+	if !budget.Take(1, fake.Now()) {
+		t.Fatalf("budget of an other vBucket must not be used")
+	}
+
+	if available := budget.Available(0, fake.Now()); !available.Equal(fake.Now().Add(time.Minute)) {
+		t.Fatalf("expected attempt back after the window, got %v", available)
+	}
+
+	fake.Advance(time.Minute)
+
+	if available := budget.Available(0, fake.Now()); !available.Equal(fake.Now()) {
+		t.Fatalf("expected attempt available now, got %v", available)
+	}
+
+	if !budget.Take(0, fake.Now()) {
+		t.Fatalf("attempt must be taken after the window slides")
+	}
+}
+
+func TestReconnectBudget_SlidingWindow(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	budget := newReconnectBudget(2, time.Minute)
+
+	budget.Take(0, fake.Now())
+	fake.Advance(30 * time.Second)
+	budget.Take(0, fake.Now())
+	fake.Advance(40 * time.Second)
+
+	// the first attempt is out of the window, the second is not
+	if !budget.Take(0, fake.Now()) {
+		t.Fatalf("attempt out of the window must be given back")
+	}
+
+	if budget.Take(0, fake.Now()) {
+		t.Fatalf("attempts in the window must be counted")
+	}
+
+	expected := time.Unix(0, 0).Add(30*time.Second + time.Minute)
+	if available := budget.Available(0, fake.Now()); !available.Equal(expected) {
+		t.Fatalf("expected attempt back at %v, got %v", expected, available)
+	}
+}
+
+func TestReconnectBudget_Unlimited(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	budget := newReconnectBudget(0, time.Minute)
+
+	for i := 0; i < 100; i++ {
+		if !budget.Take(0, fake.Now()) {
+			t.Fatalf("unlimited budget must take every attempt")
+		}
+	}
+
+	if available := budget.Available(0, fake.Now()); !available.Equal(fake.Now()) {
+		t.Fatalf("unlimited budget must be available now, got %v", available)
+	}
+}
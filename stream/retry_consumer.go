@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+// errRetryStopped is returned when the stream is closed during a backoff, the event is left unacked.
+var errRetryStopped = errors.New("retry is stopped")
+
+// clockUser is implemented by consumers which wait on the clock of the stream.
+type clockUser interface {
+	useClock(clock clock.Clock)
+}
+
+// retryConsumer calls the consumer again with exponential backoff until it succeeds,
+// the error is not retryable or the attempts are over. The last error is returned.
+type retryConsumer struct {
+	models.ConsumerWrapper
+	consumer    models.FailableConsumer
+	config      *config.Dcp
+	isRetryable func(err error) bool
+	clock       clock.Clock
+	stopCh      chan struct{}
+	lock        sync.Mutex
+}
+
+func (c *retryConsumer) ConsumeEvent(ctx *models.ListenerContext) error {
+	retry := c.config.Consumer.Retry
+	backoff := retry.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := c.consumer.ConsumeEvent(ctx)
+		if err == nil || attempt >= retry.MaxAttempts || (c.isRetryable != nil && !c.isRetryable(err)) {
+			return err
+		}
+
+		logger.Log.Warn("consume event failed, vbID: %v, attempt: %v, retry after: %v, err: %v", ctx.VbID, attempt, backoff, err)
+
+		if !c.wait(backoff) {
+			return errRetryStopped
+		}
+
+		backoff = time.Duration(float64(backoff) * retry.Multiplier)
+		if backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+	}
+}
+
+// wait waits for the backoff and reports false when the consumer is discarded before.
+func (c *retryConsumer) wait(backoff time.Duration) bool {
+	c.lock.Lock()
+	clock, stopCh := c.clock, c.stopCh
+	c.lock.Unlock()
+
+	select {
+	case <-stopCh:
+		return false
+	case <-clock.After(backoff):
+		return true
+	}
+}
+
+func (c *retryConsumer) useClock(clock clock.Clock) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.clock = clock
+
+	if user, ok := c.consumer.(clockUser); ok {
+		user.useClock(clock)
+	}
+}
+
+func (c *retryConsumer) TrackOffset(vbID uint16, offset *models.Offset) {
+	c.consumer.TrackOffset(vbID, offset)
+}
+
+// Discard stops the backoffs in progress, the consumer retries again after the stream is opened.
+func (c *retryConsumer) Discard() {
+	c.lock.Lock()
+	close(c.stopCh)
+	c.stopCh = make(chan struct{})
+	c.lock.Unlock()
+
+	c.ConsumerWrapper.Discard()
+}
+
+// NewRetryConsumer retries the consumer with consumer.retry settings. Errors are retried when isRetryable
+// is nil or returns true for them. Consumers implementing models.RetryClassifier classify their own errors.
+func NewRetryConsumer(consumer models.FailableConsumer, config *config.Dcp, isRetryable func(err error) bool) models.FailableConsumer {
+	if classifier, ok := consumer.(models.RetryClassifier); ok && isRetryable == nil {
+		isRetryable = classifier.IsRetryable
+	}
+
+	return &retryConsumer{
+		ConsumerWrapper: models.ConsumerWrapper{Wrapped: consumer},
+		consumer:        consumer,
+		config:          config,
+		isRetryable:     isRetryable,
+		clock:           clock.New(),
+		stopCh:          make(chan struct{}),
+	}
+}
@@ -0,0 +1,160 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+var errConsume = errors.New("consume")
+
+// failingConsumer fails the first failures calls.
+type failingConsumer struct {
+	failures int
+	calls    int
+	lock     sync.Mutex
+}
+
+func (c *failingConsumer) ConsumeEvent(_ *models.ListenerContext) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.calls++
+	if c.calls <= c.failures {
+		return errConsume
+	}
+
+	return nil
+}
+
+func (c *failingConsumer) TrackOffset(_ uint16, _ *models.Offset) {}
+
+func (c *failingConsumer) callCount() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.calls
+}
+
+func newTestRetryConsumer(consumer models.FailableConsumer, isRetryable func(err error) bool) (*retryConsumer, *clock.Fake) {
+	logger.InitDefaultLogger("error")
+
+	c := &config.Dcp{}
+	c.Consumer.Retry = config.ConsumerRetry{
+		MaxAttempts:    4,
+		InitialBackoff: time.Second,
+		MaxBackoff:     3 * time.Second,
+		Multiplier:     2,
+	}
+
+	fake := clock.NewFake(time.Unix(0, 0))
+
+	retry := NewRetryConsumer(consumer, c, isRetryable).(*retryConsumer)
+	retry.useClock(fake)
+
+	return retry, fake
+}
+
+// consumeAsync consumes an event in its own goroutine, the returned channel receives its error.
+func consumeAsync(c *retryConsumer) chan error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.ConsumeEvent(&models.ListenerContext{})
+	}()
+
+	return done
+}
+
+func TestRetryConsumer_Backoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		backoffs []time.Duration
+		expected error
+	}{
+		{name: "succeeds at once", failures: 0},
+		{name: "succeeds after retries", failures: 2, backoffs: []time.Duration{time.Second, 2 * time.Second}},
+		{
+			// the third backoff is capped by maxBackoff
+			name:     "attempts are over",
+			failures: 10,
+			backoffs: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+			expected: errConsume,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := &failingConsumer{failures: tt.failures}
+			retry, fake := newTestRetryConsumer(consumer, nil)
+
+			done := consumeAsync(retry)
+
+			for i, backoff := range tt.backoffs {
+				fake.BlockUntil(1)
+
+				// the consumer is not called again before the backoff is over
+				fake.Advance(backoff - time.Millisecond)
+				if calls := consumer.callCount(); calls != i+1 {
+					t.Fatalf("expected %d calls before backoff %v, got %d", i+1, backoff, calls)
+				}
+
+				fake.Advance(time.Millisecond)
+			}
+
+			if err := <-done; !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+
+			if calls := consumer.callCount(); calls != len(tt.backoffs)+1 {
+				t.Fatalf("expected %d calls, got %d", len(tt.backoffs)+1, calls)
+			}
+		})
+	}
+}
+
+func TestRetryConsumer_NotRetryable(t *testing.T) {
+	consumer := &failingConsumer{failures: 10}
+	retry, _ := newTestRetryConsumer(consumer, func(err error) bool { return false })
+
+	if err := retry.ConsumeEvent(&models.ListenerContext{}); !errors.Is(err, errConsume) {
+		t.Fatalf("expected consume error, got %v", err)
+	}
+
+	if consumer.callCount() != 1 {
+		t.Fatalf("error which is not retryable must not be retried, got %d calls", consumer.callCount())
+	}
+}
+
+func TestRetryConsumer_DiscardStopsBackoff(t *testing.T) {
+	consumer := &failingConsumer{failures: 10}
+	retry, fake := newTestRetryConsumer(consumer, nil)
+
+	done := consumeAsync(retry)
+
+	fake.BlockUntil(1)
+	retry.Discard()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errRetryStopped) {
+			t.Fatalf("expected retry stopped error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("discard must stop the backoff")
+	}
+
+	// the consumer retries again after the stream is opened
+	consumer.failures = 0
+	if err := retry.ConsumeEvent(&models.ListenerContext{}); err != nil {
+		t.Fatalf("expected success after discard, got %v", err)
+	}
+}
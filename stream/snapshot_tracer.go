@@ -0,0 +1,155 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/tracing"
+)
+
+const (
+	snapshotSpanName = "dcp.snapshot"
+
+	// maxPendingSnapshotSpans bounds the spans of a vBucket whose snapshots are not committed yet, the oldest span is
+	// ended as dropped when a manual checkpoint is not committed for long.
+	maxPendingSnapshotSpans = 64
+)
+
+// snapshotCommitter is told the checkpoints once they are saved, to end the spans of the committed snapshots.
+type snapshotCommitter interface {
+	snapshotsCommitted(checkpoints map[uint16]*models.CheckpointDocument)
+}
+
+func (s *stream) snapshotsCommitted(checkpoints map[uint16]*models.CheckpointDocument) {
+	s.snapshotTracer.Committed(checkpoints)
+}
+
+type snapshotSpan struct {
+	span      tracing.RequestSpan
+	endSeqNo  uint64
+	processed bool
+}
+
+// snapshotTracer keeps a span for each snapshot of a vBucket from its marker till the checkpoint covering its end
+// seqno is saved. The span has a processed event when the offset reaches the end of the snapshot, and a committed
+// event when it is persisted, so a tracing backend shows how long each snapshot took to be processed and committed.
+type snapshotTracer struct {
+	tracerComponent *tracing.TracerComponent
+	spans           map[uint16][]*snapshotSpan
+	lock            sync.Mutex
+	enabled         bool
+}
+
+func newSnapshotTracer(tc *tracing.TracerComponent) *snapshotTracer {
+	return &snapshotTracer{
+		tracerComponent: tc,
+		spans:           map[uint16][]*snapshotSpan{},
+		enabled:         tc != nil && tc.Enabled(),
+	}
+}
+
+func (t *snapshotTracer) Snapshot(marker models.DcpSnapshotMarker) {
+	if !t.enabled {
+		return
+	}
+
+	span := t.tracerComponent.StartSpan(snapshotSpanName, map[string]interface{}{
+		"vb_id":         marker.VbID,
+		"start_seq_no":  marker.StartSeqNo,
+		"end_seq_no":    marker.EndSeqNo,
+		"snapshot_type": snapshotType(marker.SnapshotType),
+	})
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	pending := t.spans[marker.VbID]
+	if len(pending) == maxPendingSnapshotSpans {
+		pending[0].span.SetAttribute("dropped", true)
+		pending[0].span.End()
+		pending = pending[1:]
+	}
+
+	t.spans[marker.VbID] = append(pending, &snapshotSpan{span: span, endSeqNo: marker.EndSeqNo})
+}
+
+// Processed marks the snapshots of the vBucket which end at or before the seqno as processed.
+func (t *snapshotTracer) Processed(vbID uint16, seqNo uint64) {
+	if !t.enabled {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, pending := range t.spans[vbID] {
+		if pending.processed || pending.endSeqNo > seqNo {
+			continue
+		}
+
+		pending.processed = true
+		pending.span.AddEvent("processed", time.Now())
+	}
+}
+
+// Committed ends the spans of the processed snapshots which are covered by the saved checkpoints.
+func (t *snapshotTracer) Committed(checkpoints map[uint16]*models.CheckpointDocument) {
+	if !t.enabled {
+		return
+	}
+
+	now := time.Now()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for vbID, pending := range t.spans {
+		doc, ok := checkpoints[vbID]
+		if !ok || doc.Checkpoint == nil {
+			continue
+		}
+
+		remaining := pending[:0]
+		for _, s := range pending {
+			if !s.processed || s.endSeqNo > doc.Checkpoint.SeqNo {
+				remaining = append(remaining, s)
+				continue
+			}
+
+			s.span.AddEvent("committed", now)
+			s.span.End()
+		}
+
+		if len(remaining) == 0 {
+			delete(t.spans, vbID)
+		} else {
+			t.spans[vbID] = remaining
+		}
+	}
+}
+
+// Close ends the spans of the vBuckets whose streams are closed, all of them when vbIDs is nil.
+func (t *snapshotTracer) Close(vbIDs []uint16) {
+	if !t.enabled {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if vbIDs == nil {
+		for vbID := range t.spans {
+			vbIDs = append(vbIDs, vbID)
+		}
+	}
+
+	for _, vbID := range vbIDs {
+		for _, pending := range t.spans[vbID] {
+			pending.span.SetAttribute("closed", true)
+			pending.span.End()
+		}
+
+		delete(t.spans, vbID)
+	}
+}
@@ -0,0 +1,329 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/state"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+type stateChange struct {
+	value   []byte
+	deleted bool
+}
+
+type vbState struct {
+	committed map[string][]byte
+	pending   map[string]stateChange
+	offset    *models.Offset
+	dirty     bool
+}
+
+// stateStore keeps the processing state of the vBuckets. Changes of an event stay pending until the offset
+// of its vBucket advances, then they are kept with the offset and committed to the backend before the checkpoint.
+// The two writes are not atomic, so on open the offset of each snapshot is checked against its checkpoint, and the
+// snapshots which match take precedence over the checkpoint.
+type stateStore struct {
+	backend    state.Backend
+	handoff    state.Handoff
+	vbs        map[uint16]*vbState
+	bucketUUID string
+	lock       sync.Mutex
+	open       bool
+}
+
+func (s *stateStore) getVb(vbID uint16) *vbState {
+	vb, ok := s.vbs[vbID]
+	if !ok {
+		vb = &vbState{
+			committed: map[string][]byte{},
+			pending:   map[string]stateChange{},
+		}
+		s.vbs[vbID] = vb
+	}
+
+	return vb
+}
+
+// View returns the state of the vBucket, it is nil when the state store is not configured.
+func (s *stateStore) View(vbID uint16) models.State {
+	if s == nil {
+		return nil
+	}
+
+	return &stateView{store: s, vbID: vbID}
+}
+
+func (s *stateStore) Advance(vbID uint16, offset *models.Offset) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Late acks of a released stream must not overwrite the snapshots with an empty state.
+	if !s.open {
+		return
+	}
+
+	vb := s.getVb(vbID)
+
+	for key, change := range vb.pending {
+		if change.deleted {
+			delete(vb.committed, key)
+		} else {
+			vb.committed[key] = change.value
+		}
+	}
+
+	if len(vb.pending) > 0 {
+		vb.pending = map[string]stateChange{}
+	}
+
+	vb.offset = offset
+	vb.dirty = true
+}
+
+// snapshot must be called with the lock held.
+func (s *stateStore) snapshot(onlyDirty bool) map[uint16]*models.StateSnapshot {
+	snapshots := map[uint16]*models.StateSnapshot{}
+
+	for vbID, vb := range s.vbs {
+		if (onlyDirty && !vb.dirty) || vb.offset == nil {
+			continue
+		}
+
+		values := make(map[string][]byte, len(vb.committed))
+		for key, value := range vb.committed {
+			values[key] = value
+		}
+
+		snapshots[vbID] = &models.StateSnapshot{
+			Checkpoint: &models.CheckpointDocument{
+				Checkpoint: &models.CheckpointDocumentCheckpoint{
+					VbUUID: uint64(vb.offset.VbUUID),
+					SeqNo:  vb.offset.SeqNo,
+					Snapshot: &models.CheckpointDocumentSnapshot{
+						StartSeqNo: vb.offset.StartSeqNo,
+						EndSeqNo:   vb.offset.EndSeqNo,
+					},
+				},
+				BucketUUID: s.bucketUUID,
+			},
+			Values: values,
+		}
+
+		vb.dirty = false
+	}
+
+	return snapshots
+}
+
+func (s *stateStore) Commit() error {
+	s.lock.Lock()
+	snapshots := s.snapshot(true)
+	s.lock.Unlock()
+
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	err := s.backend.Save(snapshots)
+	if err != nil {
+		s.lock.Lock()
+		for vbID := range snapshots {
+			if vb, ok := s.vbs[vbID]; ok {
+				vb.dirty = true
+			}
+		}
+		s.lock.Unlock()
+	}
+
+	return err
+}
+
+// Load restores the state of the vBuckets and returns the offsets which the snapshots are consistent with.
+// Snapshots of another bucket and snapshots which do not match their checkpoints are ignored.
+func (s *stateStore) Load(
+	vbIDs []uint16,
+	bucketUUID string,
+	checkpoints *wrapper.ConcurrentSwissMap[uint16, *models.Offset],
+) (map[uint16]*models.Offset, error) {
+	snapshots, err := s.backend.Load(vbIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.handoff != nil {
+		if err = s.adoptHandoff(vbIDs, snapshots); err != nil {
+			return nil, err
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.bucketUUID = bucketUUID
+	s.vbs = map[uint16]*vbState{}
+	s.open = true
+
+	offsets := map[uint16]*models.Offset{}
+
+	for vbID, snapshot := range snapshots {
+		if snapshot.Checkpoint == nil || snapshot.Checkpoint.Checkpoint == nil || snapshot.Checkpoint.BucketUUID != bucketUUID {
+			continue
+		}
+
+		checkpoint := snapshot.Checkpoint.Checkpoint
+
+		if current, ok := checkpoints.Load(vbID); ok && !matchesCheckpoint(checkpoint, current) {
+			logger.Log.Warn(
+				"vbID: %d state snapshot does not match the checkpoint and is dropped, snapshot vbUUID: %d seqNo: %d, "+
+					"checkpoint vbUUID: %d seqNo: %d", vbID, checkpoint.VbUUID, checkpoint.SeqNo, current.VbUUID, current.SeqNo,
+			)
+			continue
+		}
+
+		offsets[vbID] = &models.Offset{
+			SnapshotMarker: &models.SnapshotMarker{
+				StartSeqNo: checkpoint.Snapshot.StartSeqNo,
+				EndSeqNo:   checkpoint.Snapshot.EndSeqNo,
+			},
+			VbUUID: gocbcore.VbUUID(checkpoint.VbUUID),
+			SeqNo:  checkpoint.SeqNo,
+		}
+
+		vb := s.getVb(vbID)
+		if snapshot.Values != nil {
+			vb.committed = snapshot.Values
+		}
+		vb.offset = offsets[vbID]
+	}
+
+	return offsets, nil
+}
+
+// adoptHandoff replaces the snapshots with the newer ones of the handoff and saves them to the backend. The handoff
+// is complete then, so its snapshots of the vBuckets are removed.
+func (s *stateStore) adoptHandoff(vbIDs []uint16, snapshots map[uint16]*models.StateSnapshot) error {
+	handedOff, err := s.handoff.Fetch(vbIDs)
+	if err != nil {
+		return err
+	}
+
+	if len(handedOff) == 0 {
+		return nil
+	}
+
+	adopted := map[uint16]*models.StateSnapshot{}
+
+	for vbID, snapshot := range handedOff {
+		if current, ok := snapshots[vbID]; !ok || isNewerSnapshot(snapshot, current) {
+			snapshots[vbID] = snapshot
+			adopted[vbID] = snapshot
+		}
+	}
+
+	if len(adopted) > 0 {
+		if err = s.backend.Save(adopted); err != nil {
+			return err
+		}
+	}
+
+	removed := make([]uint16, 0, len(handedOff))
+	for vbID := range handedOff {
+		removed = append(removed, vbID)
+	}
+
+	if err = s.handoff.Remove(removed); err != nil {
+		logger.Log.Warn("error while removing state handoff, err: %v", err)
+	}
+
+	return nil
+}
+
+// Release commits the state when the stream closes and publishes the snapshots of every vBucket to the handoff,
+// then drops the state. It is loaded again when the stream opens, on this member or on the next owner.
+func (s *stateStore) Release() {
+	if err := s.Commit(); err != nil {
+		logger.Log.Error("error while committing state on release, err: %v", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.handoff != nil {
+		if err := s.handoff.Publish(s.snapshot(false)); err != nil {
+			logger.Log.Error("error while publishing state handoff, err: %v", err)
+		}
+	}
+
+	s.vbs = map[uint16]*vbState{}
+	s.open = false
+}
+
+func isNewerSnapshot(snapshot *models.StateSnapshot, current *models.StateSnapshot) bool {
+	if snapshot.Checkpoint == nil || snapshot.Checkpoint.Checkpoint == nil {
+		return false
+	}
+
+	if current.Checkpoint == nil || current.Checkpoint.Checkpoint == nil {
+		return true
+	}
+
+	return snapshot.Checkpoint.Checkpoint.SeqNo > current.Checkpoint.Checkpoint.SeqNo
+}
+
+// matchesCheckpoint reports whether the snapshot is consistent with the checkpoint of its vBucket. The state is
+// committed first, so a snapshot is at or ahead of the checkpoint saved after it on the same vbUUID. A checkpoint
+// ahead of the snapshot or on another vbUUID was moved without the state, by a reset or a rollback.
+func matchesCheckpoint(snapshot *models.CheckpointDocumentCheckpoint, checkpoint *models.Offset) bool {
+	if checkpoint.SeqNo == 0 && checkpoint.VbUUID == 0 {
+		return true
+	}
+
+	return snapshot.VbUUID == uint64(checkpoint.VbUUID) && snapshot.SeqNo >= checkpoint.SeqNo
+}
+
+func newStateStore(backend state.Backend, handoff state.Handoff) *stateStore {
+	return &stateStore{
+		backend: backend,
+		handoff: handoff,
+		vbs:     map[uint16]*vbState{},
+	}
+}
+
+type stateView struct {
+	store *stateStore
+	vbID  uint16
+}
+
+func (v *stateView) Get(key string) ([]byte, bool) {
+	v.store.lock.Lock()
+	defer v.store.lock.Unlock()
+
+	vb := v.store.getVb(v.vbID)
+
+	if change, ok := vb.pending[key]; ok {
+		return change.value, !change.deleted
+	}
+
+	value, ok := vb.committed[key]
+
+	return value, ok
+}
+
+func (v *stateView) Set(key string, value []byte) {
+	v.store.lock.Lock()
+	defer v.store.lock.Unlock()
+
+	v.store.getVb(v.vbID).pending[key] = stateChange{value: append([]byte(nil), value...)}
+}
+
+func (v *stateView) Delete(key string) {
+	v.store.lock.Lock()
+	defer v.store.lock.Unlock()
+
+	v.store.getVb(v.vbID).pending[key] = stateChange{deleted: true}
+}
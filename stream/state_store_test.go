@@ -0,0 +1,189 @@
+package stream
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/state"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+type fakeHandoff struct {
+	snapshots map[uint16]*models.StateSnapshot
+	removed   []uint16
+}
+
+func (h *fakeHandoff) Publish(snapshots map[uint16]*models.StateSnapshot) error {
+	for vbID, snapshot := range snapshots {
+		h.snapshots[vbID] = snapshot
+	}
+
+	return nil
+}
+
+func (h *fakeHandoff) Fetch(vbIds []uint16) (map[uint16]*models.StateSnapshot, error) {
+	snapshots := map[uint16]*models.StateSnapshot{}
+
+	for _, vbID := range vbIds {
+		if snapshot, ok := h.snapshots[vbID]; ok {
+			snapshots[vbID] = snapshot
+		}
+	}
+
+	return snapshots, nil
+}
+
+func (h *fakeHandoff) Remove(vbIds []uint16) error {
+	for _, vbID := range vbIds {
+		delete(h.snapshots, vbID)
+		h.removed = append(h.removed, vbID)
+	}
+
+	return nil
+}
+
+func newTestSnapshot(vbUUID uint64, seqNo uint64, value string) *models.StateSnapshot {
+	return &models.StateSnapshot{
+		Checkpoint: &models.CheckpointDocument{
+			Checkpoint: &models.CheckpointDocumentCheckpoint{
+				VbUUID:   vbUUID,
+				SeqNo:    seqNo,
+				Snapshot: &models.CheckpointDocumentSnapshot{StartSeqNo: seqNo, EndSeqNo: seqNo},
+			},
+			BucketUUID: "bucket",
+		},
+		Values: map[string][]byte{"count": []byte(value)},
+	}
+}
+
+func TestStateStore_LoadChecksSnapshotsAgainstCheckpoints(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	tests := []struct {
+		checkpoint    *models.Offset
+		name          string
+		expectedState bool
+	}{
+		{name: "no checkpoint", expectedState: true},
+		{name: "empty checkpoint", checkpoint: &models.Offset{SnapshotMarker: &models.SnapshotMarker{}}, expectedState: true},
+		{name: "checkpoint at the snapshot", checkpoint: &models.Offset{VbUUID: 1, SeqNo: 100}, expectedState: true},
+		{name: "checkpoint behind the snapshot", checkpoint: &models.Offset{VbUUID: 1, SeqNo: 80}, expectedState: true},
+		{name: "checkpoint ahead of the snapshot", checkpoint: &models.Offset{VbUUID: 1, SeqNo: 120}},
+		{name: "checkpoint on another vbUUID", checkpoint: &models.Offset{VbUUID: 2, SeqNo: 100}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			backend := state.NewMemoryBackend()
+			_ = backend.Save(map[uint16]*models.StateSnapshot{0: newTestSnapshot(1, 100, "7")})
+
+			checkpoints := wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](0)
+			if tt.checkpoint != nil {
+				checkpoints.Store(0, tt.checkpoint)
+			}
+
+			store := newStateStore(backend, nil)
+
+			offsets, err := store.Load([]uint16{0}, "bucket", checkpoints)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, ok := store.View(0).Get("count")
+
+			if _, loaded := offsets[0]; loaded != tt.expectedState || ok != tt.expectedState {
+				t.Fatalf("expected state %v, got offset %v and value %v", tt.expectedState, loaded, ok)
+			}
+
+			if tt.expectedState && (string(value) != "7" || offsets[0].SeqNo != 100 || offsets[0].VbUUID != gocbcore.VbUUID(1)) {
+				t.Fatalf("state must be loaded with the offset of its snapshot, got %s at %+v", value, offsets[0])
+			}
+		})
+	}
+}
+
+func TestStateStore_LoadAdoptsHandoff(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	backend := state.NewMemoryBackend()
+	_ = backend.Save(map[uint16]*models.StateSnapshot{
+		0: newTestSnapshot(1, 100, "old"),
+		1: newTestSnapshot(1, 100, "own"),
+	})
+
+	handoff := &fakeHandoff{snapshots: map[uint16]*models.StateSnapshot{
+		0: newTestSnapshot(1, 150, "handed off"),
+		1: newTestSnapshot(1, 50, "stale"),
+	}}
+
+	store := newStateStore(backend, handoff)
+
+	if _, err := store.Load([]uint16{0, 1}, "bucket", wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](0)); err != nil {
+		t.Fatal(err)
+	}
+
+	for vbID, expected := range map[uint16]string{0: "handed off", 1: "own"} {
+		if value, _ := store.View(vbID).Get("count"); string(value) != expected {
+			t.Fatalf("vbID: %d expected state %s, got %s", vbID, expected, value)
+		}
+	}
+
+	saved, _ := backend.Load([]uint16{0})
+	if string(saved[0].Values["count"]) != "handed off" {
+		t.Fatalf("handed off snapshot must be saved to the backend")
+	}
+
+	if len(handoff.snapshots) != 0 || len(handoff.removed) != 2 {
+		t.Fatalf("handoff must be removed after it is saved, left: %v", handoff.snapshots)
+	}
+}
+
+func TestStateStore_CommitAndRelease(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	backend := state.NewMemoryBackend()
+	handoff := &fakeHandoff{snapshots: map[uint16]*models.StateSnapshot{}}
+
+	store := newStateStore(backend, handoff)
+	if _, err := store.Load([]uint16{0}, "bucket", wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](0)); err != nil {
+		t.Fatal(err)
+	}
+
+	store.View(0).Set("count", []byte("1"))
+
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if saved, _ := backend.Load([]uint16{0}); len(saved) != 0 {
+		t.Fatalf("pending changes must not be committed before the offset advances")
+	}
+
+	store.Advance(0, &models.Offset{SnapshotMarker: &models.SnapshotMarker{}, VbUUID: 1, SeqNo: 10})
+	store.Release()
+
+	saved, _ := backend.Load([]uint16{0})
+	if !reflect.DeepEqual(saved[0].Values, map[string][]byte{"count": []byte("1")}) || saved[0].Checkpoint.Checkpoint.SeqNo != 10 {
+		t.Fatalf("released state must be committed with its offset, got %+v", saved[0])
+	}
+
+	if handoff.snapshots[0] == nil {
+		t.Fatalf("released state must be published to the handoff")
+	}
+
+	// late acks of the released stream are ignored
+	store.Advance(0, &models.Offset{SnapshotMarker: &models.SnapshotMarker{}, VbUUID: 1, SeqNo: 20})
+
+	if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if saved, _ = backend.Load([]uint16{0}); saved[0].Checkpoint.Checkpoint.SeqNo != 10 {
+		t.Fatalf("late ack must not be committed, got seqNo %d", saved[0].Checkpoint.Checkpoint.SeqNo)
+	}
+}
@@ -1,8 +1,10 @@
 package stream
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,12 +19,14 @@ import (
 
 	"github.com/Trendyol/go-dcp/wrapper"
 
+	"github.com/Trendyol/go-dcp/clock"
 	"github.com/Trendyol/go-dcp/config"
 
 	"github.com/Trendyol/go-dcp/metadata"
 
 	"github.com/Trendyol/go-dcp/couchbase"
 	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/state"
 
 	"github.com/Trendyol/go-dcp/logger"
 
@@ -33,22 +37,61 @@ type Stream interface {
 	Open()
 	Rebalance()
 	Save()
+	Commit() <-chan error
 	Close(bool)
 	GetOffsets() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool)
 	GetObservers() *wrapper.ConcurrentSwissMap[uint16, couchbase.Observer]
 	GetMetric() (*Metric, int32)
+	GetMetricsSnapshot() *MetricsSnapshot
 	UnmarkDirtyOffsets()
 	GetCheckpointMetric() *CheckpointMetric
 	IsOpen() bool
+	Pause()
+	Resume()
+	PauseFor(reason PauseReason)
+	ResumeFor(reason PauseReason)
+	IsPaused() bool
+	IsPausedFor(reason PauseReason) bool
+	VerifyCheckpoints() ([]CheckpointIssue, error)
+	GetCheckpointStatuses() (map[uint16]CheckpointStatus, error)
+	GetFailoverLogs(vbIDs []uint16) (map[uint16][]FailoverLogEntry, error)
+	Export(w io.Writer, format string) error
+	Import(r io.Reader) error
+	ResetOffsets(policy ResetPolicy, vbIDs []uint16) error
+	GetWatermarks() *Watermarks
+	GetVBucketStats() (map[uint16]VBucketStats, error)
+	CommitState() error
+	IsProgressing(window time.Duration) bool
+	Drain(ctx context.Context)
+	SetMaintenanceMode(enabled bool)
+	IsInMaintenanceMode() bool
+	SetHoldMode(enabled bool)
+	IsInHoldMode() bool
 }
 
 type Metric struct {
-	ProcessLatency int64
-	DcpLatency     int64
-	Rebalance      int
+	ProcessLatency   *LatencyHistogram
+	DcpLatency       *LatencyHistogram
+	ClockSkew        int64
+	DcpRawLatency    int64
+	Reconnect        int64
+	ReconnectFailure int64
+	Filtered         int64
+	Binary           int64
+	Invalid          int64
+	Rebalance        int64
+}
+
+func newMetric() *Metric {
+	return &Metric{
+		ProcessLatency: NewLatencyHistogram(latencyWindowSize),
+		DcpLatency:     NewLatencyHistogram(latencyWindowSize),
+	}
 }
 
 type stream struct {
+	ctx                          context.Context
+	rootCtx                      context.Context
 	client                       couchbase.Client
 	metadata                     metadata.Metadata
 	checkpoint                   Checkpoint
@@ -57,10 +100,27 @@ type stream struct {
 	eventHandler                 models.EventHandler
 	config                       *config.Dcp
 	metric                       *Metric
-	rebalanceTimer               *time.Timer
-	vbIDRange                    *models.VbIDRange
+	clockSkew                    *clockSkewEstimator
+	clock                        clock.Clock
+	rebalanceTimer               clock.Timer
+	reconnectBudget              *reconnectBudget
+	pauseGate                    *pauseGate
+	keyFilter                    *keyFilter
+	watermarks                   *watermarkTracker
+	vBucketStats                 *vBucketStatsTracker
+	scheduler                    *fairScheduler
+	parallel                     *parallelDispatcher
+	stateStore                   *stateStore
+	purgeMonitor                 *purgeMonitor
+	handoff                      couchbase.VBucketHandoff
+	vbIDs                        []uint16
+	validators                   []models.Validator
+	idGenerator                  models.IDGenerator
+	decorators                   []models.Decorator
 	dirtyOffsets                 *wrapper.ConcurrentSwissMap[uint16, bool]
+	resetTimes                   *wrapper.ConcurrentSwissMap[uint16, time.Time]
 	stopCh                       chan struct{}
+	cancelCtx                    context.CancelFunc
 	consumer                     models.Consumer
 	bucketInfo                   *couchbase.BucketInfo
 	finishStreamWithEndEventCh   chan struct{}
@@ -68,14 +128,28 @@ type stream struct {
 	offsets                      *wrapper.ConcurrentSwissMap[uint16, *models.Offset]
 	observers                    *wrapper.ConcurrentSwissMap[uint16, couchbase.Observer]
 	collectionIDs                map[uint32]string
+	finiteBounds                 map[uint16]uint64
 	streamEndNotSupportedData    *streamEndNotSupportedData
 	tracerComponent              *tracing.TracerComponent
+	snapshotTracer               *snapshotTracer
 	rebalanceLock                sync.Mutex
+	reassignLock                 sync.Mutex
+	flushLock                    sync.Mutex
+	maintenanceLock              sync.Mutex
+	stateLock                    sync.RWMutex
+	ctxLock                      sync.RWMutex
 	activeStreams                atomic.Int32
+	hold                         atomic.Bool
+	holdDropped                  atomic.Bool
+	consuming                    atomic.Int64
+	progressTime                 atomic.Int64
 	streamFinishedWithCloseCh    bool
 	streamFinishedWithEndEventCh bool
 	anyDirtyOffset               bool
 	balancing                    bool
+	incremental                  bool
+	maintenance                  bool
+	rebalanceDeferred            bool
 	closeWithCancel              bool
 	open                         bool
 }
@@ -86,68 +160,252 @@ type streamEndNotSupportedData struct {
 }
 
 func (s *stream) setOffset(vbID uint16, offset *models.Offset, dirty bool) {
-	if s.vbIDRange.In(vbID) {
-		if current, ok := s.offsets.Load(vbID); ok && current.SeqNo > offset.SeqNo {
-			return
-		}
-		s.offsets.Store(vbID, offset)
-		s.consumer.TrackOffset(vbID, offset)
-		if !dirty {
-			return
-		}
+	if s.parallel != nil && s.parallel.Defer(vbID, offset, dirty) {
+		return
+	}
 
-		s.dirtyOffsets.StoreIf(vbID, func(p bool, f bool) (v bool, s bool) {
-			if !f || (f && !p) {
-				return true, true
-			}
+	s.applyOffset(vbID, offset, dirty)
+}
 
-			return p, false
-		})
-	} else {
+func (s *stream) applyOffset(vbID uint16, offset *models.Offset, dirty bool) {
+	s.stateLock.RLock()
+	offsets, dirtyOffsets := s.offsets, s.dirtyOffsets
+	s.stateLock.RUnlock()
+
+	// the offsets of the assigned vBuckets are loaded when they are opened, and dropped when they are released
+	current, ok := offsets.Load(vbID)
+	if !ok {
 		logger.Log.Warn("vbID: %v not belong our vbID range", vbID)
+		return
 	}
+
+	if current.SeqNo > offset.SeqNo {
+		return
+	}
+
+	offset.ProcessedAt = s.clock.Now()
+	offsets.Store(vbID, offset)
+	s.snapshotTracer.Processed(vbID, offset.SeqNo)
+	s.watermarks.Ack(vbID, offset.SeqNo)
+	s.notifyWatermark()
+	if s.stateStore != nil {
+		s.stateStore.Advance(vbID, offset)
+	}
+	s.consumer.TrackOffset(vbID, offset)
+	if !dirty {
+		return
+	}
+
+	dirtyOffsets.StoreIf(vbID, func(p bool, f bool) (v bool, s bool) {
+		if !f || (f && !p) {
+			return true, true
+		}
+
+		return p, false
+	})
 }
 
 func (s *stream) waitAndForward(
 	payload interface{},
+	key []byte,
 	spanCtx tracing.RequestSpanContext,
 	offset *models.Offset,
+	seqNo uint64,
 	vbID uint16,
-	eventTime time.Time,
+	serverTime time.Time,
+	receivedTime time.Time,
 ) {
 	if helpers.IsMetadata(payload) {
 		s.setOffset(vbID, offset, false)
 		return
 	}
 
-	s.metric.DcpLatency = time.Since(eventTime).Milliseconds()
+	if !s.keyFilter.Match(key) || s.isBeforeReset(vbID, serverTime) {
+		atomic.AddInt64(&s.metric.Filtered, 1)
+		s.skip(vbID, offset, serverTime)
+		return
+	}
+
+	id, eventMetadata := s.decorate(payload)
 
-	ctx := &models.ListenerContext{
-		Commit: s.checkpoint.Save,
-		Event:  payload,
-		Ack: func() {
-			s.setOffset(vbID, offset, true)
+	payload, binary := s.applyBinaryPolicy(payload, id, eventMetadata)
+	if binary == binarySkip {
+		s.skip(vbID, offset, serverTime)
+		return
+	}
+
+	validation, validationErr := s.validate(payload)
+	if validation == validationDrop {
+		s.skip(vbID, offset, serverTime)
+		return
+	}
+
+	s.clockSkew.Observe(serverTime, receivedTime)
+	atomic.StoreInt64(&s.metric.ClockSkew, s.clockSkew.Skew().Milliseconds())
+	now := time.Now()
+	s.metric.DcpLatency.Record(s.clockSkew.Latency(serverTime, now).Milliseconds())
+	atomic.StoreInt64(&s.metric.DcpRawLatency, s.clockSkew.RawLatency(serverTime, now).Milliseconds())
+
+	s.watermarks.Observe(vbID, offset.SeqNo, serverTime)
+
+	ack := func() {
+		s.setOffset(vbID, offset, true)
+		s.anyDirtyOffset = true
+	}
+
+	if s.parallel != nil {
+		pending := s.parallel.Track(vbID, offset)
+		ack = func() {
+			s.parallel.Ack(vbID, pending, s.applyOffset)
 			s.anyDirtyOffset = true
+		}
+	}
+
+	if s.scheduler != nil {
+		s.scheduler.Acquire(vbID)
+
+		var release sync.Once
+		setOffset := ack
+		ack = func() {
+			setOffset()
+			release.Do(func() {
+				s.scheduler.Release(vbID)
+			})
+		}
+	}
+
+	checkpoint := s.checkpoint
+
+	ctx := &models.ListenerContext{
+		Commit: func() {
+			<-checkpoint.Commit()
 		},
+		CommitAsync:             checkpoint.Commit,
+		Event:                   payload,
+		Ack:                     ack,
 		ListenerTracerComponent: s.tracerComponent.NewListenerTracerComponent(spanCtx),
+		State:                   s.stateStore.View(vbID),
+		Metadata:                eventMetadata,
+		ID:                      id,
+		SnapshotComplete:        isSnapshotEnd(seqNo, offset),
+		Bucket:                  s.config.BucketName,
+		VbID:                    vbID,
+	}
+
+	if binary == binaryDeadLetter {
+		s.consumer.(deadLetterWriter).DeadLetter(ctx, ErrBinaryDocument)
+		return
 	}
 
+	if validation == validationDeadLetter {
+		s.consumer.(deadLetterWriter).DeadLetter(ctx, validationErr)
+		return
+	}
+
+	ctx.ValidationError = validationErr
+
+	if s.parallel != nil {
+		s.parallel.Submit(key, func() {
+			s.consume(ctx)
+		})
+		return
+	}
+
+	s.consume(ctx)
+}
+
+// isSnapshotEnd reports whether the event of the seqNo is the end of the snapshot of its offset. The end of a snapshot
+// is not streamed when it is an event of another collection or a system event, so a snapshot can end without a
+// complete event. Offsets of an oso backfill stay at its start, so its events do not complete a snapshot.
+func isSnapshotEnd(seqNo uint64, offset *models.Offset) bool {
+	return offset.SnapshotMarker != nil && offset.SeqNo == seqNo && seqNo == offset.EndSeqNo
+}
+
+func (s *stream) consume(ctx *models.ListenerContext) {
 	start := time.Now()
 
+	if s.consuming.Add(1) == 1 {
+		s.progressTime.Store(start.UnixNano())
+	}
+
 	s.consumer.ConsumeEvent(ctx)
 
-	s.metric.ProcessLatency = time.Since(start).Milliseconds()
+	s.progressTime.Store(time.Now().UnixNano())
+	s.consuming.Add(-1)
+
+	s.metric.ProcessLatency.Record(time.Since(start).Milliseconds())
+}
+
+// Drain pauses the stream and waits until the consumer returns from the events it is on or the ctx is done. The
+// events which are not consumed yet are streamed again from the checkpoint.
+func (s *stream) Drain(ctx context.Context) {
+	s.PauseFor(PauseReasonShutdown)
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for s.consuming.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			logger.Log.Warn("stream is not drained, consumer is still on %d events", s.consuming.Load())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IsProgressing reports false when the consumer is on events and has not returned from any of them within the window.
+// An idle or paused stream is progressing, since it has nothing to consume.
+func (s *stream) IsProgressing(window time.Duration) bool {
+	if s.consuming.Load() == 0 {
+		return true
+	}
+
+	return time.Since(time.Unix(0, s.progressTime.Load())) < window
+}
+
+// skip advances the offset of an event which does not reach the consumer.
+func (s *stream) skip(vbID uint16, offset *models.Offset, serverTime time.Time) {
+	s.watermarks.Observe(vbID, offset.SeqNo, serverTime)
+	s.setOffset(vbID, offset, true)
+	s.anyDirtyOffset = true
 }
 
 func (s *stream) listen(args models.ListenerArgs) {
+	if s.scheduler != nil {
+		s.scheduler.Schedule(args.VbID, func() {
+			s.dispatch(args)
+		})
+		return
+	}
+
+	if !s.pauseGate.Wait() {
+		return
+	}
+
+	s.dispatch(args)
+}
+
+func (s *stream) dispatch(args models.ListenerArgs) {
+	if s.holdEvent(args.Event) {
+		return
+	}
+
 	switch v := args.Event.(type) {
+	case models.DcpSnapshotMarker:
+		s.vBucketStats.Snapshot(v)
+		s.snapshotTracer.Snapshot(v)
 	case models.DcpMutation:
-		s.waitAndForward(v, args.TraceContext, v.Offset, v.VbID, v.EventTime)
+		s.vBucketStats.Sent(v.VbID, v.SeqNo, true)
+		s.waitAndForward(v, v.Key, args.TraceContext, v.Offset, v.SeqNo, v.VbID, v.ServerTime, v.ReceivedTime)
 	case models.DcpDeletion:
-		s.waitAndForward(v, args.TraceContext, v.Offset, v.VbID, v.EventTime)
+		s.vBucketStats.Sent(v.VbID, v.SeqNo, true)
+		s.waitAndForward(v, v.Key, args.TraceContext, v.Offset, v.SeqNo, v.VbID, v.ServerTime, v.ReceivedTime)
 	case models.DcpExpiration:
-		s.waitAndForward(v, args.TraceContext, v.Offset, v.VbID, v.EventTime)
+		s.vBucketStats.Sent(v.VbID, v.SeqNo, true)
+		s.waitAndForward(v, v.Key, args.TraceContext, v.Offset, v.SeqNo, v.VbID, v.ServerTime, v.ReceivedTime)
 	case models.DcpSeqNoAdvanced:
+		s.vBucketStats.Sent(v.VbID, v.Offset.SeqNo, false)
 		s.setOffset(v.VbID, v.Offset, true)
 	case models.DcpCollectionCreation:
 		s.setOffset(v.VbID, v.Offset, true)
@@ -165,25 +423,123 @@ func (s *stream) listen(args models.ListenerArgs) {
 	}
 }
 
+// reopenStream retries to open the stream of the vBucket till the ctx of the stream it is started for is done, a
+// later Open has its own ctx and streams the vBucket on its own.
 func (s *stream) reopenStream(vbID uint16) {
+	ctx := s.streamCtx()
 	retry := 5
 
 	for {
-		err := s.openStream(vbID)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !s.isAssigned(vbID) {
+			logger.Ctx(ctx).Info("stream is not re-opened, vbID: %d is released", vbID)
+			return
+		}
+
+		if !s.reconnectBudget.Take(vbID, s.clock.Now()) {
+			err := fmt.Errorf("reconnect budget exhausted, %d attempts in %v",
+				s.config.Dcp.Reconnect.MaxAttempts, s.config.Dcp.Reconnect.Window)
+			if !s.onReconnectFailure(vbID, err) {
+				return
+			}
+
+			if !s.sleep(ctx, s.reconnectBudget.Available(vbID, s.clock.Now()).Sub(s.clock.Now())) {
+				return
+			}
+
+			continue
+		}
+
+		atomic.AddInt64(&s.metric.Reconnect, 1)
+
+		var err error
+		if !s.config.Dcp.Flush.Disabled {
+			err = s.resetFlushedOffsets()
+		}
+
+		if err == nil {
+			err = s.openStream(vbID)
+		}
 		if err == nil {
-			logger.Log.Info("re-open stream, vbID: %d", vbID)
+			logger.Ctx(ctx).Info("re-open stream, vbID: %d", vbID)
 			break
 		} else {
-			logger.Log.Warn("cannot re-open stream, vbID: %d, err: %v", vbID, err)
+			logger.Ctx(ctx).Warn("cannot re-open stream, vbID: %d, err: %v", vbID, err)
 		}
 
 		retry--
 		if retry == 0 {
-			logger.Log.Error("error while re-open stream, vbID: %d, err: give up after few retry", vbID)
-			panic(err)
+			if !s.onReconnectFailure(vbID, err) {
+				return
+			}
+
+			retry = 5
 		}
 
-		time.Sleep(time.Second)
+		if !s.sleep(ctx, time.Second) {
+			return
+		}
+	}
+}
+
+// notifyWatermark notifies the event handler when the global watermark advances.
+func (s *stream) notifyWatermark() {
+	handler, ok := s.eventHandler.(models.WatermarkHandler)
+	if !ok {
+		return
+	}
+
+	if watermark, advanced := s.watermarks.Advanced(); advanced {
+		handler.OnWatermarkAdvanced(models.WatermarkAdvanced{Time: s.clock.Now(), Watermark: watermark})
+	}
+}
+
+// sleep waits for the duration and reports false when the ctx is done before.
+func (s *stream) sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-s.clock.After(d):
+		return true
+	}
+}
+
+// onReconnectFailure applies the reconnect policy and reports whether re-open should be retried.
+func (s *stream) onReconnectFailure(vbID uint16, err error) bool {
+	atomic.AddInt64(&s.metric.ReconnectFailure, 1)
+
+	switch s.config.Dcp.Reconnect.Policy {
+	case config.ReconnectPolicyHalt:
+		logger.Ctx(s.streamCtx()).Error("error while re-open stream, vbID: %d halted, err: %v", vbID, err)
+		s.endStream()
+		return false
+	case config.ReconnectPolicyAlert:
+		logger.Ctx(s.streamCtx()).Error("error while re-open stream, vbID: %d will be retried, err: %v", vbID, err)
+		return true
+	default:
+		logger.Ctx(s.streamCtx()).Error("error while re-open stream, vbID: %d, err: %v", vbID, err)
+		panic(err)
+	}
+}
+
+// isAssigned reports whether the vBucket is streamed by the member, vBuckets released by an incremental rebalance
+// are not.
+func (s *stream) isAssigned(vbID uint16) bool {
+	s.stateLock.RLock()
+	offsets := s.offsets
+	s.stateLock.RUnlock()
+
+	_, ok := offsets.Load(vbID)
+	return ok
+}
+
+func (s *stream) endStream() {
+	activeStreams := s.activeStreams.Add(-1)
+	if activeStreams == 0 && !s.streamFinishedWithCloseCh {
+		s.finishStreamWithEndEventCh <- struct{}{}
 	}
 }
 
@@ -212,10 +568,7 @@ func (s *stream) listenEnd(endContext models.DcpStreamEndContext) {
 			errors.Is(endContext.Err, gocbcore.ErrDCPStreamDisconnected)) {
 		go s.reopenStream(endContext.Event.VbID)
 	} else {
-		activeStreams := s.activeStreams.Add(-1)
-		if activeStreams == 0 && !s.streamFinishedWithCloseCh {
-			s.finishStreamWithEndEventCh <- struct{}{}
-		}
+		s.endStream()
 	}
 }
 
@@ -226,63 +579,192 @@ func (s *stream) Open() {
 	s.eventHandler.BeforeStreamStart()
 
 	vbIDs := s.vBucketDiscovery.Get()
-	s.vbIDRange = &models.VbIDRange{
-		Start: vbIDs[0],
-		End:   vbIDs[len(vbIDs)-1],
+	s.vbIDs = vbIDs
+
+	// the checkpoints are loaded after the former owners save them
+	if s.handoff != nil {
+		s.handoff.Claim(vbIDs)
 	}
 
+	discoveryMetric := s.vBucketDiscovery.GetMetric()
+	rootCtx, cancelCtx := context.WithCancel(context.Background())
+	ctx := models.NewStreamContext(rootCtx, &models.StreamIdentity{
+		GroupName:    s.config.Dcp.Group.Name,
+		MemberNumber: discoveryMetric.MemberNumber,
+		TotalMembers: discoveryMetric.TotalMembers,
+	})
+
+	s.ctxLock.Lock()
+	s.rootCtx, s.ctx, s.cancelCtx = rootCtx, ctx, cancelCtx
+	s.ctxLock.Unlock()
+
 	if !s.config.RollbackMitigation.Disabled {
 		if s.bucketInfo.IsEphemeral() {
 			logger.Log.Info("rollback mitigation is disabled for ephemeral bucket")
 			s.config.RollbackMitigation.Disabled = true
 		} else {
-			s.rollbackMitigation = couchbase.NewRollbackMitigation(s.client, s.config, vbIDs, s.dispatchPersistSeqNo)
+			s.rollbackMitigation = couchbase.NewRollbackMitigation(s.client, s.config, vbIDs, s.dispatchPersistSeqNo, s.clock)
 			s.rollbackMitigation.Start()
 		}
 	}
 
 	s.activeStreams.Swap(int32(len(vbIDs)))
+	s.pauseGate.Arm()
+
+	if s.scheduler != nil {
+		s.scheduler.Start()
+	}
+
+	if s.parallel != nil {
+		s.parallel.Start()
+	}
 
 	latestSeqNoInitializer := offset.NewOffsetLatestSeqNoInit(s.config)
 
-	s.checkpoint = NewCheckpoint(s, vbIDs, s.client, s.metadata, s.config, latestSeqNoInitializer)
-	s.offsets, s.dirtyOffsets, s.anyDirtyOffset = s.checkpoint.Load()
+	checkpoint := NewCheckpoint(s, vbIDs, s.client, s.metadata, s.config, latestSeqNoInitializer, s.clock)
+	offsets, dirtyOffsets, anyDirtyOffset := checkpoint.Load()
 
-	s.observers = wrapper.CreateConcurrentSwissMap[uint16, couchbase.Observer](1024)
-	s.offsets.Range(func(vbID uint16, offset *models.Offset) bool {
-		s.observers.Store(
+	if s.stateStore != nil && s.applyState(vbIDs, offsets, dirtyOffsets) {
+		anyDirtyOffset = true
+	}
+
+	if s.config.IsDcpModeFinite() {
+		s.applyFiniteBounds(offsets)
+	}
+
+	observers := wrapper.CreateConcurrentSwissMap[uint16, couchbase.Observer](s.config.GetMapInitialSize(len(vbIDs)))
+	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		observers.Store(
 			vbID,
-			couchbase.NewObserver(s.config,
-				vbID, offset.LatestSeqNo, s.listen, s.listenEnd, s.collectionIDs, s.tracerComponent,
+			couchbase.NewObserver(ctx, s.config,
+				vbID, offset.LatestSeqNo, s.listen, s.listenEnd, s.collectionIDs, s.tracerComponent, s.clock,
 			),
 		)
 
 		return true
 	})
 
+	s.stateLock.Lock()
+	s.checkpoint = checkpoint
+	s.offsets, s.dirtyOffsets, s.anyDirtyOffset = offsets, dirtyOffsets, anyDirtyOffset
+	s.observers = observers
+	s.stateLock.Unlock()
+
 	s.openAllStreams(vbIDs)
 
-	logger.Log.Info("stream started")
+	logger.Ctx(ctx).Info("stream started")
 	s.eventHandler.AfterStreamStart()
 
 	s.checkpoint.StartSchedule()
 
+	if s.purgeMonitor != nil {
+		s.purgeMonitor.Start()
+	}
+
 	go s.wait()
 	s.open = true
 }
 
+// applyState restores the state of the vBuckets and starts them from the offsets of their snapshots,
+// so events which changed the state after the snapshot are streamed again.
+func (s *stream) applyState(
+	vbIDs []uint16,
+	offsets *wrapper.ConcurrentSwissMap[uint16, *models.Offset],
+	dirtyOffsets *wrapper.ConcurrentSwissMap[uint16, bool],
+) bool {
+	stateOffsets, err := s.stateStore.Load(vbIDs, getBucketUUID(s.client), offsets)
+	if err != nil {
+		logger.Log.Error("error while loading state, err: %v", err)
+		panic(err)
+	}
+
+	for vbID, stateOffset := range stateOffsets {
+		if current, ok := offsets.Load(vbID); ok {
+			if current.SeqNo == stateOffset.SeqNo && current.VbUUID == stateOffset.VbUUID {
+				continue
+			}
+
+			stateOffset.LatestSeqNo = current.LatestSeqNo
+		}
+
+		offsets.Store(vbID, stateOffset)
+		dirtyOffsets.Store(vbID, true)
+	}
+
+	return len(stateOffsets) > 0
+}
+
+// applyFiniteBounds keeps the high seqnos captured when a vBucket is opened first in finite mode,
+// so streams re-opened by a rebalance stop at the same point instead of the new high seqno.
+func (s *stream) applyFiniteBounds(offsets *wrapper.ConcurrentSwissMap[uint16, *models.Offset]) {
+	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		bound, ok := s.finiteBounds[vbID]
+		if !ok {
+			s.finiteBounds[vbID] = offset.LatestSeqNo
+			return true
+		}
+
+		if bound < offset.SeqNo {
+			bound = offset.SeqNo
+		}
+
+		offset.LatestSeqNo = bound
+		return true
+	})
+}
+
 func (s *stream) IsOpen() bool {
 	return s.open
 }
 
+// Pause stops dispatching events to the consumer, dcp streams stay open and keep their state.
+func (s *stream) Pause() {
+	s.PauseFor(PauseReasonManual)
+}
+
+// Resume clears the manual pause, the stream stays paused while any other reason is set.
+func (s *stream) Resume() {
+	s.ResumeFor(PauseReasonManual)
+}
+
+func (s *stream) PauseFor(reason PauseReason) {
+	if s.pauseGate.Pause(reason) {
+		logger.Log.Info("stream paused, reason: %s", reason)
+	}
+}
+
+func (s *stream) ResumeFor(reason PauseReason) {
+	if s.pauseGate.Resume(reason) {
+		logger.Log.Info("stream resumed, reason: %s", reason)
+	} else if s.pauseGate.IsPaused() {
+		logger.Log.Info("%s pause is cleared, stream is still paused", reason)
+	}
+}
+
+func (s *stream) IsPaused() bool {
+	return s.pauseGate.IsPaused()
+}
+
+func (s *stream) IsPausedFor(reason PauseReason) bool {
+	return s.pauseGate.IsPausedFor(reason)
+}
+
 func (s *stream) Rebalance() {
+	if s.deferRebalance() {
+		return
+	}
+
 	if s.balancing && s.rebalanceTimer != nil {
 		// Is rebalance timer triggered already
+		if s.incremental {
+			s.releaseVBuckets()
+		}
+
 		if s.rebalanceTimer.Stop() {
 			s.rebalanceTimer.Reset(s.config.Dcp.Group.Membership.RebalanceDelay)
 			logger.Log.Info("latest rebalance time is resetted")
 		} else {
-			s.rebalanceTimer = time.AfterFunc(s.config.Dcp.Group.Membership.RebalanceDelay, s.Rebalance)
+			s.rebalanceTimer = s.clock.AfterFunc(s.config.Dcp.Group.Membership.RebalanceDelay, s.Rebalance)
 			logger.Log.Info("latest rebalance time is reassigned")
 		}
 		return
@@ -294,16 +776,22 @@ func (s *stream) Rebalance() {
 
 	if !s.balancing {
 		s.balancing = true
-		s.Close(false)
+		s.incremental = s.canRebalanceIncrementally()
+
+		if s.incremental {
+			s.releaseVBuckets()
+		} else {
+			s.Close(false)
+		}
 	}
 
 	s.eventHandler.AfterRebalanceStart()
 
 	if s.config.Dcp.Group.Membership.Type == membership.DynamicMembershipType {
-		s.rebalanceTimer = time.AfterFunc(0, s.rebalance)
+		s.rebalanceTimer = s.clock.AfterFunc(0, s.rebalance)
 		logger.Log.Info("rebalance delay is disabled on dynamic membership")
 	} else {
-		s.rebalanceTimer = time.AfterFunc(s.config.Dcp.Group.Membership.RebalanceDelay, s.rebalance)
+		s.rebalanceTimer = s.clock.AfterFunc(s.config.Dcp.Group.Membership.RebalanceDelay, s.rebalance)
 		logger.Log.Info("rebalance will start after %v", s.config.Dcp.Group.Membership.RebalanceDelay)
 	}
 }
@@ -314,8 +802,15 @@ func (s *stream) rebalance() {
 	defer s.rebalanceLock.Unlock()
 
 	s.eventHandler.BeforeRebalanceEnd()
-	s.Open()
-	s.metric.Rebalance++
+
+	if s.incremental {
+		s.reassignVBuckets()
+		s.incremental = false
+	} else {
+		s.Open()
+	}
+
+	atomic.AddInt64(&s.metric.Rebalance, 1)
 
 	logger.Log.Info("rebalance is finished")
 	s.balancing = false
@@ -326,23 +821,49 @@ func (s *stream) Save() {
 	s.checkpoint.Save()
 }
 
+func (s *stream) Commit() <-chan error {
+	return s.checkpoint.Commit()
+}
+
+func (s *stream) CommitState() error {
+	if s.stateStore == nil {
+		return nil
+	}
+
+	return s.stateStore.Commit()
+}
+
 func (s *stream) dispatchPersistSeqNo(persistSeqNo *models.PersistSeqNo) {
-	if s.observers != nil {
-		if observer, ok := s.observers.Load(persistSeqNo.VbID); ok {
+	s.stateLock.RLock()
+	observers := s.observers
+	s.stateLock.RUnlock()
+
+	if observers != nil {
+		if observer, ok := observers.Load(persistSeqNo.VbID); ok {
 			observer.SetPersistSeqNo(persistSeqNo.SeqNo)
 		}
 	}
 }
 
 func (s *stream) openStream(vbID uint16) error {
+	ctx := s.streamCtx()
+
 	offset, exist := s.offsets.Load(vbID)
 	if !exist {
 		err := fmt.Errorf("vbID: %d not found on offset map", vbID)
-		logger.Log.Error("error while opening stream, err: %v", err)
+		logger.Ctx(ctx).Error("error while opening stream, err: %v", err)
 		return err
 	}
 	observer, _ := s.observers.Load(vbID)
-	return s.client.OpenStream(vbID, s.collectionIDs, offset, observer)
+	return s.client.OpenStream(ctx, vbID, s.collectionIDs, offset, observer)
+}
+
+// streamCtx returns the ctx of the latest opened streams, it is replaced by the rebalances.
+func (s *stream) streamCtx() context.Context {
+	s.ctxLock.RLock()
+	defer s.ctxLock.RUnlock()
+
+	return s.ctx
 }
 
 func (s *stream) openAllStreams(vbIDs []uint16) {
@@ -353,7 +874,7 @@ func (s *stream) openAllStreams(vbIDs []uint16) {
 		go func(innerVbId uint16) {
 			err := s.openStream(innerVbId)
 			if err != nil {
-				logger.Log.Error("error while open stream, vbID: %d, err: %v", innerVbId, err)
+				logger.Ctx(s.streamCtx()).Error("error while open stream, vbID: %d, err: %v", innerVbId, err)
 				panic(err)
 			}
 			openWg.Done()
@@ -367,12 +888,14 @@ func (s *stream) closeAllStreams() {
 	// We need to do this without async when couchbase version below v5.5.0.
 	// Because "gocbcore - memdopmap.go - FindOpenStream" is not thread safe.
 	// BTW We cannot use ConcurrentSwissMap either. You know it's concurrent :/
+	ctx := s.streamCtx()
+
 	if s.streamEndNotSupportedData != nil {
 		s.streamEndNotSupportedData.ending = true
-		for vbID := s.vbIDRange.Start; vbID <= s.vbIDRange.End; vbID++ {
+		for _, vbID := range s.vbIDs {
 			s.streamEndNotSupportedData.queue <- struct{}{}
-			if err := s.client.CloseStream(vbID); err != nil {
-				logger.Log.Error(
+			if err := s.client.CloseStream(ctx, vbID); err != nil {
+				logger.Ctx(ctx).Error(
 					"cannot close stream on (stream end not supporting) mode, vbID: %d, err: %v",
 					vbID, err,
 				)
@@ -384,8 +907,8 @@ func (s *stream) closeAllStreams() {
 		wg.Add(s.offsets.Count())
 		s.offsets.Range(func(vbID uint16, _ *models.Offset) bool {
 			go func(vbID uint16) {
-				if err := s.client.CloseStream(vbID); err != nil {
-					logger.Log.Error("cannot close stream, vbID: %d, err: %v", vbID, err)
+				if err := s.client.CloseStream(ctx, vbID); err != nil {
+					logger.Ctx(ctx).Error("cannot close stream, vbID: %d, err: %v", vbID, err)
 				}
 
 				wg.Done()
@@ -425,22 +948,64 @@ func (s *stream) Close(closeWithCancel bool) {
 		return true
 	})
 
+	s.pauseGate.Release()
+
+	if s.scheduler != nil {
+		s.scheduler.Stop()
+	}
+
+	if s.parallel != nil {
+		s.parallel.Stop()
+	}
+
+	if s.purgeMonitor != nil {
+		s.purgeMonitor.Stop()
+	}
+
+	if buffered, ok := s.consumer.(models.BufferedConsumer); ok {
+		buffered.Discard()
+	}
+
+	s.watermarks.Reset()
+
+	if s.stateStore != nil {
+		s.stateStore.Release()
+	}
+
 	if s.checkpoint != nil {
 		s.checkpoint.StopSchedule()
 	}
 
 	s.closeAllStreams()
 
+	s.snapshotTracer.Close(nil)
+
+	if s.handoff != nil {
+		if s.config.Checkpoint.Type == CheckpointTypeAuto && s.checkpoint != nil {
+			s.checkpoint.Save()
+		}
+
+		s.handoff.Release(s.vbIDs)
+	}
+
+	if s.cancelCtx != nil {
+		s.cancelCtx()
+	}
+
 	s.observers.Range(func(_ uint16, observer couchbase.Observer) bool {
 		observer.CloseEnd()
 		return true
 	})
-	s.observers = nil
 
-	s.offsets = wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](1024)
-	s.dirtyOffsets = wrapper.CreateConcurrentSwissMap[uint16, bool](1024)
+	mapInitialSize := s.config.GetMapInitialSize(len(s.vbIDs))
+
+	s.stateLock.Lock()
+	s.observers = nil
+	s.offsets = wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](mapInitialSize)
+	s.dirtyOffsets = wrapper.CreateConcurrentSwissMap[uint16, bool](mapInitialSize)
+	s.stateLock.Unlock()
 
-	logger.Log.Info("stream stopped")
+	logger.Ctx(s.streamCtx()).Info("stream stopped")
 	s.eventHandler.AfterStreamStop()
 	s.open = false
 
@@ -449,25 +1014,136 @@ func (s *stream) Close(closeWithCancel bool) {
 	}
 }
 
+// GetOffsets returns copies of the offset maps, so they stay consistent while the stream is rebalanced.
 func (s *stream) GetOffsets() (*wrapper.ConcurrentSwissMap[uint16, *models.Offset], *wrapper.ConcurrentSwissMap[uint16, bool], bool) {
-	return s.offsets, s.dirtyOffsets, s.anyDirtyOffset
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+
+	mapInitialSize := s.config.GetMapInitialSize(len(s.vbIDs))
+
+	offsets := wrapper.CreateConcurrentSwissMap[uint16, *models.Offset](mapInitialSize)
+	s.offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		o := *offset
+		offsets.Store(vbID, &o)
+		return true
+	})
+
+	dirtyOffsets := wrapper.CreateConcurrentSwissMap[uint16, bool](mapInitialSize)
+	s.dirtyOffsets.Range(func(vbID uint16, dirty bool) bool {
+		dirtyOffsets.Store(vbID, dirty)
+		return true
+	})
+
+	return offsets, dirtyOffsets, s.anyDirtyOffset
 }
 
+// GetObservers returns a copy of the observer map, or nil when the stream is closed.
 func (s *stream) GetObservers() *wrapper.ConcurrentSwissMap[uint16, couchbase.Observer] {
-	return s.observers
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+
+	if s.observers == nil {
+		return nil
+	}
+
+	observers := wrapper.CreateConcurrentSwissMap[uint16, couchbase.Observer](s.config.GetMapInitialSize(len(s.vbIDs)))
+	s.observers.Range(func(vbID uint16, observer couchbase.Observer) bool {
+		observers.Store(vbID, observer)
+		return true
+	})
+
+	return observers
 }
 
+// GetMetric returns a copy of the metric, the counters are loaded atomically since the listeners update them.
 func (s *stream) GetMetric() (*Metric, int32) {
-	return s.metric, s.activeStreams.Load()
+	return &Metric{
+		ProcessLatency:   s.metric.ProcessLatency,
+		DcpLatency:       s.metric.DcpLatency,
+		ClockSkew:        atomic.LoadInt64(&s.metric.ClockSkew),
+		DcpRawLatency:    atomic.LoadInt64(&s.metric.DcpRawLatency),
+		Reconnect:        atomic.LoadInt64(&s.metric.Reconnect),
+		ReconnectFailure: atomic.LoadInt64(&s.metric.ReconnectFailure),
+		Filtered:         atomic.LoadInt64(&s.metric.Filtered),
+		Binary:           atomic.LoadInt64(&s.metric.Binary),
+		Invalid:          atomic.LoadInt64(&s.metric.Invalid),
+		Rebalance:        atomic.LoadInt64(&s.metric.Rebalance),
+	}, s.activeStreams.Load()
 }
 
 func (s *stream) GetCheckpointMetric() *CheckpointMetric {
-	return s.checkpoint.GetMetric()
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+
+	if s.checkpoint == nil {
+		return &CheckpointMetric{}
+	}
+
+	metric := *s.checkpoint.GetMetric()
+	return &metric
+}
+
+func (s *stream) GetMetricsSnapshot() *MetricsSnapshot {
+	observers := s.GetObservers()
+	offsets, _, _ := s.GetOffsets()
+	metric, activeStreams := s.GetMetric()
+
+	snapshot := newMetricsSnapshot(observers, offsets, metric, activeStreams, s.GetCheckpointMetric(), s.IsPaused())
+	snapshot.Held = s.IsInHoldMode()
+	snapshot.Watermark = s.watermarks.Get().Global
+	if s.scheduler != nil {
+		snapshot.Scheduler = s.scheduler.GetMetric()
+	}
+	if s.purgeMonitor != nil {
+		snapshot.PurgeMargin = s.purgeMonitor.GetMargins()
+	}
+
+	return snapshot
+}
+
+func (s *stream) GetWatermarks() *Watermarks {
+	return s.watermarks.Get()
+}
+
+// GetVBucketStats returns the stream stats of the vBuckets of this member.
+func (s *stream) GetVBucketStats() (map[uint16]VBucketStats, error) {
+	offsets, _, _ := s.GetOffsets()
+
+	seqNos := make(map[uint16]uint64, offsets.Count())
+	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		seqNos[vbID] = offset.SeqNo
+		return true
+	})
+
+	highSeqNos, err := s.client.GetVBucketSeqNos(false)
+	if err != nil {
+		return nil, err
+	}
+
+	high := make(map[uint16]uint64, len(seqNos))
+	for vbID := range seqNos {
+		high[vbID], _ = highSeqNos.Load(vbID)
+	}
+
+	stats := s.vBucketStats.Get(seqNos, high)
+
+	offsets.Range(func(vbID uint16, offset *models.Offset) bool {
+		if stat, ok := stats[vbID]; ok {
+			stat.ProcessedAt = offset.ProcessedAt
+			stats[vbID] = stat
+		}
+		return true
+	})
+
+	return stats, nil
 }
 
 func (s *stream) UnmarkDirtyOffsets() {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
 	s.anyDirtyOffset = false
-	s.dirtyOffsets = wrapper.CreateConcurrentSwissMap[uint16, bool](1024)
+	s.dirtyOffsets = wrapper.CreateConcurrentSwissMap[uint16, bool](s.config.GetMapInitialSize(len(s.vbIDs)))
 }
 
 func NewStream(client couchbase.Client,
@@ -481,7 +1157,17 @@ func NewStream(client couchbase.Client,
 	stopCh chan struct{},
 	eventHandler models.EventHandler,
 	tc *tracing.TracerComponent,
+	stateBackend state.Backend,
+	stateHandoff state.Handoff,
+	clock clock.Clock,
+	validators []models.Validator,
+	idGenerator models.IDGenerator,
+	decorators []models.Decorator,
 ) Stream {
+	if user, ok := consumer.(clockUser); ok {
+		user.useClock(clock)
+	}
+
 	stream := &stream{
 		client:                     client,
 		metadata:                   metadata,
@@ -490,12 +1176,67 @@ func NewStream(client couchbase.Client,
 		bucketInfo:                 bucketInfo,
 		vBucketDiscovery:           vBucketDiscovery,
 		collectionIDs:              collectionIDs,
+		finiteBounds:               map[uint16]uint64{},
 		finishStreamWithCloseCh:    make(chan struct{}, 1),
 		finishStreamWithEndEventCh: make(chan struct{}, 1),
 		stopCh:                     stopCh,
 		eventHandler:               eventHandler,
-		metric:                     &Metric{},
+		metric:                     newMetric(),
+		clockSkew:                  &clockSkewEstimator{},
+		reconnectBudget:            newReconnectBudget(config.Dcp.Reconnect.MaxAttempts, config.Dcp.Reconnect.Window),
+		pauseGate:                  newPauseGate(),
+		keyFilter:                  newKeyFilter(config.Dcp.Filter),
+		watermarks:                 newWatermarkTracker(),
+		resetTimes:                 wrapper.CreateConcurrentSwissMap[uint16, time.Time](0),
+		vBucketStats:               newVBucketStatsTracker(client.GetNumVBuckets()),
 		tracerComponent:            tc,
+		snapshotTracer:             newSnapshotTracer(tc),
+		clock:                      clock,
+		validators:                 validators,
+		idGenerator:                idGenerator,
+		decorators:                 decorators,
+	}
+
+	stream.hold.Store(config.Dcp.Hold)
+
+	if scheduler := config.Dcp.Listener.Scheduler; scheduler.Enabled {
+		stream.scheduler = newFairScheduler(
+			client.GetNumVBuckets(), scheduler.Workers, scheduler.MaxInFlight, scheduler.QueueSize, scheduler.StarvedAfter, stream.pauseGate,
+		)
+	}
+
+	capabilities, err := consumerCapabilities(consumer)
+	if err != nil {
+		logger.Log.Error("error while create stream, err: %v", err)
+		panic(err)
+	}
+
+	stream.applyCapabilities(capabilities, stateBackend != nil)
+
+	if err := validateBinaryPolicy(config.Dcp.Binary.Policy, consumer); err != nil {
+		logger.Log.Error("error while create stream, err: %v", err)
+		panic(err)
+	}
+
+	if err := validateValidationPolicy(config.Dcp.Validation.Policy, consumer); err != nil {
+		logger.Log.Error("error while create stream, err: %v", err)
+		panic(err)
+	}
+
+	if config.PurgeMonitor.Enabled {
+		stream.purgeMonitor = newPurgeMonitor(stream)
+	}
+
+	if config.Dcp.Group.Membership.Handoff.Enabled {
+		if config.IsCouchbaseMetadata() {
+			stream.handoff = couchbase.NewVBucketHandoff(client, config)
+		} else {
+			logger.Log.Warn("vBucket handoff is disabled, it requires couchbase metadata")
+		}
+	}
+
+	if stateBackend != nil {
+		stream.stateStore = newStateStore(stateBackend, stateHandoff)
 	}
 
 	if version.Lower(couchbase.SrvVer550) {
@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+type validationAction int
+
+const (
+	validationValid validationAction = iota
+	validationFlag
+	validationDeadLetter
+	validationDrop
+)
+
+func validateValidationPolicy(policy string, consumer models.Consumer) error {
+	switch policy {
+	case config.ValidationPolicyFlag, config.ValidationPolicyDrop:
+		return nil
+	case config.ValidationPolicyDeadLetter:
+		if _, ok := consumer.(deadLetterWriter); !ok {
+			return errors.New("validation policy deadLetter requires a dead letter consumer")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown validation policy: %s", policy)
+	}
+}
+
+// validate runs the validators on the event till the first rejection, and returns the action of dcp.validation.policy.
+func (s *stream) validate(payload interface{}) (validationAction, error) {
+	for _, validator := range s.validators {
+		err := validator(payload)
+		if err == nil {
+			continue
+		}
+
+		atomic.AddInt64(&s.metric.Invalid, 1)
+
+		err = fmt.Errorf("%w: %w", ErrInvalidEvent, err)
+
+		switch s.config.Dcp.Validation.Policy {
+		case config.ValidationPolicyDrop:
+			return validationDrop, err
+		case config.ValidationPolicyDeadLetter:
+			return validationDeadLetter, err
+		default:
+			return validationFlag, err
+		}
+	}
+
+	return validationValid, nil
+}
@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/wrapper"
+)
+
+// ErrVBucketCountChanged is returned when the checkpoints are saved from a bucket with another vBucket count. Keys are
+// hashed to vBuckets by the count, so the offsets cannot be mapped to the new vBuckets.
+var ErrVBucketCountChanged = errors.New("vBucket count of the bucket differs from the checkpoints")
+
+// vBucketCountNotifier is implemented by the stream to notify the event handler of a vBucket count change found by
+// the checkpoint.
+type vBucketCountNotifier interface {
+	notifyVBucketCountChange(checkpointed int, current int, reset bool)
+}
+
+// checkpointedVBuckets returns the vBucket count of the bucket the checkpoints are saved from, 0 when it is not known.
+func checkpointedVBuckets(dump *wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument], current int) int {
+	checkpointed := 0
+
+	dump.Range(func(_ uint16, doc *models.CheckpointDocument) bool {
+		if doc.VBuckets != 0 && doc.VBuckets != current {
+			checkpointed = doc.VBuckets
+			return false
+		}
+
+		return true
+	})
+
+	return checkpointed
+}
+
+// resetVBucketCountChange clears the checkpoints saved from a bucket with another vBucket count with
+// checkpoint.vBucketCountChange reset, so the vBuckets start with checkpoint.autoReset. It panics with
+// ErrVBucketCountChanged otherwise, instead of streaming the vBuckets from the offsets of other ones.
+func (s *checkpoint) resetVBucketCountChange(dump *wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument]) bool {
+	current := s.client.GetNumVBuckets()
+
+	checkpointed := checkpointedVBuckets(dump, current)
+	if checkpointed == 0 {
+		return false
+	}
+
+	reset := s.config.Checkpoint.VBucketCountChange == config.VBucketCountChangeReset
+
+	if notifier, ok := s.stream.(vBucketCountNotifier); ok {
+		notifier.notifyVBucketCountChange(checkpointed, current, reset)
+	}
+
+	if !reset {
+		err := fmt.Errorf("%w, checkpointed: %d, current: %d", ErrVBucketCountChanged, checkpointed, current)
+		logger.Log.Error(
+			"error while loading checkpoint, set checkpoint.vBucketCountChange to reset or reset the offsets of the group, err: %v",
+			err,
+		)
+		panic(err)
+	}
+
+	var stale []uint16
+	dump.Range(func(vbID uint16, doc *models.CheckpointDocument) bool {
+		if doc.VBuckets != 0 {
+			stale = append(stale, vbID)
+		}
+
+		return true
+	})
+
+	if err := s.metadata.Clear(stale); err != nil {
+		logger.Log.Error("error while clear checkpoints of another vBucket count, err: %v", err)
+		panic(err)
+	}
+
+	logger.Log.Warn(
+		"vBucket count changed from %d to %d, checkpoints are reset to %v", checkpointed, current, s.config.Checkpoint.AutoReset,
+	)
+
+	return true
+}
+
+func newEmptyCheckpoints(vbIDs []uint16, bucketUUID string) *wrapper.ConcurrentSwissMap[uint16, *models.CheckpointDocument] {
+	dump := wrapper.CreateConcurrentSwissMap[uint16, *models.CheckpointDocument](uint64(len(vbIDs)))
+	for _, vbID := range vbIDs {
+		dump.Store(vbID, models.NewEmptyCheckpointDocument(bucketUUID))
+	}
+
+	return dump
+}
+
+func (s *stream) notifyVBucketCountChange(checkpointed int, current int, reset bool) {
+	if handler, ok := s.eventHandler.(models.VBucketCountChangeHandler); ok {
+		handler.OnVBucketCountChanged(models.VBucketCountChanged{
+			Time:         time.Now(),
+			Checkpointed: checkpointed,
+			Current:      current,
+			Reset:        reset,
+		})
+	}
+}
@@ -2,16 +2,19 @@ package stream
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/asaskevich/EventBus"
 
 	"github.com/Trendyol/go-dcp/config"
 
 	"github.com/Trendyol/go-dcp/kubernetes"
+	"github.com/Trendyol/go-dcp/membership/consul"
+	"github.com/Trendyol/go-dcp/membership/etcd"
+	"github.com/Trendyol/go-dcp/membership/redis"
 
 	"github.com/Trendyol/go-dcp/couchbase"
 
-	"github.com/Trendyol/go-dcp/helpers"
 	"github.com/Trendyol/go-dcp/logger"
 	"github.com/Trendyol/go-dcp/membership"
 )
@@ -20,11 +23,13 @@ type VBucketDiscovery interface {
 	Get() []uint16
 	Close()
 	GetMetric() *VBucketDiscoveryMetric
+	SetLivenessProbe(probe membership.LivenessProbe)
 }
 
 type vBucketDiscovery struct {
 	membership             membership.Membership
 	vBucketDiscoveryMetric *VBucketDiscoveryMetric
+	assignment             membership.AssignmentStrategy
 	vBucketNumber          int
 }
 
@@ -38,38 +43,101 @@ type VBucketDiscoveryMetric struct {
 }
 
 func (s *vBucketDiscovery) Get() []uint16 {
+	receivedInfo := s.membership.GetInfo()
+
+	s.vBucketDiscoveryMetric.TotalMembers = receivedInfo.TotalMembers
+	s.vBucketDiscoveryMetric.MemberNumber = receivedInfo.MemberNumber
+
+	if receivedInfo.IsIdle() {
+		logger.Log.Info("member is standby, active members: %v, no vbuckets are assigned", receivedInfo.TotalMembers)
+
+		s.vBucketDiscoveryMetric.VBucketRangeStart = 0
+		s.vBucketDiscoveryMetric.VBucketRangeEnd = 0
+
+		return []uint16{}
+	}
+
 	vBuckets := make([]uint16, 0, s.vBucketNumber)
 
 	for i := 0; i < s.vBucketNumber; i++ {
 		vBuckets = append(vBuckets, uint16(i))
 	}
 
-	receivedInfo := s.membership.GetInfo()
+	weights := receivedInfo.Weights
+	if len(weights) != receivedInfo.TotalMembers || isUniform(weights) {
+		weights = nil
+	}
+
+	chunks := s.assignment.Assign(vBuckets, receivedInfo.TotalMembers, weights)
+	if len(chunks) != receivedInfo.TotalMembers {
+		err := errors.New("assignment strategy must return the vBuckets of each member")
+		logger.Log.Error("error while assign vBuckets, members: %v, assigned: %v, err: %v",
+			receivedInfo.TotalMembers, len(chunks), err)
+		panic(err)
+	}
 
-	readyToStreamVBuckets := helpers.ChunkSlice[uint16](vBuckets, receivedInfo.TotalMembers)[receivedInfo.MemberNumber-1]
+	readyToStreamVBuckets := chunks[receivedInfo.MemberNumber-1]
+	sort.Slice(readyToStreamVBuckets, func(i, j int) bool {
+		return readyToStreamVBuckets[i] < readyToStreamVBuckets[j]
+	})
+
+	if len(readyToStreamVBuckets) == 0 {
+		logger.Log.Info("member: %v/%v, no vbuckets are assigned", receivedInfo.MemberNumber, receivedInfo.TotalMembers)
+
+		s.vBucketDiscoveryMetric.VBucketRangeStart = 0
+		s.vBucketDiscoveryMetric.VBucketRangeEnd = 0
+
+		return []uint16{}
+	}
 
 	start := readyToStreamVBuckets[0]
 	end := readyToStreamVBuckets[len(readyToStreamVBuckets)-1]
 
 	logger.Log.Info(
-		"member: %v/%v, vbucket range: %v-%v",
+		"member: %v/%v, vbucket count: %v, vbucket range: %v-%v",
 		receivedInfo.MemberNumber, receivedInfo.TotalMembers,
-		start, end,
+		len(readyToStreamVBuckets), start, end,
 	)
 
-	s.vBucketDiscoveryMetric.TotalMembers = receivedInfo.TotalMembers
-	s.vBucketDiscoveryMetric.MemberNumber = receivedInfo.MemberNumber
 	s.vBucketDiscoveryMetric.VBucketRangeStart = start
 	s.vBucketDiscoveryMetric.VBucketRangeEnd = end
 
 	return readyToStreamVBuckets
 }
 
+func isUniform(weights []int) bool {
+	for _, weight := range weights {
+		if weight != weights[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// supportsStandby reports whether the membership shares the standby setting of its members.
+func supportsStandby(membershipType string) bool {
+	switch membershipType {
+	case membership.CouchbaseMembershipType, membership.ConsulMembershipType, membership.EtcdMembershipType,
+		membership.RedisMembershipType:
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *vBucketDiscovery) Close() {
 	s.membership.Close()
 	logger.Log.Debug("vbucket discovery closed")
 }
 
+// SetLivenessProbe shares the liveness of the member when the membership supports it.
+func (s *vBucketDiscovery) SetLivenessProbe(probe membership.LivenessProbe) {
+	if aware, ok := s.membership.(membership.LivenessAware); ok {
+		aware.SetLivenessProbe(probe)
+	}
+}
+
 func (s *vBucketDiscovery) GetMetric() *VBucketDiscoveryMetric {
 	return s.vBucketDiscoveryMetric
 }
@@ -83,26 +151,50 @@ func NewVBucketDiscovery(client couchbase.Client,
 
 	switch {
 	case config.Dcp.Group.Membership.Type == membership.StaticMembershipType:
-		ms = membership.NewStaticMembership(config)
+		ms = membership.NewStaticMembership(config, bus)
 	case config.Dcp.Group.Membership.Type == membership.CouchbaseMembershipType:
 		ms = couchbase.NewCBMembership(config, client, bus)
 	case config.Dcp.Group.Membership.Type == membership.KubernetesStatefulSetMembershipType:
 		ms = kubernetes.NewStatefulSetMembership(config)
 	case config.Dcp.Group.Membership.Type == membership.KubernetesHaMembershipType:
 		ms = kubernetes.NewHaMembership(config, bus)
+	case config.Dcp.Group.Membership.Type == membership.KubernetesLeaseMembershipType:
+		ms = kubernetes.NewLeaseMembership(config, kubernetes.NewClient(), bus)
+	case config.Dcp.Group.Membership.Type == membership.ConsulMembershipType:
+		ms = consul.NewMembership(config, bus)
+	case config.Dcp.Group.Membership.Type == membership.EtcdMembershipType:
+		ms = etcd.NewMembership(config, bus)
+	case config.Dcp.Group.Membership.Type == membership.RedisMembershipType:
+		ms = redis.NewMembership(config, bus)
 	case config.Dcp.Group.Membership.Type == membership.DynamicMembershipType:
 		ms = membership.NewDynamicMembership(bus)
 	default:
-		err := errors.New("unknown membership")
-		logger.Log.Error("error while try to use membership: %s, err: %v", config.Dcp.Group.Membership.Type, err)
+		var err error
+		ms, err = membership.New(config.Dcp.Group.Membership.Type, config, bus)
+		if err != nil {
+			logger.Log.Error("error while try to use membership: %s, registered: %v, err: %v",
+				config.Dcp.Group.Membership.Type, membership.Types(), err)
+			panic(err)
+		}
+	}
+
+	assignment, err := membership.GetAssignmentStrategy(config.Dcp.Group.Membership.Assignment)
+	if err != nil {
+		logger.Log.Error("error while try to use assignment: %s, registered: %v, err: %v",
+			config.Dcp.Group.Membership.Assignment, membership.AssignmentStrategies(), err)
 		panic(err)
 	}
 
+	if config.Dcp.Group.Membership.Standby && !supportsStandby(config.Dcp.Group.Membership.Type) {
+		logger.Log.Warn("standby is not supported by membership type: %s, member is active", config.Dcp.Group.Membership.Type)
+	}
+
 	logger.Log.Debug("vbucket discovery opened with membership type: %s", config.Dcp.Group.Membership.Type)
 
 	return &vBucketDiscovery{
 		vBucketNumber: vBucketNumber,
 		membership:    ms,
+		assignment:    assignment,
 		vBucketDiscoveryMetric: &VBucketDiscoveryMetric{
 			VBucketCount: vBucketNumber,
 			Type:         config.Dcp.Group.Membership.Type,
@@ -0,0 +1,153 @@
+package stream
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10"
+
+	"github.com/Trendyol/go-dcp/models"
+)
+
+const (
+	VBucketPhaseBackfill = "backfill"
+	VBucketPhaseInMemory = "in-memory"
+)
+
+// VBucketStats follows the stream stats of cbstats dcp. Phase is backfill while the snapshots are read from disk,
+// and itemsRemaining is the distance from the last sent seqno to the high seqno of the vBucket. ProcessedAt is the
+// time the offset of the vBucket was last advanced.
+type VBucketStats struct {
+	LastSnapshotAt   time.Time `json:"lastSnapshotAt"`
+	LastSentAt       time.Time `json:"lastSentAt"`
+	ProcessedAt      time.Time `json:"processedAt"`
+	Phase            string    `json:"phase"`
+	LastSnapshotType string    `json:"lastSnapshotType"`
+	SnapStartSeqNo   uint64    `json:"snapStartSeqNo"`
+	SnapEndSeqNo     uint64    `json:"snapEndSeqNo"`
+	LastSentSeqNo    uint64    `json:"lastSentSeqNo"`
+	HighSeqNo        uint64    `json:"highSeqNo"`
+	ItemsRemaining   uint64    `json:"itemsRemaining"`
+	ItemsSent        uint64    `json:"itemsSent"`
+	Snapshots        uint64    `json:"snapshots"`
+}
+
+type vBucketStat struct {
+	stats VBucketStats
+	lock  sync.Mutex
+}
+
+// vBucketStatsTracker keeps the stats of each vBucket under its own lock, events of different vBuckets
+// are dispatched concurrently.
+type vBucketStatsTracker struct {
+	stats []*vBucketStat
+}
+
+func (t *vBucketStatsTracker) get(vbID uint16) *vBucketStat {
+	if int(vbID) >= len(t.stats) {
+		return nil
+	}
+
+	return t.stats[vbID]
+}
+
+func (t *vBucketStatsTracker) Snapshot(marker models.DcpSnapshotMarker) {
+	stat := t.get(marker.VbID)
+	if stat == nil {
+		return
+	}
+
+	stat.lock.Lock()
+	defer stat.lock.Unlock()
+
+	stat.stats.SnapStartSeqNo = marker.StartSeqNo
+	stat.stats.SnapEndSeqNo = marker.EndSeqNo
+	stat.stats.LastSnapshotType = snapshotType(marker.SnapshotType)
+	stat.stats.LastSnapshotAt = time.Now()
+	stat.stats.Snapshots++
+
+	if marker.SnapshotType.HasOnDisk() {
+		stat.stats.Phase = VBucketPhaseBackfill
+	} else {
+		stat.stats.Phase = VBucketPhaseInMemory
+	}
+}
+
+func (t *vBucketStatsTracker) Sent(vbID uint16, seqNo uint64, item bool) {
+	stat := t.get(vbID)
+	if stat == nil {
+		return
+	}
+
+	stat.lock.Lock()
+	defer stat.lock.Unlock()
+
+	stat.stats.LastSentSeqNo = seqNo
+	stat.stats.LastSentAt = time.Now()
+
+	if item {
+		stat.stats.ItemsSent++
+	}
+}
+
+// Get returns the stats of the vBuckets with their high seqnos. A vBucket which has not sent an event yet
+// is counted from its offset.
+func (t *vBucketStatsTracker) Get(offsets map[uint16]uint64, highSeqNos map[uint16]uint64) map[uint16]VBucketStats {
+	result := make(map[uint16]VBucketStats, len(offsets))
+
+	for vbID, offset := range offsets {
+		stat := t.get(vbID)
+		if stat == nil {
+			continue
+		}
+
+		stat.lock.Lock()
+		stats := stat.stats
+		stat.lock.Unlock()
+
+		if stats.LastSentSeqNo < offset {
+			stats.LastSentSeqNo = offset
+		}
+
+		stats.HighSeqNo = highSeqNos[vbID]
+		if stats.HighSeqNo > stats.LastSentSeqNo {
+			stats.ItemsRemaining = stats.HighSeqNo - stats.LastSentSeqNo
+		}
+
+		result[vbID] = stats
+	}
+
+	return result
+}
+
+func snapshotType(state gocbcore.SnapshotState) string {
+	var flags []string
+
+	if state.HasInMemory() {
+		flags = append(flags, "memory")
+	}
+
+	if state.HasOnDisk() {
+		flags = append(flags, "disk")
+	}
+
+	if state.HasHistory() {
+		flags = append(flags, "history")
+	}
+
+	if state.HasMayDuplicateKeys() {
+		flags = append(flags, "mayDuplicateKeys")
+	}
+
+	return strings.Join(flags, ",")
+}
+
+func newVBucketStatsTracker(numVBuckets int) *vBucketStatsTracker {
+	stats := make([]*vBucketStat, numVBuckets)
+	for i := range stats {
+		stats[i] = &vBucketStat{}
+	}
+
+	return &vBucketStatsTracker{stats: stats}
+}
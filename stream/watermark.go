@@ -0,0 +1,150 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// Watermarks are the event times of the oldest unprocessed events. A vBucket without unprocessed events
+// reports its latest processed event time and does not hold the global watermark back.
+type Watermarks struct {
+	Global   time.Time            `json:"global"`
+	VBuckets map[uint16]time.Time `json:"vBuckets"`
+}
+
+type pendingEvent struct {
+	eventTime time.Time
+	seqNo     uint64
+}
+
+type vbWatermark struct {
+	processed time.Time
+	pending   []pendingEvent
+}
+
+type watermarkTracker struct {
+	advanced time.Time
+	vbs      map[uint16]*vbWatermark
+	lock     sync.Mutex
+}
+
+// Observe records an event when it is streamed. Events of a vBucket are observed in seqno order.
+func (t *watermarkTracker) Observe(vbID uint16, seqNo uint64, eventTime time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	vb, ok := t.vbs[vbID]
+	if !ok {
+		vb = &vbWatermark{}
+		t.vbs[vbID] = vb
+	}
+
+	vb.pending = append(vb.pending, pendingEvent{seqNo: seqNo, eventTime: eventTime})
+}
+
+// Ack marks the events of the vBucket up to the seqno as processed. It is called when the offset of the vBucket
+// advances, so with parallel consumers an out of order ack does not pass the events which are still consumed.
+func (t *watermarkTracker) Ack(vbID uint16, seqNo uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	vb, ok := t.vbs[vbID]
+	if !ok {
+		vb = &vbWatermark{}
+		t.vbs[vbID] = vb
+	}
+
+	i := 0
+	for i < len(vb.pending) && vb.pending[i].seqNo <= seqNo {
+		if vb.pending[i].eventTime.After(vb.processed) {
+			vb.processed = vb.pending[i].eventTime
+		}
+
+		i++
+	}
+
+	vb.pending = vb.pending[i:]
+}
+
+func (t *watermarkTracker) Get() *Watermarks {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	watermarks := &Watermarks{
+		Global:   t.global(),
+		VBuckets: make(map[uint16]time.Time, len(t.vbs)),
+	}
+
+	for vbID, vb := range t.vbs {
+		if len(vb.pending) > 0 {
+			watermarks.VBuckets[vbID] = vb.pending[0].eventTime
+		} else {
+			watermarks.VBuckets[vbID] = vb.processed
+		}
+	}
+
+	return watermarks
+}
+
+// Advanced returns the global watermark when it is after the one returned before, a watermark which goes back, e.g.
+// after a rebalance, is not returned again.
+func (t *watermarkTracker) Advanced() (time.Time, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	global := t.global()
+	if !global.After(t.advanced) {
+		return time.Time{}, false
+	}
+
+	t.advanced = global
+
+	return global, true
+}
+
+func (t *watermarkTracker) global() time.Time {
+	var oldestPending, latestProcessed time.Time
+
+	for _, vb := range t.vbs {
+		if len(vb.pending) > 0 {
+			if eventTime := vb.pending[0].eventTime; oldestPending.IsZero() || eventTime.Before(oldestPending) {
+				oldestPending = eventTime
+			}
+
+			continue
+		}
+
+		if vb.processed.After(latestProcessed) {
+			latestProcessed = vb.processed
+		}
+	}
+
+	if oldestPending.IsZero() {
+		return latestProcessed
+	}
+
+	return oldestPending
+}
+
+func (t *watermarkTracker) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.vbs = map[uint16]*vbWatermark{}
+}
+
+// Remove drops the vBuckets which are not streamed by the member anymore.
+func (t *watermarkTracker) Remove(vbIDs []uint16) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, vbID := range vbIDs {
+		delete(t.vbs, vbID)
+	}
+}
+
+func newWatermarkTracker() *watermarkTracker {
+	return &watermarkTracker{
+		vbs: map[uint16]*vbWatermark{},
+	}
+}
@@ -0,0 +1,160 @@
+package stream
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Trendyol/go-dcp/clock"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+func TestWatermarkTracker_Get(t *testing.T) {
+	base := time.Unix(1000, 0)
+
+	tests := []struct {
+		setup    func(tracker *watermarkTracker)
+		expected *Watermarks
+		name     string
+	}{
+		{
+			name:     "empty",
+			setup:    func(_ *watermarkTracker) {},
+			expected: &Watermarks{VBuckets: map[uint16]time.Time{}},
+		},
+		{
+			name: "oldest pending event holds the global watermark",
+			setup: func(tracker *watermarkTracker) {
+				tracker.Observe(0, 1, base.Add(time.Second))
+				tracker.Observe(0, 2, base.Add(2*time.Second))
+				tracker.Observe(1, 1, base.Add(3*time.Second))
+			},
+			expected: &Watermarks{
+				Global:   base.Add(time.Second),
+				VBuckets: map[uint16]time.Time{0: base.Add(time.Second), 1: base.Add(3 * time.Second)},
+			},
+		},
+		{
+			name: "acked events advance the watermark",
+			setup: func(tracker *watermarkTracker) {
+				tracker.Observe(0, 1, base.Add(time.Second))
+				tracker.Observe(0, 2, base.Add(2*time.Second))
+				tracker.Ack(0, 1)
+			},
+			expected: &Watermarks{
+				Global:   base.Add(2 * time.Second),
+				VBuckets: map[uint16]time.Time{0: base.Add(2 * time.Second)},
+			},
+		},
+		{
+			name: "vBucket without pending events does not hold the watermark back",
+			setup: func(tracker *watermarkTracker) {
+				tracker.Observe(0, 1, base.Add(time.Second))
+				tracker.Ack(0, 1)
+				tracker.Observe(1, 1, base.Add(5*time.Second))
+			},
+			expected: &Watermarks{
+				Global:   base.Add(5 * time.Second),
+				VBuckets: map[uint16]time.Time{0: base.Add(time.Second), 1: base.Add(5 * time.Second)},
+			},
+		},
+		{
+			name: "latest processed event is the watermark when every event is acked",
+			setup: func(tracker *watermarkTracker) {
+				tracker.Observe(0, 1, base.Add(2*time.Second))
+				tracker.Observe(0, 2, base.Add(time.Second))
+				tracker.Observe(1, 1, base.Add(3*time.Second))
+				tracker.Ack(0, 2)
+				tracker.Ack(1, 1)
+			},
+			expected: &Watermarks{
+				Global:   base.Add(3 * time.Second),
+				VBuckets: map[uint16]time.Time{0: base.Add(2 * time.Second), 1: base.Add(3 * time.Second)},
+			},
+		},
+		{
+			name: "removed vBuckets are dropped",
+			setup: func(tracker *watermarkTracker) {
+				tracker.Observe(0, 1, base.Add(time.Second))
+				tracker.Observe(1, 1, base.Add(2*time.Second))
+				tracker.Remove([]uint16{0})
+			},
+			expected: &Watermarks{
+				Global:   base.Add(2 * time.Second),
+				VBuckets: map[uint16]time.Time{1: base.Add(2 * time.Second)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newWatermarkTracker()
+			tt.setup(tracker)
+
+			if watermarks := tracker.Get(); !reflect.DeepEqual(watermarks, tt.expected) {
+				t.Fatalf("expected %+v, got %+v", tt.expected, watermarks)
+			}
+		})
+	}
+}
+
+func TestWatermarkTracker_Advanced(t *testing.T) {
+	base := time.Unix(1000, 0)
+	tracker := newWatermarkTracker()
+
+	if _, advanced := tracker.Advanced(); advanced {
+		t.Fatalf("empty tracker must not advance")
+	}
+
+	tracker.Observe(0, 1, base.Add(time.Second))
+	tracker.Observe(0, 2, base.Add(2*time.Second))
+
+	if watermark, advanced := tracker.Advanced(); !advanced || !watermark.Equal(base.Add(time.Second)) {
+		t.Fatalf("expected watermark %v, got %v %v", base.Add(time.Second), watermark, advanced)
+	}
+
+	if _, advanced := tracker.Advanced(); advanced {
+		t.Fatalf("same watermark must not advance again")
+	}
+
+	tracker.Ack(0, 1)
+
+	if watermark, advanced := tracker.Advanced(); !advanced || !watermark.Equal(base.Add(2*time.Second)) {
+		t.Fatalf("expected watermark %v, got %v %v", base.Add(2*time.Second), watermark, advanced)
+	}
+
+	// a rebalance resets the tracker, the watermark going back is not notified
+	tracker.Reset()
+	tracker.Observe(0, 3, base)
+
+	if _, advanced := tracker.Advanced(); advanced {
+		t.Fatalf("watermark going back must not advance")
+	}
+}
+
+type watermarkHandler struct {
+	models.EmptyEventHandler
+	events []models.WatermarkAdvanced
+}
+
+func (h *watermarkHandler) OnWatermarkAdvanced(event models.WatermarkAdvanced) {
+	h.events = append(h.events, event)
+}
+
+func TestStream_NotifyWatermark(t *testing.T) {
+	base := time.Unix(1000, 0)
+	handler := &watermarkHandler{}
+	fake := clock.NewFake(base.Add(time.Hour))
+
+	s := &stream{watermarks: newWatermarkTracker(), eventHandler: handler, clock: fake}
+
+	s.watermarks.Observe(0, 1, base)
+	s.notifyWatermark()
+	s.notifyWatermark()
+
+	expected := []models.WatermarkAdvanced{{Time: fake.Now(), Watermark: base}}
+	if !reflect.DeepEqual(handler.events, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, handler.events)
+	}
+}
@@ -90,6 +90,7 @@ func (t *Trace) RootContext() RequestSpanContext {
 
 type ObserverLabels struct {
 	collectionIDs map[uint32]string
+	attributes    map[string]interface{}
 	vbID          uint16
 }
 
@@ -97,6 +98,12 @@ func NewObserverLabels(vbID uint16, collectionIDs map[uint32]string) *ObserverLa
 	return &ObserverLabels{vbID: vbID, collectionIDs: collectionIDs}
 }
 
+// WithAttributes sets extra attributes appended to the spans, like the stream identity.
+func (l *ObserverLabels) WithAttributes(attributes map[string]interface{}) *ObserverLabels {
+	l.attributes = attributes
+	return l
+}
+
 type TracerComponent struct {
 	tracer RequestTracer
 }
@@ -108,6 +115,23 @@ func NewTracerComponent() *TracerComponent {
 	}
 }
 
+// Enabled returns false while no RequestTracer is registered, so the spans which cost more than a noop can be skipped.
+func (tc *TracerComponent) Enabled() bool {
+	_, noop := tc.tracer.(*NoopTracer)
+	return !noop
+}
+
+// StartSpan starts a root span with the attributes. It is ended by the caller.
+func (tc *TracerComponent) StartSpan(operationName string, attributes map[string]interface{}) RequestSpan {
+	span := tc.tracer.RequestSpan(RequestSpanContext{RefCtx: context.Background()}, operationName)
+
+	for key, value := range attributes {
+		span.SetAttribute(key, value)
+	}
+
+	return span
+}
+
 func (tc *TracerComponent) StartOpTelemeteryHandler(
 	service string,
 	operation string,
@@ -134,6 +158,9 @@ func (tc *TracerComponent) createOpTrace(
 	// Append labels
 	opSpan.SetAttribute("vb_id", observerLabels.vbID)
 	opSpan.SetAttribute("collection_ids", observerLabels.collectionIDs)
+	for key, value := range observerLabels.attributes {
+		opSpan.SetAttribute(key, value)
+	}
 
 	return &opTracer{
 		parentContext: parentContext,
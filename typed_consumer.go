@@ -0,0 +1,184 @@
+package dcp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Trendyol/go-dcp/config"
+	"github.com/Trendyol/go-dcp/couchbase"
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+	"github.com/Trendyol/go-dcp/stream"
+)
+
+// Change is a document change with its decoded value. Document is the zero value for deletions and expirations,
+// since they carry no document. Event is the original models.DcpMutation, models.DcpDeletion or models.DcpExpiration.
+type Change[T any] struct {
+	EventTime      time.Time
+	Document       T
+	Event          interface{}
+	CollectionName string
+	Key            []byte
+	Cas            uint64
+	SeqNo          uint64
+	VbID           uint16
+	Deleted        bool
+	Expired        bool
+}
+
+type deadLetterWriter interface {
+	DeadLetter(ctx *models.ListenerContext, err error)
+}
+
+// decodingConsumer is configured with consumer.decodeErrorPolicy when the connector starts.
+type decodingConsumer interface {
+	setDecodeErrorPolicy(policy string, deadLetters deadLetterWriter)
+}
+
+type typedConsumer[T any] struct {
+	deadLetters  deadLetterWriter
+	decode       func(value []byte) (T, error)
+	handle       func(ctx *models.ListenerContext, change Change[T])
+	policy       string
+	capabilities models.ConsumerCapabilities
+}
+
+// NewTypedConsumer creates a consumer which decodes the values of the mutations with decode and passes the changes
+// to handle. Events are acked when handle returns, so handle must not ack them. Events which cannot be decoded are
+// handled with consumer.decodeErrorPolicy, the other events of the stream are acked without calling handle.
+func NewTypedConsumer[T any](
+	decode func(value []byte) (T, error),
+	handle func(ctx *models.ListenerContext, change Change[T]),
+) models.Consumer {
+	return &typedConsumer[T]{
+		decode: decode,
+		handle: handle,
+		policy: config.DecodeErrorPolicySkip,
+	}
+}
+
+// NewTypedConsumerWithCapabilities creates a consumer like NewTypedConsumer, which declares the ordering and the
+// concurrency handle allows with models.CapableConsumer.
+func NewTypedConsumerWithCapabilities[T any](
+	decode func(value []byte) (T, error),
+	handle func(ctx *models.ListenerContext, change Change[T]),
+	capabilities models.ConsumerCapabilities,
+) models.Consumer {
+	return &typedConsumer[T]{
+		decode:       decode,
+		handle:       handle,
+		policy:       config.DecodeErrorPolicySkip,
+		capabilities: capabilities,
+	}
+}
+
+func (c *typedConsumer[T]) ConsumeEvent(ctx *models.ListenerContext) {
+	change, ok, err := c.newChange(ctx.Event)
+	if err != nil {
+		c.onDecodeError(ctx, err)
+		return
+	}
+
+	if ok {
+		c.handle(ctx, change)
+	}
+
+	ctx.Ack()
+}
+
+func (c *typedConsumer[T]) TrackOffset(_ uint16, _ *models.Offset) {}
+
+func (c *typedConsumer[T]) Capabilities() models.ConsumerCapabilities {
+	return c.capabilities
+}
+
+func (c *typedConsumer[T]) newChange(event interface{}) (Change[T], bool, error) {
+	var change Change[T]
+
+	switch e := event.(type) {
+	case models.DcpMutation:
+		value, err := e.DecompressedValue()
+		if err != nil {
+			return change, false, err
+		}
+
+		document, err := c.decode(value)
+		if err != nil {
+			return change, false, err
+		}
+
+		change = Change[T]{
+			EventTime: e.EventTime, CollectionName: e.CollectionName, Key: e.Key, Cas: e.Cas, SeqNo: e.SeqNo, VbID: e.VbID,
+			Document: document,
+		}
+	case models.DcpDeletion:
+		change = Change[T]{
+			EventTime: e.EventTime, CollectionName: e.CollectionName, Key: e.Key, Cas: e.Cas, SeqNo: e.SeqNo, VbID: e.VbID,
+			Deleted: true,
+		}
+	case models.DcpExpiration:
+		change = Change[T]{
+			EventTime: e.EventTime, CollectionName: e.CollectionName, Key: e.Key, Cas: e.Cas, SeqNo: e.SeqNo, VbID: e.VbID,
+			Expired: true,
+		}
+	default:
+		return change, false, nil
+	}
+
+	change.Event = event
+
+	return change, true, nil
+}
+
+func (c *typedConsumer[T]) onDecodeError(ctx *models.ListenerContext, err error) {
+	err = fmt.Errorf("cannot decode event: %w", err)
+
+	switch c.policy {
+	case config.DecodeErrorPolicyDeadLetter:
+		c.deadLetters.DeadLetter(ctx, err)
+	case config.DecodeErrorPolicyHalt:
+		logger.Log.Error("error while consume event, vbID: %v, err: %v", ctx.VbID, err)
+		panic(err)
+	default:
+		logger.Log.Warn("event is skipped, vbID: %v, err: %v", ctx.VbID, err)
+		ctx.Ack()
+	}
+}
+
+func (c *typedConsumer[T]) setDecodeErrorPolicy(policy string, deadLetters deadLetterWriter) {
+	c.policy = policy
+	c.deadLetters = deadLetters
+}
+
+// configureDecoding applies consumer.decodeErrorPolicy to a consumer created by NewTypedConsumer.
+func configureDecoding(consumer models.Consumer, client couchbase.Client, c *config.Dcp) error {
+	decoding, ok := consumer.(decodingConsumer)
+	if !ok {
+		return nil
+	}
+
+	switch c.Consumer.DecodeErrorPolicy {
+	case config.DecodeErrorPolicySkip, config.DecodeErrorPolicyHalt:
+		decoding.setDecodeErrorPolicy(c.Consumer.DecodeErrorPolicy, nil)
+	case config.DecodeErrorPolicyDeadLetter:
+		var queue models.DeadLetterQueue
+
+		switch c.DeadLetter.Type {
+		case config.DeadLetterTypeFile:
+			queue = stream.NewFileDeadLetterQueue(c.DeadLetter.FileName)
+		case config.DeadLetterTypeCouchbase:
+			queue = couchbase.NewCBDeadLetterQueue(client, c)
+		default:
+			return errors.New("decode error policy deadLetter requires a dead letter queue")
+		}
+
+		// the dead letter consumer is used only to write the letters, the events are consumed by the typed consumer
+		deadLetters := stream.NewDeadLetterConsumer(nil, queue).(deadLetterWriter)
+		decoding.setDecodeErrorPolicy(c.Consumer.DecodeErrorPolicy, deadLetters)
+	default:
+		return fmt.Errorf("unknown decode error policy: %s", c.Consumer.DecodeErrorPolicy)
+	}
+
+	return nil
+}
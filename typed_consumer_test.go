@@ -0,0 +1,94 @@
+package dcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/couchbase/gocbcore/v10"
+	"github.com/golang/snappy"
+
+	"github.com/Trendyol/go-dcp/logger"
+	"github.com/Trendyol/go-dcp/models"
+)
+
+type order struct {
+	ID int `json:"id"`
+}
+
+func decodeOrder(value []byte) (order, error) {
+	var o order
+	err := json.Unmarshal(value, &o)
+	return o, err
+}
+
+func TestTypedConsumer(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	var changes []Change[order]
+	consumer := NewTypedConsumer(decodeOrder, func(_ *models.ListenerContext, change Change[order]) {
+		changes = append(changes, change)
+	})
+
+	acks := 0
+	consume := func(event interface{}) {
+		consumer.ConsumeEvent(&models.ListenerContext{Event: event, Ack: func() { acks++ }})
+	}
+
+	consume(models.DcpMutation{DcpMutation: &gocbcore.DcpMutation{Key: []byte("order::1"), Value: []byte(`{"id":1}`)}})
+	consume(models.DcpMutation{DcpMutation: &gocbcore.DcpMutation{Key: []byte("order::2"), Value: []byte(`{"id":`)}})
+	consume(models.DcpDeletion{DcpDeletion: &gocbcore.DcpDeletion{Key: []byte("order::1")}})
+	consume(models.DcpSeqNoAdvanced{DcpSeqNoAdvanced: &gocbcore.DcpSeqNoAdvanced{}})
+
+	if acks != 4 {
+		t.Fatalf("expected every event to be acked, got %d acks", acks)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+
+	if changes[0].Document.ID != 1 || string(changes[0].Key) != "order::1" || changes[0].Deleted {
+		t.Errorf("unexpected mutation change: %+v", changes[0])
+	}
+
+	if !changes[1].Deleted || changes[1].Document.ID != 0 {
+		t.Errorf("unexpected deletion change: %+v", changes[1])
+	}
+}
+
+func TestTypedConsumerWithCompressedValue(t *testing.T) {
+	logger.InitDefaultLogger("error")
+
+	var changes []Change[order]
+	consumer := NewTypedConsumer(decodeOrder, func(_ *models.ListenerContext, change Change[order]) {
+		changes = append(changes, change)
+	})
+
+	consumer.ConsumeEvent(&models.ListenerContext{
+		Event: models.DcpMutation{
+			DcpMutation: &gocbcore.DcpMutation{Key: []byte("order::3"), Value: snappy.Encode(nil, []byte(`{"id":3}`))},
+			Compressed:  true,
+		},
+		Ack: func() {},
+	})
+
+	if len(changes) != 1 || changes[0].Document.ID != 3 {
+		t.Errorf("compressed value must be decompressed before decode, changes: %+v", changes)
+	}
+}
+
+func TestTypedConsumerCapabilities(t *testing.T) {
+	handle := func(_ *models.ListenerContext, _ Change[order]) {}
+
+	if capabilities := NewTypedConsumer(decodeOrder, handle).(models.CapableConsumer).Capabilities(); capabilities.Ordering != "" {
+		t.Errorf("typed consumer must not declare an ordering by default, got %+v", capabilities)
+	}
+
+	expected := models.ConsumerCapabilities{Ordering: models.OrderingPerKey, Concurrency: 4}
+	consumer := NewTypedConsumerWithCapabilities(decodeOrder, handle, expected)
+
+	filtered := newFilteredConsumer(consumer, nil)
+	if capabilities := filtered.Capabilities(); capabilities != expected {
+		t.Errorf("capabilities must be forwarded through the pipeline filters, got %+v", capabilities)
+	}
+}